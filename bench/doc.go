@@ -0,0 +1,7 @@
+// Package bench holds benchmarks of the query hot path: URL key
+// generation, the bloom check chain, cached ID lookups and the full Hit
+// pipeline. Results before and after allocation work are kept in
+// results.txt; rerun them with
+//
+//	go test -run '^$' -bench . -benchmem ./bench/
+package bench
@@ -0,0 +1,218 @@
+package bench
+
+import (
+	"blacked/features/bloom"
+	"blacked/features/cache/ristretto_provider"
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	v2 "blacked/features/web/handlers/v2"
+	"blacked/internal/config"
+	idb "blacked/internal/db"
+	"blacked/internal/query"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestMain(m *testing.M) {
+	// Keep config and provider logs out of the results; loading the config
+	// sets the configured level, so it is loaded first.
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	config.GetConfig()
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+const (
+	hitURL  = "https://cdn.evil-bench.test/a/b/c/payload.exe?ref=mail"
+	missURL = "https://www.clean-bench.test/docs/guide/index.html?lang=en"
+)
+
+// newBloom returns a manager holding a few thousand hosts and paths of
+// three sources, with hitURL listed by its full URL.
+func newBloom(b *testing.B) *bloom.BloomManager {
+	b.Helper()
+	bm := bloom.NewBloomManager(10_000)
+	for i := range 3000 {
+		source := fmt.Sprintf("source-%d", i%3)
+		keys, err := bloom.ParseURL(fmt.Sprintf("https://host-%d.listed-bench.test/p/%d/file-%d.bin", i, i, i))
+		if err != nil {
+			b.Fatal(err)
+		}
+		bm.PopulateEntry(source, keys)
+	}
+	keys, err := bloom.ParseURL(hitURL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	bm.PopulateEntry("source-0", keys)
+	return bm
+}
+
+func BenchmarkParseURL(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := bloom.ParseURL(hitURL); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGenerateCheckKeys(b *testing.B) {
+	keys, err := bloom.ParseURL(hitURL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = keys.GenerateCheckKeys()
+	}
+}
+
+func BenchmarkBloomLikely(b *testing.B) {
+	bm := newBloom(b)
+	for name, u := range map[string]string{"hit": hitURL, "miss": missURL} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := bm.Likely(u); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkQueryHit(b *testing.B) {
+	svc := query.NewQueryService(v2.NewBloomAdapter(newBloom(b)), nil, nil)
+	ctx := context.Background()
+	for name, u := range map[string]string{"hit": hitURL, "miss": missURL} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := svc.Hit(ctx, u); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkNormalizeHits(b *testing.B) {
+	// What QueryLink gathers for a listed URL: the same entries found by
+	// exact URL, host and domain.
+	var hits []entries.Hit
+	for _, match := range []string{entries.MatchTypeDomain, entries.MatchTypeHost, entries.MatchTypeExactURL} {
+		for i := range 3 {
+			hits = append(hits, entries.Hit{ID: fmt.Sprintf("entry-%d", i), MatchType: match, ActivatedAt: int64(i)})
+		}
+	}
+	buf := make([]entries.Hit, len(hits))
+
+	b.ReportAllocs()
+	for b.Loop() {
+		copy(buf, hits)
+		_ = entries.NormalizeHits(buf)
+	}
+}
+
+func BenchmarkRistrettoGet(b *testing.B) {
+	cfg := config.GetConfig()
+	prev := cfg.Cache
+	cfg.Cache.MaxMemory = 1 << 24
+	cfg.Cache.NumCounters = 100_000
+	cfg.Cache.TTL = nil
+	b.Cleanup(func() { cfg.Cache = prev })
+
+	p := ristretto_provider.NewRistrettoProvider()
+	if err := p.Initialize(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { p.Close() })
+
+	ids := "d0ljs1ovh2s8ck2a0rp0,d0ljs1ovh2s8ck2a0rpg,d0ljs1ovh2s8ck2a0rq0,d0ljs1ovh2s8ck2a0rqg"
+	if err := p.Set(hitURL, ids); err != nil {
+		b.Fatal(err)
+	}
+	p.Commit()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := p.Get(hitURL); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkQueryHydration compares a scored lookup's two ways to get the
+// matched entries' source, categories and confidence: the match queries
+// followed by GetEntriesByIDs, and the enriched match queries alone.
+func BenchmarkQueryHydration(b *testing.B) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { conn.Close() })
+	if err := idb.MigrateSchema(conn); err != nil {
+		b.Fatal(err)
+	}
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+	var batch []*entries.Entry
+	for i := range 5000 {
+		e, err := entries.FromURL(fmt.Sprintf("https://host-%d.listed-bench.test/p/%d/file-%d.bin", i, i, i), fmt.Sprintf("source-%d", i%3), "bench")
+		if err != nil {
+			b.Fatal(err)
+		}
+		batch = append(batch, e)
+	}
+	// hitURL matches by exact URL, host and domain across three sources.
+	for i := range 3 {
+		e, err := entries.FromURL(hitURL, fmt.Sprintf("source-%d", i), "bench")
+		if err != nil {
+			b.Fatal(err)
+		}
+		batch = append(batch, e)
+	}
+	for _, u := range []string{"https://cdn.evil-bench.test/", "https://www.evil-bench.test/x"} {
+		e, err := entries.FromURL(u, "source-0", "bench")
+		if err != nil {
+			b.Fatal(err)
+		}
+		batch = append(batch, e)
+	}
+	if err := repo.BatchSaveEntries(ctx, batch); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("two_step", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			hits, err := repo.QueryLinkByType(ctx, hitURL, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			ids := make([]string, 0, len(hits))
+			for _, h := range hits {
+				ids = append(ids, h.ID)
+			}
+			found, err := repo.GetEntriesByIDs(ctx, ids)
+			if err != nil || len(found) != len(hits) {
+				b.Fatalf("hydrated %d of %d hits: %v", len(found), len(hits), err)
+			}
+		}
+	})
+	b.Run("enriched", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			hits, err := repo.QueryLinkEnriched(ctx, hitURL, nil)
+			if err != nil || len(hits) == 0 || hits[0].Entry == nil {
+				b.Fatalf("enriched %d hits: %v", len(hits), err)
+			}
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"blacked/features/allowlist"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+var ErrCreateAllowlistService = errors.New("failed to create allowlist service")
+
+var allowlistJSONFlag = &cli.BoolFlag{
+	Name:    "json",
+	Aliases: []string{"j"},
+	Usage:   "Output in JSON format.",
+}
+
+// AllowlistCommand manages the domains and URLs never reported as hits.
+var AllowlistCommand = &cli.Command{
+	Name:  "allowlist",
+	Usage: "Manage domains and URLs that are never reported as hits",
+	Subcommands: []*cli.Command{
+		{
+			Name:   "list",
+			Usage:  "List allowlist rules",
+			Flags:  []cli.Flag{allowlistJSONFlag},
+			Action: listAllowlist,
+		},
+		{
+			Name:  "add",
+			Usage: "Add an allowlist rule",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "pattern",
+					Aliases:  []string{"p"},
+					Usage:    "Domain (matches all subdomains) or URL (matches everything below its path).",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:    "kind",
+					Aliases: []string{"k"},
+					Usage:   "Rule kind: [domain, url]. Inferred from the pattern when empty.",
+				},
+				&cli.StringFlag{
+					Name:    "reason",
+					Aliases: []string{"r"},
+					Usage:   "Why the pattern is allowlisted.",
+				},
+				allowlistJSONFlag,
+			},
+			Action: addAllowlist,
+		},
+		{
+			Name:  "remove",
+			Usage: "Remove an allowlist rule",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "id",
+					Usage:    "ID of the rule to remove.",
+					Required: true,
+				},
+			},
+			Action: removeAllowlist,
+		},
+		{
+			Name:  "check",
+			Usage: "Show the rule allowlisting a URL, if any",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "url",
+					Aliases:  []string{"u"},
+					Usage:    "URL to check.",
+					Required: true,
+				},
+				allowlistJSONFlag,
+			},
+			Action: checkAllowlist,
+		},
+	},
+}
+
+func newAllowlistService() (*allowlist.Service, error) {
+	svc, err := allowlist.NewService()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create allowlist service")
+		return nil, ErrCreateAllowlistService
+	}
+	return svc, nil
+}
+
+func listAllowlist(c *cli.Context) error {
+	svc, err := newAllowlistService()
+	if err != nil {
+		return err
+	}
+
+	rules, err := svc.List(c.Context)
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(rules)
+	}
+
+	for _, r := range rules {
+		printAllowlistRule(&r)
+	}
+	fmt.Printf("\nRules: %d\n", len(rules))
+	return nil
+}
+
+func addAllowlist(c *cli.Context) error {
+	svc, err := newAllowlistService()
+	if err != nil {
+		return err
+	}
+
+	rule, err := svc.Add(c.Context, allowlist.Kind(c.String("kind")), c.String("pattern"), c.String("reason"))
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(rule)
+	}
+
+	printAllowlistRule(rule)
+	return nil
+}
+
+func removeAllowlist(c *cli.Context) error {
+	svc, err := newAllowlistService()
+	if err != nil {
+		return err
+	}
+
+	if err := svc.Delete(c.Context, c.String("id")); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s\n", c.String("id"))
+	return nil
+}
+
+func checkAllowlist(c *cli.Context) error {
+	svc, err := newAllowlistService()
+	if err != nil {
+		return err
+	}
+
+	rule, err := svc.Match(c.Context, c.String("url"))
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(map[string]any{"url": c.String("url"), "allowlisted": rule != nil, "rule": rule})
+	}
+
+	if rule == nil {
+		fmt.Println("Not allowlisted")
+		return nil
+	}
+	printAllowlistRule(rule)
+	return nil
+}
+
+func printAllowlistRule(r *allowlist.Rule) {
+	fmt.Printf("%s  %-6s  %s", r.ID, r.Kind, r.Pattern)
+	if r.Reason != "" {
+		fmt.Printf("  (%s)", r.Reason)
+	}
+	fmt.Println()
+}
+
+func printJSON(v any) error {
+	jsonData, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to marshal JSON")
+		return ErrMarshalJSON
+	}
+	fmt.Println(string(jsonData))
+	return nil
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"blacked/features/apikeys"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+var ErrCreateAPIKeyService = errors.New("failed to create API key service")
+
+var apiKeyJSONFlag = &cli.BoolFlag{
+	Name:    "json",
+	Aliases: []string{"j"},
+	Usage:   "Output in JSON format.",
+}
+
+// APIKeyCommand manages the API keys callers authenticate with.
+var APIKeyCommand = &cli.Command{
+	Name:  "apikey",
+	Usage: "Manage the API keys callers authenticate with when [Auth] is enabled",
+	Subcommands: []*cli.Command{
+		{
+			Name:   "list",
+			Usage:  "List API keys",
+			Flags:  []cli.Flag{apiKeyJSONFlag},
+			Action: listAPIKeys,
+		},
+		{
+			Name:  "create",
+			Usage: "Create an API key and print its token, which cannot be shown again",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "name",
+					Aliases:  []string{"n"},
+					Usage:    "Who or what the key is for.",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:  "role",
+					Usage: "Key role: [reader, importer, admin]. Readers only query; importers also import and run providers.",
+					Value: string(apikeys.RoleReader),
+				},
+				&cli.Float64Flag{
+					Name:    "rate-limit",
+					Aliases: []string{"r"},
+					Usage:   "Requests per second; 0 uses [Auth] default_rate_limit.",
+				},
+				apiKeyJSONFlag,
+			},
+			Action: createAPIKey,
+		},
+		{
+			Name:  "delete",
+			Usage: "Delete an API key",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "id",
+					Usage:    "ID of the key to delete.",
+					Required: true,
+				},
+			},
+			Action: deleteAPIKey,
+		},
+	},
+}
+
+func newAPIKeyService() (*apikeys.Service, error) {
+	svc, err := apikeys.NewService()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create API key service")
+		return nil, ErrCreateAPIKeyService
+	}
+	return svc, nil
+}
+
+func listAPIKeys(c *cli.Context) error {
+	svc, err := newAPIKeyService()
+	if err != nil {
+		return err
+	}
+
+	keys, err := svc.List(c.Context)
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(keys)
+	}
+
+	for _, k := range keys {
+		printAPIKey(&k)
+	}
+	fmt.Printf("\nKeys: %d\n", len(keys))
+	return nil
+}
+
+func createAPIKey(c *cli.Context) error {
+	svc, err := newAPIKeyService()
+	if err != nil {
+		return err
+	}
+
+	key, token, err := svc.Create(c.Context, c.String("name"), apikeys.Role(c.String("role")), c.Float64("rate-limit"))
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(map[string]any{"key": key, "token": token})
+	}
+
+	printAPIKey(key)
+	fmt.Printf("\nToken (shown once): %s\n", token)
+	return nil
+}
+
+func deleteAPIKey(c *cli.Context) error {
+	svc, err := newAPIKeyService()
+	if err != nil {
+		return err
+	}
+
+	if err := svc.Delete(c.Context, c.String("id")); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %s\n", c.String("id"))
+	return nil
+}
+
+func printAPIKey(k *apikeys.Key) {
+	fmt.Printf("%s  %-8s  %s…  %s", k.ID, k.Role, k.Prefix, k.Name)
+	if k.RateLimit > 0 {
+		fmt.Printf("  (%g req/s)", k.RateLimit)
+	}
+	if k.LastUsedAt != nil {
+		fmt.Printf("  last used %s", k.LastUsedAt.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"blacked/features/entry_collector"
+	"blacked/internal/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	ErrCacheSyncRequest = errors.New("cache sync request failed")
+	ErrCacheSyncFailed  = errors.New("cache sync job failed")
+)
+
+var cacheSyncCommand = &cli.Command{
+	Name:  "sync",
+	Usage: "Trigger a cache sync on the running server and optionally wait for it",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "mode",
+			Aliases: []string{"m"},
+			Usage:   "Sync scope: [full, delta, source].",
+			Value:   string(entry_collector.CacheSyncFull),
+		},
+		&cli.StringFlag{
+			Name:  "process-id",
+			Usage: "Delta mode: resync URLs changed since this process first wrote.",
+		},
+		&cli.StringFlag{
+			Name:    "source",
+			Aliases: []string{"s"},
+			Usage:   "Source mode: resync every URL of this source (provider name).",
+		},
+		&cli.StringFlag{
+			Name:  "target",
+			Usage: "Base server URL. Defaults to the configured server address.",
+		},
+		&cli.BoolFlag{
+			Name:    "wait",
+			Aliases: []string{"w"},
+			Usage:   "Poll the job until it completes or fails.",
+		},
+		&cli.DurationFlag{
+			Name:  "poll-interval",
+			Usage: "How often to poll the job with --wait.",
+			Value: time.Second,
+		},
+		&cli.BoolFlag{
+			Name:    "json",
+			Aliases: []string{"j"},
+			Usage:   "Output the job in JSON format.",
+		},
+	},
+	Action: syncCache,
+}
+
+// CacheCommand groups cache operations run against a live server.
+var CacheCommand = &cli.Command{
+	Name:        "cache",
+	Usage:       "Cache operations on the running server",
+	Subcommands: []*cli.Command{cacheSyncCommand},
+}
+
+// cacheSyncEnvelope is the response shape of the cache sync endpoints.
+type cacheSyncEnvelope struct {
+	Success bool                         `json:"success"`
+	Data    entry_collector.CacheSyncJob `json:"data"`
+	Error   string                       `json:"error"`
+}
+
+func syncCache(c *cli.Context) error {
+	target := c.String("target")
+	if target == "" {
+		target = config.GetConfig().Server.GetServerURL()
+	}
+	target = strings.TrimRight(target, "/") + "/cache/sync"
+
+	body, err := json.Marshal(map[string]string{
+		"mode":       c.String("mode"),
+		"process_id": c.String("process-id"),
+		"source":     c.String("source"),
+	})
+	if err != nil {
+		return ErrMarshalJSON
+	}
+
+	job, err := doCacheSyncRequest(c.Context, http.MethodPost, target, body)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("wait") {
+		ticker := time.NewTicker(c.Duration("poll-interval"))
+		defer ticker.Stop()
+
+		for job.State == entry_collector.CacheSyncJobQueued || job.State == entry_collector.CacheSyncJobRunning {
+			select {
+			case <-c.Context.Done():
+				return c.Context.Err()
+			case <-ticker.C:
+			}
+			if job, err = doCacheSyncRequest(c.Context, http.MethodGet, target+"/"+job.ID, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := printCacheSyncJob(job, c.Bool("json")); err != nil {
+		return err
+	}
+	if job.State == entry_collector.CacheSyncJobFailed {
+		return ErrCacheSyncFailed
+	}
+	return nil
+}
+
+func doCacheSyncRequest(ctx context.Context, method, url string, body []byte) (*entry_collector.CacheSyncJob, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Error().Err(err).Str("url", url).Msg("Failed to reach server")
+		return nil, ErrCacheSyncRequest
+	}
+	defer resp.Body.Close()
+
+	var env cacheSyncEnvelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		log.Error().Err(err).Int("status", resp.StatusCode).Msg("Failed to decode cache sync response")
+		return nil, ErrCacheSyncRequest
+	}
+	if !env.Success {
+		log.Error().Int("status", resp.StatusCode).Str("error", env.Error).Msg("Server rejected cache sync request")
+		return nil, ErrCacheSyncRequest
+	}
+
+	return &env.Data, nil
+}
+
+func printCacheSyncJob(job *entry_collector.CacheSyncJob, asJSON bool) error {
+	if asJSON {
+		jsonData, err := json.MarshalIndent(job, "", "  ")
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal JSON")
+			return ErrMarshalJSON
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	fmt.Printf("Job:      %s\n", job.ID)
+	fmt.Printf("Mode:     %s\n", job.Scope.Mode)
+	if job.Scope.Source != "" {
+		fmt.Printf("Source:   %s\n", job.Scope.Source)
+	}
+	if job.Scope.ProcessID != "" {
+		fmt.Printf("Process:  %s\n", job.Scope.ProcessID)
+	}
+	fmt.Printf("State:    %s\n", job.State)
+	fmt.Printf("Synced:   %d\n", job.Synced)
+	fmt.Printf("Removed:  %d\n", job.Removed)
+	if job.Progress.Expected > 0 {
+		fmt.Printf("Progress: %.1f%% of %d (%.0f keys/s)\n", job.Progress.Percent, job.Progress.Expected, job.Progress.KeysPerSec)
+	}
+	if job.Progress.RateLimit > 0 {
+		fmt.Printf("Throttle: %.0f keys/s\n", job.Progress.RateLimit)
+	}
+	if job.StartedAt != nil && job.FinishedAt != nil {
+		fmt.Printf("Duration: %s\n", job.FinishedAt.Sub(*job.StartedAt))
+	}
+	if job.Error != "" {
+		fmt.Printf("Error:    %s\n", job.Error)
+	}
+
+	return nil
+}
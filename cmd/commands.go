@@ -10,4 +10,18 @@ var Commands = []*cli.Command{
 	provider_processor.ProcessCommand,
 	QueryCommand,
 	WebServer,
+	WorkerCommand,
+	LoadTestCommand,
+	ReparseCommand,
+	MergeCommand,
+	EdgeCommand,
+	CacheCommand,
+	AllowlistCommand,
+	PatternsCommand,
+	APIKeyCommand,
+	RetrohuntCommand,
+	ExportCommand,
+	DBCommand,
+	ProvidersCommand,
+	SeedCommand,
 }
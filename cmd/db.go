@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"blacked/features/entries/services"
+	"blacked/features/maintenance"
+	"blacked/internal/config"
+	"blacked/internal/db"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+var dbMaintainCommand = &cli.Command{
+	Name:  "maintain",
+	Usage: "Return free pages to the file system, truncate the WAL and report file sizes",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Only report sizes and estimate what maintenance would reclaim.",
+		},
+		&cli.BoolFlag{
+			Name:    "json",
+			Aliases: []string{"j"},
+			Usage:   "Output the report in JSON format.",
+		},
+	},
+	Action: maintainDB,
+}
+
+var dbPurgeDeletedCommand = &cli.Command{
+	Name:  "purge-deleted",
+	Usage: "Remove soft-deleted entries past the retention for good and vacuum the database",
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "older-than",
+			Usage: "Remove entries soft deleted longer ago than this (default [Retention] deleted).",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Only count the entries that would be removed.",
+		},
+		&cli.BoolFlag{
+			Name:    "json",
+			Aliases: []string{"j"},
+			Usage:   "Output the report in JSON format.",
+		},
+	},
+	Action: purgeDeleted,
+}
+
+// DBCommand groups operations on the local database file.
+var DBCommand = &cli.Command{
+	Name:        "db",
+	Usage:       "Database file operations",
+	Subcommands: []*cli.Command{dbMaintainCommand, dbPurgeDeletedCommand},
+}
+
+func maintainDB(c *cli.Context) error {
+	conn, err := db.GetWriteDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get write database connection")
+		return err
+	}
+
+	report, err := db.Maintain(c.Context, conn, c.Bool("dry-run"))
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(report)
+	}
+
+	fmt.Printf("Database: %s\n", report.Path)
+	fmt.Printf("%-8s %14s %14s %10s %14s\n", "", "db bytes", "wal bytes", "pages", "fragmentation")
+	printFileStats("Before", report.Before)
+	after := "After"
+	if report.DryRun {
+		after = "Estimate"
+	}
+	printFileStats(after, report.After)
+	fmt.Printf("\nReclaimed: %d bytes  Duration: %s\n", report.ReclaimedBytes, report.Duration)
+	if report.Converted {
+		fmt.Println("Switched to incremental auto_vacuum with a full VACUUM; later runs are incremental.")
+	}
+	return nil
+}
+
+func purgeDeleted(c *cli.Context) error {
+	cfg := config.GetConfig().Retention
+	olderThan := cfg.Deleted
+	if c.IsSet("older-than") {
+		olderThan = c.Duration("older-than")
+	}
+
+	// Backups are taken in read-only mode; only counting is allowed then.
+	if !c.Bool("dry-run") {
+		if err := maintenance.Check(c.Context); err != nil {
+			log.Error().Msg("Refusing to purge deleted entries in read-only maintenance mode; clear it with DELETE /maintenance/read-only first")
+			return err
+		}
+	}
+
+	svc, err := services.NewRetentionService(cfg)
+	if err != nil {
+		return err
+	}
+	report, err := svc.Purge(c.Context, olderThan, c.Bool("dry-run"))
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(report)
+	}
+
+	verb := "Purged"
+	if report.DryRun {
+		verb = "Would purge"
+	}
+	fmt.Printf("%s %d entries soft deleted before %s\n", verb, report.Purged, report.Before.Format(time.RFC3339))
+	if report.Maintenance != nil {
+		fmt.Printf("Reclaimed: %d bytes  Fragmentation: %.1f%% -> %.1f%%\n", report.ReclaimedBytes,
+			report.Maintenance.Before.Fragmentation*100, report.Maintenance.After.Fragmentation*100)
+	}
+	fmt.Printf("Duration: %s\n", report.Duration)
+	return nil
+}
+
+func printFileStats(label string, s db.FileStats) {
+	fmt.Printf("%-8s %14d %14d %10d %13.1f%%\n", label, s.DBBytes, s.WALBytes, s.Pages, s.Fragmentation*100)
+}
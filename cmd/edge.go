@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"blacked/features/edgeset"
+	"blacked/features/entries/repository"
+	"blacked/features/web"
+	"blacked/internal/config"
+	"blacked/internal/db"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ory/graceful"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	ErrEdgeDatasetPathMissing = errors.New("edge dataset path not set: use --dataset or [Edge] dataset_path")
+	ErrOpenEdgeDataset        = errors.New("failed to open edge dataset")
+)
+
+var datasetFlag = &cli.StringFlag{
+	Name:  "dataset",
+	Usage: "Edge dataset file. Defaults to [Edge] dataset_path.",
+}
+
+var edgeCompileCommand = &cli.Command{
+	Name:  "compile",
+	Usage: "Compile the active entries into an edge dataset file",
+	Flags: []cli.Flag{
+		datasetFlag,
+		&cli.BoolFlag{
+			Name:    "json",
+			Aliases: []string{"j"},
+			Usage:   "Output the build stats in JSON format.",
+		},
+	},
+	Action: compileEdgeDataset,
+}
+
+var edgeServeCommand = &cli.Command{
+	Name:   "serve",
+	Usage:  "Serve the query API from a memory-mapped edge dataset, without SQLite or Badger",
+	Flags:  []cli.Flag{datasetFlag},
+	Action: serveEdgeDataset,
+}
+
+// EdgeCommand compiles and serves the read-only dataset used by edge nodes.
+var EdgeCommand = &cli.Command{
+	Name:        "edge",
+	Usage:       "Compile or serve the compact read-only edge dataset",
+	Subcommands: []*cli.Command{edgeCompileCommand, edgeServeCommand},
+}
+
+// IsEdgeServe reports whether args (without the program name) run `edge serve`,
+// which must start without initializing the database, cache or providers.
+func IsEdgeServe(args []string) bool {
+	return len(args) >= 2 && args[0] == EdgeCommand.Name && args[1] == edgeServeCommand.Name
+}
+
+func edgeDatasetPath(c *cli.Context) (string, error) {
+	path := c.String("dataset")
+	if path == "" {
+		path = config.GetConfig().Edge.DatasetPath
+	}
+	if path == "" {
+		return "", ErrEdgeDatasetPathMissing
+	}
+	return path, nil
+}
+
+func compileEdgeDataset(c *cli.Context) error {
+	path, err := edgeDatasetPath(c)
+	if err != nil {
+		return err
+	}
+
+	readDB, err := db.GetDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return err
+	}
+
+	stats, err := edgeset.Compile(c.Context, repository.NewSQLiteRepository(readDB), path)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("json") {
+		jsonData, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal JSON")
+			return ErrMarshalJSON
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	fmt.Printf("Path:     %s\n", stats.Path)
+	fmt.Printf("Entries:  %d (skipped %d)\n", stats.Entries, stats.Skipped)
+	fmt.Printf("Keys:     %d from %d sources\n", stats.Keys, stats.Sources)
+	fmt.Printf("Size:     %d bytes\n", stats.Bytes)
+	fmt.Printf("Duration: %s\n", stats.Duration)
+
+	return nil
+}
+
+func serveEdgeDataset(c *cli.Context) error {
+	path, err := edgeDatasetPath(c)
+	if err != nil {
+		return err
+	}
+
+	ds, err := edgeset.Open(path)
+	if err != nil {
+		log.Error().Err(err).Str("path", path).Msg("Failed to open edge dataset")
+		return ErrOpenEdgeDataset
+	}
+	defer ds.Close()
+
+	info := ds.Info()
+	log.Info().
+		Str("path", path).
+		Time("created_at", info.CreatedAt).
+		Uint64("keys", info.Keys).
+		Int("bytes", info.Bytes).
+		Msg("Edge dataset mapped")
+
+	cfg := config.GetConfig()
+	app, err := web.NewEdgeApplication(&cfg.Server, ds, config.LoadScoringConfig())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create edge application")
+		return err
+	}
+
+	server := graceful.WithDefaults(app.Echo.Server)
+	log.Info().Msgf("Starting edge server on %s", server.Addr)
+
+	if err := graceful.Graceful(server.ListenAndServe, server.Shutdown); err != nil {
+		log.Error().Err(err).Msg("Failed to start edge server")
+		return err
+	}
+
+	log.Info().Msg("Edge server stopped gracefully.")
+	return nil
+}
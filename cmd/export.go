@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"blacked/features/entries/repository"
+	"blacked/features/export"
+	"blacked/internal/config"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	ErrCreateExportService = errors.New("failed to create export service")
+	ErrCreateExportFile    = errors.New("failed to create export file")
+)
+
+// ExportCommand writes the active entries in a list format for downstream
+// blockers such as Pi-hole or squid.
+var ExportCommand = &cli.Command{
+	Name:  "export",
+	Usage: "Export active entries as a hosts file, Adblock list, plain URL list, CSV or JSON",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "format",
+			Aliases: []string{"f"},
+			Usage:   "Output format: [" + strings.Join(formatNames(), ", ") + "].",
+			Value:   string(export.FormatPlain),
+		},
+		&cli.StringSliceFlag{
+			Name:    "source",
+			Aliases: []string{"s"},
+			Usage:   "Only export entries of these sources or provider groups (comma-separated).",
+		},
+		&cli.StringSliceFlag{
+			Name:    "category",
+			Aliases: []string{"c"},
+			Usage:   "Only export entries of these categories (comma-separated).",
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "File to write; standard output when empty.",
+		},
+	},
+	Action: runExport,
+}
+
+func formatNames() []string {
+	names := make([]string, 0, len(export.Formats))
+	for _, f := range export.Formats {
+		names = append(names, string(f))
+	}
+	return names
+}
+
+func runExport(c *cli.Context) error {
+	format, err := export.ParseFormat(c.String("format"))
+	if err != nil {
+		return err
+	}
+
+	svc, err := export.NewService()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create export service")
+		return ErrCreateExportService
+	}
+
+	var out io.Writer = os.Stdout
+	if path := c.String("output"); path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			log.Error().Err(err).Str("file", path).Msg("Failed to create export file")
+			return ErrCreateExportFile
+		}
+		defer file.Close()
+		out = file
+	}
+	bw := bufio.NewWriter(out)
+
+	w, err := export.NewWriter(format, bw)
+	if err != nil {
+		return err
+	}
+	// A group stands for its providers.
+	sources, _ := config.GetConfig().ExpandProviderGroups(c.StringSlice("source"))
+	f := repository.Filter{
+		Sources:    sources,
+		Categories: c.StringSlice("category"),
+	}
+	count, err := svc.Export(c.Context, f, w)
+	if err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		log.Error().Err(err).Msg("Failed to write export")
+		return export.ErrExportFailed
+	}
+
+	if c.String("output") != "" {
+		fmt.Printf("Exported %d entries to %s\n", count, c.String("output"))
+	}
+	return nil
+}
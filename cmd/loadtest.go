@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"blacked/internal/config"
+	"blacked/internal/loadtest"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	ErrLoadTestFailed          = errors.New("load test failed")
+	ErrInvalidLoadTestEndpoint = errors.New("endpoint must be one of: check, hit")
+)
+
+// LoadTestCommand drives the local query API at a fixed rate and reports latency percentiles.
+var LoadTestCommand = &cli.Command{
+	Name:  "loadtest",
+	Usage: "Drive the query API at a fixed rate and report latency percentiles and error rates",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "rps",
+			Usage: "Target requests per second.",
+			Value: 1000,
+		},
+		&cli.DurationFlag{
+			Name:    "duration",
+			Aliases: []string{"d"},
+			Usage:   "How long to run the test.",
+			Value:   30 * time.Second,
+		},
+		&cli.StringFlag{
+			Name:  "urls-file",
+			Usage: "File with one URL per line, sampled at random for each request.",
+		},
+		&cli.Float64Flag{
+			Name:  "miss-ratio",
+			Usage: "Fraction (0-1) of requests sent with synthetic URLs that are never listed.",
+			Value: 0.5,
+		},
+		&cli.StringFlag{
+			Name:  "endpoint",
+			Usage: "Query endpoint to drive: [check, hit].",
+			Value: "check",
+		},
+		&cli.StringFlag{
+			Name:  "target",
+			Usage: "Base server URL. Defaults to the configured server address.",
+		},
+		&cli.IntFlag{
+			Name:  "concurrency",
+			Usage: "Number of concurrent request workers.",
+			Value: 64,
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "Per-request timeout.",
+			Value: 5 * time.Second,
+		},
+		&cli.BoolFlag{
+			Name:    "json",
+			Aliases: []string{"j"},
+			Usage:   "Output the report in JSON format.",
+		},
+	},
+	Action: runLoadTest,
+}
+
+func runLoadTest(c *cli.Context) error {
+	endpoint := c.String("endpoint")
+	if !slices.Contains([]string{"check", "hit"}, endpoint) {
+		return ErrInvalidLoadTestEndpoint
+	}
+
+	target := c.String("target")
+	if target == "" {
+		target = config.GetConfig().Server.GetServerURL()
+	}
+
+	var urls []string
+	if path := c.String("urls-file"); path != "" {
+		loaded, err := loadtest.LoadURLs(path)
+		if err != nil {
+			return err
+		}
+		urls = loaded
+	}
+
+	opts := loadtest.Options{
+		Target:      target,
+		Endpoint:    endpoint,
+		RPS:         c.Int("rps"),
+		Duration:    c.Duration("duration"),
+		Concurrency: c.Int("concurrency"),
+		MissRatio:   c.Float64("miss-ratio"),
+		URLs:        urls,
+		Timeout:     c.Duration("timeout"),
+	}
+
+	log.Info().
+		Str("target", target).
+		Str("endpoint", endpoint).
+		Int("rps", opts.RPS).
+		Dur("duration", opts.Duration).
+		Int("urls", len(urls)).
+		Float64("miss_ratio", opts.MissRatio).
+		Msg("Starting load test")
+
+	report, err := loadtest.Run(c.Context, opts)
+	if err != nil {
+		log.Err(err).Msg("Load test failed")
+		return ErrLoadTestFailed
+	}
+
+	return printLoadTestReport(report, c.Bool("json"))
+}
+
+func printLoadTestReport(report *loadtest.Report, asJSON bool) error {
+	if asJSON {
+		jsonData, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal JSON")
+			return ErrMarshalJSON
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	fmt.Printf("Requests:      %d (hit %d / miss %d, dropped %d)\n",
+		report.Requests, report.HitRequests, report.MissRequests, report.Dropped)
+	fmt.Printf("Elapsed:       %s\n", report.Elapsed.Round(time.Millisecond))
+	fmt.Printf("Achieved RPS:  %.1f\n", report.AchievedRPS)
+	fmt.Printf("Errors:        %d (%.2f%%)\n", report.Errors, report.ErrorRate*100)
+	fmt.Printf("Status codes:  %v\n", report.StatusCodes)
+	l := report.Latency
+	fmt.Printf("Latency:       min %s  mean %s  p50 %s  p90 %s  p95 %s  p99 %s  max %s\n",
+		l.Min, l.Mean, l.P50, l.P90, l.P95, l.P99, l.Max)
+
+	return nil
+}
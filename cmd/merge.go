@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"blacked/features/entries/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	ErrCreateMergeService = errors.New("failed to create merge service")
+	ErrMergeModeConflict  = errors.New("--dry-run and --apply are mutually exclusive")
+)
+
+// MergeCommand consolidates scheme and trailing-slash variants of the same URL.
+var MergeCommand = &cli.Command{
+	Name:  "merge",
+	Usage: "Find entries of a source that differ only in scheme, host case or trailing slashes and merge them into the oldest one",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Only report near-duplicate groups (default when --apply is not set).",
+		},
+		&cli.BoolFlag{
+			Name:  "apply",
+			Usage: "Merge each group into its canonical entry and soft delete the rest under a dedicated process id.",
+		},
+		&cli.StringFlag{
+			Name:    "source",
+			Aliases: []string{"s"},
+			Usage:   "Only merge entries of this source (provider name).",
+		},
+		&cli.IntFlag{
+			Name:  "max-groups",
+			Usage: "Maximum number of groups to print (0 = all).",
+			Value: 50,
+		},
+		&cli.BoolFlag{
+			Name:    "json",
+			Aliases: []string{"j"},
+			Usage:   "Output the report in JSON format.",
+		},
+	},
+	Action: mergeEntries,
+}
+
+func mergeEntries(c *cli.Context) error {
+	if c.Bool("dry-run") && c.Bool("apply") {
+		return ErrMergeModeConflict
+	}
+
+	svc, err := services.NewMergeService()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create merge service")
+		return ErrCreateMergeService
+	}
+
+	report, err := svc.Run(c.Context, services.MergeOptions{
+		Source:    c.String("source"),
+		Apply:     c.Bool("apply"),
+		MaxGroups: c.Int("max-groups"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return printMergeReport(report, c.Bool("json"))
+}
+
+func printMergeReport(report *services.MergeReport, asJSON bool) error {
+	if asJSON {
+		jsonData, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal JSON")
+			return ErrMarshalJSON
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	for _, g := range report.Details {
+		fmt.Printf("%s [%s] %s %v\n", g.CanonicalID, g.Source, g.CanonicalURL, g.Categories)
+		for i, id := range g.MergedIDs {
+			fmt.Printf("    <- %s %s\n", id, g.MergedURLs[i])
+		}
+	}
+
+	mode := "dry-run"
+	if report.Applied {
+		mode = "applied (process " + report.ProcessID + ")"
+	}
+	fmt.Printf("\nMode:       %s\n", mode)
+	fmt.Printf("Candidates: %d\n", report.Candidates)
+	fmt.Printf("Groups:     %d\n", report.Groups)
+	fmt.Printf("Merged:     %d\n", report.Merged)
+	fmt.Printf("Duration:   %s\n", report.Duration)
+	if report.Applied && report.Merged > 0 {
+		fmt.Printf("\nRun `blacked cache sync --mode delta --process-id %s` against a running server to drop the merged URLs from its caches.\n", report.ProcessID)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import "slices"
+
+// RunMode is a deployment role: which long-running subsystems a process starts.
+type RunMode string
+
+const (
+	RunModeAll       RunMode = "all"       // Everything in one process (default)
+	RunModeAPI       RunMode = "api"       // Stateless query API pods
+	RunModeScheduler RunMode = "scheduler" // The single ingest pod, driven by cron
+	RunModeWorker    RunMode = "worker"    // One-shot ingest, e.g. from an external cron job
+)
+
+// Subsystems are the parts of the service a run mode starts. The database,
+// collector and provider registry are needed by every mode except edge serve.
+type Subsystems struct {
+	Cache     bool // Query cache and bloom, rebuilt from the database
+	API       bool // HTTP API and, when grpc_port is set, gRPC
+	Scheduler bool // Cron runner triggering providers
+	Startup   bool // Startup provider evaluation (skip, restore or fetch)
+}
+
+var runModeSubsystems = map[RunMode]Subsystems{
+	RunModeAll:       {Cache: true, API: true, Scheduler: true, Startup: true},
+	RunModeAPI:       {Cache: true, API: true},
+	RunModeScheduler: {Scheduler: true, Startup: true},
+	RunModeWorker:    {Startup: true},
+}
+
+// Subsystems returns what mode starts.
+func (m RunMode) Subsystems() Subsystems {
+	return runModeSubsystems[m]
+}
+
+// RunModeFromArgs returns the run mode selected by args (without the program
+// name), or "" for commands that are not a serving role.
+func RunModeFromArgs(args []string) RunMode {
+	if len(args) == 0 {
+		return ""
+	}
+	if args[0] == WorkerCommand.Name {
+		return RunModeWorker
+	}
+	if args[0] != WebServer.Name && !slices.Contains(WebServer.Aliases, args[0]) {
+		return ""
+	}
+
+	if len(args) > 1 {
+		for _, sub := range WebServer.Subcommands {
+			if sub.Name == args[1] {
+				return RunMode(sub.Name)
+			}
+		}
+	}
+	return RunModeAll
+}
+
+// SkipsCache reports whether args run an ingest-only role, which must not
+// open the query cache.
+func SkipsCache(args []string) bool {
+	mode := RunModeFromArgs(args)
+	return mode != "" && !mode.Subsystems().Cache
+}
@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/services"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+var ErrCreatePatternService = errors.New("failed to create pattern service")
+
+var (
+	patternKindFlag = &cli.StringFlag{
+		Name:    "kind",
+		Aliases: []string{"k"},
+		Usage:   "Pattern kind: [regex, glob].",
+		Value:   string(entries.PatternKindGlob),
+	}
+	patternSourceFlag = &cli.StringFlag{
+		Name:     "source",
+		Aliases:  []string{"s"},
+		Usage:    "Source (feed name) the patterns belong to.",
+		Required: true,
+	}
+	patternCategoryFlag = &cli.StringFlag{
+		Name:    "category",
+		Aliases: []string{"c"},
+		Usage:   "Category of the patterns.",
+	}
+)
+
+// PatternsCommand manages the regex and glob entries matched by link queries.
+var PatternsCommand = &cli.Command{
+	Name:  "patterns",
+	Usage: "Manage regex and glob entries matched against queried URLs",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "list",
+			Usage: "List active patterns",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "source",
+					Aliases: []string{"s"},
+					Usage:   "Only list the patterns of this source.",
+				},
+				allowlistJSONFlag,
+			},
+			Action: listPatterns,
+		},
+		{
+			Name:  "add",
+			Usage: "Add a pattern",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "pattern",
+					Aliases:  []string{"p"},
+					Usage:    "Regex (RE2) or glob matched against the normalized, lowercase URL.",
+					Required: true,
+				},
+				patternKindFlag,
+				patternSourceFlag,
+				patternCategoryFlag,
+				allowlistJSONFlag,
+			},
+			Action: addPattern,
+		},
+		{
+			Name:  "import",
+			Usage: "Import one pattern per line from a file",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "file",
+					Aliases:  []string{"f"},
+					Usage:    "File of patterns; blank lines and # comments are skipped.",
+					Required: true,
+				},
+				patternKindFlag,
+				patternSourceFlag,
+				patternCategoryFlag,
+				&cli.BoolFlag{
+					Name:  "replace",
+					Usage: "Remove the source's patterns that are not in the file.",
+				},
+				allowlistJSONFlag,
+			},
+			Action: importPatterns,
+		},
+		{
+			Name:  "remove",
+			Usage: "Remove a pattern",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "id",
+					Usage:    "ID of the pattern to remove.",
+					Required: true,
+				},
+			},
+			Action: removePattern,
+		},
+		{
+			Name:  "check",
+			Usage: "Show the patterns matching a URL",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "url",
+					Aliases:  []string{"u"},
+					Usage:    "URL to check.",
+					Required: true,
+				},
+				allowlistJSONFlag,
+			},
+			Action: checkPatterns,
+		},
+	},
+}
+
+func newPatternService() (*services.PatternService, error) {
+	svc, err := services.NewPatternService()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create pattern service")
+		return nil, ErrCreatePatternService
+	}
+	return svc, nil
+}
+
+func listPatterns(c *cli.Context) error {
+	svc, err := newPatternService()
+	if err != nil {
+		return err
+	}
+
+	patterns, err := svc.List(c.Context, c.String("source"))
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(patterns)
+	}
+
+	for i := range patterns {
+		printPattern(&patterns[i])
+	}
+	fmt.Printf("\nPatterns: %d\n", len(patterns))
+	return nil
+}
+
+func addPattern(c *cli.Context) error {
+	svc, err := newPatternService()
+	if err != nil {
+		return err
+	}
+
+	p, err := svc.Add(c.Context, entries.PatternKind(c.String("kind")), c.String("pattern"), c.String("source"), c.String("category"))
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(p)
+	}
+
+	printPattern(p)
+	return nil
+}
+
+func importPatterns(c *cli.Context) error {
+	svc, err := newPatternService()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(c.String("file"))
+	if err != nil {
+		log.Error().Err(err).Str("file", c.String("file")).Msg("Failed to open pattern file")
+		return err
+	}
+	defer f.Close()
+
+	report, err := svc.Import(c.Context, f, services.PatternImportOptions{
+		Source:   c.String("source"),
+		Kind:     entries.PatternKind(c.String("kind")),
+		Category: c.String("category"),
+		Replace:  c.Bool("replace"),
+	})
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(report)
+	}
+
+	fmt.Printf("Source:    %s\n", report.Source)
+	fmt.Printf("Process:   %s\n", report.ProcessID)
+	fmt.Printf("Imported:  %d\n", report.Imported)
+	fmt.Printf("Rejected:  %d\n", report.Rejected)
+	fmt.Printf("Removed:   %d\n", report.Removed)
+	fmt.Printf("Duration:  %s\n", report.Duration)
+	return nil
+}
+
+func removePattern(c *cli.Context) error {
+	svc, err := newPatternService()
+	if err != nil {
+		return err
+	}
+
+	if err := svc.Remove(c.Context, c.String("id")); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s\n", c.String("id"))
+	return nil
+}
+
+func checkPatterns(c *cli.Context) error {
+	svc, err := newPatternService()
+	if err != nil {
+		return err
+	}
+
+	matched, err := svc.Match(c.Context, c.String("url"))
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(map[string]any{"url": c.String("url"), "patterns": matched})
+	}
+
+	if len(matched) == 0 {
+		fmt.Println("No pattern matches")
+		return nil
+	}
+	for _, p := range matched {
+		printPattern(p)
+	}
+	return nil
+}
+
+func printPattern(p *entries.Pattern) {
+	fmt.Printf("%s  %-5s  %s  [%s]", p.ID, p.Kind, p.Pattern, p.Source)
+	if p.Category != "" {
+		fmt.Printf("  (%s)", p.Category)
+	}
+	fmt.Println()
+}
@@ -12,7 +12,7 @@ var ProcessCommand = &cli.Command{
 		&cli.StringSliceFlag{
 			Name:    "provider",
 			Aliases: []string{"p"},
-			Usage:   "Specify providers to process (comma-separated). If omitted, process all providers.",
+			Usage:   "Specify providers or provider groups to process (comma-separated). If omitted, process all providers.",
 		},
 		&cli.StringSliceFlag{
 			Name:    "remove-provider",
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"blacked/features/providers"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+var ErrProviderAuthCheckFailed = errors.New("provider credential check failed")
+
+var providersCheckAuthCommand = &cli.Command{
+	Name:  "check-auth",
+	Usage: "Check that the credentials of the providers are accepted, with one lightweight request each",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    "provider",
+			Aliases: []string{"p"},
+			Usage:   "Providers to check (comma-separated). If omitted, check every provider sending credentials.",
+		},
+		&cli.BoolFlag{
+			Name:    "json",
+			Aliases: []string{"j"},
+			Usage:   "Output in JSON format.",
+		},
+	},
+	Action: checkProvidersAuth,
+}
+
+// ProvidersCommand groups operations on the configured providers.
+var ProvidersCommand = &cli.Command{
+	Name:        "providers",
+	Usage:       "Provider operations",
+	Subcommands: []*cli.Command{providersCheckAuthCommand},
+}
+
+// checkProvidersAuth prints the credential status of the providers and fails
+// when any was rejected or could not be checked, for cron jobs and CI.
+func checkProvidersAuth(c *cli.Context) error {
+	statuses, err := providers.GetProviders().CheckAuth(c.Context, c.StringSlice("provider"))
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("json") {
+		if err := printJSON(statuses); err != nil {
+			return err
+		}
+	} else if len(statuses) == 0 {
+		fmt.Println("No provider sends credentials.")
+	} else {
+		fmt.Printf("%-32s %-9s %6s %-20s %10s  %s\n", "PROVIDER", "STATUS", "HTTP", "EXPIRES", "DURATION", "ERROR")
+		for _, s := range statuses {
+			expires := "-"
+			if s.ExpiresAt != nil {
+				expires = s.ExpiresAt.Format(time.RFC3339)
+			}
+			code := "-"
+			if s.StatusCode != 0 {
+				code = fmt.Sprint(s.StatusCode)
+			}
+			fmt.Printf("%-32s %-9s %6s %-20s %10s  %s\n", s.Provider, s.Status, code, expires, s.Duration.Round(time.Millisecond), s.Error)
+		}
+	}
+
+	if providers.AuthFailed(statuses) {
+		return ErrProviderAuthCheckFailed
+	}
+	return nil
+}
@@ -36,9 +36,17 @@ var QueryCommand = &cli.Command{
 		&cli.StringFlag{
 			Name:    "type",
 			Aliases: []string{"t"},
-			Usage:   "Type of URL query: [full, host, domain, path, mixed].",
+			Usage:   "Type of URL query: [full, host, domain, path, mixed, subdomain, wildcard].",
 			Value:   "mixed",
 		},
+		&cli.StringSliceFlag{
+			Name:  "source",
+			Usage: "Only report entries of this source (provider name); repeatable.",
+		},
+		&cli.StringSliceFlag{
+			Name:  "category",
+			Usage: "Only report entries with this category; repeatable.",
+		},
 		&cli.BoolFlag{
 			Name:    "json",
 			Aliases: []string{"j"},
@@ -68,13 +76,17 @@ func queryBlacklist(c *cli.Context) error {
 		return err
 	}
 
-	hits, err := queryService.Query(context.Background(), urlToQuery, queryType)
+	result, err := queryService.QueryScored(context.Background(), urlToQuery, queryType, services.QueryOptions{
+		Sources:    c.StringSlice("source"),
+		Categories: c.StringSlice("category"),
+	})
 	if err != nil {
 		log.Err(err).Str("url", urlToQuery).Str("query_type", queryType.String()).Msg("Failed to query blacklist entries")
 		return ErrQueryBlacklist
 	}
 
-	queryResponse := entries.NewQueryResponse(urlToQuery, hits, *queryType, c.Bool("verbose"))
+	queryResponse := entries.NewQueryResponse(urlToQuery, result.Hits, *queryType, c.Bool("verbose"))
+	queryResponse.Score, queryResponse.Level, queryResponse.Sources = result.Score, result.Level, result.Sources
 
 	return printQueryResponse(queryResponse, c.Bool("json"))
 }
@@ -114,6 +126,8 @@ func printQueryResponse(response *entries.QueryResponse, asJSON bool) error {
 		Int("Total Hits", response.Count).
 		Str("Query Type", response.QueryType.String()).
 		Int("Shown Hits", len(response.Hits)).
+		Float64("Score", response.Score).
+		Str("Level", response.Level).
 		Msg("Query response")
 
 	return nil
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"blacked/features/entries/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	ErrCreateReparseService = errors.New("failed to create reparse service")
+	ErrReparseModeConflict  = errors.New("--dry-run and --apply are mutually exclusive")
+)
+
+// ReparseCommand re-runs URL normalization over stored entries and reports or fixes drift.
+var ReparseCommand = &cli.Command{
+	Name:  "reparse",
+	Usage: "Re-parse stored source URLs and report (or rewrite) entries whose domain/host/path fields differ",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Only report differences (default when --apply is not set).",
+		},
+		&cli.BoolFlag{
+			Name:  "apply",
+			Usage: "Rewrite changed rows under a dedicated process id.",
+		},
+		&cli.StringFlag{
+			Name:    "source",
+			Aliases: []string{"s"},
+			Usage:   "Only re-parse entries of this source (provider name).",
+		},
+		&cli.IntFlag{
+			Name:  "batch-size",
+			Usage: "Number of rows read and rewritten per batch.",
+			Value: 1000,
+		},
+		&cli.IntFlag{
+			Name:  "max-diffs",
+			Usage: "Maximum number of entry diffs to print (0 = all).",
+			Value: 50,
+		},
+		&cli.BoolFlag{
+			Name:    "json",
+			Aliases: []string{"j"},
+			Usage:   "Output the report in JSON format.",
+		},
+	},
+	Action: reparseEntries,
+}
+
+func reparseEntries(c *cli.Context) error {
+	if c.Bool("dry-run") && c.Bool("apply") {
+		return ErrReparseModeConflict
+	}
+
+	svc, err := services.NewReparseService()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create reparse service")
+		return ErrCreateReparseService
+	}
+
+	report, err := svc.Run(c.Context, services.ReparseOptions{
+		Source:    c.String("source"),
+		BatchSize: c.Int("batch-size"),
+		Apply:     c.Bool("apply"),
+		MaxDiffs:  c.Int("max-diffs"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return printReparseReport(report, c.Bool("json"))
+}
+
+func printReparseReport(report *services.ReparseReport, asJSON bool) error {
+	if asJSON {
+		jsonData, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal JSON")
+			return ErrMarshalJSON
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	for _, d := range report.Diffs {
+		fmt.Printf("%s [%s] %s\n", d.ID, d.Source, d.SourceURL)
+		for _, f := range d.Fields {
+			fmt.Printf("    %-12s %q -> %q\n", f.Field, f.Stored, f.Parsed)
+		}
+	}
+
+	mode := "dry-run"
+	if report.Applied {
+		mode = "applied (process " + report.ProcessID + ")"
+	}
+	fmt.Printf("\nMode:      %s\n", mode)
+	fmt.Printf("Scanned:   %d\n", report.Scanned)
+	fmt.Printf("Changed:   %d %v\n", report.Changed, report.ByField)
+	fmt.Printf("Failed:    %d\n", report.Failed)
+	fmt.Printf("Rewritten: %d\n", report.Rewritten)
+	fmt.Printf("Duration:  %s\n", report.Duration)
+
+	return nil
+}
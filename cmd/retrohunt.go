@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"blacked/features/retrohunt"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	ErrCreateRetrohuntService = errors.New("failed to create retrohunt service")
+	ErrOpenTrafficLog         = errors.New("failed to open traffic log")
+)
+
+// RetrohuntCommand checks the URLs of past traffic against the current
+// blacklist.
+var RetrohuntCommand = &cli.Command{
+	Name:  "retrohunt",
+	Usage: "Report past visits to now-listed URLs from a HAR, Zeek or Squid log",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "file",
+			Aliases:  []string{"f"},
+			Usage:    "Traffic log to read: browser HAR, Zeek http.log (TSV or JSON) or Squid access.log.",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "Log format: [har, zeek, squid]. Detected when empty.",
+		},
+		&cli.BoolFlag{
+			Name:    "json",
+			Aliases: []string{"j"},
+			Usage:   "Output the report in JSON format.",
+		},
+	},
+	Action: runRetrohunt,
+}
+
+func runRetrohunt(c *cli.Context) error {
+	svc, err := retrohunt.NewService()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create retrohunt service")
+		return ErrCreateRetrohuntService
+	}
+
+	file, err := os.Open(c.String("file"))
+	if err != nil {
+		log.Error().Err(err).Str("file", c.String("file")).Msg("Failed to open traffic log")
+		return ErrOpenTrafficLog
+	}
+	defer file.Close()
+
+	report, err := svc.HuntLog(c.Context, file, c.String("format"))
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(report)
+	}
+
+	for _, f := range report.Findings {
+		sources := make([]string, 0, len(f.Matches))
+		for _, m := range f.Matches {
+			sources = append(sources, m.Source+"/"+m.Category)
+		}
+		fmt.Printf("%s  %s  %dx  %s  [%s]", f.FirstSeen.Format(time.RFC3339), f.LastSeen.Format(time.RFC3339), f.Visits, f.URL, strings.Join(sources, ", "))
+		if len(f.Clients) > 0 {
+			fmt.Printf("  clients: %s", strings.Join(f.Clients, ", "))
+		}
+		fmt.Println()
+	}
+	fmt.Printf("\nFormat: %s  Visits: %d  URLs: %d  Listed: %d\n", report.Format, report.Visits, report.URLs, report.Matched)
+	return nil
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"blacked/features/entry_collector"
+	"blacked/internal/seed"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+var ErrSeedCacheSync = errors.New("failed to sync the cache after seeding")
+
+// SeedCommand fills the database and cache with synthetic entries for benchmarks.
+var SeedCommand = &cli.Command{
+	Name:  "seed",
+	Usage: "Generate realistic synthetic entries into the database and cache for performance work",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:    "entries",
+			Aliases: []string{"n"},
+			Usage:   "Number of entries to generate across all sources.",
+			Value:   100_000,
+		},
+		&cli.IntFlag{
+			Name:  "sources",
+			Usage: "Number of synthetic sources; the first is the largest.",
+			Value: 5,
+		},
+		&cli.StringFlag{
+			Name:  "prefix",
+			Usage: "Source name prefix; sources are named <prefix>-1..n.",
+			Value: "seed",
+		},
+		&cli.Uint64Flag{
+			Name:  "seed",
+			Usage: "Random seed; the same seed generates the same entries.",
+			Value: 1,
+		},
+		&cli.Float64Flag{
+			Name:  "duplicate-ratio",
+			Usage: "Fraction (0-1) of entries repeating a URL listed by another source.",
+			Value: 0.1,
+		},
+		&cli.Float64Flag{
+			Name:  "path-ratio",
+			Usage: "Fraction (0-1) of URLs with a path beyond \"/\".",
+			Value: 0.6,
+		},
+		&cli.BoolFlag{
+			Name:  "skip-cache",
+			Usage: "Only write the database; leave the cache sync to the next server start.",
+		},
+		&cli.BoolFlag{
+			Name:    "json",
+			Aliases: []string{"j"},
+			Usage:   "Output the report in JSON format.",
+		},
+	},
+	Action: seedEntries,
+}
+
+func seedEntries(c *cli.Context) error {
+	opts := seed.Options{
+		Entries:        c.Int("entries"),
+		Sources:        c.Int("sources"),
+		Prefix:         c.String("prefix"),
+		Seed:           c.Uint64("seed"),
+		DuplicateRatio: c.Float64("duplicate-ratio"),
+		PathRatio:      c.Float64("path-ratio"),
+	}
+
+	log.Info().
+		Int("entries", opts.Entries).
+		Int("sources", opts.Sources).
+		Uint64("seed", opts.Seed).
+		Msg("Seeding synthetic entries")
+
+	pond := entry_collector.GetPondCollector()
+	started := time.Now()
+	var collector seed.Collector
+	if pond != nil {
+		collector = pond
+	}
+	report, err := seed.Run(c.Context, collector, opts)
+	if report == nil {
+		return err
+	}
+
+	if !c.Bool("skip-cache") && report.Saved > 0 {
+		log.Info().Msg("Syncing seeded entries to the cache")
+		scope := entry_collector.CacheSyncScope{Mode: entry_collector.CacheSyncDelta, Since: started.UnixNano()}
+		if !pond.ScheduleCacheSyncScope(c.Context, scope, true) {
+			log.Error().Msg("Failed to schedule cache sync after seeding")
+			return ErrSeedCacheSync
+		}
+	}
+
+	if c.Bool("json") {
+		if jsonErr := printJSON(report); jsonErr != nil {
+			return jsonErr
+		}
+		return err
+	}
+
+	fmt.Printf("%-16s %-10s %12s %12s\n", "Source", "Category", "Generated", "Saved")
+	for _, s := range report.Sources {
+		fmt.Printf("%-16s %-10s %12d %12d\n", s.Name, s.Category, s.Generated, s.Saved)
+	}
+	fmt.Printf("\nGenerated:  %d\n", report.Generated)
+	fmt.Printf("Saved:      %d\n", report.Saved)
+	fmt.Printf("Duplicates: %d (listed by another source)\n", report.Duplicates)
+	fmt.Printf("With path:  %d\n", report.WithPath)
+	fmt.Printf("IP hosts:   %d\n", report.IPHosts)
+	fmt.Printf("Duration:   %s\n", report.Duration)
+	return err
+}
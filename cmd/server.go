@@ -1,64 +1,260 @@
 package cmd
 
 import (
+	"blacked/features/allowlist"
 	"blacked/features/cache"
+	"blacked/features/dnsbl"
+	"blacked/features/entries/services"
 	"blacked/features/entry_collector"
+	"blacked/features/eventbus"
+	"blacked/features/export"
+	"blacked/features/grpcapi"
+	"blacked/features/providers"
+	providerrepo "blacked/features/providers/repository"
+	"blacked/features/replication"
 	"blacked/features/web"
+	v2 "blacked/features/web/handlers/v2"
+	"blacked/internal/collector"
 	"blacked/internal/config"
+	"blacked/internal/db"
+	"blacked/internal/psl"
+	"blacked/internal/query"
 	"blacked/internal/runner"
+	"context"
+	"errors"
+	"os"
+	"time"
 
 	"github.com/ory/graceful"
 	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v2"
+	"google.golang.org/grpc"
 )
 
-// WebServer is the CLI command that starts the web API server.
+var ErrInitialCacheSync = errors.New("failed to build the cache on startup")
+
+var serveAPICommand = &cli.Command{
+	Name:  string(RunModeAPI),
+	Usage: "Serve the HTTP (and gRPC) query API only; no scheduler and no startup ingestion",
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "cache-resync",
+			Usage: "Rebuild the cache from the database this often, to pick up entries written by a separate ingest process. 0 disables.",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		return serveRole(c, RunModeAPI)
+	},
+}
+
+var serveSchedulerCommand = &cli.Command{
+	Name:  string(RunModeScheduler),
+	Usage: "Run startup ingestion and the provider cron scheduler; no API and no query cache",
+	Action: func(c *cli.Context) error {
+		return serveRole(c, RunModeScheduler)
+	},
+}
+
+var serveAllCommand = &cli.Command{
+	Name:  string(RunModeAll),
+	Usage: "Run the API, the scheduler and startup ingestion in one process",
+	Action: func(c *cli.Context) error {
+		return serveRole(c, RunModeAll)
+	},
+}
+
+// WebServer is the CLI command that starts the service in one of its run
+// modes; without a subcommand it runs every role, as `serve all`.
 var WebServer = &cli.Command{
-	Name:    "serve",
-	Aliases: []string{"s"},
-	Usage:   "Start web API server",
-	Action:  serve,
+	Name:        "serve",
+	Aliases:     []string{"s"},
+	Usage:       "Start the service: api, scheduler or all (default)",
+	Subcommands: []*cli.Command{serveAPICommand, serveSchedulerCommand, serveAllCommand},
+	Action: func(c *cli.Context) error {
+		return serveRole(c, RunModeAll)
+	},
 }
 
-func serve(c *cli.Context) (err error) {
-	if err := config.InitConfig(); err != nil {
-		log.Error().Err(err).Msg("Failed to load config")
-		return err
-	}
+// WorkerCommand runs startup ingestion once and exits.
+var WorkerCommand = &cli.Command{
+	Name:  string(RunModeWorker),
+	Usage: "Evaluate every enabled provider once (skip, restore or fetch), then exit",
+	Action: func(c *cli.Context) error {
+		return serveRole(c, RunModeWorker)
+	},
+}
+
+// serveRole starts the subsystems of mode and blocks until the API server
+// stops or, for API-less long-running modes, until the context is cancelled.
+func serveRole(c *cli.Context, mode RunMode) (err error) {
 	cfg := config.GetConfig()
+	subs := mode.Subsystems()
+	providerList := *providers.GetProviders()
 
-	app, err := web.NewApplication(&cfg.Server)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to create web application")
-		return err
+	log.Info().Str("mode", string(mode)).Msg("Starting")
+
+	var app *web.Application
+	if subs.API {
+		if app, err = web.NewApplication(&cfg.Server); err != nil {
+			log.Error().Err(err).Msg("Failed to create web application")
+			return err
+		}
+	} else {
+		// The web application registers these itself; ingest-only modes still record provider metrics.
+		collector.NewMetricsCollector(providerList.GetNames())
 	}
 
-	log.Trace().Msg("Initializing Cache Provider")
-	if err = cache.InitializeCache(c.Context); err != nil {
-		log.Error().Err(err).Msg("Failed to initialize Cache Provider")
-		return err
+	// Feeds split hosts with the list, so the stored copy is loaded before any run.
+	var pslUpdater *psl.Updater
+	if cfg.PSL.URL != "" {
+		pslUpdater = psl.NewUpdater(cfg.PSL)
+		if err := pslUpdater.Load(); err != nil {
+			log.Warn().Err(err).Str("path", cfg.PSL.Path).Msg("Failed to load the stored public suffix list, using the built-in one")
+		}
 	}
 
-	entryCollector := entry_collector.GetPondCollector()
-	if ok := entryCollector.ScheduleCacheSync(true); !ok {
-		log.Error().Msg("Failed to schedule cache sync")
-		return err
+	pond := entry_collector.GetPondCollector()
+	if subs.Cache {
+		if err := initCache(c.Context, pond, cfg.Cache); err != nil {
+			return err
+		}
+		log.Debug().Msg("Cache initialized")
+
+		pond.StartCacheScrubber(c.Context, cfg.Cache.ScrubInterval, cfg.Cache.ScrubSample)
 	}
-	log.Debug().Msg("Badger cache initialized")
 
-	server := graceful.WithDefaults(app.Echo.Server)
-	log.Info().Msgf("Starting server on %s", server.Addr)
+	var bus *eventbus.Bus
+	if eventbus.Enabled(cfg.EventBus) {
+		if bus, err = eventbus.Init(cfg.EventBus); err != nil {
+			log.Error().Err(err).Msg("Failed to start the event bus publisher")
+			return err
+		}
+		if mode != RunModeWorker {
+			go bus.Run(c.Context)
+		}
+	}
+
+	if subs.Scheduler {
+		if _, err := runner.InitializeRunner(providerList); err != nil {
+			log.Fatal().Err(err).Msg("Failed to initialize runner")
+		}
+	}
+	if subs.Scheduler || subs.Startup {
+		// Also waits for the notifications of the runs, workers' included.
+		defer runner.ShutdownRunner(c.Context)
+	}
+
+	if subs.Startup {
+		// Run startup decision engine — determines whether to skip, restore, or fetch each provider
+		if err := runner.RunStartupProviders(c.Context, providerList); err != nil {
+			log.Error().Err(err).Msg("Startup provider evaluation failed, continuing")
+		}
+	}
+
+	if subs.Scheduler && cfg.Replication.PrimaryURL == "" && cfg.Collector.ExpiryInterval > 0 {
+		// Replicas apply the primary's deletions instead of purging their own copy.
+		expiry, err := services.NewExpiryService()
+		if err != nil {
+			return err
+		}
+		expiry.SetCacheSyncer(pond)
+		if mode == RunModeWorker {
+			expiry.Purge(c.Context)
+		} else {
+			go expiry.Run(c.Context, cfg.Collector.ExpiryInterval)
+		}
+	}
+
+	if subs.Scheduler && cfg.Replication.PrimaryURL == "" && cfg.Retention.Scheduled() && mode != RunModeWorker {
+		retention, err := services.NewRetentionService(cfg.Retention)
+		if err != nil {
+			return err
+		}
+		writeDB, err := db.GetWriteDB()
+		if err != nil {
+			return err
+		}
+		retention.SetProcessEvents(providerrepo.NewSQLiteProviderProcessRepository(writeDB))
+		go retention.Run(c.Context, cfg.Retention)
+	}
+
+	if cfg.Database.CheckpointInterval > 0 && mode != RunModeWorker {
+		writeDB, err := db.GetWriteDB()
+		if err != nil {
+			return err
+		}
+		go db.RunCheckpoints(c.Context, writeDB, cfg.Database.CheckpointInterval)
+	}
+
+	if pslUpdater != nil && mode != RunModeWorker {
+		go pslUpdater.Run(c.Context)
+	}
+
+	if mode == RunModeWorker {
+		// Pending batches are flushed when the collector is closed on exit.
+		// Finished runs have written theirs, so their changes are published.
+		if bus != nil {
+			if err := bus.Drain(c.Context); err != nil {
+				log.Warn().Err(err).Msg("Failed to publish the worker's entry changes")
+			}
+		}
+		log.Info().Msg("Worker finished.")
+		return nil
+	}
+
+	if !subs.API {
+		<-c.Context.Done()
+		log.Info().Str("mode", string(mode)).Msg("Stopped gracefully.")
+		return nil
+	}
+
+	go app.Services().HealthMonitor.Run(c.Context)
+
+	if every := c.Duration("cache-resync"); every > 0 {
+		go resyncCache(c, pond, every)
+	}
+
+	if cfg.Replication.PrimaryURL != "" {
+		if err := startReplica(c, cfg, app, pond, subs); err != nil {
+			return err
+		}
+	}
 
-	if _, err := runner.InitializeRunner(*app.GetProviders()); err != nil {
-		log.Fatal().Err(err).Msg("Failed to initialize runner")
+	if cfg.Server.GRPCPort > 0 {
+		queryService, err := services.NewQueryService()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to create query service for gRPC")
+			return err
+		}
+		var opts []grpc.ServerOption
+		if cfg.Auth.Enabled {
+			opts = grpcapi.AuthOptions(app.Services().APIKeyService)
+		}
+		stopGRPC, err := grpcapi.Listen(grpcapi.NewGRPCServer(grpcapi.NewServer(queryService, cfg.Server.MaxBulkURLs), opts...), cfg.Server.GRPCPort)
+		if err != nil {
+			return err
+		}
+		defer stopGRPC()
 	}
 
-	// Run startup decision engine — determines whether to skip, restore, or fetch each provider
-	if err := runner.RunStartupProviders(c.Context, *app.GetProviders()); err != nil {
-		log.Error().Err(err).Msg("Startup provider evaluation failed, continuing with server startup")
+	if cfg.DNSBL.Port > 0 {
+		stopDNSBL, err := listenDNSBL(cfg, pond)
+		if err != nil {
+			return err
+		}
+		defer stopDNSBL()
 	}
 
-	defer runner.ShutdownRunner(c.Context)
+	if cfg.RPZ.AXFRPort > 0 {
+		stopAXFR, err := listenAXFR(cfg)
+		if err != nil {
+			return err
+		}
+		defer stopAXFR()
+	}
+
+	server := graceful.WithDefaults(app.Echo.Server)
+	log.Info().Msgf("Starting server on %s", server.Addr)
 
 	if err = graceful.Graceful(server.ListenAndServe, server.Shutdown); err != nil {
 		log.Error().Err(err).Msg("Failed to start server")
@@ -68,3 +264,223 @@ func serve(c *cli.Context) (err error) {
 	log.Info().Msg("Server stopped gracefully.")
 	return nil
 }
+
+// listenDNSBL serves the DNSBL zone from the same query core, allowlist and
+// policy as /api/v1/hit.
+func listenDNSBL(cfg *config.Config, pond *entry_collector.PondCollector) (stop func(), err error) {
+	database, err := db.GetDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection for DNSBL")
+		return nil, err
+	}
+	policy, err := query.NewPolicy(cfg.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := query.NewQueryService(v2.NewBloomAdapter(pond.GetBloomManager()), db.NewEntryRepository(database), query.NewScorer(config.LoadScoringConfig())).
+		SetAllowlist(allowlist.NewServiceWithRepository(allowlist.NewSQLiteRepository(database))).
+		SetPolicy(policy, providers.GetProviders().Categories()).
+		SetNegativeCache(query.SharedNegativeCache())
+
+	srv, err := dnsbl.NewServer(svc, cfg.DNSBL)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid DNSBL configuration")
+		return nil, err
+	}
+	return dnsbl.Listen(srv, cfg.DNSBL.Port)
+}
+
+// listenAXFR serves the RPZ of /export/rpz to secondary resolvers by zone transfer.
+func listenAXFR(cfg *config.Config) (stop func(), err error) {
+	svc, err := export.NewService()
+	if err != nil {
+		return nil, err
+	}
+	allow, err := allowlist.NewReadService()
+	if err != nil {
+		return nil, err
+	}
+	svc.SetAllowlist(allow)
+
+	rpz, err := export.NewRPZ(cfg.RPZ)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid RPZ configuration")
+		return nil, err
+	}
+	srv, err := export.NewAXFRServer(svc, rpz, cfg.RPZ.AXFRAllow)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid RPZ AXFR configuration")
+		return nil, err
+	}
+	return export.ListenAXFR(srv, cfg.RPZ.AXFRPort)
+}
+
+// startReplica polls the primary's change feed in the background, applying
+// it to the local database, cache and bloom.
+func startReplica(c *cli.Context, cfg *config.Config, app *web.Application, pond *entry_collector.PondCollector, subs Subsystems) error {
+	replica, err := replication.NewReplica(cfg.Replication, pond)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid replication configuration")
+		return err
+	}
+	if subs.Scheduler {
+		log.Warn().Msg("Replica also runs the provider scheduler; run replicas with `serve api` so only the primary ingests")
+	}
+
+	app.Services().ReplicationService.SetReplica(replica)
+	go replica.Run(c.Context)
+	return nil
+}
+
+// initCache builds the cache and bloom filter before queries are served. A
+// cache kept on disk, or a bloom filter saved by an earlier run, answers at
+// once instead, while the entries changed since are synced in the background.
+// With [Cache] warmup_gate the server starts at once and every startup sync
+// runs in the background, with /healthz/ready failing until they complete.
+func initCache(ctx context.Context, pond *entry_collector.PondCollector, cfg config.CacheSettings) error {
+	scope := entry_collector.CacheSyncScope{Mode: entry_collector.CacheSyncFull}
+	var resumed cache.PersistentCache
+
+	if pc, ok := cache.GetPersistentCache(); ok && cfg.TTL == nil {
+		if syncedAt, ok := resumeCache(ctx, pc); ok {
+			scope = entry_collector.CacheSyncScope{Mode: entry_collector.CacheSyncDelta, Since: syncedAt.UnixNano()}
+			resumed = pc
+		}
+	} else if cfg.Filter() != config.FilterNone && cfg.BloomPath != "" {
+		builtAt, err := cache.LoadBloomFilter(cfg.BloomPath)
+		if err == nil {
+			scope = entry_collector.CacheSyncScope{Mode: entry_collector.CacheSyncDelta, Since: builtAt.UnixNano()}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			log.Warn().Err(err).Str("path", cfg.BloomPath).Msg("Failed to load the saved bloom filter, rebuilding it")
+		}
+	}
+
+	if cfg.WarmupGate {
+		pond.BeginWarmup()
+		go warmUpCache(ctx, pond, scope, resumed, cfg)
+		return nil
+	}
+
+	if scope.Mode == entry_collector.CacheSyncFull {
+		if ok := pond.ScheduleCacheSync(true); !ok {
+			log.Error().Msg("Failed to schedule cache sync")
+			return ErrInitialCacheSync
+		}
+		return nil
+	}
+
+	if !pond.ScheduleChangedCacheSync(scope.Since) {
+		log.Warn().Msg("Failed to schedule cache sync of the entries changed since the cache was saved")
+	}
+	if resumed != nil {
+		go func() {
+			if validateResumedCache(ctx, pond, resumed, cfg) && !pond.ScheduleCacheSync(false) {
+				log.Warn().Msg("Failed to schedule the rebuild of the cache kept on disk")
+			}
+		}()
+	}
+	return nil
+}
+
+// warmUpCache runs the startup syncs of a gated warm-up, retrying failed
+// ones, and marks the instance ready once they completed.
+func warmUpCache(ctx context.Context, pond *entry_collector.PondCollector, scope entry_collector.CacheSyncScope, resumed cache.PersistentCache, cfg config.CacheSettings) {
+	if err := pond.WarmCacheSync(ctx, scope); err != nil {
+		return
+	}
+	if resumed != nil && validateResumedCache(ctx, pond, resumed, cfg) {
+		if err := pond.WarmCacheSync(ctx, entry_collector.CacheSyncScope{Mode: entry_collector.CacheSyncFull}); err != nil {
+			return
+		}
+	}
+	pond.EndWarmup()
+}
+
+// resumeCache rebuilds the bloom filter from a cache kept on disk and
+// returns its sync mark, after which only the changed entries are missing.
+// A cache never fully synced, or one that fails to resume, is reset for a
+// full sync.
+func resumeCache(ctx context.Context, pc cache.PersistentCache) (time.Time, bool) {
+	syncedAt, err := pc.SyncedAt()
+	if err == nil && !syncedAt.IsZero() {
+		var provider cache.EntryCache
+		if provider, err = cache.GetCacheProvider(); err == nil {
+			err = cache.BuildBloomFilterFromCache(ctx, provider)
+		}
+		if err == nil {
+			log.Info().Time("synced_at", syncedAt).Msg("Resuming the cache kept on disk, syncing the entries changed since")
+			return syncedAt, true
+		}
+	}
+
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to resume the cache kept on disk, rebuilding it")
+	} else {
+		log.Info().Msg("Cache kept on disk was never fully synced, rebuilding it")
+	}
+	if err := pc.Reset(); err != nil {
+		log.Warn().Err(err).Msg("Failed to reset the cache kept on disk")
+	}
+	return time.Time{}, false
+}
+
+// validateResumedCache compares sampled keys of a resumed cache with the
+// database once the syncs queued at startup are done. When more of them
+// drifted than [Cache] badger_warm_max_drift allows, it empties the cache and
+// returns true: the cache must be rebuilt with a full sync.
+func validateResumedCache(ctx context.Context, pond *entry_collector.PondCollector, pc cache.PersistentCache, cfg config.CacheSettings) bool {
+	if cfg.ScrubSample <= 0 {
+		return false
+	}
+
+	var report entry_collector.ScrubReport
+	for {
+		pond.WaitForCacheSyncCompletion()
+		var err error
+		report, err = pond.ScrubCache(ctx, cfg.ScrubSample)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, entry_collector.ErrCacheScrubSkipped) || ctx.Err() != nil {
+			log.Warn().Err(err).Msg("Failed to validate the cache kept on disk")
+			return false
+		}
+	}
+	if report.Checked == 0 {
+		return false
+	}
+
+	drift := float64(report.Drift()) / float64(report.Checked)
+	if drift <= cfg.BadgerWarmMaxDrift {
+		log.Info().Int("checked", report.Checked).Int("drift", report.Drift()).Msg("Cache kept on disk validated against the database")
+		return false
+	}
+
+	log.Warn().
+		Int("checked", report.Checked).
+		Int("drift", report.Drift()).
+		Float64("max_drift", cfg.BadgerWarmMaxDrift).
+		Msg("Cache kept on disk drifted from the database, rebuilding it")
+	if err := pc.Reset(); err != nil {
+		log.Warn().Err(err).Msg("Failed to reset the cache kept on disk")
+	}
+	return true
+}
+
+// resyncCache schedules a full cache sync every interval until the context is done.
+func resyncCache(c *cli.Context, pond *entry_collector.PondCollector, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Context.Done():
+			return
+		case <-ticker.C:
+			if !pond.ScheduleCacheSync(false) {
+				log.Debug().Msg("Periodic cache resync not scheduled - sync queue is full")
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package alerts
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/internal/config"
+	idb "blacked/internal/db"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher(t *testing.T) {
+	m := NewMatcher([]string{" Example.COM ", "*.corp-*.test", "", "[bad"})
+
+	for host, want := range map[string]string{
+		"example.com":            "example.com",
+		"login.example.com.":     "example.com",
+		"vpn.corp-eu.test":       "*.corp-*.test",
+		"notexample.com":         "",
+		"corp-eu.test":           "",
+		"example.com.attack.net": "",
+	} {
+		got, ok := m.Match(host)
+		assert.Equal(t, want != "", ok, host)
+		assert.Equal(t, want, got, host)
+	}
+
+	assert.True(t, NewMatcher([]string{"[bad", " "}).Empty())
+}
+
+func TestNotifyProviderAdditions(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+
+	save := func(source string, links ...string) {
+		var batch []*entries.Entry
+		for _, l := range links {
+			e, err := entries.FromURL(l, source, "p1")
+			require.NoError(t, err)
+			batch = append(batch, e)
+		}
+		require.NoError(t, repo.BatchSaveEntries(ctx, batch))
+	}
+
+	save("feed", "https://old.example.com/") // Listed before the run started
+	time.Sleep(time.Millisecond)
+	since := time.Now()
+	save("feed", "https://login.example.com/a", "https://sso.example.com/b", "https://evil.test/")
+	save("other", "https://www.example.com/")
+
+	var got []Alert
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a Alert
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&a))
+		got = append(got, a)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := config.AlertsConfig{
+		WebhookURL: srv.URL,
+		Protected:  []string{"example.com"},
+		MaxEntries: 1,
+		Timeout:    time.Second,
+	}
+	require.NoError(t, NotifyProviderAdditions(ctx, cfg, repo, "feed", "p1", since))
+
+	require.Len(t, got, 1)
+	assert.Equal(t, AlertProtectedEntriesAdded, got[0].Type)
+	assert.Equal(t, "feed", got[0].Provider)
+	assert.Equal(t, 2, got[0].Count, "only entries added by this provider since the run started")
+	assert.True(t, got[0].Truncated)
+	require.Len(t, got[0].Entries, 1)
+	assert.Equal(t, "example.com", got[0].Entries[0].Pattern)
+
+	// Nothing new matches: no webhook call.
+	require.NoError(t, NotifyProviderAdditions(ctx, cfg, repo, "feed", "p2", time.Now()))
+	assert.Len(t, got, 1)
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	err = NotifyProviderAdditions(ctx, cfg, repo, "feed", "p1", since)
+	assert.ErrorIs(t, err, ErrWebhookStatus)
+}
@@ -0,0 +1,61 @@
+// Package alerts raises webhook alerts when provider feeds add suspicious
+// entries, such as hosts we protect showing up in a blocklist.
+package alerts
+
+import (
+	"path"
+	"strings"
+)
+
+// Matcher matches hosts against protected domain and glob patterns.
+type Matcher struct {
+	domains []string // Match the domain itself and every subdomain
+	globs   []string // path.Match patterns applied to the whole host
+}
+
+// NewMatcher builds a Matcher from patterns. Plain patterns such as
+// "example.com" match the domain and all of its subdomains; patterns
+// containing glob metacharacters (e.g. "*.corp-*.example") match the host.
+func NewMatcher(patterns []string) *Matcher {
+	m := &Matcher{}
+	for _, p := range patterns {
+		p = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(p)), ".")
+		if p == "" {
+			continue
+		}
+		if strings.ContainsAny(p, "*?[") {
+			if _, err := path.Match(p, ""); err != nil {
+				continue
+			}
+			m.globs = append(m.globs, p)
+			continue
+		}
+		m.domains = append(m.domains, p)
+	}
+	return m
+}
+
+// Empty reports whether the matcher has no usable patterns.
+func (m *Matcher) Empty() bool {
+	return len(m.domains) == 0 && len(m.globs) == 0
+}
+
+// Match returns the first pattern matching host.
+func (m *Matcher) Match(host string) (string, bool) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return "", false
+	}
+
+	for _, d := range m.domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return d, true
+		}
+	}
+	for _, g := range m.globs {
+		if ok, _ := path.Match(g, host); ok {
+			return g, true
+		}
+	}
+	return "", false
+}
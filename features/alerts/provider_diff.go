@@ -0,0 +1,111 @@
+package alerts
+
+import (
+	"blacked/features/entries"
+	"blacked/internal/config"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var ErrScanAdditions = errors.New("failed to scan provider additions")
+
+// AlertProtectedEntriesAdded is the type of alerts raised when a provider
+// run adds entries for protected hosts.
+const AlertProtectedEntriesAdded = "protected_entries_added"
+
+// Alert is the webhook payload.
+type Alert struct {
+	Type       string           `json:"type"`
+	Provider   string           `json:"provider"`
+	ProcessID  string           `json:"process_id"`
+	DetectedAt time.Time        `json:"detected_at"`
+	Count      int              `json:"count"`     // All offending entries
+	Truncated  bool             `json:"truncated"` // Entries holds only the first MaxEntries
+	Entries    []OffendingEntry `json:"entries"`
+}
+
+// OffendingEntry is a newly added entry that matched a protected pattern.
+type OffendingEntry struct {
+	ID        string `json:"id"`
+	SourceURL string `json:"source_url"`
+	Host      string `json:"host"`
+	Category  string `json:"category,omitempty"`
+	Pattern   string `json:"pattern"`
+}
+
+// EntryStreamer is the repository method CheckProviderAdditions needs;
+// implemented by the entries SQLite repository.
+type EntryStreamer interface {
+	StreamEntriesActivatedSince(ctx context.Context, source string, since int64, fn func(*entries.Entry) error) error
+}
+
+// CheckProviderAdditions scans the entries provider added or reactivated
+// since the run started and returns an alert for those matching m, or nil
+// when none did. At most maxEntries offending entries are attached.
+func CheckProviderAdditions(ctx context.Context, repo EntryStreamer, m *Matcher, provider, processID string, since time.Time, maxEntries int) (*Alert, error) {
+	alert := &Alert{
+		Type:      AlertProtectedEntriesAdded,
+		Provider:  provider,
+		ProcessID: processID,
+	}
+
+	err := repo.StreamEntriesActivatedSince(ctx, provider, since.UnixNano(), func(e *entries.Entry) error {
+		pattern, ok := m.Match(e.Host)
+		if !ok {
+			return nil
+		}
+		alert.Count++
+		if maxEntries > 0 && len(alert.Entries) >= maxEntries {
+			alert.Truncated = true
+			return nil
+		}
+		alert.Entries = append(alert.Entries, OffendingEntry{
+			ID:        e.ID,
+			SourceURL: e.SourceURL,
+			Host:      e.Host,
+			Category:  e.Category,
+			Pattern:   pattern,
+		})
+		return nil
+	})
+	if err != nil {
+		log.Err(err).Str("provider", provider).Msg("Failed to scan provider additions")
+		return nil, ErrScanAdditions
+	}
+
+	if alert.Count == 0 {
+		return nil, nil
+	}
+	alert.DetectedAt = time.Now().UTC()
+	return alert, nil
+}
+
+// Enabled reports whether protected-entry alerting is configured.
+func Enabled(cfg config.AlertsConfig) bool {
+	return cfg.WebhookURL != "" && len(cfg.Protected) > 0
+}
+
+// NotifyProviderAdditions runs CheckProviderAdditions with the configured
+// patterns and posts the alert, if any, to the configured webhook.
+func NotifyProviderAdditions(ctx context.Context, cfg config.AlertsConfig, repo EntryStreamer, provider, processID string, since time.Time) error {
+	m := NewMatcher(cfg.Protected)
+	if cfg.WebhookURL == "" || m.Empty() {
+		return nil
+	}
+
+	alert, err := CheckProviderAdditions(ctx, repo, m, provider, processID, since, cfg.MaxEntries)
+	if err != nil || alert == nil {
+		return err
+	}
+
+	log.Warn().
+		Str("provider", provider).
+		Str("process_id", processID).
+		Int("count", alert.Count).
+		Msg("Provider added entries matching protected patterns")
+
+	return NewWebhook(cfg.WebhookURL, cfg.Timeout).Send(ctx, alert)
+}
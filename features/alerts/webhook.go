@@ -0,0 +1,63 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrWebhookDelivery = errors.New("failed to deliver alert webhook")
+	ErrWebhookStatus   = errors.New("alert webhook returned a non-2xx status")
+)
+
+// Webhook POSTs alerts as JSON to a fixed URL.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates a Webhook for url with a per-request timeout.
+func NewWebhook(url string, timeout time.Duration) *Webhook {
+	return &Webhook{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Send delivers alert; any non-2xx response is an error.
+func (w *Webhook) Send(ctx context.Context, alert *Alert) error {
+	return w.Post(ctx, alert.Type, alert)
+}
+
+// Post delivers any JSON payload of the given type; any non-2xx response is
+// an error.
+func (w *Webhook) Post(ctx context.Context, typ string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Err(err).Str("type", typ).Msg("Failed to marshal alert")
+		return ErrWebhookDelivery
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		log.Err(err).Msg("Failed to build alert webhook request")
+		return ErrWebhookDelivery
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		log.Err(err).Str("type", typ).Msg("Failed to send alert webhook")
+		return ErrWebhookDelivery
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Error().Int("status", resp.StatusCode).Str("type", typ).Msg("Alert webhook rejected")
+		return ErrWebhookStatus
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// Package allowlist keeps the domains and URLs that must never be reported
+// as hits, even when a provider lists them.
+package allowlist
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidRule  = errors.New("invalid allowlist rule")
+	ErrRuleExists   = errors.New("allowlist rule already exists")
+	ErrRuleNotFound = errors.New("allowlist rule not found")
+)
+
+// Kind selects how a rule's pattern matches queried links.
+type Kind string
+
+const (
+	KindDomain Kind = "domain" // The host and every subdomain
+	KindURL    Kind = "url"    // The host+path and everything below the path
+)
+
+// Rule is one allowlist entry.
+type Rule struct {
+	ID        string    `json:"id"`
+	Kind      Kind      `json:"kind"`
+	Pattern   string    `json:"pattern"` // Normalized: lowercase host, or host+path without scheme, query or trailing slash
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NormalizeRule validates pattern and returns it in stored form. An empty
+// kind is inferred: patterns with a scheme or a path are URL rules.
+func NormalizeRule(kind Kind, pattern string) (Kind, string, error) {
+	pattern = strings.TrimSpace(pattern)
+	host, path, err := splitLink(pattern)
+	if err != nil || host == "" {
+		return "", "", errors.Join(ErrInvalidRule, errors.New("pattern must be a domain or URL"))
+	}
+
+	if kind == "" {
+		kind = KindDomain
+		if path != "" || strings.Contains(pattern, "://") {
+			kind = KindURL
+		}
+	}
+
+	switch kind {
+	case KindDomain:
+		if path != "" {
+			return "", "", errors.Join(ErrInvalidRule, errors.New("domain rules cannot have a path"))
+		}
+		return kind, host, nil
+	case KindURL:
+		return kind, host + path, nil
+	default:
+		return "", "", errors.Join(ErrInvalidRule, errors.New("kind must be one of domain, url"))
+	}
+}
+
+// candidates returns the patterns that would allowlist link: every parent
+// domain of its host for domain rules, and the host plus every parent path
+// for URL rules.
+func candidates(link string) (domains, urls []string, err error) {
+	host, path, err := splitLink(link)
+	if err != nil || host == "" {
+		return nil, nil, ErrInvalidRule
+	}
+
+	for name := host; name != ""; {
+		domains = append(domains, name)
+		_, name, _ = strings.Cut(name, ".")
+	}
+
+	urls = append(urls, host)
+	prefix := host
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		prefix += "/" + segment
+		urls = append(urls, prefix)
+	}
+	return domains, urls, nil
+}
+
+// splitLink returns the lowercase host and the path without trailing slash
+// of link, which may omit the scheme.
+func splitLink(link string) (host, path string, err error) {
+	if !strings.Contains(link, "://") && !strings.HasPrefix(link, "//") {
+		link = "//" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", "", err
+	}
+	host = strings.Trim(strings.ToLower(u.Hostname()), ".")
+	path = strings.TrimRight(u.Path, "/")
+	return host, path, nil
+}
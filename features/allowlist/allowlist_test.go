@@ -0,0 +1,89 @@
+package allowlist
+
+import (
+	idb "blacked/internal/db"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeRule(t *testing.T) {
+	for _, tc := range []struct {
+		kind    Kind
+		pattern string
+		want    Kind
+		norm    string
+	}{
+		{"", " Example.COM. ", KindDomain, "example.com"},
+		{"", "https://Example.com/Login/", KindURL, "example.com/Login"},
+		{"", "example.com/a/b?x=1", KindURL, "example.com/a/b"},
+		{KindURL, "https://example.com", KindURL, "example.com"},
+		{KindDomain, "https://example.com:8443", KindDomain, "example.com"},
+	} {
+		kind, norm, err := NormalizeRule(tc.kind, tc.pattern)
+		require.NoError(t, err, tc.pattern)
+		assert.Equal(t, tc.want, kind, tc.pattern)
+		assert.Equal(t, tc.norm, norm, tc.pattern)
+	}
+
+	for _, bad := range []struct {
+		kind    Kind
+		pattern string
+	}{
+		{"", ""},
+		{KindDomain, "example.com/path"},
+		{"regex", "example.com"},
+	} {
+		_, _, err := NormalizeRule(bad.kind, bad.pattern)
+		assert.ErrorIs(t, err, ErrInvalidRule, bad.pattern)
+	}
+}
+
+func TestServiceMatch(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	svc := NewServiceWithRepository(NewSQLiteRepository(conn))
+
+	domain, err := svc.Add(ctx, "", "example.com", "our domain")
+	require.NoError(t, err)
+	docs, err := svc.Add(ctx, "", "https://cdn.test/docs/", "")
+	require.NoError(t, err)
+
+	_, err = svc.Add(ctx, KindDomain, "EXAMPLE.com", "")
+	assert.ErrorIs(t, err, ErrRuleExists)
+
+	for link, want := range map[string]*Rule{
+		"https://example.com/":          domain,
+		"http://login.example.com:8080": domain,
+		"notexample.com":                nil,
+		"https://cdn.test/docs":         docs,
+		"https://cdn.test/docs/a/b.pdf": docs,
+		"https://cdn.test/docsx":        nil,
+		"https://sub.cdn.test/docs":     nil,
+		"::not a url":                   nil,
+	} {
+		got, err := svc.Match(ctx, link)
+		require.NoError(t, err, link)
+		if want == nil {
+			assert.Nil(t, got, link)
+			continue
+		}
+		require.NotNil(t, got, link)
+		assert.Equal(t, want.ID, got.ID, link)
+	}
+
+	rules, err := svc.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, rules, 2)
+
+	require.NoError(t, svc.Delete(ctx, domain.ID))
+	assert.ErrorIs(t, svc.Delete(ctx, domain.ID), ErrRuleNotFound)
+	_, err = svc.Get(ctx, domain.ID)
+	assert.ErrorIs(t, err, ErrRuleNotFound)
+}
@@ -0,0 +1,165 @@
+package allowlist
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrQueryRules = errors.New("failed to query allowlist rules from SQLite")
+	ErrSaveRule   = errors.New("failed to save allowlist rule in SQLite")
+	ErrDeleteRule = errors.New("failed to delete allowlist rule in SQLite")
+)
+
+// Repository stores allowlist rules.
+type Repository interface {
+	List(ctx context.Context) ([]Rule, error)
+	Get(ctx context.Context, id string) (*Rule, error)
+	Add(ctx context.Context, rule Rule) error // ErrRuleExists when kind+pattern is taken
+	Delete(ctx context.Context, id string) error
+	// Match returns the most specific rule among the domain and URL
+	// candidate patterns, or nil when none is allowlisted.
+	Match(ctx context.Context, domains, urls []string) (*Rule, error)
+}
+
+// SQLiteRepository is the SQLite implementation of Repository.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLiteRepository instance.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+const ruleColumns = "id, kind, pattern, reason, created_at"
+
+func scanRule(row interface{ Scan(...any) error }) (*Rule, error) {
+	var (
+		rule      Rule
+		reason    sql.NullString
+		createdAt int64
+	)
+	if err := row.Scan(&rule.ID, &rule.Kind, &rule.Pattern, &reason, &createdAt); err != nil {
+		return nil, err
+	}
+	rule.Reason = reason.String
+	rule.CreatedAt = time.Unix(0, createdAt).UTC()
+	return &rule, nil
+}
+
+// List returns every rule, oldest first.
+func (r *SQLiteRepository) List(ctx context.Context) ([]Rule, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+ruleColumns+" FROM allowlist ORDER BY created_at, id")
+	if err != nil {
+		log.Err(err).Msg("Failed to query allowlist rules")
+		return nil, ErrQueryRules
+	}
+	defer rows.Close()
+
+	rules := []Rule{}
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			log.Err(err).Msg("Failed to scan allowlist rule")
+			return nil, ErrQueryRules
+		}
+		rules = append(rules, *rule)
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Rows iteration error for allowlist rules")
+		return nil, ErrQueryRules
+	}
+	return rules, nil
+}
+
+// Get returns the rule with id, or ErrRuleNotFound.
+func (r *SQLiteRepository) Get(ctx context.Context, id string) (*Rule, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+ruleColumns+" FROM allowlist WHERE id = ?", id)
+	rule, err := scanRule(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRuleNotFound
+	}
+	if err != nil {
+		log.Err(err).Str("id", id).Msg("Failed to query allowlist rule")
+		return nil, ErrQueryRules
+	}
+	return rule, nil
+}
+
+// Add inserts rule; an existing rule with the same kind and pattern is left untouched.
+func (r *SQLiteRepository) Add(ctx context.Context, rule Rule) error {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO allowlist (id, kind, pattern, reason, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (kind, pattern) DO NOTHING`,
+		rule.ID, rule.Kind, rule.Pattern, rule.Reason, rule.CreatedAt.UnixNano(),
+	)
+	if err != nil {
+		log.Err(err).Str("pattern", rule.Pattern).Msg("Failed to insert allowlist rule")
+		return ErrSaveRule
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrRuleExists
+	}
+	return nil
+}
+
+// Delete removes the rule with id, or returns ErrRuleNotFound.
+func (r *SQLiteRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM allowlist WHERE id = ?", id)
+	if err != nil {
+		log.Err(err).Str("id", id).Msg("Failed to delete allowlist rule")
+		return ErrDeleteRule
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// Match looks the candidates up in one query on the (kind, pattern) index.
+func (r *SQLiteRepository) Match(ctx context.Context, domains, urls []string) (*Rule, error) {
+	if len(domains) == 0 && len(urls) == 0 {
+		return nil, nil
+	}
+
+	args := make([]any, 0, len(domains)+len(urls)+2)
+	args = append(args, KindDomain)
+	for _, d := range domains {
+		args = append(args, d)
+	}
+	args = append(args, KindURL)
+	for _, u := range urls {
+		args = append(args, u)
+	}
+
+	query := "SELECT " + ruleColumns + ` FROM allowlist
+		WHERE (kind = ? AND pattern IN (` + placeholders(len(domains)) + `))
+		   OR (kind = ? AND pattern IN (` + placeholders(len(urls)) + `))
+		ORDER BY length(pattern) DESC
+		LIMIT 1`
+
+	rule, err := scanRule(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Err(err).Strs("domains", domains).Msg("Failed to match allowlist rules")
+		return nil, ErrQueryRules
+	}
+	return rule, nil
+}
+
+// placeholders returns n comma-separated "?"; "NULL" for n = 0 so the IN list stays valid.
+func placeholders(n int) string {
+	if n == 0 {
+		return "NULL"
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
@@ -0,0 +1,104 @@
+package allowlist
+
+import (
+	"blacked/internal/db"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrDatabaseConnection = errors.New("failed to connect to the database")
+
+// Service manages allowlist rules and checks links against them.
+type Service struct {
+	repo Repository
+}
+
+// NewService creates a Service on the write database connection, for
+// managing rules. Query paths that only match links use NewReadService.
+func NewService() (*Service, error) {
+	dbConn, err := db.GetWriteDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+
+	return NewServiceWithRepository(NewSQLiteRepository(dbConn)), nil
+}
+
+// NewReadService creates a Service on the read database pool.
+func NewReadService() (*Service, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+
+	return NewServiceWithRepository(NewSQLiteRepository(dbConn)), nil
+}
+
+// NewServiceWithRepository creates a Service on the given repository.
+func NewServiceWithRepository(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// List returns every rule.
+func (s *Service) List(ctx context.Context) ([]Rule, error) {
+	return s.repo.List(ctx)
+}
+
+// Get returns the rule with id.
+func (s *Service) Get(ctx context.Context, id string) (*Rule, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// Add normalizes and stores a new rule. An empty kind is inferred from pattern.
+func (s *Service) Add(ctx context.Context, kind Kind, pattern, reason string) (*Rule, error) {
+	kind, pattern, err := NormalizeRule(kind, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := Rule{
+		ID:        uuid.New().String(),
+		Kind:      kind,
+		Pattern:   pattern,
+		Reason:    reason,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.repo.Add(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("id", rule.ID).Str("kind", string(kind)).Str("pattern", pattern).Msg("Allowlist rule added")
+	return &rule, nil
+}
+
+// Delete removes the rule with id.
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	log.Info().Str("id", id).Msg("Allowlist rule removed")
+	return nil
+}
+
+// Match returns the most specific rule allowlisting link, or nil.
+func (s *Service) Match(ctx context.Context, link string) (*Rule, error) {
+	domains, urls, err := candidates(link)
+	if err != nil {
+		// Links we can't parse can't be allowlisted either.
+		return nil, nil
+	}
+	return s.repo.Match(ctx, domains, urls)
+}
+
+// Allowed reports whether any rule allowlists link.
+func (s *Service) Allowed(ctx context.Context, link string) (bool, error) {
+	rule, err := s.Match(ctx, link)
+	return rule != nil, err
+}
@@ -0,0 +1,174 @@
+package apikeys
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	idb "blacked/internal/db"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	require.NoError(t, idb.MigrateSchema(conn))
+	return NewServiceWithRepository(NewSQLiteRepository(conn))
+}
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	key, token, err := svc.Create(ctx, " soc-proxy ", "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "soc-proxy", key.Name)
+	assert.Equal(t, RoleReader, key.Role, "keys are readers by default")
+	assert.True(t, strings.HasPrefix(token, key.Prefix))
+	assert.True(t, key.Allows(RoleReader))
+	assert.False(t, key.Allows(RoleImporter))
+	assert.False(t, key.Allows(RoleAdmin))
+	assert.Equal(t, "key:soc-proxy", key.Caller())
+
+	got, err := svc.Authenticate(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, got.ID)
+
+	stored, err := svc.Get(ctx, key.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, stored.LastUsedAt, "authenticating stamps the last use")
+
+	_, err = svc.Authenticate(ctx, token+"x")
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+	_, err = svc.Authenticate(ctx, "")
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	admin, _, err := svc.Create(ctx, "ops", RoleAdmin, 0)
+	require.NoError(t, err)
+	assert.True(t, admin.Allows(RoleReader))
+	assert.True(t, admin.Allows(RoleImporter))
+
+	importer, _, err := svc.Create(ctx, "feeds", "Importer", 0)
+	require.NoError(t, err)
+	assert.Equal(t, RoleImporter, importer.Role)
+	assert.True(t, importer.Allows(RoleReader))
+	assert.False(t, importer.Allows(RoleAdmin))
+
+	keys, err := svc.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, keys, 3)
+
+	// Deleting a key drops it from the cache at once.
+	require.NoError(t, svc.Delete(ctx, key.ID))
+	_, err = svc.Authenticate(ctx, token)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+	assert.ErrorIs(t, svc.Delete(ctx, key.ID), ErrKeyNotFound)
+
+	_, _, err = svc.Create(ctx, "", RoleReader, 0)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, _, err = svc.Create(ctx, "x", "root", 0)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, _, err = svc.Create(ctx, "x", RoleReader, -1)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestAuthenticateCachesLookups(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	other := NewServiceWithRepository(svc.repo).SetCacheTTL(time.Hour)
+
+	key, token, err := svc.Create(ctx, "cached", RoleReader, 0)
+	require.NoError(t, err)
+	_, err = other.Authenticate(ctx, token)
+	require.NoError(t, err)
+
+	// A key deleted elsewhere keeps working until its cache entry expires.
+	require.NoError(t, svc.Delete(ctx, key.ID))
+	_, err = other.Authenticate(ctx, token)
+	assert.NoError(t, err)
+	_, err = NewServiceWithRepository(svc.repo).SetCacheTTL(0).Authenticate(ctx, token)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestAllowRateLimits(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t).SetDefaultRateLimit(1)
+
+	limited, _, err := svc.Create(ctx, "limited", RoleReader, 2)
+	require.NoError(t, err)
+	assert.True(t, svc.Allow(limited))
+	assert.True(t, svc.Allow(limited))
+	assert.False(t, svc.Allow(limited), "a burst of two at 2 req/s")
+
+	defaulted, _, err := svc.Create(ctx, "defaulted", RoleReader, 0)
+	require.NoError(t, err)
+	assert.True(t, svc.Allow(defaulted))
+	assert.False(t, svc.Allow(defaulted), "keys without a limit get the default")
+
+	svc.SetDefaultRateLimit(0)
+	for range 100 {
+		assert.True(t, svc.Allow(defaulted))
+	}
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	ctx := context.Background()
+	secret := "0123456789abcdef0123456789abcdef"
+	svc := newTestService(t).SetJWT(secret, "sso.example.com", "blacked")
+
+	sign := func(method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+		t.Helper()
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		require.NoError(t, err)
+		return token
+	}
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub":  "alice",
+			"role": "importer",
+			"iss":  "sso.example.com",
+			"aud":  "blacked",
+			"exp":  time.Now().Add(time.Hour).Unix(),
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+
+	key, err := svc.Authenticate(ctx, sign(jwt.SigningMethodHS256, []byte(secret), claims(nil)))
+	require.NoError(t, err)
+	assert.Equal(t, "jwt:alice", key.ID)
+	assert.Equal(t, RoleImporter, key.Role)
+	assert.Equal(t, "jwt:alice", key.Caller())
+
+	rejected := map[string]string{
+		"wrong secret":   sign(jwt.SigningMethodHS256, []byte("another-secret-another-secret-xx"), claims(nil)),
+		"expired":        sign(jwt.SigningMethodHS256, []byte(secret), claims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"no expiry":      sign(jwt.SigningMethodHS256, []byte(secret), claims(jwt.MapClaims{"exp": nil})),
+		"wrong issuer":   sign(jwt.SigningMethodHS256, []byte(secret), claims(jwt.MapClaims{"iss": "evil.example.com"})),
+		"wrong audience": sign(jwt.SigningMethodHS256, []byte(secret), claims(jwt.MapClaims{"aud": "other"})),
+		"no role":        sign(jwt.SigningMethodHS256, []byte(secret), claims(jwt.MapClaims{"role": nil})),
+		"unknown role":   sign(jwt.SigningMethodHS256, []byte(secret), claims(jwt.MapClaims{"role": "root"})),
+		"no subject":     sign(jwt.SigningMethodHS256, []byte(secret), claims(jwt.MapClaims{"sub": nil})),
+		"unsigned":       sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims(nil)),
+	}
+	for name, token := range rejected {
+		_, err := svc.Authenticate(ctx, token)
+		assert.ErrorIs(t, err, ErrUnauthenticated, name)
+	}
+
+	// Without a secret JWTs are not accepted at all.
+	_, err = svc.SetJWT("", "", "").Authenticate(ctx, sign(jwt.SigningMethodHS256, []byte(secret), claims(nil)))
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
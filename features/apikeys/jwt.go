@@ -0,0 +1,58 @@
+package apikeys
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// jwtLeeway absorbs clock skew between the issuer and this server.
+const jwtLeeway = 30 * time.Second
+
+// jwtClaims are the claims of a JWT standing in for an API key: the subject
+// names the caller, role is required and rate_limit is optional.
+type jwtClaims struct {
+	Role      string  `json:"role"`
+	RateLimit float64 `json:"rate_limit,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// authenticateJWT returns the key a JWT signed with the shared secret stands
+// for. Tokens must expire, and match the issuer and audience when set.
+func (s *Service) authenticateJWT(token string) (*Key, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+	}
+	if s.jwtIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(s.jwtIssuer))
+	}
+	if s.jwtAudience != "" {
+		opts = append(opts, jwt.WithAudience(s.jwtAudience))
+	}
+
+	claims := &jwtClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) { return s.jwtSecret, nil }, opts...); err != nil {
+		log.Debug().Err(err).Msg("JWT rejected")
+		return nil, ErrUnauthenticated
+	}
+	if claims.Subject == "" || claims.Role == "" {
+		log.Debug().Str("sub", claims.Subject).Msg("JWT rejected: sub and role claims are required")
+		return nil, ErrUnauthenticated
+	}
+	role, err := ParseRole(claims.Role)
+	if err != nil {
+		log.Debug().Str("sub", claims.Subject).Str("role", claims.Role).Msg("JWT rejected: unknown role")
+		return nil, ErrUnauthenticated
+	}
+
+	return &Key{
+		ID:        "jwt:" + claims.Subject,
+		Name:      claims.Subject,
+		Role:      role,
+		RateLimit: max(claims.RateLimit, 0),
+		jwt:       true,
+	}, nil
+}
@@ -0,0 +1,140 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrQueryKeys = errors.New("failed to query API keys from SQLite")
+	ErrSaveKey   = errors.New("failed to save API key in SQLite")
+	ErrDeleteKey = errors.New("failed to delete API key in SQLite")
+)
+
+// Repository stores API keys.
+type Repository interface {
+	List(ctx context.Context) ([]Key, error)
+	Get(ctx context.Context, id string) (*Key, error)
+	// GetByHash returns the key whose token hashes to hash, or ErrKeyNotFound.
+	GetByHash(ctx context.Context, hash string) (*Key, error)
+	Add(ctx context.Context, key Key) error
+	Delete(ctx context.Context, id string) error
+	// Touch stamps at as the last use of the key.
+	Touch(ctx context.Context, id string, at time.Time) error
+}
+
+// SQLiteRepository is the SQLite implementation of Repository.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLiteRepository instance.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+const keyColumns = "id, name, prefix, key_hash, role, rate_limit, created_at, last_used_at"
+
+func scanKey(row interface{ Scan(...any) error }) (*Key, error) {
+	var (
+		key       Key
+		createdAt int64
+		lastUsed  sql.NullInt64
+	)
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.hash, &key.Role, &key.RateLimit, &createdAt, &lastUsed); err != nil {
+		return nil, err
+	}
+	key.CreatedAt = time.Unix(0, createdAt).UTC()
+	if lastUsed.Valid {
+		t := time.Unix(0, lastUsed.Int64).UTC()
+		key.LastUsedAt = &t
+	}
+	return &key, nil
+}
+
+// List returns every key, oldest first.
+func (r *SQLiteRepository) List(ctx context.Context) ([]Key, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+keyColumns+" FROM api_keys ORDER BY created_at, id")
+	if err != nil {
+		log.Err(err).Msg("Failed to query API keys")
+		return nil, ErrQueryKeys
+	}
+	defer rows.Close()
+
+	keys := []Key{}
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			log.Err(err).Msg("Failed to scan API key")
+			return nil, ErrQueryKeys
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Rows iteration error for API keys")
+		return nil, ErrQueryKeys
+	}
+	return keys, nil
+}
+
+// Get returns the key with id, or ErrKeyNotFound.
+func (r *SQLiteRepository) Get(ctx context.Context, id string) (*Key, error) {
+	return r.get(ctx, "id", id)
+}
+
+// GetByHash returns the key with the token hash, or ErrKeyNotFound.
+func (r *SQLiteRepository) GetByHash(ctx context.Context, hash string) (*Key, error) {
+	return r.get(ctx, "key_hash", hash)
+}
+
+func (r *SQLiteRepository) get(ctx context.Context, column, value string) (*Key, error) {
+	key, err := scanKey(r.db.QueryRowContext(ctx, "SELECT "+keyColumns+" FROM api_keys WHERE "+column+" = ?", value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		log.Err(err).Str("by", column).Msg("Failed to query API key")
+		return nil, ErrQueryKeys
+	}
+	return key, nil
+}
+
+// Add inserts key.
+func (r *SQLiteRepository) Add(ctx context.Context, key Key) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO api_keys (id, name, prefix, key_hash, role, rate_limit, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		key.ID, key.Name, key.Prefix, key.hash, key.Role, key.RateLimit, key.CreatedAt.UnixNano(),
+	)
+	if err != nil {
+		log.Err(err).Str("name", key.Name).Msg("Failed to insert API key")
+		return ErrSaveKey
+	}
+	return nil
+}
+
+// Delete removes the key with id, or returns ErrKeyNotFound.
+func (r *SQLiteRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM api_keys WHERE id = ?", id)
+	if err != nil {
+		log.Err(err).Str("id", id).Msg("Failed to delete API key")
+		return ErrDeleteKey
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+// Touch stamps the last use of the key with id.
+func (r *SQLiteRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	if _, err := r.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = ? WHERE id = ?", at.UnixNano(), id); err != nil {
+		log.Err(err).Str("id", id).Msg("Failed to stamp API key use")
+		return ErrSaveKey
+	}
+	return nil
+}
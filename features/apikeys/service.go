@@ -0,0 +1,263 @@
+package apikeys
+
+import (
+	"blacked/internal/config"
+	"blacked/internal/db"
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+var ErrDatabaseConnection = errors.New("failed to connect to the database")
+
+const (
+	// MaxNameLength caps the name of a key.
+	MaxNameLength = 128
+
+	// minJWTSecretLength is the HS256 key size; shorter secrets are warned about.
+	minJWTSecretLength = 32
+
+	// maxCachedKeys bounds the lookup cache; it is emptied when full, so
+	// random tokens cannot grow it.
+	maxCachedKeys = 10000
+)
+
+var (
+	globalService *Service
+	once          sync.Once
+)
+
+// Init creates the global service authenticating requests on db, with the
+// rate limit, cache lifetime and JWT settings of [Auth], and returns it.
+func Init(db *sql.DB) *Service {
+	once.Do(func() {
+		cfg := config.GetConfig().Auth
+		globalService = NewServiceWithRepository(NewSQLiteRepository(db)).
+			SetDefaultRateLimit(cfg.DefaultRateLimit).
+			SetCacheTTL(cfg.CacheTTL).
+			SetJWT(cfg.JWTSecret, cfg.JWTIssuer, cfg.JWTAudience)
+		if cfg.JWTSecret != "" && len(cfg.JWTSecret) < minJWTSecretLength {
+			log.Warn().Int("length", len(cfg.JWTSecret)).Msg("[Auth] jwt_secret is shorter than 32 bytes and easy to brute force")
+		}
+		if cfg.Enabled {
+			log.Info().Float64("default_rate_limit", cfg.DefaultRateLimit).Msg("API key authentication enabled")
+		}
+	})
+	return globalService
+}
+
+// Get returns the global service, or nil before Init.
+func Get() *Service {
+	return globalService
+}
+
+// Service manages API keys and authenticates tokens against them. Looked-up
+// keys are cached for the cache TTL, so a key deleted by another process
+// keeps working until its entry expires.
+type Service struct {
+	repo        Repository
+	defaultRate float64
+	cacheTTL    time.Duration
+
+	jwtSecret   []byte // nil disables JWTs
+	jwtIssuer   string
+	jwtAudience string
+
+	mu       sync.Mutex
+	cache    map[string]cachedKey     // Token hash → lookup
+	limiters map[string]*rate.Limiter // Key ID → limiter
+}
+
+type cachedKey struct {
+	key     *Key // nil for unknown tokens
+	expires time.Time
+}
+
+// NewService creates a Service on the write database connection.
+func NewService() (*Service, error) {
+	dbConn, err := db.GetWriteDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+	return NewServiceWithRepository(NewSQLiteRepository(dbConn)), nil
+}
+
+// NewServiceWithRepository creates a Service on the given repository.
+func NewServiceWithRepository(repo Repository) *Service {
+	return &Service{
+		repo:     repo,
+		cacheTTL: 30 * time.Second,
+		cache:    make(map[string]cachedKey),
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// SetDefaultRateLimit sets the requests per second of keys without their
+// own limit; 0 leaves them unlimited.
+func (s *Service) SetDefaultRateLimit(rps float64) *Service {
+	s.defaultRate = max(rps, 0)
+	return s
+}
+
+// SetCacheTTL sets how long a looked-up key is trusted; 0 reads every request.
+func (s *Service) SetCacheTTL(ttl time.Duration) *Service {
+	s.cacheTTL = max(ttl, 0)
+	return s
+}
+
+// SetJWT accepts JWTs signed with secret (HS256, HS384 or HS512) besides
+// API keys, when secret is set; issuer and audience, when set, must match the
+// iss and aud claims.
+func (s *Service) SetJWT(secret, issuer, audience string) *Service {
+	s.jwtSecret = nil
+	if secret != "" {
+		s.jwtSecret = []byte(secret)
+	}
+	s.jwtIssuer, s.jwtAudience = issuer, audience
+	return s
+}
+
+// List returns every key.
+func (s *Service) List(ctx context.Context) ([]Key, error) {
+	return s.repo.List(ctx)
+}
+
+// Get returns the key with id.
+func (s *Service) Get(ctx context.Context, id string) (*Key, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// Create stores a new key and returns it with its token, which is not
+// stored and cannot be shown again.
+func (s *Service) Create(ctx context.Context, name string, role Role, rateLimit float64) (*Key, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > MaxNameLength {
+		return nil, "", errors.Join(ErrInvalidKey, errors.New("name is required and at most 128 bytes"))
+	}
+	role, err := ParseRole(string(role))
+	if err != nil {
+		return nil, "", err
+	}
+	if rateLimit < 0 || math.IsNaN(rateLimit) || math.IsInf(rateLimit, 0) {
+		return nil, "", errors.Join(ErrInvalidKey, errors.New("rate_limit must be a non-negative number"))
+	}
+
+	token, hash, err := newToken()
+	if err != nil {
+		log.Err(err).Msg("Failed to generate API key")
+		return nil, "", ErrSaveKey
+	}
+	key := Key{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Prefix:    displayPrefix(token),
+		Role:      role,
+		RateLimit: rateLimit,
+		CreatedAt: time.Now().UTC(),
+		hash:      hash,
+	}
+	if err := s.repo.Add(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	log.Info().Str("id", key.ID).Str("name", name).Str("role", string(role)).Msg("API key created")
+	return &key, token, nil
+}
+
+// Delete removes the key with id; it stops authenticating immediately on
+// this service.
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	for hash, c := range s.cache {
+		if c.key != nil && c.key.ID == id {
+			delete(s.cache, hash)
+		}
+	}
+	delete(s.limiters, id)
+	s.mu.Unlock()
+
+	log.Info().Str("id", id).Msg("API key deleted")
+	return nil
+}
+
+// Authenticate returns the key of token, an API key or a JWT, or
+// ErrUnauthenticated. Each read of an API key from the database stamps its
+// last use.
+func (s *Service) Authenticate(ctx context.Context, token string) (*Key, error) {
+	if s.jwtSecret != nil && strings.Count(token, ".") == 2 {
+		return s.authenticateJWT(token)
+	}
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return nil, ErrUnauthenticated
+	}
+	hash := hashToken(token)
+	now := time.Now()
+
+	s.mu.Lock()
+	c, ok := s.cache[hash]
+	s.mu.Unlock()
+	if ok && now.Before(c.expires) {
+		if c.key == nil {
+			return nil, ErrUnauthenticated
+		}
+		return c.key, nil
+	}
+
+	key, err := s.repo.GetByHash(ctx, hash)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return nil, err
+	}
+	if key != nil {
+		if err := s.repo.Touch(ctx, key.ID, now); err != nil {
+			log.Warn().Err(err).Str("id", key.ID).Msg("Failed to stamp API key use")
+		}
+	}
+
+	if s.cacheTTL > 0 {
+		s.mu.Lock()
+		if len(s.cache) >= maxCachedKeys {
+			clear(s.cache)
+		}
+		s.cache[hash] = cachedKey{key: key, expires: now.Add(s.cacheTTL)}
+		s.mu.Unlock()
+	}
+
+	if key == nil {
+		return nil, ErrUnauthenticated
+	}
+	return key, nil
+}
+
+// Allow reports whether key may make another request now, taking one
+// from its rate limit. Keys without a limit are always allowed.
+func (s *Service) Allow(key *Key) bool {
+	rps := key.RateLimit
+	if rps == 0 {
+		rps = s.defaultRate
+	}
+	if rps == 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	limiter, ok := s.limiters[key.ID]
+	if !ok || limiter.Limit() != rate.Limit(rps) {
+		limiter = rate.NewLimiter(rate.Limit(rps), max(1, int(math.Ceil(rps))))
+		s.limiters[key.ID] = limiter
+	}
+	s.mu.Unlock()
+	return limiter.Allow()
+}
@@ -0,0 +1,244 @@
+// Package audit records every URL the query API answers — the verdict, the
+// caller and the latency — so security teams can review what was looked up
+// and when. Records are written off the query path and deleted once older
+// than the configured retention.
+package audit
+
+import (
+	"blacked/internal/config"
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// flushBatch writes queued queries before the flush interval once this many are pending.
+	flushBatch = 1000
+	// pruneInterval is how often records past the retention are deleted.
+	pruneInterval = time.Hour
+)
+
+// Query is one audited lookup of a URL.
+type Query struct {
+	ID        int64     `json:"id"`
+	QueriedAt time.Time `json:"queried_at"`
+	URL       string    `json:"url"`
+	Endpoint  string    `json:"endpoint"` // Route the query came in on, e.g. "GET /api/v2/hit"
+	Hit       bool      `json:"hit"`
+	MatchType string    `json:"match_type,omitempty"`
+	Caller    string    `json:"caller,omitempty"` // Caller header of the request, if sent
+	RemoteIP  string    `json:"remote_ip,omitempty"`
+	LatencyUS int64     `json:"latency_us"` // Time to answer the request; the URLs of a bulk request share it
+}
+
+// Request is the request one or more queried URLs came in on.
+type Request struct {
+	Endpoint string
+	Caller   string
+	RemoteIP string
+	Start    time.Time
+	Latency  time.Duration
+}
+
+// Result is the answer to one queried URL.
+type Result struct {
+	URL       string
+	Hit       bool
+	MatchType string
+}
+
+var (
+	globalRecorder *Recorder
+	once           sync.Once
+)
+
+// Init starts the global recorder on db when [Audit] is enabled and returns
+// it; nil means the audit log is off.
+func Init(db *sql.DB) *Recorder {
+	once.Do(func() {
+		cfg := config.GetConfig().Audit
+		if !cfg.Enabled {
+			log.Debug().Msg("Query audit log disabled")
+			return
+		}
+		globalRecorder = NewRecorder(NewSQLiteRepository(db), cfg)
+		log.Info().
+			Int("buffer_size", cfg.BufferSize).
+			Dur("flush_interval", cfg.FlushInterval).
+			Dur("retention", cfg.Retention).
+			Msg("Query audit log enabled")
+	})
+	return globalRecorder
+}
+
+// Get returns the global recorder, or nil when the audit log is off.
+func Get() *Recorder {
+	return globalRecorder
+}
+
+// Recorder writes audited queries off the query path: Record only enqueues
+// them, and a background loop inserts them in batches and prunes old ones.
+type Recorder struct {
+	repo          Repository
+	queue         chan Query
+	flushInterval time.Duration
+	retention     time.Duration
+	callerHeader  string
+	dropped       atomic.Int64
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewRecorder starts a Recorder with the buffer, flush interval, retention
+// and caller header of cfg.
+func NewRecorder(repo Repository, cfg config.AuditConfig) *Recorder {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+
+	r := &Recorder{
+		repo:          repo,
+		queue:         make(chan Query, cfg.BufferSize),
+		flushInterval: cfg.FlushInterval,
+		retention:     max(cfg.Retention, 0),
+		callerHeader:  cfg.CallerHeader,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// CallerHeader returns the request header naming the caller; empty when
+// callers are only known by address.
+func (r *Recorder) CallerHeader() string {
+	return r.callerHeader
+}
+
+// Record queues one audit record per result of req. It never blocks: when
+// the queue is full the record is dropped, since a gap in the log beats
+// slowing a query.
+func (r *Recorder) Record(req Request, results ...Result) {
+	at := req.Start
+	if at.IsZero() {
+		at = time.Now()
+	}
+	for _, res := range results {
+		q := Query{
+			QueriedAt: at,
+			URL:       res.URL,
+			Endpoint:  req.Endpoint,
+			Hit:       res.Hit,
+			MatchType: res.MatchType,
+			Caller:    req.Caller,
+			RemoteIP:  req.RemoteIP,
+			LatencyUS: req.Latency.Microseconds(),
+		}
+		select {
+		case r.queue <- q:
+		default:
+			r.dropped.Add(1)
+		}
+	}
+}
+
+// Dropped returns the number of records lost to a full queue.
+func (r *Recorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// Queued returns the number of records waiting in the queue and its capacity.
+func (r *Recorder) Queued() (queued, capacity int) {
+	return len(r.queue), cap(r.queue)
+}
+
+// Close stops the recorder after writing every queued record.
+func (r *Recorder) Close() {
+	r.stopOnce.Do(func() { close(r.stop) })
+	<-r.done
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+	pruner := time.NewTicker(pruneInterval)
+	defer pruner.Stop()
+
+	r.prune()
+	var pending []Query
+	for {
+		select {
+		case q := <-r.queue:
+			pending = append(pending, q)
+			if len(pending) >= flushBatch {
+				pending = r.flush(pending)
+			}
+		case <-ticker.C:
+			pending = r.flush(pending)
+		case <-pruner.C:
+			r.prune()
+		case <-r.stop:
+			for {
+				select {
+				case q := <-r.queue:
+					pending = append(pending, q)
+				default:
+					r.flush(pending)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush inserts pending and returns the slice to queue into. Records that
+// fail to save are kept for the next flush, up to one queue's worth.
+func (r *Recorder) flush(pending []Query) []Query {
+	if len(pending) == 0 {
+		return pending
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := r.repo.Insert(ctx, pending); err != nil {
+		log.Warn().Err(err).Int("queries", len(pending)).Msg("Failed to save audited queries, retrying next flush")
+		if over := len(pending) - cap(r.queue); over > 0 {
+			r.dropped.Add(int64(over))
+			pending = pending[over:]
+		}
+		return pending
+	}
+	log.Trace().Int("queries", len(pending)).Msg("Audited queries saved")
+	return pending[:0]
+}
+
+// prune deletes the records older than the retention.
+func (r *Recorder) prune() {
+	if r.retention == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	deleted, err := r.repo.Prune(ctx, time.Now().Add(-r.retention))
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to prune the query audit log")
+		return
+	}
+	if deleted > 0 {
+		log.Debug().Int64("deleted", deleted).Dur("retention", r.retention).Msg("Pruned the query audit log")
+	}
+}
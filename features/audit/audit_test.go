@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"blacked/internal/config"
+	idb "blacked/internal/db"
+	"blacked/internal/pagination"
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorderFlushesOnClose(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := NewSQLiteRepository(conn)
+	recorder := NewRecorder(repo, config.AuditConfig{BufferSize: 100, FlushInterval: time.Hour, Retention: 24 * time.Hour, CallerHeader: "X-Client-ID"})
+	assert.Equal(t, "X-Client-ID", recorder.CallerHeader())
+
+	start := time.Now()
+	recorder.Record(Request{Endpoint: "GET /api/v2/hit", Caller: "soc-proxy", RemoteIP: "10.0.0.1", Start: start, Latency: 1500 * time.Microsecond},
+		Result{URL: "https://login.evil.com/a", Hit: true, MatchType: "host"})
+	recorder.Record(Request{Endpoint: "POST /api/v2/bulk-hit", RemoteIP: "10.0.0.2", Start: start.Add(time.Second), Latency: time.Millisecond},
+		Result{URL: "https://example.com/"},
+		Result{URL: "https://sso.evil.com/b", Hit: true, MatchType: "domain"})
+	recorder.Close()
+	assert.Zero(t, recorder.Dropped())
+
+	svc := NewServiceWithRepository(repo)
+	page, err := svc.List(ctx, Filter{}, pagination.Params{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 3)
+	assert.Equal(t, 3, page.TotalEstimate)
+	assert.Equal(t, "https://sso.evil.com/b", page.Items[0].URL, "newest first")
+	assert.Equal(t, "POST /api/v2/bulk-hit", page.Items[0].Endpoint)
+	assert.EqualValues(t, 1000, page.Items[0].LatencyUS, "a bulk request's URLs share its latency")
+
+	first := page.Items[2]
+	assert.Equal(t, "https://login.evil.com/a", first.URL)
+	assert.True(t, first.Hit)
+	assert.Equal(t, "host", first.MatchType)
+	assert.Equal(t, "soc-proxy", first.Caller)
+	assert.Equal(t, "10.0.0.1", first.RemoteIP)
+	assert.EqualValues(t, 1500, first.LatencyUS)
+	assert.Equal(t, start.UnixNano(), first.QueriedAt.UnixNano())
+}
+
+func TestListFiltersAndPages(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := NewSQLiteRepository(conn)
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	var queries []Query
+	for i := range 5 {
+		queries = append(queries, Query{
+			QueriedAt: base.Add(time.Duration(i) * time.Hour),
+			URL:       "https://host" + string(rune('a'+i)) + ".evil.com/",
+			Endpoint:  "GET /api/v2/hit",
+			Hit:       i%2 == 0,
+			Caller:    "soc-proxy",
+			RemoteIP:  "10.0.0.1",
+		})
+	}
+	queries = append(queries, Query{QueriedAt: base, URL: "https://100%_off.example.com/", Endpoint: "GET /check", RemoteIP: "10.0.0.9"})
+	require.NoError(t, repo.Insert(ctx, queries))
+	svc := NewServiceWithRepository(repo)
+
+	f, err := ParseFilter(url.Values{"hit": {"true"}, "caller": {"soc-proxy"}})
+	require.NoError(t, err)
+	page, err := svc.List(ctx, f, pagination.Params{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, 3, page.TotalEstimate)
+	assert.Equal(t, "https://hoste.evil.com/", page.Items[0].URL)
+	require.NotEmpty(t, page.NextCursor)
+
+	after, err := pagination.DecodeCursor(page.NextCursor)
+	require.NoError(t, err)
+	page, err = svc.List(ctx, f, pagination.Params{Limit: 2, After: after})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "https://hosta.evil.com/", page.Items[0].URL)
+	assert.Empty(t, page.NextCursor)
+
+	f, err = ParseFilter(url.Values{"since": {"2024-06-01T13:00:00Z"}, "until": {"2024-06-01T15:00:00Z"}})
+	require.NoError(t, err)
+	page, err = svc.List(ctx, f, pagination.Params{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, page.TotalEstimate)
+
+	// LIKE wildcards in the url filter match literally; the caller filter also matches addresses.
+	page, err = svc.List(ctx, Filter{URL: "100%_off"}, pagination.Params{})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	page, err = svc.List(ctx, Filter{URL: "%"}, pagination.Params{})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	page, err = svc.List(ctx, Filter{Caller: "10.0.0.9"}, pagination.Params{})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+
+	_, err = svc.List(ctx, Filter{}, pagination.Params{After: "not-an-id"})
+	assert.ErrorIs(t, err, pagination.ErrInvalidCursor)
+
+	_, err = ParseFilter(url.Values{"hit": {"maybe"}})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+	_, err = ParseFilter(url.Values{"since": {"2024-06-02T00:00:00Z"}, "until": {"2024-06-01T00:00:00Z"}})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+}
+
+func TestRecorderPrunesPastRetention(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := NewSQLiteRepository(conn)
+	now := time.Now()
+	require.NoError(t, repo.Insert(ctx, []Query{
+		{QueriedAt: now.Add(-48 * time.Hour), URL: "https://old.example.com/", Endpoint: "GET /check"},
+		{QueriedAt: now.Add(-time.Hour), URL: "https://recent.example.com/", Endpoint: "GET /check"},
+	}))
+
+	// A recorder prunes as it starts.
+	recorder := NewRecorder(repo, config.AuditConfig{BufferSize: 10, FlushInterval: time.Hour, Retention: 24 * time.Hour})
+	recorder.Close()
+
+	list, err := repo.List(ctx, Filter{}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "https://recent.example.com/", list[0].URL)
+}
@@ -0,0 +1,176 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrQueryAudit = errors.New("failed to query the audit log from SQLite")
+	ErrSaveAudit  = errors.New("failed to save the audit log in SQLite")
+)
+
+// Repository stores audited queries.
+type Repository interface {
+	// Insert saves queries in one transaction.
+	Insert(ctx context.Context, queries []Query) error
+	// List returns up to limit queries matching f with an ID below beforeID
+	// (0 for the newest), newest first.
+	List(ctx context.Context, f Filter, beforeID int64, limit int) ([]Query, error)
+	// Count returns the number of queries matching f.
+	Count(ctx context.Context, f Filter) (int, error)
+	// Prune deletes the queries made before cutoff and returns how many.
+	Prune(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// SQLiteRepository is the SQLite implementation of Repository.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLiteRepository instance.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// Insert saves queries in one transaction.
+func (r *SQLiteRepository) Insert(ctx context.Context, queries []Query) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Err(err).Msg("Failed to begin query audit transaction")
+		return ErrSaveAudit
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO query_audit (queried_at, url, endpoint, hit, match_type, caller, remote_ip, latency_us)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		log.Err(err).Msg("Failed to prepare query audit insert")
+		return ErrSaveAudit
+	}
+	defer stmt.Close()
+
+	for _, q := range queries {
+		if _, err := stmt.ExecContext(ctx, q.QueriedAt.UnixNano(), q.URL, q.Endpoint, q.Hit,
+			nullString(q.MatchType), nullString(q.Caller), nullString(q.RemoteIP), q.LatencyUS); err != nil {
+			log.Err(err).Msg("Failed to insert audited query")
+			return ErrSaveAudit
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Msg("Failed to commit audited queries")
+		return ErrSaveAudit
+	}
+	return nil
+}
+
+// List returns up to limit queries matching f before beforeID, newest first.
+func (r *SQLiteRepository) List(ctx context.Context, f Filter, beforeID int64, limit int) ([]Query, error) {
+	where, args := f.where()
+	if beforeID > 0 {
+		where += " AND id < ?"
+		args = append(args, beforeID)
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, queried_at, url, endpoint, hit, match_type, caller, remote_ip, latency_us
+		FROM query_audit
+		WHERE `+where+`
+		ORDER BY id DESC
+		LIMIT ?`, args...)
+	if err != nil {
+		log.Err(err).Msg("Failed to query the audit log")
+		return nil, ErrQueryAudit
+	}
+	defer rows.Close()
+
+	queries := []Query{}
+	for rows.Next() {
+		var (
+			q                           Query
+			queriedAt                   int64
+			matchType, caller, remoteIP sql.NullString
+		)
+		if err := rows.Scan(&q.ID, &queriedAt, &q.URL, &q.Endpoint, &q.Hit, &matchType, &caller, &remoteIP, &q.LatencyUS); err != nil {
+			log.Err(err).Msg("Failed to scan audited query")
+			return nil, ErrQueryAudit
+		}
+		q.QueriedAt = time.Unix(0, queriedAt).UTC()
+		q.MatchType = matchType.String
+		q.Caller = caller.String
+		q.RemoteIP = remoteIP.String
+		queries = append(queries, q)
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Rows iteration error for the audit log")
+		return nil, ErrQueryAudit
+	}
+	return queries, nil
+}
+
+// Count returns the number of queries matching f.
+func (r *SQLiteRepository) Count(ctx context.Context, f Filter) (int, error) {
+	where, args := f.where()
+	var n int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM query_audit WHERE "+where, args...).Scan(&n); err != nil {
+		log.Err(err).Msg("Failed to count audited queries")
+		return 0, ErrQueryAudit
+	}
+	return n, nil
+}
+
+// Prune deletes the queries made before cutoff.
+func (r *SQLiteRepository) Prune(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM query_audit WHERE queried_at < ?", cutoff.UnixNano())
+	if err != nil {
+		log.Err(err).Time("cutoff", cutoff).Msg("Failed to prune the audit log")
+		return 0, ErrSaveAudit
+	}
+	deleted, _ := res.RowsAffected()
+	return deleted, nil
+}
+
+// where renders f as a WHERE clause and its arguments; every value is a placeholder.
+func (f Filter) where() (string, []any) {
+	conds := []string{"1 = 1"}
+	var args []any
+	if f.Since != nil {
+		conds = append(conds, "queried_at >= ?")
+		args = append(args, f.Since.UnixNano())
+	}
+	if f.Until != nil {
+		conds = append(conds, "queried_at < ?")
+		args = append(args, f.Until.UnixNano())
+	}
+	if f.Hit != nil {
+		conds = append(conds, "hit = ?")
+		args = append(args, *f.Hit)
+	}
+	if f.URL != "" {
+		conds = append(conds, `url LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+likeEscaper.Replace(f.URL)+"%")
+	}
+	if f.Caller != "" {
+		conds = append(conds, "(caller = ? OR remote_ip = ?)")
+		args = append(args, f.Caller, f.Caller)
+	}
+	if f.MatchType != "" {
+		conds = append(conds, "match_type = ?")
+		args = append(args, f.MatchType)
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"blacked/internal/db"
+	"blacked/internal/pagination"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+
+	// maxFilterLength caps the length of a single filter value.
+	maxFilterLength = 2048
+)
+
+var (
+	ErrDatabaseConnection = errors.New("failed to connect to the database")
+	ErrInvalidFilter      = errors.New("invalid audit filter")
+	ErrListQueries        = errors.New("failed to list audited queries")
+)
+
+// Filter selects audited queries. Zero fields match everything; set fields are ANDed.
+type Filter struct {
+	Since     *time.Time // Queried at or after
+	Until     *time.Time // Queried before
+	Hit       *bool
+	URL       string // Substring of the queried URL
+	Caller    string // Caller header or remote IP
+	MatchType string
+}
+
+// ParseFilter reads a Filter from the since, until, hit, url, caller and
+// match_type query parameters; times are RFC3339.
+func ParseFilter(values url.Values) (Filter, error) {
+	var f Filter
+	for _, key := range []string{"since", "until"} {
+		raw := strings.TrimSpace(values.Get(key))
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return Filter{}, fmt.Errorf("%w: %s must be an RFC3339 time", ErrInvalidFilter, key)
+		}
+		if key == "since" {
+			f.Since = &t
+		} else {
+			f.Until = &t
+		}
+	}
+	if f.Since != nil && f.Until != nil && !f.Since.Before(*f.Until) {
+		return Filter{}, fmt.Errorf("%w: since must be before until", ErrInvalidFilter)
+	}
+
+	if raw := strings.TrimSpace(values.Get("hit")); raw != "" {
+		hit, err := strconv.ParseBool(raw)
+		if err != nil {
+			return Filter{}, fmt.Errorf("%w: hit must be true or false", ErrInvalidFilter)
+		}
+		f.Hit = &hit
+	}
+
+	for key, dst := range map[string]*string{"url": &f.URL, "caller": &f.Caller, "match_type": &f.MatchType} {
+		v := strings.TrimSpace(values.Get(key))
+		if len(v) > maxFilterLength {
+			return Filter{}, fmt.Errorf("%w: %s is longer than %d bytes", ErrInvalidFilter, key, maxFilterLength)
+		}
+		*dst = v
+	}
+	return f, nil
+}
+
+// Service reads the query audit log.
+type Service struct {
+	repo Repository
+}
+
+// NewService creates a Service on the database connection.
+func NewService() (*Service, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+	return NewServiceWithRepository(NewSQLiteRepository(dbConn)), nil
+}
+
+// NewServiceWithRepository creates a Service on the given repository.
+func NewServiceWithRepository(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// List returns the page of queries matching f after the p.After ID, newest
+// first. The total estimate counts every query matching f.
+func (s *Service) List(ctx context.Context, f Filter, p pagination.Params) (*pagination.Page[Query], error) {
+	if p.Limit <= 0 || p.Limit > MaxListLimit {
+		p.Limit = DefaultListLimit
+	}
+
+	var before int64
+	if p.After != "" {
+		id, err := strconv.ParseInt(p.After, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("%w: pass the next_cursor of the previous page", pagination.ErrInvalidCursor)
+		}
+		before = id
+	}
+
+	// One extra row tells whether another page follows.
+	list, err := s.repo.List(ctx, f, before, p.Limit+1)
+	if err != nil {
+		return nil, ErrListQueries
+	}
+	total, err := s.repo.Count(ctx, f)
+	if err != nil {
+		return nil, ErrListQueries
+	}
+
+	page := pagination.NewPage(list, p.Limit, total, func(q Query) string { return strconv.FormatInt(q.ID, 10) })
+	return &page, nil
+}
@@ -75,6 +75,20 @@ func (bs *BloomSet) TestSource(sourceID, key string) bool {
 	return sf.Test([]byte(key))
 }
 
+// FirstSource returns a source whose filter holds key, without listing the
+// sources first as GetSourceIDs would.
+func (bs *BloomSet) FirstSource(key string) (string, bool) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	for id, sf := range bs.SourceFilters {
+		if sf != nil && sf.Test([]byte(key)) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
 // GetFilterNames returns human friendly string for the bloom set
 func (bs *BloomSet) GetFilterNames() string {
 	return string(bs.Type) + " bloom set"
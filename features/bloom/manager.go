@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"path"
 	"sync"
+	"sync/atomic"
 
 	"github.com/rs/zerolog/log"
 )
@@ -130,91 +131,89 @@ func determineBloomTarget(keys *URLKeys) (BloomType, string) {
 	return "", ""
 }
 
+// TargetFor returns the bloom type and key an entry with these keys is stored under.
+// Exposed so other indexes (e.g. the edge dataset) file entries the same way.
+func TargetFor(keys *URLKeys) (BloomType, string) {
+	return determineBloomTarget(keys)
+}
+
+// likelyCheck is the state of one Likely call, pooled so a check allocates
+// nothing but its result.
+type likelyCheck struct {
+	keys  []CheckKey
+	wg    sync.WaitGroup
+	done  atomic.Bool // Set by the first hit; later checks return early
+	match BloomMatch
+}
+
+var likelyCheckPool = sync.Pool{
+	New: func() any { return &likelyCheck{keys: make([]CheckKey, 0, maxCheckKeys)} },
+}
+
 // Likely checks a URL against all applicable bloom types in parallel.
 // Check order: Domain → Host → HostPath → File → FullURL.
-// First hit wins — other goroutines are cancelled via context.
+// First hit wins — the other goroutines return without testing once it is recorded.
 func (bm *BloomManager) Likely(urlStr string) (*BloomResult, error) {
 	keys, err := ParseURL(urlStr)
 	if err != nil {
 		return nil, fmt.Errorf("parse url: %w", err)
 	}
 
-	checkKeys := keys.GenerateCheckKeys()
-	if len(checkKeys) == 0 {
+	lc := likelyCheckPool.Get().(*likelyCheck)
+	defer func() {
+		clear(lc.keys)
+		lc.keys = lc.keys[:0]
+		lc.done.Store(false)
+		lc.match = BloomMatch{}
+		likelyCheckPool.Put(lc)
+	}()
+
+	lc.keys = keys.AppendCheckKeys(lc.keys)
+	if len(lc.keys) == 0 {
 		return &BloomResult{Likely: false, Matches: nil}, nil
 	}
 
 	bm.mu.RLock()
 	defer bm.mu.RUnlock()
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	resultCh := make(chan BloomMatch, 1)
-	var wg sync.WaitGroup
-
-	for _, ck := range checkKeys {
-		wg.Go(func() {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-			}
-
-			bs, ok := bm.sets[ck.Type]
-			if !ok || bs == nil {
-				return
-			}
-
-			if !bs.Test(ck.Key) {
-				return
-			}
-
-			// Hit — send result and cancel others
-			for _, sid := range bs.GetSourceIDs() {
-				if bs.TestSource(sid, ck.Key) {
-					select {
-					case resultCh <- BloomMatch{
-						Type:     ck.Type,
-						SourceID: sid,
-						Key:      ck.Key,
-					}:
-						cancel()
-					case <-ctx.Done():
-					}
-					return
-				}
-			}
-		})
+	lc.wg.Add(len(lc.keys))
+	for i := range lc.keys {
+		go bm.checkKey(lc, i)
 	}
+	lc.wg.Wait()
 
-	go func() {
-		wg.Wait()
-		close(resultCh)
-	}()
+	if !lc.done.Load() {
+		return &BloomResult{Likely: false, Matches: nil}, nil
+	}
 
-	matches := make([]BloomMatch, 0)
-	for m := range resultCh {
-		matches = append(matches, m)
+	result := &BloomResult{
+		Likely:  true,
+		Matches: []BloomMatch{lc.match},
+	}
+	if w, ok := DepthWeight[lc.match.Type]; ok {
+		result.MaxDepth = int(w * 100)
 	}
+	return result, nil
+}
 
-	if len(matches) > 0 {
-		result := &BloomResult{
-			Likely:  true,
-			Matches: matches,
-		}
-		for _, m := range matches {
-			if w, ok := DepthWeight[m.Type]; ok {
-				scaled := int(w * 100)
-				if scaled > result.MaxDepth {
-					result.MaxDepth = scaled
-				}
-			}
-		}
-		return result, nil
+// checkKey tests the i-th key of lc, recording the first source that holds it
+// unless another key already hit. The caller holds bm.mu.
+func (bm *BloomManager) checkKey(lc *likelyCheck, i int) {
+	defer lc.wg.Done()
+	if lc.done.Load() {
+		return
 	}
 
-	return &BloomResult{Likely: false, Matches: nil}, nil
+	ck := lc.keys[i]
+	bs, ok := bm.sets[ck.Type]
+	if !ok || bs == nil || !bs.Test(ck.Key) {
+		return
+	}
+
+	sid, ok := bs.FirstSource(ck.Key)
+	if ok && lc.done.CompareAndSwap(false, true) {
+		lc.match = BloomMatch{Type: ck.Type, SourceID: sid, Key: ck.Key}
+	}
 }
 
 // RebuildSource rebuilds only the bloom filters for a specific source.
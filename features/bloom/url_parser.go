@@ -49,28 +49,23 @@ func ParseURL(raw string) (*URLKeys, error) {
 	if err != nil {
 		return nil, ErrInvalidURL
 	}
+	utils.DefaultNormalizer().Apply(u)
 
 	host := u.Hostname()
 	if host == "" {
 		return nil, ErrInvalidURL
 	}
 
-	domain, _, err := utils.ExtractDomainAndSubDomains(host)
-	if err != nil {
-		domain = host
-	}
-
-	keys := &URLKeys{}
-
-	if domain != "" {
-		keys.Domain = domain
+	keys := &URLKeys{
+		Domain: utils.ExtractDomain(host),
+		Host:   host,
 	}
-	keys.Host = host
 
 	if u.Path != "" && u.Path != "/" {
-		clean := path.Clean(u.Path)
-		keys.HostPath = host + clean
-		keys.Path = clean
+		// Path is sliced from HostPath so check keys can slice every
+		// parent level from one string.
+		keys.HostPath = host + path.Clean(u.Path)
+		keys.Path = keys.HostPath[len(host):]
 	}
 
 	if u.RawQuery != "" {
@@ -156,60 +151,88 @@ func (uk *URLKeys) KeysFor(types []BloomType) map[BloomType]string {
 // Order: Domain → Host → IP → HostPath (parents) → File → FullURL.
 // Shallowest bloom first; first hit wins in parallel check.
 func (uk *URLKeys) GenerateCheckKeys() []CheckKey {
-	var keys []CheckKey
+	return uk.AppendCheckKeys(nil)
+}
+
+// maxCheckKeys is the usual length of a check chain: domain, host, IP,
+// a few path levels, file and full URL.
+const maxCheckKeys = 12
+
+// AppendCheckKeys appends the check chain of GenerateCheckKeys to dst.
+// HostPath levels are slices of one host+path string, so the only
+// allocations are that string (when HostPath doesn't hold it already),
+// the full URL and growing dst.
+func (uk *URLKeys) AppendCheckKeys(dst []CheckKey) []CheckKey {
+	if dst == nil {
+		dst = make([]CheckKey, 0, maxCheckKeys)
+	}
 
 	// 1. Domain (widest)
 	if uk.Domain != "" {
-		keys = append(keys, CheckKey{BloomDomain, uk.Domain})
+		dst = append(dst, CheckKey{BloomDomain, uk.Domain})
 	}
 
 	// 2. Host
 	if uk.Host != "" {
-		keys = append(keys, CheckKey{BloomHost, uk.Host})
+		dst = append(dst, CheckKey{BloomHost, uk.Host})
 	}
 
 	// 3. IP (exact match — after host, before path)
 	if uk.IP != "" {
-		keys = append(keys, CheckKey{BloomIP, uk.IP})
+		dst = append(dst, CheckKey{BloomIP, uk.IP})
 	}
 
-	// 4. HostPath variants — shallowest → deepest
+	hostPath := ""
 	if uk.Host != "" && uk.Path != "" {
-		for _, p := range parentPaths(uk.Path) {
-			keys = append(keys, CheckKey{BloomHostPath, uk.Host + p})
+		hostPath = uk.HostPath
+		if len(hostPath) != len(uk.Host)+len(uk.Path) || !strings.HasPrefix(hostPath, uk.Host) || !strings.HasSuffix(hostPath, uk.Path) {
+			hostPath = uk.Host + uk.Path
 		}
 	}
 
+	// 4. HostPath variants — shallowest → deepest
+	if hostPath != "" {
+		dst = appendParentPaths(dst, hostPath, len(uk.Host))
+	}
+
 	// 4. File
 	if uk.File != "" {
-		keys = append(keys, CheckKey{BloomFile, uk.File})
+		dst = append(dst, CheckKey{BloomFile, uk.File})
 	}
 
 	// 5. FullURL (most specific)
-	if uk.Host != "" && uk.Path != "" {
-		full := uk.Host + uk.Path
+	if hostPath != "" {
+		full := hostPath
 		if uk.Query != "" {
 			full += "?" + uk.Query
 		}
-		keys = append(keys, CheckKey{BloomFullURL, full})
+		dst = append(dst, CheckKey{BloomFullURL, full})
 	}
 
-	return keys
+	return dst
 }
 
-// parentPaths returns all path prefixes from shallowest → deepest,
-// including the full path itself. Used by GenerateCheckKeys to scan
-// every HostPath level the check URL might fall under.
-// "/a/b/c/file.exe" → ["/a", "/a/b", "/a/b/c", "/a/b/c/file.exe"]
-func parentPaths(p string) []string {
-	p = strings.TrimSuffix(p, "/")
+// appendParentPaths appends a HostPath key for every prefix of the path
+// starting at hostPath[hostLen:], shallowest → deepest, including the full
+// path itself, so a check URL hits entries listed under any of its parents.
+// "host/a/b/c/file.exe" → host/a, host/a/b, host/a/b/c, host/a/b/c/file.exe
+func appendParentPaths(dst []CheckKey, hostPath string, hostLen int) []CheckKey {
+	p := strings.TrimSuffix(hostPath[hostLen:], "/")
 	if p == "" || p == "/" {
-		return nil
+		return dst
 	}
-	parts := strings.Split(p, "/")
-	var result []string
-	for i := 1; i < len(parts); i++ {
-		result = append(result, strings.Join(parts[:i+1], "/"))
+
+	// Every slash but the first ends a parent: "/a/b" → "/a".
+	first := true
+	for i := 0; i < len(p); i++ {
+		if p[i] != '/' {
+			continue
+		}
+		if first {
+			first = false
+			continue
+		}
+		dst = append(dst, CheckKey{BloomHostPath, hostPath[:hostLen+i]})
 	}
-	return result
+	return append(dst, CheckKey{BloomHostPath, hostPath[:hostLen+len(p)]})
 }
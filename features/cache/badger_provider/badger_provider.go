@@ -4,6 +4,8 @@ import (
 	"blacked/features/cache/cache_errors"
 	"blacked/internal/config"
 	"context"
+	"encoding/binary"
+	"errors"
 	"strings"
 	"sync"
 	"time"
@@ -13,6 +15,13 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// metaPrefix starts the keys Badger keeps about the cache itself. No URL
+// starts with a NUL byte, and Iterate skips them.
+const metaPrefix = "\x00blacked/"
+
+// syncedAtKey holds the SyncedAt mark as big-endian Unix nanos.
+var syncedAtKey = []byte(metaPrefix + "synced_at")
+
 // BadgerProvider implements the EntryCache interface using Badger
 type BadgerProvider struct {
 	db          *badger.DB
@@ -21,6 +30,10 @@ type BadgerProvider struct {
 	initialized bool
 	txn         *badger.Txn
 	ttl         *time.Duration
+	path        string // Directory of an on-disk cache; empty in memory
+
+	stopGC chan struct{}
+	gcDone chan struct{}
 }
 
 // NewBadgerProvider creates a new Badger provider
@@ -34,25 +47,74 @@ func (p *BadgerProvider) Initialize(ctx context.Context) error {
 		return nil
 	}
 
+	cfg := config.GetConfig().Cache
+
 	opts := badger.DefaultOptions("").WithInMemory(true)
+	if cfg.BadgerPath != "" {
+		opts = badger.DefaultOptions(cfg.BadgerPath).WithLoggingLevel(badger.WARNING)
+	}
 
 	db, err := badger.Open(opts)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to open Badger database")
+		log.Error().Err(err).Str("path", cfg.BadgerPath).Msg("Failed to open Badger database")
 		return err
 	}
 
 	p.db = db
+	p.path = cfg.BadgerPath
 	p.initialized = true
-	log.Info().Msg("Badger initialized successfully")
+	p.ttl = cfg.TTL
 
-	p.ttl = config.GetConfig().Cache.TTL
+	if p.path != "" && cfg.BadgerGCInterval > 0 {
+		p.stopGC = make(chan struct{})
+		p.gcDone = make(chan struct{})
+		go p.runValueLogGC(cfg.BadgerGCInterval, cfg.BadgerGCDiscardRatio)
+	}
 
+	log.Info().Str("path", p.path).Bool("persistent", p.path != "").Msg("Badger initialized successfully")
 	return nil
 }
 
+// runValueLogGC rewrites the value log files with at least discardRatio of
+// stale data every interval, until Close.
+func (p *BadgerProvider) runValueLogGC(interval time.Duration, discardRatio float64) {
+	defer close(p.gcDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopGC:
+			return
+		case <-ticker.C:
+			// Each call rewrites at most one file; repeat until none qualifies.
+			rewritten := 0
+			for {
+				err := p.db.RunValueLogGC(discardRatio)
+				if err == nil {
+					rewritten++
+					continue
+				}
+				if !errors.Is(err, badger.ErrNoRewrite) && !errors.Is(err, badger.ErrRejected) {
+					log.Warn().Err(err).Msg("Badger value log GC failed")
+				}
+				break
+			}
+			if rewritten > 0 {
+				log.Debug().Int("files", rewritten).Msg("Badger value log GC rewrote files")
+			}
+		}
+	}
+}
+
 // Close releases Badger resources
 func (p *BadgerProvider) Close() error {
+	if p.stopGC != nil {
+		close(p.stopGC)
+		<-p.gcDone
+		p.stopGC = nil
+	}
 	if p.db != nil {
 		err := p.db.Close()
 		p.db = nil
@@ -62,6 +124,64 @@ func (p *BadgerProvider) Close() error {
 	return nil
 }
 
+// Persistent reports whether the cache is kept on disk.
+func (p *BadgerProvider) Persistent() bool {
+	return p.path != ""
+}
+
+// SyncedAt returns the sync mark, zero when none was recorded.
+func (p *BadgerProvider) SyncedAt() (time.Time, error) {
+	if !p.initialized {
+		return time.Time{}, cache_errors.ErrCacheNotInitialized
+	}
+
+	var syncedAt time.Time
+	err := p.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(syncedAtKey)
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			if len(val) == 8 {
+				syncedAt = time.Unix(0, int64(binary.BigEndian.Uint64(val))).UTC()
+			}
+			return nil
+		})
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return time.Time{}, nil
+	}
+	return syncedAt, err
+}
+
+// MarkSynced records at as the start of the last complete sync. Pending
+// writes are committed first, so the mark never covers keys not yet stored.
+func (p *BadgerProvider) MarkSynced(at time.Time) error {
+	if !p.initialized {
+		return cache_errors.ErrCacheNotInitialized
+	}
+	if err := p.Commit(); err != nil {
+		return err
+	}
+
+	val := binary.BigEndian.AppendUint64(nil, uint64(at.UnixNano()))
+	return p.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(syncedAtKey, val)
+	})
+}
+
+// Reset deletes every key, the sync mark included.
+func (p *BadgerProvider) Reset() error {
+	if !p.initialized {
+		return cache_errors.ErrCacheNotInitialized
+	}
+	if p.txn != nil {
+		p.txn.Discard()
+		p.txn = nil
+	}
+	return p.db.DropAll()
+}
+
 // Get retrieves IDs associated with a key
 func (p *BadgerProvider) Get(key string) ([]string, error) {
 	if !p.initialized {
@@ -223,13 +343,16 @@ func (p *BadgerProvider) Iterate(ctx context.Context, fn func(key string) error)
 
 	return p.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
-		opts.PrefetchSize = 1000
+		opts.PrefetchValues = false // Only keys are read
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
 		for it.Rewind(); it.Valid(); it.Next() {
 			item := it.Item()
 			key := string(item.Key())
+			if strings.HasPrefix(key, metaPrefix) {
+				continue
+			}
 			if err := fn(key); err != nil {
 				return err
 			}
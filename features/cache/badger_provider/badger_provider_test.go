@@ -0,0 +1,76 @@
+package badger_provider
+
+import (
+	"blacked/internal/config"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestProvider(t *testing.T, path string) *BadgerProvider {
+	t.Helper()
+	cfg := config.GetConfig()
+	prev := cfg.Cache
+	cfg.Cache.BadgerPath = path
+	cfg.Cache.BadgerGCInterval = time.Minute
+	cfg.Cache.BadgerGCDiscardRatio = 0.5
+	cfg.Cache.TTL = nil
+	t.Cleanup(func() { cfg.Cache = prev })
+
+	p := NewBadgerProvider()
+	require.NoError(t, p.Initialize(context.Background()))
+	return p
+}
+
+func keys(t *testing.T, p *BadgerProvider) []string {
+	t.Helper()
+	var keys []string
+	require.NoError(t, p.Iterate(context.Background(), func(key string) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	return keys
+}
+
+func TestBadgerInMemoryIsNotPersistent(t *testing.T) {
+	p := openTestProvider(t, "")
+	defer p.Close()
+
+	assert.False(t, p.Persistent())
+	syncedAt, err := p.SyncedAt()
+	require.NoError(t, err)
+	assert.True(t, syncedAt.IsZero())
+}
+
+func TestBadgerOnDiskSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	syncedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	p := openTestProvider(t, dir)
+	assert.True(t, p.Persistent())
+	require.NoError(t, p.Set("https://bad.com/a", "id1,id2"))
+	require.NoError(t, p.SetIds("https://bad.com/b", []string{"id3"}))
+	require.NoError(t, p.MarkSynced(syncedAt), "marking commits the pending writes")
+	require.NoError(t, p.Close())
+
+	p = openTestProvider(t, dir)
+	defer p.Close()
+
+	ids, err := p.Get("https://bad.com/a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id1", "id2"}, ids)
+	assert.ElementsMatch(t, []string{"https://bad.com/a", "https://bad.com/b"}, keys(t, p), "the sync mark is not a cache key")
+
+	got, err := p.SyncedAt()
+	require.NoError(t, err)
+	assert.True(t, got.Equal(syncedAt), "got %v", got)
+
+	require.NoError(t, p.Reset())
+	assert.Empty(t, keys(t, p))
+	got, err = p.SyncedAt()
+	require.NoError(t, err)
+	assert.True(t, got.IsZero(), "reset drops the sync mark")
+}
@@ -7,6 +7,7 @@ import (
 	"blacked/internal/config"
 	"context"
 	"errors"
+	"sync"
 
 	"github.com/rs/zerolog/log"
 )
@@ -24,7 +25,7 @@ func GetEntryStream(sourceUrl string) (entryStream entries.EntryStream, err erro
 		return
 	}
 
-	if config.GetConfig().Cache.UseBloom {
+	if config.GetConfig().Cache.Filter() != config.FilterNone {
 		isLikely, err := CheckURL(sourceUrl)
 		log.Debug().Bool("is_likely", isLikely).Msg("Checked bloom filter")
 		if err != nil {
@@ -74,3 +75,48 @@ func GetEntryStream(sourceUrl string) (entryStream entries.EntryStream, err erro
 		IDs:       ids,
 	}, nil
 }
+
+// LookupResult is the outcome of one URL looked up by GetEntryStreams.
+// A URL the bloom filter rules out has no IDs and no error.
+type LookupResult struct {
+	entries.EntryStream
+	Err error
+}
+
+// GetEntryStreams runs the bloom → cache → repository lookup of GetEntryStream
+// for every URL on up to workers goroutines. Results keep the order of urls;
+// URLs not started before ctx is done carry ctx.Err().
+func GetEntryStreams(ctx context.Context, urls []string, workers int) []LookupResult {
+	results := make([]LookupResult, len(urls))
+	workers = max(1, min(workers, len(urls)))
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for i := range next {
+				stream, err := GetEntryStream(urls[i])
+				if errors.Is(err, ErrBloomKeyNotFound) {
+					err = nil
+				}
+				results[i] = LookupResult{EntryStream: stream, Err: err}
+			}
+		})
+	}
+
+	for i := range urls {
+		select {
+		case next <- i:
+			continue
+		case <-ctx.Done():
+		}
+		for j := i; j < len(urls); j++ {
+			results[j] = LookupResult{EntryStream: entries.EntryStream{SourceUrl: urls[j]}, Err: ctx.Err()}
+		}
+		break
+	}
+	close(next)
+	wg.Wait()
+
+	return results
+}
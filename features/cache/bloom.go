@@ -2,70 +2,199 @@ package cache
 
 import (
 	"blacked/features/entries"
+	"blacked/internal/config"
 	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/bits-and-blooms/bloom/v3"
 	"github.com/rs/zerolog/log"
 )
 
 var (
-	bloomFilter *bloom.BloomFilter
-
 	ErrBloomFilterNotInitialized = errors.New("bloom filter not initialized")
 	ErrPopulateBloom             = errors.New("failed to populate bloom filter")
 )
 
-func GetBloomFilter() (*bloom.BloomFilter, error) {
-	if bloomFilter == nil {
+// BloomManager holds the filter in use, of the kind set by [Cache]
+// probabilistic_filter. A rebuild fills a new filter off to the side,
+// together with the keys added meanwhile, and swaps it in once complete, so
+// lookups never see a partial filter and a failed rebuild keeps the
+// previous one.
+type BloomManager struct {
+	current  atomic.Pointer[Filter]
+	building atomic.Pointer[Filter]
+	buildMu  sync.Mutex // One rebuild at a time
+}
+
+var bloomManager = &BloomManager{}
+
+// GetBloomManager returns the process-wide BloomManager.
+func GetBloomManager() *BloomManager {
+	return bloomManager
+}
+
+// Current returns the filter in use.
+func (m *BloomManager) Current() (Filter, error) {
+	bf := m.current.Load()
+	if bf == nil {
 		return nil, ErrBloomFilterNotInitialized
 	}
-	return bloomFilter, nil
+	return *bf, nil
 }
 
-func BuildBloomFromChannel(ctx context.Context, keyCount int, ch <-chan entries.EntryStream) error {
-	ctx, cancel := context.WithTimeout(ctx, 1*time.Hour)
-	defer cancel()
+// next returns the filter being rebuilt, or nil.
+func (m *BloomManager) next() Filter {
+	if next := m.building.Load(); next != nil {
+		return *next
+	}
+	return nil
+}
+
+// Add adds the keys not yet present to the filter in use and to the one
+// being rebuilt.
+func (m *BloomManager) Add(keys ...string) error {
+	bf, err := m.Current()
+	if err != nil {
+		return err
+	}
+	next := m.next()
+	for _, key := range keys {
+		bf.AddAbsent(key)
+		if next != nil {
+			next.AddAbsent(key)
+		}
+	}
+	return nil
+}
 
-	if keyCount < 1000 {
-		keyCount = 1000
+// Remove takes keys out of the filter in use and the one being rebuilt.
+func (m *BloomManager) Remove(keys ...string) error {
+	bf, err := m.Current()
+	if err != nil {
+		return err
+	}
+	next := m.next()
+	for _, key := range keys {
+		bf.Remove(key)
+		if next != nil {
+			next.Remove(key)
+		}
 	}
+	return nil
+}
 
-	bloomFilter = bloom.NewWithEstimates(uint(keyCount), 0.01)
+// Rebuild creates a filter for keyCount keys, fills it through fill and
+// swaps it in when fill succeeds. With [Cache] bloom_path set, the new
+// filter is also saved there.
+func (m *BloomManager) Rebuild(ctx context.Context, keyCount int, fill func(add func(key string)) error) error {
+	m.buildMu.Lock()
+	defer m.buildMu.Unlock()
 
+	cfg := config.GetConfig().Cache
+	next := NewFilter(cfg.Filter(), keyCount, cfg.BloomShards)
 	log.Info().
+		Str("kind", next.Kind()).
 		Int("cache_keys", keyCount).
-		Uint("bloom_capacity", bloomFilter.Cap()).
-		Uint("hash_functions", bloomFilter.K()).
+		Int("shards", next.Shards()).
+		Uint("bloom_capacity", next.Cap()).
+		Uint("hash_functions", next.K()).
 		Msg("Created bloom filter & Starting to populate bloom filter")
 
-	addedKeys := 0
-	startTime := time.Now()
+	m.building.Store(&next)
+	defer m.building.Store(nil)
 
-	for {
-		select {
-		case <-ctx.Done():
-			logState(startTime, addedKeys, "Context Done")
-			return ctx.Err()
-		case entry, ok := <-ch:
-			if !ok {
-				logState(startTime, addedKeys, "channel !ok done")
-				return nil
-			}
+	if err := fill(next.Add); err != nil {
+		log.Warn().Err(err).Msg("Bloom filter rebuild failed, keeping the previous filter")
+		return err
+	}
+	m.current.Store(&next)
+
+	if cfg.BloomPath != "" && next.Kind() != config.FilterNone {
+		startTime := time.Now()
+		if err := SaveFilter(next, cfg.BloomPath); err != nil {
+			log.Warn().Err(err).Str("path", cfg.BloomPath).Msg("Failed to save bloom filter")
+		} else {
+			log.Debug().Str("path", cfg.BloomPath).Dur("duration", time.Since(startTime)).Msg("Bloom filter saved")
+		}
+	}
+	return nil
+}
 
-			bloomFilter.AddString(entry.SourceUrl)
-			addedKeys++
+// Load makes the filter saved at path current and returns it. A filter of
+// another kind than [Cache] probabilistic_filter is not loaded.
+func (m *BloomManager) Load(path string) (Filter, error) {
+	bf, err := LoadFilter(path)
+	if err != nil {
+		return nil, err
+	}
+	if kind := config.GetConfig().Cache.Filter(); bf.Kind() != kind {
+		return nil, fmt.Errorf("%w: saved %s filter, %s configured", ErrInvalidBloomFile, bf.Kind(), kind)
+	}
+	m.current.Store(&bf)
+	log.Info().
+		Str("path", path).
+		Str("kind", bf.Kind()).
+		Int("shards", bf.Shards()).
+		Time("built_at", bf.BuiltAt()).
+		Msg("Bloom filter loaded")
+	return bf, nil
+}
 
-			if log.Trace().Enabled() {
-				log.Trace().Str("key", entry.SourceUrl).Msg("Adding key to bloom filter")
-			}
-			if addedKeys%100000 == 0 {
-				logState(startTime, addedKeys, "progress : addedKeys%100000")
-			}
-		}
+func GetBloomFilter() (Filter, error) {
+	return bloomManager.Current()
+}
 
+// LoadBloomFilter makes the filter saved at path current and returns when
+// its build started; entries changed since then are missing from it.
+func LoadBloomFilter(path string) (time.Time, error) {
+	bf, err := bloomManager.Load(path)
+	if err != nil {
+		return time.Time{}, err
 	}
+	return bf.BuiltAt(), nil
+}
+
+// BuildBloomFromChannel replaces the bloom filter with one holding every key
+// read from ch. onAdd, when set, is called with the running count after each
+// key. It returns the number of keys added.
+func BuildBloomFromChannel(ctx context.Context, keyCount int, ch <-chan entries.EntryStream, onAdd func(added int)) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Hour)
+	defer cancel()
+
+	addedKeys := 0
+	startTime := time.Now()
+
+	err := bloomManager.Rebuild(ctx, keyCount, func(add func(string)) error {
+		for {
+			select {
+			case <-ctx.Done():
+				logState(startTime, addedKeys, "Context Done")
+				return ctx.Err()
+			case entry, ok := <-ch:
+				if !ok {
+					logState(startTime, addedKeys, "channel !ok done")
+					return nil
+				}
+
+				add(entry.SourceUrl)
+				addedKeys++
+				if onAdd != nil {
+					onAdd(addedKeys)
+				}
+
+				if log.Trace().Enabled() {
+					log.Trace().Str("key", entry.SourceUrl).Msg("Adding key to bloom filter")
+				}
+				if addedKeys%100000 == 0 {
+					logState(startTime, addedKeys, "progress : addedKeys%100000")
+				}
+			}
+		}
+	})
+	return addedKeys, err
 }
 
 func logState(startTime time.Time, addedKeys int, msg string) {
@@ -82,37 +211,36 @@ func BuildBloomFilterFromCacheProvider(ctx context.Context, cacheProvider EntryC
 	ctx, cancel := context.WithTimeout(ctx, 1*time.Hour)
 	defer cancel()
 
-	if keyCount < 1000 {
-		keyCount = 1000
-	}
-
-	bloomFilter = bloom.NewWithEstimates(uint(keyCount), 0.01)
-
-	log.Info().
-		Int("cache_keys", keyCount).
-		Uint("bloom_capacity", bloomFilter.Cap()).
-		Uint("hash_functions", bloomFilter.K()).
-		Msg("Created bloom filter & Starting to populate bloom filter")
-
 	addedKeys := 0
 	startTime := time.Now()
 
-	cacheProvider.Iterate(ctx, func(key string) error {
+	bloomManager.Rebuild(ctx, keyCount, func(add func(string)) error {
+		return cacheProvider.Iterate(ctx, func(key string) error {
 
-		bloomFilter.AddString(key)
-		addedKeys++
+			add(key)
+			addedKeys++
 
-		if log.Trace().Enabled() {
-			log.Trace().Str("key", key).Msg("Adding key to bloom filter")
-			if addedKeys%100000 == 0 {
-				logState(startTime, addedKeys, "on going progress : addedKeys%100000 == 0")
+			if log.Trace().Enabled() {
+				log.Trace().Str("key", key).Msg("Adding key to bloom filter")
+				if addedKeys%100000 == 0 {
+					logState(startTime, addedKeys, "on going progress : addedKeys%100000 == 0")
+				}
 			}
-		}
 
-		return nil
+			return nil
+		})
 	})
+}
+
+// AddToBloomFilter adds keys to the current bloom filter without rebuilding it.
+func AddToBloomFilter(keys ...string) error {
+	return bloomManager.Add(keys...)
+}
 
-	return
+// RemoveFromBloomFilter takes keys no entry lists anymore out of the current
+// bloom filter without rebuilding it.
+func RemoveFromBloomFilter(keys ...string) error {
+	return bloomManager.Remove(keys...)
 }
 
 func CheckURL(url string) (bool, error) {
@@ -125,7 +253,7 @@ func CheckURL(url string) (bool, error) {
 		return false, err
 	}
 
-	isLikely := bf.Test([]byte(url))
+	isLikely := bf.Test(url)
 
 	return isLikely, nil
 }
@@ -140,7 +268,7 @@ func CheckURLs(urls []string) ([]string, error) {
 	var possibleMatches []string
 
 	for _, url := range urls {
-		if url != "" && bf.Test([]byte(url)) {
+		if url != "" && bf.Test(url) {
 			possibleMatches = append(possibleMatches, url)
 		}
 	}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"blacked/internal/config"
+)
+
+const (
+	// maxBloomShards bounds the shard count of a new or loaded filter.
+	maxBloomShards = 4096
+
+	// minShardKeys is the fewest keys a shard is sized for.
+	minShardKeys = 1000
+
+	// filterHeaderSize is the size of the header every saved filter starts
+	// with: its magic, build time and shard count.
+	filterHeaderSize = 8 + 8 + 4
+)
+
+var ErrInvalidBloomFile = errors.New("invalid bloom filter file")
+
+// Filter is the probabilistic set of cache keys checked before the cache:
+// a key it doesn't hold is not listed, while one it holds most likely is.
+// Keys can be added and removed again without a rebuild.
+type Filter interface {
+	// Add counts key once more.
+	Add(key string)
+	// AddAbsent adds key unless it tests present and reports whether it did.
+	AddAbsent(key string) bool
+	// Remove takes key out if it tests present and reports whether it did.
+	Remove(key string) bool
+	// Test reports whether key may have been added.
+	Test(key string) bool
+
+	// Kind returns the [Cache] probabilistic_filter the filter implements.
+	Kind() string
+	// Shards returns the number of shards.
+	Shards() int
+	// BuiltAt returns when the build of the filter started.
+	BuiltAt() time.Time
+	// Cap returns the number of counters or slots of all shards.
+	Cap() uint
+	// K returns the number of locations a key is hashed to.
+	K() uint
+	// ApproximatedSize estimates the number of keys held.
+	ApproximatedSize() uint32
+
+	// WriteTo writes the filter in the format read by ReadFilter.
+	WriteTo(w io.Writer) (int64, error)
+}
+
+// NewFilter creates a filter of kind, one of the config.Filter* kinds,
+// sized for keyCount keys and split into shards.
+func NewFilter(kind string, keyCount, shards int) Filter {
+	switch kind {
+	case config.FilterCuckoo:
+		return NewShardedCuckoo(keyCount, shards)
+	case config.FilterNone:
+		return &noFilter{builtAt: time.Now()}
+	default:
+		return NewShardedBloom(keyCount, shards)
+	}
+}
+
+// shardIndex returns the shard of n holding key.
+func shardIndex(key string, n int) int {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int(h.Sum64() % uint64(n))
+}
+
+// appendFilterHeader appends the header of a saved filter to b.
+func appendFilterHeader(b []byte, magic string, builtAt time.Time, shards int) []byte {
+	b = append(b, magic...)
+	b = binary.BigEndian.AppendUint64(b, uint64(builtAt.UnixNano()))
+	return binary.BigEndian.AppendUint32(b, uint32(shards))
+}
+
+// ReadFilter reads a filter written by its WriteTo, of whichever kind.
+func ReadFilter(r io.Reader) (Filter, error) {
+	header := make([]byte, filterHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.Join(ErrInvalidBloomFile, err)
+	}
+	builtAt := time.Unix(0, int64(binary.BigEndian.Uint64(header[8:])))
+	shards := binary.BigEndian.Uint32(header[16:])
+	if shards == 0 || shards > maxBloomShards {
+		return nil, fmt.Errorf("%w: %d shards", ErrInvalidBloomFile, shards)
+	}
+
+	switch string(header[:8]) {
+	case bloomFileMagic:
+		return readShardedBloom(r, builtAt, int(shards))
+	case cuckooFileMagic:
+		return readShardedCuckoo(r, builtAt, int(shards))
+	default:
+		return nil, fmt.Errorf("%w: unknown format", ErrInvalidBloomFile)
+	}
+}
+
+// SaveFilter writes f to path atomically.
+func SaveFilter(f Filter, path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".bloom-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if _, err := f.WriteTo(w); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFilter reads the filter saved at path.
+func LoadFilter(path string) (Filter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadFilter(bufio.NewReader(f))
+}
+
+// noFilter is the filter of [Cache] probabilistic_filter = "none": it holds
+// every key, so every lookup goes on to the cache. It is never saved.
+type noFilter struct {
+	builtAt time.Time
+}
+
+func (f *noFilter) Add(string)               { /* Holds every key already */ }
+func (f *noFilter) AddAbsent(string) bool    { return false }
+func (f *noFilter) Remove(string) bool       { return false }
+func (f *noFilter) Test(string) bool         { return true }
+func (f *noFilter) Kind() string             { return config.FilterNone }
+func (f *noFilter) Shards() int              { return 0 }
+func (f *noFilter) BuiltAt() time.Time       { return f.builtAt }
+func (f *noFilter) Cap() uint                { return 0 }
+func (f *noFilter) K() uint                  { return 0 }
+func (f *noFilter) ApproximatedSize() uint32 { return 0 }
+func (f *noFilter) WriteTo(io.Writer) (int64, error) {
+	return 0, errors.New("a filter of kind none is not saved")
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"blacked/features/cache/badger_provider"
 	"blacked/features/cache/cache_errors"
+	"blacked/features/cache/ristretto_provider"
 	"blacked/internal/config"
 	"context"
 	"errors"
@@ -51,6 +52,8 @@ func InitializeCache(ctx context.Context) error {
 		switch strings.ToLower(cfg.CacheType) {
 		case "badger":
 			selectedType = BadgerCache
+		case "ristretto":
+			selectedType = RistrettoCache
 		default:
 			log.Warn().Str("configured_type", cfg.CacheType).Msg("Unsupported cache type, defaulting to Badger")
 			selectedType = BadgerCache
@@ -61,6 +64,8 @@ func InitializeCache(ctx context.Context) error {
 		switch selectedType {
 		case BadgerCache:
 			cacheInstance = badger_provider.NewBadgerProvider()
+		case RistrettoCache:
+			cacheInstance = ristretto_provider.NewRistrettoProvider()
 		default:
 			// This case should technically not be reachable due to default above
 			cacheInitErr = errors.New("internal error: invalid cache type selected")
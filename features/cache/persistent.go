@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// PersistentCache is implemented by caches that can keep their keys on disk
+// across restarts. Such a cache records when it was last known to hold every
+// active entry, so a restart only syncs the entries changed since.
+type PersistentCache interface {
+	// Persistent reports whether the keys are kept on disk.
+	Persistent() bool
+	// SyncedAt returns the start of the last sync after which the cache held
+	// every active entry; zero when it never completed one.
+	SyncedAt() (time.Time, error)
+	// MarkSynced records at as the start of such a sync.
+	MarkSynced(at time.Time) error
+	// Reset deletes every key and the sync mark.
+	Reset() error
+}
+
+// GetPersistentCache returns the cache when it keeps its keys on disk.
+func GetPersistentCache() (PersistentCache, bool) {
+	pc, ok := cacheInstance.(PersistentCache)
+	if !ok || !pc.Persistent() {
+		return nil, false
+	}
+	return pc, true
+}
+
+// BuildBloomFilterFromCache rebuilds the filter from the keys the cache
+// already holds, as after a start on a cache kept on disk.
+func BuildBloomFilterFromCache(ctx context.Context, cacheProvider EntryCache) error {
+	keyCount := 0
+	err := cacheProvider.Iterate(ctx, func(string) error {
+		keyCount++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	BuildBloomFilterFromCacheProvider(ctx, cacheProvider, keyCount)
+	return nil
+}
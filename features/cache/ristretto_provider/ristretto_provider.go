@@ -0,0 +1,251 @@
+package ristretto_provider
+
+import (
+	"blacked/features/cache/cache_errors"
+	"blacked/internal/config"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/ristretto/v2"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrInvalidMaxMemory = errors.New("ristretto cache max_memory must be positive")
+
+const (
+	// avgItemCost is the assumed size of a cached source URL plus its IDs,
+	// used to derive the admission counters when num_counters is not set.
+	avgItemCost = 128
+
+	// itemOverhead approximates ristretto's per-item bookkeeping in bytes.
+	itemOverhead = 64
+)
+
+// cacheValue keeps the key next to the IDs so eviction callbacks, which
+// only see key hashes, can drop the key from the iteration set. The IDs are
+// split once on Set so lookups don't allocate.
+type cacheValue struct {
+	key string
+	ids []string
+}
+
+// RistrettoProvider implements the EntryCache interface using Ristretto.
+// Memory is capped by cost-based eviction: each item costs the bytes of its
+// key and IDs, and the total never exceeds the configured max_memory.
+type RistrettoProvider struct {
+	cache       *ristretto.Cache[string, *cacheValue]
+	ttl         *time.Duration
+	initialized bool
+
+	// Ristretto can't be iterated, so the keys it holds are tracked here
+	// with the value last written for each.
+	keysMu sync.RWMutex
+	keys   map[string]*cacheValue
+}
+
+// NewRistrettoProvider creates a new Ristretto provider
+func NewRistrettoProvider() *RistrettoProvider {
+	return &RistrettoProvider{
+		keys: make(map[string]*cacheValue),
+	}
+}
+
+// Initialize sets up the Ristretto instance
+func (p *RistrettoProvider) Initialize(ctx context.Context) error {
+	if p.initialized {
+		return nil
+	}
+
+	cfg := config.GetConfig().Cache
+
+	maxCost := cfg.MaxMemory
+	if maxCost <= 0 {
+		return ErrInvalidMaxMemory
+	}
+	numCounters := cfg.NumCounters
+	if numCounters <= 0 {
+		// Ristretto recommends ~10 counters per item expected at capacity.
+		numCounters = max(maxCost/avgItemCost*10, 1000)
+	}
+
+	cache, err := ristretto.NewCache(&ristretto.Config[string, *cacheValue]{
+		NumCounters: numCounters,
+		MaxCost:     maxCost,
+		BufferItems: 64,
+		Metrics:     true,
+		OnEvict: func(item *ristretto.Item[*cacheValue]) {
+			p.forget(item.Value)
+		},
+		OnReject: func(item *ristretto.Item[*cacheValue]) {
+			p.forget(item.Value)
+		},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create Ristretto cache")
+		return err
+	}
+
+	p.cache = cache
+	p.ttl = cfg.TTL
+	p.initialized = true
+
+	log.Info().
+		Int64("max_memory", maxCost).
+		Int64("num_counters", numCounters).
+		Msg("Ristretto initialized successfully")
+
+	return nil
+}
+
+// Close releases Ristretto resources
+func (p *RistrettoProvider) Close() error {
+	if p.cache != nil {
+		if m := p.cache.Metrics; m != nil {
+			log.Debug().
+				Uint64("keys_added", m.KeysAdded()).
+				Uint64("keys_evicted", m.KeysEvicted()).
+				Uint64("sets_rejected", m.SetsRejected()).
+				Float64("hit_ratio", m.Ratio()).
+				Msg("Ristretto cache stats")
+		}
+		p.cache.Close()
+		p.cache = nil
+		p.initialized = false
+
+		p.keysMu.Lock()
+		p.keys = make(map[string]*cacheValue)
+		p.keysMu.Unlock()
+	}
+	return nil
+}
+
+// Get retrieves IDs associated with a key. The slice is a copy the caller
+// may modify.
+func (p *RistrettoProvider) Get(key string) ([]string, error) {
+	if !p.initialized {
+		return nil, cache_errors.ErrCacheNotInitialized
+	}
+
+	value, ok := p.cache.Get(key)
+	if !ok || value == nil {
+		return nil, cache_errors.ErrKeyNotFound
+	}
+
+	return slices.Clone(value.ids), nil
+}
+
+// Set stores IDs associated with a key. Writes are buffered by Ristretto;
+// call Commit to make them visible to Get.
+func (p *RistrettoProvider) Set(key string, ids string) error {
+	if !p.initialized {
+		return cache_errors.ErrCacheNotInitialized
+	}
+
+	cost := int64(len(key)+len(ids)) + itemOverhead
+	if cost > p.cache.MaxCost() {
+		return cache_errors.ErrValueTooLarge
+	}
+
+	value := &cacheValue{key: key}
+	if len(ids) > 0 {
+		value.ids = strings.Split(ids, ",")
+	}
+
+	// Track the key before the write: eviction callbacks may fire as soon as it lands.
+	p.keysMu.Lock()
+	p.keys[key] = value
+	p.keysMu.Unlock()
+
+	var accepted bool
+	if p.ttl != nil {
+		accepted = p.cache.SetWithTTL(key, value, cost, *p.ttl)
+	} else {
+		accepted = p.cache.Set(key, value, cost)
+	}
+
+	if !accepted {
+		// Dropped by contention; a cache miss falls back to the DB.
+		if log.Trace().Enabled() {
+			log.Trace().Str("key", key).Msg("Ristretto dropped set")
+		}
+		p.forget(value)
+	}
+
+	return nil
+}
+
+func (p *RistrettoProvider) SetIds(key string, ids []string) error {
+	return p.Set(key, strings.Join(ids, ","))
+}
+
+// Commit waits until all buffered writes are applied.
+func (p *RistrettoProvider) Commit() error {
+	if p.cache != nil {
+		p.cache.Wait()
+	}
+	return nil
+}
+
+// Delete removes a key from the cache
+func (p *RistrettoProvider) Delete(key string) error {
+	if !p.initialized {
+		return cache_errors.ErrCacheNotInitialized
+	}
+
+	p.cache.Del(key)
+
+	p.keysMu.Lock()
+	delete(p.keys, key)
+	p.keysMu.Unlock()
+
+	return nil
+}
+
+// Iterate calls fn for every key currently held. Keys evicted or expired
+// since they were tracked are skipped and forgotten.
+func (p *RistrettoProvider) Iterate(ctx context.Context, fn func(key string) error) error {
+	if !p.initialized {
+		return cache_errors.ErrCacheNotInitialized
+	}
+
+	p.keysMu.RLock()
+	keys := make([]string, 0, len(p.keys))
+	for k := range p.keys {
+		keys = append(keys, k)
+	}
+	p.keysMu.RUnlock()
+
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, ok := p.cache.Get(key); !ok {
+			p.keysMu.Lock()
+			delete(p.keys, key)
+			p.keysMu.Unlock()
+			continue
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forget drops a key from the iteration set unless a newer value replaced it.
+// It runs inside Ristretto callbacks, which may hold store locks, so it must
+// not call back into the cache.
+func (p *RistrettoProvider) forget(value *cacheValue) {
+	if value == nil {
+		return
+	}
+	p.keysMu.Lock()
+	if p.keys[value.key] == value {
+		delete(p.keys, value.key)
+	}
+	p.keysMu.Unlock()
+}
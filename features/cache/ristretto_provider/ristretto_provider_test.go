@@ -0,0 +1,75 @@
+package ristretto_provider
+
+import (
+	"blacked/features/cache/cache_errors"
+	"blacked/internal/config"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProvider(t *testing.T, maxMemory int64) *RistrettoProvider {
+	t.Helper()
+	cfg := config.GetConfig()
+	prev := cfg.Cache
+	cfg.Cache.MaxMemory = maxMemory
+	cfg.Cache.NumCounters = 10_000
+	cfg.Cache.TTL = nil
+	t.Cleanup(func() { cfg.Cache = prev })
+
+	p := NewRistrettoProvider()
+	require.NoError(t, p.Initialize(context.Background()))
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func TestRistrettoSetGetDeleteIterate(t *testing.T) {
+	p := newTestProvider(t, 1<<20)
+
+	require.NoError(t, p.Set("https://bad.com/a", "id1,id2"))
+	require.NoError(t, p.SetIds("https://bad.com/b", []string{"id3"}))
+	require.NoError(t, p.Commit())
+
+	ids, err := p.Get("https://bad.com/a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id1", "id2"}, ids)
+
+	ids[0] = "modified"
+	ids, err = p.Get("https://bad.com/a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id1", "id2"}, ids, "Get returns a copy of the cached IDs")
+
+	var keys []string
+	require.NoError(t, p.Iterate(context.Background(), func(key string) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{"https://bad.com/a", "https://bad.com/b"}, keys)
+
+	require.NoError(t, p.Delete("https://bad.com/a"))
+	_, err = p.Get("https://bad.com/a")
+	assert.ErrorIs(t, err, cache_errors.ErrKeyNotFound)
+}
+
+func TestRistrettoCapsMemory(t *testing.T) {
+	const maxMemory = 64 << 10
+	p := newTestProvider(t, maxMemory)
+
+	assert.ErrorIs(t, p.Set("huge", string(make([]byte, maxMemory))), cache_errors.ErrValueTooLarge)
+
+	for i := range 5000 {
+		require.NoError(t, p.Set(fmt.Sprintf("https://bad.com/%d", i), "0123456789abcdefghij"))
+	}
+	require.NoError(t, p.Commit())
+
+	held := 0
+	require.NoError(t, p.Iterate(context.Background(), func(string) error {
+		held++
+		return nil
+	}))
+	assert.Less(t, held, 5000, "items beyond max_memory are evicted")
+	assert.LessOrEqual(t, int64(p.cache.Metrics.CostAdded()-p.cache.Metrics.CostEvicted()), int64(maxMemory))
+}
@@ -0,0 +1,254 @@
+package cache
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"blacked/internal/config"
+
+	"github.com/bits-and-blooms/bloom/v3"
+)
+
+const (
+	// bloomFileMagic starts a saved bloom filter; the last byte is the format version.
+	bloomFileMagic = "BLKBLM\x00\x02"
+
+	// maxCount is the value at which a 4-bit counter sticks: it is no longer
+	// decremented, so a key can't be removed from under others sharing it.
+	maxCount = 15
+
+	// falsePositiveRate is the rate each shard is sized for.
+	falsePositiveRate = 0.01
+)
+
+// ShardedBloom is a counting bloom filter split into shards by key hash.
+// Each shard has its own lock, so keys added or removed while URLs are
+// checked only contend on one shard. A key added once can be removed again,
+// which plain bloom filters don't allow.
+type ShardedBloom struct {
+	shards  []*bloomShard
+	builtAt time.Time // Start of the build, the point up to which it holds every entry
+}
+
+// bloomShard is a counting bloom filter of 4-bit counters, two per byte.
+type bloomShard struct {
+	mu       sync.RWMutex
+	m, k     uint
+	counters []byte
+}
+
+func newBloomShard(m, k uint) *bloomShard {
+	return &bloomShard{m: m, k: k, counters: make([]byte, (m+1)/2)}
+}
+
+func (s *bloomShard) locations(key string) []uint64 {
+	locs := bloom.Locations([]byte(key), s.k)
+	for i := range locs {
+		locs[i] %= uint64(s.m)
+	}
+	return locs
+}
+
+func (s *bloomShard) count(i uint64) byte {
+	return s.counters[i/2] >> (4 * (i % 2)) & 0x0f
+}
+
+func (s *bloomShard) setCount(i uint64, c byte) {
+	shift := 4 * (i % 2)
+	s.counters[i/2] = s.counters[i/2]&^(0x0f<<shift) | c<<shift
+}
+
+func (s *bloomShard) test(locs []uint64) bool {
+	for _, i := range locs {
+		if s.count(i) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *bloomShard) add(locs []uint64) {
+	for _, i := range locs {
+		if c := s.count(i); c < maxCount {
+			s.setCount(i, c+1)
+		}
+	}
+}
+
+func (s *bloomShard) remove(locs []uint64) {
+	for _, i := range locs {
+		if c := s.count(i); c > 0 && c < maxCount {
+			s.setCount(i, c-1)
+		}
+	}
+}
+
+// NewShardedBloom creates a filter sized for keyCount keys at a 1% false
+// positive rate, split into shards.
+func NewShardedBloom(keyCount, shards int) *ShardedBloom {
+	shards = min(max(shards, 1), maxBloomShards)
+	perShard := max(keyCount/shards+1, minShardKeys)
+	m, k := bloom.EstimateParameters(uint(perShard), falsePositiveRate)
+
+	b := &ShardedBloom{shards: make([]*bloomShard, shards), builtAt: time.Now()}
+	for i := range b.shards {
+		b.shards[i] = newBloomShard(m, k)
+	}
+	return b
+}
+
+func (b *ShardedBloom) shard(key string) *bloomShard {
+	return b.shards[shardIndex(key, len(b.shards))]
+}
+
+// Add counts key once more.
+func (b *ShardedBloom) Add(key string) {
+	s := b.shard(key)
+	locs := s.locations(key)
+	s.mu.Lock()
+	s.add(locs)
+	s.mu.Unlock()
+}
+
+// AddAbsent adds key unless it tests present, so re-adding a listed key
+// doesn't raise its count, and reports whether it was added.
+func (b *ShardedBloom) AddAbsent(key string) bool {
+	s := b.shard(key)
+	locs := s.locations(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.test(locs) {
+		return false
+	}
+	s.add(locs)
+	return true
+}
+
+// Remove takes key out if it tests present and reports whether it did.
+// Callers only remove keys they added: removing a false positive lowers the
+// counts of the keys it collides with.
+func (b *ShardedBloom) Remove(key string) bool {
+	s := b.shard(key)
+	locs := s.locations(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.test(locs) {
+		return false
+	}
+	s.remove(locs)
+	return true
+}
+
+// Test reports whether key may have been added.
+func (b *ShardedBloom) Test(key string) bool {
+	s := b.shard(key)
+	locs := s.locations(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.test(locs)
+}
+
+// Kind returns config.FilterBloom.
+func (b *ShardedBloom) Kind() string {
+	return config.FilterBloom
+}
+
+// Shards returns the number of shards.
+func (b *ShardedBloom) Shards() int {
+	return len(b.shards)
+}
+
+// BuiltAt returns when the build of b started.
+func (b *ShardedBloom) BuiltAt() time.Time {
+	return b.builtAt
+}
+
+// Cap returns the number of counters of all shards.
+func (b *ShardedBloom) Cap() uint {
+	var total uint
+	for _, s := range b.shards {
+		total += s.m
+	}
+	return total
+}
+
+// K returns the number of hash functions of each shard.
+func (b *ShardedBloom) K() uint {
+	return b.shards[0].k
+}
+
+// ApproximatedSize estimates the number of keys held by all shards from
+// their non-zero counters.
+func (b *ShardedBloom) ApproximatedSize() uint32 {
+	var total float64
+	for _, s := range b.shards {
+		s.mu.RLock()
+		set := 0
+		for i := range uint64(s.m) {
+			if s.count(i) > 0 {
+				set++
+			}
+		}
+		s.mu.RUnlock()
+		m, k := float64(s.m), float64(s.k)
+		total += -m / k * math.Log(1-min(float64(set), m-1)/m)
+	}
+	return uint32(math.Floor(total + 0.5))
+}
+
+// WriteTo writes b in the format read by ReadFilter: a header with the
+// build time and shard count, then the size, hash count and counters of
+// every shard.
+func (b *ShardedBloom) WriteTo(w io.Writer) (int64, error) {
+	header := appendFilterHeader(make([]byte, 0, filterHeaderSize), bloomFileMagic, b.builtAt, len(b.shards))
+
+	n, err := w.Write(header)
+	total := int64(n)
+	if err != nil {
+		return total, err
+	}
+	for _, s := range b.shards {
+		s.mu.RLock()
+		shardHeader := binary.BigEndian.AppendUint64(nil, uint64(s.m))
+		shardHeader = binary.BigEndian.AppendUint64(shardHeader, uint64(s.k))
+		n, err := w.Write(shardHeader)
+		if err == nil {
+			var c int
+			c, err = w.Write(s.counters)
+			n += c
+		}
+		s.mu.RUnlock()
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// readShardedBloom reads the shards of a bloom filter after its header.
+func readShardedBloom(r io.Reader, builtAt time.Time, shards int) (*ShardedBloom, error) {
+	b := &ShardedBloom{shards: make([]*bloomShard, shards), builtAt: builtAt}
+	shardHeader := make([]byte, 16)
+	for i := range b.shards {
+		if _, err := io.ReadFull(r, shardHeader); err != nil {
+			return nil, errors.Join(ErrInvalidBloomFile, err)
+		}
+		m := binary.BigEndian.Uint64(shardHeader)
+		k := binary.BigEndian.Uint64(shardHeader[8:])
+		if m == 0 || m > math.MaxInt32 || k == 0 || k > 64 {
+			return nil, fmt.Errorf("%w: shard of %d counters and %d hashes", ErrInvalidBloomFile, m, k)
+		}
+		s := newBloomShard(uint(m), uint(k))
+		if _, err := io.ReadFull(r, s.counters); err != nil {
+			return nil, errors.Join(ErrInvalidBloomFile, err)
+		}
+		b.shards[i] = s
+	}
+	return b, nil
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"blacked/internal/config"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedBloomRoundTrip(t *testing.T) {
+	b := NewShardedBloom(10_000, 8)
+	for i := range 5000 {
+		b.Add(fmt.Sprintf("https://bad%d.example/", i))
+	}
+
+	var buf bytes.Buffer
+	_, err := b.WriteTo(&buf)
+	require.NoError(t, err)
+
+	loaded, err := ReadFilter(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 8, loaded.Shards())
+	assert.Equal(t, b.Cap(), loaded.Cap())
+	assert.True(t, b.BuiltAt().Equal(loaded.BuiltAt()))
+	for i := range 5000 {
+		require.True(t, loaded.Test(fmt.Sprintf("https://bad%d.example/", i)))
+	}
+
+	_, err = ReadFilter(bytes.NewReader([]byte("not a bloom filter")))
+	assert.ErrorIs(t, err, ErrInvalidBloomFile)
+}
+
+func TestShardedBloomCounting(t *testing.T) {
+	b := NewShardedBloom(1000, 4)
+	b.Add("https://a.example/")
+	b.Add("https://b.example/")
+
+	assert.True(t, b.Remove("https://a.example/"))
+	assert.False(t, b.Test("https://a.example/"))
+	assert.True(t, b.Test("https://b.example/"), "removing a key keeps the others")
+	assert.False(t, b.Remove("https://a.example/"), "a removed key is not removed twice")
+
+	assert.True(t, b.AddAbsent("https://c.example/"))
+	assert.False(t, b.AddAbsent("https://c.example/"), "a listed key is not counted twice")
+	b.Remove("https://c.example/")
+	assert.False(t, b.Test("https://c.example/"))
+
+	// A counter that reached its maximum sticks, so it never drops under a
+	// key still sharing it.
+	for range maxCount + 5 {
+		b.Add("https://hot.example/")
+	}
+	for range maxCount + 5 {
+		b.Remove("https://hot.example/")
+	}
+	assert.True(t, b.Test("https://hot.example/"))
+}
+
+func TestBloomManagerRebuild(t *testing.T) {
+	cfg := config.GetConfig()
+	path := filepath.Join(t.TempDir(), "bloom.bin")
+	cfg.Cache.BloomPath = path
+	t.Cleanup(func() { cfg.Cache.BloomPath = "" })
+
+	m := &BloomManager{}
+	assert.ErrorIs(t, m.Add("https://early.example/"), ErrBloomFilterNotInitialized)
+
+	require.NoError(t, m.Rebuild(context.Background(), 100, func(add func(string)) error {
+		add("https://old.example/")
+		return nil
+	}))
+
+	// Keys added during a rebuild reach the new filter; a failed rebuild
+	// keeps the one in use.
+	failed := errors.New("stream failed")
+	err := m.Rebuild(context.Background(), 100, func(add func(string)) error {
+		add("https://partial.example/")
+		require.NoError(t, m.Add("https://during.example/"))
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+	bf, err := m.Current()
+	require.NoError(t, err)
+	assert.True(t, bf.Test("https://old.example/"))
+	assert.False(t, bf.Test("https://partial.example/"))
+
+	require.NoError(t, m.Rebuild(context.Background(), 100, func(add func(string)) error {
+		require.NoError(t, m.Add("https://during.example/"))
+		return nil
+	}))
+	bf, err = m.Current()
+	require.NoError(t, err)
+	assert.True(t, bf.Test("https://during.example/"))
+	assert.False(t, bf.Test("https://old.example/"))
+
+	// The saved filter is the last one swapped in.
+	restarted := &BloomManager{}
+	loaded, err := restarted.Load(path)
+	require.NoError(t, err)
+	assert.True(t, loaded.Test("https://during.example/"))
+	assert.True(t, loaded.BuiltAt().Equal(bf.BuiltAt()))
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"blacked/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedCuckoo(t *testing.T) {
+	// A shard sized for 1000 keys overfilled, so fingerprints reach the stash.
+	c := NewShardedCuckoo(0, 1)
+	keys := 2500
+	for i := range keys {
+		require.True(t, c.AddAbsent(fmt.Sprintf("https://bad%d.example/", i)))
+	}
+	require.NotEmpty(t, c.shards[0].stash)
+	assert.EqualValues(t, keys, c.ApproximatedSize())
+	for i := range keys {
+		require.True(t, c.Test(fmt.Sprintf("https://bad%d.example/", i)))
+	}
+	assert.False(t, c.AddAbsent("https://bad0.example/"), "a listed key is not stored twice")
+
+	var buf bytes.Buffer
+	_, err := c.WriteTo(&buf)
+	require.NoError(t, err)
+	loaded, err := ReadFilter(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, config.FilterCuckoo, loaded.Kind())
+	assert.Equal(t, c.Cap(), loaded.Cap())
+	assert.EqualValues(t, keys, loaded.ApproximatedSize())
+
+	for i := range keys {
+		require.True(t, loaded.Remove(fmt.Sprintf("https://bad%d.example/", i)))
+	}
+	assert.Zero(t, loaded.ApproximatedSize())
+	assert.False(t, loaded.Test("https://bad0.example/"))
+	assert.True(t, c.Test("https://bad0.example/"), "the loaded filter is a copy")
+}
+
+func TestShardedCuckooFalsePositives(t *testing.T) {
+	c := NewShardedCuckoo(100_000, 8)
+	for i := range 100_000 {
+		c.Add(fmt.Sprintf("https://bad%d.example/", i))
+	}
+	positives := 0
+	for i := range 100_000 {
+		if c.Test(fmt.Sprintf("https://clean%d.example/", i)) {
+			positives++
+		}
+	}
+	assert.Less(t, positives, 100, "false positive rate above 0.1%%")
+}
+
+func TestBloomManagerLoadsConfiguredKind(t *testing.T) {
+	cfg := config.GetConfig()
+	path := filepath.Join(t.TempDir(), "bloom.bin")
+	cfg.Cache.BloomPath = path
+	cfg.Cache.ProbabilisticFilter = config.FilterCuckoo
+	t.Cleanup(func() {
+		cfg.Cache.BloomPath = ""
+		cfg.Cache.ProbabilisticFilter = config.FilterBloom
+	})
+
+	m := &BloomManager{}
+	require.NoError(t, m.Rebuild(context.Background(), 100, func(add func(string)) error {
+		add("https://bad.example/")
+		return nil
+	}))
+	bf, err := m.Current()
+	require.NoError(t, err)
+	assert.IsType(t, &ShardedCuckoo{}, bf)
+
+	loaded, err := (&BloomManager{}).Load(path)
+	require.NoError(t, err)
+	assert.True(t, loaded.Test("https://bad.example/"))
+
+	// A filter saved under another setting is rebuilt rather than loaded.
+	cfg.Cache.ProbabilisticFilter = config.FilterBloom
+	_, err = (&BloomManager{}).Load(path)
+	assert.ErrorIs(t, err, ErrInvalidBloomFile)
+}
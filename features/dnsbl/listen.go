@@ -0,0 +1,185 @@
+package dnsbl
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxInflight bounds the UDP queries answered at once; later ones are
+	// dropped and retried by the client.
+	maxInflight = 1024
+
+	// maxTCPConns bounds the open TCP connections; later ones are closed
+	// at once.
+	maxTCPConns = 256
+
+	// tcpIdleTimeout closes TCP connections without a query for this long.
+	tcpIdleTimeout = 10 * time.Second
+)
+
+// Listen starts serving s over UDP and TCP on port in the background. The
+// returned stop function closes both listeners and open TCP connections,
+// and waits for the queries being answered.
+func Listen(s *Server, port int) (stop func(), err error) {
+	addr := ":" + strconv.Itoa(port)
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		log.Error().Err(err).Int("port", port).Msg("Failed to listen for DNSBL over UDP")
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		pc.Close()
+		log.Error().Err(err).Int("port", port).Msg("Failed to listen for DNSBL over TCP")
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Go(func() { s.ServeUDP(ctx, pc) })
+	wg.Go(func() { s.ServeTCP(ctx, ln) })
+	log.Info().Str("address", addr).Str("zone", s.zone).Msg("DNSBL listening")
+
+	return func() {
+		cancel()
+		pc.Close()
+		ln.Close()
+		wg.Wait()
+	}, nil
+}
+
+// ServeUDP answers the queries read from pc until it is closed, then waits
+// for the queries being answered.
+func (s *Server) ServeUDP(ctx context.Context, pc net.PacketConn) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	inflight := make(chan struct{}, maxInflight)
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Error().Err(err).Msg("DNSBL UDP server stopped")
+			}
+			return
+		}
+
+		select {
+		case inflight <- struct{}{}:
+		default:
+			log.Warn().Msg("DNSBL query dropped - too many in flight")
+			continue
+		}
+
+		req := append([]byte(nil), buf[:n]...)
+		wg.Go(func() {
+			defer func() { <-inflight }()
+
+			resp, err := s.Answer(ctx, req)
+			if err != nil {
+				log.Debug().Err(err).Msg("Dropped malformed DNSBL query")
+				return
+			}
+			if len(resp) > maxUDPSize {
+				if resp, err = truncated(resp); err != nil {
+					return
+				}
+			}
+			if _, err := pc.WriteTo(resp, addr); err != nil {
+				log.Debug().Err(err).Msg("Failed to send DNSBL response")
+			}
+		})
+	}
+}
+
+// ServeTCP answers the length-prefixed queries of the connections accepted
+// on ln, at most maxTCPConns at once, until it is closed. It then closes the
+// open connections and waits for them.
+func (s *Server) ServeTCP(ctx context.Context, ln net.Listener) {
+	s.serveTCP(ctx, ln, maxTCPConns)
+}
+
+func (s *Server) serveTCP(ctx context.Context, ln net.Listener, maxConns int) {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		conns = make(map[net.Conn]struct{})
+	)
+	defer func() {
+		// Idle connections would otherwise hold the stop for tcpIdleTimeout.
+		mu.Lock()
+		for conn := range conns {
+			conn.Close()
+		}
+		mu.Unlock()
+		wg.Wait()
+	}()
+
+	open := make(chan struct{}, maxConns)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Error().Err(err).Msg("DNSBL TCP server stopped")
+			}
+			return
+		}
+
+		select {
+		case open <- struct{}{}:
+		default:
+			log.Warn().Str("remote", conn.RemoteAddr().String()).Msg("DNSBL TCP connection closed - too many open")
+			conn.Close()
+			continue
+		}
+
+		mu.Lock()
+		conns[conn] = struct{}{}
+		mu.Unlock()
+		wg.Go(func() {
+			defer func() {
+				mu.Lock()
+				delete(conns, conn)
+				mu.Unlock()
+				<-open
+			}()
+			s.serveConn(ctx, conn)
+		})
+	}
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	var size [2]byte
+	for {
+		conn.SetDeadline(time.Now().Add(tcpIdleTimeout))
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+
+		resp, err := s.Answer(ctx, req)
+		if err != nil {
+			log.Debug().Err(err).Msg("Dropped malformed DNSBL query")
+			return
+		}
+		out := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(resp)), uint16(len(resp)))
+		if _, err := conn.Write(append(out, resp...)); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,339 @@
+// Package dnsbl answers DNS blocklist queries (RFC 5782) against the query
+// core, so mail servers and firewalls can use blacked without HTTP.
+//
+// IPs are queried under the zone with their octets (IPv4) or nibbles (IPv6)
+// reversed, 4.3.2.1.bl.example.com for 1.2.3.4; domain names are queried as
+// they are, evil.example.bl.example.com. Listed names get an A record and a
+// TXT record naming the sources; unlisted ones get NXDOMAIN.
+package dnsbl
+
+import (
+	"blacked/internal/config"
+	"blacked/internal/logger"
+	"blacked/internal/query"
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var (
+	ErrInvalidZone   = errors.New("dnsbl zone must be a domain name")
+	ErrInvalidAnswer = errors.New("dnsbl answer must be an IPv4 address")
+)
+
+const (
+	// queryTimeout bounds the lookup behind one DNS query; resolvers retry
+	// long before it.
+	queryTimeout = 2 * time.Second
+
+	// maxUDPSize is the largest response sent over UDP; clients retry
+	// truncated responses over TCP.
+	maxUDPSize = 512
+
+	// maxTXTLen is the longest character string a TXT record holds.
+	maxTXTLen = 255
+)
+
+// testPoints are the RFC 5782 names every DNSBL lists, or never lists, so
+// clients can check the zone works.
+var testPoints = map[string]bool{
+	"127.0.0.2":        true,
+	"::ffff:127.0.0.2": true,
+	"test":             true,
+	"127.0.0.1":        false,
+	"::ffff:127.0.0.1": false,
+	"invalid":          false,
+}
+
+// Checker looks a host or IP up; implemented by query.QueryService.
+type Checker interface {
+	Hit(ctx context.Context, urlStr string) (*query.QueryResponse, error)
+}
+
+// Server answers DNSBL queries for one zone.
+type Server struct {
+	checker  Checker
+	zone     string // Lower case and fully qualified
+	zoneName dnsmessage.Name
+	soa      dnsmessage.SOAResource
+	answer   [4]byte
+	ttl      uint32
+}
+
+// NewServer creates a Server answering for cfg.Zone from checker.
+func NewServer(checker Checker, cfg config.DNSBLConfig) (*Server, error) {
+	zone := strings.ToLower(strings.Trim(cfg.Zone, "."))
+	if zone == "" {
+		return nil, ErrInvalidZone
+	}
+	zone += "."
+	zoneName, err := dnsmessage.NewName(zone)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidZone, err)
+	}
+	mbox, err := dnsmessage.NewName("hostmaster." + zone)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidZone, err)
+	}
+
+	answer, err := netip.ParseAddr(cfg.Answer)
+	if err != nil || !answer.Is4() {
+		return nil, errors.Join(ErrInvalidAnswer, err)
+	}
+
+	ttl := uint32(cfg.TTL / time.Second)
+	return &Server{
+		checker:  checker,
+		zone:     zone,
+		zoneName: zoneName,
+		soa: dnsmessage.SOAResource{
+			NS:      zoneName,
+			MBox:    mbox,
+			Serial:  1,
+			Refresh: 3600,
+			Retry:   600,
+			Expire:  86400,
+			MinTTL:  ttl,
+		},
+		answer: answer.As4(),
+		ttl:    ttl,
+	}, nil
+}
+
+// Answer returns the response to the DNS message req. Messages too
+// malformed to answer return an error and should be dropped.
+func (s *Server) Answer(ctx context.Context, req []byte) ([]byte, error) {
+	var p dnsmessage.Parser
+	hdr, err := p.Start(req)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Response {
+		return nil, errors.New("dns message is a response")
+	}
+
+	resp := dnsmessage.Header{
+		ID:               hdr.ID,
+		Response:         true,
+		OpCode:           hdr.OpCode,
+		RecursionDesired: hdr.RecursionDesired,
+	}
+
+	q, err := p.Question()
+	switch {
+	case hdr.OpCode != 0:
+		resp.RCode = dnsmessage.RCodeNotImplemented
+		return s.build(resp, nil, nil, nil)
+	case err != nil:
+		resp.RCode = dnsmessage.RCodeFormatError
+		return s.build(resp, nil, nil, nil)
+	}
+
+	subject, inZone := s.subject(q.Name.String())
+	if !inZone || q.Class != dnsmessage.ClassINET {
+		resp.RCode = dnsmessage.RCodeRefused
+		return s.build(resp, &q, nil, nil)
+	}
+	resp.Authoritative = true
+
+	if subject == "" {
+		// The zone apex exists but lists nothing.
+		if q.Type == dnsmessage.TypeSOA || q.Type == dnsmessage.TypeALL {
+			return s.build(resp, &q, s.soaRecord, nil)
+		}
+		return s.build(resp, &q, nil, s.soaRecord)
+	}
+
+	listed, reason, err := s.lookup(ctx, subject)
+	if err != nil {
+		log.Error().Err(err).Str("subject", logger.RedactURL(subject)).Msg("DNSBL lookup failed")
+		resp.RCode = dnsmessage.RCodeServerFailure
+		return s.build(resp, &q, nil, nil)
+	}
+	log.Debug().Str("subject", logger.RedactURL(subject)).Bool("listed", listed).Msg("DNSBL query")
+
+	if !listed {
+		resp.RCode = dnsmessage.RCodeNameError
+		return s.build(resp, &q, nil, s.soaRecord)
+	}
+
+	answers := func(b *dnsmessage.Builder) error {
+		rh := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: s.ttl}
+		if q.Type == dnsmessage.TypeA || q.Type == dnsmessage.TypeALL {
+			if err := b.AResource(rh, dnsmessage.AResource{A: s.answer}); err != nil {
+				return err
+			}
+		}
+		if q.Type == dnsmessage.TypeTXT || q.Type == dnsmessage.TypeALL {
+			if err := b.TXTResource(rh, dnsmessage.TXTResource{TXT: []string{reason}}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if q.Type != dnsmessage.TypeA && q.Type != dnsmessage.TypeTXT && q.Type != dnsmessage.TypeALL {
+		// Listed, but with no record of the queried type.
+		return s.build(resp, &q, nil, s.soaRecord)
+	}
+	return s.build(resp, &q, answers, nil)
+}
+
+// subject returns the IP or domain name a query name asks about, empty for
+// the zone apex, and whether name is in the zone at all.
+func (s *Server) subject(name string) (string, bool) {
+	name = strings.ToLower(name)
+	if name == s.zone {
+		return "", true
+	}
+	prefix, ok := strings.CutSuffix(name, "."+s.zone)
+	if !ok || prefix == "" {
+		return "", false
+	}
+
+	labels := strings.Split(prefix, ".")
+	if ip, ok := reversedIP(labels); ok {
+		return ip.String(), true
+	}
+	return prefix, true
+}
+
+// reversedIP reads labels as the reversed octets of an IPv4 address or the
+// reversed nibbles of an IPv6 address.
+func reversedIP(labels []string) (netip.Addr, bool) {
+	switch len(labels) {
+	case 4:
+		octets := slices.Clone(labels)
+		slices.Reverse(octets)
+		ip, err := netip.ParseAddr(strings.Join(octets, "."))
+		return ip, err == nil && ip.Is4()
+	case 32:
+		var hexIP strings.Builder
+		for i := len(labels) - 1; i >= 0; i-- {
+			if len(labels[i]) != 1 {
+				return netip.Addr{}, false
+			}
+			hexIP.WriteString(labels[i])
+		}
+		b, err := hex.DecodeString(hexIP.String())
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		return netip.AddrFrom16([16]byte(b)), true
+	}
+	return netip.Addr{}, false
+}
+
+// lookup reports whether subject is listed and why. Results the policy
+// allows are not listed.
+func (s *Server) lookup(ctx context.Context, subject string) (bool, string, error) {
+	if listed, ok := testPoints[subject]; ok {
+		return listed, "RFC 5782 test point", nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	target := subject
+	if ip, err := netip.ParseAddr(subject); err == nil && ip.Is6() {
+		target = "[" + subject + "]"
+	}
+	res, err := s.checker.Hit(ctx, target)
+	if err != nil {
+		return false, "", err
+	}
+	if !res.Blocked || res.Action == query.ActionAllow {
+		return false, "", nil
+	}
+	return true, reason(res), nil
+}
+
+// reason describes a blocked result for the TXT record.
+func reason(res *query.QueryResponse) string {
+	var sources []string
+	for _, m := range res.Matches {
+		if !slices.Contains(sources, m.SourceID) {
+			sources = append(sources, m.SourceID)
+		}
+	}
+
+	text := "Listed"
+	if len(sources) > 0 {
+		text += " by " + strings.Join(sources, ", ")
+	}
+	if res.MatchType != "" {
+		text += " (" + res.MatchType + " match)"
+	}
+	if len(text) > maxTXTLen {
+		text = text[:maxTXTLen]
+	}
+	return text
+}
+
+func (s *Server) soaRecord(b *dnsmessage.Builder) error {
+	return b.SOAResource(dnsmessage.ResourceHeader{
+		Name:  s.zoneName,
+		Class: dnsmessage.ClassINET,
+		TTL:   s.ttl,
+	}, s.soa)
+}
+
+// build packs a response to q with the records answers and authority add.
+func (s *Server) build(hdr dnsmessage.Header, q *dnsmessage.Question, answers, authority func(*dnsmessage.Builder) error) ([]byte, error) {
+	b := dnsmessage.NewBuilder(make([]byte, 0, maxUDPSize), hdr)
+	b.EnableCompression()
+
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if q != nil {
+		if err := b.Question(*q); err != nil {
+			return nil, err
+		}
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	if answers != nil {
+		if err := answers(&b); err != nil {
+			return nil, err
+		}
+	}
+	if err := b.StartAuthorities(); err != nil {
+		return nil, err
+	}
+	if authority != nil {
+		if err := authority(&b); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+// truncated returns the header and question of resp with the TC bit set,
+// for a response too large for UDP.
+func truncated(resp []byte) ([]byte, error) {
+	var p dnsmessage.Parser
+	hdr, err := p.Start(resp)
+	if err != nil {
+		return nil, err
+	}
+	hdr.Truncated = true
+
+	b := dnsmessage.NewBuilder(make([]byte, 0, maxUDPSize), hdr)
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if q, err := p.Question(); err == nil {
+		if err := b.Question(q); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
@@ -0,0 +1,204 @@
+package dnsbl
+
+import (
+	"blacked/internal/config"
+	"blacked/internal/query"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeChecker lists the hosts and IPs it holds.
+type fakeChecker map[string]*query.QueryResponse
+
+func (f fakeChecker) Hit(_ context.Context, urlStr string) (*query.QueryResponse, error) {
+	if urlStr == "broken.example" {
+		return nil, errors.New("database is gone")
+	}
+	if res, ok := f[urlStr]; ok {
+		return res, nil
+	}
+	return &query.QueryResponse{URL: urlStr}, nil
+}
+
+func newTestServer(t *testing.T) *Server {
+	checker := fakeChecker{
+		"evil.example": {
+			Blocked:   true,
+			MatchType: "domain",
+			Matches: []query.Match{
+				{SourceID: "oisd-big", Type: "domain"},
+				{SourceID: "urlhaus-online", Type: "domain"},
+				{SourceID: "oisd-big", Type: "host"},
+			},
+		},
+		"203.0.113.7":   {Blocked: true, MatchType: "ip", Matches: []query.Match{{SourceID: "feodotracker-ipblocklist", Type: "ip"}}},
+		"[2001:db8::1]": {Blocked: true, MatchType: "ip"},
+		"allowed.example": {
+			Blocked: true,
+			Action:  query.ActionAllow,
+		},
+	}
+	s, err := NewServer(checker, config.DNSBLConfig{Zone: "BL.Example.com.", Answer: "127.0.0.2", TTL: 5 * time.Minute})
+	require.NoError(t, err)
+	return s
+}
+
+// resolver sends the queries of a Go resolver to a UDP server answering from s.
+func resolver(t *testing.T, s *Server) *net.Resolver {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.ServeUDP(ctx, pc)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		pc.Close()
+		<-done
+	})
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", pc.LocalAddr().String())
+		},
+	}
+}
+
+func TestLookupOverUDP(t *testing.T) {
+	r := resolver(t, newTestServer(t))
+	ctx := context.Background()
+
+	for _, name := range []string{
+		"evil.example.bl.example.com",
+		"7.113.0.203.bl.example.com",
+		"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.bl.example.com",
+		"2.0.0.127.bl.example.com",
+		"test.bl.example.com",
+	} {
+		addrs, err := r.LookupHost(ctx, name)
+		require.NoError(t, err, name)
+		assert.Equal(t, []string{"127.0.0.2"}, addrs, name)
+	}
+
+	txt, err := r.LookupTXT(ctx, "evil.example.bl.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Listed by oisd-big, urlhaus-online (domain match)"}, txt)
+
+	for _, name := range []string{
+		"good.example.bl.example.com",
+		"allowed.example.bl.example.com",
+		"1.0.0.127.bl.example.com",
+		"invalid.bl.example.com",
+	} {
+		_, err := r.LookupHost(ctx, name)
+		var dnsErr *net.DNSError
+		require.ErrorAs(t, err, &dnsErr, name)
+		assert.True(t, dnsErr.IsNotFound, name)
+	}
+}
+
+func TestServeTCPLimitsConnections(t *testing.T) {
+	s := newTestServer(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	done := make(chan struct{})
+	go func() {
+		s.serveTCP(context.Background(), ln, 1)
+		close(done)
+	}()
+
+	req, err := (&dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 7},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName("evil.example.bl.example.com."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+	}).Pack()
+	require.NoError(t, err)
+
+	first, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer first.Close()
+	_, err = first.Write(binary.BigEndian.AppendUint16(nil, uint16(len(req))))
+	require.NoError(t, err)
+	_, err = first.Write(req)
+	require.NoError(t, err)
+	var size [2]byte
+	_, err = io.ReadFull(first, size[:])
+	require.NoError(t, err, "the first connection is served")
+
+	second, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer second.Close()
+	second.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err = second.Read(size[:])
+	assert.ErrorIs(t, err, io.EOF, "connections past the limit are closed")
+
+	// Closing the listener closes the idle first connection too.
+	ln.Close()
+	select {
+	case <-done:
+	case <-time.After(tcpIdleTimeout / 2):
+		t.Fatal("ServeTCP waited for an idle connection")
+	}
+}
+
+func ask(t *testing.T, s *Server, name string, qtype dnsmessage.Type) dnsmessage.Message {
+	req, err := (&dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 42, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET}},
+	}).Pack()
+	require.NoError(t, err)
+
+	resp, err := s.Answer(context.Background(), req)
+	require.NoError(t, err)
+	var msg dnsmessage.Message
+	require.NoError(t, msg.Unpack(resp))
+	assert.Equal(t, uint16(42), msg.Header.ID)
+	return msg
+}
+
+func TestAnswer(t *testing.T) {
+	s := newTestServer(t)
+
+	msg := ask(t, s, "evil.example.elsewhere.com.", dnsmessage.TypeA)
+	assert.Equal(t, dnsmessage.RCodeRefused, msg.Header.RCode, "names outside the zone")
+
+	msg = ask(t, s, "broken.example.bl.example.com.", dnsmessage.TypeA)
+	assert.Equal(t, dnsmessage.RCodeServerFailure, msg.Header.RCode)
+
+	msg = ask(t, s, "good.example.bl.example.com.", dnsmessage.TypeA)
+	assert.Equal(t, dnsmessage.RCodeNameError, msg.Header.RCode)
+	assert.True(t, msg.Header.Authoritative)
+	require.Len(t, msg.Authorities, 1, "negative answers carry the SOA for caching")
+	assert.Equal(t, uint32(300), msg.Authorities[0].Body.(*dnsmessage.SOAResource).MinTTL)
+
+	msg = ask(t, s, "evil.example.bl.example.com.", dnsmessage.TypeAAAA)
+	assert.Equal(t, dnsmessage.RCodeSuccess, msg.Header.RCode, "listed names exist for every type")
+	assert.Empty(t, msg.Answers)
+
+	msg = ask(t, s, "evil.example.bl.example.com.", dnsmessage.TypeA)
+	require.Len(t, msg.Answers, 1)
+	assert.Equal(t, uint32(300), msg.Answers[0].Header.TTL)
+
+	msg = ask(t, s, "bl.example.com.", dnsmessage.TypeSOA)
+	assert.Equal(t, dnsmessage.RCodeSuccess, msg.Header.RCode)
+	require.Len(t, msg.Answers, 1)
+}
+
+func TestNewServerValidates(t *testing.T) {
+	_, err := NewServer(fakeChecker{}, config.DNSBLConfig{Answer: "127.0.0.2"})
+	assert.ErrorIs(t, err, ErrInvalidZone)
+	_, err = NewServer(fakeChecker{}, config.DNSBLConfig{Zone: "bl.example.com", Answer: "::1"})
+	assert.ErrorIs(t, err, ErrInvalidAnswer)
+}
@@ -61,6 +61,7 @@ func setupMinimalServer(t *testing.T, bm *bloom.BloomManager) *httptest.Server {
 	g.GET("/hit", handler.Hit)
 	g.POST("/bulk-check", handler.BulkCheck)
 	g.POST("/bulk-hit", handler.BulkHit)
+	e.GET("/check", handler.EdgeCheck)
 
 	return httptest.NewServer(e)
 }
@@ -429,4 +430,32 @@ func TestBlacked_BloomE2E(t *testing.T) {
 
 		fmt.Println("  ✓ BulkHit: 2 URLs, 1 blocked + 1 clean with confidence/level")
 	})
+
+	// ------------------------------------------------------------------
+	// 15. Edge check: verdict as status code and headers only
+	// ------------------------------------------------------------------
+	t.Run("EdgeCheck", func(t *testing.T) {
+		bm := bloom.NewBloomManager(1000)
+		mustPopulate(t, bm, "oisd", "https://evil.com")
+		srv := setupMinimalServer(t, bm)
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/check?url=" + url.QueryEscape("https://sub.evil.com/path"))
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+		require.Equal(t, "blocked", string(body))
+		require.Equal(t, "domain", resp.Header.Get(v2.HeaderMatchType))
+		require.NotEmpty(t, resp.Header.Get(v2.HeaderScore))
+		require.NotEmpty(t, resp.Header.Get(v2.HeaderLevel))
+
+		resp, err = http.Get(srv.URL + "/check?url=" + url.QueryEscape("https://google.com"))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, 204, resp.StatusCode)
+		require.Empty(t, resp.Header.Get(v2.HeaderMatchType))
+		fmt.Println("  ✓ EdgeCheck: 200 + headers when blocked, 204 when clean")
+	})
 }
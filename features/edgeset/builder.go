@@ -0,0 +1,222 @@
+package edgeset
+
+import (
+	"blacked/features/bloom"
+	"blacked/features/entries"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Builder accumulates entries and writes them as an edge dataset.
+// It is not safe for concurrent use.
+type Builder struct {
+	sources   []string
+	sourceIdx map[string]int
+	records   map[string][]uint32 // record key → sorted source indexes
+	skipped   int
+}
+
+// BuildStats describes a written dataset.
+type BuildStats struct {
+	Path     string        `json:"path"`
+	Entries  int           `json:"entries"`
+	Keys     int           `json:"keys"`
+	Sources  int           `json:"sources"`
+	Skipped  int           `json:"skipped"`
+	Bytes    int64         `json:"bytes"`
+	Duration time.Duration `json:"duration"`
+}
+
+// NewBuilder creates an empty Builder.
+func NewBuilder() *Builder {
+	return &Builder{
+		sourceIdx: make(map[string]int),
+		records:   make(map[string][]uint32),
+	}
+}
+
+// Add files an entry under its single bloom target. Entries without a
+// usable key are counted as skipped.
+func (b *Builder) Add(e *entries.Entry) {
+	ip := ""
+	if host := strings.TrimSpace(e.Host); host != "" && net.ParseIP(host) != nil {
+		ip = host
+	}
+	keys := bloom.EntryToKeys(bloom.Entry{
+		SourceID: e.Source,
+		Domain:   e.Domain,
+		Host:     e.Host,
+		Path:     e.Path,
+		Query:    e.RawQuery,
+		IP:       ip,
+	})
+
+	bt, key := bloom.TargetFor(keys)
+	rk, ok := recordKey(bt, key)
+	if key == "" || !ok {
+		b.skipped++
+		return
+	}
+
+	idx, ok := b.sourceIdx[e.Source]
+	if !ok {
+		idx = len(b.sources)
+		b.sources = append(b.sources, e.Source)
+		b.sourceIdx[e.Source] = idx
+	}
+
+	srcs := b.records[string(rk)]
+	if !slices.Contains(srcs, uint32(idx)) {
+		b.records[string(rk)] = append(srcs, uint32(idx))
+	}
+}
+
+// Len returns the number of distinct record keys added so far.
+func (b *Builder) Len() int {
+	return len(b.records)
+}
+
+// Skipped returns the number of entries that had no usable key.
+func (b *Builder) Skipped() int {
+	return b.skipped
+}
+
+// WriteFile writes the dataset to path atomically: it is written to a
+// temporary file in the same directory and renamed over path.
+func (b *Builder) WriteFile(path string) (int64, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	n, err := b.writeTo(tmp)
+	if err != nil {
+		_ = tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (b *Builder) writeTo(f *os.File) (int64, error) {
+	keys := make([]string, 0, len(b.records))
+	for k := range b.records {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	// Sources section
+	var sources bytes.Buffer
+	for _, s := range b.sources {
+		var l [2]byte
+		binary.LittleEndian.PutUint16(l[:], uint16(len(s)))
+		sources.Write(l[:])
+		sources.WriteString(s)
+	}
+
+	// Bloom section
+	m, k := bloomSize(len(keys))
+	bits := make([]byte, m/8)
+	for _, key := range keys {
+		h1, h2 := bloomHashes([]byte(key))
+		for i := range uint64(k) {
+			bit := (h1 + i*h2) % m
+			bits[bit/8] |= 1 << (bit % 8)
+		}
+	}
+
+	// Data section and index
+	var data bytes.Buffer
+	index := make([]byte, 8*len(keys))
+	var tmp [binary.MaxVarintLen64]byte
+	for i, key := range keys {
+		binary.LittleEndian.PutUint64(index[i*8:], uint64(data.Len()))
+		data.Write(tmp[:binary.PutUvarint(tmp[:], uint64(len(key)))])
+		data.WriteString(key)
+		srcs := b.records[key]
+		slices.Sort(srcs)
+		data.Write(tmp[:binary.PutUvarint(tmp[:], uint64(len(srcs)))])
+		for _, s := range srcs {
+			data.Write(tmp[:binary.PutUvarint(tmp[:], uint64(s))])
+		}
+	}
+
+	h := header{
+		Version:     version,
+		CreatedAt:   time.Now().UnixNano(),
+		KeyCount:    uint64(len(keys)),
+		BloomBits:   m,
+		BloomK:      k,
+		SourceCount: uint32(len(b.sources)),
+	}
+	h.SourcesOff = headerSize
+	h.BloomOff = align8(h.SourcesOff + uint64(sources.Len()))
+	h.IndexOff = align8(h.BloomOff + uint64(len(bits)))
+	h.DataOff = h.IndexOff + uint64(len(index))
+	h.DataLen = uint64(data.Len())
+
+	w := bufio.NewWriterSize(f, 1<<20)
+	var written int64
+	write := func(p []byte) error {
+		n, err := w.Write(p)
+		written += int64(n)
+		return err
+	}
+	pad := func(to uint64) error {
+		return write(make([]byte, to-uint64(written)))
+	}
+
+	for _, step := range []func() error{
+		func() error { return write(h.marshal()) },
+		func() error { return write(sources.Bytes()) },
+		func() error { return pad(h.BloomOff) },
+		func() error { return write(bits) },
+		func() error { return pad(h.IndexOff) },
+		func() error { return write(index) },
+		func() error { return write(data.Bytes()) },
+		w.Flush,
+	} {
+		if err := step(); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// bloomSize returns the bit count (a multiple of 64) and probe count for n keys.
+func bloomSize(n int) (uint64, uint32) {
+	if n < 1 {
+		n = 1
+	}
+	m := math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	bits := max(uint64(m+63)/64*64, 64)
+	k := uint32(max(1, math.Round(float64(bits)/float64(n)*math.Ln2)))
+	return bits, k
+}
+
+func align8(n uint64) uint64 {
+	return (n + 7) &^ 7
+}
@@ -0,0 +1,64 @@
+package edgeset
+
+import (
+	"blacked/features/entries"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrCompileScan  = errors.New("failed to read entries for edge dataset")
+	ErrCompileWrite = errors.New("failed to write edge dataset")
+)
+
+// EntryStreamer is the repository method Compile needs; implemented by
+// the entries SQLite repository.
+type EntryStreamer interface {
+	StreamEntriesActivatedSince(ctx context.Context, source string, since int64, fn func(*entries.Entry) error) error
+}
+
+// Compile streams every active entry from repo and writes the dataset to path.
+func Compile(ctx context.Context, repo EntryStreamer, path string) (*BuildStats, error) {
+	start := time.Now()
+	b := NewBuilder()
+
+	count := 0
+	err := repo.StreamEntriesActivatedSince(ctx, "", 0, func(e *entries.Entry) error {
+		b.Add(e)
+		count++
+		return nil
+	})
+	if err != nil {
+		log.Err(err).Str("path", path).Msg("Failed to stream entries for edge dataset")
+		return nil, ErrCompileScan
+	}
+
+	size, err := b.WriteFile(path)
+	if err != nil {
+		log.Err(err).Str("path", path).Msg("Failed to write edge dataset")
+		return nil, ErrCompileWrite
+	}
+
+	stats := &BuildStats{
+		Path:     path,
+		Entries:  count,
+		Keys:     b.Len(),
+		Sources:  len(b.sources),
+		Skipped:  b.Skipped(),
+		Bytes:    size,
+		Duration: time.Since(start),
+	}
+
+	log.Info().
+		Str("path", path).
+		Int("entries", stats.Entries).
+		Int("keys", stats.Keys).
+		Int64("bytes", stats.Bytes).
+		Dur("duration", stats.Duration).
+		Msg("Edge dataset compiled")
+
+	return stats, nil
+}
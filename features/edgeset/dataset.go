@@ -0,0 +1,235 @@
+package edgeset
+
+import (
+	"blacked/features/bloom"
+	"blacked/internal/query"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Dataset is a memory-mapped, read-only edge dataset.
+// It implements query.BloomChecker with exact (index-confirmed) matches.
+type Dataset struct {
+	path    string
+	data    []byte
+	unmap   func() error
+	hdr     *header
+	sources []string
+	bits    []byte
+	index   []byte
+	records []byte
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// Info summarises an open dataset.
+type Info struct {
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"created_at"`
+	Keys      uint64    `json:"keys"`
+	Sources   []string  `json:"sources"`
+	Bytes     int       `json:"bytes"`
+}
+
+// Open memory-maps the dataset at path and validates its header.
+func Open(path string) (*Dataset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, unmap, err := mapFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	ds, err := newDataset(path, data)
+	if err != nil {
+		_ = unmap()
+		return nil, err
+	}
+	ds.unmap = unmap
+	return ds, nil
+}
+
+func newDataset(path string, data []byte) (*Dataset, error) {
+	hdr, err := unmarshalHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]string, 0, hdr.SourceCount)
+	pos := hdr.SourcesOff
+	for range hdr.SourceCount {
+		if pos+2 > hdr.BloomOff {
+			return nil, ErrInvalidDataset
+		}
+		n := uint64(binary.LittleEndian.Uint16(data[pos:]))
+		pos += 2
+		if pos+n > hdr.BloomOff {
+			return nil, ErrInvalidDataset
+		}
+		sources = append(sources, string(data[pos:pos+n]))
+		pos += n
+	}
+
+	return &Dataset{
+		path:    path,
+		data:    data,
+		hdr:     hdr,
+		sources: sources,
+		bits:    data[hdr.BloomOff : hdr.BloomOff+hdr.BloomBits/8],
+		index:   data[hdr.IndexOff : hdr.IndexOff+hdr.KeyCount*8],
+		records: data[hdr.DataOff : hdr.DataOff+hdr.DataLen],
+	}, nil
+}
+
+// Close unmaps the dataset. Lookups after Close return ErrDatasetClosed.
+func (d *Dataset) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	if d.unmap != nil {
+		return d.unmap()
+	}
+	return nil
+}
+
+// Info returns the dataset header details.
+func (d *Dataset) Info() Info {
+	return Info{
+		Path:      d.path,
+		CreatedAt: time.Unix(0, d.hdr.CreatedAt).UTC(),
+		Keys:      d.hdr.KeyCount,
+		Sources:   d.sources,
+		Bytes:     len(d.data),
+	}
+}
+
+// Lookup returns the sources listing key under bloom type t.
+func (d *Dataset) Lookup(t bloom.BloomType, key string) ([]string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return nil, ErrDatasetClosed
+	}
+	rk, ok := recordKey(t, key)
+	if !ok {
+		return nil, nil
+	}
+	return d.lookup(rk)
+}
+
+// Check implements query.BloomChecker: the URL's check chain is tested
+// against the bloom section and every bloom positive is confirmed in the index.
+func (d *Dataset) Check(urlStr string) (bool, []query.Match, error) {
+	keys, err := bloom.ParseURL(urlStr)
+	if err != nil {
+		return false, nil, fmt.Errorf("parse url: %w", err)
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return false, nil, ErrDatasetClosed
+	}
+
+	var matches []query.Match
+	for _, ck := range keys.GenerateCheckKeys() {
+		rk, ok := recordKey(ck.Type, ck.Key)
+		if !ok {
+			continue
+		}
+		sources, err := d.lookup(rk)
+		if err != nil {
+			return false, nil, err
+		}
+		for _, s := range sources {
+			matches = append(matches, query.Match{
+				SourceID: s,
+				Type:     string(ck.Type),
+				Key:      ck.Key,
+			})
+		}
+	}
+	return len(matches) > 0, matches, nil
+}
+
+func (d *Dataset) lookup(rk []byte) ([]string, error) {
+	if !d.mayContain(rk) {
+		return nil, nil
+	}
+
+	n := int(d.hdr.KeyCount)
+	var corrupt bool
+	i := sort.Search(n, func(i int) bool {
+		key, _, ok := d.record(i)
+		if !ok {
+			corrupt = true
+			return true
+		}
+		return bytes.Compare(key, rk) >= 0
+	})
+	if corrupt {
+		return nil, ErrInvalidDataset
+	}
+	if i >= n {
+		return nil, nil
+	}
+	key, rest, _ := d.record(i)
+	if !bytes.Equal(key, rk) {
+		return nil, nil
+	}
+
+	count, w := binary.Uvarint(rest)
+	if w <= 0 {
+		return nil, ErrInvalidDataset
+	}
+	rest = rest[w:]
+	sources := make([]string, 0, count)
+	for range count {
+		idx, w := binary.Uvarint(rest)
+		if w <= 0 || idx >= uint64(len(d.sources)) {
+			return nil, ErrInvalidDataset
+		}
+		rest = rest[w:]
+		sources = append(sources, d.sources[idx])
+	}
+	return sources, nil
+}
+
+// record returns the key of record i and the bytes following it.
+func (d *Dataset) record(i int) (key, rest []byte, ok bool) {
+	off := binary.LittleEndian.Uint64(d.index[i*8:])
+	if off >= uint64(len(d.records)) {
+		return nil, nil, false
+	}
+	r := d.records[off:]
+	l, w := binary.Uvarint(r)
+	if w <= 0 || uint64(len(r)-w) < l {
+		return nil, nil, false
+	}
+	return r[w : w+int(l)], r[w+int(l):], true
+}
+
+func (d *Dataset) mayContain(rk []byte) bool {
+	h1, h2 := bloomHashes(rk)
+	m := d.hdr.BloomBits
+	for i := range uint64(d.hdr.BloomK) {
+		bit := (h1 + i*h2) % m
+		if d.bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
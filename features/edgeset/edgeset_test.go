@@ -0,0 +1,125 @@
+package edgeset
+
+import (
+	"blacked/features/bloom"
+	"blacked/features/entries"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubStreamer []*entries.Entry
+
+func (s stubStreamer) StreamEntriesActivatedSince(ctx context.Context, source string, since int64, fn func(*entries.Entry) error) error {
+	for _, e := range s {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newEntry(t *testing.T, source, rawURL string) *entries.Entry {
+	t.Helper()
+	e := entries.NewEntry().WithSource(source)
+	require.NoError(t, e.SetURL(rawURL))
+	return e
+}
+
+func TestCompileAndQuery(t *testing.T) {
+	repo := stubStreamer{
+		newEntry(t, "oisd", "https://malicious.com"),
+		newEntry(t, "urlhaus", "https://malicious.com"),
+		newEntry(t, "openphish", "https://sub.phish.net/login"),
+		newEntry(t, "urlhaus", "http://evil.org/payload.exe"),
+		newEntry(t, "urlhaus", "http://192.168.1.1:8080/malware"),
+	}
+
+	path := filepath.Join(t.TempDir(), "edge.dat")
+	stats, err := Compile(context.Background(), repo, path)
+	require.NoError(t, err)
+	assert.Equal(t, 5, stats.Entries)
+	assert.Equal(t, 4, stats.Keys)
+	assert.Equal(t, 3, stats.Sources)
+
+	ds, err := Open(path)
+	require.NoError(t, err)
+	defer ds.Close()
+
+	info := ds.Info()
+	assert.Equal(t, uint64(4), info.Keys)
+	assert.ElementsMatch(t, []string{"oisd", "urlhaus", "openphish"}, info.Sources)
+
+	tests := []struct {
+		url      string
+		likely   bool
+		wantType string
+		sources  []string
+	}{
+		{"https://malicious.com/any/path", true, "domain", []string{"oisd", "urlhaus"}},
+		{"https://sub.phish.net/login/step2", true, "host_path", []string{"openphish"}},
+		{"https://cdn.example.com/payload.exe", true, "file", []string{"urlhaus"}},
+		{"http://192.168.1.1/other", true, "ip", []string{"urlhaus"}},
+		{"https://safe.example.com/", false, "", nil},
+		{"https://sub.phish.net/other", false, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			likely, matches, err := ds.Check(tt.url)
+			require.NoError(t, err)
+			assert.Equal(t, tt.likely, likely)
+
+			var got []string
+			for _, m := range matches {
+				assert.Equal(t, tt.wantType, m.Type)
+				got = append(got, m.SourceID)
+			}
+			assert.ElementsMatch(t, tt.sources, got)
+		})
+	}
+
+	sources, err := ds.Lookup(bloom.BloomDomain, "malicious.com")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"oisd", "urlhaus"}, sources)
+
+	_, _, err = ds.Check("")
+	assert.ErrorIs(t, err, bloom.ErrInvalidURL)
+
+	require.NoError(t, ds.Close())
+	_, _, err = ds.Check("https://malicious.com")
+	assert.ErrorIs(t, err, ErrDatasetClosed)
+}
+
+func TestCompileEmptyDataset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "edge.dat")
+	_, err := Compile(context.Background(), stubStreamer{}, path)
+	require.NoError(t, err)
+
+	ds, err := Open(path)
+	require.NoError(t, err)
+	defer ds.Close()
+
+	likely, matches, err := ds.Check("https://malicious.com")
+	require.NoError(t, err)
+	assert.False(t, likely)
+	assert.Empty(t, matches)
+}
+
+func TestOpenRejectsInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	short := filepath.Join(dir, "short.dat")
+	require.NoError(t, os.WriteFile(short, []byte("BLKEDGE1"), 0o644))
+	_, err := Open(short)
+	assert.ErrorIs(t, err, ErrInvalidDataset)
+
+	garbage := filepath.Join(dir, "garbage.dat")
+	require.NoError(t, os.WriteFile(garbage, make([]byte, 4*headerSize), 0o644))
+	_, err = Open(garbage)
+	assert.ErrorIs(t, err, ErrInvalidDataset)
+}
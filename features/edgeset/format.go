@@ -0,0 +1,145 @@
+// Package edgeset compiles the active blacklist into a compact read-only
+// artifact for edge nodes, and queries it through a memory map without
+// SQLite or Badger.
+//
+// Layout (little endian):
+//
+//	header   magic "BLKEDGE1", version, created_at, counts and section offsets
+//	sources  uint16 length-prefixed source names, indexed by position
+//	bloom    m-bit filter over every record key (k double-hashed probes)
+//	index    uint64 offset of every record in data, sorted by record key
+//	data     records: uvarint key length, key, uvarint source count, uvarint source indexes
+//
+// A record key is one type byte followed by the bloom key, so each entry is
+// filed under the same single bloom type the BloomManager would use.
+package edgeset
+
+import (
+	"blacked/features/bloom"
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+)
+
+var (
+	ErrInvalidDataset     = errors.New("invalid edge dataset")
+	ErrUnsupportedVersion = errors.New("unsupported edge dataset version")
+	ErrDatasetClosed      = errors.New("edge dataset is closed")
+)
+
+const (
+	magic      = "BLKEDGE1"
+	version    = uint32(1)
+	headerSize = 88
+
+	// falsePositiveRate sizes the bloom filter; misses rarely touch the index.
+	falsePositiveRate = 0.01
+)
+
+// typeCodes maps each bloom type to the byte prefix of its record keys.
+// Codes are part of the file format and must never be renumbered.
+var typeCodes = map[bloom.BloomType]byte{
+	bloom.BloomDomain:   1,
+	bloom.BloomHost:     2,
+	bloom.BloomHostPath: 3,
+	bloom.BloomPath:     4,
+	bloom.BloomQuery:    5,
+	bloom.BloomFile:     6,
+	bloom.BloomFullURL:  7,
+	bloom.BloomLogin:    8,
+	bloom.BloomIP:       9,
+}
+
+// header is the fixed-size file header.
+type header struct {
+	Version     uint32
+	CreatedAt   int64 // unix nanos
+	KeyCount    uint64
+	BloomBits   uint64
+	BloomK      uint32
+	SourceCount uint32
+	SourcesOff  uint64
+	BloomOff    uint64
+	IndexOff    uint64
+	DataOff     uint64
+	DataLen     uint64
+}
+
+func (h *header) marshal() []byte {
+	b := make([]byte, headerSize)
+	copy(b, magic)
+	le := binary.LittleEndian
+	le.PutUint32(b[8:], h.Version)
+	le.PutUint32(b[12:], 0) // flags, reserved
+	le.PutUint64(b[16:], uint64(h.CreatedAt))
+	le.PutUint64(b[24:], h.KeyCount)
+	le.PutUint64(b[32:], h.BloomBits)
+	le.PutUint32(b[40:], h.BloomK)
+	le.PutUint32(b[44:], h.SourceCount)
+	le.PutUint64(b[48:], h.SourcesOff)
+	le.PutUint64(b[56:], h.BloomOff)
+	le.PutUint64(b[64:], h.IndexOff)
+	le.PutUint64(b[72:], h.DataOff)
+	le.PutUint64(b[80:], h.DataLen)
+	return b
+}
+
+func unmarshalHeader(b []byte) (*header, error) {
+	if len(b) < headerSize || string(b[:8]) != magic {
+		return nil, ErrInvalidDataset
+	}
+	le := binary.LittleEndian
+	h := &header{
+		Version:     le.Uint32(b[8:]),
+		CreatedAt:   int64(le.Uint64(b[16:])),
+		KeyCount:    le.Uint64(b[24:]),
+		BloomBits:   le.Uint64(b[32:]),
+		BloomK:      le.Uint32(b[40:]),
+		SourceCount: le.Uint32(b[44:]),
+		SourcesOff:  le.Uint64(b[48:]),
+		BloomOff:    le.Uint64(b[56:]),
+		IndexOff:    le.Uint64(b[64:]),
+		DataOff:     le.Uint64(b[72:]),
+		DataLen:     le.Uint64(b[80:]),
+	}
+	if h.Version != version {
+		return nil, ErrUnsupportedVersion
+	}
+
+	size := uint64(len(b))
+	if h.KeyCount > size/8 || h.BloomBits > size*8 || h.DataLen > size ||
+		h.BloomBits == 0 || h.BloomBits%8 != 0 || h.BloomK == 0 ||
+		h.BloomOff+h.BloomBits/8 > size ||
+		h.IndexOff+h.KeyCount*8 > size ||
+		h.DataOff+h.DataLen > size ||
+		h.SourcesOff > h.BloomOff {
+		return nil, ErrInvalidDataset
+	}
+	return h, nil
+}
+
+// recordKey builds the sorted-index key for a bloom type and key.
+func recordKey(t bloom.BloomType, key string) ([]byte, bool) {
+	code, ok := typeCodes[t]
+	if !ok {
+		return nil, false
+	}
+	rk := make([]byte, 0, len(key)+1)
+	rk = append(rk, code)
+	rk = append(rk, key...)
+	return rk, true
+}
+
+// bloomHashes returns the two base hashes used for double hashing.
+func bloomHashes(key []byte) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write(key)
+	h1 := h.Sum64()
+
+	// splitmix64 finaliser for an independent second hash
+	h2 := h1 + 0x9e3779b97f4a7c15
+	h2 = (h2 ^ (h2 >> 30)) * 0xbf58476d1ce4e5b9
+	h2 = (h2 ^ (h2 >> 27)) * 0x94d049bb133111eb
+	h2 ^= h2 >> 31
+	return h1, h2 | 1
+}
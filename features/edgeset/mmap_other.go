@@ -0,0 +1,20 @@
+//go:build !unix
+
+package edgeset
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads f into memory on platforms without mmap support.
+func mapFile(f *os.File) ([]byte, func() error, error) {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) < headerSize {
+		return nil, nil, ErrInvalidDataset
+	}
+	return data, func() error { return nil }, nil
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package edgeset
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps f read-only into memory.
+func mapFile(f *os.File) ([]byte, func() error, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if fi.Size() < headerSize {
+		return nil, nil, ErrInvalidDataset
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
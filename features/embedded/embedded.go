@@ -0,0 +1,210 @@
+// Package embedded runs the blacklist engine inside another Go program
+// instead of as a daemon: Open sets up the SQLite store, the cache and bloom
+// filters, and optionally the provider scheduler, and the returned Checker
+// answers lookups through the same query core as /api/v1/hit.
+//
+// The engine is built on process-wide singletons, so a process opens at
+// most one Checker, once.
+package embedded
+
+import (
+	"blacked/features/allowlist"
+	"blacked/features/cache"
+	"blacked/features/entry_collector"
+	"blacked/features/providers"
+	v2 "blacked/features/web/handlers/v2"
+	"blacked/internal/config"
+	"blacked/internal/db"
+	"blacked/internal/query"
+	"blacked/internal/runner"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrAlreadyOpened = errors.New("an embedded checker was already opened in this process")
+	ErrClosed        = errors.New("embedded checker is closed")
+	ErrInitialSync   = errors.New("failed to build the cache and bloom filters from the database")
+)
+
+// shutdownTimeout bounds how long Close waits for running feed refreshes.
+const shutdownTimeout = 10 * time.Second
+
+// opened is set by the first Open, successful or not.
+var opened atomic.Bool
+
+// Options configures Open.
+type Options struct {
+	// ConfigFile is the TOML configuration, as for the daemon. Empty reads
+	// $CONFIG_FILE or .env.toml from the working directory, and missing files
+	// leave the defaults.
+	ConfigFile string
+
+	// Dir holds blacked.db and the stored feed responses; empty is the
+	// working directory. It is created when missing.
+	Dir string
+
+	// Refresh fetches missing or stale feeds in the background after Open,
+	// then keeps every enabled provider current on its cron schedule.
+	// Without it the Checker serves the database as it is.
+	Refresh bool
+}
+
+// Checker looks URLs up in the embedded engine. It is safe for concurrent use.
+type Checker struct {
+	svc     *query.QueryService
+	pond    *entry_collector.PondCollector
+	refresh bool
+	cancel  context.CancelFunc
+	closed  atomic.Bool
+}
+
+// Open starts the engine and returns once the cache and bloom filters hold
+// every active entry of the database, so the first Query is already
+// answered from them. Close releases it.
+func Open(opts Options) (*Checker, error) {
+	if !opened.CompareAndSwap(false, true) {
+		return nil, ErrAlreadyOpened
+	}
+
+	configFile := opts.ConfigFile
+	if configFile == "" {
+		configFile = "./" + config.GetEnv("CONFIG_FILE", ".env.toml")
+	}
+	if err := config.InitConfigFile(configFile); err != nil {
+		log.Error().Err(err).Str("config_file", configFile).Msg("Failed to load embedded configuration")
+		return nil, err
+	}
+	cfg := config.GetConfig()
+
+	dbOpts := []db.Option{db.WithConfig(cfg.Database)}
+	if opts.Dir != "" {
+		if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+			log.Error().Err(err).Str("dir", opts.Dir).Msg("Failed to create embedded data directory")
+			return nil, err
+		}
+		dbOpts = append(dbOpts, db.WithPath(filepath.Join(opts.Dir, "blacked.db")))
+		cfg.Collector.StorePath = filepath.Join(opts.Dir, "responses")
+	}
+	db.InitializeDB(dbOpts...)
+	writeDB, err := db.GetWriteDB()
+	if err != nil {
+		return nil, err
+	}
+	readDB, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Checker{refresh: opts.Refresh, cancel: cancel}
+	if err := cache.InitializeCache(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to initialize embedded cache")
+		c.release()
+		return nil, err
+	}
+	c.pond = entry_collector.InitPondCollector(ctx, writeDB)
+
+	if _, err := providers.InitProviders(); err != nil {
+		log.Error().Err(err).Msg("Failed to initialize providers")
+		c.release()
+		return nil, err
+	}
+	providerList := *providers.GetProviders()
+
+	policy, err := query.NewPolicy(cfg.Policy)
+	if err != nil {
+		c.release()
+		return nil, err
+	}
+
+	if !c.pond.ScheduleCacheSync(true) {
+		c.release()
+		return nil, ErrInitialSync
+	}
+	if last := c.pond.GetCacheSyncStatus().Last; last != nil && last.State == entry_collector.CacheSyncJobFailed {
+		log.Error().Str("error", last.Error).Msg("Initial embedded cache sync failed")
+		c.release()
+		return nil, ErrInitialSync
+	}
+
+	c.svc = query.NewQueryService(v2.NewBloomAdapter(c.pond.GetBloomManager()), db.NewEntryRepository(readDB), query.NewScorer(config.LoadScoringConfig())).
+		SetAllowlist(allowlist.NewServiceWithRepository(allowlist.NewSQLiteRepository(readDB))).
+		SetPolicy(policy, providerList.Categories())
+
+	if opts.Refresh {
+		if _, err := runner.InitializeRunner(providerList); err != nil {
+			c.release()
+			return nil, err
+		}
+		go func() {
+			if err := runner.RunStartupProviders(ctx, providerList); err != nil {
+				log.Error().Err(err).Msg("Embedded startup feed refresh failed")
+			}
+		}()
+	}
+
+	log.Info().
+		Str("dir", opts.Dir).
+		Bool("refresh", opts.Refresh).
+		Int("providers", len(providerList)).
+		Msg("Embedded checker opened")
+	return c, nil
+}
+
+// Query fully checks one URL: bloom filters, database confirmation, score
+// and policy action, as /api/v1/hit does.
+func (c *Checker) Query(ctx context.Context, url string) (*query.QueryResponse, error) {
+	if c.closed.Load() {
+		return nil, ErrClosed
+	}
+	return c.svc.Hit(ctx, url)
+}
+
+// BulkQuery checks every URL like Query and returns the results in order. A
+// URL that can't be checked gets its reason in Error instead of failing the
+// batch.
+func (c *Checker) BulkQuery(ctx context.Context, urls []string) ([]query.QueryResponse, error) {
+	if c.closed.Load() {
+		return nil, ErrClosed
+	}
+	return c.svc.BulkHit(ctx, urls)
+}
+
+// Close stops the feed refreshes, writes pending entries and closes the
+// cache and database. Queries after Close return ErrClosed.
+func (c *Checker) Close() error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	return c.release()
+}
+
+// release tears down whatever Open set up.
+func (c *Checker) release() error {
+	var errs []error
+	if c.refresh {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := runner.ShutdownRunner(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	c.cancel()
+	if c.pond != nil {
+		c.pond.Close()
+	}
+	cache.CloseCache()
+	if err := db.Close(); err != nil {
+		errs = append(errs, err)
+	}
+
+	log.Info().Msg("Embedded checker closed")
+	return errors.Join(errs...)
+}
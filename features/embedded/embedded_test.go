@@ -0,0 +1,48 @@
+package embedded
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	idb "blacked/internal/db"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenQueryClose(t *testing.T) {
+	dir := t.TempDir()
+
+	// An existing database, as a previous run or the daemon left it.
+	conn, err := idb.Connect(idb.WithPath(filepath.Join(dir, "blacked.db")))
+	require.NoError(t, err)
+	require.NoError(t, idb.MigrateSchema(conn))
+	e, err := entries.FromURL("https://login.bad.example.com/verify", "feed", "p1")
+	require.NoError(t, err)
+	require.NoError(t, repository.NewSQLiteRepository(conn).BatchSaveEntries(context.Background(), []*entries.Entry{e}))
+	require.NoError(t, conn.Close())
+
+	checker, err := Open(Options{ConfigFile: filepath.Join(dir, "missing.toml"), Dir: dir})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	res, err := checker.Query(ctx, "https://login.bad.example.com/verify")
+	require.NoError(t, err)
+	assert.True(t, res.Blocked, "entries already in the database are served at once")
+
+	results, err := checker.BulkQuery(ctx, []string{"https://login.bad.example.com/verify", "https://good.example.org/"})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Blocked)
+	assert.False(t, results[1].Blocked)
+
+	_, err = Open(Options{Dir: dir})
+	assert.ErrorIs(t, err, ErrAlreadyOpened)
+
+	require.NoError(t, checker.Close())
+	_, err = checker.Query(ctx, "https://login.bad.example.com/verify")
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.NoError(t, checker.Close(), "closing twice is harmless")
+}
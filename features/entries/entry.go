@@ -4,7 +4,10 @@ import (
 	"blacked/internal/collector"
 	"blacked/internal/utils"
 	"errors"
+	"fmt"
+	"net/netip"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
@@ -27,13 +30,19 @@ type Entry struct {
 	SubDomains []string `json:"sub_domains"`
 	Path       string   `json:"path"`
 	RawQuery   string   `json:"raw_query"`
+	CIDR       string   `json:"cidr,omitempty"`       // Network of a CIDR entry, e.g. 203.0.113.0/24; Host is its address
+	Wildcard   bool     `json:"wildcard,omitempty"`   // Covers every subdomain of Host, as entries of wildcard feeds do
 	SourceURL  string   `json:"source_url"`           // Raw URL From the source
 	Source     string   `json:"source"`               // Name of the provider
 	Category   string   `json:"category"`             // Category tag
+	Categories []string `json:"categories,omitempty"` // Every category of a multi-category entry, Category first
 	Confidence float64  `json:"confidence,omitempty"` // Optional confidence score
 	CreatedAt  int64    `json:"created_at"`           // Unix timestamp (nanoseconds), zero-alloc
 	UpdatedAt  int64    `json:"updated_at"`           // Unix timestamp (nanoseconds), zero-alloc
 	DeletedAt  *int64   `json:"deleted_at,omitempty"` // Pointer to timestamp, nil if not deleted
+	ExpiresAt  *int64   `json:"expires_at,omitempty"` // Unix nanos after which the entry is purged unless its feed lists it again; nil never expires
+
+	ActivatedAt int64 `json:"activated_at,omitempty"` // Unix nanos of first insert or last reactivation; set only by delta reads
 }
 
 // NewEntry creates a new Entry with default values.
@@ -60,22 +69,51 @@ func (b *Entry) GetURL() url.URL {
 // SetURL parses a URL string and populates the Entry fields
 // This method can't be part of the fluent interface because it may fail
 func (b *Entry) SetURL(link string) error {
-	mc, _ := collector.GetMetricsCollector()
+	err := b.ParseURL(link)
+	if err == nil {
+		if utils.UnusualScheme(b.Scheme) {
+			log.Debug().Str("source", b.Source).Str("scheme", b.Scheme).Msg("Entry listed with an unusual scheme")
+			if mc, _ := collector.GetMetricsCollector(); mc != nil {
+				mc.IncrementUnusualSchemes(b.Source, b.Scheme)
+			}
+		}
+		return nil
+	}
+
+	if mc, _ := collector.GetMetricsCollector(); mc != nil {
+		mc.IncrementImportErrors(b.Source)
+	}
+	if errors.Is(err, ErrURLParse) {
+		log.Warn().Err(err).Str("link", link).Msg("Failed to parse URL")
+		return ErrURLParse
+	}
+	log.Err(err).Str("host", b.Host).Msg("Failed to extract domain and subdomains")
+	return ErrDomainExtraction
+}
 
-	_link := strings.TrimSpace(link)
-	if !strings.Contains(_link, "://") && !strings.HasPrefix(_link, "//") {
-		_link = "//" + _link
+// ParseURL populates the Entry fields from link as SetURL does, without
+// logging or counting failures. Its errors wrap ErrURLParse or
+// ErrDomainExtraction with the cause. A link of an opaque scheme, such as
+// javascript: or data:, keeps only its scheme and source URL, trimmed and
+// with a lower-case scheme as it is looked up, by which it is matched.
+func (b *Entry) ParseURL(link string) error {
+	if opaque := utils.OpaqueLink(link); opaque != "" {
+		b.Scheme, b.SourceURL = utils.OpaqueScheme(opaque), opaque
+		b.Host, b.Domain, b.SubDomains, b.Path, b.RawQuery, b.CIDR = "", "", nil, "", "", ""
+		b.UpdatedAt = time.Now().UnixNano()
+		return nil
 	}
 
+	var raw string
+	raw, b.CIDR = LinkURL(link)
+
 	b.SourceURL = link
-	u, err := url.Parse(_link)
+	u, err := url.Parse(raw)
 	if err != nil {
-		if mc != nil {
-			mc.IncrementImportErrors(b.Source)
-		}
-		log.Warn().Err(err).Str("link", link).Msg("Failed to parse URL")
-		return ErrURLParse
+		return fmt.Errorf("%w: %w", ErrURLParse, err)
 	}
+	// Stored in the form queried links are normalized to, so they match.
+	utils.DefaultNormalizer().Apply(u)
 
 	b.Scheme = u.Scheme
 	b.Host = u.Hostname() // Normalize: strip port — port is irrelevant for URL blacklist
@@ -83,11 +121,7 @@ func (b *Entry) SetURL(link string) error {
 	// Extract domain + subdomains properly via PSL
 	domain, subdomains, err := utils.ExtractDomainAndSubDomains(b.Host)
 	if err != nil {
-		if mc != nil {
-			mc.IncrementImportErrors(b.Source)
-		}
-		log.Err(err).Str("host", u.Host).Msg("Failed to extract domain and subdomains")
-		return ErrDomainExtraction
+		return fmt.Errorf("%w: %w", ErrDomainExtraction, err)
 	}
 
 	b.Domain = domain
@@ -99,6 +133,42 @@ func (b *Entry) SetURL(link string) error {
 	return nil
 }
 
+// LinkURL returns the form of link ParseURL reads as a URL: trimmed, a CIDR
+// network replaced by its address, and a link without a scheme prefixed
+// with "//". cidr is the network of a CIDR link wider than one address.
+func LinkURL(link string) (raw, cidr string) {
+	raw = strings.TrimSpace(link)
+	if prefix, err := netip.ParsePrefix(raw); err == nil {
+		// A network is stored under its address; a single-address prefix is
+		// an ordinary IP entry.
+		prefix = prefix.Masked()
+		if !prefix.IsSingleIP() {
+			cidr = prefix.String()
+		}
+		raw = prefix.Addr().String()
+		if prefix.Addr().Is6() {
+			raw = "[" + raw + "]"
+		}
+	}
+	if !strings.Contains(raw, "://") && !strings.HasPrefix(raw, "//") {
+		raw = "//" + raw
+	}
+	return raw, cidr
+}
+
+// SetWildcardURL parses a line of a wildcard feed, where every listed host
+// covers all its subdomains: a leading "*." is dropped from the host, and
+// the entry is flagged Wildcard. The source URL keeps the line as listed.
+func (b *Entry) SetWildcardURL(link string) error {
+	host := strings.TrimPrefix(strings.TrimSpace(link), "*.")
+	if err := b.SetURL(host); err != nil {
+		return err
+	}
+	b.SourceURL = link
+	b.Wildcard = true
+	return nil
+}
+
 // WithSource sets the source name and returns the entry for chaining
 func (b *Entry) WithSource(source string) *Entry {
 	b.Source = source
@@ -127,6 +197,40 @@ func (b *Entry) WithCategory(category string) *Entry {
 	return b
 }
 
+// WithCategories sets the first category as Category and keeps the
+// de-duplicated list in Categories when there is more than one.
+func (b *Entry) WithCategories(categories ...string) *Entry {
+	var unique []string
+	for _, c := range categories {
+		if c != "" && !slices.Contains(unique, c) {
+			unique = append(unique, c)
+		}
+	}
+
+	b.Categories = nil
+	switch len(unique) {
+	case 0:
+	case 1:
+		b.Category = unique[0]
+	default:
+		b.Category = unique[0]
+		b.Categories = unique
+	}
+	b.UpdatedAt = time.Now().UnixNano()
+	return b
+}
+
+// AllCategories returns every category of the entry.
+func (b *Entry) AllCategories() []string {
+	if len(b.Categories) > 0 {
+		return b.Categories
+	}
+	if b.Category == "" {
+		return nil
+	}
+	return []string{b.Category}
+}
+
 // Clone creates a copy of the Entry with a new ID
 func (b *Entry) Clone() *Entry {
 	clone := *b
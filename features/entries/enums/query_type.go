@@ -10,4 +10,6 @@ const (
 	QueryTypeDomain
 	QueryTypePath
 	QueryTypeMixed
+	QueryTypeSubdomain // The host or any parent of it up to the registered domain
+	QueryTypeWildcard  // As Subdomain, plus "*.parent" entries of wildcard feeds
 )
@@ -9,11 +9,11 @@ import (
 	"strings"
 )
 
-const _QueryTypeName = "FULLHOSTDOMAINPATHMIXED"
+const _QueryTypeName = "FULLHOSTDOMAINPATHMIXEDSUBDOMAINWILDCARD"
 
-var _QueryTypeIndex = [...]uint8{0, 4, 8, 14, 18, 23}
+var _QueryTypeIndex = [...]uint8{0, 4, 8, 14, 18, 23, 32, 40}
 
-const _QueryTypeLowerName = "fullhostdomainpathmixed"
+const _QueryTypeLowerName = "fullhostdomainpathmixedsubdomainwildcard"
 
 func (i QueryType) String() string {
 	if i < 0 || i >= QueryType(len(_QueryTypeIndex)-1) {
@@ -35,9 +35,11 @@ func _QueryTypeNoOp() {
 	_ = x[QueryTypeDomain-(2)]
 	_ = x[QueryTypePath-(3)]
 	_ = x[QueryTypeMixed-(4)]
+	_ = x[QueryTypeSubdomain-(5)]
+	_ = x[QueryTypeWildcard-(6)]
 }
 
-var _QueryTypeValues = []QueryType{QueryTypeFull, QueryTypeHost, QueryTypeDomain, QueryTypePath, QueryTypeMixed}
+var _QueryTypeValues = []QueryType{QueryTypeFull, QueryTypeHost, QueryTypeDomain, QueryTypePath, QueryTypeMixed, QueryTypeSubdomain, QueryTypeWildcard}
 
 var _QueryTypeNameToValueMap = map[string]QueryType{
 	_QueryTypeName[0:4]:        QueryTypeFull,
@@ -50,6 +52,10 @@ var _QueryTypeNameToValueMap = map[string]QueryType{
 	_QueryTypeLowerName[14:18]: QueryTypePath,
 	_QueryTypeName[18:23]:      QueryTypeMixed,
 	_QueryTypeLowerName[18:23]: QueryTypeMixed,
+	_QueryTypeName[23:32]:      QueryTypeSubdomain,
+	_QueryTypeLowerName[23:32]: QueryTypeSubdomain,
+	_QueryTypeName[32:40]:      QueryTypeWildcard,
+	_QueryTypeLowerName[32:40]: QueryTypeWildcard,
 }
 
 var _QueryTypeNames = []string{
@@ -58,6 +64,8 @@ var _QueryTypeNames = []string{
 	_QueryTypeName[8:14],
 	_QueryTypeName[14:18],
 	_QueryTypeName[18:23],
+	_QueryTypeName[23:32],
+	_QueryTypeName[32:40],
 }
 
 // QueryTypeString retrieves an enum value from the enum constants string name.
@@ -1,7 +1,118 @@
 package entries
 
+import (
+	"cmp"
+	"slices"
+)
+
+// Match types of a Hit, strongest first. FULL is the exact URL match of a
+// typed query, SUBDOMAIN a wildcard entry, an entry for a parent domain or a
+// "*.parent" wildcard of the queried host, NETWORK a CIDR entry containing
+// the queried IP and PATTERN a regex or glob pattern entry.
+const (
+	MatchTypeExactURL  = "EXACT_URL"
+	MatchTypeFull      = "FULL"
+	MatchTypeHost      = "HOST"
+	MatchTypeSubdomain = "SUBDOMAIN"
+	MatchTypeNetwork   = "NETWORK"
+	MatchTypeDomain    = "DOMAIN"
+	MatchTypePath      = "PATH"
+	MatchTypePattern   = "PATTERN"
+)
+
 type Hit struct {
 	ID           string `json:"id"`
 	MatchType    string `json:"match_type"`
 	MatchedValue string `json:"matched_value"`
+	Source       string `json:"source,omitempty"`       // Set by scored queries and pattern matches
+	ActivatedAt  int64  `json:"activated_at,omitempty"` // Unix nanos the entry was inserted or last reactivated
+
+	// Entry holds the matched entry's fields when the query was asked for
+	// them, so the hit needs no second lookup by ID.
+	Entry *HitEntry `json:"entry,omitempty"`
+}
+
+// HitEntry is the part of a matched entry an enriched query returns with
+// its hit. Timestamps are Unix nanos.
+type HitEntry struct {
+	Source     string   `json:"source"`
+	Category   string   `json:"category,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Confidence float64  `json:"confidence"`
+	CreatedAt  int64    `json:"created_at"`
+	UpdatedAt  int64    `json:"updated_at,omitempty"`
+}
+
+// AllCategories returns every category of the entry.
+func (e *HitEntry) AllCategories() []string {
+	if len(e.Categories) > 0 {
+		return e.Categories
+	}
+	if e.Category == "" {
+		return nil
+	}
+	return []string{e.Category}
+}
+
+// matchTypeRank is the precedence of a match type; lower is stronger and
+// unknown types rank last.
+func matchTypeRank(matchType string) int {
+	switch matchType {
+	case MatchTypeExactURL, MatchTypeFull:
+		return 0
+	case MatchTypeHost:
+		return 1
+	case MatchTypeSubdomain:
+		return 2
+	case MatchTypeNetwork:
+		return 3
+	case MatchTypeDomain:
+		return 4
+	case MatchTypePath:
+		return 5
+	case MatchTypePattern:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// smallHits is the size up to which NormalizeHits de-duplicates by scanning.
+const smallHits = 16
+
+// NormalizeHits keeps one hit per entry ID, the one with the strongest match
+// type, and orders the result by match type (exact URL, host, subdomain,
+// network, domain, path, pattern), then most recently activated first, then by ID. hits is
+// sorted in place.
+func NormalizeHits(hits []Hit) []Hit {
+	slices.SortStableFunc(hits, func(a, b Hit) int {
+		if c := cmp.Compare(matchTypeRank(a.MatchType), matchTypeRank(b.MatchType)); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.ActivatedAt, a.ActivatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	unique := hits[:0]
+	if len(hits) <= smallHits {
+		// A scan of a handful of IDs beats allocating a set.
+		for _, h := range hits {
+			if !slices.ContainsFunc(unique, func(u Hit) bool { return u.ID == h.ID }) {
+				unique = append(unique, h)
+			}
+		}
+		return unique
+	}
+
+	seen := make(map[string]struct{}, len(hits))
+	for _, h := range hits {
+		if _, ok := seen[h.ID]; ok {
+			continue
+		}
+		seen[h.ID] = struct{}{}
+		unique = append(unique, h)
+	}
+	return unique
 }
@@ -0,0 +1,36 @@
+package entries
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeHits(t *testing.T) {
+	hits := []Hit{
+		{ID: "b", MatchType: MatchTypeDomain, ActivatedAt: 30},
+		{ID: "a", MatchType: MatchTypePath, ActivatedAt: 10},
+		{ID: "c", MatchType: MatchTypeDomain, ActivatedAt: 20},
+		{ID: "a", MatchType: MatchTypeHost, ActivatedAt: 10},
+		{ID: "d", MatchType: MatchTypeDomain, ActivatedAt: 30},
+		{ID: "b", MatchType: MatchTypeExactURL, ActivatedAt: 30},
+	}
+
+	assert.Equal(t, []Hit{
+		{ID: "b", MatchType: MatchTypeExactURL, ActivatedAt: 30},
+		{ID: "a", MatchType: MatchTypeHost, ActivatedAt: 10},
+		{ID: "d", MatchType: MatchTypeDomain, ActivatedAt: 30}, // Recency ties break by ID
+		{ID: "c", MatchType: MatchTypeDomain, ActivatedAt: 20},
+	}, NormalizeHits(hits))
+
+	assert.Empty(t, NormalizeHits(nil))
+
+	// Past a handful of hits duplicates are dropped through a set.
+	var many []Hit
+	for i := range 40 {
+		many = append(many, Hit{ID: string(rune('a' + i%20)), MatchType: MatchTypeDomain, ActivatedAt: int64(i)})
+	}
+	normalized := NormalizeHits(many)
+	assert.Len(t, normalized, 20)
+	assert.Equal(t, "t", normalized[0].ID, "the most recent duplicate is kept")
+}
@@ -0,0 +1,169 @@
+package entries
+
+import (
+	"blacked/internal/utils"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrInvalidPattern = errors.New("invalid pattern")
+
+// PatternKind selects how a Pattern matches URLs.
+type PatternKind string
+
+const (
+	PatternKindRegex PatternKind = "regex" // RE2 syntax, found anywhere in the URL unless anchored
+	PatternKindGlob  PatternKind = "glob"  // "*" any run of characters, "?" one; covers the whole URL
+)
+
+// Pattern is a blacklist entry given as a regex or glob over URLs instead of
+// a single URL, as some feeds publish them. Patterns are matched against the
+// normalized, lowercase URL; globs also against the URL without its scheme,
+// so "*.bad.example/*" needs no scheme.
+type Pattern struct {
+	ID        string      `json:"id"` // xid
+	ProcessID string      `json:"process_id"`
+	Source    string      `json:"source"`
+	Kind      PatternKind `json:"kind"`
+	Pattern   string      `json:"pattern"`
+	Category  string      `json:"category"`
+	CreatedAt int64       `json:"created_at"`
+	UpdatedAt int64       `json:"updated_at"`
+	DeletedAt *int64      `json:"deleted_at,omitempty"`
+}
+
+// NewPattern validates pattern and returns it as a new Pattern of source.
+func NewPattern(kind PatternKind, pattern, source, processID string) (*Pattern, error) {
+	p := &Pattern{
+		ID:        xid.New().String(),
+		ProcessID: processID,
+		Source:    source,
+		Kind:      kind,
+		Pattern:   strings.TrimSpace(pattern),
+	}
+	if p.Pattern == "" {
+		return nil, errors.Join(ErrInvalidPattern, errors.New("pattern is empty"))
+	}
+	if _, err := p.compile(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixNano()
+	p.CreatedAt, p.UpdatedAt = now, now
+	return p, nil
+}
+
+// WithCategory sets the category of the Pattern.
+func (p *Pattern) WithCategory(category string) *Pattern {
+	p.Category = category
+	return p
+}
+
+// Entry returns the Pattern as an Entry carrying its ID, source and
+// category, for the code that filters and scores hits by entry.
+func (p *Pattern) Entry() *Entry {
+	return &Entry{
+		ID:        p.ID,
+		ProcessID: p.ProcessID,
+		SourceURL: p.Pattern,
+		Source:    p.Source,
+		Category:  p.Category,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+		DeletedAt: p.DeletedAt,
+	}
+}
+
+// compile returns the regular expression the Pattern matches with.
+func (p *Pattern) compile() (*regexp.Regexp, error) {
+	expr := p.Pattern
+	switch p.Kind {
+	case PatternKindRegex:
+	case PatternKindGlob:
+		expr = globToRegex(strings.ToLower(expr))
+	default:
+		return nil, errors.Join(ErrInvalidPattern, errors.New("kind must be one of regex, glob"))
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidPattern, err)
+	}
+	return re, nil
+}
+
+// globToRegex anchors glob and turns its "*" and "?" into their regex forms.
+func globToRegex(glob string) string {
+	var b strings.Builder
+	b.WriteByte('^')
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteByte('.')
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteByte('$')
+	return b.String()
+}
+
+type compiledPattern struct {
+	pattern *Pattern
+	re      *regexp.Regexp
+}
+
+// PatternMatcher matches URLs against a fixed set of compiled patterns. It
+// is immutable once built and safe for concurrent use.
+type PatternMatcher struct {
+	patterns []compiledPattern
+}
+
+// NewPatternMatcher compiles patterns. Patterns that no longer compile are
+// skipped with a warning rather than failing every lookup.
+func NewPatternMatcher(patterns []Pattern) *PatternMatcher {
+	m := &PatternMatcher{patterns: make([]compiledPattern, 0, len(patterns))}
+	for i := range patterns {
+		p := &patterns[i]
+		re, err := p.compile()
+		if err != nil {
+			log.Warn().Err(err).Str("id", p.ID).Str("pattern", p.Pattern).Msg("Skipping pattern that does not compile")
+			continue
+		}
+		m.patterns = append(m.patterns, compiledPattern{pattern: p, re: re})
+	}
+	return m
+}
+
+// Len returns the number of patterns the matcher checks.
+func (m *PatternMatcher) Len() int {
+	return len(m.patterns)
+}
+
+// Match returns the patterns matching link, in the order they were given.
+func (m *PatternMatcher) Match(link string) []*Pattern {
+	if len(m.patterns) == 0 {
+		return nil
+	}
+
+	normalized := utils.NormalizeURL(strings.TrimSpace(link))
+	_, bare, found := strings.Cut(normalized, "://")
+	if !found {
+		bare = strings.TrimPrefix(normalized, "//")
+	}
+
+	var matched []*Pattern
+	for _, cp := range m.patterns {
+		if cp.re.MatchString(normalized) || (cp.pattern.Kind == PatternKindGlob && cp.re.MatchString(bare)) {
+			matched = append(matched, cp.pattern)
+		}
+	}
+	return matched
+}
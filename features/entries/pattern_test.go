@@ -0,0 +1,43 @@
+package entries
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatternMatcher(t *testing.T) {
+	newPattern := func(kind PatternKind, pattern string) Pattern {
+		t.Helper()
+		p, err := NewPattern(kind, pattern, "feed", "p1")
+		require.NoError(t, err)
+		return *p
+	}
+	patterns := []Pattern{
+		newPattern(PatternKindGlob, "*.bad.example/login*"),
+		newPattern(PatternKindRegex, `/wp-admin/[a-z]+\.php$`),
+		newPattern(PatternKindGlob, "https://cdn.example/??.js"),
+	}
+	m := NewPatternMatcher(patterns)
+	require.Equal(t, 3, m.Len())
+
+	ids := func(link string) []string {
+		var got []string
+		for _, p := range m.Match(link) {
+			got = append(got, p.ID)
+		}
+		return got
+	}
+
+	assert.Equal(t, []string{patterns[0].ID}, ids("http://Sub.BAD.example/login/step2"), "globs match without the scheme, case-insensitively")
+	assert.Equal(t, []string{patterns[1].ID}, ids("https://blog.example/wp-admin/evil.php"))
+	assert.Equal(t, []string{patterns[2].ID}, ids("https://cdn.example/ab.js"))
+	assert.Empty(t, ids("https://cdn.example/abc.js"), "? is a single character")
+	assert.Empty(t, ids("https://bad.example.org/login"), "globs cover the whole URL")
+
+	_, err := NewPattern(PatternKindRegex, "([", "feed", "p1")
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+	_, err = NewPattern("substring", "bad", "feed", "p1")
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+}
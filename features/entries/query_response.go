@@ -8,6 +8,20 @@ type QueryResponse struct {
 	Hits      []Hit           `json:"hits"`
 	QueryType enums.QueryType `json:"query_type"`
 	Count     int             `json:"count"`
+
+	// Set by scored queries.
+	Score   float64       `json:"score,omitempty"` // Aggregated risk, 0–1
+	Level   string        `json:"level,omitempty"`
+	Sources []SourceScore `json:"sources,omitempty"`
+}
+
+// SourceScore is what one source contributes to the risk score of a query.
+type SourceScore struct {
+	Source    string  `json:"source"`
+	Trust     float64 `json:"trust"`      // Configured trust of the source
+	MatchType string  `json:"match_type"` // Strongest match of its entries
+	Hits      int     `json:"hits"`
+	Score     float64 `json:"score"`
 }
 
 func NewQueryResponse(url string, hits []Hit, queryType enums.QueryType, verbose bool) *QueryResponse {
@@ -11,16 +11,46 @@ type BlacklistRepository interface {
 	StreamEntries(ctx context.Context, out chan<- entries.EntryStream) error
 	StreamEntriesCount(ctx context.Context) (int, error)
 	StreamEntriesCountBySource(ctx context.Context, source string) (int, error)
+	StreamEntriesActivatedSince(ctx context.Context, source string, since int64, fn func(*entries.Entry) error) error
+	StreamFilteredEntries(ctx context.Context, f Filter, fn func(*entries.Entry) error) error
+	StreamChangedSourceURLs(ctx context.Context, source string, since int64, out chan<- entries.EntryStream) error
+	CountChangedSourceURLs(ctx context.Context, source string, since int64) (int, error)
+	ProcessFirstWrite(ctx context.Context, processID string) (int64, error)
 	GetAllEntries(ctx context.Context) ([]entries.Entry, error)
 	GetEntryByID(ctx context.Context, id string) (*entries.Entry, error)
 	GetEntriesBySource(ctx context.Context, source string) ([]entries.Entry, error)
 	GetEntriesByCategory(ctx context.Context, category string) ([]entries.Entry, error)
 	GetEntriesByIDs(ctx context.Context, ids []string) ([]*entries.Entry, error)
+	GetEntriesPage(ctx context.Context, source, afterID string, limit int) ([]entries.Entry, error)
+	SearchEntries(ctx context.Context, f Filter, afterID string, limit int) ([]entries.Entry, error)
+	GetEntryStats(ctx context.Context, f Filter) (*EntryStats, error)
+	LatestChange(ctx context.Context) (int64, error)
+	GetEntriesUnderHost(ctx context.Context, name string, limit int) ([]entries.Entry, error)
+	GetEntriesByIPs(ctx context.Context, ips []string, limit int) ([]entries.Entry, error)
+	GetIDsBySourceURLs(ctx context.Context, sourceURLs []string) (map[string][]string, error)
+	SampleSourceURLs(ctx context.Context, n int) ([]string, error)
 	SaveEntry(ctx context.Context, entry entries.Entry) error
 	BatchSaveEntries(ctx context.Context, entries []*entries.Entry) error // Batched UPSERT
+	UpdateEntryURLFields(ctx context.Context, batch []*entries.Entry) error
+	GetNearDuplicates(ctx context.Context, source string) ([]entries.Entry, error)
+	MergeEntries(ctx context.Context, merges []EntryMerge) error
+	UpdateEntryCategories(ctx context.Context, batch []*entries.Entry) error
 	ClearAllEntries(ctx context.Context) error                            // Soft Delete All
 	SoftDeleteEntryByID(ctx context.Context, id string) error
+	SoftDeleteEntries(ctx context.Context, f Filter) (int64, error)
+	SoftDeleteSourceURLs(ctx context.Context, source string, sourceURLs []string) (int64, error)
+	SoftDeleteExpired(ctx context.Context, now int64, limit int) (int64, error)
+	SetSourceExpiry(ctx context.Context, source string, expiresAt *int64) (int64, error)
+	CountDeletedBefore(ctx context.Context, before int64) (int64, error)
+	PurgeDeletedBefore(ctx context.Context, before int64, limit int) (int64, error)
 	QueryLink(ctx context.Context, link string) ([]entries.Hit, error)
 	QueryLinkByType(ctx context.Context, link string, queryType *enums.QueryType) ([]entries.Hit, error)
 	QueryExactURLMatch(ctx context.Context, normalizedLink string) []entries.Hit
+	QueryLinkEnriched(ctx context.Context, link string, queryType *enums.QueryType) ([]entries.Hit, error)
+	ListPatterns(ctx context.Context, source string) ([]entries.Pattern, error)
+	GetPatternsByIDs(ctx context.Context, ids []string) ([]entries.Pattern, error)
+	SavePatterns(ctx context.Context, patterns []*entries.Pattern) error // Upsert on (source, kind, pattern)
+	SoftDeletePattern(ctx context.Context, id string) error
+	RemoveOlderPatterns(ctx context.Context, source, processID string) (int64, error)
+	MatchPatterns(ctx context.Context, link string) ([]*entries.Pattern, error)
 }
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxFilterValues caps the values of one list filter, e.g. source=a,b,c.
+	MaxFilterValues = 100
+	// MaxFilterValueLength caps the length of a single filter value.
+	MaxFilterValueLength = 2048
+
+	// activatedAtExpr is when an entry was inserted or last reactivated.
+	activatedAtExpr = "COALESCE(activated_at, created_at)"
+)
+
+var (
+	ErrInvalidFilter = errors.New("invalid entries filter")
+	ErrEmptyFilter   = errors.New("entries filter matches every entry")
+)
+
+// Filter selects active entries. Zero fields match everything; set fields
+// are ANDed, and list fields match any of their values. It is only ever
+// rendered as placeholders, so values never reach the SQL text.
+type Filter struct {
+	Sources       []string   `json:"sources,omitempty"`    // Compared case-insensitively
+	Categories    []string   `json:"categories,omitempty"` // Any category of an entry; compared case-insensitively
+	Domain        string     `json:"domain,omitempty"`
+	Host          string     `json:"host,omitempty"`
+	ProcessID     string     `json:"process_id,omitempty"`
+	MinConfidence *float64   `json:"min_confidence,omitempty"`
+	MaxConfidence *float64   `json:"max_confidence,omitempty"`
+	Since         *time.Time `json:"since,omitempty"` // Activated at or after
+	Until         *time.Time `json:"until,omitempty"` // Activated before
+}
+
+// FilterParams are the query parameters ParseFilter reads.
+var FilterParams = []string{"source", "category", "domain", "host", "process_id", "min_confidence", "max_confidence", "since", "until"}
+
+// ParseFilter reads a Filter from query parameters. List parameters take
+// comma-separated or repeated values; times are RFC3339.
+func ParseFilter(values url.Values) (Filter, error) {
+	var f Filter
+	var err error
+
+	if f.Sources, err = filterList(values, "source"); err != nil {
+		return Filter{}, err
+	}
+	if f.Categories, err = filterList(values, "category"); err != nil {
+		return Filter{}, err
+	}
+	if f.Domain, err = filterString(values, "domain"); err != nil {
+		return Filter{}, err
+	}
+	if f.Host, err = filterString(values, "host"); err != nil {
+		return Filter{}, err
+	}
+	if f.ProcessID, err = filterString(values, "process_id"); err != nil {
+		return Filter{}, err
+	}
+	if f.MinConfidence, err = filterConfidence(values, "min_confidence"); err != nil {
+		return Filter{}, err
+	}
+	if f.MaxConfidence, err = filterConfidence(values, "max_confidence"); err != nil {
+		return Filter{}, err
+	}
+	if f.Since, err = filterTime(values, "since"); err != nil {
+		return Filter{}, err
+	}
+	if f.Until, err = filterTime(values, "until"); err != nil {
+		return Filter{}, err
+	}
+
+	if f.MinConfidence != nil && f.MaxConfidence != nil && *f.MinConfidence > *f.MaxConfidence {
+		return Filter{}, fmt.Errorf("%w: min_confidence is above max_confidence", ErrInvalidFilter)
+	}
+	if f.Since != nil && f.Until != nil && !f.Since.Before(*f.Until) {
+		return Filter{}, fmt.Errorf("%w: since must be before until", ErrInvalidFilter)
+	}
+	return f, nil
+}
+
+// IsEmpty reports whether f matches every active entry.
+func (f Filter) IsEmpty() bool {
+	return len(f.Sources) == 0 && len(f.Categories) == 0 && f.Domain == "" && f.Host == "" &&
+		f.ProcessID == "" && f.MinConfidence == nil && f.MaxConfidence == nil && f.Since == nil && f.Until == nil
+}
+
+// Where renders f as a WHERE clause over active entries and its arguments.
+// Column names and operators are fixed; every value is a placeholder.
+func (f Filter) Where() (string, []any) {
+	var w whereBuilder
+	w.add("deleted_at IS NULL")
+	w.in("source", f.Sources)
+	w.categories(f.Categories)
+	if f.Domain != "" {
+		w.add("domain = ?", strings.ToLower(f.Domain))
+	}
+	if f.Host != "" {
+		w.add("host = ?", strings.ToLower(f.Host))
+	}
+	if f.ProcessID != "" {
+		w.add("process_id = ?", f.ProcessID)
+	}
+	if f.MinConfidence != nil {
+		w.add("confidence >= ?", *f.MinConfidence)
+	}
+	if f.MaxConfidence != nil {
+		w.add("confidence <= ?", *f.MaxConfidence)
+	}
+	if f.Since != nil {
+		w.add(activatedAtExpr+" >= ?", f.Since.UnixNano())
+	}
+	if f.Until != nil {
+		w.add(activatedAtExpr+" < ?", f.Until.UnixNano())
+	}
+	return strings.Join(w.conds, " AND "), w.args
+}
+
+// whereBuilder collects ANDed conditions and their arguments.
+type whereBuilder struct {
+	conds []string
+	args  []any
+}
+
+func (w *whereBuilder) add(cond string, args ...any) {
+	w.conds = append(w.conds, cond)
+	w.args = append(w.args, args...)
+}
+
+// in adds a case-insensitive "column IN (...)" condition when values is set.
+func (w *whereBuilder) in(column string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	w.add(column+" COLLATE NOCASE IN ("+placeholders+")", args...)
+}
+
+// categories adds a condition matching entries whose primary category or
+// any of their extra categories is one of values.
+func (w *whereBuilder) categories(values []string) {
+	if len(values) == 0 {
+		return
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	args := make([]any, 0, 2*len(values))
+	for range 2 {
+		for _, v := range values {
+			args = append(args, v)
+		}
+	}
+	w.add("(category COLLATE NOCASE IN ("+placeholders+") OR id IN "+
+		"(SELECT entry_id FROM entry_categories WHERE category COLLATE NOCASE IN ("+placeholders+")))", args...)
+}
+
+func filterString(values url.Values, key string) (string, error) {
+	v := strings.TrimSpace(values.Get(key))
+	if len(v) > MaxFilterValueLength {
+		return "", fmt.Errorf("%w: %s is longer than %d bytes", ErrInvalidFilter, key, MaxFilterValueLength)
+	}
+	return v, nil
+}
+
+func filterList(values url.Values, key string) ([]string, error) {
+	var list []string
+	for _, raw := range values[key] {
+		for v := range strings.SplitSeq(raw, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			if len(v) > MaxFilterValueLength {
+				return nil, fmt.Errorf("%w: %s value is longer than %d bytes", ErrInvalidFilter, key, MaxFilterValueLength)
+			}
+			list = append(list, v)
+			if len(list) > MaxFilterValues {
+				return nil, fmt.Errorf("%w: more than %d %s values", ErrInvalidFilter, MaxFilterValues, key)
+			}
+		}
+	}
+	return list, nil
+}
+
+func filterConfidence(values url.Values, key string) (*float64, error) {
+	raw := strings.TrimSpace(values.Get(key))
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || !(v >= 0 && v <= 1) {
+		return nil, fmt.Errorf("%w: %s must be a number between 0 and 1", ErrInvalidFilter, key)
+	}
+	return &v, nil
+}
+
+func filterTime(values url.Values, key string) (*time.Time, error) {
+	raw := strings.TrimSpace(values.Get(key))
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be an RFC3339 timestamp, e.g. 2024-06-01T00:00:00Z", ErrInvalidFilter, key)
+	}
+	return &t, nil
+}
@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"blacked/features/entries"
+	idb "blacked/internal/db"
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// filterCondition matches every condition Where may render.
+var filterCondition = regexp.MustCompile(`^(deleted_at IS NULL|` +
+	`source COLLATE NOCASE IN \(\?(, \?)*\)|` +
+	`\(category COLLATE NOCASE IN \(\?(, \?)*\) OR id IN \(SELECT entry_id FROM entry_categories WHERE category COLLATE NOCASE IN \(\?(, \?)*\)\)\)|` +
+	`(domain|host|process_id) = \?|` +
+	`confidence [<>]= \?|` +
+	`COALESCE\(activated_at, created_at\) (>=|<) \?)$`)
+
+func TestParseFilter(t *testing.T) {
+	f, err := ParseFilter(url.Values{
+		"source":         {"oisd-big, urlhaus-online", "openphish-feed"},
+		"category":       {"phishing"},
+		"domain":         {"Bad.Example"},
+		"min_confidence": {"0.5"},
+		"since":          {"2024-06-01T00:00:00Z"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"oisd-big", "urlhaus-online", "openphish-feed"}, f.Sources)
+	assert.False(t, f.IsEmpty())
+
+	where, args := f.Where()
+	assert.Equal(t, "deleted_at IS NULL AND source COLLATE NOCASE IN (?, ?, ?) AND (category COLLATE NOCASE IN (?) "+
+		"OR id IN (SELECT entry_id FROM entry_categories WHERE category COLLATE NOCASE IN (?))) "+
+		"AND domain = ? AND confidence >= ? AND COALESCE(activated_at, created_at) >= ?", where)
+	assert.Equal(t, []any{"oisd-big", "urlhaus-online", "openphish-feed", "phishing", "phishing", "bad.example", 0.5,
+		time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC).UnixNano()}, args)
+
+	f, err = ParseFilter(url.Values{"source": {" , "}})
+	require.NoError(t, err)
+	assert.True(t, f.IsEmpty())
+
+	for _, bad := range []url.Values{
+		{"min_confidence": {"1.5"}},
+		{"max_confidence": {"NaN"}},
+		{"min_confidence": {"0.9"}, "max_confidence": {"0.1"}},
+		{"since": {"yesterday"}},
+		{"since": {"2024-06-02T00:00:00Z"}, "until": {"2024-06-01T00:00:00Z"}},
+		{"source": {strings.Repeat("a,", MaxFilterValues+1)}},
+		{"domain": {strings.Repeat("a", MaxFilterValueLength+1)}},
+	} {
+		_, err := ParseFilter(bad)
+		assert.ErrorIs(t, err, ErrInvalidFilter, bad)
+	}
+}
+
+func TestFilteredEntries(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := NewSQLiteRepository(conn)
+
+	var batch []*entries.Entry
+	for _, e := range []struct {
+		source, category, link string
+		confidence             float64
+	}{
+		{"oisd-big", "ads", "https://ads.example/a", 0.4},
+		{"oisd-big", "ads", "https://ads.example/b", 0.9},
+		{"openphish-feed", "phishing", "https://login.example/c", 0.9},
+	} {
+		entry := entries.NewEntry().WithSource(e.source).WithCategory(e.category).WithProcessID("p1")
+		require.NoError(t, entry.SetURL(e.link))
+		entry.Confidence = e.confidence
+		batch = append(batch, entry)
+	}
+	require.NoError(t, repo.BatchSaveEntries(ctx, batch))
+
+	minConfidence := 0.5
+	found, err := repo.SearchEntries(ctx, Filter{Sources: []string{"OISD-BIG"}, MinConfidence: &minConfidence}, "", 10)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "https://ads.example/b", found[0].SourceURL)
+
+	stats, err := repo.GetEntryStats(ctx, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Total)
+	assert.Equal(t, map[string]int{"oisd-big": 2, "openphish-feed": 1}, stats.BySource)
+	assert.Equal(t, map[string]int{"ads": 2, "phishing": 1}, stats.ByCategory)
+
+	_, err = repo.SoftDeleteEntries(ctx, Filter{})
+	assert.ErrorIs(t, err, ErrEmptyFilter)
+
+	deleted, err := repo.SoftDeleteEntries(ctx, Filter{Categories: []string{"ads"}, Domain: "ads.example"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	var streamed []string
+	require.NoError(t, repo.StreamFilteredEntries(ctx, Filter{}, func(e *entries.Entry) error {
+		streamed = append(streamed, e.SourceURL)
+		return nil
+	}))
+	assert.Equal(t, []string{"https://login.example/c"}, streamed)
+}
+
+// FuzzParseFilter checks that no query string can put text of its own into
+// the rendered SQL, and that every rendered clause runs.
+func FuzzParseFilter(f *testing.F) {
+	for _, seed := range []string{
+		"source=oisd-big,urlhaus-online&category=phishing",
+		"domain=bad.example&host=www.bad.example&process_id=p1",
+		"min_confidence=0.2&max_confidence=0.8",
+		"since=2024-06-01T00:00:00Z&until=2024-07-01T00:00:00Z",
+		"source=x'%20OR%201=1;--&domain=%22);DROP%20TABLE%20entries;--",
+		"category=%3F&source=,,,",
+	} {
+		f.Add(seed)
+	}
+
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(f, err)
+	defer conn.Close()
+	require.NoError(f, idb.MigrateSchema(conn))
+
+	f.Fuzz(func(t *testing.T, rawQuery string) {
+		values, err := url.ParseQuery(rawQuery)
+		if err != nil {
+			return
+		}
+		filter, err := ParseFilter(values)
+		if err != nil {
+			require.ErrorIs(t, err, ErrInvalidFilter)
+			return
+		}
+
+		where, args := filter.Where()
+		for _, cond := range strings.Split(where, " AND ") {
+			require.Regexp(t, filterCondition, cond)
+		}
+		require.Equal(t, strings.Count(where, "?"), len(args))
+
+		rows, err := conn.Query("SELECT COUNT(*) FROM entries WHERE "+where, args...)
+		require.NoError(t, err)
+		require.NoError(t, rows.Close())
+	})
+}
+
+func TestMultiCategoryEntries(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := NewSQLiteRepository(conn)
+
+	tagged := entries.NewEntry().WithSource("urlhaus-online").WithCategories("malware", "botnet", "ransomware", "botnet")
+	require.NoError(t, tagged.SetURL("http://1.2.3.4/mozi.m"))
+	plain := entries.NewEntry().WithSource("urlhaus-online").WithCategory("malware")
+	require.NoError(t, plain.SetURL("http://bad.example/x"))
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{tagged, plain}))
+
+	stored, err := repo.GetEntryByID(ctx, tagged.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "malware", stored.Category)
+	assert.Equal(t, []string{"malware", "botnet", "ransomware"}, stored.Categories)
+
+	found, err := repo.SearchEntries(ctx, Filter{Categories: []string{"Botnet"}}, "", 10)
+	require.NoError(t, err)
+	require.Len(t, found, 1, "any category of an entry matches")
+	assert.Equal(t, tagged.ID, found[0].ID)
+
+	byCategory, err := repo.GetEntriesByCategory(ctx, "ransomware")
+	require.NoError(t, err)
+	assert.Len(t, byCategory, 1)
+
+	stats, err := repo.GetEntryStats(ctx, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Total)
+	assert.Equal(t, map[string]int{"malware": 2, "botnet": 1, "ransomware": 1}, stats.ByCategory)
+
+	// The next run drops a tag: the upsert rewrites the category index.
+	again := entries.NewEntry().WithSource("urlhaus-online").WithCategories("malware", "ransomware")
+	require.NoError(t, again.SetURL("http://1.2.3.4/mozi.m"))
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{again}))
+
+	found, err = repo.SearchEntries(ctx, Filter{Categories: []string{"botnet"}}, "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+	found, err = repo.SearchEntries(ctx, Filter{Categories: []string{"ransomware"}}, "", 10)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, tagged.ID, found[0].ID, "the upsert keeps the stored ID")
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"blacked/features/entries"
+	"blacked/internal/iptrie"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// indexReloadInterval bounds how long a process serves an in-memory index
+// after another process (the CLI, a replica applying changes) changed its
+// rows. Writes through this process invalidate it at once.
+const indexReloadInterval = 30 * time.Second
+
+type loadedIndex[T any] struct {
+	value    T
+	loadedAt time.Time
+}
+
+// memoryIndex is an index built in memory from the database. Lookups read
+// it without locking; mu only serializes rebuilds.
+type memoryIndex[T any] struct {
+	mu      sync.Mutex
+	current atomic.Pointer[loadedIndex[T]]
+}
+
+// get returns the index, building it when missing or older than
+// indexReloadInterval.
+func (m *memoryIndex[T]) get(build func() (T, error)) (T, error) {
+	if idx := m.current.Load(); idx != nil && time.Since(idx.loadedAt) < indexReloadInterval {
+		return idx.value, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if idx := m.current.Load(); idx != nil && time.Since(idx.loadedAt) < indexReloadInterval {
+		return idx.value, nil // Rebuilt while we waited
+	}
+
+	value, err := build()
+	if err != nil {
+		return value, err
+	}
+	m.current.Store(&loadedIndex[T]{value: value, loadedAt: time.Now()})
+	return value, nil
+}
+
+// invalidate makes the next get rebuild the index.
+func (m *memoryIndex[T]) invalidate() {
+	m.current.Store(nil)
+}
+
+// memoryIndexes are the in-memory indexes of one database.
+type memoryIndexes struct {
+	patterns memoryIndex[*entries.PatternMatcher]
+	networks memoryIndex[*iptrie.Trie[string]] // CIDR entry IDs by network
+}
+
+// indexesByDB holds the memoryIndexes per *sql.DB, shared by every
+// repository on the same connection pool.
+var indexesByDB sync.Map
+
+func (r *SQLiteRepository) indexes() *memoryIndexes {
+	idx, _ := indexesByDB.LoadOrStore(r.db, &memoryIndexes{})
+	return idx.(*memoryIndexes)
+}
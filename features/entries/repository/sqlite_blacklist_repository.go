@@ -3,11 +3,17 @@ package repository
 import (
 	"blacked/features/entries"
 	"blacked/features/entries/enums"
+	idb "blacked/internal/db"
+	"blacked/internal/logger"
+	"blacked/internal/query"
 	"blacked/internal/utils"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"net/netip"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
@@ -28,17 +34,120 @@ var (
 	ErrTxPrepare     = errors.New("failed to prepare transaction for SQLite")
 	ErrUpsert        = errors.New("failed to UPSERT entry in SQLite")
 	ErrDelete        = errors.New("failed to delete entry in SQLite")
+
+	ErrProcessNotFound = errors.New("no entries written by process")
 )
 
+// hitColumns is the column list scanned into entries.Hit by the match queries.
+const hitColumns = "id, COALESCE(activated_at, created_at, 0)"
+
+// hitEntryColumns follow hitColumns in enriched match queries: the fields
+// of entries.HitEntry.
+const hitEntryColumns = ", source, COALESCE(category, ''), categories, COALESCE(confidence, 0), COALESCE(created_at, 0), COALESCE(updated_at, 0)"
+
+// hitSelect returns the hit columns of a match query, with the entry fields
+// when enrich is set.
+func hitSelect(enrich bool) string {
+	if enrich {
+		return hitColumns + hitEntryColumns
+	}
+	return hitColumns
+}
+
+// scanHit scans a row selected with hitSelect(enrich) and then the columns
+// of extra into h.
+func scanHit(rows *sql.Rows, h *entries.Hit, enrich bool, extra ...any) error {
+	if !enrich {
+		return rows.Scan(append([]any{&h.ID, &h.ActivatedAt}, extra...)...)
+	}
+
+	e := &entries.HitEntry{}
+	var categories sql.NullString
+	dest := append([]any{&h.ID, &h.ActivatedAt, &e.Source, &e.Category, &categories, &e.Confidence, &e.CreatedAt, &e.UpdatedAt}, extra...)
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+	e.Categories = decodeCategories(categories)
+	h.Source, h.Entry = e.Source, e
+	return nil
+}
+
+// entryColumns is the column list scanned into entries.Entry by the Get* methods.
+// Listed explicitly so columns added by later migrations don't break row scans.
+const entryColumns = "id, process_id, scheme, domain, host, sub_domains, path, raw_query, source_url, source, category, confidence, created_at, updated_at, deleted_at, categories, COALESCE(cidr, ''), wildcard, expires_at"
+
+// sourceURLDeleteChunk bounds the source URLs of one soft delete statement,
+// well under SQLite's host parameter limit.
+const sourceURLDeleteChunk = 500
+
+// removeOlderBatch bounds the entries one RemoveOlderInsertions transaction
+// soft deletes; removeOlderYield is the pause between its batches.
+var (
+	removeOlderBatch = 5_000
+	removeOlderYield = 10 * time.Millisecond
+)
+
+// encodeCategories stores the categories of a multi-category entry as a JSON
+// array; single-category entries store NULL and rely on the category column.
+func encodeCategories(categories []string) any {
+	if len(categories) < 2 {
+		return nil
+	}
+	b, err := json.Marshal(categories)
+	if err != nil {
+		return nil
+	}
+	return string(b)
+}
+
+// nullIfEmpty stores empty optional columns as NULL.
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// decodeCategories reads a categories column written by encodeCategories.
+func decodeCategories(raw sql.NullString) []string {
+	if !raw.Valid || raw.String == "" {
+		return nil
+	}
+	var categories []string
+	if err := json.Unmarshal([]byte(raw.String), &categories); err != nil {
+		log.Warn().Err(err).Str("categories", raw.String).Msg("Skipping malformed entry categories")
+		return nil
+	}
+	return categories
+}
+
 // SQLiteRepository is the concrete implementation of BlacklistRepository using SQLite.
 type SQLiteRepository struct {
-	db *sql.DB
+	db    *sql.DB
+	stmts *idb.Statements // Prepared match queries of db; nil runs them unprepared
 	// writeLock removed - no longer needed with single-threaded writer
 }
 
-// NewSQLiteRepository creates a new SQLiteRepository instance.
+// NewSQLiteRepository creates a new SQLiteRepository instance. On a pool of
+// db.InitializeDB it shares the pool's prepared statements, which are closed
+// with the pool.
 func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
-	return &SQLiteRepository{db: db}
+	return NewSQLiteRepositoryWithStatements(db, idb.GetStatements(db))
+}
+
+// NewSQLiteRepositoryWithStatements creates a SQLiteRepository preparing its
+// match queries in stmts, a cache of db the caller closes before db.
+func NewSQLiteRepositoryWithStatements(db *sql.DB, stmts *idb.Statements) *SQLiteRepository {
+	return &SQLiteRepository{db: db, stmts: stmts}
+}
+
+// queryCached runs query with the cached prepared statement for it, or
+// unprepared without a statement cache.
+func (r *SQLiteRepository) queryCached(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if r.stmts == nil {
+		return r.db.QueryContext(ctx, query, args...)
+	}
+	return r.stmts.QueryContext(ctx, query, args...)
 }
 
 func (r *SQLiteRepository) StreamEntriesCount(ctx context.Context) (int, error) {
@@ -132,9 +241,490 @@ func (r *SQLiteRepository) StreamEntries(ctx context.Context, out chan<- entries
 	return nil
 }
 
+// StreamChangedSourceURLs streams every source URL with a row updated or
+// soft-deleted at or after since (Unix nanos), paired with the IDs of its
+// active entries across all sources. An empty IDsRaw means the URL is no
+// longer listed anywhere. An empty source matches all sources.
+func (r *SQLiteRepository) StreamChangedSourceURLs(ctx context.Context, source string, since int64, out chan<- entries.EntryStream) error {
+	defer close(out)
+
+	changed, args := changedSourceURLsQuery(source, since)
+
+	query := `
+	SELECT
+		u.source_url,
+		COALESCE((
+			SELECT GROUP_CONCAT(a.id, ',')
+			FROM entries a
+			WHERE a.source_url = u.source_url AND a.deleted_at IS NULL
+		), '')
+	FROM (` + changed + `) u`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Err(err).Str("source", source).Int64("since", since).Msg("Failed to query changed source URLs")
+		return ErrToQuery
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sourceURL, idsConcat string
+		if err := rows.Scan(&sourceURL, &idsConcat); err != nil {
+			log.Err(err).Msg("Failed to scan changed source URL")
+			return ErrToScan
+		}
+
+		ids := []string{}
+		if idsConcat != "" {
+			ids = strings.Split(idsConcat, ",")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- entries.EntryStream{SourceUrl: sourceURL, IDs: ids, IDsRaw: idsConcat}:
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Error iterating changed source URLs")
+		return ErrRowsIteration
+	}
+	return nil
+}
+
+// CountChangedSourceURLs counts the source URLs StreamChangedSourceURLs would stream.
+func (r *SQLiteRepository) CountChangedSourceURLs(ctx context.Context, source string, since int64) (int, error) {
+	changed, args := changedSourceURLsQuery(source, since)
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+changed+")", args...).Scan(&count); err != nil {
+		log.Err(err).Str("source", source).Int64("since", since).Msg("Failed to count changed source URLs")
+		return 0, ErrToQuery
+	}
+	return count, nil
+}
+
+// changedSourceURLsQuery selects the distinct source URLs with a row updated
+// or soft-deleted at or after since, optionally limited to one source.
+func changedSourceURLsQuery(source string, since int64) (string, []any) {
+	query := "SELECT DISTINCT source_url FROM entries WHERE (updated_at >= ? OR deleted_at >= ?)"
+	args := []any{since, since}
+	if source != "" {
+		query += " AND source = ? COLLATE NOCASE"
+		args = append(args, source)
+	}
+	return query, args
+}
+
+// ProcessFirstWrite returns the earliest updated_at (Unix nanos) among the
+// rows still attributed to processID. processID may also name a provider
+// process run (provider_processes), in which case its start time is used.
+func (r *SQLiteRepository) ProcessFirstWrite(ctx context.Context, processID string) (int64, error) {
+	var first sql.NullInt64
+	err := r.db.QueryRowContext(ctx, "SELECT MIN(updated_at) FROM entries WHERE process_id = ?", processID).Scan(&first)
+	if err != nil {
+		log.Err(err).Str("process_id", processID).Msg("Failed to look up first write of process")
+		return 0, ErrToQuery
+	}
+	if first.Valid {
+		return first.Int64, nil
+	}
+
+	var started time.Time
+	err = r.db.QueryRowContext(ctx, "SELECT start_time FROM provider_processes WHERE id = ?", processID).Scan(&started)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrProcessNotFound
+	}
+	if err != nil {
+		log.Err(err).Str("process_id", processID).Msg("Failed to look up provider process start")
+		return 0, ErrToQuery
+	}
+	return started.UnixNano(), nil
+}
+
+// StreamEntriesActivatedSince calls fn for every active entry inserted or
+// reactivated at or after since (Unix nanos), oldest first. An empty source
+// matches all sources; source names are compared case-insensitively.
+func (r *SQLiteRepository) StreamEntriesActivatedSince(ctx context.Context, source string, since int64, fn func(*entries.Entry) error) error {
+	var f Filter
+	if source != "" {
+		f.Sources = []string{source}
+	}
+	if since > 0 {
+		t := time.Unix(0, since)
+		f.Since = &t
+	}
+	return r.StreamFilteredEntries(ctx, f, fn)
+}
+
+// StreamFilteredEntries calls fn for every entry matching f, oldest
+// activation first.
+func (r *SQLiteRepository) StreamFilteredEntries(ctx context.Context, f Filter, fn func(*entries.Entry) error) error {
+	where, args := f.Where()
+	query := "SELECT " + entryColumns + ", " + activatedAtExpr + " FROM entries WHERE " + where +
+		" ORDER BY " + activatedAtExpr + ", id"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Err(err).Interface("filter", f).Msg("Failed to query filtered entries")
+		return ErrToQuery
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry entries.Entry
+		var subDomainsStr string
+		var deletedAt sql.NullInt64
+		var categories sql.NullString
+		err := rows.Scan(
+			&entry.ID, &entry.ProcessID, &entry.Scheme, &entry.Domain, &entry.Host, &subDomainsStr,
+			&entry.Path, &entry.RawQuery, &entry.SourceURL, &entry.Source, &entry.Category,
+			&entry.Confidence, &entry.CreatedAt, &entry.UpdatedAt, &deletedAt, &categories, &entry.CIDR, &entry.Wildcard, &entry.ExpiresAt, &entry.ActivatedAt,
+		)
+		if err != nil {
+			log.Err(err).Interface("filter", f).Msg("Failed to scan filtered entry")
+			return ErrToScan
+		}
+		entry.Categories = decodeCategories(categories)
+		if subDomainsStr != "" {
+			entry.SubDomains = strings.Split(subDomainsStr, ",")
+		}
+
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Err(err).Interface("filter", f).Msg("Rows iteration error for filtered entries")
+		return ErrRowsIteration
+	}
+	return nil
+}
+
+// SearchEntries returns up to limit entries matching f with an ID greater
+// than afterID, ordered by ID, for keyset-paginated searches.
+func (r *SQLiteRepository) SearchEntries(ctx context.Context, f Filter, afterID string, limit int) ([]entries.Entry, error) {
+	where, args := f.Where()
+	query := "SELECT " + entryColumns + " FROM entries WHERE " + where + " AND id > ? ORDER BY id LIMIT ?"
+	args = append(args, afterID, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Err(err).Interface("filter", f).Str("after_id", afterID).Msg("Failed to search entries")
+		return nil, ErrToQuery
+	}
+	defer rows.Close()
+
+	return scanEntryRows(rows, limit)
+}
+
+// LatestChange returns when any entry was last inserted, updated,
+// reactivated or deleted, in Unix nanos; 0 for an empty table.
+func (r *SQLiteRepository) LatestChange(ctx context.Context) (int64, error) {
+	var latest sql.NullInt64
+	err := r.db.QueryRowContext(ctx,
+		"SELECT MAX(MAX(COALESCE(updated_at, 0)), MAX(COALESCE(deleted_at, 0)), MAX("+activatedAtExpr+")) FROM entries",
+	).Scan(&latest)
+	if err != nil {
+		log.Err(err).Msg("Failed to query latest entry change")
+		return 0, ErrToQuery
+	}
+	return latest.Int64, nil
+}
+
+// EntryStats counts the entries matching a filter.
+type EntryStats struct {
+	Total      int            `json:"total"`
+	BySource   map[string]int `json:"by_source"`
+	ByCategory map[string]int `json:"by_category"`
+}
+
+// GetEntryStats counts the entries matching f, in total and per source and
+// category. A multi-category entry counts once under each of its categories.
+func (r *SQLiteRepository) GetEntryStats(ctx context.Context, f Filter) (*EntryStats, error) {
+	where, args := f.Where()
+	query := "SELECT source, category, categories, COUNT(*) FROM entries WHERE " + where + " GROUP BY source, category, categories"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Err(err).Interface("filter", f).Msg("Failed to query entry stats")
+		return nil, ErrToQuery
+	}
+	defer rows.Close()
+
+	stats := &EntryStats{BySource: map[string]int{}, ByCategory: map[string]int{}}
+	for rows.Next() {
+		var source, category string
+		var categories sql.NullString
+		var count int
+		if err := rows.Scan(&source, &category, &categories, &count); err != nil {
+			log.Err(err).Msg("Failed to scan entry stats row")
+			return nil, ErrToScan
+		}
+		stats.Total += count
+		stats.BySource[source] += count
+
+		all := decodeCategories(categories)
+		if len(all) == 0 {
+			all = []string{category}
+		}
+		for _, c := range all {
+			stats.ByCategory[c] += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Rows iteration error for entry stats")
+		return nil, ErrRowsIteration
+	}
+	return stats, nil
+}
+
+// GetEntriesPage returns up to limit active entries with an ID greater than
+// afterID, ordered by ID, for keyset-paginated scans. An empty source matches all.
+func (r *SQLiteRepository) GetEntriesPage(ctx context.Context, source, afterID string, limit int) ([]entries.Entry, error) {
+	query := "SELECT " + entryColumns + " FROM entries WHERE deleted_at IS NULL AND id > ?"
+	args := []any{afterID}
+	if source != "" {
+		query += " AND source = ?"
+		args = append(args, source)
+	}
+	query += " ORDER BY id LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Err(err).Str("source", source).Str("after_id", afterID).Msg("Failed to query entries page")
+		return nil, ErrToQuery
+	}
+	defer rows.Close()
+
+	page := make([]entries.Entry, 0, limit)
+	for rows.Next() {
+		var entry entries.Entry
+		var subDomainsStr string
+		var deletedAt sql.NullInt64
+		var categories sql.NullString
+		err := rows.Scan(
+			&entry.ID, &entry.ProcessID, &entry.Scheme, &entry.Domain, &entry.Host, &subDomainsStr,
+			&entry.Path, &entry.RawQuery, &entry.SourceURL, &entry.Source, &entry.Category,
+			&entry.Confidence, &entry.CreatedAt, &entry.UpdatedAt, &deletedAt, &categories, &entry.CIDR, &entry.Wildcard, &entry.ExpiresAt,
+		)
+		if err != nil {
+			log.Err(err).Str("source", source).Msg("Failed to scan entries page row")
+			return nil, ErrToScan
+		}
+		entry.Categories = decodeCategories(categories)
+		if subDomainsStr != "" {
+			entry.SubDomains = strings.Split(subDomainsStr, ",")
+		}
+		page = append(page, entry)
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Str("source", source).Msg("Rows iteration error for entries page")
+		return nil, ErrRowsIteration
+	}
+	return page, nil
+}
+
+// GetEntriesUnderHost returns up to limit active entries whose host is name
+// or any subdomain of it, ordered by host. It is a range scan on the
+// reversed-host index, so passing a registered domain returns every host under it.
+func (r *SQLiteRepository) GetEntriesUnderHost(ctx context.Context, name string, limit int) ([]entries.Entry, error) {
+	prefix := utils.ReverseHost(name)
+	if prefix == "" {
+		return []entries.Entry{}, nil
+	}
+	// "/" sorts right after ".", so [prefix, upper) holds every key starting with prefix.
+	upper := strings.TrimSuffix(prefix, ".") + "/"
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+entryColumns+` FROM entries
+		WHERE reversed_host >= ? AND reversed_host < ? AND deleted_at IS NULL
+		ORDER BY reversed_host, id LIMIT ?`, prefix, upper, limit)
+	if err != nil {
+		log.Err(err).Str("name", name).Msg("Failed to query entries under host")
+		return nil, ErrToQuery
+	}
+	defer rows.Close()
+
+	return scanEntryRows(rows, limit)
+}
+
+// GetEntriesByIPs returns up to limit active entries whose host is one of the given IP literals.
+func (r *SQLiteRepository) GetEntriesByIPs(ctx context.Context, ips []string, limit int) ([]entries.Entry, error) {
+	args := make([]any, 0, len(ips)+1)
+	for _, ip := range ips {
+		if canonical := utils.HostIP(ip); canonical != "" {
+			args = append(args, canonical)
+		}
+	}
+	if len(args) == 0 {
+		return []entries.Entry{}, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+entryColumns+` FROM entries
+		WHERE ip IN (`+placeholders+`) AND deleted_at IS NULL
+		ORDER BY ip, id LIMIT ?`, args...)
+	if err != nil {
+		log.Err(err).Strs("ips", ips).Msg("Failed to query entries by IP")
+		return nil, ErrToQuery
+	}
+	defer rows.Close()
+
+	return scanEntryRows(rows, limit)
+}
+
+// GetIDsBySourceURLs returns the active entry IDs of each source URL, the
+// value a cache key holds. Source URLs without active entries are absent.
+func (r *SQLiteRepository) GetIDsBySourceURLs(ctx context.Context, sourceURLs []string) (map[string][]string, error) {
+	result := make(map[string][]string, len(sourceURLs))
+	if len(sourceURLs) == 0 {
+		return result, nil
+	}
+
+	args := make([]any, len(sourceURLs))
+	for i, u := range sourceURLs {
+		args[i] = u
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+
+	rows, err := r.db.QueryContext(ctx, `SELECT source_url, id FROM entries
+		WHERE source_url IN (`+placeholders+`) AND deleted_at IS NULL`, args...)
+	if err != nil {
+		log.Err(err).Int("source_urls", len(sourceURLs)).Msg("Failed to query IDs by source URL")
+		return nil, ErrToQuery
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sourceURL, id string
+		if err := rows.Scan(&sourceURL, &id); err != nil {
+			log.Err(err).Msg("Failed to scan source URL ID row")
+			return nil, ErrToScan
+		}
+		result[sourceURL] = append(result[sourceURL], id)
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Rows iteration error for source URL IDs")
+		return nil, ErrRowsIteration
+	}
+	return result, nil
+}
+
+// SampleSourceURLs returns up to n distinct active source URLs starting at a
+// random rowid, which avoids the full scan of ORDER BY RANDOM().
+func (r *SQLiteRepository) SampleSourceURLs(ctx context.Context, n int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT source_url FROM entries
+		WHERE rowid >= (SELECT ABS(RANDOM()) % (COALESCE(MAX(rowid), 0) + 1) FROM entries)
+		  AND deleted_at IS NULL
+		LIMIT ?`, n)
+	if err != nil {
+		log.Err(err).Msg("Failed to sample source URLs")
+		return nil, ErrToQuery
+	}
+	defer rows.Close()
+
+	urls := make([]string, 0, n)
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			log.Err(err).Msg("Failed to scan sampled source URL")
+			return nil, ErrToScan
+		}
+		urls = append(urls, u)
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Rows iteration error for sampled source URLs")
+		return nil, ErrRowsIteration
+	}
+	return urls, nil
+}
+
+// scanEntryRows scans rows selected with entryColumns.
+func scanEntryRows(rows *sql.Rows, capacity int) ([]entries.Entry, error) {
+	result := make([]entries.Entry, 0, capacity)
+	for rows.Next() {
+		var entry entries.Entry
+		var subDomainsStr string
+		var deletedAt sql.NullInt64
+		var categories sql.NullString
+		err := rows.Scan(
+			&entry.ID, &entry.ProcessID, &entry.Scheme, &entry.Domain, &entry.Host, &subDomainsStr,
+			&entry.Path, &entry.RawQuery, &entry.SourceURL, &entry.Source, &entry.Category,
+			&entry.Confidence, &entry.CreatedAt, &entry.UpdatedAt, &deletedAt, &categories, &entry.CIDR, &entry.Wildcard, &entry.ExpiresAt,
+		)
+		if err != nil {
+			log.Err(err).Msg("Failed to scan entry row")
+			return nil, ErrToScan
+		}
+		entry.Categories = decodeCategories(categories)
+		if subDomainsStr != "" {
+			entry.SubDomains = strings.Split(subDomainsStr, ",")
+		}
+		if deletedAt.Valid {
+			entry.DeletedAt = &deletedAt.Int64
+		}
+		result = append(result, entry)
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Rows iteration error for entries")
+		return nil, ErrRowsIteration
+	}
+	return result, nil
+}
+
+// UpdateEntryURLFields rewrites the parsed URL fields and process ID of
+// existing entries by ID, leaving source_url, timestamps of first sight and
+// soft-delete state untouched.
+func (r *SQLiteRepository) UpdateEntryURLFields(ctx context.Context, batch []*entries.Entry) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to begin transaction for UpdateEntryURLFields")
+		return ErrTx
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		UPDATE entries SET
+			process_id = ?, scheme = ?, domain = ?, host = ?, sub_domains = ?,
+			path = ?, raw_query = ?, updated_at = ?, reversed_host = ?, ip = ?, cidr = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		log.Err(err).Msg("Failed to prepare entry URL fields update")
+		return ErrTxPrepare
+	}
+	defer stmt.Close()
+
+	for _, entry := range batch {
+		_, err := stmt.ExecContext(ctx,
+			entry.ProcessID, entry.Scheme, entry.Domain, entry.Host, strings.Join(entry.SubDomains, ","),
+			entry.Path, entry.RawQuery, entry.UpdatedAt, utils.ReverseHost(entry.Host), utils.HostIP(entry.Host), nullIfEmpty(entry.CIDR), entry.ID,
+		)
+		if err != nil {
+			log.Err(err).Str("entry_id", entry.ID).Msg("Failed to update entry URL fields")
+			return ErrUpsert
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	r.indexes().networks.invalidate() // A reparse may add or drop networks
+	query.PurgeNegativeCache()
+	return nil
+}
+
 // GetAllEntries retrieves all active blacklist entries from SQLite.
 func (r *SQLiteRepository) GetAllEntries(ctx context.Context) ([]entries.Entry, error) {
-	rows, err := r.db.QueryContext(ctx, "SELECT * FROM entries WHERE deleted_at IS NULL") // WHERE clause to filter out deleted entries
+	rows, err := r.db.QueryContext(ctx, "SELECT "+entryColumns+" FROM entries WHERE deleted_at IS NULL") // WHERE clause to filter out deleted entries
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to query all active entries from SQLite")
 		return nil, ErrQueryAllEntries
@@ -146,15 +736,17 @@ func (r *SQLiteRepository) GetAllEntries(ctx context.Context) ([]entries.Entry,
 		var entry entries.Entry
 		var subDomainsStr string
 		var deletedAt sql.NullInt64 // Use sql.NullInt64 for nullable DATETIME in DB
+		var categories sql.NullString
 		err := rows.Scan(
 			&entry.ID, &entry.ProcessID, &entry.Scheme, &entry.Domain, &entry.Host, &subDomainsStr,
 			&entry.Path, &entry.RawQuery, &entry.SourceURL, &entry.Source, &entry.Category,
-			&entry.Confidence, &entry.CreatedAt, &entry.UpdatedAt, &deletedAt, 
+			&entry.Confidence, &entry.CreatedAt, &entry.UpdatedAt, &deletedAt, &categories, &entry.CIDR, &entry.Wildcard, &entry.ExpiresAt, 
 		)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to scan row from SQLite")
 			return nil, ErrToScan
 		}
+		entry.Categories = decodeCategories(categories)
 		entry.SubDomains = strings.Split(subDomainsStr, ",")
 		if entry.SubDomains[0] == "" {
 			entry.SubDomains = nil
@@ -175,15 +767,16 @@ func (r *SQLiteRepository) GetAllEntries(ctx context.Context) ([]entries.Entry,
 
 // GetEntryByID retrieves a blacklist entry by its ID from SQLite, even if deleted.
 func (r *SQLiteRepository) GetEntryByID(ctx context.Context, id string) (*entries.Entry, error) {
-	row := r.db.QueryRowContext(ctx, "SELECT * FROM entries WHERE id = ?", id) // No WHERE deleted_at IS NULL here if you want to retrieve deleted entries too
+	row := r.db.QueryRowContext(ctx, "SELECT "+entryColumns+" FROM entries WHERE id = ?", id) // No WHERE deleted_at IS NULL here if you want to retrieve deleted entries too
 	var entry entries.Entry
 	var subDomainsStr string
 	var deletedAt sql.NullInt64 
+	var categories sql.NullString
 
 	err := row.Scan(
 		&entry.ID, &entry.ProcessID, &entry.Scheme, &entry.Domain, &entry.Host, &subDomainsStr,
 		&entry.Path, &entry.RawQuery, &entry.SourceURL, &entry.Source, &entry.Category,
-		&entry.Confidence, &entry.CreatedAt, &entry.UpdatedAt, &deletedAt,
+		&entry.Confidence, &entry.CreatedAt, &entry.UpdatedAt, &deletedAt, &categories, &entry.CIDR, &entry.Wildcard, &entry.ExpiresAt,
 	)
 
 	if err != nil {
@@ -198,6 +791,7 @@ func (r *SQLiteRepository) GetEntryByID(ctx context.Context, id string) (*entrie
 		return nil, ErrToQuery
 	}
 
+	entry.Categories = decodeCategories(categories)
 	entry.SubDomains = strings.Split(subDomainsStr, ",") // Split the string
 	if entry.SubDomains[0] == "" {
 		entry.SubDomains = nil
@@ -217,7 +811,7 @@ func (r *SQLiteRepository) GetEntriesByIDs(ctx context.Context, ids []string) ([
 
 	// Construct the query with a WHERE id IN (...) clause
 	query := `
-		SELECT id, process_id, scheme, domain, host, sub_domains, path, raw_query, source_url, source, category, confidence, created_at, updated_at, deleted_at
+		SELECT `+entryColumns+`
 		FROM entries
 		WHERE id IN (` + strings.Join(strings.Split(strings.Repeat("?", len(ids)), ""), ", ") + `)` // Generate placeholders
 	// AND deleted_at IS NULL -- If you only want active entries
@@ -243,17 +837,19 @@ func (r *SQLiteRepository) GetEntriesByIDs(ctx context.Context, ids []string) ([
 		var entry entries.Entry
 		var subDomainsStr string
 		var deletedAt sql.NullInt64 
+		var categories sql.NullString
 
 		err := rows.Scan(
 			&entry.ID, &entry.ProcessID, &entry.Scheme, &entry.Domain, &entry.Host, &subDomainsStr,
 			&entry.Path, &entry.RawQuery, &entry.SourceURL, &entry.Source, &entry.Category,
-			&entry.Confidence, &entry.CreatedAt, &entry.UpdatedAt, &deletedAt,
+			&entry.Confidence, &entry.CreatedAt, &entry.UpdatedAt, &deletedAt, &categories, &entry.CIDR, &entry.Wildcard, &entry.ExpiresAt,
 		)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to scan row from SQLite")
 			continue // Skip to the next row
 		}
 
+		entry.Categories = decodeCategories(categories)
 		entry.SubDomains = strings.Split(subDomainsStr, ",")
 		if entry.SubDomains[0] == "" {
 			entry.SubDomains = nil
@@ -276,7 +872,7 @@ func (r *SQLiteRepository) GetEntriesByIDs(ctx context.Context, ids []string) ([
 
 // GetEntriesBySource retrieves all active blacklist entries for a given source from SQLite.
 func (r *SQLiteRepository) GetEntriesBySource(ctx context.Context, source string) ([]entries.Entry, error) {
-	rows, err := r.db.QueryContext(ctx, "SELECT * FROM entries WHERE source = ? AND deleted_at IS NULL") // Added WHERE deleted_at IS NULL
+	rows, err := r.db.QueryContext(ctx, "SELECT "+entryColumns+" FROM entries WHERE source = ? AND deleted_at IS NULL", source) // Added WHERE deleted_at IS NULL
 	if err != nil {
 		log.Err(err).
 			Str("source", source).
@@ -291,10 +887,11 @@ func (r *SQLiteRepository) GetEntriesBySource(ctx context.Context, source string
 		var entry entries.Entry
 		var subDomainsStr string
 		var deletedAt sql.NullInt64 
+		var categories sql.NullString
 		err := rows.Scan(
 			&entry.ID, &entry.ProcessID, &entry.Scheme, &entry.Domain, &entry.Host, &subDomainsStr,
 			&entry.Path, &entry.RawQuery, &entry.SourceURL, &entry.Source, &entry.Category,
-			&entry.Confidence, &entry.CreatedAt, &entry.UpdatedAt, &deletedAt, 
+			&entry.Confidence, &entry.CreatedAt, &entry.UpdatedAt, &deletedAt, &categories, &entry.CIDR, &entry.Wildcard, &entry.ExpiresAt, 
 		)
 		if err != nil {
 			log.Err(err).
@@ -303,6 +900,7 @@ func (r *SQLiteRepository) GetEntriesBySource(ctx context.Context, source string
 
 			return nil, ErrToScan
 		}
+		entry.Categories = decodeCategories(categories)
 		entry.SubDomains = strings.Split(subDomainsStr, ",")
 		if entry.SubDomains[0] == "" {
 			entry.SubDomains = nil
@@ -324,9 +922,11 @@ func (r *SQLiteRepository) GetEntriesBySource(ctx context.Context, source string
 	return _entries, nil
 }
 
-// GetEntriesByCategory retrieves all active blacklist entries for a given category from SQLite.
+// GetEntriesByCategory retrieves all active blacklist entries listing category,
+// as their primary category or any other, from SQLite.
 func (r *SQLiteRepository) GetEntriesByCategory(ctx context.Context, category string) ([]entries.Entry, error) {
-	rows, err := r.db.QueryContext(ctx, "SELECT * FROM entries WHERE category = ? AND deleted_at IS NULL") // Added WHERE deleted_at IS NULL
+	rows, err := r.db.QueryContext(ctx, "SELECT "+entryColumns+` FROM entries
+		WHERE (category = ? OR id IN (SELECT entry_id FROM entry_categories WHERE category = ?)) AND deleted_at IS NULL`, category, category)
 	if err != nil {
 		log.Err(err).
 			Str("category", category).
@@ -341,10 +941,11 @@ func (r *SQLiteRepository) GetEntriesByCategory(ctx context.Context, category st
 		var entry entries.Entry
 		var subDomainsStr string
 		var deletedAt sql.NullInt64 
+		var categories sql.NullString
 		err := rows.Scan(
 			&entry.ID, &entry.ProcessID, &entry.Scheme, &entry.Domain, &entry.Host, &subDomainsStr,
 			&entry.Path, &entry.RawQuery, &entry.SourceURL, &entry.Source, &entry.Category,
-			&entry.Confidence, &entry.CreatedAt, &entry.UpdatedAt, &deletedAt, 
+			&entry.Confidence, &entry.CreatedAt, &entry.UpdatedAt, &deletedAt, &categories, &entry.CIDR, &entry.Wildcard, &entry.ExpiresAt, 
 		)
 		if err != nil {
 			log.Err(err).
@@ -353,6 +954,7 @@ func (r *SQLiteRepository) GetEntriesByCategory(ctx context.Context, category st
 
 			return nil, ErrToScan
 		}
+		entry.Categories = decodeCategories(categories)
 		entry.SubDomains = strings.Split(subDomainsStr, ",")
 		if entry.SubDomains[0] == "" {
 			entry.SubDomains = nil
@@ -387,25 +989,33 @@ func (r *SQLiteRepository) SaveEntry(ctx context.Context, entry entries.Entry) e
 
 	_, err = tx.ExecContext(ctx, `
 			INSERT INTO entries (
-				id, process_id, scheme, domain, host, sub_domains, path, raw_query, source_url, source, category, confidence, created_at, updated_at, deleted_at
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL) -- Insert with NULL deleted_at for new entries
+				id, process_id, scheme, domain, host, sub_domains, path, raw_query, source_url, source, category, confidence, created_at, updated_at, deleted_at, activated_at, reversed_host, ip, categories, cidr, wildcard, expires_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL, ?, ?, ?, ?, ?, ?, ?) -- Insert with NULL deleted_at for new entries
 			ON CONFLICT (source_url, source) DO UPDATE SET -- UPSERT logic on conflict of 'source_url' and 'source'
 				process_id = EXCLUDED.process_id,
 				scheme = EXCLUDED.scheme,
 				domain = EXCLUDED.domain,
 				host = EXCLUDED.host,
+				reversed_host = EXCLUDED.reversed_host,
+				ip = EXCLUDED.ip,
 				sub_domains = EXCLUDED.sub_domains,
 				path = EXCLUDED.path,
 				raw_query = EXCLUDED.raw_query,
 				category = EXCLUDED.category,
+				categories = EXCLUDED.categories,
+				cidr = EXCLUDED.cidr,
+				wildcard = EXCLUDED.wildcard,
 				confidence = EXCLUDED.confidence,
+				expires_at = EXCLUDED.expires_at, -- Listing the entry again restarts its TTL
 				updated_at = EXCLUDED.updated_at, -- Update 'updated_at' on update
+				activated_at = CASE WHEN entries.deleted_at IS NOT NULL THEN EXCLUDED.updated_at ELSE entries.activated_at END, -- Reactivation restarts activated_at
 				deleted_at = NULL                  -- Ensure entry is NOT deleted upon update (reset soft delete)
 			WHERE EXCLUDED.updated_at > entries.updated_at -- Optional: Update only if new data is "newer" (based on UpdatedAt)
 		`,
 		entry.ID, entry.ProcessID, entry.Scheme, entry.Domain, entry.Host, strings.Join(entry.SubDomains, ","),
 		entry.Path, entry.RawQuery, entry.SourceURL, entry.Source, entry.Category, entry.Confidence,
-		entry.CreatedAt, entry.UpdatedAt,
+		entry.CreatedAt, entry.UpdatedAt, entry.CreatedAt, utils.ReverseHost(entry.Host), utils.HostIP(entry.Host),
+		encodeCategories(entry.Categories), nullIfEmpty(entry.CIDR), entry.Wildcard, entry.ExpiresAt,
 	)
 
 	if err != nil {
@@ -413,7 +1023,12 @@ func (r *SQLiteRepository) SaveEntry(ctx context.Context, entry entries.Entry) e
 		log.Error().Err(err).Str("entry_id", entry.ID).Str("source_url", entry.SourceURL).Msg("Failed to UPSERT entry")
 		return ErrUpsert
 	}
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	r.invalidateNetworks([]*entries.Entry{&entry})
+	query.PurgeNegativeCache()
+	return nil
 }
 
 // blackLinks/repository.go
@@ -442,19 +1057,26 @@ func (r *SQLiteRepository) BatchSaveEntries(ctx context.Context, entries []*entr
 
 	stmt, err := tx.PrepareContext(ctx, `
         INSERT INTO entries (
-            id, process_id, scheme, domain, host, sub_domains, path, raw_query, source_url, source, category, confidence, created_at, updated_at, deleted_at
-        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL)
+            id, process_id, scheme, domain, host, sub_domains, path, raw_query, source_url, source, category, confidence, created_at, updated_at, deleted_at, activated_at, reversed_host, ip, categories, cidr, wildcard, expires_at
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL, ?, ?, ?, ?, ?, ?, ?)
         ON CONFLICT (source_url, source) DO UPDATE SET
             process_id = EXCLUDED.process_id,
             scheme = EXCLUDED.scheme,
             domain = EXCLUDED.domain,
             host = EXCLUDED.host,
+            reversed_host = EXCLUDED.reversed_host,
+            ip = EXCLUDED.ip,
             sub_domains = EXCLUDED.sub_domains,
             path = EXCLUDED.path,
             raw_query = EXCLUDED.raw_query,
             category = EXCLUDED.category,
+            categories = EXCLUDED.categories,
+            cidr = EXCLUDED.cidr,
+            wildcard = EXCLUDED.wildcard,
             confidence = EXCLUDED.confidence,
+            expires_at = EXCLUDED.expires_at,
             updated_at = EXCLUDED.updated_at,
+            activated_at = CASE WHEN entries.deleted_at IS NOT NULL THEN EXCLUDED.updated_at ELSE entries.activated_at END,
             deleted_at = NULL
     `)
 	if err != nil {
@@ -478,7 +1100,8 @@ func (r *SQLiteRepository) BatchSaveEntries(ctx context.Context, entries []*entr
 		_, err := stmt.ExecContext(ctx,
 			entry.ID, entry.ProcessID, entry.Scheme, entry.Domain, entry.Host, subDomainsStr,
 		entry.Path, entry.RawQuery, entry.SourceURL, entry.Source, entry.Category, entry.Confidence,
-		entry.CreatedAt, entry.UpdatedAt,
+		entry.CreatedAt, entry.UpdatedAt, entry.CreatedAt, utils.ReverseHost(entry.Host), utils.HostIP(entry.Host),
+		encodeCategories(entry.Categories), nullIfEmpty(entry.CIDR), entry.Wildcard, entry.ExpiresAt,
 		)
 		if err != nil {
 			log.Error().Err(err).Str("entry_id", entry.ID).Str("source_url", entry.SourceURL).Msg("Error executing batch statement for entry")
@@ -486,43 +1109,87 @@ func (r *SQLiteRepository) BatchSaveEntries(ctx context.Context, entries []*entr
 		}
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	r.invalidateNetworks(entries)
+	query.PurgeNegativeCache()
+	return nil
 }
 
 // RemoveOlderInsertions soft deletes blacklist entries from a provider that do not have the latest insertion ID.
+// Large providers are swept in rowid ranges of removeOlderBatch entries, each its own
+// transaction with a short pause after it, so queries and other writers get the database
+// between batches instead of waiting out one statement over millions of rows. Every batch
+// stamps the same deleted_at. A failed run leaves the batches before it deleted; the next
+// run of the provider sweeps the rest.
 func (r *SQLiteRepository) RemoveOlderInsertions(ctx context.Context, providerName string, currentProcessID string) error {
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		log.Err(err).
-			Str("provider", providerName).
-			Msg("Failed to begin transaction for RemoveOlderInsertions")
+	tracer := otel.Tracer("blacked/repository")
+	ctx, span := tracer.Start(ctx, "repository.remove_older",
+		trace.WithAttributes(
+			attribute.String("provider.name", providerName),
+			attribute.String("process.id", currentProcessID),
+		),
+	)
+	defer span.End()
 
-		return ErrTx
-	}
-	defer tx.Rollback()
+	started := time.Now()
+	currentTime := started.UnixNano()
+	var after, removed int64
+	batches := 0
+	for {
+		// Upper rowid of the next batch; idx_entries_source keeps the scan to the provider's rows.
+		var upTo sql.NullInt64
+		err := r.db.QueryRowContext(ctx, `
+			SELECT MAX(rowid) FROM (
+				SELECT rowid FROM entries
+				WHERE source = ? AND rowid > ?
+				  AND process_id != ?
+				  AND deleted_at IS NULL -- Only update entries that are currently NOT deleted (active)
+				ORDER BY rowid LIMIT ?)
+		`, providerName, after, currentProcessID, removeOlderBatch).Scan(&upTo)
+		if err != nil {
+			log.Error().Err(err).Str("provider", providerName).Msg("Failed to find the next batch of older insertions")
+			return ErrDelete
+		}
+		if !upTo.Valid {
+			break
+		}
 
-	currentTime := time.Now().UnixNano()
-	result, err := tx.ExecContext(ctx, `
-		UPDATE entries
-		SET deleted_at = ?
-		WHERE source = ?
-		  AND process_id != ?
-		  AND deleted_at IS NULL -- Only update entries that are currently NOT deleted (active)
-	`, currentTime, providerName, currentProcessID)
+		result, err := r.db.ExecContext(ctx, `
+			UPDATE entries
+			SET deleted_at = ?
+			WHERE source = ?
+			  AND rowid > ? AND rowid <= ?
+			  AND process_id != ?
+			  AND deleted_at IS NULL
+		`, currentTime, providerName, after, upTo.Int64, currentProcessID)
+		if err != nil {
+			log.Error().Err(err).Str("provider", providerName).Int64("removed", removed).Msg("Failed to soft delete older insertions")
+			return ErrDelete
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			removed += n
+		}
+		after = upTo.Int64
+		batches++
+		log.Debug().Str("provider", providerName).Int("batch", batches).Int64("removed", removed).Msg("Soft deleted a batch of older insertions")
 
-	if err != nil {
-		log.Error().Err(err).Str("provider", providerName).Msg("Failed to soft delete older insertions")
-		return ErrDelete
+		select {
+		case <-ctx.Done():
+			log.Warn().Err(ctx.Err()).Str("provider", providerName).Int64("removed", removed).Msg("Removal of older insertions interrupted")
+			return ErrDelete
+		case <-time.After(removeOlderYield):
+		}
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		log.Warn().Err(err).Str("provider", providerName).Msg("Failed to get rows affected count during RemoveOlderInsertions")
-	} else {
-		log.Debug().Int64("rows_affected", rowsAffected).Str("provider", providerName).Msg("Rows affected during RemoveOlderInsertions")
+	span.SetAttributes(attribute.Int64("entries.removed", removed), attribute.Int("batches", batches))
+	event := log.Debug()
+	if batches > 1 {
+		event = log.Info()
 	}
-
-	return tx.Commit()
+	event.Int64("rows_affected", removed).Int("batches", batches).Dur("duration", time.Since(started)).Str("provider", providerName).Msg("Rows affected during RemoveOlderInsertions")
+	return nil
 }
 
 // ClearAllEntries performs a SOFT DELETE of all blacklist entries.
@@ -569,66 +1236,417 @@ func (r *SQLiteRepository) SoftDeleteEntryByID(ctx context.Context, id string) e
 	return tx.Commit()
 }
 
+// SoftDeleteEntries soft deletes every entry matching f and returns how many
+// were deleted. An empty filter is refused rather than deleting everything;
+// ClearAllEntries does that.
+func (r *SQLiteRepository) SoftDeleteEntries(ctx context.Context, f Filter) (int64, error) {
+	if f.IsEmpty() {
+		return 0, ErrEmptyFilter
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Err(err).Msg("Failed to begin transaction for SoftDeleteEntries")
+		return 0, ErrTx
+	}
+	defer tx.Rollback()
+
+	where, args := f.Where()
+	args = append([]any{time.Now().UnixNano()}, args...)
+	res, err := tx.ExecContext(ctx, "UPDATE entries SET deleted_at = ? WHERE "+where, args...)
+	if err != nil {
+		log.Err(err).Interface("filter", f).Msg("Failed to soft delete filtered entries in SQLite")
+		return 0, ErrDelete
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		log.Err(err).Msg("Failed to count soft deleted entries")
+		return 0, ErrDelete
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Msg("Failed to commit soft delete of filtered entries")
+		return 0, ErrTx
+	}
+	return deleted, nil
+}
+
+// SoftDeleteSourceURLs soft deletes the active entries of source with the
+// given source URLs, as delta ingestion does for lines gone from a feed, and
+// returns how many were deleted.
+func (r *SQLiteRepository) SoftDeleteSourceURLs(ctx context.Context, source string, sourceURLs []string) (int64, error) {
+	if len(sourceURLs) == 0 {
+		return 0, nil
+	}
+
+	tracer := otel.Tracer("blacked/repository")
+	ctx, span := tracer.Start(ctx, "repository.soft_delete_source_urls",
+		trace.WithAttributes(
+			attribute.String("source", source),
+			attribute.Int("source_urls", len(sourceURLs)),
+		),
+	)
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Err(err).Str("source", source).Msg("Failed to begin transaction for SoftDeleteSourceURLs")
+		return 0, ErrTx
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixNano()
+	var deleted int64
+	for chunk := range slices.Chunk(sourceURLs, sourceURLDeleteChunk) {
+		args := make([]any, 0, len(chunk)+2)
+		args = append(args, now, source)
+		for _, u := range chunk {
+			args = append(args, u)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(chunk)), ", ")
+
+		res, err := tx.ExecContext(ctx, `UPDATE entries SET deleted_at = ?
+			WHERE source = ? AND source_url IN (`+placeholders+`) AND deleted_at IS NULL`, args...)
+		if err != nil {
+			log.Err(err).Str("source", source).Int("source_urls", len(chunk)).Msg("Failed to soft delete entries by source URL")
+			return 0, ErrDelete
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			log.Err(err).Str("source", source).Msg("Failed to count soft deleted entries")
+			return 0, ErrDelete
+		}
+		deleted += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Str("source", source).Msg("Failed to commit soft delete by source URL")
+		return 0, ErrTx
+	}
+	return deleted, nil
+}
+
+// SoftDeleteExpired soft deletes up to limit active entries whose expires_at
+// is at or before now (Unix nanos) and returns how many were deleted.
+func (r *SQLiteRepository) SoftDeleteExpired(ctx context.Context, now int64, limit int) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE entries SET deleted_at = ?
+		WHERE id IN (
+			SELECT id FROM entries
+			WHERE expires_at IS NOT NULL AND expires_at <= ? AND deleted_at IS NULL
+			LIMIT ?)`, now, now, limit)
+	if err != nil {
+		log.Err(err).Msg("Failed to soft delete expired entries")
+		return 0, ErrDelete
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		log.Err(err).Msg("Failed to count expired entries")
+		return 0, ErrDelete
+	}
+	return deleted, nil
+}
+
+// SetSourceExpiry sets the expiry of every active entry of source, as a
+// delta ingestion run does for the entries it left unchanged, and returns
+// how many changed. A nil expiresAt clears it.
+func (r *SQLiteRepository) SetSourceExpiry(ctx context.Context, source string, expiresAt *int64) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE entries SET expires_at = ?
+		WHERE source = ? AND deleted_at IS NULL AND expires_at IS NOT ?`, expiresAt, source, expiresAt)
+	if err != nil {
+		log.Err(err).Str("source", source).Msg("Failed to set the expiry of source entries")
+		return 0, ErrUpsert
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		log.Err(err).Str("source", source).Msg("Failed to count entries with a new expiry")
+		return 0, ErrUpsert
+	}
+	return n, nil
+}
+
+// CountDeletedBefore returns how many entries were soft deleted before
+// before (Unix nanos).
+func (r *SQLiteRepository) CountDeletedBefore(ctx context.Context, before int64) (int64, error) {
+	var n int64
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM entries WHERE deleted_at IS NOT NULL AND deleted_at < ?", before).Scan(&n); err != nil {
+		log.Err(err).Msg("Failed to count deleted entries")
+		return 0, ErrToQuery
+	}
+	return n, nil
+}
+
+// PurgeDeletedBefore removes up to limit entries soft deleted before before
+// (Unix nanos) for good, with their hit counters, and returns how many were
+// removed. The delete triggers record the removals in the change feed.
+func (r *SQLiteRepository) PurgeDeletedBefore(ctx context.Context, before int64, limit int) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Err(err).Msg("Failed to begin transaction for PurgeDeletedBefore")
+		return 0, ErrTx
+	}
+	defer tx.Rollback()
+
+	// Both statements pick the same rows: the transaction sees no other writes.
+	const purged = `SELECT id FROM entries
+		WHERE deleted_at IS NOT NULL AND deleted_at < ?
+		ORDER BY deleted_at, id LIMIT ?`
+	if _, err := tx.ExecContext(ctx, "DELETE FROM entry_hits WHERE entry_id IN ("+purged+")", before, limit); err != nil {
+		log.Err(err).Msg("Failed to delete hit counters of deleted entries")
+		return 0, ErrDelete
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM entries WHERE id IN ("+purged+")", before, limit)
+	if err != nil {
+		log.Err(err).Msg("Failed to purge deleted entries")
+		return 0, ErrDelete
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		log.Err(err).Msg("Failed to count purged entries")
+		return 0, ErrDelete
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Msg("Failed to commit PurgeDeletedBefore")
+		return 0, ErrTx
+	}
+	return n, nil
+}
+
+// GetNearDuplicates returns the active entries sharing their source, host
+// (case-insensitively), path without trailing slashes and query with another
+// active entry, ordered by that key and then oldest first. Scheme variants
+// and trailing-slash variants of one URL come back next to each other.
+// Entries without a host, such as javascript: links, are keyed by their
+// source URL. An empty source matches all.
+func (r *SQLiteRepository) GetNearDuplicates(ctx context.Context, source string) ([]entries.Entry, error) {
+	const key = "source, lower(host), rtrim(path, '/'), coalesce(raw_query, ''), " +
+		"CASE WHEN coalesce(host, '') = '' THEN source_url ELSE '' END"
+	where := "deleted_at IS NULL"
+	var args []any
+	if source != "" {
+		where += " AND source = ?"
+		args = append(args, source)
+	}
+	query := "SELECT " + entryColumns + " FROM entries WHERE " + where +
+		" AND (" + key + ") IN (SELECT " + key + " FROM entries WHERE " + where +
+		" GROUP BY " + key + " HAVING COUNT(*) > 1)" +
+		" ORDER BY " + key + ", created_at, id"
+	args = append(args, args...)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Err(err).Str("source", source).Msg("Failed to query near-duplicate entries")
+		return nil, ErrToQuery
+	}
+	defer rows.Close()
+	return scanEntryRows(rows, 0)
+}
+
+// EntryMerge folds the Merged entries into Canonical.
+type EntryMerge struct {
+	Canonical *entries.Entry // Rewritten with its categories, confidence, process ID and updated_at
+	Merged    []string       // IDs of the entries soft deleted into it
+}
+
+// MergeEntries applies merges in one transaction: each canonical entry is
+// rewritten, its merged entries are soft deleted and their hit counters are
+// added to the canonical entry's.
+func (r *SQLiteRepository) MergeEntries(ctx context.Context, merges []EntryMerge) error {
+	if len(merges) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Err(err).Msg("Failed to begin transaction for MergeEntries")
+		return ErrTx
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixNano()
+	for _, m := range merges {
+		c := m.Canonical
+		_, err := tx.ExecContext(ctx, `UPDATE entries SET
+				process_id = ?, category = ?, categories = ?, confidence = ?, updated_at = ?
+			WHERE id = ?`,
+			c.ProcessID, c.Category, encodeCategories(c.Categories), c.Confidence, c.UpdatedAt, c.ID,
+		)
+		if err != nil {
+			log.Err(err).Str("entry_id", c.ID).Msg("Failed to rewrite canonical entry")
+			return ErrUpsert
+		}
+		if len(m.Merged) == 0 {
+			continue
+		}
+
+		ids := make([]any, 0, len(m.Merged)+1)
+		for _, id := range m.Merged {
+			ids = append(ids, id)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+		args := append([]any{now}, ids...)
+		if _, err := tx.ExecContext(ctx, "UPDATE entries SET deleted_at = ? WHERE id IN ("+placeholders+") AND deleted_at IS NULL", args...); err != nil {
+			log.Err(err).Str("entry_id", c.ID).Msg("Failed to soft delete merged entries")
+			return ErrDelete
+		}
+
+		args = append([]any{c.ID}, append(ids, c.ID)...)
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO entry_hits (entry_id, hits, first_hit_at, last_hit_at)
+			SELECT ?, SUM(hits), MIN(first_hit_at), MAX(last_hit_at)
+			FROM entry_hits WHERE entry_id IN (`+placeholders+`, ?) HAVING COUNT(*) > 0
+			ON CONFLICT(entry_id) DO UPDATE SET
+				hits = excluded.hits,
+				first_hit_at = excluded.first_hit_at,
+				last_hit_at = excluded.last_hit_at`, args...)
+		if err != nil {
+			log.Err(err).Str("entry_id", c.ID).Msg("Failed to merge entry hits")
+			return ErrUpsert
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM entry_hits WHERE entry_id IN ("+placeholders+")", ids...); err != nil {
+			log.Err(err).Str("entry_id", c.ID).Msg("Failed to drop merged entry hits")
+			return ErrDelete
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Msg("Failed to commit entry merges")
+		return ErrTx
+	}
+	return nil
+}
+
+// UpdateEntryCategories rewrites the categories, process ID and updated_at
+// of each active entry of batch in one transaction. Deleted entries are left
+// alone.
+func (r *SQLiteRepository) UpdateEntryCategories(ctx context.Context, batch []*entries.Entry) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Err(err).Msg("Failed to begin transaction for UpdateEntryCategories")
+		return ErrTx
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `UPDATE entries SET
+			process_id = ?, category = ?, categories = ?, updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL`)
+	if err != nil {
+		log.Err(err).Msg("Failed to prepare entry category update")
+		return ErrUpsert
+	}
+	defer stmt.Close()
+
+	for _, e := range batch {
+		if _, err := stmt.ExecContext(ctx, e.ProcessID, e.Category, encodeCategories(e.Categories), e.UpdatedAt, e.ID); err != nil {
+			log.Err(err).Str("entry_id", e.ID).Msg("Failed to update entry categories")
+			return ErrUpsert
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Msg("Failed to commit entry category updates")
+		return ErrTx
+	}
+	return nil
+}
+
+// QueryLink matches link against active entries by exact URL, host,
+// registered domain and path. Each entry is reported once, under its
+// strongest match, ordered as entries.NormalizeHits documents.
 func (r *SQLiteRepository) QueryLink(ctx context.Context, link string) (
 	hits []entries.Hit,
 	err error) {
+	return r.queryLink(ctx, link, false)
+}
+
+// queryLink runs QueryLink, with the entry fields on every hit when enrich
+// is set.
+func (r *SQLiteRepository) queryLink(ctx context.Context, link string, enrich bool) ([]entries.Hit, error) {
+	var hits []entries.Hit
 	tracer := otel.Tracer("blacked/repository")
 	ctx, span := tracer.Start(ctx, "repository.query_link",
 		trace.WithAttributes(
-			attribute.String("query.link", link),
+			attribute.String("query.link", logger.RedactURL(link)),
 		),
 	)
 	defer span.End()
 
+	// Links of opaque schemes, such as javascript:, are stored trimmed with
+	// a lower-case scheme and have no host, domain or path to match on.
+	if opaque := utils.OpaqueLink(link); opaque != "" {
+		hits = r.queryExactURLMatch(ctx, nil, opaque, enrich)
+		return entries.NormalizeHits(r.queryPatternMatch(ctx, hits, link, enrich)), nil
+	}
+
 	normalizedLink := utils.NormalizeURL(link)
 	parsedURL, parseErr := url.Parse(normalizedLink)
 	if parseErr != nil {
 		// --- URL Parsing Failed ---
-		log.Warn().Err(parseErr).Str("raw_link", link).Msg("Failed to parse input URL, attempting exact match query only")
-		hits = append(hits, r.QueryExactURLMatch(ctx, normalizedLink)...)
-		return hits, nil
+		log.Warn().Err(parseErr).Str("raw_link", logger.RedactURL(link)).Msg("Failed to parse input URL, attempting exact match query only")
+		hits = r.queryExactURLMatch(ctx, nil, normalizedLink, enrich)
+		return entries.NormalizeHits(r.queryPatternMatch(ctx, hits, link, enrich)), nil
 	}
 
 	host := parsedURL.Hostname()
-	domain := ""
-
-	d, _, err := utils.ExtractDomainAndSubDomains(parsedURL.Host)
+	domain := utils.ExtractDomain(parsedURL.Host)
+	path := parsedURL.Path
 
+	// Exact URL, host, wildcard parent, domain and path matches in one
+	// statement. Source URLs are stored as the feed listed them, so the link
+	// is looked up as given too.
+	urls := []string{normalizedLink}
+	if raw := strings.TrimSpace(link); raw != normalizedLink {
+		urls = append(urls, raw)
+	}
+	// Paths are skipped when empty or "/", which every bare host shares.
+	if path == "/" {
+		path = ""
+	}
+	hits, err := r.queryLinkMatch(ctx, hits, urls, host, domain, path, enrich)
 	if err != nil {
-		log.Err(err).
-			Str("raw_link", link).
-			Str("parsed_host", parsedURL.Host).
-			Msg("error extracting domain and subdomains")
-	} else {
-		domain = d
+		return nil, err
 	}
-	path := parsedURL.Path
 
-	// Exact URL match
-	hits = append(hits, r.QueryExactURLMatch(ctx, normalizedLink)...)
+	// CIDR entries containing an IP host
+	if ip, err := netip.ParseAddr(host); err == nil {
+		hits = r.queryNetworkMatch(ctx, hits, ip, enrich)
+	}
 
-	// Host match
-	hits = append(hits, r.queryHostMatch(ctx, host)...)
+	// Regex and glob entries
+	hits = r.queryPatternMatch(ctx, hits, link, enrich)
 
-	// Domain match
-	hits = append(hits, r.queryDomainMatch(ctx, domain)...)
+	return entries.NormalizeHits(hits), nil
+}
 
-	// Path match
-	// query if path is empty or "/" and skip if so
-	// TODO Make path match optional or configurable
-	if path != "" && len(path) != 0 && path != "/" {
-		hits = append(hits, r.queryPathMatch(ctx, path)...)
-	}
+// QueryLinkByType queries blacklist entries based on URL criteria and query type. If queryType is nil, it defaults to a mixed query (QueryLink).
+// Hits follow the QueryLink ordering.
+func (r *SQLiteRepository) QueryLinkByType(ctx context.Context, link string, queryType *enums.QueryType) (
+	hits []entries.Hit,
+	err error) {
+	return r.queryLinkByType(ctx, link, queryType, false)
+}
 
-	return hits, nil
+// QueryLinkEnriched is QueryLinkByType with the matched entry's source,
+// categories, confidence and timestamps on every hit, selected by the match
+// queries themselves, so callers need no GetEntriesByIDs round trip.
+func (r *SQLiteRepository) QueryLinkEnriched(ctx context.Context, link string, queryType *enums.QueryType) ([]entries.Hit, error) {
+	return r.queryLinkByType(ctx, link, queryType, true)
 }
 
-// QueryLinkByType queries blacklist entries based on URL criteria and query type.  If queryType is nil, it defaults to a full URL query.
-func (r *SQLiteRepository) QueryLinkByType(ctx context.Context, link string, queryType *enums.QueryType) (
+func (r *SQLiteRepository) queryLinkByType(ctx context.Context, link string, queryType *enums.QueryType, enrich bool) (
 	hits []entries.Hit,
 	err error) {
 	if queryType == nil || *queryType == enums.QueryTypeMixed {
-		return r.QueryLink(ctx, link)
+		return r.queryLink(ctx, link, enrich)
+	}
+
+	if *queryType == enums.QueryTypeSubdomain || *queryType == enums.QueryTypeWildcard {
+		return r.querySubdomainMatch(ctx, link, *queryType == enums.QueryTypeWildcard, enrich)
 	}
 
 	startTime := time.Now()
@@ -636,24 +1654,24 @@ func (r *SQLiteRepository) QueryLinkByType(ctx context.Context, link string, que
 
 	switch *queryType {
 	case enums.QueryTypeFull:
-		query = "SELECT id FROM entries WHERE source_url = ? AND deleted_at IS NULL"
+		query = "SELECT " + hitSelect(enrich) + " FROM entries WHERE source_url = ? AND deleted_at IS NULL"
 	case enums.QueryTypeHost:
-		query = "SELECT id FROM entries WHERE host = ? AND deleted_at IS NULL"
+		query = "SELECT " + hitSelect(enrich) + " FROM entries WHERE host = ? AND deleted_at IS NULL"
 	case enums.QueryTypeDomain:
-		query = "SELECT id FROM entries WHERE domain = ? AND deleted_at IS NULL"
+		query = "SELECT " + hitSelect(enrich) + " FROM entries WHERE domain = ? AND deleted_at IS NULL"
 	case enums.QueryTypePath:
-		query = "SELECT id FROM entries WHERE path = ? AND deleted_at IS NULL"
+		query = "SELECT " + hitSelect(enrich) + " FROM entries WHERE path = ? AND deleted_at IS NULL"
 	default:
 		log.Error().Str("query_type", queryType.String()).Msg("Invalid query type")
 		return nil, ErrInvalidEntryQueryType
 	}
 	log.Debug().Str("query", query).Str("type", queryType.String()).Msg("starting query")
-	rows, err := r.db.QueryContext(ctx, query, link)
+	rows, err := r.queryCached(ctx, query, link)
 	if err != nil {
 		log.Err(err).
 			Str("query", query).
 			Str("type", queryType.String()).
-			Str("link", link).
+			Str("link", logger.RedactURL(link)).
 			Msg("Query failed")
 
 		return nil, ErrToQuery
@@ -661,19 +1679,14 @@ func (r *SQLiteRepository) QueryLinkByType(ctx context.Context, link string, que
 	defer rows.Close()
 
 	for rows.Next() {
-		var id string
-		err := rows.Scan(&id)
-		if err != nil {
+		hit := entries.Hit{MatchType: queryType.String(), MatchedValue: link}
+		if err := scanHit(rows, &hit, enrich); err != nil {
 			log.Err(err).
 				Msg("Failed to scan row")
 
 			return nil, ErrToScan
 		}
-		hits = append(hits, entries.Hit{
-			ID:           id,
-			MatchType:    queryType.String(),
-			MatchedValue: link,
-		})
+		hits = append(hits, hit)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -685,212 +1698,223 @@ func (r *SQLiteRepository) QueryLinkByType(ctx context.Context, link string, que
 
 	log.Debug().Dur("duration", time.Since(startTime)).Str("query_type", queryType.String()).Msg("Query completed")
 
-	return hits, nil
+	return entries.NormalizeHits(hits), nil
 }
 
-func (r *SQLiteRepository) QueryExactURLMatch(ctx context.Context, normalizedLink string) []entries.Hit {
-	tracer := otel.Tracer("blacked/repository")
-	_, span := tracer.Start(ctx, "repository.query_exact_url",
-		trace.WithAttributes(
-			attribute.String("query.url", normalizedLink),
-		),
-	)
-	defer span.End()
-
+// querySubdomainMatch returns the entries whose host is the host of link,
+// which may also be a bare host, or a parent of it down to the registered
+// domain. With wildcard set it also returns the "*.parent" entries of
+// wildcard feeds for every parent above the host. All candidates go into one
+// IN on the reversed-host index, so the lookup stays a single statement.
+func (r *SQLiteRepository) querySubdomainMatch(ctx context.Context, link string, wildcard, enrich bool) ([]entries.Hit, error) {
 	startTime := time.Now()
-	query := "SELECT id FROM entries WHERE source_url = ? AND deleted_at IS NULL"
-	rows, err := r.db.QueryContext(ctx, query, normalizedLink)
-	if err != nil {
-		log.Err(err).Msg("Exact URL match query failed")
-		if err == sql.ErrNoRows {
-			log.Debug().Str("normalized_link", normalizedLink).Msg("No exact URL match found")
-		} else {
-			log.Err(err).Msg("Error executing exact URL match query")
+	host := linkHost(link)
+	parents := utils.ParentHosts(host)
+	if len(parents) == 0 {
+		return []entries.Hit{}, nil
+	}
+	host = parents[0]
+
+	args := make([]any, 0, 2*len(parents))
+	for i, parent := range parents {
+		args = append(args, utils.ReverseHost(parent))
+		if wildcard && i > 0 {
+			args = append(args, utils.ReverseHost("*."+parent))
 		}
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
 
-		return nil
+	rows, err := r.queryCached(ctx, "SELECT "+hitSelect(enrich)+`, host FROM entries
+		WHERE reversed_host IN (`+placeholders+`) AND deleted_at IS NULL`, args...)
+	if err != nil {
+		log.Err(err).
+			Str("host", logger.RedactURL(host)).
+			Bool("wildcard", wildcard).
+			Msg("Subdomain match query failed")
+
+		return nil, ErrToQuery
 	}
 	defer rows.Close()
 
 	var hits []entries.Hit
-
 	for rows.Next() {
-		var id string
-		err := rows.Scan(&id)
-		if err != nil {
-			log.Err(err).Msg("Failed to scan row in queryExactURLMatch")
-			continue // Or handle the error as appropriate
+		hit := entries.Hit{MatchType: entries.MatchTypeSubdomain}
+		if err := scanHit(rows, &hit, enrich, &hit.MatchedValue); err != nil {
+			log.Err(err).Msg("Failed to scan row in querySubdomainMatch")
+			return nil, ErrToScan
 		}
-		hits = append(hits, entries.Hit{
-			ID:           id,
-			MatchType:    "EXACT_URL",
-			MatchedValue: normalizedLink,
-		})
+		if strings.EqualFold(hit.MatchedValue, host) {
+			hit.MatchType = entries.MatchTypeHost
+		}
+		hits = append(hits, hit)
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Err(err).Msg("Error iterating rows in queryExactURLMatch")
-		return nil
+		log.Err(err).
+			Str("host", logger.RedactURL(host)).
+			Msg("Error iterating rows in querySubdomainMatch")
+
+		return nil, ErrRowsIteration
 	}
 
-	duration := time.Since(startTime)
-	log.Debug().Dur("duration", duration).Str("match_type", "EXACT_URL").Msg("Exact URL match query completed")
+	log.Debug().Dur("duration", time.Since(startTime)).Int("candidates", len(args)).Bool("wildcard", wildcard).Msg("Subdomain match query completed")
 
-	return hits
+	return entries.NormalizeHits(hits), nil
 }
 
-func (r *SQLiteRepository) queryHostMatch(ctx context.Context, host string) []entries.Hit {
+// queryLinkMatch appends the exact URL hits of urls and the HOST, wildcard
+// SUBDOMAIN, DOMAIN and PATH hits of a link to hits with the single
+// statement of linkMatchQuery. On error hits is returned unchanged with
+// ErrToQuery, ErrToScan or ErrRowsIteration.
+func (r *SQLiteRepository) queryLinkMatch(ctx context.Context, hits []entries.Hit, urls []string, host, domain, path string, enrich bool) ([]entries.Hit, error) {
 	tracer := otel.Tracer("blacked/repository")
-	_, span := tracer.Start(ctx, "repository.query_host",
+	_, span := tracer.Start(ctx, "repository.query_link_match",
 		trace.WithAttributes(
-			attribute.String("query.host", host),
+			attribute.String("query.host", logger.RedactURL(host)),
 		),
 	)
 	defer span.End()
 
 	startTime := time.Now()
-	query := "SELECT id FROM entries WHERE host = ? AND deleted_at IS NULL"
-	rows, err := r.db.QueryContext(ctx, query, host)
+	query, args := linkMatchQuery(urls, host, domain, path, enrich)
+	rows, err := r.queryCached(ctx, query, args...)
 	if err != nil {
 		log.Err(err).
-			Str("host", host).
-			Msg("Host match query failed")
+			Str("host", logger.RedactURL(host)).
+			Msg("Link match query failed")
 
-		return nil
+		return hits, ErrToQuery
 	}
 	defer rows.Close()
 
-	var hits []entries.Hit
-
+	n := len(hits)
 	for rows.Next() {
-		var id string
-		err := rows.Scan(&id)
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to scan row in queryHostMatch")
-			continue // Or handle the error as appropriate
+		var h entries.Hit
+		if err := scanHit(rows, &h, enrich, &h.MatchType, &h.MatchedValue); err != nil {
+			log.Err(err).Msg("Failed to scan row in queryLinkMatch")
+			return hits[:n], ErrToScan
 		}
-		hits = append(hits, entries.Hit{
-			ID:           id,
-			MatchType:    "HOST",
-			MatchedValue: host,
-		})
+		hits = append(hits, h)
 	}
 
 	if err := rows.Err(); err != nil {
 		log.Err(err).
-			Str("host", host).
-			Msg("Error iterating rows in queryHostMatch")
+			Str("host", logger.RedactURL(host)).
+			Msg("Error iterating rows in queryLinkMatch")
 
-		return nil
+		return hits[:n], ErrRowsIteration
 	}
 
-	duration := time.Since(startTime)
-	log.Debug().Dur("duration", duration).Str("match_type", "HOST").Msg("Host match query completed")
-
-	return hits
+	log.Debug().Dur("duration", time.Since(startTime)).Int("hits", len(hits)-n).Msg("Link match query completed")
+	return hits, nil
 }
 
-func (r *SQLiteRepository) queryDomainMatch(ctx context.Context, domain string) []entries.Hit {
-	tracer := otel.Tracer("blacked/repository")
-	_, span := tracer.Start(ctx, "repository.query_domain",
-		trace.WithAttributes(
-			attribute.String("query.domain", domain),
-		),
-	)
-	defer span.End()
-
-	startTime := time.Now()
-	query := "SELECT id FROM entries WHERE domain = ? AND deleted_at IS NULL"
-	rows, err := r.db.QueryContext(ctx, query, domain)
-	if err != nil {
-		log.Err(err).
-			Str("domain", domain).
-			Msg("Domain match query failed")
-		return nil
+// linkMatchQuery returns the statement queryLinkMatch runs: UNION ALL
+// branches that each search their own index (source_url, host,
+// reversed_host, domain and the partial path index) and select the hit's ID,
+// activation time, entry fields when enrich is set, match type and matched
+// value. An empty host, domain or path leaves its branch out.
+func linkMatchQuery(urls []string, host, domain, path string, enrich bool) (string, []any) {
+	var branches []string
+	var args []any
+	branch := func(matchType, matched, where string, whereArgs ...any) {
+		branches = append(branches, "SELECT "+hitSelect(enrich)+", ?, "+matched+" FROM entries WHERE "+where+" AND deleted_at IS NULL")
+		args = append(append(args, matchType), whereArgs...)
 	}
-	defer rows.Close()
-
-	var hits []entries.Hit
-
-	for rows.Next() {
-		var id string
-		err := rows.Scan(&id)
-		if err != nil {
-			log.Err(err).
-				Str("domain", domain).
-				Msg("Failed to scan row in queryDomainMatch")
 
-			continue // Or handle the error as appropriate
+	urlArgs := make([]any, len(urls))
+	for i, u := range urls {
+		urlArgs[i] = u
+	}
+	branch(entries.MatchTypeExactURL, "source_url", "source_url IN ("+placeholderList(len(urls))+")", urlArgs...)
+	if host != "" {
+		branch(entries.MatchTypeHost, "host", "host = ?", host)
+	}
+	if parents := utils.ParentHosts(host); len(parents) > 1 {
+		// Wildcard entries of a parent host, down to the registered domain.
+		reversed := make([]any, 0, len(parents)-1)
+		for _, parent := range parents[1:] {
+			reversed = append(reversed, utils.ReverseHost(parent))
 		}
-		hits = append(hits, entries.Hit{
-			ID:           id,
-			MatchType:    "DOMAIN",
-			MatchedValue: domain,
-		})
+		branch(entries.MatchTypeSubdomain, "host", "reversed_host IN ("+placeholderList(len(reversed))+") AND wildcard = 1", reversed...)
 	}
+	if domain != "" {
+		branch(entries.MatchTypeDomain, "domain", "domain = ?", domain)
+	}
+	if path != "" {
+		branch(entries.MatchTypePath, "path", "path = ?", path)
+	}
+	return strings.Join(branches, " UNION ALL "), args
+}
 
-	if err := rows.Err(); err != nil {
-		log.Err(err).
-			Str("domain", domain).
-			Msg("Error iterating rows in queryDomainMatch")
+// placeholderList returns n comma-separated SQL placeholders.
+func placeholderList(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
 
-		return nil
+// linkHost returns the host of link, a URL with or without a scheme or a bare host.
+func linkHost(link string) string {
+	link = strings.TrimSpace(link)
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
 	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return utils.DefaultNormalizer().Host(u.Hostname())
+}
 
-	log.Debug().Dur("duration", time.Since(startTime)).Str("match_type", "DOMAIN").Msg("Domain match query completed")
-
-	return hits
+func (r *SQLiteRepository) QueryExactURLMatch(ctx context.Context, normalizedLink string) []entries.Hit {
+	return r.queryExactURLMatch(ctx, nil, normalizedLink, false)
 }
 
-func (r *SQLiteRepository) queryPathMatch(ctx context.Context, path string) []entries.Hit {
+// queryExactURLMatch appends the exact URL hits to hits. Like the other
+// match queries it appends into the caller's slice, so QueryLink grows one
+// slice instead of concatenating four; on error hits is returned unchanged.
+func (r *SQLiteRepository) queryExactURLMatch(ctx context.Context, hits []entries.Hit, normalizedLink string, enrich bool) []entries.Hit {
 	tracer := otel.Tracer("blacked/repository")
-	_, span := tracer.Start(ctx, "repository.query_path",
+	_, span := tracer.Start(ctx, "repository.query_exact_url",
 		trace.WithAttributes(
-			attribute.String("query.path", path),
+			attribute.String("query.url", logger.RedactURL(normalizedLink)),
 		),
 	)
 	defer span.End()
 
 	startTime := time.Now()
-	query := "SELECT id FROM entries WHERE path = ? AND deleted_at IS NULL"
-	rows, err := r.db.QueryContext(ctx, query, path)
+	query := "SELECT " + hitSelect(enrich) + " FROM entries WHERE source_url = ? AND deleted_at IS NULL"
+	rows, err := r.queryCached(ctx, query, normalizedLink)
 	if err != nil {
-		log.Err(err).
-			Str("path", path).
-			Msg("Path match query failed")
+		log.Err(err).Msg("Exact URL match query failed")
+		if err == sql.ErrNoRows {
+			log.Debug().Str("normalized_link", logger.RedactURL(normalizedLink)).Msg("No exact URL match found")
+		} else {
+			log.Err(err).Msg("Error executing exact URL match query")
+		}
 
-		return nil
+		return hits
 	}
 	defer rows.Close()
 
-	var hits []entries.Hit
+	n := len(hits)
 
 	for rows.Next() {
-		var id string
-		err := rows.Scan(&id)
-		if err != nil {
-			log.Err(err).
-				Str("path", path).
-				Msg("Failed to scan row in queryPathMatch")
-
+		hit := entries.Hit{MatchType: entries.MatchTypeExactURL, MatchedValue: normalizedLink}
+		if err := scanHit(rows, &hit, enrich); err != nil {
+			log.Err(err).Msg("Failed to scan row in queryExactURLMatch")
 			continue // Or handle the error as appropriate
 		}
-		hits = append(hits, entries.Hit{
-			ID:           id,
-			MatchType:    "PATH",
-			MatchedValue: path,
-		})
+		hits = append(hits, hit)
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Err(err).
-			Str("path", path).
-			Msg("Error iterating rows in queryPathMatch")
-
-		return nil
+		log.Err(err).Msg("Error iterating rows in queryExactURLMatch")
+		return hits[:n]
 	}
 
-	log.Debug().Dur("duration", time.Since(startTime)).Str("match_type", "PATH").Msg("Path match query completed")
+	duration := time.Since(startTime)
+	log.Debug().Dur("duration", duration).Str("match_type", entries.MatchTypeExactURL).Msg("Exact URL match query completed")
 
 	return hits
 }
+
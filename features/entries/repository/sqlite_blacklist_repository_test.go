@@ -0,0 +1,465 @@
+package repository
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/enums"
+	idb "blacked/internal/db"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryLinkHits(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := NewSQLiteRepository(conn)
+
+	save := func(link string, activatedAt int64) string {
+		t.Helper()
+		e := entries.NewEntry().WithSource("test-feed").WithCategory("malware")
+		require.NoError(t, e.SetURL(link))
+		e.CreatedAt = activatedAt
+		require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{e}))
+		return e.ID
+	}
+
+	// exact matches the queried URL by every strategy; the others only by
+	// host, domain or path.
+	exact := save("https://login.bad.example/signin", 100)
+	olderHost := save("https://login.bad.example/other", 200)
+	newerHost := save("https://login.bad.example/", 300)
+	domain := save("https://cdn.bad.example/x", 400)
+	path := save("https://elsewhere.example/signin", 500)
+
+	for range 3 {
+		hits, err := repo.QueryLink(ctx, "https://login.bad.example/signin")
+		require.NoError(t, err)
+
+		var got []string
+		for _, h := range hits {
+			got = append(got, h.ID+" "+h.MatchType)
+		}
+		assert.Equal(t, []string{
+			exact + " " + entries.MatchTypeExactURL,
+			newerHost + " " + entries.MatchTypeHost,
+			olderHost + " " + entries.MatchTypeHost,
+			domain + " " + entries.MatchTypeDomain,
+			path + " " + entries.MatchTypePath,
+		}, got, "one hit per entry under its strongest match, newest first within a match type")
+	}
+
+	hostType := enums.QueryTypeHost
+	hits, err := repo.QueryLinkByType(ctx, "login.bad.example", &hostType)
+	require.NoError(t, err)
+	require.Len(t, hits, 3)
+	assert.Equal(t, newerHost, hits[0].ID)
+	assert.Equal(t, exact, hits[2].ID)
+	assert.Equal(t, int64(100), hits[2].ActivatedAt)
+}
+
+func TestQueryLinkSubdomainAndWildcard(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := NewSQLiteRepository(conn)
+
+	save := func(link string, activatedAt int64) string {
+		t.Helper()
+		e := entries.NewEntry().WithSource("test-feed").WithCategory("malware")
+		require.NoError(t, e.SetURL(link))
+		e.CreatedAt = activatedAt
+		require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{e}))
+		return e.ID
+	}
+
+	host := save("https://sub.a.example.com/login", 100)
+	parent := save("a.example.com", 200)
+	registered := save("example.com", 300)
+	wildcard := save("*.a.example.com", 400)
+	save("*.sub.a.example.com", 500) // Covers hosts under sub.a.example.com only
+	save("other.example.com", 600)
+	save("deeper.sub.a.example.com", 700)
+
+	matches := func(queryType enums.QueryType, link string) []string {
+		t.Helper()
+		hits, err := repo.QueryLinkByType(ctx, link, &queryType)
+		require.NoError(t, err)
+		var got []string
+		for _, h := range hits {
+			got = append(got, h.ID+" "+h.MatchType+" "+h.MatchedValue)
+		}
+		return got
+	}
+
+	assert.Equal(t, []string{
+		host + " " + entries.MatchTypeHost + " sub.a.example.com",
+		registered + " " + entries.MatchTypeSubdomain + " example.com",
+		parent + " " + entries.MatchTypeSubdomain + " a.example.com",
+	}, matches(enums.QueryTypeSubdomain, "https://SUB.a.example.com/anything"))
+
+	assert.Equal(t, []string{
+		host + " " + entries.MatchTypeHost + " sub.a.example.com",
+		wildcard + " " + entries.MatchTypeSubdomain + " *.a.example.com",
+		registered + " " + entries.MatchTypeSubdomain + " example.com",
+		parent + " " + entries.MatchTypeSubdomain + " a.example.com",
+	}, matches(enums.QueryTypeWildcard, "sub.a.example.com"), "a bare host works too")
+
+	assert.Equal(t, []string{
+		registered + " " + entries.MatchTypeHost + " example.com",
+	}, matches(enums.QueryTypeWildcard, "example.com"), "wildcards never cover their own name")
+}
+
+func TestQueryLinkWildcardEntries(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := NewSQLiteRepository(conn)
+
+	wild := entries.NewEntry().WithSource("oisd-big").WithCategory("blocklist")
+	require.NoError(t, wild.SetWildcardURL("*.ads.example.com"))
+	plain := entries.NewEntry().WithSource("plain-feed").WithCategory("blocklist")
+	require.NoError(t, plain.SetURL("tracker.example.com"))
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{wild, plain}))
+
+	stored, err := repo.GetEntryByID(ctx, wild.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.Wildcard)
+	assert.Equal(t, "ads.example.com", stored.Host)
+	assert.Equal(t, "*.ads.example.com", stored.SourceURL, "the source URL keeps the listed line")
+
+	matchTypes := func(link string) map[string]string {
+		t.Helper()
+		hits, err := repo.QueryLink(ctx, link)
+		require.NoError(t, err)
+		got := make(map[string]string)
+		for _, h := range hits {
+			got[h.ID] = h.MatchType
+		}
+		return got
+	}
+
+	got := matchTypes("https://x.y.ads.example.com/")
+	assert.Equal(t, entries.MatchTypeSubdomain, got[wild.ID], "a wildcard entry covers every subdomain")
+	assert.Equal(t, entries.MatchTypeDomain, got[plain.ID], "a plain entry only shares the registered domain")
+
+	got = matchTypes("https://ads.example.com/")
+	assert.Equal(t, entries.MatchTypeHost, got[wild.ID])
+
+	got = matchTypes("https://x.tracker.example.com/")
+	assert.Equal(t, entries.MatchTypeDomain, got[plain.ID])
+}
+
+func TestQueryLinkPatterns(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := NewSQLiteRepository(conn)
+
+	e := entries.NewEntry().WithSource("test-feed").WithCategory("malware")
+	require.NoError(t, e.SetURL("https://kit.bad.example/login"))
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{e}))
+
+	p, err := entries.NewPattern(entries.PatternKindGlob, "*.bad.example/login*", "pattern-feed", "p1")
+	require.NoError(t, err)
+	require.NoError(t, repo.SavePatterns(ctx, []*entries.Pattern{p.WithCategory("phishing")}))
+
+	hits, err := repo.QueryLink(ctx, "https://kit.bad.example/login")
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	assert.Equal(t, e.ID, hits[0].ID)
+	assert.Equal(t, entries.Hit{
+		ID:           p.ID,
+		MatchType:    entries.MatchTypePattern,
+		MatchedValue: "*.bad.example/login*",
+		Source:       "pattern-feed",
+		ActivatedAt:  p.CreatedAt,
+	}, hits[1], "patterns rank after every indexed match")
+
+	// Saving again under a new process keeps the ID; a replacing run drops
+	// what it did not write, and lookups see it at once.
+	again, err := entries.NewPattern(entries.PatternKindGlob, "*.bad.example/login*", "pattern-feed", "p2")
+	require.NoError(t, err)
+	require.NoError(t, repo.SavePatterns(ctx, []*entries.Pattern{again}))
+	stored, err := repo.ListPatterns(ctx, "pattern-feed")
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, p.ID, stored[0].ID)
+	assert.Equal(t, "p2", stored[0].ProcessID)
+
+	removed, err := repo.RemoveOlderPatterns(ctx, "pattern-feed", "p3")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+	hits, err = repo.QueryLink(ctx, "https://kit.bad.example/login")
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.ErrorIs(t, repo.SoftDeletePattern(ctx, p.ID), ErrPatternNotFound)
+}
+
+func TestQueryLinkNetworks(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := NewSQLiteRepository(conn)
+
+	save := func(link string) *entries.Entry {
+		t.Helper()
+		e, err := entries.FromURL(link, "drop", "p1")
+		require.NoError(t, err)
+		require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{e}))
+		return e
+	}
+
+	wide := save("10.0.0.0/8")
+	narrow := save("10.1.2.99/24")
+	v6 := save("2001:db8::/32")
+	single := save("192.0.2.7/32")
+	assert.Equal(t, "10.1.2.0/24", narrow.CIDR, "host bits are masked")
+	assert.Equal(t, "10.1.2.0", narrow.Host)
+	assert.Empty(t, single.CIDR, "a single address is an IP entry")
+	assert.Equal(t, "192.0.2.7", single.Host)
+
+	matches := func(link string) []string {
+		t.Helper()
+		hits, err := repo.QueryLink(ctx, link)
+		require.NoError(t, err)
+		var got []string
+		for _, h := range hits {
+			got = append(got, h.ID+" "+h.MatchType+" "+h.MatchedValue)
+		}
+		return got
+	}
+
+	assert.ElementsMatch(t, []string{
+		narrow.ID + " " + entries.MatchTypeNetwork + " 10.1.2.0/24",
+		wide.ID + " " + entries.MatchTypeNetwork + " 10.0.0.0/8",
+	}, matches("http://10.1.2.3:8080/payload.exe"))
+	assert.Equal(t, []string{v6.ID + " " + entries.MatchTypeNetwork + " 2001:db8::/32"}, matches("https://[2001:db8:1::5]/"))
+	assert.Empty(t, matches("http://11.0.0.1/"))
+
+	stored, err := repo.GetEntryByID(ctx, narrow.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "10.1.2.0/24", stored.CIDR)
+
+	require.NoError(t, repo.SoftDeleteEntryByID(ctx, wide.ID))
+	assert.Equal(t, []string{narrow.ID + " " + entries.MatchTypeNetwork + " 10.1.2.0/24"}, matches("http://10.1.2.3/"),
+		"deleted networks stop matching before the index is rebuilt")
+}
+
+func TestQueryLinkMatchError(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Without the schema the match statement fails.
+	hits, err := NewSQLiteRepository(conn).QueryLink(context.Background(), "https://login.bad.example/signin")
+	assert.ErrorIs(t, err, ErrToQuery)
+	assert.Empty(t, hits)
+}
+
+func TestQueryLinkNormalizes(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := NewSQLiteRepository(conn)
+
+	e, err := entries.FromURL("HTTPS://Login.Bücher.example:443/SignIn/?utm_source=feed&id=%7e1", "norm-feed", "p1")
+	require.NoError(t, err)
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{e}))
+	assert.Equal(t, "login.xn--bcher-kva.example", e.Host)
+	assert.Equal(t, "/signin", e.Path)
+	assert.Equal(t, "id=~1", e.RawQuery)
+
+	for _, link := range []string{
+		"https://login.xn--bcher-kva.example/signin",
+		"http://LOGIN.bücher.example/SignIn/?fbclid=abc",
+		"https://login.bücher.example./other",
+	} {
+		hits, err := repo.QueryLink(ctx, link)
+		require.NoError(t, err)
+		require.Len(t, hits, 1, link)
+		assert.Equal(t, e.ID, hits[0].ID, link)
+		assert.Equal(t, entries.MatchTypeHost, hits[0].MatchType, link)
+	}
+
+	hits, err := repo.QueryLink(ctx, "HTTPS://Login.Bücher.example:443/SignIn/?utm_source=feed&id=%7e1")
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, entries.MatchTypeExactURL, hits[0].MatchType, "the source URL as listed matches exactly")
+}
+
+func TestRemoveOlderInsertionsBatches(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	batch, yield := removeOlderBatch, removeOlderYield
+	removeOlderBatch, removeOlderYield = 3, 0
+	defer func() { removeOlderBatch, removeOlderYield = batch, yield }()
+
+	ctx := context.Background()
+	repo := NewSQLiteRepository(conn)
+
+	save := func(source, processID string, n int) []string {
+		t.Helper()
+		var ids []string
+		var batch []*entries.Entry
+		for i := range n {
+			e, err := entries.FromURL(fmt.Sprintf("https://%s-%s-%d.example/", source, processID, i), source, processID)
+			require.NoError(t, err)
+			batch = append(batch, e)
+			ids = append(ids, e.ID)
+		}
+		require.NoError(t, repo.BatchSaveEntries(ctx, batch))
+		return ids
+	}
+	stale := save("feed", "p1", 10)
+	current := save("feed", "p2", 4)
+	other := save("other", "p1", 2)
+
+	require.NoError(t, repo.RemoveOlderInsertions(ctx, "feed", "p2"))
+
+	deletedAt := func(ids []string) map[int64]int {
+		t.Helper()
+		stamps := map[int64]int{}
+		for _, id := range ids {
+			e, err := repo.GetEntryByID(ctx, id)
+			require.NoError(t, err)
+			if e.DeletedAt != nil {
+				stamps[*e.DeletedAt]++
+			}
+		}
+		return stamps
+	}
+	stamps := deletedAt(stale)
+	assert.Len(t, stamps, 1, "every batch stamps the same deleted_at")
+	for _, n := range stamps {
+		assert.Equal(t, len(stale), n, "every batch of the older process is soft deleted")
+	}
+	assert.Empty(t, deletedAt(current), "the current process is kept")
+	assert.Empty(t, deletedAt(other), "other sources are untouched")
+}
+
+func TestLinkMatchQueryUsesIndexes(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	for _, enrich := range []bool{false, true} {
+		query, args := linkMatchQuery([]string{"https://login.evil.example/wp/", "HTTPS://login.evil.example/wp/"},
+			"login.evil.example", "evil.example", "/wp/", enrich)
+		rows, err := conn.Query("EXPLAIN QUERY PLAN "+query, args...)
+		require.NoError(t, err)
+
+		searches := 0
+		for rows.Next() {
+			var id, parent, notUsed int
+			var detail string
+			require.NoError(t, rows.Scan(&id, &parent, &notUsed, &detail))
+			assert.NotRegexp(t, `^SCAN entries\b`, detail, "every branch searches an index")
+			if strings.HasPrefix(detail, "SEARCH entries USING INDEX") {
+				searches++
+			}
+		}
+		require.NoError(t, rows.Err())
+		rows.Close()
+		assert.Equal(t, 5, searches, "exact, host, wildcard, domain and path branches")
+	}
+}
+
+func TestQueryLinkEnriched(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := NewSQLiteRepository(conn)
+
+	e, err := entries.FromURL("https://login.evil.example/wp/", "feed", "p1")
+	require.NoError(t, err)
+	e.Category = "phishing"
+	e.Confidence = 0.8
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{e}))
+
+	plain, err := repo.QueryLinkByType(ctx, "https://login.evil.example/wp/", nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, plain)
+	for _, h := range plain {
+		assert.Nil(t, h.Entry, "plain queries leave the entry out")
+	}
+
+	hits, err := repo.QueryLinkEnriched(ctx, "https://login.evil.example/wp/", nil)
+	require.NoError(t, err)
+	require.Len(t, hits, len(plain))
+	for _, h := range hits {
+		require.NotNil(t, h.Entry, "%s hit carries its entry", h.MatchType)
+		assert.Equal(t, "feed", h.Entry.Source)
+		assert.Equal(t, "feed", h.Source)
+		assert.Equal(t, []string{"phishing"}, h.Entry.AllCategories())
+		assert.InDelta(t, 0.8, h.Entry.Confidence, 1e-9)
+		assert.NotZero(t, h.Entry.CreatedAt)
+	}
+}
+
+func TestStatementCache(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+	stmts := idb.NewStatements(conn)
+	defer stmts.Close()
+
+	ctx := context.Background()
+	repo := NewSQLiteRepositoryWithStatements(conn, stmts)
+	e, err := entries.FromURL("https://login.evil.example/wp/", "feed", "p1")
+	require.NoError(t, err)
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{e}))
+
+	first, err := repo.QueryLinkByType(ctx, "https://login.evil.example/wp/", nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, first)
+	prepared := stmts.Len()
+	assert.Positive(t, prepared)
+
+	// Another repository on the same cache reuses the statements.
+	again, err := NewSQLiteRepositoryWithStatements(conn, stmts).QueryLinkByType(ctx, "https://login.evil.example/wp/", nil)
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+	assert.Equal(t, prepared, stmts.Len(), "repeated queries prepare nothing")
+
+	// Without a cache, and once it is closed, the queries run unprepared.
+	plain, err := NewSQLiteRepository(conn).QueryLinkByType(ctx, "https://login.evil.example/wp/", nil)
+	require.NoError(t, err)
+	assert.Equal(t, first, plain)
+	require.NoError(t, stmts.Close())
+	assert.Zero(t, stmts.Len())
+	closed, err := repo.QueryLinkByType(ctx, "https://login.evil.example/wp/", nil)
+	require.NoError(t, err)
+	assert.Equal(t, first, closed)
+	assert.Zero(t, stmts.Len(), "a closed cache prepares nothing")
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"blacked/features/entries"
+	"blacked/internal/iptrie"
+	"context"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// networkIndex returns the radix tree of the active CIDR entries, holding
+// their IDs.
+func (r *SQLiteRepository) networkIndex(ctx context.Context) (*iptrie.Trie[string], error) {
+	return r.indexes().networks.get(func() (*iptrie.Trie[string], error) {
+		rows, err := r.db.QueryContext(ctx, "SELECT id, cidr FROM entries WHERE cidr IS NOT NULL AND deleted_at IS NULL")
+		if err != nil {
+			log.Err(err).Msg("Failed to query CIDR entries")
+			return nil, ErrToQuery
+		}
+		defer rows.Close()
+
+		trie := iptrie.New[string]()
+		for rows.Next() {
+			var id, cidr string
+			if err := rows.Scan(&id, &cidr); err != nil {
+				log.Err(err).Msg("Failed to scan CIDR entry")
+				return nil, ErrToScan
+			}
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				log.Warn().Err(err).Str("id", id).Str("cidr", cidr).Msg("Skipping malformed CIDR entry")
+				continue
+			}
+			trie.Insert(prefix, id)
+		}
+		if err := rows.Err(); err != nil {
+			log.Err(err).Msg("Rows iteration error for CIDR entries")
+			return nil, ErrRowsIteration
+		}
+
+		log.Debug().Int("networks", trie.Len()).Msg("Network index built")
+		return trie, nil
+	})
+}
+
+// invalidateNetworks rebuilds the network index on its next use when batch
+// writes a CIDR entry. Deletes need no invalidation: queryNetworkMatch
+// confirms every candidate.
+func (r *SQLiteRepository) invalidateNetworks(batch []*entries.Entry) {
+	for _, e := range batch {
+		if e.CIDR != "" {
+			r.indexes().networks.invalidate()
+			return
+		}
+	}
+}
+
+// queryNetworkMatch appends a NETWORK hit for every active CIDR entry
+// containing ip. The radix tree nominates the candidates and one query by
+// ID confirms them, so entries deleted since the tree was built never match.
+func (r *SQLiteRepository) queryNetworkMatch(ctx context.Context, hits []entries.Hit, ip netip.Addr, enrich bool) []entries.Hit {
+	startTime := time.Now()
+	trie, err := r.networkIndex(ctx)
+	if err != nil {
+		return hits
+	}
+	ids := trie.Lookup(ip)
+	if len(ids) == 0 {
+		return hits
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	rows, err := r.db.QueryContext(ctx, "SELECT "+hitSelect(enrich)+`, cidr FROM entries
+		WHERE id IN (`+placeholders+`) AND cidr IS NOT NULL AND deleted_at IS NULL`, args...)
+	if err != nil {
+		log.Err(err).Str("ip", ip.String()).Msg("Network match query failed")
+		return hits
+	}
+	defer rows.Close()
+
+	n := len(hits)
+	for rows.Next() {
+		hit := entries.Hit{MatchType: entries.MatchTypeNetwork}
+		if err := scanHit(rows, &hit, enrich, &hit.MatchedValue); err != nil {
+			log.Error().Err(err).Msg("Failed to scan row in queryNetworkMatch")
+			continue
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Str("ip", ip.String()).Msg("Error iterating rows in queryNetworkMatch")
+		return hits[:n]
+	}
+
+	log.Debug().Dur("duration", time.Since(startTime)).Int("candidates", len(ids)).Str("match_type", entries.MatchTypeNetwork).Msg("Network match query completed")
+	return hits
+}
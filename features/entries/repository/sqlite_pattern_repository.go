@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"blacked/features/entries"
+	"blacked/internal/logger"
+	"blacked/internal/query"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrPatternNotFound = errors.New("pattern not found")
+	ErrQueryPatterns   = errors.New("failed to query patterns from SQLite")
+	ErrSavePatterns    = errors.New("failed to save patterns in SQLite")
+)
+
+const patternColumns = "id, COALESCE(process_id, ''), source, kind, pattern, COALESCE(category, ''), COALESCE(created_at, 0), COALESCE(updated_at, 0), deleted_at"
+
+// patternMatcher returns the compiled set of active patterns.
+func (r *SQLiteRepository) patternMatcher(ctx context.Context) (*entries.PatternMatcher, error) {
+	return r.indexes().patterns.get(func() (*entries.PatternMatcher, error) {
+		patterns, err := r.ListPatterns(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		m := entries.NewPatternMatcher(patterns)
+		log.Debug().Int("patterns", m.Len()).Msg("Pattern matcher compiled")
+		return m, nil
+	})
+}
+
+func scanPattern(row interface{ Scan(...any) error }) (*entries.Pattern, error) {
+	var (
+		p         entries.Pattern
+		deletedAt sql.NullInt64
+	)
+	if err := row.Scan(&p.ID, &p.ProcessID, &p.Source, &p.Kind, &p.Pattern, &p.Category, &p.CreatedAt, &p.UpdatedAt, &deletedAt); err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		p.DeletedAt = &deletedAt.Int64
+	}
+	return &p, nil
+}
+
+func scanPatternRows(rows *sql.Rows) ([]entries.Pattern, error) {
+	patterns := []entries.Pattern{}
+	for rows.Next() {
+		p, err := scanPattern(rows)
+		if err != nil {
+			log.Err(err).Msg("Failed to scan pattern")
+			return nil, ErrToScan
+		}
+		patterns = append(patterns, *p)
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Rows iteration error for patterns")
+		return nil, ErrRowsIteration
+	}
+	return patterns, nil
+}
+
+// ListPatterns returns the active patterns of source, or of every source
+// when it is empty, oldest first.
+func (r *SQLiteRepository) ListPatterns(ctx context.Context, source string) ([]entries.Pattern, error) {
+	query := "SELECT " + patternColumns + " FROM entry_patterns WHERE deleted_at IS NULL"
+	var args []any
+	if source != "" {
+		query += " AND source = ?"
+		args = append(args, source)
+	}
+	query += " ORDER BY created_at, id"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Err(err).Str("source", source).Msg("Failed to query patterns")
+		return nil, ErrQueryPatterns
+	}
+	defer rows.Close()
+
+	return scanPatternRows(rows)
+}
+
+// GetPatternsByIDs returns the active patterns among ids.
+func (r *SQLiteRepository) GetPatternsByIDs(ctx context.Context, ids []string) ([]entries.Pattern, error) {
+	if len(ids) == 0 {
+		return []entries.Pattern{}, nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+patternColumns+` FROM entry_patterns
+		WHERE id IN (`+placeholders+`) AND deleted_at IS NULL`, args...)
+	if err != nil {
+		log.Err(err).Int("ids", len(ids)).Msg("Failed to query patterns by ID")
+		return nil, ErrQueryPatterns
+	}
+	defer rows.Close()
+
+	return scanPatternRows(rows)
+}
+
+// SavePatterns upserts patterns on (source, kind, pattern) in one
+// transaction. A pattern already stored keeps its ID and creation time, takes
+// the new process ID and category, and is reactivated if it was deleted.
+func (r *SQLiteRepository) SavePatterns(ctx context.Context, patterns []*entries.Pattern) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Err(err).Msg("Failed to begin pattern transaction")
+		return ErrTx
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO entry_patterns (id, process_id, source, kind, pattern, category, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (source, kind, pattern) DO UPDATE SET
+			process_id = excluded.process_id,
+			category = excluded.category,
+			updated_at = excluded.updated_at,
+			deleted_at = NULL`)
+	if err != nil {
+		log.Err(err).Msg("Failed to prepare pattern upsert")
+		return ErrTxPrepare
+	}
+	defer stmt.Close()
+
+	for _, p := range patterns {
+		if _, err := stmt.ExecContext(ctx, p.ID, p.ProcessID, p.Source, p.Kind, p.Pattern, p.Category, p.CreatedAt, p.UpdatedAt); err != nil {
+			log.Err(err).Str("pattern", p.Pattern).Msg("Failed to upsert pattern")
+			return ErrSavePatterns
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Msg("Failed to commit patterns")
+		return ErrSavePatterns
+	}
+	r.indexes().patterns.invalidate()
+	query.PurgeNegativeCache()
+	return nil
+}
+
+// SoftDeletePattern marks the pattern with id as deleted, or returns ErrPatternNotFound.
+func (r *SQLiteRepository) SoftDeletePattern(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE entry_patterns SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL",
+		time.Now().UnixNano(), id)
+	if err != nil {
+		log.Err(err).Str("id", id).Msg("Failed to delete pattern")
+		return ErrDelete
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrPatternNotFound
+	}
+	r.indexes().patterns.invalidate()
+	return nil
+}
+
+// RemoveOlderPatterns soft deletes the active patterns of source not written
+// by processID, as RemoveOlderInsertions does for entries, and returns how
+// many it removed.
+func (r *SQLiteRepository) RemoveOlderPatterns(ctx context.Context, source, processID string) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE entry_patterns SET deleted_at = ?
+		WHERE source = ? AND COALESCE(process_id, '') != ? AND deleted_at IS NULL`,
+		time.Now().UnixNano(), source, processID)
+	if err != nil {
+		log.Err(err).Str("source", source).Msg("Failed to remove older patterns")
+		return 0, ErrDelete
+	}
+	n, _ := res.RowsAffected()
+	if n > 0 {
+		r.indexes().patterns.invalidate()
+	}
+	return n, nil
+}
+
+// MatchPatterns returns the active patterns matching link.
+func (r *SQLiteRepository) MatchPatterns(ctx context.Context, link string) ([]*entries.Pattern, error) {
+	m, err := r.patternMatcher(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.Match(link), nil
+}
+
+// queryPatternMatch appends a PATTERN hit for every pattern matching link.
+// A failed reload leaves hits unchanged, like the other match queries.
+func (r *SQLiteRepository) queryPatternMatch(ctx context.Context, hits []entries.Hit, link string, enrich bool) []entries.Hit {
+	matched, err := r.MatchPatterns(ctx, link)
+	if err != nil {
+		log.Err(err).Str("link", logger.RedactURL(link)).Msg("Pattern match failed")
+		return hits
+	}
+	for _, p := range matched {
+		hit := entries.Hit{
+			ID:           p.ID,
+			MatchType:    entries.MatchTypePattern,
+			MatchedValue: p.Pattern,
+			Source:       p.Source,
+			ActivatedAt:  p.CreatedAt,
+		}
+		if enrich {
+			hit.Entry = &entries.HitEntry{Source: p.Source, Category: p.Category, CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt}
+		}
+		hits = append(hits, hit)
+	}
+	return hits
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"blacked/features/entries/repository"
+	"blacked/internal/db"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var ErrBulkDelete = errors.New("failed to delete blacklist entries")
+
+// CacheSyncer resyncs the source URLs changed since a time (Unix nanos);
+// implemented by entry_collector.PondCollector.
+type CacheSyncer interface {
+	ScheduleChangedCacheSync(since int64) bool
+}
+
+// DeleteService soft deletes entries in bulk.
+type DeleteService struct {
+	repo  repository.BlacklistRepository
+	cache CacheSyncer // nil leaves the caches to the next scheduled sync
+}
+
+// NewDeleteService creates a DeleteService on the write database connection.
+func NewDeleteService() (*DeleteService, error) {
+	dbConn, err := db.GetWriteDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+
+	return NewDeleteServiceWithRepository(repository.NewSQLiteRepository(dbConn)), nil
+}
+
+// NewDeleteServiceWithRepository creates a DeleteService on the given repository.
+func NewDeleteServiceWithRepository(repo repository.BlacklistRepository) *DeleteService {
+	return &DeleteService{repo: repo}
+}
+
+// SetCacheSyncer sets what is asked to resync the caches after a delete.
+func (s *DeleteService) SetCacheSyncer(c CacheSyncer) *DeleteService {
+	s.cache = c
+	return s
+}
+
+// Delete soft deletes every entry matching f and, when any were deleted,
+// schedules a sync that drops them from the caches. An empty filter returns
+// repository.ErrEmptyFilter.
+func (s *DeleteService) Delete(ctx context.Context, f repository.Filter) (int64, error) {
+	since := time.Now().UnixNano()
+	deleted, err := s.repo.SoftDeleteEntries(ctx, f)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmptyFilter) {
+			return 0, err
+		}
+		log.Error().Err(err).Msg("Failed to bulk delete entries")
+		return 0, ErrBulkDelete
+	}
+
+	log.Info().Int64("deleted", deleted).Interface("filter", f).Msg("Bulk deleted entries")
+	if deleted > 0 && s.cache != nil && !s.cache.ScheduleChangedCacheSync(since) {
+		log.Warn().Msg("Cache sync after bulk delete not scheduled - sync queue is full")
+	}
+	return deleted, nil
+}
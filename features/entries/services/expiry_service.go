@@ -0,0 +1,101 @@
+package services
+
+import (
+	"blacked/features/entries/repository"
+	"blacked/features/maintenance"
+	"blacked/internal/db"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// expiryBatch bounds the entries soft deleted by one purge statement, so
+// the writer is never held for long.
+const expiryBatch = 10_000
+
+var ErrPurgeExpired = errors.New("failed to purge expired entries")
+
+// ExpiryService soft deletes entries past their expires_at, set from the
+// TTL of their source when they were last listed.
+type ExpiryService struct {
+	repo  repository.BlacklistRepository
+	cache CacheSyncer // nil leaves the caches to the next scheduled sync
+}
+
+// NewExpiryService creates an ExpiryService on the write database connection.
+func NewExpiryService() (*ExpiryService, error) {
+	dbConn, err := db.GetWriteDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+
+	return NewExpiryServiceWithRepository(repository.NewSQLiteRepository(dbConn)), nil
+}
+
+// NewExpiryServiceWithRepository creates an ExpiryService on the given repository.
+func NewExpiryServiceWithRepository(repo repository.BlacklistRepository) *ExpiryService {
+	return &ExpiryService{repo: repo}
+}
+
+// SetCacheSyncer sets what is asked to resync the caches after a purge.
+func (s *ExpiryService) SetCacheSyncer(c CacheSyncer) *ExpiryService {
+	s.cache = c
+	return s
+}
+
+// Purge soft deletes every expired entry and, when any were deleted,
+// schedules a sync that drops them from the caches and bloom filters. In
+// read-only maintenance mode it writes nothing and returns
+// maintenance.ErrReadOnly.
+func (s *ExpiryService) Purge(ctx context.Context) (int64, error) {
+	if err := maintenance.Check(ctx); err != nil {
+		return 0, err
+	}
+
+	since := time.Now().UnixNano()
+	var purged int64
+	for {
+		deleted, err := s.repo.SoftDeleteExpired(ctx, time.Now().UnixNano(), expiryBatch)
+		if err != nil {
+			log.Error().Err(err).Int64("purged", purged).Msg("Failed to purge expired entries")
+			return purged, ErrPurgeExpired
+		}
+		purged += deleted
+		if deleted < expiryBatch {
+			break
+		}
+	}
+
+	if purged == 0 {
+		return 0, nil
+	}
+	log.Info().Int64("purged", purged).Msg("Purged expired entries")
+	if s.cache != nil && !s.cache.ScheduleChangedCacheSync(since) {
+		log.Warn().Msg("Cache sync after expiry purge not scheduled - sync queue is full")
+	}
+	return purged, nil
+}
+
+// Run purges expired entries every interval until ctx is done, skipping
+// the ticks that find the instance in read-only maintenance mode.
+func (s *ExpiryService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Info().Dur("interval", interval).Msg("Expired entry purger started")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if maintenance.ReadOnly(ctx) {
+				log.Info().Msg("Skipping expired entry purge in read-only maintenance mode")
+				continue
+			}
+			s.Purge(ctx)
+		}
+	}
+}
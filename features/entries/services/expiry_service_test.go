@@ -0,0 +1,100 @@
+package services
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/features/maintenance"
+	idb "blacked/internal/db"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiryPurge(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+
+	now := time.Now().UnixNano()
+	newEntry := func(link, source string, expiresAt *int64) *entries.Entry {
+		e, err := entries.FromURL(link, source, "p1")
+		require.NoError(t, err)
+		e.ExpiresAt = expiresAt
+		return e
+	}
+	past, future := now-int64(time.Hour), now+int64(time.Hour)
+	expired := newEntry("https://a.example/", "feed", &past)
+	live := newEntry("https://b.example/", "feed", &future)
+	forever := newEntry("https://c.example/", "other", nil)
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{expired, live, forever}))
+
+	cache := &fakeImportCollector{}
+	svc := NewExpiryServiceWithRepository(repo).SetCacheSyncer(cache)
+
+	clearReadOnly := setReadOnly(t)
+	_, err = svc.Purge(ctx)
+	assert.ErrorIs(t, err, maintenance.ErrReadOnly)
+	clearReadOnly()
+
+	purged, err := svc.Purge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	assert.Equal(t, 1, cache.syncs, "a purge resyncs the caches")
+
+	deleted := func(e *entries.Entry) bool {
+		t.Helper()
+		stored, err := repo.GetEntryByID(ctx, e.ID)
+		require.NoError(t, err)
+		return stored.DeletedAt != nil
+	}
+	assert.True(t, deleted(expired), "read-only mode only postponed the purge")
+	assert.False(t, deleted(live))
+	assert.False(t, deleted(forever), "entries without expiry are kept")
+
+	purged, err = svc.Purge(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+	assert.Equal(t, 1, cache.syncs, "an empty purge schedules no sync")
+
+	// Relisting pushes the expiry of the source's active entries.
+	n, err := repo.SetSourceExpiry(ctx, "feed", &past)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n, "deleted entries keep their expiry")
+	n, err = repo.SetSourceExpiry(ctx, "feed", &past)
+	require.NoError(t, err)
+	assert.Zero(t, n, "an unchanged expiry is not rewritten")
+
+	purged, err = svc.Purge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	assert.True(t, deleted(live))
+}
+
+// setReadOnly switches the global maintenance mode to read-only and returns
+// the function clearing it, also run when the test ends.
+func setReadOnly(t *testing.T) func() {
+	t.Helper()
+	if maintenance.Get() == nil {
+		// Left open: the global service outlives the test.
+		conn, err := idb.Connect(idb.WithInMemory(true))
+		require.NoError(t, err)
+		require.NoError(t, idb.MigrateSchema(conn))
+		maintenance.Init(conn)
+	}
+
+	_, err := maintenance.Get().Enable(context.Background(), "test")
+	require.NoError(t, err)
+	disable := func() {
+		_, err := maintenance.Get().Disable(context.Background())
+		require.NoError(t, err)
+	}
+	t.Cleanup(disable)
+	return disable
+}
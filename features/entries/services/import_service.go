@@ -0,0 +1,270 @@
+package services
+
+import (
+	"blacked/features/entries"
+	"blacked/internal/collector"
+	"blacked/internal/utils"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrImportUnavailable = errors.New("import unavailable: entry collector not ready")
+	ErrInvalidImport     = errors.New("invalid import")
+)
+
+const (
+	// ImportSource is the source of imported entries; a label names
+	// ImportSource-<label>.
+	ImportSource = "import"
+
+	maxImportURLLength = 8192
+	maxImportErrors    = 100 // Rejected items listed in a report
+)
+
+var importLabel = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ImportCollector writes imported entries; implemented by
+// entry_collector.PondCollector.
+type ImportCollector interface {
+	Submit(entry *entries.Entry)
+	StartProviderProcessing(ctx context.Context, providerName, processID string)
+	FinishProviderProcessing(providerName, processID string) (count int, duration time.Duration, ok bool)
+	CacheSyncer
+}
+
+// ImportItem is one imported entry. Items may also be bare URL strings.
+type ImportItem struct {
+	URL        string   `json:"url"`
+	Category   string   `json:"category,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+func (i *ImportItem) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &i.URL)
+	}
+	type item ImportItem
+	return json.Unmarshal(data, (*item)(i))
+}
+
+// ImportOptions controls an import.
+type ImportOptions struct {
+	Label    string // Names the source ImportSource-<label>; empty uses ImportSource
+	Category string // Category of items without one
+}
+
+// ImportError is an item rejected by validation.
+type ImportError struct {
+	Index int    `json:"index"` // Position in the body, from 0
+	URL   string `json:"url,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportReport summarises an import.
+type ImportReport struct {
+	Source     string        `json:"source"`
+	ProcessID  string        `json:"process_id"`
+	Parsed     int           `json:"parsed"`     // Items read from the body
+	Saved      int           `json:"saved"`      // Entries written; may trail submitted ones if a batch fails
+	Skipped    int           `json:"skipped"`    // Invalid plus duplicate items
+	Invalid    int           `json:"invalid"`    // Items failing validation
+	Duplicates int           `json:"duplicates"` // Repeated URLs within the body
+	Errors     []ImportError `json:"errors,omitempty"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// ImportService imports entries through the entry collector under a
+// synthetic import source, one import at a time.
+type ImportService struct {
+	mu        sync.Mutex
+	collector ImportCollector // nil until the collector is ready
+}
+
+// NewImportService creates an ImportService; imports fail until a collector
+// is set.
+func NewImportService() *ImportService {
+	return &ImportService{}
+}
+
+// SetCollector sets the collector imported entries are written through.
+func (s *ImportService) SetCollector(c ImportCollector) *ImportService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.collector = c
+	return s
+}
+
+// Import reads a JSON array or newline-delimited JSON of ImportItems from r,
+// validates and de-duplicates them, writes the rest through the collector
+// and schedules a cache sync of what changed. A body that is not JSON fails
+// with ErrInvalidImport; invalid items are only skipped.
+func (s *ImportService) Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	started := time.Now()
+
+	source := ImportSource
+	if opts.Label != "" {
+		label := strings.ToLower(opts.Label)
+		if !importLabel.MatchString(label) {
+			return nil, errors.Join(ErrInvalidImport, errors.New("source label must be 1-63 letters, digits, '-' or '_'"))
+		}
+		source += "-" + label
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.collector == nil {
+		return nil, ErrImportUnavailable
+	}
+
+	if mc, _ := collector.GetMetricsCollector(); mc != nil {
+		mc.IncrementImportRequests(source)
+	}
+
+	report := &ImportReport{Source: source, ProcessID: uuid.New().String()}
+	s.collector.StartProviderProcessing(ctx, source, report.ProcessID)
+
+	seen := make(map[string]bool)
+	err := decodeImport(r, func(index int, item ImportItem) {
+		report.Parsed++
+		entry, err := importEntry(item, source, report.ProcessID, opts.Category)
+		if err != nil {
+			report.Invalid++
+			if len(report.Errors) < maxImportErrors {
+				report.Errors = append(report.Errors, ImportError{Index: index, URL: item.URL, Error: err.Error()})
+			}
+			return
+		}
+		if seen[entry.SourceURL] {
+			report.Duplicates++
+			return
+		}
+		seen[entry.SourceURL] = true
+		s.collector.Submit(entry)
+	})
+
+	// Entries submitted before a malformed body are still written.
+	report.Saved, _, _ = s.collector.FinishProviderProcessing(source, report.ProcessID)
+	report.Skipped = report.Invalid + report.Duplicates
+	report.Duration = time.Since(started)
+	if report.Saved > 0 && !s.collector.ScheduleChangedCacheSync(started.UnixNano()) {
+		log.Warn().Str("source", source).Msg("Cache sync after import not scheduled - sync queue is full")
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("source", source).Int("parsed", report.Parsed).Msg("Import body is not valid JSON")
+		return report, errors.Join(ErrInvalidImport, err)
+	}
+
+	log.Info().
+		Str("source", source).
+		Str("process_id", report.ProcessID).
+		Int("parsed", report.Parsed).
+		Int("saved", report.Saved).
+		Int("skipped", report.Skipped).
+		Dur("duration", report.Duration).
+		Msg("Import completed")
+	return report, nil
+}
+
+// decodeImport calls fn with every item of a JSON array or of
+// newline-delimited JSON. Items that are neither objects nor strings are
+// passed with their URL empty so they are counted as invalid.
+func decodeImport(r io.Reader, fn func(index int, item ImportItem)) error {
+	br := bufio.NewReader(r)
+	first, err := firstByte(br)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(br)
+	if first == '[' {
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	}
+
+	for index := 0; ; index++ {
+		if first == '[' && !dec.More() {
+			_, err := dec.Token() // Closing bracket
+			return err
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if err == io.EOF && first != '[' {
+				return nil
+			}
+			return fmt.Errorf("item %d: %w", index, err)
+		}
+
+		var item ImportItem
+		if err := json.Unmarshal(raw, &item); err != nil {
+			item = ImportItem{}
+		}
+		fn(index, item)
+	}
+}
+
+// firstByte peeks at the first byte of r that is not white space.
+func firstByte(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.ContainsAny(b, " \t\r\n") {
+			return b[0], nil
+		}
+		br.Discard(1)
+	}
+}
+
+// importEntry validates item and builds its entry.
+func importEntry(item ImportItem, source, processID, category string) (*entries.Entry, error) {
+	link := strings.TrimSpace(item.URL)
+	switch {
+	case link == "":
+		return nil, errors.New("url is required")
+	case len(link) > maxImportURLLength:
+		return nil, fmt.Errorf("url is longer than %d bytes", maxImportURLLength)
+	case item.Confidence != nil && (*item.Confidence < 0 || *item.Confidence > 1):
+		return nil, errors.New("confidence must be between 0 and 1")
+	}
+
+	entry := entries.NewEntry().WithSource(source).WithProcessID(processID)
+	if err := entry.SetURL(link); err != nil {
+		return nil, err
+	}
+	if entry.Host == "" && utils.OpaqueScheme(link) == "" {
+		return nil, errors.New("url has no host")
+	}
+
+	switch {
+	case len(item.Categories) > 0:
+		entry.WithCategories(append([]string{item.Category}, item.Categories...)...)
+	case item.Category != "":
+		entry.WithCategory(item.Category)
+	default:
+		entry.WithCategory(category)
+	}
+	if item.Confidence != nil {
+		entry.WithConfidence(*item.Confidence)
+	}
+	return entry, nil
+}
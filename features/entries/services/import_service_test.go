@@ -0,0 +1,83 @@
+package services
+
+import (
+	"blacked/features/entries"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeImportCollector struct {
+	submitted []*entries.Entry
+	started   string
+	syncs     int
+}
+
+func (f *fakeImportCollector) Submit(e *entries.Entry) { f.submitted = append(f.submitted, e) }
+
+func (f *fakeImportCollector) StartProviderProcessing(_ context.Context, name, _ string) {
+	f.started = name
+}
+
+func (f *fakeImportCollector) FinishProviderProcessing(string, string) (int, time.Duration, bool) {
+	return len(f.submitted), time.Millisecond, true
+}
+
+func (f *fakeImportCollector) ScheduleChangedCacheSync(int64) bool {
+	f.syncs++
+	return true
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	_, err := NewImportService().Import(ctx, strings.NewReader(`[]`), ImportOptions{})
+	assert.ErrorIs(t, err, ErrImportUnavailable)
+
+	fake := &fakeImportCollector{}
+	svc := NewImportService().SetCollector(fake)
+
+	body := `[
+		{"url": "https://evil.example/login", "category": "phishing", "confidence": 0.9},
+		"evil.example/login",
+		{"url": "https://evil.example/login"},
+		{"url": "https://bad.example/", "categories": ["malware", "c2"]},
+		{"url": ""},
+		{"url": "https://x.example/", "confidence": 2},
+		42
+	]`
+	report, err := svc.Import(ctx, strings.NewReader(body), ImportOptions{Label: "Partner", Category: "imported"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "import-partner", report.Source)
+	assert.Equal(t, "import-partner", fake.started)
+	assert.Equal(t, 7, report.Parsed)
+	assert.Equal(t, 3, report.Saved)
+	assert.Equal(t, 1, report.Duplicates)
+	assert.Equal(t, 3, report.Invalid)
+	assert.Equal(t, 4, report.Skipped)
+	require.Len(t, report.Errors, 3)
+	assert.Equal(t, 4, report.Errors[0].Index)
+	assert.Equal(t, 1, fake.syncs)
+
+	require.Len(t, fake.submitted, 3)
+	assert.Equal(t, "phishing", fake.submitted[0].Category)
+	assert.Equal(t, 0.9, fake.submitted[0].Confidence)
+	assert.Equal(t, "imported", fake.submitted[1].Category, "items without a category get the default")
+	assert.Equal(t, []string{"malware", "c2"}, fake.submitted[2].AllCategories())
+
+	// NDJSON, with a broken last line.
+	fake = &fakeImportCollector{}
+	svc.SetCollector(fake)
+	report, err = svc.Import(ctx, strings.NewReader("{\"url\": \"https://a.example/\"}\n\"https://b.example/\"\n{broken"), ImportOptions{})
+	assert.ErrorIs(t, err, ErrInvalidImport)
+	assert.Equal(t, ImportSource, report.Source)
+	assert.Equal(t, 2, report.Saved, "items before the broken line are kept")
+	assert.Equal(t, 1, fake.syncs)
+
+	_, err = svc.Import(ctx, strings.NewReader(`[]`), ImportOptions{Label: "no spaces"})
+	assert.ErrorIs(t, err, ErrInvalidImport)
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/internal/db"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrMergeScan  = errors.New("failed to scan entries for near-duplicates")
+	ErrMergeWrite = errors.New("failed to merge near-duplicate entries")
+)
+
+// mergeBatchSize is the number of groups merged per transaction.
+const mergeBatchSize = 500
+
+// MergeOptions controls a merge run.
+type MergeOptions struct {
+	Source    string // Restrict to one source; empty means all
+	Apply     bool   // Merge the groups found; false is a dry run
+	MaxGroups int    // Maximum number of groups kept in the report
+}
+
+// MergeGroup is a set of near-duplicate entries of one source and the
+// canonical entry they merge into.
+type MergeGroup struct {
+	Source       string   `json:"source"`
+	CanonicalID  string   `json:"canonical_id"`
+	CanonicalURL string   `json:"canonical_url"`
+	MergedIDs    []string `json:"merged_ids"`
+	MergedURLs   []string `json:"merged_urls"`
+	Categories   []string `json:"categories,omitempty"`
+	Confidence   float64  `json:"confidence,omitempty"`
+}
+
+// MergeReport summarises a merge run.
+type MergeReport struct {
+	ProcessID  string        `json:"process_id,omitempty"`
+	Applied    bool          `json:"applied"`
+	Candidates int           `json:"candidates"` // Entries with at least one near-duplicate
+	Groups     int           `json:"groups"`
+	Merged     int           `json:"merged"` // Entries soft deleted into a canonical one
+	Details    []MergeGroup  `json:"details"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// MergeService consolidates entries of one source that differ only in
+// scheme, host case or trailing slashes, which feeds and runs have stored
+// as separate rows.
+type MergeService struct {
+	repo  repository.BlacklistRepository
+	cache CacheSyncer // nil leaves the caches to the next scheduled sync
+}
+
+// NewMergeService creates a MergeService on the write database connection.
+func NewMergeService() (*MergeService, error) {
+	dbConn, err := db.GetWriteDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+
+	return NewMergeServiceWithRepository(repository.NewSQLiteRepository(dbConn)), nil
+}
+
+// NewMergeServiceWithRepository creates a MergeService on the given repository.
+func NewMergeServiceWithRepository(repo repository.BlacklistRepository) *MergeService {
+	return &MergeService{repo: repo}
+}
+
+// SetCacheSyncer sets what is asked to resync the caches after a merge.
+func (s *MergeService) SetCacheSyncer(c CacheSyncer) *MergeService {
+	s.cache = c
+	return s
+}
+
+// Run groups the active near-duplicates of each source under their
+// canonical key. The oldest entry of a group is canonical: it keeps its ID
+// and hits, and takes the union of the group's categories and the highest
+// confidence. With Apply set, the rest are soft deleted into it under a
+// dedicated process ID.
+func (s *MergeService) Run(ctx context.Context, opts MergeOptions) (*MergeReport, error) {
+	start := time.Now()
+	report := &MergeReport{
+		Applied: opts.Apply,
+		Details: []MergeGroup{},
+	}
+	if opts.Apply {
+		report.ProcessID = uuid.New().String()
+	}
+
+	candidates, err := s.repo.GetNearDuplicates(ctx, opts.Source)
+	if err != nil {
+		log.Err(err).Str("source", opts.Source).Msg("Near-duplicate query failed")
+		return report, ErrMergeScan
+	}
+	report.Candidates = len(candidates)
+
+	var merges []repository.EntryMerge
+	for _, group := range groupNearDuplicates(candidates) {
+		canonical := group[0]
+		categories := slices.Clone(canonical.AllCategories())
+		confidence := canonical.Confidence
+		detail := MergeGroup{
+			Source:       canonical.Source,
+			CanonicalID:  canonical.ID,
+			CanonicalURL: canonical.SourceURL,
+		}
+		for _, e := range group[1:] {
+			categories = append(categories, e.AllCategories()...)
+			confidence = max(confidence, e.Confidence)
+			detail.MergedIDs = append(detail.MergedIDs, e.ID)
+			detail.MergedURLs = append(detail.MergedURLs, e.SourceURL)
+		}
+		canonical.WithCategories(categories...).WithConfidence(confidence)
+		detail.Categories = canonical.AllCategories()
+		detail.Confidence = canonical.Confidence
+
+		report.Groups++
+		report.Merged += len(detail.MergedIDs)
+		if opts.MaxGroups <= 0 || len(report.Details) < opts.MaxGroups {
+			report.Details = append(report.Details, detail)
+		}
+
+		if opts.Apply {
+			canonical.ProcessID = report.ProcessID
+			merges = append(merges, repository.EntryMerge{Canonical: canonical, Merged: detail.MergedIDs})
+		}
+	}
+
+	since := start.UnixNano()
+	for len(merges) > 0 {
+		batch := merges[:min(mergeBatchSize, len(merges))]
+		merges = merges[len(batch):]
+		if err := s.repo.MergeEntries(ctx, batch); err != nil {
+			log.Err(err).Int("batch", len(batch)).Msg("Merge batch failed")
+			return report, ErrMergeWrite
+		}
+	}
+	if opts.Apply && report.Merged > 0 && s.cache != nil && !s.cache.ScheduleChangedCacheSync(since) {
+		log.Warn().Msg("Cache sync after merge not scheduled - sync queue is full")
+	}
+
+	report.Duration = time.Since(start)
+	log.Info().
+		Bool("applied", report.Applied).
+		Str("process_id", report.ProcessID).
+		Str("source", opts.Source).
+		Int("candidates", report.Candidates).
+		Int("groups", report.Groups).
+		Int("merged", report.Merged).
+		Dur("duration", report.Duration).
+		Msg("Merge completed")
+
+	return report, nil
+}
+
+// groupNearDuplicates splits candidates into groups of two or more sharing
+// a canonical key, each oldest first, in the order the groups first appear.
+func groupNearDuplicates(candidates []entries.Entry) [][]*entries.Entry {
+	var keys []string
+	groups := make(map[string][]*entries.Entry)
+	for i := range candidates {
+		e := &candidates[i]
+		key := canonicalKey(e)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], e)
+	}
+
+	result := make([][]*entries.Entry, 0, len(keys))
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			result = append(result, groups[key])
+		}
+	}
+	return result
+}
+
+// canonicalKey identifies an entry regardless of scheme, host case and
+// trailing slashes, as repository.GetNearDuplicates groups them. An entry
+// without a host is only a duplicate of the same source URL.
+func canonicalKey(e *entries.Entry) string {
+	if e.Host == "" {
+		return e.Source + "\x00" + e.SourceURL
+	}
+	return e.Source + "\x00" + strings.ToLower(e.Host) + strings.TrimRight(e.Path, "/") + "?" + e.RawQuery
+}
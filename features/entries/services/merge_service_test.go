@@ -0,0 +1,92 @@
+package services
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	idb "blacked/internal/db"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeDryRunAndApply(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+
+	newEntry := func(link, source string, createdAt int64, categories ...string) *entries.Entry {
+		e, err := entries.FromURL(link, source, "p1")
+		require.NoError(t, err)
+		e.WithCategories(categories...)
+		e.CreatedAt = createdAt
+		return e
+	}
+	canonical := newEntry("https://bad.example.com/login", "feed", 1, "phishing")
+	slash := newEntry("https://bad.example.com/login/", "feed", 2, "malware")
+	plain := newEntry("http://bad.example.com/login", "feed", 3, "phishing")
+	plain.WithConfidence(0.9)
+	other := newEntry("http://bad.example.com/login", "other", 4, "phishing")
+	query := newEntry("https://bad.example.com/login?x=1", "feed", 5, "phishing")
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{canonical, slash, plain, other, query}))
+
+	_, err = conn.Exec(`INSERT INTO entry_hits (entry_id, hits, first_hit_at, last_hit_at) VALUES (?, 2, 10, 20), (?, 3, 5, 15)`,
+		canonical.ID, slash.ID)
+	require.NoError(t, err)
+
+	svc := NewMergeServiceWithRepository(repo)
+
+	report, err := svc.Run(ctx, MergeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Candidates)
+	assert.Equal(t, 1, report.Groups)
+	assert.Equal(t, 2, report.Merged)
+	require.Len(t, report.Details, 1)
+	assert.Equal(t, canonical.ID, report.Details[0].CanonicalID, "the oldest entry is canonical")
+	assert.Equal(t, []string{slash.ID, plain.ID}, report.Details[0].MergedIDs)
+	assert.Equal(t, []string{"phishing", "malware"}, report.Details[0].Categories)
+
+	stored, err := repo.GetEntryByID(ctx, slash.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.DeletedAt, "a dry run changes nothing")
+
+	report, err = svc.Run(ctx, MergeOptions{Apply: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Merged)
+	assert.NotEmpty(t, report.ProcessID)
+
+	stored, err = repo.GetEntryByID(ctx, canonical.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.DeletedAt)
+	assert.Equal(t, []string{"phishing", "malware"}, stored.AllCategories())
+	assert.Equal(t, 0.9, stored.Confidence)
+	assert.Equal(t, report.ProcessID, stored.ProcessID)
+
+	for _, id := range []string{slash.ID, plain.ID} {
+		stored, err := repo.GetEntryByID(ctx, id)
+		require.NoError(t, err)
+		assert.NotNil(t, stored.DeletedAt, "merged entries are soft deleted")
+	}
+	for _, id := range []string{other.ID, query.ID} {
+		stored, err := repo.GetEntryByID(ctx, id)
+		require.NoError(t, err)
+		assert.Nil(t, stored.DeletedAt, "other sources and queries are kept apart")
+	}
+
+	var hits, first, last int64
+	require.NoError(t, conn.QueryRow(`SELECT hits, first_hit_at, last_hit_at FROM entry_hits WHERE entry_id = ?`, canonical.ID).
+		Scan(&hits, &first, &last))
+	assert.Equal(t, []int64{5, 5, 20}, []int64{hits, first, last})
+	var merged int
+	require.NoError(t, conn.QueryRow(`SELECT COUNT(*) FROM entry_hits WHERE entry_id = ?`, slash.ID).Scan(&merged))
+	assert.Zero(t, merged)
+
+	report, err = svc.Run(ctx, MergeOptions{Apply: true})
+	require.NoError(t, err)
+	assert.Zero(t, report.Groups, "second pass finds nothing to merge")
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/internal/db"
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrPatternSource = errors.New("pattern source is required")
+
+// patternBatchSize is the number of patterns upserted per transaction on import.
+const patternBatchSize = 1000
+
+// PatternImportOptions controls a pattern import.
+type PatternImportOptions struct {
+	Source   string              // Source the patterns belong to; required
+	Kind     entries.PatternKind // Kind of every line
+	Category string
+	Replace  bool // Remove the source's patterns missing from this import
+}
+
+// PatternImportReport summarises a pattern import.
+type PatternImportReport struct {
+	Source    string        `json:"source"`
+	ProcessID string        `json:"process_id"`
+	Imported  int           `json:"imported"`
+	Rejected  int           `json:"rejected"` // Lines that do not compile
+	Removed   int64         `json:"removed"`  // Older patterns removed by Replace
+	Duration  time.Duration `json:"duration"`
+}
+
+// PatternService manages the regex and glob entries merged into link queries.
+type PatternService struct {
+	repo repository.BlacklistRepository
+}
+
+// NewPatternService creates a PatternService on the write database connection.
+func NewPatternService() (*PatternService, error) {
+	dbConn, err := db.GetWriteDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+
+	return NewPatternServiceWithRepository(repository.NewSQLiteRepository(dbConn)), nil
+}
+
+// NewPatternServiceWithRepository creates a PatternService on the given repository.
+func NewPatternServiceWithRepository(repo repository.BlacklistRepository) *PatternService {
+	return &PatternService{repo: repo}
+}
+
+// List returns the active patterns of source, or of every source when it is empty.
+func (s *PatternService) List(ctx context.Context, source string) ([]entries.Pattern, error) {
+	return s.repo.ListPatterns(ctx, source)
+}
+
+// Add validates and stores one pattern of source.
+func (s *PatternService) Add(ctx context.Context, kind entries.PatternKind, pattern, source, category string) (*entries.Pattern, error) {
+	if source == "" {
+		return nil, ErrPatternSource
+	}
+	p, err := entries.NewPattern(kind, pattern, source, uuid.New().String())
+	if err != nil {
+		return nil, err
+	}
+	p.WithCategory(category)
+	if err := s.repo.SavePatterns(ctx, []*entries.Pattern{p}); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("id", p.ID).Str("kind", string(kind)).Str("source", source).Str("pattern", p.Pattern).Msg("Pattern added")
+	return p, nil
+}
+
+// Remove deletes the pattern with id.
+func (s *PatternService) Remove(ctx context.Context, id string) error {
+	if err := s.repo.SoftDeletePattern(ctx, id); err != nil {
+		return err
+	}
+
+	log.Info().Str("id", id).Msg("Pattern removed")
+	return nil
+}
+
+// Match returns the active patterns matching link.
+func (s *PatternService) Match(ctx context.Context, link string) ([]*entries.Pattern, error) {
+	return s.repo.MatchPatterns(ctx, link)
+}
+
+// Import stores one pattern per line of r, skipping blank lines and "#"
+// comments, under a new process ID. Lines that do not compile are counted
+// and skipped. With Replace set, the source's patterns not in r are removed
+// afterwards, as a provider run replaces its entries.
+func (s *PatternService) Import(ctx context.Context, r io.Reader, opts PatternImportOptions) (*PatternImportReport, error) {
+	if opts.Source == "" {
+		return nil, ErrPatternSource
+	}
+	start := time.Now()
+	report := &PatternImportReport{Source: opts.Source, ProcessID: uuid.New().String()}
+
+	batch := make([]*entries.Pattern, 0, patternBatchSize)
+	flush := func() error {
+		if err := s.repo.SavePatterns(ctx, batch); err != nil {
+			return err
+		}
+		report.Imported += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := entries.NewPattern(opts.Kind, line, opts.Source, report.ProcessID)
+		if err != nil {
+			log.Warn().Err(err).Str("pattern", line).Msg("Skipping invalid pattern")
+			report.Rejected++
+			continue
+		}
+		batch = append(batch, p.WithCategory(opts.Category))
+		if len(batch) == patternBatchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Err(err).Str("source", opts.Source).Msg("Failed to read patterns")
+		return report, errors.Join(ErrInvalidImport, err)
+	}
+	if err := flush(); err != nil {
+		return report, err
+	}
+
+	if opts.Replace {
+		removed, err := s.repo.RemoveOlderPatterns(ctx, opts.Source, report.ProcessID)
+		if err != nil {
+			return report, err
+		}
+		report.Removed = removed
+	}
+
+	report.Duration = time.Since(start)
+	log.Info().
+		Str("source", opts.Source).
+		Str("process_id", report.ProcessID).
+		Int("imported", report.Imported).
+		Int("rejected", report.Rejected).
+		Int64("removed", report.Removed).
+		Dur("duration", report.Duration).
+		Msg("Patterns imported")
+	return report, nil
+}
@@ -1,12 +1,22 @@
 package services
 
 import (
+	"blacked/features/allowlist"
 	"blacked/features/entries"
 	"blacked/features/entries/enums"
 	"blacked/features/entries/repository"
+	"blacked/features/hits"
+	"blacked/internal/collector"
+	"blacked/internal/config"
 	"blacked/internal/db"
+	"blacked/internal/logger"
+	"blacked/internal/pagination"
+	"blacked/internal/query"
+	"cmp"
 	"context"
 	"errors"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -16,11 +26,91 @@ import (
 var (
 	ErrDatabaseConnection = errors.New("failed to connect to the database")
 	ErrQueryBlacklist     = errors.New("failed to query blacklist entries")
+	ErrStreamEntries      = errors.New("failed to stream blacklist entries")
+	ErrSearchEntries      = errors.New("failed to search blacklist entries")
 )
 
+const (
+	// DefaultStreamPageSize is the number of rows StreamEntries reads per page.
+	DefaultStreamPageSize = 1000
+
+	// DefaultSearchLimit and MaxSearchLimit bound one page of Search results.
+	DefaultSearchLimit = 100
+	MaxSearchLimit     = 1000
+
+	idLookupChunk = 500
+
+	// defaultSourceTrust weighs sources without a configured trust, as the
+	// bloom scorer does.
+	defaultSourceTrust = 0.5
+)
+
+// matchStrength scales the trust of a source by how closely its entry
+// matched the queried URL: the exact URL counts fully, the whole host or a
+// path less, a parent domain or network less again, and a shared registered
+// domain or a pattern least.
+var matchStrength = map[string]float64{
+	entries.MatchTypeExactURL:  1.0,
+	entries.MatchTypeFull:      1.0,
+	entries.MatchTypeHost:      0.8,
+	entries.MatchTypePath:      0.7,
+	entries.MatchTypeSubdomain: 0.6,
+	entries.MatchTypeNetwork:   0.6,
+	entries.MatchTypeDomain:    0.5,
+	entries.MatchTypePattern:   0.5,
+}
+
+// QueryOptions narrows a scored query.
+type QueryOptions struct {
+	Sources    []string // Only entries of these sources; empty means all
+	Categories []string // Only entries with one of these categories; empty means all
+}
+
+// matches reports whether e passes the source and category filters, which
+// are compared case-insensitively like repository.Filter's.
+func (o QueryOptions) matches(e *entries.HitEntry) bool {
+	if len(o.Sources) > 0 && !containsFold(o.Sources, e.Source) {
+		return false
+	}
+	if len(o.Categories) > 0 && !slices.ContainsFunc(e.AllCategories(), func(c string) bool {
+		return containsFold(o.Categories, c)
+	}) {
+		return false
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(v string) bool { return strings.EqualFold(v, s) })
+}
+
+// QueryResult is the hits of a scored query and the risk they add up to.
+type QueryResult struct {
+	Hits    []entries.Hit         `json:"hits"`
+	Score   float64               `json:"score"`
+	Level   string                `json:"level"`
+	Sources []entries.SourceScore `json:"sources"` // Highest score first
+}
+
+// Allowlist reports whether a link must never be reported as a hit;
+// implemented by allowlist.Service.
+type Allowlist interface {
+	Allowed(ctx context.Context, link string) (bool, error)
+}
+
+// HitRecorder counts matched entries without blocking the query;
+// implemented by hits.Counter.
+type HitRecorder interface {
+	Record(ids ...string)
+}
+
 // QueryService handles queries against the blacklist entries.
 type QueryService struct {
-	repo repository.BlacklistRepository
+	repo      repository.BlacklistRepository
+	allowlist Allowlist            // nil disables allowlist checks
+	hits      HitRecorder          // nil disables hit accounting
+	trust     map[string]float64   // Source → trust of scored queries
+	negative  *query.NegativeCache // nil disables negative caching
 }
 
 // NewQueryService creates a new QueryService instance.  It should handle potential errors during database connection initialization more robustly in a production environment.
@@ -31,22 +121,210 @@ func NewQueryService() (*QueryService, error) {
 		return nil, ErrDatabaseConnection
 	}
 
-	return &QueryService{repo: repository.NewSQLiteRepository(dbConn)}, nil
+	s := &QueryService{
+		repo:      repository.NewSQLiteRepository(dbConn),
+		allowlist: allowlist.NewServiceWithRepository(allowlist.NewSQLiteRepository(dbConn)),
+		trust:     config.LoadScoringConfig(),
+		negative:  query.SharedNegativeCache(),
+	}
+	if counter := hits.GetCounter(); counter != nil {
+		s.hits = counter
+	}
+	return s, nil
+}
+
+// NewQueryServiceWithRepository creates a QueryService on the given repository.
+func NewQueryServiceWithRepository(repo repository.BlacklistRepository) *QueryService {
+	return &QueryService{repo: repo}
+}
+
+// SetAllowlist sets the allowlist consulted before hits are returned; nil disables it.
+func (s *QueryService) SetAllowlist(a Allowlist) *QueryService {
+	s.allowlist = a
+	return s
+}
+
+// SetHitRecorder sets where matched entry IDs are counted; nil disables it.
+func (s *QueryService) SetHitRecorder(h HitRecorder) *QueryService {
+	s.hits = h
+	return s
+}
+
+// SetSourceTrust sets the 0–1 trust of each source weighing scored queries;
+// sources missing from trust weigh 0.5.
+func (s *QueryService) SetSourceTrust(trust map[string]float64) *QueryService {
+	s.trust = trust
+	return s
+}
+
+// SetNegativeCache sets the cache of links that found no entries; nil disables it.
+func (s *QueryService) SetNegativeCache(c *query.NegativeCache) *QueryService {
+	s.negative = c
+	return s
+}
+
+// RecordHits counts one hit for each matched entry ID, if hit accounting is on.
+func (s *QueryService) RecordHits(ids ...string) {
+	if s.hits != nil && len(ids) > 0 {
+		s.hits.Record(ids...)
+	}
+}
+
+// Allowlisted reports whether url is exempt from hits. Without an allowlist
+// nothing is.
+func (s *QueryService) Allowlisted(ctx context.Context, url string) (bool, error) {
+	if s.allowlist == nil {
+		return false, nil
+	}
+	return s.allowlist.Allowed(ctx, url)
 }
 
 // Query performs a query based on the provided URL and query type.  It handles various query types and returns the results.
 func (s *QueryService) Query(ctx context.Context, url string, queryType *enums.QueryType) ([]entries.Hit, error) {
-	log.Info().Msgf("Querying blacklist entries by URL: %s (type: %v)", url, queryType)
+	hits, err := s.lookup(ctx, url, queryType, false)
+	if err != nil {
+		return nil, err
+	}
+	s.recordHits(hits)
+	return hits, nil
+}
+
+// QueryScored runs Query over the entries of the sources and categories of
+// opts only, and scores the hits left. Each source contributes its trust
+// scaled by its strongest match, and by the entry's own confidence when
+// set; contributions combine as independent evidence, 1 − Π(1 − score), so
+// agreeing sources raise the score without it passing 1.
+func (s *QueryService) QueryScored(ctx context.Context, url string, queryType *enums.QueryType, opts QueryOptions) (*QueryResult, error) {
+	result := &QueryResult{
+		Hits:    []entries.Hit{},
+		Level:   query.ConfidenceLevel(0),
+		Sources: []entries.SourceScore{},
+	}
+
+	// The match queries select the entry fields scoring needs with the hits.
+	hits, err := s.lookup(ctx, url, queryType, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return result, nil
+	}
+
+	bySource := make(map[string]*entries.SourceScore)
+	for _, hit := range hits {
+		e := hit.Entry
+		if e == nil || !opts.matches(e) {
+			continue
+		}
+		result.Hits = append(result.Hits, hit)
+
+		src, ok := bySource[e.Source]
+		if !ok {
+			src = &entries.SourceScore{Source: e.Source, Trust: s.sourceTrust(e.Source)}
+			bySource[e.Source] = src
+		}
+		src.Hits++
+
+		score := src.Trust * matchStrength[hit.MatchType]
+		if e.Confidence > 0 {
+			score *= min(e.Confidence, 1)
+		}
+		if src.MatchType == "" || score > src.Score {
+			src.Score, src.MatchType = score, hit.MatchType
+		}
+	}
+
+	remaining := 1.0
+	for _, src := range bySource {
+		remaining *= 1 - src.Score
+		result.Sources = append(result.Sources, *src)
+	}
+	slices.SortFunc(result.Sources, func(a, b entries.SourceScore) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Source, b.Source)
+	})
+	result.Score = 1 - remaining
+	result.Level = query.ConfidenceLevel(result.Score)
+
+	s.recordHits(result.Hits)
+	return result, nil
+}
+
+// sourceTrust is the configured trust of source.
+func (s *QueryService) sourceTrust(source string) float64 {
+	if t, ok := s.trust[source]; ok {
+		return t
+	}
+	return defaultSourceTrust
+}
+
+// lookup returns the hits of url, or none when it is allowlisted.
+// With enrich set, the hits carry their entry's fields.
+func (s *QueryService) lookup(ctx context.Context, url string, queryType *enums.QueryType, enrich bool) ([]entries.Hit, error) {
+	log.Info().Msgf("Querying blacklist entries by URL: %s (type: %v)", logger.RedactURL(url), queryType)
 	startTime := time.Now()
-	hits, err := s.repo.QueryLinkByType(ctx, url, queryType)
+	key := negativeKey(url, queryType)
+	if s.negative.Contains(key) {
+		collector.ObserveQueryLatency(time.Since(startTime))
+		log.Debug().Msg("Query answered by the negative cache")
+		return []entries.Hit{}, nil
+	}
+	query := s.repo.QueryLinkByType
+	if enrich {
+		query = s.repo.QueryLinkEnriched
+	}
+	hits, err := query(ctx, url, queryType)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to query blacklist entries")
 		return nil, ErrQueryBlacklist
 	}
-	log.Debug().Dur("duration", time.Since(startTime)).Msgf("Query completed, %d hits found", len(hits))
+	duration := time.Since(startTime)
+	collector.ObserveQueryLatency(duration)
+	log.Debug().Dur("duration", duration).Msgf("Query completed, %d hits found", len(hits))
+
+	if len(hits) == 0 {
+		s.negative.Add(key)
+	}
+
+	if len(hits) > 0 {
+		allowed, err := s.Allowlisted(ctx, url)
+		if err != nil {
+			// Keep reporting the hits: a broken allowlist must not unblock links.
+			log.Warn().Err(err).Msg("Failed to check allowlist, returning hits")
+		} else if allowed {
+			log.Debug().Int("hits", len(hits)).Msg("URL is allowlisted, dropping hits")
+			return []entries.Hit{}, nil
+		}
+	}
 	return hits, nil
 }
 
+// negativeKey is the NegativeCache key of a lookup of url by queryType.
+func negativeKey(url string, queryType *enums.QueryType) string {
+	kind := "all"
+	if queryType != nil {
+		kind = queryType.String()
+	}
+	return "query:" + kind + ":" + url
+}
+
+// recordHits counts the entries of hits, once per query however many ways
+// each matched.
+func (s *QueryService) recordHits(hits []entries.Hit) {
+	if s.hits != nil {
+		// One query counts once per entry, however many ways it matched.
+		ids := make([]string, 0, len(hits))
+		for _, hit := range hits {
+			if !slices.Contains(ids, hit.ID) {
+				ids = append(ids, hit.ID)
+			}
+		}
+		s.RecordHits(ids...)
+	}
+}
+
 func (s *QueryService) GetEntryByID(ctx context.Context, id string) (*entries.Entry, error) {
 	// Call your repository to get the entry by ID
 	entry, err := s.repo.GetEntryByID(ctx, id)
@@ -57,6 +335,20 @@ func (s *QueryService) GetEntryByID(ctx context.Context, id string) (*entries.En
 	return entry, nil
 }
 
+// GetEntriesByIDs returns the entries with the given IDs, skipping unknown
+// ones. IDs are looked up in chunks to stay under SQLite's variable limit.
+func (s *QueryService) GetEntriesByIDs(ctx context.Context, ids []string) ([]*entries.Entry, error) {
+	var found []*entries.Entry
+	for chunk := range slices.Chunk(ids, idLookupChunk) {
+		list, err := s.repo.GetEntriesByIDs(ctx, chunk)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, list...)
+	}
+	return found, nil
+}
+
 func (s *QueryService) GetIdsByLink(ctx context.Context, link string) ([]string, error) {
 	hits := s.repo.QueryExactURLMatch(ctx, link)
 
@@ -67,3 +359,65 @@ func (s *QueryService) GetIdsByLink(ctx context.Context, link string) ([]string,
 
 	return ids, nil
 }
+
+// StreamEntries calls fn for every active entry, optionally of one source,
+// in ID order. Entries are read pageSize rows at a time so the stream never
+// holds the whole table; returning an error from fn stops the stream.
+func (s *QueryService) StreamEntries(ctx context.Context, source string, pageSize int, fn func(*entries.Entry) error) error {
+	if pageSize <= 0 {
+		pageSize = DefaultStreamPageSize
+	}
+
+	afterID := ""
+	for {
+		page, err := s.repo.GetEntriesPage(ctx, source, afterID, pageSize)
+		if err != nil {
+			log.Error().Err(err).Str("source", source).Msg("Failed to read entries page")
+			return ErrStreamEntries
+		}
+
+		for i := range page {
+			if err := fn(&page[i]); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < pageSize {
+			return nil
+		}
+		afterID = page[len(page)-1].ID
+	}
+}
+
+// Search returns the page of entries matching f after the p.After ID, in ID
+// order. The total estimate counts every entry matching f.
+func (s *QueryService) Search(ctx context.Context, f repository.Filter, p pagination.Params) (*pagination.Page[entries.Entry], error) {
+	if p.Limit <= 0 || p.Limit > MaxSearchLimit {
+		p.Limit = DefaultSearchLimit
+	}
+
+	// One extra row tells whether another page follows.
+	list, err := s.repo.SearchEntries(ctx, f, p.After, p.Limit+1)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to search entries")
+		return nil, ErrSearchEntries
+	}
+	stats, err := s.repo.GetEntryStats(ctx, f)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to count searched entries")
+		return nil, ErrSearchEntries
+	}
+
+	page := pagination.NewPage(list, p.Limit, stats.Total, func(e entries.Entry) string { return e.ID })
+	return &page, nil
+}
+
+// Stats counts the entries matching f.
+func (s *QueryService) Stats(ctx context.Context, f repository.Filter) (*repository.EntryStats, error) {
+	stats, err := s.repo.GetEntryStats(ctx, f)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to count entries")
+		return nil, ErrQueryBlacklist
+	}
+	return stats, nil
+}
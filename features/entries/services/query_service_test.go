@@ -0,0 +1,198 @@
+package services
+
+import (
+	"blacked/features/allowlist"
+	"blacked/features/entries"
+	"blacked/features/entries/enums"
+	"blacked/features/entries/repository"
+	idb "blacked/internal/db"
+	"blacked/internal/query"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryServiceDropsAllowlistedHits(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+	e, err := entries.FromURL("https://login.example.com/", "feed", "p1")
+	require.NoError(t, err)
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{e}))
+
+	allow := allowlist.NewServiceWithRepository(allowlist.NewSQLiteRepository(conn))
+	query := NewQueryServiceWithRepository(repo).SetAllowlist(allow)
+	qt := enums.QueryTypeMixed
+
+	hits, err := query.Query(ctx, "https://login.example.com/", &qt)
+	require.NoError(t, err)
+	assert.NotEmpty(t, hits)
+
+	_, err = allow.Add(ctx, allowlist.KindDomain, "example.com", "")
+	require.NoError(t, err)
+
+	hits, err = query.Query(ctx, "https://login.example.com/", &qt)
+	require.NoError(t, err)
+	assert.Empty(t, hits, "allowlisted URLs are never reported")
+}
+
+type recordedHits []string
+
+func (r *recordedHits) Record(ids ...string) { *r = append(*r, ids...) }
+
+func TestQueryServiceRecordsHitsOncePerEntry(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+	e, err := entries.FromURL("https://login.example.com/", "feed", "p1")
+	require.NoError(t, err)
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{e}))
+
+	var recorded recordedHits
+	query := NewQueryServiceWithRepository(repo).SetHitRecorder(&recorded)
+	qt := enums.QueryTypeMixed
+
+	_, err = query.Query(ctx, "https://login.example.com/", &qt)
+	require.NoError(t, err)
+	assert.Equal(t, recordedHits{e.ID}, recorded)
+
+	_, err = query.Query(ctx, "https://other.example.org/", &qt)
+	require.NoError(t, err)
+	assert.Len(t, recorded, 1, "misses are not counted")
+}
+
+func TestQueryScoredFiltersAndWeighsSources(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+	trusted, err := entries.FromURL("https://login.example.com/verify", "trusted", "p1")
+	require.NoError(t, err)
+	trusted.WithCategory("phishing")
+	unrated, err := entries.FromURL("https://login.example.com/verify", "unrated", "p1")
+	require.NoError(t, err)
+	unrated.WithCategory("malware")
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{trusted, unrated}))
+
+	query := NewQueryServiceWithRepository(repo).SetSourceTrust(map[string]float64{"trusted": 0.9})
+	qt := enums.QueryTypeMixed
+
+	result, err := query.QueryScored(ctx, "https://login.example.com/verify", &qt, QueryOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 2)
+	require.Len(t, result.Sources, 2)
+	assert.Equal(t, "trusted", result.Sources[0].Source, "highest score first")
+	assert.Equal(t, entries.MatchTypeExactURL, result.Sources[0].MatchType)
+	assert.InDelta(t, 0.9, result.Sources[0].Score, 1e-9)
+	assert.InDelta(t, 0.5, result.Sources[1].Score, 1e-9, "unconfigured sources weigh 0.5")
+	assert.InDelta(t, 0.95, result.Score, 1e-9, "agreeing sources raise the score")
+	assert.Equal(t, "critical", result.Level)
+
+	result, err = query.QueryScored(ctx, "https://login.example.com/verify", &qt, QueryOptions{Sources: []string{"Unrated"}})
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, "unrated", result.Hits[0].Source)
+	assert.InDelta(t, 0.5, result.Score, 1e-9)
+	assert.Equal(t, "medium", result.Level)
+
+	result, err = query.QueryScored(ctx, "https://login.example.com/verify", &qt, QueryOptions{Categories: []string{"phishing"}})
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, trusted.ID, result.Hits[0].ID)
+
+	result, err = query.QueryScored(ctx, "https://login.example.com/verify", &qt, QueryOptions{Sources: []string{"other"}})
+	require.NoError(t, err)
+	assert.Empty(t, result.Hits)
+	assert.Zero(t, result.Score)
+	assert.Equal(t, "informational", result.Level)
+
+	pattern, err := entries.NewPattern(entries.PatternKindRegex, `/verify$`, "patterns", "p1")
+	require.NoError(t, err)
+	require.NoError(t, repo.SavePatterns(ctx, []*entries.Pattern{pattern.WithCategory("phishing")}))
+	result, err = query.QueryScored(ctx, "https://login.example.com/verify", &qt, QueryOptions{Sources: []string{"patterns"}})
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 1, "pattern hits are filtered and scored like entries")
+	assert.Equal(t, entries.MatchTypePattern, result.Hits[0].MatchType)
+	assert.InDelta(t, 0.25, result.Score, 1e-9)
+}
+
+func TestQueryServiceNegativeCachePurgedOnWrite(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	negative := query.SharedNegativeCache()
+	require.NotNil(t, negative, "the negative cache is on by default")
+	t.Cleanup(negative.Purge)
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+	svc := NewQueryServiceWithRepository(repo).SetNegativeCache(negative)
+	qt := enums.QueryTypeMixed
+
+	hits, err := svc.Query(ctx, "https://late.example.com/", &qt)
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+	assert.True(t, negative.Contains(negativeKey("https://late.example.com/", &qt)))
+
+	e, err := entries.FromURL("https://late.example.com/", "feed", "p1")
+	require.NoError(t, err)
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{e}))
+
+	hits, err = svc.Query(ctx, "https://late.example.com/", &qt)
+	require.NoError(t, err)
+	assert.NotEmpty(t, hits, "a write purges the cached miss")
+}
+
+func TestQueryServiceMatchesOpaqueSchemesExactly(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+	script, err := entries.FromURL(" JavaScript:alert(document.cookie)\n", "feed", "p1")
+	require.NoError(t, err)
+	assert.Equal(t, "javascript", script.Scheme)
+	assert.Equal(t, "javascript:alert(document.cookie)", script.SourceURL, "trimmed, with a lower-case scheme")
+	assert.Empty(t, script.Host, "opaque links are not read as hosts")
+	payload, err := entries.FromURL("data:text/html;base64,PHNjcmlwdD4=", "feed", "p1")
+	require.NoError(t, err)
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{script, payload}))
+
+	svc := NewQueryServiceWithRepository(repo)
+	qt := enums.QueryTypeMixed
+
+	hits, err := svc.Query(ctx, "javascript:alert(document.cookie)", &qt)
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, script.ID, hits[0].ID)
+
+	hits, err = svc.Query(ctx, "  JAVASCRIPT:alert(document.cookie)", &qt)
+	require.NoError(t, err)
+	require.Len(t, hits, 1, "the lookup is trimmed and its scheme lower-cased too")
+
+	hits, err = svc.Query(ctx, "data:text/html;base64,PHNjcmlwdD4=", &qt)
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, payload.ID, hits[0].ID)
+
+	hits, err = svc.Query(ctx, "javascript:alert(1)", &qt)
+	require.NoError(t, err)
+	assert.Empty(t, hits, "opaque links share no host to match on")
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/internal/db"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrInvalidReclassify  = errors.New("invalid reclassification")
+	ErrReclassifyBusy     = errors.New("a reclassification is already running")
+	ErrReclassifyNotFound = errors.New("reclassification not found")
+	ErrReclassifyScan     = errors.New("failed to read entries to reclassify")
+	ErrReclassifyWrite    = errors.New("failed to reclassify entries")
+)
+
+const (
+	// reclassifyBatchSize is the number of entries read and rewritten per transaction.
+	reclassifyBatchSize = 1000
+	// maxReclassifyJobs bounds the job history kept for polling.
+	maxReclassifyJobs = 32
+	// maxCategoryLength caps the target category.
+	maxCategoryLength = 128
+)
+
+// ReclassifyRequest moves the entries matching Filter to category To. When
+// the filter names categories, only those are replaced and an entry keeps
+// its others; otherwise To becomes an entry's only category.
+type ReclassifyRequest struct {
+	Filter repository.Filter `json:"filter"`
+	To     string            `json:"to"`
+}
+
+// Validate trims the target category and checks the request. An empty
+// filter returns repository.ErrEmptyFilter.
+func (r *ReclassifyRequest) Validate() error {
+	r.To = strings.TrimSpace(r.To)
+	switch {
+	case r.To == "":
+		return errors.Join(ErrInvalidReclassify, errors.New("target category is required"))
+	case len(r.To) > maxCategoryLength:
+		return errors.Join(ErrInvalidReclassify, errors.New("target category is too long"))
+	case r.Filter.IsEmpty():
+		return repository.ErrEmptyFilter
+	}
+	return nil
+}
+
+// ReclassifyState is the lifecycle state of a reclassification.
+type ReclassifyState string
+
+const (
+	ReclassifyRunning   ReclassifyState = "running"
+	ReclassifyCompleted ReclassifyState = "completed"
+	ReclassifyFailed    ReclassifyState = "failed"
+)
+
+// ReclassifyJob is a point-in-time view of a reclassification. Its ID is the
+// process ID the rewritten entries carry.
+type ReclassifyJob struct {
+	ID         string            `json:"id"`
+	Request    ReclassifyRequest `json:"request"`
+	State      ReclassifyState   `json:"state"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+	Expected   int               `json:"expected"`  // Entries the filter matched at the start
+	Processed  int               `json:"processed"` // Entries read so far
+	Updated    int               `json:"updated"`   // Entries whose categories changed
+	Percent    float64           `json:"percent"`   // Processed / Expected, capped at 100
+	Error      string            `json:"error,omitempty"`
+}
+
+// ReclassifyService moves entries between categories in bulk, as tracked
+// maintenance runs. Category changes reach replicas and the event bus as
+// entry.updated through the entry change feed; the caches hold no
+// categories, so nothing is resynced.
+type ReclassifyService struct {
+	repo repository.BlacklistRepository
+
+	mu      sync.Mutex
+	jobs    map[string]*ReclassifyJob
+	order   []string
+	running string // ID of the running job, empty when idle
+	wg      sync.WaitGroup
+}
+
+// NewReclassifyService creates a ReclassifyService on the write database connection.
+func NewReclassifyService() (*ReclassifyService, error) {
+	dbConn, err := db.GetWriteDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+
+	return NewReclassifyServiceWithRepository(repository.NewSQLiteRepository(dbConn)), nil
+}
+
+// NewReclassifyServiceWithRepository creates a ReclassifyService on the given repository.
+func NewReclassifyServiceWithRepository(repo repository.BlacklistRepository) *ReclassifyService {
+	return &ReclassifyService{repo: repo, jobs: make(map[string]*ReclassifyJob)}
+}
+
+// Start validates req, counts the entries it matches and runs it in the
+// background, returning its job for polling. Only one reclassification runs
+// at a time. The run outlives ctx.
+func (s *ReclassifyService) Start(ctx context.Context, req ReclassifyRequest) (ReclassifyJob, error) {
+	if err := req.Validate(); err != nil {
+		return ReclassifyJob{}, err
+	}
+
+	stats, err := s.repo.GetEntryStats(ctx, req.Filter)
+	if err != nil {
+		log.Err(err).Interface("filter", req.Filter).Msg("Failed to count entries to reclassify")
+		return ReclassifyJob{}, ErrReclassifyScan
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running != "" {
+		return ReclassifyJob{}, ErrReclassifyBusy
+	}
+
+	job := &ReclassifyJob{
+		ID:        uuid.New().String(),
+		Request:   req,
+		State:     ReclassifyRunning,
+		StartedAt: time.Now().UTC(),
+		Expected:  stats.Total,
+	}
+	s.add(job)
+	s.running = job.ID
+
+	ctx = context.WithoutCancel(ctx)
+	s.wg.Go(func() { s.run(ctx, job.ID, req) })
+	return *job, nil
+}
+
+// Get returns the current state of the job with id.
+func (s *ReclassifyService) Get(id string) (ReclassifyJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return ReclassifyJob{}, ErrReclassifyNotFound
+	}
+	return *job, nil
+}
+
+// Wait blocks until the running reclassification, if any, finished.
+func (s *ReclassifyService) Wait() {
+	s.wg.Wait()
+}
+
+// run pages through the entries matching req in ID order, rewriting each
+// batch in one transaction. Rewritten entries stop matching a category
+// filter, which the ID cursor does not depend on.
+func (s *ReclassifyService) run(ctx context.Context, id string, req ReclassifyRequest) {
+	start := time.Now()
+	var err error
+	defer func() {
+		s.finish(id, err)
+		job, _ := s.Get(id)
+		log.Info().
+			Str("process_id", id).
+			Str("to", req.To).
+			Str("state", string(job.State)).
+			Int("processed", job.Processed).
+			Int("updated", job.Updated).
+			Dur("duration", time.Since(start)).
+			Msg("Reclassification finished")
+	}()
+
+	afterID := ""
+	for {
+		var page []entries.Entry
+		page, err = s.repo.SearchEntries(ctx, req.Filter, afterID, reclassifyBatchSize)
+		if err != nil {
+			log.Err(err).Str("process_id", id).Msg("Failed to read entries to reclassify")
+			err = ErrReclassifyScan
+			return
+		}
+		if len(page) == 0 {
+			return
+		}
+		afterID = page[len(page)-1].ID
+
+		batch := make([]*entries.Entry, 0, len(page))
+		for i := range page {
+			e := &page[i]
+			if reclassify(e, req) {
+				e.ProcessID = id
+				batch = append(batch, e)
+			}
+		}
+		if err = s.repo.UpdateEntryCategories(ctx, batch); err != nil {
+			log.Err(err).Str("process_id", id).Int("batch", len(batch)).Msg("Reclassify batch failed")
+			err = ErrReclassifyWrite
+			return
+		}
+
+		s.update(id, len(page), len(batch))
+	}
+}
+
+// reclassify applies req to the categories of e and reports whether they changed.
+func reclassify(e *entries.Entry, req ReclassifyRequest) bool {
+	before := e.AllCategories()
+
+	var categories []string
+	if len(req.Filter.Categories) == 0 {
+		categories = []string{req.To}
+	} else {
+		for _, c := range before {
+			if containsFold(req.Filter.Categories, c) {
+				c = req.To
+			}
+			categories = append(categories, c)
+		}
+	}
+
+	e.WithCategories(categories...)
+	return !slices.Equal(before, e.AllCategories())
+}
+
+// add registers job, evicting the oldest finished jobs beyond maxReclassifyJobs.
+func (s *ReclassifyService) add(job *ReclassifyJob) {
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+	for i := 0; len(s.order) > maxReclassifyJobs && i < len(s.order); {
+		if s.order[i] == s.running {
+			i++
+			continue
+		}
+		delete(s.jobs, s.order[i])
+		s.order = append(s.order[:i], s.order[i+1:]...)
+	}
+}
+
+func (s *ReclassifyService) update(id string, processed, updated int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := s.jobs[id]
+	job.Processed += processed
+	job.Updated += updated
+	if job.Expected > 0 {
+		job.Percent = min(100, float64(job.Processed)/float64(job.Expected)*100)
+	}
+}
+
+func (s *ReclassifyService) finish(id string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := s.jobs[id]
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	job.State = ReclassifyCompleted
+	if err != nil {
+		job.State = ReclassifyFailed
+		job.Error = err.Error()
+	} else {
+		job.Percent = 100
+	}
+	s.running = ""
+}
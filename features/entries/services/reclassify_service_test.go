@@ -0,0 +1,89 @@
+package services
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	idb "blacked/internal/db"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReclassify(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+
+	newEntry := func(link, source string, categories ...string) *entries.Entry {
+		e, err := entries.FromURL(link, source, "p1")
+		require.NoError(t, err)
+		e.WithCategories(categories...)
+		return e
+	}
+	single := newEntry("https://a.example/", "feed", "malware")
+	multi := newEntry("https://b.example/", "feed", "Malware", "phishing")
+	both := newEntry("https://c.example/", "feed", "malware", "dropper")
+	untouched := newEntry("https://d.example/", "feed", "phishing")
+	otherSource := newEntry("https://e.example/", "other", "malware")
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{single, multi, both, untouched, otherSource}))
+
+	svc := NewReclassifyServiceWithRepository(repo)
+
+	_, err = svc.Start(ctx, ReclassifyRequest{To: "dropper"})
+	assert.ErrorIs(t, err, repository.ErrEmptyFilter)
+	_, err = svc.Start(ctx, ReclassifyRequest{Filter: repository.Filter{Sources: []string{"feed"}}, To: " "})
+	assert.ErrorIs(t, err, ErrInvalidReclassify)
+
+	job, err := svc.Start(ctx, ReclassifyRequest{
+		Filter: repository.Filter{Sources: []string{"feed"}, Categories: []string{"malware"}},
+		To:     "dropper",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ReclassifyRunning, job.State)
+	assert.Equal(t, 3, job.Expected)
+	svc.Wait()
+
+	job, err = svc.Get(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ReclassifyCompleted, job.State)
+	assert.Equal(t, 3, job.Processed)
+	assert.Equal(t, 3, job.Updated)
+	assert.Equal(t, 100.0, job.Percent)
+
+	categories := func(e *entries.Entry) []string {
+		t.Helper()
+		stored, err := repo.GetEntryByID(ctx, e.ID)
+		require.NoError(t, err)
+		return stored.AllCategories()
+	}
+	assert.Equal(t, []string{"dropper"}, categories(single))
+	assert.Equal(t, []string{"dropper", "phishing"}, categories(multi), "other categories are kept")
+	assert.Equal(t, []string{"dropper"}, categories(both), "a category already held is not repeated")
+	assert.Equal(t, []string{"phishing"}, categories(untouched))
+	assert.Equal(t, []string{"malware"}, categories(otherSource))
+
+	stored, err := repo.GetEntryByID(ctx, single.ID)
+	require.NoError(t, err)
+	assert.Equal(t, job.ID, stored.ProcessID, "rewritten entries carry the run's process ID")
+
+	stats, err := repo.GetEntryStats(ctx, repository.Filter{Categories: []string{"dropper"}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Total, "the category index follows")
+
+	job, err = svc.Start(ctx, ReclassifyRequest{Filter: repository.Filter{Host: "d.example"}, To: "scam"})
+	require.NoError(t, err)
+	svc.Wait()
+	job, err = svc.Get(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, job.Updated)
+	assert.Equal(t, []string{"scam"}, categories(untouched), "without a category filter every category is replaced")
+
+	_, err = svc.Get("missing")
+	assert.ErrorIs(t, err, ErrReclassifyNotFound)
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/internal/db"
+	"blacked/internal/utils"
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrRelatedInput  = errors.New("domain or ip is required")
+	ErrRelatedLookup = errors.New("failed to look up related entries")
+)
+
+// Relations describe how a related entry is connected to the queried name.
+const (
+	RelationHost      = "host"      // Same host
+	RelationSubdomain = "subdomain" // Host is below the queried host
+	RelationDomain    = "domain"    // Same registered domain, different branch
+	RelationIP        = "ip"        // Host is the queried or resolved IP
+)
+
+const (
+	DefaultRelatedLimit = 500
+	MaxRelatedLimit     = 5000
+
+	resolveTimeout = 3 * time.Second
+)
+
+// Resolver looks up the IP addresses of a host; *net.Resolver satisfies it.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// RelatedQuery selects the infrastructure to cross-reference.
+type RelatedQuery struct {
+	Domain  string // Host or registered domain
+	IP      string // IP literal
+	Resolve bool   // Also match entries on the IPs Domain resolves to
+	Limit   int    // Maximum entries returned; 0 means DefaultRelatedLimit
+}
+
+// RelatedEntry is an entry with its relation to the query.
+type RelatedEntry struct {
+	entries.Entry
+	Relation string `json:"relation"`
+}
+
+// RelatedResult lists every active entry sharing infrastructure with the query.
+type RelatedResult struct {
+	Host       string         `json:"host,omitempty"`
+	Domain     string         `json:"domain,omitempty"`
+	IPs        []string       `json:"ips,omitempty"`
+	Entries    []RelatedEntry `json:"entries"`
+	ByRelation map[string]int `json:"by_relation"`
+	BySource   map[string]int `json:"by_source"`
+	Truncated  bool           `json:"truncated"`
+}
+
+// RelatedService cross-references entries across sources by host, registered domain and IP.
+type RelatedService struct {
+	repo     repository.BlacklistRepository
+	resolver Resolver
+}
+
+// NewRelatedService creates a RelatedService on the read database pool.
+func NewRelatedService() (*RelatedService, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+
+	return NewRelatedServiceWithRepository(repository.NewSQLiteRepository(dbConn), net.DefaultResolver), nil
+}
+
+// NewRelatedServiceWithRepository creates a RelatedService on the given repository and resolver.
+func NewRelatedServiceWithRepository(repo repository.BlacklistRepository, resolver Resolver) *RelatedService {
+	return &RelatedService{repo: repo, resolver: resolver}
+}
+
+// Related returns the entries whose host equals, is below, or shares the
+// registered domain of q.Domain, plus entries hosted on q.IP and, with
+// q.Resolve, on the addresses q.Domain currently resolves to.
+func (s *RelatedService) Related(ctx context.Context, q RelatedQuery) (*RelatedResult, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultRelatedLimit
+	}
+	limit = min(limit, MaxRelatedLimit)
+
+	host := normalizeHost(q.Domain)
+	if ip := utils.HostIP(host); ip != "" {
+		// An IP passed as domain is an IP query.
+		host = ""
+		q.IP = ip
+	}
+	if host == "" && q.IP == "" {
+		return nil, ErrRelatedInput
+	}
+
+	res := &RelatedResult{
+		Host:       host,
+		Entries:    []RelatedEntry{},
+		ByRelation: make(map[string]int),
+		BySource:   make(map[string]int),
+	}
+	seen := make(map[string]bool)
+	add := func(list []entries.Entry, relation func(*entries.Entry) string) {
+		for i := range list {
+			if seen[list[i].ID] {
+				continue
+			}
+			if len(res.Entries) == limit {
+				res.Truncated = true
+				return
+			}
+			seen[list[i].ID] = true
+			rel := relation(&list[i])
+			res.Entries = append(res.Entries, RelatedEntry{Entry: list[i], Relation: rel})
+			res.ByRelation[rel]++
+			res.BySource[list[i].Source]++
+		}
+	}
+
+	if host != "" {
+		domain, _, err := utils.ExtractDomainAndSubDomains(host)
+		if err != nil || domain == "" {
+			domain = host
+		}
+		res.Domain = domain
+
+		under, err := s.repo.GetEntriesUnderHost(ctx, domain, limit+1)
+		if err != nil {
+			log.Err(err).Str("domain", domain).Msg("Failed to look up entries under domain")
+			return nil, ErrRelatedLookup
+		}
+		add(under, func(e *entries.Entry) string {
+			return hostRelation(host, e.Host)
+		})
+	}
+
+	if q.IP != "" {
+		if ip := utils.HostIP(q.IP); ip != "" {
+			res.IPs = append(res.IPs, ip)
+		}
+	}
+	if q.Resolve && host != "" && s.resolver != nil {
+		res.IPs = append(res.IPs, s.resolve(ctx, host)...)
+	}
+
+	if len(res.IPs) > 0 {
+		byIP, err := s.repo.GetEntriesByIPs(ctx, res.IPs, limit+1)
+		if err != nil {
+			log.Err(err).Strs("ips", res.IPs).Msg("Failed to look up entries by IP")
+			return nil, ErrRelatedLookup
+		}
+		add(byIP, func(*entries.Entry) string { return RelationIP })
+	}
+
+	return res, nil
+}
+
+// resolve returns the canonical addresses of host. Resolution failures only
+// mean no IP relations, so they are logged rather than returned.
+func (s *RelatedService) resolve(ctx context.Context, host string) []string {
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	addrs, err := s.resolver.LookupHost(ctx, host)
+	if err != nil {
+		log.Debug().Err(err).Str("host", host).Msg("Failed to resolve host for related lookup")
+		return nil
+	}
+
+	ips := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		if ip := utils.HostIP(a); ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// hostRelation classifies entryHost, already known to share the registered domain, against host.
+func hostRelation(host, entryHost string) string {
+	entryHost = strings.ToLower(entryHost)
+	switch {
+	case entryHost == host:
+		return RelationHost
+	case strings.HasSuffix(entryHost, "."+host):
+		return RelationSubdomain
+	default:
+		return RelationDomain
+	}
+}
+
+// normalizeHost accepts a bare host, an IP literal or a URL and returns its lowercase host.
+func normalizeHost(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || utils.HostIP(name) != "" {
+		return name
+	}
+	if !strings.Contains(name, "://") {
+		name = "//" + name
+	}
+	u, err := url.Parse(name)
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(u.Hostname(), ".")
+}
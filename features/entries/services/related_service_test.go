@@ -0,0 +1,72 @@
+package services
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	idb "blacked/internal/db"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubResolver map[string][]string
+
+func (r stubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return r[host], nil
+}
+
+func TestRelatedByDomainAndIP(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+
+	var batch []*entries.Entry
+	for _, e := range []struct{ link, source string }{
+		{"https://login.bad.com/a", "openphish-feed"},
+		{"https://login.bad.com/b", "urlhaus-online"},
+		{"https://cdn.login.bad.com/x.exe", "urlhaus-online"},
+		{"https://www.bad.com/", "oisd-big"},
+		{"https://notbad.com/", "oisd-big"},
+		{"http://203.0.113.7/payload", "urlhaus-online"},
+	} {
+		entry, err := entries.FromURL(e.link, e.source, "p1")
+		require.NoError(t, err)
+		batch = append(batch, entry)
+	}
+	require.NoError(t, repo.BatchSaveEntries(ctx, batch))
+
+	svc := NewRelatedServiceWithRepository(repo, stubResolver{"login.bad.com": {"203.0.113.7"}})
+
+	res, err := svc.Related(ctx, RelatedQuery{Domain: "https://LOGIN.bad.com/path"})
+	require.NoError(t, err)
+	assert.Equal(t, "login.bad.com", res.Host)
+	assert.Equal(t, "bad.com", res.Domain)
+	assert.Len(t, res.Entries, 4, "notbad.com shares no label boundary with bad.com")
+	assert.Equal(t, map[string]int{RelationHost: 2, RelationSubdomain: 1, RelationDomain: 1}, res.ByRelation)
+	assert.Equal(t, 2, res.BySource["urlhaus-online"])
+	assert.False(t, res.Truncated)
+
+	res, err = svc.Related(ctx, RelatedQuery{Domain: "login.bad.com", Resolve: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"203.0.113.7"}, res.IPs)
+	assert.Equal(t, 1, res.ByRelation[RelationIP])
+
+	res, err = svc.Related(ctx, RelatedQuery{Domain: "203.0.113.7"})
+	require.NoError(t, err)
+	require.Len(t, res.Entries, 1)
+	assert.Equal(t, RelationIP, res.Entries[0].Relation)
+
+	res, err = svc.Related(ctx, RelatedQuery{Domain: "bad.com", Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, res.Entries, 2)
+	assert.True(t, res.Truncated)
+
+	_, err = svc.Related(ctx, RelatedQuery{})
+	assert.ErrorIs(t, err, ErrRelatedInput)
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/internal/db"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrReparseScan  = errors.New("failed to scan entries for reparse")
+	ErrReparseWrite = errors.New("failed to rewrite reparsed entries")
+)
+
+// ReparseOptions controls a reparse run.
+type ReparseOptions struct {
+	Source    string // Restrict to one source; empty means all
+	BatchSize int    // Rows read (and rewritten) per batch
+	Apply     bool   // Rewrite changed rows; false is a dry run
+	MaxDiffs  int    // Maximum number of diffs kept in the report
+}
+
+// FieldDiff is a single field that differs between stored and re-parsed values.
+type FieldDiff struct {
+	Field  string `json:"field"`
+	Stored string `json:"stored"`
+	Parsed string `json:"parsed"`
+}
+
+// EntryDiff lists the differing fields of one entry.
+type EntryDiff struct {
+	ID        string      `json:"id"`
+	Source    string      `json:"source"`
+	SourceURL string      `json:"source_url"`
+	Fields    []FieldDiff `json:"fields"`
+}
+
+// ReparseReport summarises a reparse run.
+type ReparseReport struct {
+	ProcessID string         `json:"process_id,omitempty"`
+	Applied   bool           `json:"applied"`
+	Scanned   int            `json:"scanned"`
+	Changed   int            `json:"changed"`
+	Failed    int            `json:"failed"`
+	Rewritten int            `json:"rewritten"`
+	ByField   map[string]int `json:"by_field"`
+	Diffs     []EntryDiff    `json:"diffs"`
+	Duration  time.Duration  `json:"duration"`
+}
+
+// ReparseService re-runs URL normalization over stored entries.
+type ReparseService struct {
+	repo repository.BlacklistRepository
+}
+
+// NewReparseService creates a ReparseService on the write database connection.
+func NewReparseService() (*ReparseService, error) {
+	dbConn, err := db.GetWriteDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+
+	return NewReparseServiceWithRepository(repository.NewSQLiteRepository(dbConn)), nil
+}
+
+// NewReparseServiceWithRepository creates a ReparseService on the given repository.
+func NewReparseServiceWithRepository(repo repository.BlacklistRepository) *ReparseService {
+	return &ReparseService{repo: repo}
+}
+
+// Run re-parses every active entry's source_url in ID order and compares the
+// result with the stored fields. With Apply set, changed rows are rewritten
+// in batches under a dedicated process ID.
+func (s *ReparseService) Run(ctx context.Context, opts ReparseOptions) (*ReparseReport, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+
+	start := time.Now()
+	report := &ReparseReport{
+		Applied: opts.Apply,
+		ByField: make(map[string]int),
+		Diffs:   []EntryDiff{},
+	}
+	if opts.Apply {
+		report.ProcessID = uuid.New().String()
+	}
+
+	afterID := ""
+	for {
+		page, err := s.repo.GetEntriesPage(ctx, opts.Source, afterID, opts.BatchSize)
+		if err != nil {
+			log.Err(err).Str("after_id", afterID).Msg("Reparse page query failed")
+			return report, ErrReparseScan
+		}
+		if len(page) == 0 {
+			break
+		}
+		afterID = page[len(page)-1].ID
+
+		var rewrites []*entries.Entry
+		for i := range page {
+			stored := &page[i]
+			report.Scanned++
+
+			parsed := &entries.Entry{Source: stored.Source}
+			if err := parsed.SetURL(stored.SourceURL); err != nil {
+				report.Failed++
+				continue
+			}
+
+			fields := diffURLFields(stored, parsed)
+			if len(fields) == 0 {
+				continue
+			}
+
+			report.Changed++
+			for _, f := range fields {
+				report.ByField[f.Field]++
+			}
+			if opts.MaxDiffs <= 0 || len(report.Diffs) < opts.MaxDiffs {
+				report.Diffs = append(report.Diffs, EntryDiff{
+					ID:        stored.ID,
+					Source:    stored.Source,
+					SourceURL: stored.SourceURL,
+					Fields:    fields,
+				})
+			}
+
+			if opts.Apply {
+				parsed.ID = stored.ID
+				parsed.ProcessID = report.ProcessID
+				rewrites = append(rewrites, parsed)
+			}
+		}
+
+		if len(rewrites) > 0 {
+			if err := s.repo.UpdateEntryURLFields(ctx, rewrites); err != nil {
+				log.Err(err).Int("batch", len(rewrites)).Msg("Reparse batch rewrite failed")
+				return report, ErrReparseWrite
+			}
+			report.Rewritten += len(rewrites)
+		}
+
+		log.Debug().
+			Int("scanned", report.Scanned).
+			Int("changed", report.Changed).
+			Msg("Reparse batch processed")
+	}
+
+	report.Duration = time.Since(start)
+	log.Info().
+		Bool("applied", report.Applied).
+		Str("process_id", report.ProcessID).
+		Int("scanned", report.Scanned).
+		Int("changed", report.Changed).
+		Int("failed", report.Failed).
+		Int("rewritten", report.Rewritten).
+		Dur("duration", report.Duration).
+		Msg("Reparse completed")
+
+	return report, nil
+}
+
+// diffURLFields compares the URL-derived fields of two entries.
+func diffURLFields(stored, parsed *entries.Entry) []FieldDiff {
+	var diffs []FieldDiff
+	add := func(field, a, b string) {
+		if a != b {
+			diffs = append(diffs, FieldDiff{Field: field, Stored: a, Parsed: b})
+		}
+	}
+
+	add("scheme", stored.Scheme, parsed.Scheme)
+	add("domain", stored.Domain, parsed.Domain)
+	add("host", stored.Host, parsed.Host)
+	if !slices.Equal(stored.SubDomains, parsed.SubDomains) {
+		add("sub_domains", strings.Join(stored.SubDomains, ","), strings.Join(parsed.SubDomains, ","))
+	}
+	add("path", stored.Path, parsed.Path)
+	add("raw_query", stored.RawQuery, parsed.RawQuery)
+
+	return diffs
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	idb "blacked/internal/db"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReparseDryRunAndApply(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+
+	good, err := entries.FromURL("https://ok.example.com/a", "test", "p1")
+	require.NoError(t, err)
+	stale, err := entries.FromURL("https://login.bad.example.co.uk/b?x=1", "test", "p1")
+	require.NoError(t, err)
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{good, stale}))
+
+	// Simulate a row stored before the PSL-based domain split.
+	_, err = conn.Exec(`UPDATE entries SET domain = 'co.uk', sub_domains = '' WHERE id = ?`, stale.ID)
+	require.NoError(t, err)
+
+	svc := NewReparseServiceWithRepository(repo)
+
+	report, err := svc.Run(ctx, ReparseOptions{BatchSize: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Scanned)
+	assert.Equal(t, 1, report.Changed)
+	assert.Equal(t, 0, report.Rewritten)
+	assert.Equal(t, 1, report.ByField["domain"])
+
+	report, err = svc.Run(ctx, ReparseOptions{BatchSize: 1, Apply: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Rewritten)
+	assert.NotEmpty(t, report.ProcessID)
+
+	stored, err := repo.GetEntryByID(ctx, stale.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "example.co.uk", stored.Domain)
+	assert.Equal(t, report.ProcessID, stored.ProcessID)
+
+	report, err = svc.Run(ctx, ReparseOptions{})
+	require.NoError(t, err)
+	assert.Zero(t, report.Changed, "second pass finds nothing to fix")
+}
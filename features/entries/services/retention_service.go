@@ -0,0 +1,193 @@
+package services
+
+import (
+	"blacked/features/entries/repository"
+	"blacked/features/maintenance"
+	"blacked/internal/collector"
+	"blacked/internal/config"
+	"blacked/internal/db"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// retentionBatch bounds the entries removed by one purge transaction, so
+// the writer is never held for long.
+const retentionBatch = 10_000
+
+var ErrInvalidRetention = errors.New("retention age must be positive")
+
+// RetentionReport is the outcome of a retention run.
+type RetentionReport struct {
+	Before time.Time `json:"before"` // Entries soft deleted before this are removed
+	DryRun bool      `json:"dry_run"`
+	Purged int64     `json:"purged"` // Entries removed, or that would be on dry runs
+
+	// Maintenance reports the vacuum that followed the purge; nil when
+	// nothing was removed or vacuuming is off.
+	Maintenance *db.MaintenanceReport `json:"maintenance,omitempty"`
+
+	ReclaimedBytes int64         `json:"reclaimed_bytes"`
+	Duration       time.Duration `json:"duration"`
+}
+
+// ProcessEventPruner deletes the events of provider runs recorded before a
+// cutoff. Implemented by the provider process repository.
+type ProcessEventPruner interface {
+	PruneProcessEvents(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// RetentionService removes soft-deleted entries for good once they have
+// been deleted for longer than the retention, then returns the freed pages
+// to the file system. It also prunes the events of old provider runs.
+type RetentionService struct {
+	repo   repository.BlacklistRepository
+	conn   *sql.DB // Write connection the vacuum runs on; nil skips it
+	vacuum bool
+	events ProcessEventPruner // nil keeps the events of provider runs
+}
+
+// NewRetentionService creates a RetentionService on the write database
+// connection, vacuuming after purges when cfg asks for it.
+func NewRetentionService(cfg config.RetentionConfig) (*RetentionService, error) {
+	conn, err := db.GetWriteDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+
+	return NewRetentionServiceWithRepository(repository.NewSQLiteRepository(conn), conn, cfg.Vacuum), nil
+}
+
+// NewRetentionServiceWithRepository creates a RetentionService on the given
+// repository, vacuuming conn after purges when vacuum is set.
+func NewRetentionServiceWithRepository(repo repository.BlacklistRepository, conn *sql.DB, vacuum bool) *RetentionService {
+	return &RetentionService{repo: repo, conn: conn, vacuum: vacuum}
+}
+
+// SetProcessEvents sets the store the events of provider runs are pruned
+// from; nil keeps them.
+func (s *RetentionService) SetProcessEvents(p ProcessEventPruner) *RetentionService {
+	s.events = p
+	return s
+}
+
+// Purge removes the entries soft deleted more than olderThan ago, in
+// batches, and vacuums the database when any were removed. A dry run only
+// counts them. In read-only maintenance mode only dry runs are allowed;
+// others return maintenance.ErrReadOnly.
+func (s *RetentionService) Purge(ctx context.Context, olderThan time.Duration, dryRun bool) (*RetentionReport, error) {
+	if olderThan <= 0 {
+		return nil, ErrInvalidRetention
+	}
+	if !dryRun {
+		if err := maintenance.Check(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	started := time.Now()
+	report := &RetentionReport{Before: started.Add(-olderThan).UTC(), DryRun: dryRun}
+	before := report.Before.UnixNano()
+
+	if dryRun {
+		n, err := s.repo.CountDeletedBefore(ctx, before)
+		if err != nil {
+			return nil, err
+		}
+		report.Purged = n
+		report.Duration = time.Since(started)
+		return report, nil
+	}
+
+	for {
+		n, err := s.repo.PurgeDeletedBefore(ctx, before, retentionBatch)
+		report.Purged += n
+		if err != nil {
+			log.Error().Err(err).Int64("purged", report.Purged).Msg("Retention run stopped")
+			return nil, err
+		}
+		if n < retentionBatch {
+			break
+		}
+	}
+
+	if report.Purged > 0 && s.vacuum && s.conn != nil {
+		m, err := db.Maintain(ctx, s.conn, false)
+		if err != nil {
+			// The rows are gone either way; the pages wait for the next run.
+			log.Warn().Err(err).Msg("Failed to vacuum after retention purge")
+		} else {
+			report.Maintenance = m
+			report.ReclaimedBytes = m.ReclaimedBytes
+		}
+	}
+	report.Duration = time.Since(started)
+
+	if mc, _ := collector.GetMetricsCollector(); mc != nil {
+		mc.RetentionPurgedTotal.Add(float64(report.Purged))
+		mc.RetentionReclaimedBytesTotal.Add(float64(max(report.ReclaimedBytes, 0)))
+		mc.RetentionLastRunTimestamp.SetToCurrentTime()
+	}
+	log.Info().
+		Time("before", report.Before).
+		Int64("purged", report.Purged).
+		Int64("reclaimed_bytes", report.ReclaimedBytes).
+		Dur("duration", report.Duration).
+		Msg("Retention run completed")
+	return report, nil
+}
+
+// PruneProcessEvents deletes the events of provider runs recorded more
+// than olderThan ago and returns how many were deleted; in read-only
+// maintenance mode it returns maintenance.ErrReadOnly.
+func (s *RetentionService) PruneProcessEvents(ctx context.Context, olderThan time.Duration) (int64, error) {
+	if olderThan <= 0 {
+		return 0, ErrInvalidRetention
+	}
+	if err := maintenance.Check(ctx); err != nil {
+		return 0, err
+	}
+	if s.events == nil {
+		return 0, nil
+	}
+
+	deleted, err := s.events.PruneProcessEvents(ctx, time.Now().Add(-olderThan))
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		log.Debug().Int64("deleted", deleted).Dur("retention", olderThan).Msg("Pruned provider run events")
+	}
+	return deleted, nil
+}
+
+// Run purges and prunes per cfg every cfg.Interval until ctx is done,
+// skipping the ticks that find the instance in read-only maintenance mode,
+// the window backups are taken in.
+func (s *RetentionService) Run(ctx context.Context, cfg config.RetentionConfig) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	log.Info().Dur("deleted", cfg.Deleted).Dur("process_events", cfg.ProcessEvents).Dur("interval", cfg.Interval).Msg("Retention job started")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if maintenance.ReadOnly(ctx) {
+				log.Info().Msg("Skipping retention run in read-only maintenance mode")
+				continue
+			}
+			if cfg.Enabled() {
+				s.Purge(ctx, cfg.Deleted, false)
+			}
+			if cfg.ProcessEvents > 0 {
+				s.PruneProcessEvents(ctx, cfg.ProcessEvents)
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/features/maintenance"
+	idb "blacked/internal/db"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionPurge(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+
+	now := time.Now()
+	newEntry := func(link string) *entries.Entry {
+		e, err := entries.FromURL(link, "feed", "p1")
+		require.NoError(t, err)
+		return e
+	}
+	old, recent, active := newEntry("https://a.example/"), newEntry("https://b.example/"), newEntry("https://c.example/")
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{old, recent, active}))
+	for e, ago := range map[*entries.Entry]time.Duration{old: 40 * 24 * time.Hour, recent: time.Hour} {
+		_, err := conn.Exec("UPDATE entries SET deleted_at = ? WHERE id = ?", now.Add(-ago).UnixNano(), e.ID)
+		require.NoError(t, err)
+	}
+	_, err = conn.Exec("INSERT INTO entry_hits (entry_id, hits) VALUES (?, 3), (?, 1)", old.ID, active.ID)
+	require.NoError(t, err)
+
+	svc := NewRetentionServiceWithRepository(repo, conn, true)
+
+	_, err = svc.Purge(ctx, 0, false)
+	assert.ErrorIs(t, err, ErrInvalidRetention)
+
+	report, err := svc.Purge(ctx, 30*24*time.Hour, true)
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, int64(1), report.Purged)
+	stored, err := repo.GetEntryByID(ctx, old.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, stored, "a dry run removes nothing")
+
+	clearReadOnly := setReadOnly(t)
+	_, err = svc.Purge(ctx, 30*24*time.Hour, false)
+	assert.ErrorIs(t, err, maintenance.ErrReadOnly)
+	report, err = svc.Purge(ctx, 30*24*time.Hour, true)
+	require.NoError(t, err, "dry runs are allowed in read-only mode")
+	assert.Equal(t, int64(1), report.Purged)
+	clearReadOnly()
+
+	report, err = svc.Purge(ctx, 30*24*time.Hour, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Purged)
+	assert.NotNil(t, report.Maintenance, "a purge is followed by a vacuum")
+
+	exists := func(e *entries.Entry) bool {
+		t.Helper()
+		stored, err := repo.GetEntryByID(ctx, e.ID)
+		require.NoError(t, err)
+		return stored != nil
+	}
+	assert.False(t, exists(old), "entries deleted before the retention are removed")
+	assert.True(t, exists(recent), "recent soft deletes are kept")
+	assert.True(t, exists(active))
+
+	var hits int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM entry_hits").Scan(&hits))
+	assert.Equal(t, 1, hits, "hit counters of removed entries go with them")
+
+	var changes int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM entry_changes WHERE entry_id = ?", old.ID).Scan(&changes))
+	assert.Equal(t, 1, changes, "the removal is in the change feed")
+
+	report, err = svc.Purge(ctx, 30*24*time.Hour, false)
+	require.NoError(t, err)
+	assert.Zero(t, report.Purged)
+	assert.Nil(t, report.Maintenance, "nothing removed, nothing to vacuum")
+}
+
+// eventPruner records the cutoff it was asked to prune before.
+type eventPruner struct{ cutoff time.Time }
+
+func (p *eventPruner) PruneProcessEvents(_ context.Context, cutoff time.Time) (int64, error) {
+	p.cutoff = cutoff
+	return 3, nil
+}
+
+func TestRetentionPruneProcessEvents(t *testing.T) {
+	ctx := context.Background()
+	svc := NewRetentionServiceWithRepository(nil, nil, false)
+
+	deleted, err := svc.PruneProcessEvents(ctx, time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, deleted, "without a store nothing is pruned")
+
+	pruner := &eventPruner{}
+	svc.SetProcessEvents(pruner)
+	_, err = svc.PruneProcessEvents(ctx, 0)
+	assert.ErrorIs(t, err, ErrInvalidRetention)
+
+	clearReadOnly := setReadOnly(t)
+	_, err = svc.PruneProcessEvents(ctx, 30*24*time.Hour)
+	assert.ErrorIs(t, err, maintenance.ErrReadOnly)
+	assert.True(t, pruner.cutoff.IsZero(), "nothing is pruned in read-only mode")
+	clearReadOnly()
+
+	deleted, err = svc.PruneProcessEvents(ctx, 30*24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	assert.WithinDuration(t, time.Now().Add(-30*24*time.Hour), pruner.cutoff, time.Minute)
+}
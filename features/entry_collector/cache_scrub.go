@@ -0,0 +1,253 @@
+package entry_collector
+
+import (
+	"blacked/features/cache"
+	"blacked/features/cache/cache_errors"
+	"blacked/features/entries/repository"
+	"blacked/internal/collector"
+	"blacked/internal/config"
+	"blacked/internal/db"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// scrubLookupChunk bounds the source URLs looked up per query, under SQLite's variable limit.
+const scrubLookupChunk = 500
+
+var ErrCacheScrubSkipped = errors.New("cache scrub skipped while a cache sync is pending")
+
+// ScrubReport is the outcome of one cache scrub round.
+type ScrubReport struct {
+	At         time.Time     `json:"at"`
+	Duration   time.Duration `json:"duration"`
+	Checked    int           `json:"checked"`    // Keys compared against the repository
+	Stale      int           `json:"stale"`      // Cached keys no active entry lists anymore
+	Mismatched int           `json:"mismatched"` // Cached ID lists that differ from the repository
+	Missing    int           `json:"missing"`    // Active source URLs absent from a fully built cache
+	Repaired   int           `json:"repaired"`
+}
+
+// Drift returns the number of keys found out of sync.
+func (r ScrubReport) Drift() int {
+	return r.Stale + r.Mismatched + r.Missing
+}
+
+// StartCacheScrubber checks sample cache keys against the repository every
+// interval until ctx is done, repairing the ones that drifted. Rounds are
+// skipped while a cache sync runs, since that rewrites the keys anyway.
+func (c *PondCollector) StartCacheScrubber(ctx context.Context, interval time.Duration, sample int) {
+	if interval <= 0 || sample <= 0 {
+		log.Debug().Msg("Cache scrubber disabled")
+		return
+	}
+
+	log.Info().Dur("interval", interval).Int("sample", sample).Msg("Cache scrubber started")
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := c.ScrubCache(ctx, sample); err != nil && !errors.Is(err, ErrCacheScrubSkipped) {
+					log.Warn().Err(err).Msg("Cache scrub failed")
+				}
+			}
+		}
+	}()
+}
+
+// LastCacheScrub returns the report of the last completed scrub round, or nil.
+func (c *PondCollector) LastCacheScrub() *ScrubReport {
+	c.cacheSyncMutex.Lock()
+	defer c.cacheSyncMutex.Unlock()
+	return c.lastScrub
+}
+
+// ScrubCache runs one scrub round over up to sample cache keys and, when the
+// cache is built eagerly, up to sample source URLs from the repository.
+func (c *PondCollector) ScrubCache(ctx context.Context, sample int) (ScrubReport, error) {
+	report := ScrubReport{At: time.Now().UTC()}
+	if !c.cacheSyncIdle() {
+		return report, ErrCacheScrubSkipped
+	}
+
+	cacheProvider, err := cache.GetCacheProvider()
+	if err != nil {
+		return report, err
+	}
+	_db, err := db.GetDB()
+	if err != nil {
+		return report, err
+	}
+	repo := repository.NewSQLiteRepository(_db)
+
+	keys, err := sampleCacheKeys(ctx, cacheProvider, sample)
+	if err != nil {
+		return report, err
+	}
+
+	// With a TTL the cache only holds looked-up keys, so absent keys are expected.
+	lazy := config.GetConfig().Cache.TTL != nil
+	if !lazy {
+		urls, err := repo.SampleSourceURLs(ctx, sample)
+		if err != nil {
+			return report, err
+		}
+		for _, u := range urls {
+			if !slices.Contains(keys, u) {
+				keys = append(keys, u)
+			}
+		}
+	}
+
+	for chunk := range slices.Chunk(keys, scrubLookupChunk) {
+		want, err := repo.GetIDsBySourceURLs(ctx, chunk)
+		if err != nil {
+			return report, err
+		}
+		if !c.cacheSyncIdle() {
+			// A sync started meanwhile; its writes are newer than ours.
+			return report, ErrCacheScrubSkipped
+		}
+		// Read-through lookups cache queried URLs too; resolve those like the lookup did.
+		exact := func(key string) []string {
+			var ids []string
+			for _, hit := range repo.QueryExactURLMatch(ctx, key) {
+				ids = append(ids, hit.ID)
+			}
+			return ids
+		}
+		if err := scrubKeys(cacheProvider, chunk, want, exact, lazy, &report); err != nil {
+			return report, err
+		}
+	}
+
+	if report.Repaired > 0 {
+		if err := cacheProvider.Commit(); err != nil {
+			return report, err
+		}
+	}
+	report.Duration = time.Since(report.At)
+
+	c.cacheSyncMutex.Lock()
+	c.lastScrub = &report
+	c.cacheSyncMutex.Unlock()
+
+	if mc, err := collector.GetMetricsCollector(); err == nil {
+		mc.ObserveCacheScrub(report.Checked, report.Stale, report.Mismatched, report.Missing)
+	}
+
+	event := log.Debug()
+	if report.Drift() > 0 {
+		event = log.Warn()
+	}
+	event.
+		Int("checked", report.Checked).
+		Int("stale", report.Stale).
+		Int("mismatched", report.Mismatched).
+		Int("missing", report.Missing).
+		Int("repaired", report.Repaired).
+		Dur("duration", report.Duration).
+		Msg("Cache scrub completed")
+
+	return report, nil
+}
+
+// scrubKeys compares the cached IDs of keys with want, falling back to exact
+// for cached keys that are not a source URL, and repairs drift: a lazy cache
+// drops the key so the next lookup reloads it, an eager cache is rewritten
+// from the repository.
+func scrubKeys(cacheProvider cache.EntryCache, keys []string, want map[string][]string, exact func(string) []string, lazy bool, report *ScrubReport) error {
+	for _, key := range keys {
+		report.Checked++
+
+		cached, err := cacheProvider.Get(key)
+		found := err == nil
+		if err != nil && !errors.Is(err, cache_errors.ErrKeyNotFound) {
+			return err
+		}
+		ids, ok := want[key]
+		if !ok && len(cached) > 0 {
+			ids = exact(key)
+		}
+
+		switch {
+		case len(cached) == 0 && len(ids) == 0:
+			// Absent on both sides, or a cached negative lookup that still holds.
+			continue
+		case !found:
+			if lazy {
+				continue
+			}
+			report.Missing++
+			if err := cacheProvider.SetIds(key, ids); err != nil {
+				return err
+			}
+			if err := cache.AddToBloomFilter(key); err != nil {
+				log.Debug().Err(err).Msg("Bloom filter not available during cache scrub")
+			}
+		case len(ids) == 0:
+			report.Stale++
+			if err := cacheProvider.Delete(key); err != nil {
+				return err
+			}
+		case sameIDs(cached, ids):
+			continue
+		default:
+			report.Mismatched++
+			if lazy {
+				err = cacheProvider.Delete(key)
+			} else {
+				err = cacheProvider.Set(key, strings.Join(ids, ","))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		report.Repaired++
+	}
+	return nil
+}
+
+// sampleCacheKeys picks up to n keys uniformly from the cache (reservoir sampling).
+func sampleCacheKeys(ctx context.Context, cacheProvider cache.EntryCache, n int) ([]string, error) {
+	keys := make([]string, 0, n)
+	seen := 0
+	err := cacheProvider.Iterate(ctx, func(key string) error {
+		seen++
+		if len(keys) < n {
+			keys = append(keys, key)
+		} else if j := rand.IntN(seen); j < n {
+			keys[j] = key
+		}
+		return ctx.Err()
+	})
+	return keys, err
+}
+
+// sameIDs reports whether a and b hold the same IDs in any order.
+func sameIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// cacheSyncIdle reports whether no cache sync is running or queued.
+func (c *PondCollector) cacheSyncIdle() bool {
+	c.cacheSyncMutex.Lock()
+	defer c.cacheSyncMutex.Unlock()
+	return c.cacheSyncState == CacheSyncStateIdle && !c.cacheSyncDisabled
+}
@@ -0,0 +1,110 @@
+package entry_collector
+
+import (
+	"blacked/features/cache/cache_errors"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScrubKeysRepairsDrift(t *testing.T) {
+	want := map[string][]string{
+		"https://ok.example/":      {"1", "2"},
+		"https://changed.example/": {"3"},
+		"https://missing.example/": {"4"},
+	}
+	exact := func(key string) []string {
+		if key == "queried.example/path" {
+			return []string{"1"}
+		}
+		return nil
+	}
+	keys := []string{
+		"https://ok.example/",
+		"https://changed.example/",
+		"https://missing.example/",
+		"https://gone.example/",
+		"queried.example/path",
+		"https://negative.example/",
+	}
+	seed := func() *mapCache {
+		return &mapCache{m: map[string]string{
+			"https://ok.example/":       "2,1",
+			"https://changed.example/":  "3,9",
+			"https://gone.example/":     "7",
+			"queried.example/path":      "1",
+			"https://negative.example/": "",
+		}}
+	}
+
+	eager := seed()
+	var report ScrubReport
+	require.NoError(t, scrubKeys(eager, keys, want, exact, false, &report))
+	assert.Equal(t, ScrubReport{Checked: 6, Stale: 1, Mismatched: 1, Missing: 1, Repaired: 3}, report)
+	assert.Equal(t, map[string]string{
+		"https://ok.example/":       "2,1",
+		"https://changed.example/":  "3",
+		"https://missing.example/":  "4",
+		"queried.example/path":      "1",
+		"https://negative.example/": "",
+	}, eager.m)
+
+	lazy := seed()
+	report = ScrubReport{}
+	require.NoError(t, scrubKeys(lazy, keys, want, exact, true, &report))
+	assert.Equal(t, ScrubReport{Checked: 6, Stale: 1, Mismatched: 1, Repaired: 2}, report, "a lazy cache does not miss keys")
+	assert.NotContains(t, lazy.m, "https://changed.example/", "a lazy cache drops mismatched keys for the next lookup")
+	assert.NotContains(t, lazy.m, "https://missing.example/")
+}
+
+func TestSameIDs(t *testing.T) {
+	assert.True(t, sameIDs([]string{"a", "b"}, []string{"b", "a"}))
+	assert.False(t, sameIDs([]string{"a", "b"}, []string{"a"}))
+	assert.False(t, sameIDs([]string{"a", "a"}, []string{"a", "b"}))
+}
+
+// mapCache is an EntryCache on a plain map.
+type mapCache struct {
+	m map[string]string
+}
+
+func (c *mapCache) Initialize(ctx context.Context) error { return nil }
+func (c *mapCache) Close() error                         { return nil }
+func (c *mapCache) Commit() error                        { return nil }
+
+func (c *mapCache) Get(key string) ([]string, error) {
+	v, ok := c.m[key]
+	if !ok {
+		return nil, cache_errors.ErrKeyNotFound
+	}
+	if v == "" {
+		return []string{}, nil
+	}
+	return strings.Split(v, ","), nil
+}
+
+func (c *mapCache) Set(key string, ids string) error {
+	c.m[key] = ids
+	return nil
+}
+
+func (c *mapCache) SetIds(key string, ids []string) error {
+	return c.Set(key, strings.Join(ids, ","))
+}
+
+func (c *mapCache) Delete(key string) error {
+	delete(c.m, key)
+	return nil
+}
+
+func (c *mapCache) Iterate(ctx context.Context, fn func(key string) error) error {
+	for k := range c.m {
+		if err := fn(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
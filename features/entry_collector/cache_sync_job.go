@@ -0,0 +1,184 @@
+package entry_collector
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidCacheSyncScope = errors.New("invalid cache sync scope")
+	ErrCacheSyncBusy         = errors.New("a cache sync is already running and another is queued")
+	ErrCacheSyncJobNotFound  = errors.New("cache sync job not found")
+	ErrCacheSyncDisabled     = errors.New("cache sync is disabled in this run mode")
+)
+
+// CacheSyncMode selects which keys a cache sync rebuilds.
+type CacheSyncMode string
+
+const (
+	CacheSyncFull   CacheSyncMode = "full"   // Rebuild cache and bloom from every active entry
+	CacheSyncDelta  CacheSyncMode = "delta"  // Resync URLs changed since a process first wrote
+	CacheSyncSource CacheSyncMode = "source" // Resync every URL of one source
+)
+
+// CacheSyncScope describes one cache sync request.
+type CacheSyncScope struct {
+	Mode      CacheSyncMode `json:"mode"`
+	ProcessID string        `json:"process_id,omitempty"` // Delta only
+	Source    string        `json:"source,omitempty"`     // Source only
+	Since     int64         `json:"since,omitempty"`      // Delta lower bound (Unix nanos), resolved from ProcessID
+}
+
+// Validate checks that the fields required by the mode are present.
+func (s CacheSyncScope) Validate() error {
+	switch s.Mode {
+	case CacheSyncFull:
+		return nil
+	case CacheSyncDelta:
+		if s.ProcessID == "" {
+			return errors.Join(ErrInvalidCacheSyncScope, errors.New("delta sync requires a process id"))
+		}
+		return nil
+	case CacheSyncSource:
+		if s.Source == "" {
+			return errors.Join(ErrInvalidCacheSyncScope, errors.New("source sync requires a source"))
+		}
+		return nil
+	default:
+		return errors.Join(ErrInvalidCacheSyncScope, errors.New("mode must be one of full, delta, source"))
+	}
+}
+
+// CacheSyncJobState is the lifecycle state of a cache sync job.
+type CacheSyncJobState string
+
+const (
+	CacheSyncJobQueued    CacheSyncJobState = "queued"
+	CacheSyncJobRunning   CacheSyncJobState = "running"
+	CacheSyncJobCompleted CacheSyncJobState = "completed"
+	CacheSyncJobFailed    CacheSyncJobState = "failed"
+)
+
+// CacheSyncJob is a point-in-time view of a scheduled cache sync.
+type CacheSyncJob struct {
+	ID         string            `json:"id"`
+	Scope      CacheSyncScope    `json:"scope"`
+	State      CacheSyncJobState `json:"state"`
+	QueuedAt   time.Time         `json:"queued_at"`
+	StartedAt  *time.Time        `json:"started_at,omitempty"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+	Synced     int               `json:"synced"`  // Keys written to cache/bloom
+	Removed    int               `json:"removed"` // Keys dropped because no source lists them anymore
+	Progress   SyncProgress      `json:"progress"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// maxCacheSyncJobs bounds the job history kept for polling.
+const maxCacheSyncJobs = 64
+
+// cacheSyncJobs keeps the most recent jobs so their progress can be polled.
+type cacheSyncJobs struct {
+	mu    sync.Mutex
+	byID  map[string]*CacheSyncJob
+	order []string
+}
+
+func newCacheSyncJobs() *cacheSyncJobs {
+	return &cacheSyncJobs{byID: make(map[string]*CacheSyncJob)}
+}
+
+// add registers a queued job for scope and returns its id, evicting the
+// oldest finished jobs beyond maxCacheSyncJobs.
+func (j *cacheSyncJobs) add(scope CacheSyncScope) string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	id := uuid.New().String()
+	j.byID[id] = &CacheSyncJob{
+		ID:       id,
+		Scope:    scope,
+		State:    CacheSyncJobQueued,
+		QueuedAt: time.Now().UTC(),
+	}
+	j.order = append(j.order, id)
+
+	for i := 0; len(j.order) > maxCacheSyncJobs && i < len(j.order); {
+		old := j.byID[j.order[i]]
+		if old.State == CacheSyncJobQueued || old.State == CacheSyncJobRunning {
+			i++
+			continue
+		}
+		delete(j.byID, old.ID)
+		j.order = append(j.order[:i], j.order[i+1:]...)
+	}
+
+	return id
+}
+
+// get returns a copy of the job with id.
+func (j *cacheSyncJobs) get(id string) (CacheSyncJob, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job, ok := j.byID[id]
+	if !ok {
+		return CacheSyncJob{}, false
+	}
+	return *job, true
+}
+
+// snapshot returns copies of the running job, the queued job and the most
+// recently finished job; any of them may be nil.
+func (j *cacheSyncJobs) snapshot() (running, queued, last *CacheSyncJob) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for i := len(j.order) - 1; i >= 0; i-- {
+		job := *j.byID[j.order[i]]
+		switch job.State {
+		case CacheSyncJobRunning:
+			if running == nil {
+				running = &job
+			}
+		case CacheSyncJobQueued:
+			if queued == nil {
+				queued = &job
+			}
+		default:
+			if last == nil {
+				last = &job
+			}
+		}
+	}
+	return running, queued, last
+}
+
+// lastCompleted returns when the most recent successful job still tracked
+// finished, or nil when there is none.
+func (j *cacheSyncJobs) lastCompleted() *time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var last *time.Time
+	for _, id := range j.order {
+		job := j.byID[id]
+		if job.State == CacheSyncJobCompleted && job.FinishedAt != nil && (last == nil || job.FinishedAt.After(*last)) {
+			finished := *job.FinishedAt
+			last = &finished
+		}
+	}
+	return last
+}
+
+// update applies fn to the job with id, if it is still tracked.
+func (j *cacheSyncJobs) update(id string, fn func(*CacheSyncJob)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if job, ok := j.byID[id]; ok {
+		fn(job)
+	}
+}
@@ -0,0 +1,178 @@
+package entry_collector
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	idb "blacked/internal/db"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheSyncScopeValidate(t *testing.T) {
+	assert.NoError(t, CacheSyncScope{Mode: CacheSyncFull}.Validate())
+	assert.NoError(t, CacheSyncScope{Mode: CacheSyncDelta, ProcessID: "p1"}.Validate())
+	assert.NoError(t, CacheSyncScope{Mode: CacheSyncSource, Source: "oisd-big"}.Validate())
+
+	assert.ErrorIs(t, CacheSyncScope{Mode: CacheSyncDelta}.Validate(), ErrInvalidCacheSyncScope)
+	assert.ErrorIs(t, CacheSyncScope{Mode: CacheSyncSource}.Validate(), ErrInvalidCacheSyncScope)
+	assert.ErrorIs(t, CacheSyncScope{Mode: "partial"}.Validate(), ErrInvalidCacheSyncScope)
+}
+
+func TestCacheSyncJobsKeepsUnfinished(t *testing.T) {
+	jobs := newCacheSyncJobs()
+
+	running := jobs.add(CacheSyncScope{Mode: CacheSyncFull})
+	jobs.update(running, func(j *CacheSyncJob) { j.State = CacheSyncJobRunning })
+
+	var finished []string
+	for range maxCacheSyncJobs {
+		id := jobs.add(CacheSyncScope{Mode: CacheSyncFull})
+		jobs.update(id, func(j *CacheSyncJob) { j.State = CacheSyncJobCompleted })
+		finished = append(finished, id)
+	}
+	jobs.add(CacheSyncScope{Mode: CacheSyncFull})
+
+	_, ok := jobs.get(running)
+	assert.True(t, ok, "running jobs are never evicted")
+	for _, id := range finished[:2] {
+		_, ok = jobs.get(id)
+		assert.False(t, ok, "oldest finished jobs are evicted")
+	}
+	_, ok = jobs.get(finished[2])
+	assert.True(t, ok)
+	assert.Len(t, jobs.byID, maxCacheSyncJobs)
+}
+
+func TestCacheSyncJobsLastCompleted(t *testing.T) {
+	jobs := newCacheSyncJobs()
+	assert.Nil(t, jobs.lastCompleted())
+
+	finish := func(state CacheSyncJobState, at time.Time) {
+		id := jobs.add(CacheSyncScope{Mode: CacheSyncFull})
+		jobs.update(id, func(j *CacheSyncJob) {
+			j.State = state
+			j.FinishedAt = &at
+		})
+	}
+	completed := time.Now().Add(-time.Hour).UTC()
+	finish(CacheSyncJobCompleted, completed)
+	finish(CacheSyncJobFailed, time.Now().UTC())
+
+	last := jobs.lastCompleted()
+	require.NotNil(t, last)
+	assert.True(t, completed.Equal(*last), "failed syncs are not completions")
+}
+
+func TestStreamChangedSourceURLs(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+
+	save := func(processID string, links ...[2]string) {
+		var batch []*entries.Entry
+		for _, l := range links {
+			e, err := entries.FromURL(l[0], l[1], processID)
+			require.NoError(t, err)
+			batch = append(batch, e)
+		}
+		require.NoError(t, repo.BatchSaveEntries(ctx, batch))
+	}
+
+	save("p1",
+		[2]string{"https://a.example/", "oisd-big"},
+		[2]string{"https://b.example/", "oisd-big"},
+		[2]string{"https://b.example/", "urlhaus-online"},
+	)
+	time.Sleep(time.Millisecond)
+	save("p2", [2]string{"https://c.example/", "urlhaus-online"})
+	require.NoError(t, repo.RemoveOlderInsertions(ctx, "urlhaus-online", "p2"))
+
+	collect := func(source string, since int64) map[string]int {
+		ch := make(chan entries.EntryStream)
+		errCh := make(chan error, 1)
+		go func() { errCh <- repo.StreamChangedSourceURLs(ctx, source, since, ch) }()
+		got := make(map[string]int)
+		for e := range ch {
+			got[e.SourceUrl] = len(e.IDs)
+		}
+		require.NoError(t, <-errCh)
+		return got
+	}
+
+	since, err := repo.ProcessFirstWrite(ctx, "p2")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{
+		"https://b.example/": 1, // urlhaus row removed, oisd row still lists it
+		"https://c.example/": 1,
+	}, collect("", since))
+
+	assert.Equal(t, map[string]int{
+		"https://a.example/": 1,
+		"https://b.example/": 1,
+	}, collect("oisd-big", 0))
+
+	_, err = repo.ProcessFirstWrite(ctx, "missing")
+	assert.ErrorIs(t, err, repository.ErrProcessNotFound)
+}
+
+func TestComputeSyncProgress(t *testing.T) {
+	p := computeSyncProgress(1000, 250, 5*time.Second)
+	assert.Equal(t, 25.0, p.Percent)
+	assert.Equal(t, 50.0, p.KeysPerSec)
+	assert.Equal(t, 15.0, p.ETASeconds)
+
+	p = computeSyncProgress(100, 120, time.Second)
+	assert.Equal(t, 100.0, p.Percent, "percent is capped when more keys than counted arrive")
+	assert.Zero(t, p.ETASeconds)
+
+	p = computeSyncProgress(0, 10, time.Second)
+	assert.Zero(t, p.Percent, "unknown total leaves percent unset")
+	assert.Equal(t, 10.0, p.KeysPerSec)
+}
+
+func TestSyncTrackerUpdatesJob(t *testing.T) {
+	jobs := newCacheSyncJobs()
+	id := jobs.add(CacheSyncScope{Mode: CacheSyncFull})
+
+	tracker := newSyncTracker(jobs, id)
+	tracker.expect(2 * progressEvery)
+
+	tracker.report(progressEvery-1, 0)
+	job, _ := jobs.get(id)
+	assert.Zero(t, job.Progress.Processed, "updates are batched")
+
+	tracker.report(progressEvery, 0)
+	job, _ = jobs.get(id)
+	assert.Equal(t, progressEvery, job.Synced)
+	assert.Equal(t, 50.0, job.Progress.Percent)
+
+	tracker.done(2*progressEvery-5, 5)
+	tracker.close()
+	job, _ = jobs.get(id)
+	assert.Equal(t, 5, job.Removed)
+	assert.Equal(t, 100.0, job.Progress.Percent)
+}
+
+func TestDisabledCacheSyncIsNoop(t *testing.T) {
+	c := &PondCollector{cacheSyncJobs: newCacheSyncJobs()}
+	c.DisableCacheSync()
+
+	assert.True(t, c.ScheduleCacheSync(true), "writers see a disabled sync as done")
+	assert.Equal(t, CacheSyncStateIdle, c.cacheSyncState)
+
+	_, err := c.RequestCacheSync(context.Background(), CacheSyncScope{Mode: CacheSyncFull})
+	assert.ErrorIs(t, err, ErrCacheSyncDisabled)
+
+	running, queued, last := c.cacheSyncJobs.snapshot()
+	assert.Nil(t, running)
+	assert.Nil(t, queued)
+	assert.Nil(t, last)
+}
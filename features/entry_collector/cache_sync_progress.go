@@ -0,0 +1,124 @@
+package entry_collector
+
+import (
+	"blacked/internal/collector"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// progressEvery is how many keys a sync processes between progress updates.
+	progressEvery = 10000
+	// progressLogInterval throttles the progress log line of long syncs.
+	progressLogInterval = 5 * time.Second
+)
+
+// SyncProgress is a snapshot of how far a cache sync has come.
+type SyncProgress struct {
+	Expected   int     `json:"expected"`              // Keys the count query expects to sync
+	Processed  int     `json:"processed"`             // Keys synced or removed so far
+	Percent    float64 `json:"percent"`               // Processed / Expected, capped at 100
+	KeysPerSec float64 `json:"keys_per_sec"`          // Average rate since the sync started
+	ETASeconds float64 `json:"eta_seconds,omitempty"` // Remaining keys at the average rate
+	RateLimit  float64 `json:"rate_limit,omitempty"`  // Keys/sec the throttle holds the sync to; 0 when unthrottled
+}
+
+// computeSyncProgress derives percent, rate and ETA from the key counts.
+// An unknown expected count (0) leaves percent and ETA at zero.
+func computeSyncProgress(expected, processed int, elapsed time.Duration) SyncProgress {
+	p := SyncProgress{Expected: expected, Processed: processed}
+
+	if secs := elapsed.Seconds(); secs > 0 {
+		p.KeysPerSec = float64(processed) / secs
+	}
+	if expected > 0 {
+		p.Percent = min(100, float64(processed)/float64(expected)*100)
+		if remaining := expected - processed; remaining > 0 && p.KeysPerSec > 0 {
+			p.ETASeconds = float64(remaining) / p.KeysPerSec
+		}
+	}
+	return p
+}
+
+// syncTracker turns the running key counts of one sync into job progress,
+// a throttled log line and the cache sync gauges, and paces the sync with
+// its throttle.
+type syncTracker struct {
+	jobs     *cacheSyncJobs
+	id       string
+	started  time.Time
+	expected int
+	last     int // Processed count at the last update
+	lastLog  time.Time
+	metrics  *collector.MetricsCollector // nil when metrics are not initialized
+	throttle *syncThrottle               // nil when the sync is not throttled
+}
+
+func newSyncTracker(jobs *cacheSyncJobs, id string) *syncTracker {
+	metrics, _ := collector.GetMetricsCollector()
+	t := &syncTracker{jobs: jobs, id: id, started: time.Now(), lastLog: time.Now(), metrics: metrics}
+	if t.metrics != nil {
+		t.metrics.SetCacheSyncRunning(true)
+		t.metrics.SetCacheSyncProgress(0, 0, 0, 0, 0)
+	}
+	return t
+}
+
+// expect records the number of keys the sync is about to process.
+func (t *syncTracker) expect(n int) {
+	t.expected = n
+	t.update(0, 0)
+}
+
+// report is called for every key; it paces the sync and only updates
+// every progressEvery keys.
+func (t *syncTracker) report(synced, removed int) {
+	t.throttle.pace(synced + removed)
+	if synced+removed-t.last < progressEvery {
+		return
+	}
+	t.update(synced, removed)
+}
+
+// done publishes the final counts.
+func (t *syncTracker) done(synced, removed int) {
+	t.update(synced, removed)
+}
+
+// close clears the running gauge once the sync returned, successfully or not.
+func (t *syncTracker) close() {
+	if t.metrics != nil {
+		t.metrics.SetCacheSyncRunning(false)
+	}
+}
+
+func (t *syncTracker) update(synced, removed int) {
+	t.last = synced + removed
+	p := computeSyncProgress(t.expected, t.last, time.Since(t.started))
+	p.RateLimit = t.throttle.rateLimit()
+
+	t.jobs.update(t.id, func(j *CacheSyncJob) {
+		j.Synced = synced
+		j.Removed = removed
+		j.Progress = p
+	})
+
+	if t.metrics != nil {
+		t.metrics.SetCacheSyncProgress(p.Expected, p.Processed, p.Percent, p.KeysPerSec, p.ETASeconds)
+		t.metrics.SetCacheSyncRateLimit(p.RateLimit)
+	}
+
+	if time.Since(t.lastLog) >= progressLogInterval {
+		t.lastLog = time.Now()
+		log.Info().
+			Str("job_id", t.id).
+			Int("processed", p.Processed).
+			Int("expected", p.Expected).
+			Float64("percent", p.Percent).
+			Float64("keys_per_sec", p.KeysPerSec).
+			Float64("eta_seconds", p.ETASeconds).
+			Float64("rate_limit", p.RateLimit).
+			Msg("Cache sync progress")
+	}
+}
@@ -7,3 +7,14 @@ const (
 	CacheSyncStateRunning
 	CacheSyncStateQueued
 )
+
+func (s CacheSyncState) String() string {
+	switch s {
+	case CacheSyncStateRunning:
+		return "running"
+	case CacheSyncStateQueued:
+		return "queued"
+	default:
+		return "idle"
+	}
+}
@@ -0,0 +1,143 @@
+package entry_collector
+
+import (
+	"blacked/internal/collector"
+	"blacked/internal/config"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// throttleBatch is how many keys a throttled sync writes between pauses.
+	throttleBatch = 256
+	// throttleAdjustEvery is how often adaptive pacing compares the query p99
+	// with its target; the p99 is taken over the same window.
+	throttleAdjustEvery = time.Second
+	// throttleMaxCredit bounds how far a slow stretch lets the sync run ahead
+	// of its rate afterwards.
+	throttleMaxCredit = time.Second
+)
+
+// syncThrottle paces the keys of a cache sync: under a fixed ceiling, and
+// with a target p99 also adaptively, halving the rate while queries are
+// slower than the target and growing it by a quarter while they are faster
+// than three quarters of it.
+type syncThrottle struct {
+	max    float64       // Ceiling in keys/sec; 0 is unlimited
+	min    float64       // Floor of the adaptive rate
+	target time.Duration // Query p99 to keep; 0 disables adaptive pacing
+
+	limit float64 // Rate in effect; 0 is unlimited
+	peak  float64 // Unthrottled rate, observed before the first back-off
+
+	paced     int       // Keys at the last pause check
+	since     time.Time // Start of the pacing interval
+	sinceKeys int       // Keys at its start
+	adjusted  time.Time // Last adaptive adjustment
+	adjKeys   int       // Keys at the last adjustment
+
+	latency func(window time.Duration) (time.Duration, bool)
+	now     func() time.Time
+	sleep   func(time.Duration)
+}
+
+// newSyncThrottle returns the throttle of cfg, or nil when it sets neither a
+// ceiling nor a target.
+func newSyncThrottle(cfg config.CacheSettings) *syncThrottle {
+	if cfg.SyncMaxKeysPerSec <= 0 && cfg.SyncTargetP99 <= 0 {
+		return nil
+	}
+	t := &syncThrottle{
+		max:    float64(max(cfg.SyncMaxKeysPerSec, 0)),
+		min:    float64(max(cfg.SyncMinKeysPerSec, 1)),
+		target: cfg.SyncTargetP99,
+		latency: func(window time.Duration) (time.Duration, bool) {
+			return collector.QueryLatencyQuantile(0.99, window)
+		},
+		now:   time.Now,
+		sleep: time.Sleep,
+	}
+	t.limit = t.max
+	t.since = t.now()
+	t.adjusted = t.since
+	return t
+}
+
+// rateLimit returns the rate in effect, 0 when unlimited.
+func (t *syncThrottle) rateLimit() float64 {
+	if t == nil {
+		return 0
+	}
+	return t.limit
+}
+
+// pace is called with the running key count and pauses the sync whenever it
+// is ahead of the rate in effect.
+func (t *syncThrottle) pace(processed int) {
+	if t == nil || processed-t.paced < throttleBatch {
+		return
+	}
+	t.paced = processed
+	now := t.now()
+
+	if t.target > 0 && now.Sub(t.adjusted) >= throttleAdjustEvery {
+		t.adjust(now, processed)
+	}
+	if t.limit <= 0 {
+		return
+	}
+
+	due := t.since.Add(time.Duration(float64(processed-t.sinceKeys) / t.limit * float64(time.Second)))
+	if wait := due.Sub(now); wait > 0 {
+		t.sleep(wait)
+	} else if wait < -throttleMaxCredit {
+		t.restart(now, processed)
+	}
+}
+
+// adjust moves the rate towards the query p99 target.
+func (t *syncThrottle) adjust(now time.Time, processed int) {
+	observed := float64(processed-t.adjKeys) / now.Sub(t.adjusted).Seconds()
+	t.adjusted, t.adjKeys = now, processed
+	if t.limit <= 0 {
+		t.peak = observed
+	}
+
+	p99, ok := t.latency(throttleAdjustEvery)
+	next := t.limit
+	switch {
+	case ok && p99 > t.target:
+		base := t.limit
+		if base <= 0 {
+			base = observed
+		}
+		next = max(t.min, base/2)
+		if t.max > 0 {
+			next = min(next, t.max)
+		}
+	case t.limit > 0 && t.limit != t.max && (!ok || p99 < t.target*3/4):
+		next = t.limit * 1.25
+		if t.max > 0 {
+			next = min(next, t.max)
+		} else if next >= t.peak {
+			next = 0 // Back to the unthrottled rate
+		}
+	}
+	if next == t.limit {
+		return
+	}
+
+	log.Debug().
+		Dur("query_p99", p99).
+		Dur("target_p99", t.target).
+		Float64("keys_per_sec", observed).
+		Float64("rate_limit", next).
+		Msg("Cache sync rate adjusted")
+	t.limit = next
+	t.restart(now, processed)
+}
+
+func (t *syncThrottle) restart(now time.Time, processed int) {
+	t.since, t.sinceKeys = now, processed
+}
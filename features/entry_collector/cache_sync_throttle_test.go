@@ -0,0 +1,66 @@
+package entry_collector
+
+import (
+	"blacked/internal/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeThrottle returns the throttle of cfg on a clock that sleeping advances.
+func fakeThrottle(t *testing.T, cfg config.CacheSettings, p99 *time.Duration) (*syncThrottle, *time.Duration) {
+	t.Helper()
+	th := newSyncThrottle(cfg)
+	require.NotNil(t, th)
+
+	start := time.Unix(0, 0)
+	var elapsed time.Duration
+	th.now = func() time.Time { return start.Add(elapsed) }
+	th.sleep = func(d time.Duration) { elapsed += d }
+	th.latency = func(time.Duration) (time.Duration, bool) { return *p99, *p99 > 0 }
+	th.since, th.adjusted = start, start
+	return th, &elapsed
+}
+
+func TestSyncThrottleCeiling(t *testing.T) {
+	assert.Nil(t, newSyncThrottle(config.CacheSettings{}), "no ceiling and no target")
+
+	var p99 time.Duration
+	th, elapsed := fakeThrottle(t, config.CacheSettings{SyncMaxKeysPerSec: 1000}, &p99)
+	for n := 1; n <= 4*throttleBatch; n++ {
+		th.pace(n)
+	}
+	assert.Equal(t, 1024*time.Millisecond, *elapsed, "1024 keys at 1000 keys/s")
+	assert.Equal(t, 1000.0, th.rateLimit())
+}
+
+func TestSyncThrottleAdaptive(t *testing.T) {
+	p99 := 50 * time.Millisecond
+	th, elapsed := fakeThrottle(t, config.CacheSettings{SyncTargetP99: 10 * time.Millisecond, SyncMinKeysPerSec: 100}, &p99)
+
+	// Unthrottled, the sync writes 10240 keys a second.
+	*elapsed = time.Second
+	th.pace(10 * 1024)
+	assert.Equal(t, 5120.0, th.rateLimit(), "queries over target halve the observed rate")
+
+	adjustAt := func(keys int) {
+		*elapsed += throttleAdjustEvery
+		th.pace(keys)
+	}
+	adjustAt(20 * 1024)
+	assert.Equal(t, 2560.0, th.rateLimit())
+
+	p99 = 8 * time.Millisecond
+	adjustAt(30 * 1024)
+	assert.Equal(t, 2560.0, th.rateLimit(), "between three quarters of the target and the target the rate holds")
+
+	p99 = 0 // No queries
+	adjustAt(40 * 1024)
+	assert.Equal(t, 3200.0, th.rateLimit(), "grows by a quarter")
+	for i := range 10 {
+		adjustAt((41 + i) * 1024)
+	}
+	assert.Zero(t, th.rateLimit(), "back to unthrottled once past the observed peak")
+}
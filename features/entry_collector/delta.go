@@ -0,0 +1,327 @@
+package entry_collector
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"hash/fnv"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/internal/config"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// snapshotSuffix names the delta snapshot of a provider under the store path.
+const snapshotSuffix = "_snapshot.gob"
+
+// deleteTimeout bounds the soft delete of the entries gone from a feed.
+const deleteTimeout = 5 * time.Minute
+
+var (
+	ErrDeltaNotStarted = errors.New("no delta ingestion run for provider")
+	ErrDeltaSaveFailed = errors.New("delta ingestion run lost batches")
+)
+
+// DeltaReport counts what a delta ingestion run did with a feed. A full run
+// had no usable snapshot: it wrote every entry and removed the active
+// entries of the source the feed no longer lists.
+type DeltaReport struct {
+	Full      bool `json:"full"`
+	Added     int  `json:"added"`
+	Changed   int  `json:"changed"`
+	Unchanged int  `json:"unchanged"`
+	Removed   int  `json:"removed"`
+}
+
+// feedSnapshot maps the source URLs of a feed to the fingerprint of the
+// entry written for them.
+type feedSnapshot map[string]uint64
+
+// deltaRun tracks the entries one run of a delta ingesting source submits.
+type deltaRun struct {
+	processID string
+	path      string
+	prev      feedSnapshot // nil for a full run
+
+	mu       sync.Mutex
+	seen     feedSnapshot
+	report   DeltaReport
+	lost     bool // A batch failed to save; the snapshot must not be trusted
+	finished bool // Later submissions aren't part of the run
+}
+
+// startDelta opens a delta run for providerName from its last snapshot. A
+// snapshot disagreeing with the active entries of the source, as after a
+// manual delete or a restore, is dropped for a full run.
+func (c *PondCollector) startDelta(providerName, processID string) {
+	run := &deltaRun{
+		processID: processID,
+		path:      snapshotPath(providerName),
+		seen:      make(feedSnapshot),
+	}
+
+	prev, err := loadSnapshot(run.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		log.Info().Str("provider", providerName).Msg("No delta snapshot, writing the full feed")
+	case err != nil:
+		log.Warn().Err(err).Str("provider", providerName).Msg("Failed to read delta snapshot, writing the full feed")
+	default:
+		active, err := c.repo.StreamEntriesCountBySource(c.ctx, providerName)
+		if err != nil || active != len(prev) {
+			log.Warn().Err(err).
+				Str("provider", providerName).
+				Int("snapshot", len(prev)).
+				Int("active", active).
+				Msg("Delta snapshot does not match the database, writing the full feed")
+		} else {
+			run.prev = prev
+		}
+	}
+	run.report.Full = run.prev == nil
+
+	c.deltaMu.Lock()
+	c.deltas[providerName] = run
+	c.deltaMu.Unlock()
+}
+
+// deltaKeep records entry in the delta run of its source and reports whether
+// it must be written: always in a full run, else only when it is new or
+// changed since the snapshot.
+func (c *PondCollector) deltaKeep(entry *entries.Entry) bool {
+	c.deltaMu.Lock()
+	run := c.deltas[entry.Source]
+	c.deltaMu.Unlock()
+	if run == nil {
+		return true
+	}
+
+	fp := entryFingerprint(entry)
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	if run.finished {
+		return true
+	}
+
+	if old, ok := run.seen[entry.SourceURL]; ok && old == fp {
+		return false // Listed twice in the feed
+	}
+	run.seen[entry.SourceURL] = fp
+
+	old, listed := run.prev[entry.SourceURL]
+	switch {
+	case run.prev == nil || !listed:
+		run.report.Added++
+	case old != fp:
+		run.report.Changed++
+	default:
+		run.report.Unchanged++
+		return false
+	}
+	return true
+}
+
+// deltaLost marks the delta run of source as having lost a batch.
+func (c *PondCollector) deltaLost(source string) {
+	c.deltaMu.Lock()
+	run := c.deltas[source]
+	c.deltaMu.Unlock()
+	if run == nil {
+		return
+	}
+	run.mu.Lock()
+	run.lost = true
+	run.mu.Unlock()
+}
+
+// finishDelta stops the delta run of providerName from filtering entries;
+// it waits for CommitDelta, or is dropped by the next run.
+func (c *PondCollector) finishDelta(providerName, processID string) {
+	c.deltaMu.Lock()
+	run := c.deltas[providerName]
+	c.deltaMu.Unlock()
+	if run == nil || run.processID != processID {
+		return
+	}
+	run.mu.Lock()
+	run.finished = true
+	run.mu.Unlock()
+}
+
+// CommitDelta ends the delta run of a provider whose feed parsed completely:
+// entries gone from the feed are soft deleted and the feed becomes the next
+// snapshot. Call it after FinishProviderProcessing, once every batch is
+// written. A run that is never committed leaves the previous snapshot.
+func (c *PondCollector) CommitDelta(ctx context.Context, providerName, processID string) (*DeltaReport, error) {
+	c.deltaMu.Lock()
+	run := c.deltas[providerName]
+	if run != nil && run.processID == processID {
+		delete(c.deltas, providerName)
+	}
+	c.deltaMu.Unlock()
+	if run == nil || run.processID != processID {
+		return nil, ErrDeltaNotStarted
+	}
+
+	tracer := otel.Tracer("blacked/collector")
+	ctx, span := tracer.Start(ctx, "collector.commit_delta",
+		trace.WithAttributes(
+			attribute.String("provider.name", providerName),
+			attribute.String("process.id", processID),
+		),
+	)
+	defer span.End()
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	if run.lost {
+		span.SetStatus(codes.Error, "delta ingestion run lost batches")
+		// The next run starts over rather than skip entries never written.
+		if err := os.Remove(run.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Warn().Err(err).Str("file", run.path).Msg("Failed to remove delta snapshot")
+		}
+		return &run.report, ErrDeltaSaveFailed
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
+	gone, err := run.gone(ctx, c.repo, providerName)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to list removed entries")
+		return &run.report, err
+	}
+	removed, err := c.repo.SoftDeleteSourceURLs(ctx, providerName, gone)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to remove entries")
+		return &run.report, err
+	}
+	run.report.Removed = int(removed)
+
+	// Unchanged entries were listed again without being written, so their
+	// expiry is restarted here; a full run wrote every entry with its own.
+	if run.prev != nil && run.report.Unchanged > 0 {
+		var expiresAt *int64
+		if ttl := config.GetConfig().CollectorSettingsFor(providerName).TTL; ttl > 0 {
+			at := time.Now().Add(ttl).UnixNano()
+			expiresAt = &at
+		}
+		if _, err := c.repo.SetSourceExpiry(ctx, providerName, expiresAt); err != nil {
+			log.Warn().Err(err).Str("provider", providerName).Msg("Failed to restart the expiry of unchanged entries")
+		}
+	}
+	span.SetAttributes(
+		attribute.Bool("delta.full", run.report.Full),
+		attribute.Int("delta.added", run.report.Added),
+		attribute.Int("delta.changed", run.report.Changed),
+		attribute.Int("delta.unchanged", run.report.Unchanged),
+		attribute.Int("delta.removed", run.report.Removed),
+	)
+
+	if err := saveSnapshot(run.path, run.seen); err != nil {
+		log.Warn().Err(err).Str("file", run.path).Msg("Failed to save delta snapshot, the next run writes the full feed")
+		os.Remove(run.path)
+	}
+
+	log.Info().
+		Str("provider", providerName).
+		Bool("full", run.report.Full).
+		Int("added", run.report.Added).
+		Int("changed", run.report.Changed).
+		Int("unchanged", run.report.Unchanged).
+		Int("removed", run.report.Removed).
+		Msg("Delta ingestion committed")
+	return &run.report, nil
+}
+
+// gone returns the source URLs the feed no longer lists: those of the
+// snapshot, or in a full run those of the active entries of the source.
+func (run *deltaRun) gone(ctx context.Context, repo repository.BlacklistRepository, providerName string) ([]string, error) {
+	var gone []string
+	if run.prev != nil {
+		for sourceURL := range run.prev {
+			if _, ok := run.seen[sourceURL]; !ok {
+				gone = append(gone, sourceURL)
+			}
+		}
+		return gone, nil
+	}
+
+	err := repo.StreamFilteredEntries(ctx, repository.Filter{Sources: []string{providerName}}, func(e *entries.Entry) error {
+		if _, ok := run.seen[e.SourceURL]; !ok {
+			gone = append(gone, e.SourceURL)
+		}
+		return nil
+	})
+	return gone, err
+}
+
+// entryFingerprint hashes the fields a feed sets on an entry; the URL
+// fields derive from the source URL, which keys the snapshot.
+func entryFingerprint(e *entries.Entry) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(e.Category))
+	for _, c := range e.Categories {
+		h.Write([]byte{0})
+		h.Write([]byte(c))
+	}
+	var conf [8]byte
+	binary.LittleEndian.PutUint64(conf[:], math.Float64bits(e.Confidence))
+	h.Write(conf[:])
+	return h.Sum64()
+}
+
+func snapshotPath(providerName string) string {
+	return filepath.Join(config.GetConfig().Collector.StorePath, providerName+snapshotSuffix)
+}
+
+func loadSnapshot(path string) (feedSnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var snap feedSnapshot
+	if err := gob.NewDecoder(f).Decode(&snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// saveSnapshot writes snap through a temporary file so a crash never leaves
+// a partial snapshot.
+func saveSnapshot(path string, snap feedSnapshot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(snap); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
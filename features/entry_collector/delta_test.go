@@ -0,0 +1,41 @@
+package entry_collector
+
+import (
+	"blacked/features/entries"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntryFingerprint(t *testing.T) {
+	base := entries.NewEntry().WithCategories("malware", "botnet").WithConfidence(0.9)
+	same := entries.NewEntry().WithCategories("malware", "botnet").WithConfidence(0.9)
+	assert.Equal(t, entryFingerprint(base), entryFingerprint(same))
+
+	assert.NotEqual(t, entryFingerprint(base), entryFingerprint(entries.NewEntry().WithCategories("malware").WithConfidence(0.9)))
+	assert.NotEqual(t, entryFingerprint(base), entryFingerprint(entries.NewEntry().WithCategories("malware", "botnet").WithConfidence(0.5)))
+	assert.NotEqual(t,
+		entryFingerprint(entries.NewEntry().WithCategories("ab", "c")),
+		entryFingerprint(entries.NewEntry().WithCategories("a", "bc")),
+		"categories are separated in the hash")
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feeds", "oisd-big"+snapshotSuffix)
+
+	_, err := loadSnapshot(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	snap := feedSnapshot{"bad.example": 1, "worse.example": 2}
+	require.NoError(t, saveSnapshot(path, snap))
+	loaded, err := loadSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, snap, loaded)
+
+	leftovers, err := filepath.Glob(path + ".tmp-*")
+	require.NoError(t, err)
+	assert.Empty(t, leftovers)
+}
@@ -2,6 +2,7 @@ package entry_collector
 
 import (
 	"blacked/features/entries"
+	"context"
 	"time"
 )
 
@@ -11,6 +12,7 @@ type Collector interface {
 	Wait()
 	Close()
 	GetProcessedCount(source string) int
-	StartProviderProcessing(providerName, processID string)
+	StartProviderProcessing(ctx context.Context, providerName, processID string)
 	FinishProviderProcessing(providerName, processID string) (count int, duration time.Duration, ok bool)
+	CommitDelta(ctx context.Context, providerName, processID string) (*DeltaReport, error)
 }
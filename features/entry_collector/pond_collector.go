@@ -10,15 +10,18 @@ import (
 	"time"
 
 	"blacked/features/bloom"
+	"blacked/features/cache"
 	"blacked/features/entries"
 	"blacked/features/entries/repository"
 	"blacked/internal/collector"
 	"blacked/internal/config"
+	"blacked/internal/query"
 
 	"github.com/alitto/pond/v2"
 	"github.com/rs/zerolog/log"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -26,8 +29,7 @@ import (
 var ErrMissingBloomStreamCh = errors.New("bloom stream channel is nil")
 
 const (
-	PeriodicFlushInterval = 5 * time.Second
-	MaxProvidersInMemory  = 1000
+	MaxProvidersInMemory = 1000
 )
 
 var (
@@ -45,9 +47,9 @@ type PondCollector struct {
 	pool          pond.Pool
 	repo          repository.BlacklistRepository
 	bloomMgr      *bloom.BloomManager
-	batchSize     int
-	buffer        []*entries.Entry
+	buffers       map[string]*sourceBuffer // Pending entries per source
 	bufferMu      sync.Mutex
+	flushTick     time.Duration // Smallest configured flush interval
 	providerStats map[string]*ProviderStats
 	statsMu       sync.RWMutex
 	ctx           context.Context
@@ -57,6 +59,15 @@ type PondCollector struct {
 	cacheSyncState     CacheSyncState
 	cacheSyncMutex     sync.Mutex
 	cacheSyncWaitGroup sync.WaitGroup
+	cacheSyncJobs      *cacheSyncJobs
+	queuedCacheSyncID  string       // Job waiting for the running sync, guarded by cacheSyncMutex
+	cacheSyncDisabled  bool         // Ingest-only roles have no query cache, guarded by cacheSyncMutex
+	lastScrub          *ScrubReport // Last completed cache scrub, guarded by cacheSyncMutex
+	warmup             *warmup      // Startup warm-up gating readiness, guarded by cacheSyncMutex
+
+	// Delta ingestion runs per source
+	deltas  map[string]*deltaRun
+	deltaMu sync.Mutex
 
 	// Single-threaded database writer
 	dbWriteChan chan []*entries.Entry
@@ -72,7 +83,8 @@ func InitPondCollector(
 		// Create a child context that we can cancel
 		ctxWithCancel, cancel := context.WithCancel(ctx)
 
-		collectorConfig := config.GetConfig().Collector
+		cfg := config.GetConfig()
+		collectorConfig := cfg.Collector
 
 		// Create a new pond with specified concurrency for processing work
 		// This pool is for non-DB operations (parsing, validation, etc.)
@@ -85,12 +97,14 @@ func InitPondCollector(
 			pool:           pool,
 			repo:           repository.NewSQLiteRepository(db),
 			bloomMgr:       bloomMgr,
-			batchSize:      collectorConfig.BatchSize,
-			buffer:         make([]*entries.Entry, 0, collectorConfig.BatchSize),
+			buffers:        make(map[string]*sourceBuffer),
+			flushTick:      minFlushInterval(cfg),
 			providerStats:  make(map[string]*ProviderStats),
 			ctx:            ctxWithCancel,
 			cancel:         cancel,
 			cacheSyncState: CacheSyncStateIdle,
+			cacheSyncJobs:  newCacheSyncJobs(),
+			deltas:         make(map[string]*deltaRun),
 			dbWriteChan:    make(chan []*entries.Entry, 100), // Buffered channel for batches
 		}
 
@@ -148,6 +162,8 @@ func InitPondCollector(
 		log.Info().
 			Int("concurrency", collectorConfig.Concurrency).
 			Int("batch_size", collectorConfig.BatchSize).
+			Dur("flush_interval", collectorConfig.FlushInterval).
+			Dur("save_timeout", collectorConfig.SaveTimeout).
 			Msg("Global pond collector initialized with single-threaded DB writer")
 	})
 	return globalCollector
@@ -163,8 +179,28 @@ func (c *PondCollector) GetBloomManager() *bloom.BloomManager {
 	return c.bloomMgr
 }
 
-// StartProviderProcessing initializes tracking for a provider process
-func (c *PondCollector) StartProviderProcessing(providerName, processID string) {
+// PopulateBloom adds the entries of batch that are not deleted to the shared
+// BloomManager and their source URLs to the cache bloom filter, for saved
+// batches and for entries written outside the collector, e.g. by a replica.
+// URLs are checkable as soon as they are written, before the run's cache sync.
+func (c *PondCollector) PopulateBloom(batch []*entries.Entry) {
+	for _, e := range batch {
+		if e.DeletedAt != nil {
+			continue
+		}
+		if c.bloomMgr != nil {
+			c.bloomMgr.PopulateEntry(e.Source, entryToURLKeys(e))
+		}
+		// Before the first sync there is no filter yet; its build adds them.
+		cache.AddToBloomFilter(e.SourceURL)
+	}
+	// Entries written by replication bypass the repository's purge.
+	query.PurgeNegativeCache()
+}
+
+// StartProviderProcessing initializes tracking for a provider process; the
+// batches it writes are traced under the span of ctx.
+func (c *PondCollector) StartProviderProcessing(ctx context.Context, providerName, processID string) {
 	c.statsMu.Lock()
 	defer c.statsMu.Unlock()
 
@@ -183,6 +219,11 @@ func (c *PondCollector) StartProviderProcessing(providerName, processID string)
 		startTime:      time.Now(),
 		processID:      processID,
 		active:         true,
+		trace:          trace.SpanContextFromContext(ctx),
+	}
+
+	if config.GetConfig().CollectorSettingsFor(providerName).DeltaIngest {
+		c.startDelta(providerName, processID)
 	}
 
 	log.Info().
@@ -193,6 +234,11 @@ func (c *PondCollector) StartProviderProcessing(providerName, processID string)
 
 // Submit adds an entry to the collector's buffer
 func (c *PondCollector) Submit(entry *entries.Entry) {
+	// Delta ingestion drops entries unchanged since the last snapshot
+	if !c.deltaKeep(entry) {
+		return
+	}
+
 	// First, mark that we have a pending operation for this provider
 	c.statsMu.RLock()
 	stats, exists := c.providerStats[entry.Source]
@@ -201,15 +247,18 @@ func (c *PondCollector) Submit(entry *entries.Entry) {
 	}
 	c.statsMu.RUnlock()
 
-	// Now add to buffer as usual
+	// Now add to the source's buffer
 	c.bufferMu.Lock()
-	c.buffer = append(c.buffer, entry)
+	buf, ok := c.buffers[entry.Source]
+	if !ok {
+		buf = newSourceBuffer(config.GetConfig().CollectorSettingsFor(entry.Source))
+		c.buffers[entry.Source] = buf
+	}
+	buf.entries = append(buf.entries, entry)
 
 	// If buffer is full, submit a flush task
-	if len(c.buffer) >= c.batchSize {
-		batch := make([]*entries.Entry, len(c.buffer))
-		copy(batch, c.buffer)
-		c.buffer = c.buffer[:0]
+	if len(buf.entries) >= buf.settings.BatchSize {
+		batch := buf.take()
 		c.bufferMu.Unlock()
 
 		c.submitFlush(batch)
@@ -217,6 +266,7 @@ func (c *PondCollector) Submit(entry *entries.Entry) {
 		c.bufferMu.Unlock()
 	}
 }
+
 func (c *PondCollector) submitFlush(batch []*entries.Entry) {
 	// Simply send the batch to the single-threaded DB writer channel
 	// The single writer goroutine will handle all database operations sequentially
@@ -240,7 +290,10 @@ func (c *PondCollector) singleThreadedDBWriter() {
 
 	for {
 		select {
-		case batch := <-c.dbWriteChan:
+		case batch, ok := <-c.dbWriteChan:
+			if !ok {
+				return // Closed by Wait once every batch is queued
+			}
 			// Group entries by source for more efficient processing
 			entriesBySource := make(map[string][]*entries.Entry)
 			for _, entry := range batch {
@@ -261,7 +314,10 @@ func (c *PondCollector) singleThreadedDBWriter() {
 			// Drain remaining batches
 			for {
 				select {
-				case batch := <-c.dbWriteChan:
+				case batch, ok := <-c.dbWriteChan:
+					if !ok {
+						return
+					}
 					entriesBySource := make(map[string][]*entries.Entry)
 					for _, entry := range batch {
 						entriesBySource[entry.Source] = append(entriesBySource[entry.Source], entry)
@@ -292,9 +348,16 @@ func (c *PondCollector) processBatch(source string, localEntries []*entries.Entr
 		c.statsMu.RUnlock()
 	}()
 
-	// Create span for batch save operation
+	// Create span for batch save operation, under the provider's trace
+	c.statsMu.RLock()
+	var parent trace.SpanContext
+	if stats, exists := c.providerStats[source]; exists {
+		parent = stats.trace
+	}
+	c.statsMu.RUnlock()
+
 	tracer := otel.Tracer("blacked/collector")
-	_, span := tracer.Start(context.Background(), "collector.batch_save",
+	ctx, span := tracer.Start(trace.ContextWithSpanContext(context.Background(), parent), "collector.batch_save",
 		trace.WithAttributes(
 			attribute.String("source", source),
 			attribute.Int("batch_size", len(localEntries)),
@@ -302,26 +365,25 @@ func (c *PondCollector) processBatch(source string, localEntries []*entries.Entr
 	)
 	defer span.End()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	settings := c.sourceSettings(source)
+	ctx, cancel := context.WithTimeout(ctx, settings.SaveTimeout)
 	defer cancel()
 
+	setExpiry(localEntries, settings.TTL)
 	if err := c.repo.BatchSaveEntries(ctx, localEntries); err != nil {
 		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to save batch")
 		log.Error().Err(err).
 			Int("batch_size", len(localEntries)).
 			Str("source", source).
 			Msg("Failed to save batch")
+		c.deltaLost(source)
 		return
 	}
 	span.AddEvent("batch saved to database")
 
 	// Populate the shared BloomManager so the API can check against live data
-	if c.bloomMgr != nil {
-		for _, e := range localEntries {
-			keys := entryToURLKeys(e)
-			c.bloomMgr.PopulateEntry(e.Source, keys)
-		}
-	}
+	c.PopulateBloom(localEntries)
 
 	batchSize := len(localEntries)
 
@@ -356,14 +418,39 @@ func (c *PondCollector) processBatch(source string, localEntries []*entries.Entr
 	}
 }
 
+// sourceSettings returns the collector settings configured for source.
+func (c *PondCollector) sourceSettings(source string) config.CollectorSettings {
+	c.bufferMu.Lock()
+	defer c.bufferMu.Unlock()
+	if buf, ok := c.buffers[source]; ok {
+		return buf.settings
+	}
+	return config.GetConfig().CollectorSettingsFor(source)
+}
+
+// setExpiry sets the expiry of entries, ttl after they were listed; a zero
+// ttl clears it.
+func setExpiry(batch []*entries.Entry, ttl time.Duration) {
+	for _, entry := range batch {
+		if ttl <= 0 {
+			entry.ExpiresAt = nil
+			continue
+		}
+		expiresAt := entry.UpdatedAt + int64(ttl)
+		entry.ExpiresAt = &expiresAt
+	}
+}
+
+// periodicFlush wakes at the smallest configured flush interval and writes
+// every buffer whose own interval has elapsed since its last flush.
 func (c *PondCollector) periodicFlush() {
-	ticker := time.NewTicker(PeriodicFlushInterval)
+	ticker := time.NewTicker(c.flushTick)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			c.flushBuffer()
+		case now := <-ticker.C:
+			c.flushDue(now)
 		case <-c.ctx.Done():
 			c.flushBuffer()
 			return
@@ -371,22 +458,41 @@ func (c *PondCollector) periodicFlush() {
 	}
 }
 
-func (c *PondCollector) flushBuffer() {
-	c.bufferMu.Lock()
-	if len(c.buffer) > 0 {
-		batch := batchSlicePool.Get().([]*entries.Entry)
-		batch = batch[:0]
-		batch = append(batch, c.buffer...)
+// flushDue flushes the buffers whose flush interval has elapsed at now.
+func (c *PondCollector) flushDue(now time.Time) {
+	var batches [][]*entries.Entry
 
-		c.buffer = c.buffer[:0]
-		c.bufferMu.Unlock()
+	c.bufferMu.Lock()
+	for _, buf := range c.buffers {
+		if now.Sub(buf.lastFlush) < buf.settings.FlushInterval {
+			continue
+		}
+		if len(buf.entries) > 0 {
+			batches = append(batches, buf.take())
+		}
+		buf.lastFlush = now
+	}
+	c.bufferMu.Unlock()
 
+	for _, batch := range batches {
 		c.submitFlush(batch)
+	}
+}
 
-		batch = batch[:0]
-		batchSlicePool.Put(batch)
-	} else {
-		c.bufferMu.Unlock()
+// flushBuffer flushes every non-empty buffer regardless of its interval.
+func (c *PondCollector) flushBuffer() {
+	var batches [][]*entries.Entry
+
+	c.bufferMu.Lock()
+	for _, buf := range c.buffers {
+		if len(buf.entries) > 0 {
+			batches = append(batches, buf.take())
+		}
+	}
+	c.bufferMu.Unlock()
+
+	for _, batch := range batches {
+		c.submitFlush(batch)
 	}
 }
 
@@ -449,6 +555,7 @@ func (c *PondCollector) FinishProviderProcessing(providerName, processID string)
 
 	delete(c.providerStats, providerName)
 	c.statsMu.Unlock()
+	c.finishDelta(providerName, processID)
 
 	if mc, _ := collector.GetMetricsCollector(); mc != nil {
 		mc.SetTotalProcessed(providerName, count)
@@ -512,3 +619,41 @@ func entryToURLKeys(e *entries.Entry) *bloom.URLKeys {
 		IP:       ip,
 	}
 }
+
+// sourceBuffer holds the pending entries of one source with its resolved settings.
+type sourceBuffer struct {
+	entries   []*entries.Entry
+	settings  config.CollectorSettings
+	lastFlush time.Time
+}
+
+func newSourceBuffer(settings config.CollectorSettings) *sourceBuffer {
+	return &sourceBuffer{
+		entries:   make([]*entries.Entry, 0, settings.BatchSize),
+		settings:  settings,
+		lastFlush: time.Now(),
+	}
+}
+
+// take returns the pending entries as a pooled batch and resets the buffer.
+// The DB writer returns the batch to batchSlicePool once it is saved.
+// Callers must hold PondCollector.bufferMu.
+func (b *sourceBuffer) take() []*entries.Entry {
+	batch := batchSlicePool.Get().([]*entries.Entry)[:0]
+	batch = append(batch, b.entries...)
+	b.entries = b.entries[:0]
+	b.lastFlush = time.Now()
+	return batch
+}
+
+// minFlushInterval returns the smallest flush interval across the global
+// collector settings and every provider override.
+func minFlushInterval(cfg *config.Config) time.Duration {
+	tick := cfg.Collector.FlushInterval
+	for name := range cfg.Providers {
+		if d := cfg.CollectorSettingsFor(name).FlushInterval; d < tick {
+			tick = d
+		}
+	}
+	return max(tick, config.MinFlushInterval)
+}
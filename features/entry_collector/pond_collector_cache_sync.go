@@ -2,6 +2,7 @@ package entry_collector
 
 import (
 	"blacked/features/cache"
+	"blacked/features/edgeset"
 	"blacked/features/entries"
 	"blacked/features/entries/repository"
 	"blacked/internal/config"
@@ -10,38 +11,147 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// ScheduleCacheSync schedules a cache sync operation
+// ScheduleCacheSync schedules a full cache sync operation
 // Returns true if the operation was scheduled, false if dropped
 // immediate=true will attempt to perform the sync immediately (blocking)
 // - Only one sync can run at a time.
 // - Only one queued sync is allowed.
 // - Further requests are dropped and should be logged.
 func (c *PondCollector) ScheduleCacheSync(immediate bool) bool {
+	_, ok := c.scheduleCacheSync(context.Background(), CacheSyncScope{Mode: CacheSyncFull}, immediate)
+	return ok
+}
+
+// ScheduleCacheSyncScope schedules a sync of scope as ScheduleCacheSync
+// does, waiting for it when immediate. The sync is traced under the span of
+// ctx, whose cancellation it ignores.
+func (c *PondCollector) ScheduleCacheSyncScope(ctx context.Context, scope CacheSyncScope, immediate bool) bool {
+	_, ok := c.scheduleCacheSync(ctx, scope, immediate)
+	return ok
+}
+
+// ScheduleChangedCacheSync schedules a sync of every source URL changed at or
+// after since (Unix nanos) without blocking. Unlike a full sync it also drops
+// URLs no source lists anymore, so it is what follows soft deletes.
+// Returns false if the request was dropped.
+func (c *PondCollector) ScheduleChangedCacheSync(since int64) bool {
+	_, ok := c.scheduleCacheSync(context.Background(), CacheSyncScope{Mode: CacheSyncDelta, Since: since}, false)
+	return ok
+}
+
+// DisableCacheSync turns cache syncs into no-ops, for run modes that write
+// entries but serve no queries and so never initialize the cache.
+func (c *PondCollector) DisableCacheSync() {
 	c.cacheSyncMutex.Lock()
 	defer c.cacheSyncMutex.Unlock()
+	c.cacheSyncDisabled = true
+}
+
+// RequestCacheSync schedules a scoped cache sync without blocking and returns
+// its job for polling. A delta scope is resolved to the first write of its
+// process up front, so an unknown process id fails here rather than in the job.
+// When the queue is full but the queued job is a full rebuild, that job
+// already covers the request and is returned instead.
+func (c *PondCollector) RequestCacheSync(ctx context.Context, scope CacheSyncScope) (CacheSyncJob, error) {
+	if err := scope.Validate(); err != nil {
+		return CacheSyncJob{}, err
+	}
+
+	c.cacheSyncMutex.Lock()
+	disabled := c.cacheSyncDisabled
+	c.cacheSyncMutex.Unlock()
+	if disabled {
+		return CacheSyncJob{}, ErrCacheSyncDisabled
+	}
+
+	if scope.Mode == CacheSyncDelta {
+		since, err := c.repo.ProcessFirstWrite(ctx, scope.ProcessID)
+		if err != nil {
+			return CacheSyncJob{}, err
+		}
+		scope.Since = since
+	}
+
+	id, ok := c.scheduleCacheSync(ctx, scope, false)
+	if !ok {
+		queued, found := c.cacheSyncJobs.get(id)
+		if !found || queued.Scope.Mode != CacheSyncFull {
+			return CacheSyncJob{}, ErrCacheSyncBusy
+		}
+		return queued, nil
+	}
+
+	job, _ := c.cacheSyncJobs.get(id)
+	return job, nil
+}
+
+// GetCacheSyncJob returns the current state of a cache sync job.
+func (c *PondCollector) GetCacheSyncJob(id string) (CacheSyncJob, error) {
+	job, ok := c.cacheSyncJobs.get(id)
+	if !ok {
+		return CacheSyncJob{}, ErrCacheSyncJobNotFound
+	}
+	return job, nil
+}
+
+// CacheSyncStatus summarizes cache sync activity for the stats endpoint.
+type CacheSyncStatus struct {
+	State   string        `json:"state"` // idle, running or queued
+	Running *CacheSyncJob `json:"running,omitempty"`
+	Queued  *CacheSyncJob `json:"queued,omitempty"`
+	Last    *CacheSyncJob `json:"last,omitempty"` // Most recently finished job
+
+	LastCompletedAt *time.Time `json:"last_completed_at,omitempty"` // End of the most recent successful sync
+}
+
+// GetCacheSyncStatus returns the sync state with the running, queued and last finished jobs.
+func (c *PondCollector) GetCacheSyncStatus() CacheSyncStatus {
+	c.cacheSyncMutex.Lock()
+	state := c.cacheSyncState
+	c.cacheSyncMutex.Unlock()
+
+	running, queued, last := c.cacheSyncJobs.snapshot()
+	return CacheSyncStatus{
+		State:   state.String(),
+		Running: running,
+		Queued:  queued,
+		Last:    last,
+
+		LastCompletedAt: c.cacheSyncJobs.lastCompleted(),
+	}
+}
+
+// scheduleCacheSync runs or queues a sync for scope following the
+// idle/running/queued states. It returns the job id and whether the request
+// was scheduled; a dropped request returns the id of the queued job. The
+// sync outlives ctx and only inherits its span.
+func (c *PondCollector) scheduleCacheSync(ctx context.Context, scope CacheSyncScope, immediate bool) (string, bool) {
+	ctx = context.WithoutCancel(ctx)
+
+	c.cacheSyncMutex.Lock()
+	defer c.cacheSyncMutex.Unlock()
+
+	if c.cacheSyncDisabled {
+		// Nothing to refresh; report success so writers don't treat it as contention.
+		log.Debug().Str("mode", string(scope.Mode)).Msg("Cache sync skipped - disabled in this run mode")
+		return "", true
+	}
 
 	switch c.cacheSyncState {
 	case CacheSyncStateIdle:
 		// No sync running, start one immediately
 		c.cacheSyncState = CacheSyncStateRunning
+		id := c.cacheSyncJobs.add(scope)
 
 		c.cacheSyncWaitGroup.Go(func() {
 			defer c.completeCacheSync()
-
-			log.Info().Msg("Starting cache synchronization")
-			startTime := time.Now()
-
-			ctx := context.Background()
-			if err := syncToCache(ctx); err != nil {
-				log.Error().Err(err).Msg("Cache sync failed")
-			} else {
-				duration := time.Since(startTime)
-				log.Info().
-					Dur("duration", duration).
-					Msg("Cache sync completed successfully")
-			}
+			c.runCacheSyncJob(ctx, id, scope)
 		})
 
 		// If immediate is true, wait for completion
@@ -51,64 +161,104 @@ func (c *PondCollector) ScheduleCacheSync(immediate bool) bool {
 			c.cacheSyncMutex.Lock() // Lock again before returning
 		}
 
-		return true
+		return id, true
 
 	case CacheSyncStateRunning:
 		if immediate {
 			// If immediate is requested but another sync is running
 			// we return false to indicate we couldn't fulfill the immediate request
 			log.Warn().Msg("Immediate cache sync requested but another sync is already running")
-			return false
+			return "", false
 		}
 
-		// A sync is already running, check if we can queue
-		if c.cacheSyncState != CacheSyncStateQueued {
-			// No sync is queued yet, queue this one
-			c.cacheSyncState = CacheSyncStateQueued
-
-			// Start a goroutine that will wait for the current sync to finish
-			go func() {
-				// Wait for current sync to finish
-				c.cacheSyncWaitGroup.Wait()
-
-				// Start a new sync
-				c.cacheSyncMutex.Lock()
-				if c.cacheSyncState != CacheSyncStateQueued {
-					// State changed while we were waiting
-					c.cacheSyncMutex.Unlock()
-					return
-				}
+		// A sync is running and nothing is queued yet, queue this one
+		c.cacheSyncState = CacheSyncStateQueued
+		id := c.cacheSyncJobs.add(scope)
+		c.queuedCacheSyncID = id
+
+		// Start a goroutine that will wait for the current sync to finish
+		go func() {
+			// Wait for current sync to finish
+			c.cacheSyncWaitGroup.Wait()
 
-				c.cacheSyncState = CacheSyncStateRunning
-				c.cacheSyncWaitGroup.Add(1)
+			// Start a new sync
+			c.cacheSyncMutex.Lock()
+			if c.cacheSyncState != CacheSyncStateQueued {
+				// State changed while we were waiting
 				c.cacheSyncMutex.Unlock()
+				return
+			}
 
-				defer c.cacheSyncWaitGroup.Done()
-				defer c.completeCacheSync()
+			c.cacheSyncState = CacheSyncStateRunning
+			c.queuedCacheSyncID = ""
+			c.cacheSyncWaitGroup.Add(1)
+			c.cacheSyncMutex.Unlock()
 
-				log.Info().Msg("Starting queued cache synchronization")
-				startTime := time.Now()
+			defer c.cacheSyncWaitGroup.Done()
+			defer c.completeCacheSync()
 
-				ctx := context.Background()
-				if err := syncToCache(ctx); err != nil {
-					log.Error().Err(err).Msg("Queued cache sync failed")
-				} else {
-					duration := time.Since(startTime)
-					log.Info().
-						Dur("duration", duration).
-						Msg("Queued cache sync completed successfully")
-				}
-			}()
+			c.runCacheSyncJob(ctx, id, scope)
+		}()
 
-			return true
+		return id, true
+	}
+
+	// A sync is running and another is already queued, drop this request
+	log.Debug().Str("mode", string(scope.Mode)).Msg("Cache sync request dropped - queue full")
+	return c.queuedCacheSyncID, false
+}
+
+// runCacheSyncJob runs one sync and records its progress and outcome on the job.
+func (c *PondCollector) runCacheSyncJob(ctx context.Context, id string, scope CacheSyncScope) {
+	tracer := otel.Tracer("blacked/collector")
+	ctx, span := tracer.Start(ctx, "collector.cache_sync",
+		trace.WithAttributes(
+			attribute.String("job.id", id),
+			attribute.String("sync.mode", string(scope.Mode)),
+			attribute.String("sync.source", scope.Source),
+			attribute.String("process.id", scope.ProcessID),
+		),
+	)
+	defer span.End()
+
+	started := time.Now().UTC()
+	c.cacheSyncJobs.update(id, func(j *CacheSyncJob) {
+		j.State = CacheSyncJobRunning
+		j.StartedAt = &started
+	})
+
+	log.Info().
+		Str("job_id", id).
+		Str("mode", string(scope.Mode)).
+		Str("source", scope.Source).
+		Str("process_id", scope.ProcessID).
+		Msg("Starting cache synchronization")
+
+	tracker := newSyncTracker(c.cacheSyncJobs, id)
+	tracker.throttle = newSyncThrottle(config.GetConfig().Cache)
+	err := runCacheSync(ctx, scope, tracker)
+	tracker.close()
+
+	finished := time.Now().UTC()
+	c.cacheSyncJobs.update(id, func(j *CacheSyncJob) {
+		j.FinishedAt = &finished
+		j.State = CacheSyncJobCompleted
+		if err != nil {
+			j.State = CacheSyncJobFailed
+			j.Error = err.Error()
 		}
+	})
 
-		// A sync is running and another is already queued, drop this request
-		log.Debug().Msg("Cache sync request dropped - queue full")
-		return false
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "cache sync failed")
+		log.Error().Err(err).Str("job_id", id).Msg("Cache sync failed")
+		return
 	}
-
-	return false
+	log.Info().
+		Str("job_id", id).
+		Dur("duration", finished.Sub(started)).
+		Msg("Cache sync completed successfully")
 }
 
 // completeCacheSync updates the cache sync state when a sync completes
@@ -128,7 +278,74 @@ func (c *PondCollector) WaitForCacheSyncCompletion() {
 	c.cacheSyncWaitGroup.Wait()
 }
 
-func syncToCache(ctx context.Context) error {
+// runCacheSync refreshes the cache and bloom from the DB for scope, then
+// recompiles the edge dataset when one is configured. A scoped sync in a
+// process without a bloom filter yet is a full one, since changes can only
+// be applied to a built filter.
+func runCacheSync(ctx context.Context, scope CacheSyncScope, progress *syncTracker) error {
+	_, bloomErr := cache.GetBloomFilter()
+	started := time.Now()
+
+	var err error
+	switch scope.Mode {
+	case CacheSyncDelta, CacheSyncSource:
+		if bloomErr == nil {
+			err = syncChangedToCache(ctx, scope, progress)
+			break
+		}
+		log.Info().Str("mode", string(scope.Mode)).Msg("No bloom filter built yet, running a full cache sync")
+		fallthrough
+	default:
+		scope = CacheSyncScope{Mode: CacheSyncFull}
+		err = syncToCache(ctx, progress)
+	}
+	if err != nil {
+		return err
+	}
+	markCacheSynced(scope, started)
+
+	if path := config.GetConfig().Edge.DatasetPath; path != "" {
+		_db, err := db.GetDB()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to connect to database")
+			return err
+		}
+		// A failed export keeps the previous dataset in place; the cache sync itself succeeded.
+		if _, err := edgeset.Compile(ctx, repository.NewSQLiteRepository(_db), path); err != nil {
+			log.Error().Err(err).Str("path", path).Msg("Edge dataset export failed")
+		}
+	}
+
+	return nil
+}
+
+// markCacheSynced moves the sync mark of a cache kept on disk to started,
+// when the sync of scope leaves it holding every entry written before: after
+// a full sync, or a delta sync from at most the previous mark. A cache with
+// a TTL only holds looked-up keys and is never marked.
+func markCacheSynced(scope CacheSyncScope, started time.Time) {
+	pc, ok := cache.GetPersistentCache()
+	if !ok || config.GetConfig().Cache.TTL != nil {
+		return
+	}
+
+	switch scope.Mode {
+	case CacheSyncFull:
+	case CacheSyncDelta:
+		syncedAt, err := pc.SyncedAt()
+		if err != nil || syncedAt.IsZero() || scope.Since > syncedAt.UnixNano() {
+			return
+		}
+	default:
+		return
+	}
+
+	if err := pc.MarkSynced(started); err != nil {
+		log.Warn().Err(err).Msg("Failed to record the cache sync mark")
+	}
+}
+
+func syncToCache(ctx context.Context, progress *syncTracker) error {
 	cacheProvider, err := cache.GetCacheProvider()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get cache provider")
@@ -143,9 +360,16 @@ func syncToCache(ctx context.Context) error {
 
 	repo := repository.NewSQLiteRepository(_db)
 
+	// Counting first gives progress a total to report against
+	count, err := repo.StreamEntriesCount(ctx)
+	if err != nil {
+		return err
+	}
+	progress.expect(count)
+
 	ch := make(chan entries.EntryStream)
 
-	log.Debug().Msg("Starting to stream entries from repository")
+	log.Debug().Int("expected_count", count).Msg("Starting to stream entries from repository")
 
 	go func() {
 		err := repo.StreamEntries(ctx, ch)
@@ -160,20 +384,21 @@ func syncToCache(ctx context.Context) error {
 		log.Debug().Msg("Cache will be filled and bloom will be built directly from DB channel")
 
 		// Drain channel into Badger while building bloom
-		count := 0
+		synced := 0
 		for {
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
 			case entry, ok := <-ch:
 				if !ok {
-					log.Debug().Int("processed_count", count).Msg("Finished streaming entries to cache")
+					log.Debug().Int("processed_count", synced).Msg("Finished streaming entries to cache")
 					if err := cacheProvider.Commit(); err != nil {
 						log.Error().Err(err).Msg("Failed to commit cache changes")
 						return err
 					}
 					log.Debug().Msg("Cache changes committed")
-					cache.BuildBloomFilterFromCacheProvider(ctx, cacheProvider, count)
+					progress.done(synced, 0)
+					cache.BuildBloomFilterFromCacheProvider(ctx, cacheProvider, synced)
 					log.Debug().Msg("Bloom filter built from cache provider")
 					return nil
 				}
@@ -182,22 +407,99 @@ func syncToCache(ctx context.Context) error {
 					log.Error().Err(err).Str("key", entry.SourceUrl).Msg("Failed to set entry in cache")
 					return err
 				}
-				count++
-				if count%50000 == 0 {
-					log.Info().Int("processed_count", count).Msg("Cache sync progress")
-				}
+				synced++
+				progress.report(synced, 0)
 			}
 		}
 	}
 
-	count, err := repo.StreamEntriesCount(ctx)
+	log.Debug().Int("Stream Entry Count", count).Msg("Bloom will be builded from db channel")
+
+	// this option does not set any items to cache just builds bloom
+	// requests will responsible for adding items to cache when items not found on cache but in db with ttl
+	added, err := cache.BuildBloomFromChannel(ctx, count, ch, func(added int) {
+		progress.report(added, 0)
+	})
+	progress.done(added, 0)
+	return err
+}
+
+// syncChangedToCache resyncs only the source URLs selected by scope: URLs
+// still listed are rewritten (or, with a TTL cache, invalidated so the next
+// lookup reloads them) and added to the bloom; URLs no source lists anymore
+// are deleted from the cache.
+func syncChangedToCache(ctx context.Context, scope CacheSyncScope, progress *syncTracker) error {
+	cacheProvider, err := cache.GetCacheProvider()
 	if err != nil {
+		log.Error().Err(err).Msg("Failed to get cache provider")
 		return err
 	}
 
-	log.Debug().Int("Stream Entry Count", count).Msg("Bloom will be builded from db channel")
+	_db, err := db.GetDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to connect to database")
+		return err
+	}
 
-	// this option does not set any items to cache just builds bloom
-	// requests will responsible for adding items to cache when items not found on cache but in db with ttl
-	return cache.BuildBloomFromChannel(ctx, count, ch)
+	repo := repository.NewSQLiteRepository(_db)
+
+	expected, err := repo.CountChangedSourceURLs(ctx, scope.Source, scope.Since)
+	if err != nil {
+		return err
+	}
+	progress.expect(expected)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch := make(chan entries.EntryStream)
+	streamErr := make(chan error, 1)
+	go func() {
+		streamErr <- repo.StreamChangedSourceURLs(ctx, scope.Source, scope.Since, ch)
+	}()
+
+	lazy := config.GetConfig().Cache.TTL != nil
+	synced, removed := 0, 0
+	for entry := range ch {
+		if entry.IDsRaw == "" || lazy {
+			err = cacheProvider.Delete(entry.SourceUrl)
+		} else {
+			err = cacheProvider.Set(entry.SourceUrl, entry.IDsRaw)
+		}
+		if err != nil {
+			log.Error().Err(err).Str("key", entry.SourceUrl).Msg("Failed to update entry in cache")
+			return err
+		}
+
+		if entry.IDsRaw == "" {
+			if err := cache.RemoveFromBloomFilter(entry.SourceUrl); err != nil {
+				log.Warn().Err(err).Msg("Bloom filter not available during scoped cache sync")
+			}
+			removed++
+		} else {
+			if err := cache.AddToBloomFilter(entry.SourceUrl); err != nil {
+				log.Warn().Err(err).Msg("Bloom filter not available during scoped cache sync")
+			}
+			synced++
+		}
+
+		progress.report(synced, removed)
+	}
+
+	if err := <-streamErr; err != nil {
+		return err
+	}
+
+	if err := cacheProvider.Commit(); err != nil {
+		log.Error().Err(err).Msg("Failed to commit cache changes")
+		return err
+	}
+
+	progress.done(synced, removed)
+	log.Debug().
+		Int("synced", synced).
+		Int("removed", removed).
+		Str("mode", string(scope.Mode)).
+		Msg("Scoped cache sync finished")
+	return nil
 }
@@ -0,0 +1,55 @@
+package entry_collector
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	idb "blacked/internal/db"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestBatchSaveTracedUnderProvider(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prev)
+
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	c := &PondCollector{
+		repo:          repository.NewSQLiteRepository(conn),
+		buffers:       make(map[string]*sourceBuffer),
+		providerStats: make(map[string]*ProviderStats),
+		deltas:        make(map[string]*deltaRun),
+	}
+
+	ctx, parse := otel.Tracer("test").Start(context.Background(), "provider.parse")
+	c.StartProviderProcessing(ctx, "trace-test", "p1")
+
+	entry := entries.NewEntry().WithSource("trace-test").WithProcessID("p1")
+	require.NoError(t, entry.SetURL("https://bad.example/login"))
+	c.providerStats["trace-test"].pendingOperations.Add(1)
+	c.processBatch("trace-test", []*entries.Entry{entry})
+	parse.End()
+
+	byName := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range recorder.Ended() {
+		byName[s.Name()] = s
+	}
+	require.Contains(t, byName, "collector.batch_save")
+	require.Contains(t, byName, "repository.batch_save")
+
+	batch := byName["collector.batch_save"]
+	assert.Equal(t, parse.SpanContext().TraceID(), batch.SpanContext().TraceID())
+	assert.Equal(t, parse.SpanContext().SpanID(), batch.Parent().SpanID())
+	assert.Equal(t, batch.SpanContext().SpanID(), byName["repository.batch_save"].Parent().SpanID())
+}
@@ -3,6 +3,8 @@ package entry_collector
 import (
 	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ProviderStats tracks metrics for a specific provider
@@ -11,5 +13,6 @@ type ProviderStats struct {
 	startTime         time.Time
 	processID         string
 	active            bool
-	pendingOperations sync.WaitGroup // Track pending operations
+	pendingOperations sync.WaitGroup    // Track pending operations
+	trace             trace.SpanContext // Parent of the batch save spans
 }
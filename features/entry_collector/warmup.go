@@ -0,0 +1,163 @@
+package entry_collector
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// warmupPoll is how often a warm-up sync is polled for completion.
+	warmupPoll = time.Second
+	// warmupRetryDelay is the pause before a failed warm-up sync is retried.
+	warmupRetryDelay = 30 * time.Second
+)
+
+// WarmupStatus reports the startup cache warm-up that gates readiness.
+type WarmupStatus struct {
+	Ready     bool          `json:"ready"`
+	StartedAt *time.Time    `json:"started_at,omitempty"`
+	ReadyAt   *time.Time    `json:"ready_at,omitempty"`
+	Attempts  int           `json:"attempts,omitempty"` // Warm-up syncs started, retries included
+	Error     string        `json:"error,omitempty"`    // Error of the last failed sync
+	Sync      *CacheSyncJob `json:"sync,omitempty"`     // Sync being waited for, with its progress
+}
+
+// warmup is the state behind WarmupStatus, guarded by cacheSyncMutex.
+type warmup struct {
+	started  *time.Time
+	ready    *time.Time
+	attempts int
+	err      string
+	jobID    string
+}
+
+// BeginWarmup marks the instance not ready until EndWarmup.
+func (c *PondCollector) BeginWarmup() {
+	c.cacheSyncMutex.Lock()
+	defer c.cacheSyncMutex.Unlock()
+
+	now := time.Now().UTC()
+	c.warmup = &warmup{started: &now}
+	log.Info().Msg("Cache warm-up started, not ready until it completes")
+}
+
+// EndWarmup marks the instance ready.
+func (c *PondCollector) EndWarmup() {
+	c.cacheSyncMutex.Lock()
+	defer c.cacheSyncMutex.Unlock()
+
+	if c.warmup == nil || c.warmup.ready != nil {
+		return
+	}
+	now := time.Now().UTC()
+	c.warmup.ready = &now
+	c.warmup.jobID = ""
+	log.Info().
+		Dur("duration", now.Sub(*c.warmup.started)).
+		Int("attempts", c.warmup.attempts).
+		Msg("Cache warm-up completed, ready for traffic")
+}
+
+// WarmupStatus returns the warm-up state; an instance that never began a
+// warm-up is ready.
+func (c *PondCollector) WarmupStatus() WarmupStatus {
+	c.cacheSyncMutex.Lock()
+	w := c.warmup
+	if w == nil {
+		c.cacheSyncMutex.Unlock()
+		return WarmupStatus{Ready: true}
+	}
+	status := WarmupStatus{
+		Ready:     w.ready != nil,
+		StartedAt: w.started,
+		ReadyAt:   w.ready,
+		Attempts:  w.attempts,
+		Error:     w.err,
+	}
+	jobID := w.jobID
+	c.cacheSyncMutex.Unlock()
+
+	if job, ok := c.cacheSyncJobs.get(jobID); ok {
+		status.Sync = &job
+	}
+	return status
+}
+
+// WarmCacheSync runs a sync of scope for the warm-up and waits for it. A
+// failed sync is retried as a full one every warmupRetryDelay, until one
+// completes or ctx is done.
+func (c *PondCollector) WarmCacheSync(ctx context.Context, scope CacheSyncScope) error {
+	for {
+		id, ok := c.scheduleCacheSync(ctx, scope, false)
+		if !ok {
+			// The queue is full; try again once the running sync moved on.
+			if err := sleepCtx(ctx, warmupPoll); err != nil {
+				return err
+			}
+			continue
+		}
+		c.setWarmupJob(id)
+
+		job, err := c.awaitCacheSyncJob(ctx, id)
+		if err != nil {
+			return err
+		}
+		if job.State != CacheSyncJobFailed {
+			return nil
+		}
+
+		c.cacheSyncMutex.Lock()
+		if c.warmup != nil {
+			c.warmup.err = job.Error
+		}
+		c.cacheSyncMutex.Unlock()
+		log.Error().Str("job_id", id).Str("error", job.Error).Dur("retry_in", warmupRetryDelay).Msg("Cache warm-up sync failed, retrying with a full sync")
+
+		scope = CacheSyncScope{Mode: CacheSyncFull}
+		if err := sleepCtx(ctx, warmupRetryDelay); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *PondCollector) setWarmupJob(id string) {
+	c.cacheSyncMutex.Lock()
+	defer c.cacheSyncMutex.Unlock()
+
+	if c.warmup != nil {
+		c.warmup.jobID = id
+		c.warmup.attempts++
+	}
+}
+
+// awaitCacheSyncJob polls the job with id until it finished. A job no longer
+// tracked finished long ago; it is reported as completed. Syncs skipped in
+// run modes without a cache have no id and complete at once.
+func (c *PondCollector) awaitCacheSyncJob(ctx context.Context, id string) (CacheSyncJob, error) {
+	for {
+		job, ok := c.cacheSyncJobs.get(id)
+		if !ok {
+			return CacheSyncJob{ID: id, State: CacheSyncJobCompleted}, nil
+		}
+		if job.State == CacheSyncJobCompleted || job.State == CacheSyncJobFailed {
+			return job, nil
+		}
+		if err := sleepCtx(ctx, warmupPoll); err != nil {
+			return job, err
+		}
+	}
+}
+
+// sleepCtx waits for d, or returns the error of ctx when it is done first.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
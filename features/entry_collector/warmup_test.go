@@ -0,0 +1,53 @@
+package entry_collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmupGatesReadiness(t *testing.T) {
+	c := &PondCollector{cacheSyncJobs: newCacheSyncJobs()}
+	assert.True(t, c.WarmupStatus().Ready, "an instance without a warm-up is ready")
+
+	c.BeginWarmup()
+	id := c.cacheSyncJobs.add(CacheSyncScope{Mode: CacheSyncFull})
+	c.cacheSyncJobs.update(id, func(j *CacheSyncJob) {
+		j.State = CacheSyncJobRunning
+		j.Progress = computeSyncProgress(100, 40, 0)
+	})
+	c.setWarmupJob(id)
+
+	status := c.WarmupStatus()
+	assert.False(t, status.Ready)
+	assert.Equal(t, 1, status.Attempts)
+	require.NotNil(t, status.Sync)
+	assert.Equal(t, id, status.Sync.ID)
+	assert.Equal(t, 40.0, status.Sync.Progress.Percent)
+
+	c.cacheSyncJobs.update(id, func(j *CacheSyncJob) {
+		j.State = CacheSyncJobFailed
+		j.Error = "disk full"
+	})
+	job, err := c.awaitCacheSyncJob(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, CacheSyncJobFailed, job.State)
+
+	c.EndWarmup()
+	status = c.WarmupStatus()
+	assert.True(t, status.Ready)
+	assert.NotNil(t, status.ReadyAt)
+	assert.Nil(t, status.Sync)
+}
+
+func TestWarmCacheSyncWithoutCache(t *testing.T) {
+	c := &PondCollector{cacheSyncJobs: newCacheSyncJobs()}
+	c.DisableCacheSync()
+	c.BeginWarmup()
+
+	require.NoError(t, c.WarmCacheSync(context.Background(), CacheSyncScope{Mode: CacheSyncFull}))
+	c.EndWarmup()
+	assert.True(t, c.WarmupStatus().Ready)
+}
@@ -0,0 +1,262 @@
+package eventbus
+
+import (
+	"blacked/features/replication"
+	"blacked/internal/collector"
+	"blacked/internal/config"
+	"blacked/internal/db"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrDatabaseConnection = errors.New("failed to connect to the database")
+
+var (
+	globalBus *Bus
+	globalMu  sync.RWMutex
+)
+
+// Bus tails the entry change feed into a Publisher and publishes provider
+// runs as they finish.
+type Bus struct {
+	cfg  config.EventBusConfig
+	sink string
+	pub  Publisher
+	repo Repository
+
+	mu    sync.Mutex // Serializes polls
+	state *State     // nil until read from the repository
+}
+
+// Init creates the bus of cfg on the write database connection and makes it
+// the one Get returns.
+func Init(cfg config.EventBusConfig) (*Bus, error) {
+	dbConn, err := db.GetWriteDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+	pub, err := NewPublisher(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := NewBusWithRepository(cfg, pub, NewSQLiteRepository(dbConn))
+	globalMu.Lock()
+	globalBus = bus
+	globalMu.Unlock()
+	return bus, nil
+}
+
+// Get returns the bus created by Init; nil when publishing is disabled.
+func Get() *Bus {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return globalBus
+}
+
+// NewBusWithRepository creates a Bus publishing through pub, reading the
+// change feed from repo.
+func NewBusWithRepository(cfg config.EventBusConfig, pub Publisher, repo Repository) *Bus {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = replication.DefaultLimit
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 2 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &Bus{cfg: cfg, sink: sink(cfg), pub: pub, repo: repo}
+}
+
+// Run publishes entry changes until ctx is done: back to back while behind,
+// every Interval once caught up or after a failed poll.
+func (b *Bus) Run(ctx context.Context) {
+	log.Info().Str("sink", b.sink).Dur("interval", b.cfg.Interval).Msg("Event bus publisher started")
+
+	for {
+		caughtUp, err := b.Poll(ctx)
+		if err != nil {
+			log.Warn().Err(err).Str("sink", b.sink).Msg("Event bus poll failed")
+		}
+		if err == nil && !caughtUp {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Event bus publisher stopped")
+			return
+		case <-time.After(b.cfg.Interval):
+		}
+	}
+}
+
+// Drain publishes entry changes until caught up, for one-shot runs that
+// exit once their writes are done.
+func (b *Bus) Drain(ctx context.Context) error {
+	for {
+		caughtUp, err := b.Poll(ctx)
+		if err != nil || caughtUp {
+			return err
+		}
+	}
+}
+
+// Poll publishes one page of entry changes and moves the cursor past it. It
+// reports whether the feed is exhausted. A sink without a cursor starts at
+// the head of the feed, so enabling the bus does not replay history.
+func (b *Bus) Poll(ctx context.Context) (caughtUp bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	defer func() {
+		if err != nil {
+			if mc, _ := collector.GetMetricsCollector(); mc != nil {
+				mc.IncrementEventBusErrors()
+			}
+		}
+	}()
+
+	state, err := b.loadState(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	feed, err := b.repo.Changes(ctx, state.Cursor, b.cfg.BatchSize)
+	if err != nil {
+		return false, err
+	}
+	if len(feed.Changes) == 0 {
+		b.observe(nil, feed.Head.Seq-state.Cursor)
+		return true, nil
+	}
+
+	events := make([]Event, 0, len(feed.Changes))
+	next := state
+	for _, ch := range feed.Changes {
+		events = append(events, entryEvent(ch, state.Watermark))
+		next = State{Cursor: ch.Seq, Watermark: ch.ChangedAt}
+	}
+
+	pubCtx, cancel := context.WithTimeout(ctx, b.cfg.Timeout)
+	defer cancel()
+	if err := b.pub.Publish(pubCtx, events); err != nil {
+		return false, err
+	}
+	// Events are published before the cursor moves: a crash in between
+	// publishes them again, never drops them.
+	if err := b.repo.SetState(ctx, b.sink, next); err != nil {
+		return false, err
+	}
+	b.state = &next
+
+	b.observe(events, feed.Head.Seq-next.Cursor)
+	log.Debug().Str("sink", b.sink).Int("events", len(events)).Int64("cursor", next.Cursor).Msg("Published entry changes")
+	return next.Cursor >= feed.Head.Seq, nil
+}
+
+func (b *Bus) loadState(ctx context.Context) (State, error) {
+	if b.state != nil {
+		return *b.state, nil
+	}
+
+	state, ok, err := b.repo.State(ctx, b.sink)
+	if err != nil {
+		return State{}, err
+	}
+	if !ok {
+		head, err := b.repo.Head(ctx)
+		if err != nil {
+			return State{}, err
+		}
+		state = State{Cursor: head.Seq, Watermark: head.ChangedAt}
+		if err := b.repo.SetState(ctx, b.sink, state); err != nil {
+			return State{}, err
+		}
+		log.Info().Str("sink", b.sink).Int64("cursor", state.Cursor).Msg("Event bus starts at the head of the change feed")
+	}
+	b.state = &state
+	return state, nil
+}
+
+// createdWindow bounds the gap between the activation and the last update
+// of an entry written by the run that listed it: both are stamped as the
+// entry is parsed. Later runs and edits stamp a later update.
+const createdWindow = time.Second
+
+// entryEvent classifies a change. An entry is new, or listed again after a
+// removal, when it was last written by the run that listed it, or listed
+// after the last change published, so the bus never announced the listing.
+func entryEvent(ch replication.Change, watermark int64) Event {
+	ev := Event{
+		ID:      strconv.FormatInt(ch.Seq, 10) + "-" + ch.EntryID, // Stable across republishes
+		Time:    time.Unix(0, ch.ChangedAt).UTC(),
+		Seq:     ch.Seq,
+		EntryID: ch.EntryID,
+		Entry:   ch.Entry,
+	}
+	switch e := ch.Entry; {
+	case e == nil:
+		ev.Type = EventEntryDeleted
+	case e.DeletedAt != nil:
+		ev.Type = EventEntrySoftDeleted
+	case e.UpdatedAt-max(e.ActivatedAt, e.CreatedAt) < int64(createdWindow), max(e.ActivatedAt, e.CreatedAt) > watermark:
+		ev.Type = EventEntryCreated
+	default:
+		ev.Type = EventEntryUpdated
+	}
+	return ev
+}
+
+// PublishSync publishes a sync.completed event for a finished run, and a
+// provider.failed event when it failed.
+func (b *Bus) PublishSync(ctx context.Context, sc SyncCompleted) error {
+	events := []Event{{Type: EventSyncCompleted, ID: uuid.NewString(), Time: sc.FinishedAt, Sync: &sc}}
+	if sc.Status == "failed" {
+		events = append(events, Event{Type: EventProviderFailed, ID: uuid.NewString(), Time: sc.FinishedAt, Sync: &sc})
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.cfg.Timeout)
+	defer cancel()
+	if err := b.pub.Publish(ctx, events); err != nil {
+		if mc, _ := collector.GetMetricsCollector(); mc != nil {
+			mc.IncrementEventBusErrors()
+		}
+		return err
+	}
+	b.observe(events, -1)
+	return nil
+}
+
+// observe records published events by type and the changes left, if known.
+func (b *Bus) observe(events []Event, lag int64) {
+	mc, _ := collector.GetMetricsCollector()
+	if mc == nil {
+		return
+	}
+	counts := map[string]int{}
+	for _, ev := range events {
+		counts[ev.Type]++
+	}
+	for t, n := range counts {
+		mc.IncrementEventBusPublished(t, n)
+	}
+	if lag >= 0 {
+		mc.SetEventBusLag(lag)
+	}
+}
+
+// Close closes the publisher.
+func (b *Bus) Close() error {
+	return b.pub.Close()
+}
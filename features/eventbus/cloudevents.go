@@ -0,0 +1,74 @@
+package eventbus
+
+import (
+	"blacked/features/entries"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// CloudEvents envelope of every message on the bus, in structured mode.
+const (
+	CloudEventsSpecVersion   = "1.0"
+	CloudEventsContentType   = "application/cloudevents+json; charset=utf-8"
+	CloudEventsTypePrefix    = "com.github.runaho.blacked." // Prefixes Event.Type in the envelope
+	CloudEventsDefaultSource = "/blacked"
+)
+
+// Data schemas and their versions. A version changes only when a field is
+// removed or changes meaning; added fields keep it.
+const (
+	SchemaEntry = "urn:blacked:schema:entry:v1" // entry.* events: EntryData
+	SchemaSync  = "urn:blacked:schema:sync:v1"  // sync.completed, provider.failed: SyncCompleted
+)
+
+// CloudEvent is an Event in the CloudEvents 1.0 JSON format. Subject and the
+// partitionkey extension name the entry or provider; the sequence extension
+// is the entry change feed position.
+type CloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	DataSchema      string    `json:"dataschema"`
+	Sequence        string    `json:"sequence,omitempty"`
+	PartitionKey    string    `json:"partitionkey,omitempty"`
+	Data            any       `json:"data"`
+}
+
+// EntryData is the data of entry events; entry.deleted only has the ID.
+type EntryData struct {
+	EntryID string         `json:"entry_id"`
+	Entry   *entries.Entry `json:"entry,omitempty"`
+}
+
+// CloudEvent returns the envelope of e, published from source.
+func (e Event) CloudEvent(source string) CloudEvent {
+	ce := CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              e.ID,
+		Source:          source,
+		Type:            CloudEventsTypePrefix + e.Type,
+		Subject:         e.Key(),
+		Time:            e.Time,
+		DataContentType: "application/json",
+		PartitionKey:    e.Key(),
+	}
+	if e.Sync != nil {
+		ce.DataSchema, ce.Data = SchemaSync, e.Sync
+		return ce
+	}
+	ce.DataSchema, ce.Data = SchemaEntry, EntryData{EntryID: e.EntryID, Entry: e.Entry}
+	if e.Seq > 0 {
+		ce.Sequence = strconv.FormatInt(e.Seq, 10)
+	}
+	return ce
+}
+
+// marshal encodes e as a structured-mode CloudEvent.
+func marshal(e Event, source string) ([]byte, error) {
+	return json.Marshal(e.CloudEvent(source))
+}
@@ -0,0 +1,116 @@
+// Package eventbus publishes entry changes and provider runs to a message
+// bus, Kafka or NATS, for downstream SIEM pipelines. Entry events are read
+// from the entry change feed replicas poll, so nothing written while the bus
+// is down is lost; sync.completed events are published as runs finish.
+// Events are sent as CloudEvents, so Knative, EventBridge and other routers
+// consume them without adapters.
+package eventbus
+
+import (
+	"blacked/features/entries"
+	"blacked/internal/config"
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Event types.
+const (
+	EventEntryCreated     = "entry.created"      // New entry, or a removed one listed again
+	EventEntryUpdated     = "entry.updated"      // Category, confidence or URL fields changed
+	EventEntrySoftDeleted = "entry.soft_deleted" // No longer listed by its source
+	EventEntryDeleted     = "entry.deleted"      // Row removed, e.g. by a provider removal
+	EventSyncCompleted    = "sync.completed"
+	EventProviderFailed   = "provider.failed" // Published with sync.completed when a run fails
+)
+
+// Drivers.
+const (
+	DriverKafka = "kafka"
+	DriverNATS  = "nats"
+)
+
+var (
+	ErrUnknownDriver = errors.New("unknown event bus driver")
+	ErrInvalidConfig = errors.New("invalid event bus configuration")
+	ErrPublish       = errors.New("failed to publish events")
+)
+
+// Event is one message on the bus, published as its CloudEvent. Entry
+// events carry the entry as it is now, except entry.deleted, which only has
+// its ID.
+type Event struct {
+	Type    string         `json:"type"`
+	ID      string         `json:"id"`
+	Time    time.Time      `json:"time"`
+	Seq     int64          `json:"seq,omitempty"` // Position in the entry change feed
+	EntryID string         `json:"entry_id,omitempty"`
+	Entry   *entries.Entry `json:"entry,omitempty"`
+	Sync    *SyncCompleted `json:"sync,omitempty"`
+}
+
+// Key returns the partition key of the event, so the events of one entry or
+// provider stay in order.
+func (e Event) Key() string {
+	if e.Sync != nil {
+		return e.Sync.Provider
+	}
+	return e.EntryID
+}
+
+// SyncCompleted reports a finished provider run.
+type SyncCompleted struct {
+	Provider         string    `json:"provider"`
+	ProcessID        string    `json:"process_id"`
+	Status           string    `json:"status"` // "completed" or "failed"
+	Error            string    `json:"error,omitempty"`
+	EntriesProcessed int       `json:"entries_processed"`
+	DurationMS       int64     `json:"duration_ms"`
+	StartedAt        time.Time `json:"started_at"`
+	FinishedAt       time.Time `json:"finished_at"`
+}
+
+// Publisher sends events to a bus. Publish returns once the bus accepted
+// every event, or fails as a whole; events may then be sent again.
+type Publisher interface {
+	Publish(ctx context.Context, events []Event) error
+	Close() error
+}
+
+// drivers creates the publisher of each driver.
+var drivers = map[string]func(cfg config.EventBusConfig) (Publisher, error){
+	DriverKafka: newKafkaPublisher,
+	DriverNATS:  newNATSPublisher,
+}
+
+// Enabled reports whether an event bus is configured.
+func Enabled(cfg config.EventBusConfig) bool {
+	return cfg.Driver != ""
+}
+
+// NewPublisher creates the publisher of cfg.Driver.
+func NewPublisher(cfg config.EventBusConfig) (Publisher, error) {
+	newPublisher, ok := drivers[strings.ToLower(cfg.Driver)]
+	if !ok {
+		return nil, errors.Join(ErrUnknownDriver, errors.New(cfg.Driver))
+	}
+	if cfg.Topic == "" {
+		return nil, errors.Join(ErrInvalidConfig, errors.New("topic is required"))
+	}
+	return newPublisher(cfg)
+}
+
+// source is the CloudEvents source of cfg's events.
+func source(cfg config.EventBusConfig) string {
+	if cfg.Source == "" {
+		return CloudEventsDefaultSource
+	}
+	return cfg.Source
+}
+
+// sink names the destination of cfg, keying its cursor in the change feed,
+// so pointing the bus elsewhere starts over from the head of the feed.
+func sink(cfg config.EventBusConfig) string {
+	return strings.ToLower(cfg.Driver) + ":" + cfg.Topic
+}
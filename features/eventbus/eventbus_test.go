@@ -0,0 +1,180 @@
+package eventbus
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/internal/config"
+	idb "blacked/internal/db"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePublisher struct {
+	events []Event
+	fail   bool
+}
+
+func (p *fakePublisher) Publish(_ context.Context, events []Event) error {
+	if p.fail {
+		return ErrPublish
+	}
+	p.events = append(p.events, events...)
+	return nil
+}
+
+func (p *fakePublisher) Close() error { return nil }
+
+func (p *fakePublisher) types() []string {
+	var out []string
+	for _, ev := range p.events {
+		out = append(out, ev.Type)
+	}
+	return out
+}
+
+func TestBusPublishesEntryChanges(t *testing.T) {
+	ctx := context.Background()
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+	entryRepo := repository.NewSQLiteRepository(conn)
+
+	save := func(processID string, links ...string) []*entries.Entry {
+		var batch []*entries.Entry
+		for _, link := range links {
+			e, err := entries.FromURL(link, "feed", processID)
+			require.NoError(t, err)
+			e.WithCategory("phishing")
+			batch = append(batch, e)
+		}
+		require.NoError(t, entryRepo.BatchSaveEntries(ctx, batch))
+		return batch
+	}
+
+	save("p0", "https://old.example.com/") // Listed before the bus started: not replayed
+
+	cfg := config.EventBusConfig{Driver: DriverKafka, Topic: "blacked.events", BatchSize: 2}
+	pub := &fakePublisher{}
+	repo := NewSQLiteRepository(conn)
+	bus := NewBusWithRepository(cfg, pub, repo)
+
+	caughtUp, err := bus.Poll(ctx)
+	require.NoError(t, err)
+	assert.True(t, caughtUp)
+	assert.Empty(t, pub.events)
+
+	time.Sleep(time.Millisecond)
+	added := save("p1", "https://login.evil.com/a", "https://sso.evil.com/b", "https://cdn.evil.com/c")
+	caughtUp, err = bus.Poll(ctx)
+	require.NoError(t, err)
+	assert.False(t, caughtUp, "a batch of two leaves one change")
+	require.NoError(t, bus.Drain(ctx))
+
+	assert.Equal(t, []string{EventEntryCreated, EventEntryCreated, EventEntryCreated}, pub.types())
+	assert.Equal(t, added[0].ID, pub.events[0].EntryID)
+	assert.Equal(t, added[0].ID, pub.events[0].Key())
+	require.NotNil(t, pub.events[0].Entry)
+	assert.Equal(t, "login.evil.com", pub.events[0].Entry.Host)
+
+	// Soft delete, and a category change by a later run, of entries already announced.
+	time.Sleep(time.Millisecond)
+	require.NoError(t, entryRepo.SoftDeleteEntryByID(ctx, added[0].ID))
+	later := time.Now().Add(time.Hour).UnixNano()
+	_, err = conn.ExecContext(ctx, "UPDATE entries SET category = 'malware', updated_at = ? WHERE id = ?", later, added[1].ID)
+	require.NoError(t, err)
+	require.NoError(t, bus.Drain(ctx))
+	assert.Equal(t, []string{EventEntrySoftDeleted, EventEntryUpdated}, pub.types()[3:])
+
+	// A failed publish keeps the cursor; the changes are published again.
+	_, err = conn.ExecContext(ctx, "DELETE FROM entries WHERE id = ?", added[2].ID)
+	require.NoError(t, err)
+	pub.fail = true
+	_, err = bus.Poll(ctx)
+	assert.ErrorIs(t, err, ErrPublish)
+	pub.fail = false
+
+	// A restarted bus resumes from the saved cursor.
+	restarted := NewBusWithRepository(cfg, pub, repo)
+	require.NoError(t, restarted.Drain(ctx))
+	require.Len(t, pub.events, 6)
+	assert.Equal(t, EventEntryDeleted, pub.events[5].Type)
+	assert.Nil(t, pub.events[5].Entry)
+}
+
+func TestPublishSync(t *testing.T) {
+	pub := &fakePublisher{}
+	bus := NewBusWithRepository(config.EventBusConfig{Driver: DriverNATS, Topic: "blacked"}, pub, nil)
+
+	require.NoError(t, bus.PublishSync(context.Background(), SyncCompleted{Provider: "OISD", ProcessID: "p1", Status: "completed", EntriesProcessed: 10}))
+	require.Len(t, pub.events, 1)
+	assert.Equal(t, EventSyncCompleted, pub.events[0].Type)
+	assert.Equal(t, "OISD", pub.events[0].Key())
+	assert.Equal(t, 10, pub.events[0].Sync.EntriesProcessed)
+}
+
+func TestPublishSyncFailed(t *testing.T) {
+	pub := &fakePublisher{}
+	bus := NewBusWithRepository(config.EventBusConfig{Driver: DriverNATS, Topic: "blacked"}, pub, nil)
+
+	require.NoError(t, bus.PublishSync(context.Background(), SyncCompleted{Provider: "OISD", ProcessID: "p1", Status: "failed", Error: "boom"}))
+	assert.Equal(t, []string{EventSyncCompleted, EventProviderFailed}, pub.types())
+	assert.Equal(t, "boom", pub.events[1].Sync.Error)
+}
+
+func TestCloudEvent(t *testing.T) {
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	e, err := entries.FromURL("https://login.evil.com/a", "feed", "p1")
+	require.NoError(t, err)
+
+	decode := func(ev Event) map[string]any {
+		data, err := marshal(ev, source(config.EventBusConfig{}))
+		require.NoError(t, err)
+		var out map[string]any
+		require.NoError(t, json.Unmarshal(data, &out))
+		return out
+	}
+
+	created := decode(Event{Type: EventEntryCreated, ID: "7-" + e.ID, Time: now, Seq: 7, EntryID: e.ID, Entry: e})
+	assert.Equal(t, "1.0", created["specversion"])
+	assert.Equal(t, "7-"+e.ID, created["id"])
+	assert.Equal(t, "/blacked", created["source"])
+	assert.Equal(t, "com.github.runaho.blacked.entry.created", created["type"])
+	assert.Equal(t, e.ID, created["subject"])
+	assert.Equal(t, e.ID, created["partitionkey"])
+	assert.Equal(t, "7", created["sequence"])
+	assert.Equal(t, "2026-10-17T09:00:00Z", created["time"])
+	assert.Equal(t, "application/json", created["datacontenttype"])
+	assert.Equal(t, SchemaEntry, created["dataschema"])
+	data := created["data"].(map[string]any)
+	assert.Equal(t, e.ID, data["entry_id"])
+	assert.Equal(t, "login.evil.com", data["entry"].(map[string]any)["host"])
+
+	deleted := decode(Event{Type: EventEntryDeleted, ID: "8-" + e.ID, Time: now, Seq: 8, EntryID: e.ID})
+	assert.Equal(t, map[string]any{"entry_id": e.ID}, deleted["data"])
+
+	failed := decode(Event{Type: EventProviderFailed, ID: "x", Time: now, Sync: &SyncCompleted{Provider: "OISD", Status: "failed"}})
+	assert.Equal(t, "com.github.runaho.blacked.provider.failed", failed["type"])
+	assert.Equal(t, "OISD", failed["subject"])
+	assert.Equal(t, SchemaSync, failed["dataschema"])
+	assert.NotContains(t, failed, "sequence")
+	assert.Equal(t, "OISD", failed["data"].(map[string]any)["provider"])
+}
+
+func TestNewPublisherConfig(t *testing.T) {
+	_, err := NewPublisher(config.EventBusConfig{Driver: "rabbitmq", Topic: "blacked"})
+	assert.ErrorIs(t, err, ErrUnknownDriver)
+	_, err = NewPublisher(config.EventBusConfig{Driver: DriverKafka, Topic: "blacked"})
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	_, err = NewPublisher(config.EventBusConfig{Driver: DriverNATS})
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	pub, err := NewPublisher(config.EventBusConfig{Driver: "Kafka", Brokers: []string{"localhost:9092"}, Topic: "blacked"})
+	require.NoError(t, err)
+	assert.NoError(t, pub.Close())
+}
@@ -0,0 +1,58 @@
+package eventbus
+
+import (
+	"blacked/internal/config"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaPublisher writes events to one topic, keyed by Event.Key, waiting
+// for every in-sync replica to acknowledge them.
+type kafkaPublisher struct {
+	writer *kafka.Writer
+	source string
+}
+
+func newKafkaPublisher(cfg config.EventBusConfig) (Publisher, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.Join(ErrInvalidConfig, errors.New("kafka requires brokers"))
+	}
+	return &kafkaPublisher{writer: &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Brokers...),
+		Topic:        cfg.Topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: 10 * time.Millisecond, // Batches are formed by the caller
+		WriteTimeout: cfg.Timeout,
+	}, source: source(cfg)}, nil
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, events []Event) error {
+	msgs := make([]kafka.Message, 0, len(events))
+	for _, ev := range events {
+		value, err := marshal(ev, p.source)
+		if err != nil {
+			log.Err(err).Str("type", ev.Type).Msg("Failed to marshal event")
+			return ErrPublish
+		}
+		msgs = append(msgs, kafka.Message{
+			Key:     []byte(ev.Key()),
+			Value:   value,
+			Headers: []kafka.Header{{Key: "content-type", Value: []byte(CloudEventsContentType)}},
+		})
+	}
+
+	if err := p.writer.WriteMessages(ctx, msgs...); err != nil {
+		log.Err(err).Str("topic", p.writer.Topic).Int("events", len(events)).Msg("Failed to write events to Kafka")
+		return ErrPublish
+	}
+	return nil
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,66 @@
+package eventbus
+
+import (
+	"blacked/internal/config"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+// natsPublisher publishes each event on <topic>.<type>, e.g.
+// blacked.events.entry.created, then flushes, so a publish returns once the
+// server has received the batch. Streams that must keep events across
+// subscriber restarts capture the subjects with JetStream.
+type natsPublisher struct {
+	conn    *nats.Conn
+	prefix  string
+	source  string
+	timeout time.Duration
+}
+
+func newNATSPublisher(cfg config.EventBusConfig) (Publisher, error) {
+	if cfg.URL == "" {
+		return nil, errors.Join(ErrInvalidConfig, errors.New("nats requires url"))
+	}
+	conn, err := nats.Connect(cfg.URL, nats.Name("blacked"), nats.Timeout(cfg.Timeout), nats.MaxReconnects(-1))
+	if err != nil {
+		log.Err(err).Str("url", cfg.URL).Msg("Failed to connect to NATS")
+		return nil, ErrPublish
+	}
+	return &natsPublisher{conn: conn, prefix: cfg.Topic, source: source(cfg), timeout: cfg.Timeout}, nil
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, events []Event) error {
+	for _, ev := range events {
+		data, err := marshal(ev, p.source)
+		if err != nil {
+			log.Err(err).Str("type", ev.Type).Msg("Failed to marshal event")
+			return ErrPublish
+		}
+		msg := nats.NewMsg(p.prefix + "." + ev.Type)
+		msg.Data = data
+		msg.Header.Set("Content-Type", CloudEventsContentType)
+		msg.Header.Set("Nats-Msg-Id", ev.ID) // JetStream de-duplicates republished events
+		if err := p.conn.PublishMsg(msg); err != nil {
+			log.Err(err).Str("subject", msg.Subject).Msg("Failed to publish event to NATS")
+			return ErrPublish
+		}
+	}
+
+	timeout := p.timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+	if err := p.conn.FlushTimeout(timeout); err != nil {
+		log.Err(err).Int("events", len(events)).Msg("Failed to flush events to NATS")
+		return ErrPublish
+	}
+	return nil
+}
+
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
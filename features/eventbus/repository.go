@@ -0,0 +1,71 @@
+package eventbus
+
+import (
+	"blacked/features/replication"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrQueryState = errors.New("failed to query event bus cursor from SQLite")
+	ErrSaveState  = errors.New("failed to save event bus cursor in SQLite")
+)
+
+// State is the position of a sink in the entry change feed.
+type State struct {
+	Cursor    int64 // Last seq published
+	Watermark int64 // Change time of that seq (Unix nanos); entries activated after it are new
+}
+
+// Repository reads the entry change feed and keeps the cursor of each sink.
+type Repository interface {
+	Changes(ctx context.Context, after int64, limit int) (*replication.ChangeFeed, error)
+	Head(ctx context.Context) (replication.Head, error)
+
+	// State returns the position of sink; ok is false before its first publish.
+	State(ctx context.Context, sink string) (state State, ok bool, err error)
+	SetState(ctx context.Context, sink string, state State) error
+}
+
+// SQLiteRepository is the SQLite implementation of Repository, reading the
+// change feed through the replication repository.
+type SQLiteRepository struct {
+	*replication.SQLiteRepository
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLiteRepository instance.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{SQLiteRepository: replication.NewSQLiteRepository(db), db: db}
+}
+
+// State returns the position of sink; ok is false before its first publish.
+func (r *SQLiteRepository) State(ctx context.Context, sink string) (State, bool, error) {
+	var s State
+	err := r.db.QueryRowContext(ctx, "SELECT cursor, watermark FROM eventbus_state WHERE sink = ?", sink).Scan(&s.Cursor, &s.Watermark)
+	if errors.Is(err, sql.ErrNoRows) {
+		return State{}, false, nil
+	}
+	if err != nil {
+		log.Err(err).Str("sink", sink).Msg("Failed to query event bus cursor")
+		return State{}, false, ErrQueryState
+	}
+	return s, true, nil
+}
+
+// SetState saves the position of sink.
+func (r *SQLiteRepository) SetState(ctx context.Context, sink string, s State) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO eventbus_state (sink, cursor, watermark, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (sink) DO UPDATE SET cursor = EXCLUDED.cursor, watermark = EXCLUDED.watermark, updated_at = EXCLUDED.updated_at`,
+		sink, s.Cursor, s.Watermark, time.Now().UnixNano())
+	if err != nil {
+		log.Err(err).Str("sink", sink).Int64("cursor", s.Cursor).Msg("Failed to save event bus cursor")
+		return ErrSaveState
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package export
+
+import (
+	"blacked/features/entries/repository"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var ErrInvalidAXFRAllow = errors.New("axfr_allow must list CIDRs or IPs")
+
+const (
+	// axfrRecordsPerMessage keeps every transfer message well under the
+	// 64 KiB TCP message limit.
+	axfrRecordsPerMessage = 200
+
+	// axfrIdleTimeout closes connections without a query for this long.
+	axfrIdleTimeout = 30 * time.Second
+
+	// typeIXFR is the incremental transfer query type, answered with a full
+	// transfer as RFC 1995 allows.
+	typeIXFR dnsmessage.Type = 251
+)
+
+// AXFRServer transfers the RPZ to secondary resolvers over TCP.
+type AXFRServer struct {
+	svc      *Service
+	rpz      *RPZ
+	zoneName dnsmessage.Name
+	soaName  dnsmessage.Name
+	allow    []netip.Prefix
+}
+
+// NewAXFRServer creates an AXFRServer for z. allow lists the CIDRs or IPs
+// allowed to transfer; empty allows loopback only.
+func NewAXFRServer(svc *Service, z *RPZ, allow []string) (*AXFRServer, error) {
+	s := &AXFRServer{svc: svc, rpz: z}
+
+	var err error
+	if s.zoneName, err = dnsmessage.NewName(z.zone + "."); err != nil {
+		return nil, errors.Join(ErrInvalidRPZ, err)
+	}
+	if s.soaName, err = dnsmessage.NewName("hostmaster." + z.zone + "."); err != nil {
+		return nil, errors.Join(ErrInvalidRPZ, err)
+	}
+
+	for _, a := range allow {
+		prefix, err := netip.ParsePrefix(a)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(a)
+			if addrErr != nil {
+				return nil, errors.Join(ErrInvalidAXFRAllow, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		s.allow = append(s.allow, prefix.Masked())
+	}
+	return s, nil
+}
+
+// ListenAXFR serves zone transfers of s on port in the background. The
+// returned stop function closes the listener and waits for it.
+func ListenAXFR(s *AXFRServer, port int) (stop func(), err error) {
+	addr := ":" + strconv.Itoa(port)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Error().Err(err).Int("port", port).Msg("Failed to listen for RPZ zone transfers")
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Go(func() { s.ServeTCP(ctx, ln) })
+	log.Info().Str("address", addr).Str("zone", s.rpz.zone).Msg("RPZ AXFR listening")
+
+	return func() {
+		cancel()
+		ln.Close()
+		wg.Wait()
+	}, nil
+}
+
+// ServeTCP answers the transfer requests of the connections accepted on ln
+// until it is closed.
+func (s *AXFRServer) ServeTCP(ctx context.Context, ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Error().Err(err).Msg("RPZ AXFR server stopped")
+			}
+			return
+		}
+		go s.serveConn(ctx, conn)
+	}
+}
+
+func (s *AXFRServer) allowed(addr net.Addr) bool {
+	ap, err := netip.ParseAddrPort(addr.String())
+	if err != nil {
+		return false
+	}
+	ip := ap.Addr().Unmap()
+	if len(s.allow) == 0 {
+		return ip.IsLoopback()
+	}
+	for _, p := range s.allow {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *AXFRServer) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	var size [2]byte
+	for {
+		conn.SetDeadline(time.Now().Add(axfrIdleTimeout))
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+
+		// Transfers may take long; only the wait for a query is bounded.
+		conn.SetDeadline(time.Time{})
+		if err := s.answer(ctx, conn, req); err != nil {
+			log.Debug().Err(err).Str("remote", conn.RemoteAddr().String()).Msg("RPZ transfer aborted")
+			return
+		}
+	}
+}
+
+// answer writes the response to one request: the whole zone for AXFR and
+// IXFR (always answered with a full transfer), the SOA for SOA queries.
+func (s *AXFRServer) answer(ctx context.Context, conn net.Conn, req []byte) error {
+	var p dnsmessage.Parser
+	hdr, err := p.Start(req)
+	if err != nil {
+		return err
+	}
+	q, err := p.Question()
+	if err != nil {
+		return err
+	}
+
+	resp := dnsmessage.Header{ID: hdr.ID, Response: true, OpCode: hdr.OpCode}
+	switch {
+	case hdr.Response || hdr.OpCode != 0:
+		resp.RCode = dnsmessage.RCodeNotImplemented
+		return s.send(conn, resp, q, nil)
+	case !strings.EqualFold(q.Name.String(), s.zoneName.String()) || q.Class != dnsmessage.ClassINET:
+		resp.RCode = dnsmessage.RCodeRefused
+		return s.send(conn, resp, q, nil)
+	}
+	resp.Authoritative = true
+
+	serial, err := s.svc.RPZSerial(ctx)
+	if err != nil {
+		resp.RCode = dnsmessage.RCodeServerFailure
+		return s.send(conn, resp, q, nil)
+	}
+	soa := s.soa(serial)
+
+	switch q.Type {
+	case dnsmessage.TypeSOA:
+		return s.send(conn, resp, q, []dnsmessage.Resource{soa})
+	case dnsmessage.TypeAXFR, typeIXFR:
+		if !s.allowed(conn.RemoteAddr()) {
+			log.Warn().Str("remote", conn.RemoteAddr().String()).Msg("RPZ transfer refused")
+			resp.RCode = dnsmessage.RCodeRefused
+			return s.send(conn, resp, q, nil)
+		}
+		return s.transfer(ctx, conn, resp, q, soa)
+	default:
+		resp.RCode = dnsmessage.RCodeNotImplemented
+		return s.send(conn, resp, q, nil)
+	}
+}
+
+// transfer streams the zone framed by its SOA, axfrRecordsPerMessage
+// records per message.
+func (s *AXFRServer) transfer(ctx context.Context, conn net.Conn, hdr dnsmessage.Header, q dnsmessage.Question, soa dnsmessage.Resource) error {
+	started := time.Now()
+	batch := []dnsmessage.Resource{soa}
+	flush := func() error {
+		err := s.send(conn, hdr, q, batch)
+		batch = batch[:0]
+		return err
+	}
+
+	ns := dnsmessage.Resource{
+		Header: s.header(s.zoneName),
+		Body:   &dnsmessage.NSResource{NS: dnsmessage.MustNewName("localhost.")},
+	}
+	batch = append(batch, ns)
+
+	count, err := s.svc.RPZRecords(ctx, s.rpz, repository.Filter{}, func(r RPZRecord) error {
+		owner, err := dnsmessage.NewName(s.rpz.Owner(r))
+		if err != nil {
+			return nil // Checked by RPZRecords; never expected
+		}
+		target, err := dnsmessage.NewName(r.Target)
+		if err != nil {
+			return nil
+		}
+		batch = append(batch, dnsmessage.Resource{Header: s.header(owner), Body: &dnsmessage.CNAMEResource{CNAME: target}})
+		if len(batch) >= axfrRecordsPerMessage {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	batch = append(batch, soa)
+	if err := flush(); err != nil {
+		return err
+	}
+	log.Info().
+		Str("remote", conn.RemoteAddr().String()).
+		Int("records", count).
+		Dur("duration", time.Since(started)).
+		Msg("RPZ transferred")
+	return nil
+}
+
+func (s *AXFRServer) header(name dnsmessage.Name) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: s.rpz.ttl}
+}
+
+func (s *AXFRServer) soa(serial uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: s.header(s.zoneName),
+		Body: &dnsmessage.SOAResource{
+			NS:      dnsmessage.MustNewName("localhost."),
+			MBox:    s.soaName,
+			Serial:  serial,
+			Refresh: rpzRefresh,
+			Retry:   rpzRetry,
+			Expire:  rpzExpire,
+			MinTTL:  s.rpz.ttl,
+		},
+	}
+}
+
+// send writes one length-prefixed message holding answers.
+func (s *AXFRServer) send(conn net.Conn, hdr dnsmessage.Header, q dnsmessage.Question, answers []dnsmessage.Resource) error {
+	msg := dnsmessage.Message{Header: hdr, Questions: []dnsmessage.Question{q}, Answers: answers}
+	out, err := msg.AppendPack(make([]byte, 2, 4096))
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint16(out, uint16(len(out)-2))
+	_, err = conn.Write(out)
+	return err
+}
@@ -0,0 +1,299 @@
+package export
+
+import (
+	"blacked/features/allowlist"
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/internal/config"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var ErrInvalidRPZ = errors.New("invalid rpz configuration")
+
+// RPZ policy targets; a CNAME to one of them selects the resolver action.
+var rpzActions = map[string]string{
+	"nxdomain": ".",
+	"nodata":   "*.",
+	"drop":     "rpz-drop.",
+	"passthru": "rpz-passthru.",
+	"tcp-only": "rpz-tcp-only.",
+}
+
+// maxNameLength is the longest presentation-format domain name.
+const maxNameLength = 253
+
+// RuleLister lists allowlist rules; implemented by allowlist.Service.
+type RuleLister interface {
+	List(ctx context.Context) ([]allowlist.Rule, error)
+}
+
+// RPZ is a validated Response Policy Zone layout.
+type RPZ struct {
+	zone         string // Lower case, without trailing dot
+	target       string
+	ttl          uint32
+	wildcard     bool
+	includePaths bool
+}
+
+// RPZRecord is one policy rule: a CNAME from Owner, relative to the zone,
+// to Target.
+type RPZRecord struct {
+	Owner  string
+	Target string
+}
+
+// NewRPZ validates cfg.
+func NewRPZ(cfg config.RPZConfig) (*RPZ, error) {
+	zone := strings.ToLower(strings.Trim(cfg.Zone, "."))
+	if !validName(zone) {
+		return nil, errors.Join(ErrInvalidRPZ, errors.New("zone must be a domain name"))
+	}
+
+	action := strings.ToLower(strings.TrimSpace(cfg.Action))
+	target, ok := rpzActions[action]
+	if !ok {
+		// Any other action is a host listed names are redirected to.
+		host := strings.Trim(action, ".")
+		if !validName(host) {
+			return nil, errors.Join(ErrInvalidRPZ, errors.New("action must be nxdomain, nodata, drop, passthru, tcp-only or a host name"))
+		}
+		target = host + "."
+	}
+
+	return &RPZ{
+		zone:         zone,
+		target:       target,
+		ttl:          uint32(cfg.TTL / time.Second),
+		wildcard:     cfg.Wildcard,
+		includePaths: cfg.IncludePaths,
+	}, nil
+}
+
+// Zone returns the zone origin without trailing dot.
+func (z *RPZ) Zone() string {
+	return z.zone
+}
+
+// TTL returns the TTL of every record, in seconds.
+func (z *RPZ) TTL() uint32 {
+	return z.ttl
+}
+
+// Owner returns the fully qualified owner name of a record.
+func (z *RPZ) Owner(r RPZRecord) string {
+	return r.Owner + "." + z.zone + "."
+}
+
+// SetAllowlist makes RPZ exports pass allowlisted domains through.
+func (s *Service) SetAllowlist(l RuleLister) *Service {
+	s.allowlist = l
+	return s
+}
+
+// RPZSerial returns the zone serial: the Unix second of the latest entry
+// change, so it only moves when the zone content may have changed.
+func (s *Service) RPZSerial(ctx context.Context) (uint32, error) {
+	latest, err := s.repo.LatestChange(ctx)
+	if err != nil {
+		return 0, ErrExportFailed
+	}
+	return uint32(max(latest/int64(time.Second), 1)), nil
+}
+
+// RPZRecords calls fn with the policy rules of z for the active entries
+// matching f, and returns how many rules were emitted. Each host is listed
+// once; allowlisted domains get passthru rules and their hosts are skipped.
+// URL entries only list their host when z includes paths.
+func (s *Service) RPZRecords(ctx context.Context, z *RPZ, f repository.Filter, fn func(RPZRecord) error) (int, error) {
+	count := 0
+	emit := func(owner, target string) error {
+		count++
+		return fn(RPZRecord{Owner: owner, Target: target})
+	}
+
+	var allowed map[string]bool
+	if s.allowlist != nil {
+		rules, err := s.allowlist.List(ctx)
+		if err != nil {
+			return 0, ErrExportFailed
+		}
+		allowed = make(map[string]bool)
+		for _, r := range rules {
+			if r.Kind != allowlist.KindDomain || allowed[r.Pattern] || !z.fits(r.Pattern) {
+				continue
+			}
+			allowed[r.Pattern] = true
+			if err := z.emitHost(r.Pattern, rpzActions["passthru"], false, emit); err != nil {
+				return count, err
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	err := s.repo.StreamFilteredEntries(ctx, f, func(entry *entries.Entry) error {
+		if !z.includePaths && (strings.Trim(entry.Path, "/") != "" || entry.RawQuery != "") {
+			return nil
+		}
+		host := strings.TrimSuffix(strings.ToLower(entry.Host), ".")
+		if host == "" {
+			return nil
+		}
+		// seen holds whether the subdomains of a host were listed too, so a
+		// wildcard entry still adds them after a plain entry for its host.
+		subdomains := z.wildcard || entry.Wildcard
+		listed, ok := seen[host]
+		if ok && (listed || !subdomains) {
+			return nil
+		}
+		seen[host] = subdomains
+
+		if ip, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+			if ok {
+				return nil
+			}
+			return emit(rpzIP(ip), z.target)
+		}
+		if !z.fits(host) || isAllowed(allowed, host) {
+			return nil
+		}
+		if ok {
+			return emit("*."+host, z.target)
+		}
+		return z.emitHost(host, z.target, entry.Wildcard, emit)
+	})
+	if err != nil {
+		log.Err(err).Interface("filter", f).Msg("Failed to export RPZ entries")
+		return count, ErrExportFailed
+	}
+	return count, nil
+}
+
+// WriteRPZ writes z as a BIND zone file for the active entries matching f
+// and returns how many policy rules it holds.
+func (s *Service) WriteRPZ(ctx context.Context, z *RPZ, f repository.Filter, w io.Writer) (int, error) {
+	serial, err := s.RPZSerial(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "$ORIGIN %s.\n$TTL %d\n", z.zone, z.ttl)
+	fmt.Fprintf(bw, "@ IN SOA localhost. hostmaster.%s. %d %d %d %d %d\n", z.zone, serial, rpzRefresh, rpzRetry, rpzExpire, z.ttl)
+	fmt.Fprintf(bw, "@ IN NS localhost.\n")
+
+	count, err := s.RPZRecords(ctx, z, f, func(r RPZRecord) error {
+		_, err := fmt.Fprintf(bw, "%s IN CNAME %s\n", r.Owner, r.Target)
+		return err
+	})
+	if err != nil {
+		return count, err
+	}
+	if err := bw.Flush(); err != nil {
+		log.Err(err).Msg("Failed to flush RPZ export")
+		return count, ErrExportFailed
+	}
+	return count, nil
+}
+
+// SOA timers of the zone, in seconds.
+const (
+	rpzRefresh = 3600
+	rpzRetry   = 600
+	rpzExpire  = 86400
+)
+
+// emitHost emits the rule of host and, with wildcards on or for a wildcard
+// entry, of its subdomains.
+func (z *RPZ) emitHost(host, target string, subdomains bool, emit func(owner, target string) error) error {
+	if err := emit(host, target); err != nil {
+		return err
+	}
+	if z.wildcard || subdomains {
+		return emit("*."+host, target)
+	}
+	return nil
+}
+
+// fits reports whether host makes a valid owner name, wildcard included.
+func (z *RPZ) fits(host string) bool {
+	return validName(host) && len("*."+host+"."+z.zone) <= maxNameLength
+}
+
+func isAllowed(allowed map[string]bool, host string) bool {
+	for name := host; name != ""; {
+		if allowed[name] {
+			return true
+		}
+		_, name, _ = strings.Cut(name, ".")
+	}
+	return false
+}
+
+// rpzIP returns the rpz-ip owner of an address: the prefix length and the
+// reversed octets (IPv4) or 16-bit groups (IPv6, longest zero run as "zz").
+func rpzIP(ip netip.Addr) string {
+	ip = ip.Unmap()
+	if ip.Is4() {
+		b := ip.As4()
+		return fmt.Sprintf("32.%d.%d.%d.%d.rpz-ip", b[3], b[2], b[1], b[0])
+	}
+
+	b := ip.As16()
+	var groups [8]string
+	for i := range groups {
+		groups[i] = fmt.Sprintf("%x", uint16(b[2*i])<<8|uint16(b[2*i+1]))
+	}
+
+	// The longest run of two or more zero groups is written once as zz.
+	start, length := -1, 1
+	for i := 0; i < len(groups); {
+		j := i
+		for j < len(groups) && groups[j] == "0" {
+			j++
+		}
+		if j-i > length {
+			start, length = i, j-i
+		}
+		i = max(j, i+1)
+	}
+	var labels []string
+	for i := len(groups) - 1; i >= 0; i-- {
+		if start >= 0 && i >= start && i < start+length {
+			if i == start {
+				labels = append(labels, "zz")
+			}
+			continue
+		}
+		labels = append(labels, groups[i])
+	}
+	return "128." + strings.Join(labels, ".") + ".rpz-ip"
+}
+
+// validName reports whether name is a domain name with labels of 1 to 63
+// letters, digits, hyphens or underscores.
+func validName(name string) bool {
+	if name == "" || len(name) > maxNameLength {
+		return false
+	}
+	for label := range strings.SplitSeq(name, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
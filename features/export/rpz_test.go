@@ -0,0 +1,205 @@
+package export
+
+import (
+	"blacked/features/allowlist"
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/internal/config"
+	idb "blacked/internal/db"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type fakeAllowlist []allowlist.Rule
+
+func (f fakeAllowlist) List(context.Context) ([]allowlist.Rule, error) { return f, nil }
+
+func newRPZService(t *testing.T) *Service {
+	t.Helper()
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	repo := repository.NewSQLiteRepository(conn)
+	at := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.BatchSaveEntries(context.Background(), []*entries.Entry{
+		newEntry(t, "oisd-big", "evil.example", at),
+		newEntry(t, "oisd-big", "https://Evil.example/", at), // Same host
+		newEntry(t, "urlhaus-online", "http://203.0.113.7/", at),
+		newEntry(t, "urlhaus-online", "http://[2001:db8::1]/", at),
+		newEntry(t, "urlhaus-online", "https://phish.test/login.php", at), // Has a path
+		newEntry(t, "oisd-big", "good.allowed.example", at),
+	}))
+
+	return NewServiceWithRepository(repo).SetAllowlist(fakeAllowlist{
+		{Kind: allowlist.KindDomain, Pattern: "allowed.example"},
+		{Kind: allowlist.KindURL, Pattern: "evil.example/ok"},
+	})
+}
+
+func TestWriteRPZ(t *testing.T) {
+	svc := newRPZService(t)
+	rpz, err := NewRPZ(config.RPZConfig{Zone: "RPZ.Example.", Action: "nxdomain", TTL: 5 * time.Minute, Wildcard: true})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	count, err := svc.WriteRPZ(context.Background(), rpz, repository.Filter{}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, 6, count)
+
+	zone := buf.String()
+	assert.True(t, strings.HasPrefix(zone, "$ORIGIN rpz.example.\n$TTL 300\n@ IN SOA localhost. hostmaster.rpz.example. 1717200000 "), zone)
+	for _, line := range []string{
+		"allowed.example IN CNAME rpz-passthru.",
+		"*.allowed.example IN CNAME rpz-passthru.",
+		"evil.example IN CNAME .",
+		"*.evil.example IN CNAME .",
+		"32.7.113.0.203.rpz-ip IN CNAME .",
+		"128.1.zz.db8.2001.rpz-ip IN CNAME .",
+	} {
+		assert.Contains(t, zone, line+"\n")
+	}
+	assert.NotContains(t, zone, "phish.test", "URL entries need include_paths")
+	assert.NotContains(t, zone, "good.allowed.example", "allowlisted hosts are skipped")
+
+	rpz, err = NewRPZ(config.RPZConfig{Zone: "rpz.example", Action: "walled.example.net", IncludePaths: true})
+	require.NoError(t, err)
+	buf.Reset()
+	_, err = svc.WriteRPZ(context.Background(), rpz, repository.Filter{Sources: []string{"urlhaus-online"}}, &buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "\nphish.test IN CNAME walled.example.net.\n")
+	assert.NotContains(t, buf.String(), "*.phish.test")
+}
+
+func TestWriteRPZWildcardEntries(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	at := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	wild := newEntry(t, "oisd-big", "ads.example", at)
+	require.NoError(t, wild.SetWildcardURL("ads.example"))
+	repo := repository.NewSQLiteRepository(conn)
+	require.NoError(t, repo.BatchSaveEntries(context.Background(), []*entries.Entry{
+		newEntry(t, "plain-feed", "https://ads.example/", at), // Same host, listed first
+		wild,
+		newEntry(t, "plain-feed", "tracker.example", at),
+	}))
+
+	rpz, err := NewRPZ(config.RPZConfig{Zone: "rpz.example", Action: "nxdomain"})
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	count, err := NewServiceWithRepository(repo).WriteRPZ(context.Background(), rpz, repository.Filter{}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	zone := buf.String()
+	assert.Equal(t, 1, strings.Count(zone, "\nads.example IN CNAME .\n"))
+	assert.Contains(t, zone, "\n*.ads.example IN CNAME .\n", "wildcard entries list their subdomains")
+	assert.Contains(t, zone, "\ntracker.example IN CNAME .\n")
+	assert.NotContains(t, zone, "*.tracker.example")
+}
+
+func TestNewRPZValidates(t *testing.T) {
+	_, err := NewRPZ(config.RPZConfig{Zone: "", Action: "nxdomain"})
+	assert.ErrorIs(t, err, ErrInvalidRPZ)
+	_, err = NewRPZ(config.RPZConfig{Zone: "rpz.example", Action: "block it"})
+	assert.ErrorIs(t, err, ErrInvalidRPZ)
+}
+
+func TestRPZIP(t *testing.T) {
+	for ip, want := range map[string]string{
+		"1.2.3.4":              "32.4.3.2.1.rpz-ip",
+		"::ffff:1.2.3.4":       "32.4.3.2.1.rpz-ip",
+		"2001:db8::1":          "128.1.zz.db8.2001.rpz-ip",
+		"2001:db8:0:1::":       "128.zz.1.0.db8.2001.rpz-ip",
+		"2001:db8:1:2:3:4:5:6": "128.6.5.4.3.2.1.db8.2001.rpz-ip",
+	} {
+		assert.Equal(t, want, rpzIP(netip.MustParseAddr(ip)), ip)
+	}
+}
+
+// axfr sends one query for zone and returns the resources of every
+// response message until the transfer's closing SOA.
+func axfr(t *testing.T, addr string, qtype dnsmessage.Type) ([]dnsmessage.Resource, dnsmessage.RCode) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	req, err := (&dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 7},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName("rpz.example."), Type: qtype, Class: dnsmessage.ClassINET}},
+	}).AppendPack(make([]byte, 2, 512))
+	require.NoError(t, err)
+	binary.BigEndian.PutUint16(req, uint16(len(req)-2))
+	_, err = conn.Write(req)
+	require.NoError(t, err)
+
+	var records []dnsmessage.Resource
+	for {
+		var size [2]byte
+		_, err := io.ReadFull(conn, size[:])
+		require.NoError(t, err)
+		buf := make([]byte, binary.BigEndian.Uint16(size[:]))
+		_, err = io.ReadFull(conn, buf)
+		require.NoError(t, err)
+
+		var msg dnsmessage.Message
+		require.NoError(t, msg.Unpack(buf))
+		if msg.Header.RCode != dnsmessage.RCodeSuccess {
+			return nil, msg.Header.RCode
+		}
+		records = append(records, msg.Answers...)
+		if qtype != dnsmessage.TypeAXFR || (len(records) > 1 && records[len(records)-1].Header.Type == dnsmessage.TypeSOA) {
+			return records, msg.Header.RCode
+		}
+	}
+}
+
+func TestAXFR(t *testing.T) {
+	svc := newRPZService(t)
+	rpz, err := NewRPZ(config.RPZConfig{Zone: "rpz.example", Action: "nxdomain", TTL: time.Minute, Wildcard: true})
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer ln.Close()
+
+	srv, err := NewAXFRServer(svc, rpz, nil)
+	require.NoError(t, err)
+	go srv.ServeTCP(ctx, ln)
+
+	records, _ := axfr(t, ln.Addr().String(), dnsmessage.TypeAXFR)
+	require.Len(t, records, 2+6+1, "SOA, NS, rules, closing SOA")
+	assert.Equal(t, dnsmessage.TypeSOA, records[0].Header.Type)
+	assert.Equal(t, dnsmessage.TypeNS, records[1].Header.Type)
+	assert.Equal(t, "allowed.example.rpz.example.", records[2].Header.Name.String())
+	assert.Equal(t, "rpz-passthru.", records[2].Body.(*dnsmessage.CNAMEResource).CNAME.String())
+	assert.Equal(t, uint32(1717200000), records[0].Body.(*dnsmessage.SOAResource).Serial)
+
+	records, _ = axfr(t, ln.Addr().String(), dnsmessage.TypeSOA)
+	require.Len(t, records, 1)
+
+	srv.allow = []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}
+	_, rcode := axfr(t, ln.Addr().String(), dnsmessage.TypeAXFR)
+	assert.Equal(t, dnsmessage.RCodeRefused, rcode, "peers outside axfr_allow")
+
+	_, err = NewAXFRServer(svc, rpz, []string{"not-an-ip"})
+	assert.ErrorIs(t, err, ErrInvalidAXFRAllow)
+}
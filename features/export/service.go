@@ -0,0 +1,66 @@
+package export
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/internal/db"
+	"context"
+	"errors"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrDatabaseConnection = errors.New("failed to connect to the database")
+	ErrExportFailed       = errors.New("failed to export entries")
+)
+
+// Service exports stored entries in the supported formats.
+type Service struct {
+	repo      repository.BlacklistRepository
+	allowlist RuleLister // nil exports allowlisted domains like any other
+}
+
+// NewService creates an export Service on the read database pool.
+func NewService() (*Service, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+
+	return NewServiceWithRepository(repository.NewSQLiteRepository(dbConn)), nil
+}
+
+// NewServiceWithRepository creates an export Service on the given repository.
+func NewServiceWithRepository(repo repository.BlacklistRepository) *Service {
+	return &Service{repo: repo}
+}
+
+// Export writes the active entries matching f, oldest activation first, and
+// returns how many were written. Host formats skip repeated hosts but count
+// every entry.
+func (s *Service) Export(ctx context.Context, f repository.Filter, w Writer) (int, error) {
+	count := 0
+	err := s.repo.StreamFilteredEntries(ctx, f, func(entry *entries.Entry) error {
+		count++
+		return w.Write(entry)
+	})
+	if err != nil {
+		log.Err(err).Interface("filter", f).Msg("Failed to export entries")
+		return count, ErrExportFailed
+	}
+
+	if err := w.Close(); err != nil {
+		log.Err(err).Interface("filter", f).Msg("Failed to flush export")
+		return count, ErrExportFailed
+	}
+
+	return count, nil
+}
+
+// Delta writes every entry matching f, oldest activation first, and returns
+// how many were written. f.Since is the start of the delta.
+func (s *Service) Delta(ctx context.Context, f repository.Filter, w Writer) (int, error) {
+	return s.Export(ctx, f, w)
+}
@@ -0,0 +1,117 @@
+package export
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	idb "blacked/internal/db"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEntry(t *testing.T, source, link string, at time.Time) *entries.Entry {
+	t.Helper()
+	e := entries.NewEntry().WithSource(source).WithProcessID("p1")
+	require.NoError(t, e.SetURL(link))
+	e.CreatedAt = at.UnixNano()
+	e.UpdatedAt = at.UnixNano()
+	return e
+}
+
+func TestDeltaIncludesNewAndReactivatedEntries(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+	svc := NewServiceWithRepository(repo)
+
+	t0 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	since := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	t1 := since.Add(time.Hour)
+
+	old := newEntry(t, "openphish-feed", "https://old.example.com/a", t0)
+	reactivated := newEntry(t, "openphish-feed", "https://back.example.com/b", t0)
+	other := newEntry(t, "urlhaus-online", "https://other.example.com/c", t1)
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{old, reactivated, other}))
+
+	// Soft delete, then see the entry again after since.
+	require.NoError(t, repo.SoftDeleteEntryByID(ctx, reactivated.ID))
+	again := newEntry(t, "openphish-feed", "https://back.example.com/b", t1)
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{again}))
+
+	// A plain re-sighting of an active entry must not show up in the delta.
+	seen := newEntry(t, "openphish-feed", "https://old.example.com/a", t1)
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{seen}))
+
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatPlain, &buf)
+	require.NoError(t, err)
+	count, err := svc.Delta(ctx, repository.Filter{Sources: []string{"OPENPHISH-FEED"}, Since: &since}, w)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "https://back.example.com/b\n", buf.String())
+
+	buf.Reset()
+	w, err = NewWriter(FormatCSV, &buf)
+	require.NoError(t, err)
+	count, err = svc.Delta(ctx, repository.Filter{Since: &since}, w)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Len(t, strings.Split(strings.TrimSpace(buf.String()), "\n"), 3, "header + 2 rows")
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, FormatPlain, f)
+
+	f, err = ParseFormat("JSON")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, f)
+
+	_, err = ParseFormat("xml")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestExportHostFormats(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+	svc := NewServiceWithRepository(repo)
+
+	at := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{
+		newEntry(t, "oisd-big", "https://Evil.example/a", at),
+		newEntry(t, "oisd-big", "https://evil.example/b", at.Add(time.Second)), // Same host
+		newEntry(t, "oisd-big", "http://203.0.113.7/payload", at.Add(2*time.Second)),
+		newEntry(t, "urlhaus-online", "https://other.example/", at.Add(3*time.Second)),
+	}))
+
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatHosts, &buf)
+	require.NoError(t, err)
+	count, err := svc.Export(ctx, repository.Filter{Sources: []string{"oisd-big"}}, w)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, "0.0.0.0 evil.example\n", buf.String(), "hosts files cannot list IPs")
+
+	buf.Reset()
+	w, err = NewWriter(FormatAdblock, &buf)
+	require.NoError(t, err)
+	_, err = svc.Export(ctx, repository.Filter{}, w)
+	require.NoError(t, err)
+	assert.Equal(t, "||evil.example^\n||203.0.113.7^\n||other.example^\n", buf.String())
+}
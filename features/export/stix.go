@@ -0,0 +1,144 @@
+package export
+
+import (
+	"blacked/features/entries"
+	"bufio"
+	"encoding/json"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// stixNamespace seeds the name-based UUIDs of exported indicators, so an
+// entry keeps its indicator ID across exports and consumers can de-duplicate.
+var stixNamespace = uuid.MustParse("6d4c0b1e-6f53-4b8a-9a3b-3c1f2f0e8a51")
+
+// stixTime is the timestamp layout of STIX 2.1 (UTC, millisecond precision).
+const stixTime = "2006-01-02T15:04:05.000Z"
+
+// STIXIndicator is a STIX 2.1 indicator object describing one entry.
+type STIXIndicator struct {
+	Type           string   `json:"type"`
+	SpecVersion    string   `json:"spec_version"`
+	ID             string   `json:"id"`
+	Created        string   `json:"created"`
+	Modified       string   `json:"modified"`
+	Name           string   `json:"name"`
+	IndicatorTypes []string `json:"indicator_types"`
+	Pattern        string   `json:"pattern"`
+	PatternType    string   `json:"pattern_type"`
+	ValidFrom      string   `json:"valid_from"`
+	Labels         []string `json:"labels,omitempty"`     // Categories, then "source:<source>"
+	Confidence     *int     `json:"confidence,omitempty"` // 0-100; omitted when the entry has none
+}
+
+// NewSTIXIndicator describes entry as an indicator: a url pattern for
+// entries with a path or query, else a domain-name, ipv4-addr or ipv6-addr
+// pattern on the host. valid_from is when the entry was activated.
+func NewSTIXIndicator(entry *entries.Entry) STIXIndicator {
+	created := entry.CreatedAt
+	activated := entry.ActivatedAt
+	if activated == 0 {
+		activated = created
+	}
+
+	ind := STIXIndicator{
+		Type:           "indicator",
+		SpecVersion:    "2.1",
+		ID:             "indicator--" + uuid.NewSHA1(stixNamespace, []byte(entry.Source+"\x00"+entry.SourceURL)).String(),
+		Created:        stixTimestamp(created),
+		Modified:       stixTimestamp(max(activated, created)),
+		IndicatorTypes: []string{"malicious-activity"},
+		PatternType:    "stix",
+		ValidFrom:      stixTimestamp(activated),
+	}
+	ind.Name, ind.Pattern = stixPattern(entry)
+
+	ind.Labels = append(ind.Labels, entry.AllCategories()...)
+	if entry.Source != "" {
+		ind.Labels = append(ind.Labels, "source:"+entry.Source)
+	}
+	if entry.Confidence > 0 {
+		c := int(min(entry.Confidence, 1)*100 + 0.5)
+		ind.Confidence = &c
+	}
+	return ind
+}
+
+// stixPattern returns the value an entry lists and the pattern matching it.
+func stixPattern(entry *entries.Entry) (value, pattern string) {
+	host := strings.Trim(entry.Host, "[]")
+	if (entry.Path == "" || entry.Path == "/") && entry.RawQuery == "" {
+		kind := "domain-name"
+		if addr, err := netip.ParseAddr(host); err == nil {
+			kind = "ipv4-addr"
+			if addr.Is6() && !addr.Is4In6() {
+				kind = "ipv6-addr"
+			}
+			host = addr.Unmap().String()
+		}
+		return host, "[" + kind + ":value = '" + stixEscape(host) + "']"
+	}
+
+	u := entry.GetURL()
+	if u.Scheme == "" {
+		u.Scheme = "http"
+	}
+	link := u.String()
+	return link, "[url:value = '" + stixEscape(link) + "']"
+}
+
+// stixEscape escapes a value for a single-quoted STIX pattern literal.
+func stixEscape(s string) string {
+	if !strings.ContainsAny(s, `'\`) {
+		return s
+	}
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+func stixTimestamp(nanos int64) string {
+	return time.Unix(0, nanos).UTC().Format(stixTime)
+}
+
+// stixWriter streams a STIX 2.1 bundle with one indicator per entry.
+type stixWriter struct {
+	w       *bufio.Writer
+	started bool
+}
+
+func (s *stixWriter) start() error {
+	s.started = true
+	_, err := s.w.WriteString(`{"type":"bundle","id":"bundle--` + uuid.New().String() + `","objects":[`)
+	return err
+}
+
+func (s *stixWriter) Write(entry *entries.Entry) error {
+	if !s.started {
+		if err := s.start(); err != nil {
+			return err
+		}
+	} else if err := s.w.WriteByte(','); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(NewSTIXIndicator(entry))
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(b)
+	return err
+}
+
+func (s *stixWriter) Close() error {
+	if !s.started {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.w.WriteString("]}\n"); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
@@ -0,0 +1,108 @@
+package export
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/features/providers/taxii"
+	idb "blacked/internal/db"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSTIXIndicator(t *testing.T) {
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		link, pattern string
+	}{
+		{"https://evil.example/login?x=1", "[url:value = 'https://evil.example/login?x=1']"},
+		{"evil.example/a?q=it's", `[url:value = 'http://evil.example/a?q=it\'s']`},
+		{"evil.example", "[domain-name:value = 'evil.example']"},
+		{"http://203.0.113.7/", "[ipv4-addr:value = '203.0.113.7']"},
+		{"http://[2001:db8::1]/", "[ipv6-addr:value = '2001:db8::1']"},
+	}
+	for _, tc := range cases {
+		e := newEntry(t, "feed", tc.link, at)
+		assert.Equal(t, tc.pattern, NewSTIXIndicator(e).Pattern, tc.link)
+	}
+
+	e := newEntry(t, "feed", "https://evil.example/a", at)
+	e.WithCategories("phishing", "malware").WithConfidence(0.855)
+	e.ActivatedAt = at.Add(time.Hour).UnixNano()
+
+	ind := NewSTIXIndicator(e)
+	assert.Regexp(t, `^indicator--[0-9a-f-]{36}$`, ind.ID)
+	assert.Equal(t, "2026-10-01T12:00:00.000Z", ind.Created)
+	assert.Equal(t, "2026-10-01T13:00:00.000Z", ind.Modified)
+	assert.Equal(t, "2026-10-01T13:00:00.000Z", ind.ValidFrom)
+	assert.Equal(t, []string{"phishing", "malware", "source:feed"}, ind.Labels)
+	require.NotNil(t, ind.Confidence)
+	assert.Equal(t, 86, *ind.Confidence)
+
+	// The ID follows the entry's source and URL, not its row.
+	again := newEntry(t, "feed", "https://evil.example/a", at)
+	assert.Equal(t, ind.ID, NewSTIXIndicator(again).ID)
+	assert.NotEqual(t, ind.ID, NewSTIXIndicator(newEntry(t, "other", "https://evil.example/a", at)).ID)
+}
+
+func TestSTIXExport(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+	svc := NewServiceWithRepository(repo)
+
+	at := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	phish := newEntry(t, "openphish-feed", "https://phish.example/login", at).WithCategory("phishing")
+	host := newEntry(t, "oisd-big", "ads.example", at).WithCategory("ads")
+	require.NoError(t, repo.BatchSaveEntries(ctx, []*entries.Entry{phish, host}))
+
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatSTIX, &buf)
+	require.NoError(t, err)
+	count, err := svc.Export(ctx, repository.Filter{}, w)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	var bundle struct {
+		Type    string            `json:"type"`
+		ID      string            `json:"id"`
+		Objects []json.RawMessage `json:"objects"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &bundle))
+	assert.Equal(t, "bundle", bundle.Type)
+	assert.Regexp(t, `^bundle--`, bundle.ID)
+	assert.Len(t, bundle.Objects, 2)
+
+	// The TAXII provider reads the bundle back.
+	env, err := taxii.DecodeEnvelope(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	got := map[string][]string{}
+	for _, o := range env.Objects {
+		for _, ind := range o.Indicators(time.Now()) {
+			got[ind.Value] = ind.Tags
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		"https://phish.example/login": {"malicious-activity", "phishing", "source:openphish-feed"},
+		"ads.example":                 {"malicious-activity", "ads", "source:oisd-big"},
+	}, got)
+
+	// An empty export is still a valid bundle.
+	buf.Reset()
+	w, err = NewWriter(FormatSTIX, &buf)
+	require.NoError(t, err)
+	_, err = svc.Export(ctx, repository.Filter{Sources: []string{"none"}}, w)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &bundle))
+	assert.Empty(t, bundle.Objects)
+}
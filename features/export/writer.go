@@ -0,0 +1,190 @@
+package export
+
+import (
+	"blacked/features/entries"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// Format is an export output format.
+type Format string
+
+const (
+	FormatPlain   Format = "plain"   // One source URL per line
+	FormatJSON    Format = "json"    // Newline-delimited JSON entries
+	FormatCSV     Format = "csv"     // Header row followed by one row per entry
+	FormatHosts   Format = "hosts"   // "0.0.0.0 host" per listed host name, for hosts files and Pi-hole
+	FormatAdblock Format = "adblock" // "||host^" per listed host, for Adblock-style filter lists
+	FormatSTIX    Format = "stix"    // STIX 2.1 bundle of indicators, for threat intelligence platforms
+)
+
+// Formats lists all supported export formats.
+var Formats = []Format{FormatPlain, FormatJSON, FormatCSV, FormatHosts, FormatAdblock, FormatSTIX}
+
+// ParseFormat validates a format name; empty defaults to plain.
+func ParseFormat(s string) (Format, error) {
+	if s == "" {
+		return FormatPlain, nil
+	}
+	f := Format(strings.ToLower(s))
+	for _, known := range Formats {
+		if f == known {
+			return f, nil
+		}
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// ContentType returns the HTTP content type for the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatJSON:
+		return "application/x-ndjson"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatSTIX:
+		return "application/stix+json;version=2.1"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// Writer streams entries in a single export format.
+// Close must be called to flush buffered output.
+type Writer interface {
+	Write(entry *entries.Entry) error
+	Close() error
+}
+
+// NewWriter returns a Writer for format that writes to w.
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatPlain:
+		return &plainWriter{w: bufio.NewWriter(w)}, nil
+	case FormatJSON:
+		return &jsonWriter{enc: json.NewEncoder(w)}, nil
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatHosts:
+		return &hostWriter{w: bufio.NewWriter(w), seen: make(map[string]bool), prefix: "0.0.0.0 "}, nil
+	case FormatAdblock:
+		return &hostWriter{w: bufio.NewWriter(w), seen: make(map[string]bool), prefix: "||", suffix: "^", ips: true}, nil
+	case FormatSTIX:
+		return &stixWriter{w: bufio.NewWriter(w)}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+type plainWriter struct {
+	w *bufio.Writer
+}
+
+func (p *plainWriter) Write(entry *entries.Entry) error {
+	if _, err := p.w.WriteString(entry.SourceURL); err != nil {
+		return err
+	}
+	return p.w.WriteByte('\n')
+}
+
+func (p *plainWriter) Close() error {
+	return p.w.Flush()
+}
+
+type jsonWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonWriter) Write(entry *entries.Entry) error {
+	return j.enc.Encode(entry)
+}
+
+func (j *jsonWriter) Close() error {
+	return nil
+}
+
+var csvHeader = []string{"id", "source", "source_url", "scheme", "host", "domain", "path", "raw_query", "category", "created_at", "activated_at"}
+
+type csvWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (c *csvWriter) Write(entry *entries.Entry) error {
+	if !c.wroteHeader {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+	return c.w.Write([]string{
+		entry.ID,
+		entry.Source,
+		entry.SourceURL,
+		entry.Scheme,
+		entry.Host,
+		entry.Domain,
+		entry.Path,
+		entry.RawQuery,
+		entry.Category,
+		formatNanos(entry.CreatedAt),
+		formatNanos(entry.ActivatedAt),
+	})
+}
+
+func (c *csvWriter) Close() error {
+	if !c.wroteHeader {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// hostWriter writes each host once, between prefix and suffix. Hosts files
+// can only list names, so IP hosts are skipped unless ips is set.
+type hostWriter struct {
+	w      *bufio.Writer
+	seen   map[string]bool
+	prefix string
+	suffix string
+	ips    bool
+}
+
+func (h *hostWriter) Write(entry *entries.Entry) error {
+	host := strings.TrimSuffix(strings.ToLower(strings.Trim(entry.Host, "[]")), ".")
+	if host == "" || h.seen[host] {
+		return nil
+	}
+	h.seen[host] = true
+	if !h.ips {
+		if _, err := netip.ParseAddr(host); err == nil {
+			return nil
+		}
+	}
+
+	h.w.WriteString(h.prefix)
+	h.w.WriteString(host)
+	h.w.WriteString(h.suffix)
+	return h.w.WriteByte('\n')
+}
+
+func (h *hostWriter) Close() error {
+	return h.w.Flush()
+}
+
+func formatNanos(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return time.Unix(0, n).UTC().Format(time.RFC3339Nano)
+}
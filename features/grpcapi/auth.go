@@ -0,0 +1,103 @@
+package grpcapi
+
+import (
+	"blacked/features/apikeys"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataAPIKey carries the API key of a call; "authorization: Bearer
+// <key>" is accepted too.
+const MetadataAPIKey = "x-api-key"
+
+// Authenticator resolves API keys and enforces their rate limits;
+// implemented by apikeys.Service.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*apikeys.Key, error)
+	Allow(key *apikeys.Key) bool
+}
+
+type apiKeyContextKey struct{}
+
+// AuthOptions are the server options requiring an API key or JWT with the
+// reader role on every query service call; server reflection stays open.
+func AuthOptions(auth Authenticator) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, err := authenticate(ctx, auth, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := authenticate(ss.Context(), auth, info.FullMethod)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+		}),
+	}
+}
+
+// authenticate returns ctx carrying the key of the call, or the status
+// rejecting it.
+func authenticate(ctx context.Context, auth Authenticator, method string) (context.Context, error) {
+	if strings.HasPrefix(method, "/grpc.reflection.") {
+		return ctx, nil
+	}
+
+	key, err := auth.Authenticate(ctx, callToken(ctx))
+	if err != nil {
+		if !errors.Is(err, apikeys.ErrUnauthenticated) {
+			log.Err(err).Str("method", method).Msg("Failed to authenticate API key")
+			return nil, status.Error(codes.Internal, "failed to authenticate API key")
+		}
+		return nil, status.Error(codes.Unauthenticated, "a valid API key is required in the "+MetadataAPIKey+" metadata")
+	}
+	if !key.Allows(apikeys.RoleReader) {
+		return nil, status.Error(codes.PermissionDenied, "this method requires the reader role")
+	}
+	if !auth.Allow(key) {
+		return nil, status.Error(codes.ResourceExhausted, "API key rate limit exceeded")
+	}
+	return context.WithValue(ctx, apiKeyContextKey{}, key), nil
+}
+
+// callKey returns the key a call was authenticated with, or nil.
+func callKey(ctx context.Context) *apikeys.Key {
+	key, _ := ctx.Value(apiKeyContextKey{}).(*apikeys.Key)
+	return key
+}
+
+func callToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(MetadataAPIKey); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	if values := md.Get("authorization"); len(values) > 0 {
+		token, _ := strings.CutPrefix(values[0], "Bearer ")
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// authenticatedStream is a server stream whose context carries its key.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
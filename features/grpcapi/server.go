@@ -0,0 +1,242 @@
+// Package grpcapi serves the query API over gRPC next to the Echo HTTP server.
+package grpcapi
+
+//go:generate protoc -I proto --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative proto/query.proto
+
+import (
+	"blacked/features/audit"
+	"blacked/features/entries"
+	"blacked/features/entries/enums"
+	"blacked/features/entries/services"
+	"blacked/features/grpcapi/pb"
+	"blacked/features/stats"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// MaxStreamPageSize caps the page size a StreamEntries client may request.
+const MaxStreamPageSize = 10000
+
+// Server implements pb.QueryServiceServer on top of services.QueryService.
+type Server struct {
+	pb.UnimplementedQueryServiceServer
+
+	svc          *services.QueryService
+	maxBatchURLs int
+}
+
+// NewServer creates a Server; maxBatchURLs bounds QueryBatch like the HTTP bulk endpoints.
+func NewServer(svc *services.QueryService, maxBatchURLs int) *Server {
+	return &Server{svc: svc, maxBatchURLs: maxBatchURLs}
+}
+
+// QueryURL returns the hits for a single URL.
+func (s *Server) QueryURL(ctx context.Context, req *pb.QueryURLRequest) (*pb.QueryURLResponse, error) {
+	if req.GetUrl() == "" {
+		return nil, status.Error(codes.InvalidArgument, "url is required")
+	}
+
+	start := time.Now()
+	res, err := s.query(ctx, req.GetUrl(), queryType(req.GetType()))
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, pb.QueryService_QueryURL_FullMethodName, start, res)
+	return res, nil
+}
+
+// QueryBatch returns the hits for every URL, in request order.
+func (s *Server) QueryBatch(ctx context.Context, req *pb.QueryBatchRequest) (*pb.QueryBatchResponse, error) {
+	urls := req.GetUrls()
+	if len(urls) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "urls is required")
+	}
+	if s.maxBatchURLs > 0 && len(urls) > s.maxBatchURLs {
+		return nil, status.Error(codes.InvalidArgument, "at most "+strconv.Itoa(s.maxBatchURLs)+" urls per batch")
+	}
+
+	start := time.Now()
+	qt := queryType(req.GetType())
+	res := &pb.QueryBatchResponse{Results: make([]*pb.QueryURLResponse, 0, len(urls))}
+	for _, u := range urls {
+		if u == "" {
+			res.Results = append(res.Results, &pb.QueryURLResponse{})
+			continue
+		}
+		r, err := s.query(ctx, u, qt)
+		if err != nil {
+			return nil, err
+		}
+		res.Results = append(res.Results, r)
+	}
+
+	s.audit(ctx, pb.QueryService_QueryBatch_FullMethodName, start, res.Results...)
+	return res, nil
+}
+
+// StreamEntries sends every active entry, optionally of one source, in ID order.
+func (s *Server) StreamEntries(req *pb.StreamEntriesRequest, stream grpc.ServerStreamingServer[pb.Entry]) error {
+	pageSize := int(req.GetPageSize())
+	if pageSize > MaxStreamPageSize {
+		return status.Error(codes.InvalidArgument, "page_size must not exceed "+strconv.Itoa(MaxStreamPageSize))
+	}
+
+	ctx := stream.Context()
+	err := s.svc.StreamEntries(ctx, req.GetSource(), pageSize, func(e *entries.Entry) error {
+		return stream.Send(toPBEntry(e))
+	})
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, services.ErrStreamEntries):
+		return status.Error(codes.Internal, "failed to stream entries")
+	default:
+		// Send failures already carry the transport status.
+		return err
+	}
+}
+
+func (s *Server) query(ctx context.Context, link string, qt *enums.QueryType) (*pb.QueryURLResponse, error) {
+	hits, err := s.svc.Query(ctx, link, qt)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to query blacklist entries")
+	}
+
+	res := &pb.QueryURLResponse{
+		Url:     link,
+		Blocked: len(hits) > 0,
+		Hits:    make([]*pb.Hit, 0, len(hits)),
+	}
+	for _, h := range hits {
+		res.Hits = append(res.Hits, &pb.Hit{Id: h.ID, MatchType: h.MatchType, MatchedValue: h.MatchedValue})
+	}
+	return res, nil
+}
+
+// audit records the answered URLs in the query audit log and the stats
+// rollups when they are on. The caller is the API key of the call, or else
+// the metadata key of the caller header.
+func (s *Server) audit(ctx context.Context, method string, start time.Time, responses ...*pb.QueryURLResponse) {
+	if rollups := stats.Get(); rollups != nil {
+		queries, hits := 0, 0
+		for _, r := range responses {
+			if r.GetUrl() == "" {
+				continue
+			}
+			queries++
+			if r.GetBlocked() {
+				hits++
+			}
+		}
+		rollups.Record(queries, hits)
+	}
+
+	recorder := audit.Get()
+	if recorder == nil {
+		return
+	}
+
+	req := audit.Request{Endpoint: "gRPC " + method, Start: start, Latency: time.Since(start)}
+	if key := callKey(ctx); key != nil {
+		req.Caller = key.Caller()
+	} else if header := recorder.CallerHeader(); header != "" {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(header); len(values) > 0 {
+				req.Caller = values[0]
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		req.RemoteIP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(req.RemoteIP); err == nil {
+			req.RemoteIP = host
+		}
+	}
+
+	results := make([]audit.Result, 0, len(responses))
+	for _, r := range responses {
+		if r.GetUrl() == "" {
+			continue
+		}
+		res := audit.Result{URL: r.GetUrl(), Hit: r.GetBlocked()}
+		if hits := r.GetHits(); len(hits) > 0 {
+			res.MatchType = hits[0].GetMatchType()
+		}
+		results = append(results, res)
+	}
+	recorder.Record(req, results...)
+}
+
+// queryType maps the wire enum to enums.QueryType; unknown values query mixed.
+func queryType(t pb.QueryType) *enums.QueryType {
+	qt := enums.QueryTypeMixed
+	switch t {
+	case pb.QueryType_QUERY_TYPE_FULL:
+		qt = enums.QueryTypeFull
+	case pb.QueryType_QUERY_TYPE_HOST:
+		qt = enums.QueryTypeHost
+	case pb.QueryType_QUERY_TYPE_DOMAIN:
+		qt = enums.QueryTypeDomain
+	case pb.QueryType_QUERY_TYPE_PATH:
+		qt = enums.QueryTypePath
+	}
+	return &qt
+}
+
+func toPBEntry(e *entries.Entry) *pb.Entry {
+	return &pb.Entry{
+		Id:         e.ID,
+		ProcessId:  e.ProcessID,
+		Scheme:     e.Scheme,
+		Domain:     e.Domain,
+		Host:       e.Host,
+		SubDomains: e.SubDomains,
+		Path:       e.Path,
+		RawQuery:   e.RawQuery,
+		SourceUrl:  e.SourceURL,
+		Source:     e.Source,
+		Category:   e.Category,
+		Confidence: e.Confidence,
+		CreatedAt:  e.CreatedAt,
+		UpdatedAt:  e.UpdatedAt,
+	}
+}
+
+// NewGRPCServer registers srv (and server reflection, for grpcurl) on a new
+// grpc.Server created with opts.
+func NewGRPCServer(srv *Server, opts ...grpc.ServerOption) *grpc.Server {
+	gs := grpc.NewServer(opts...)
+	pb.RegisterQueryServiceServer(gs, srv)
+	reflection.Register(gs)
+	return gs
+}
+
+// Listen starts serving gs on port in the background. The returned stop
+// function drains in-flight calls before returning.
+func Listen(gs *grpc.Server, port int) (stop func(), err error) {
+	lis, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		log.Error().Err(err).Int("port", port).Msg("Failed to listen for gRPC")
+		return nil, err
+	}
+
+	go func() {
+		if err := gs.Serve(lis); err != nil {
+			log.Error().Err(err).Msg("gRPC server stopped")
+		}
+	}()
+	log.Info().Str("address", lis.Addr().String()).Msg("gRPC query API listening")
+
+	return gs.GracefulStop, nil
+}
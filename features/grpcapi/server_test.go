@@ -0,0 +1,89 @@
+package grpcapi
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/features/entries/services"
+	"blacked/features/grpcapi/pb"
+	idb "blacked/internal/db"
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestQueryServiceOverGRPC(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+
+	var batch []*entries.Entry
+	for _, e := range []struct{ link, source string }{
+		{"https://bad.example/login", "openphish-feed"},
+		{"https://worse.example/payload.exe", "urlhaus-online"},
+		{"https://worse.example/other", "urlhaus-online"},
+	} {
+		entry, err := entries.FromURL(e.link, e.source, "p1")
+		require.NoError(t, err)
+		batch = append(batch, entry)
+	}
+	require.NoError(t, repo.BatchSaveEntries(ctx, batch))
+
+	lis := bufconn.Listen(1 << 20)
+	gs := NewGRPCServer(NewServer(services.NewQueryServiceWithRepository(repo), 2))
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer cc.Close()
+	client := pb.NewQueryServiceClient(cc)
+
+	res, err := client.QueryURL(ctx, &pb.QueryURLRequest{Url: "https://bad.example/login", Type: pb.QueryType_QUERY_TYPE_FULL})
+	require.NoError(t, err)
+	assert.True(t, res.GetBlocked())
+	assert.NotEmpty(t, res.GetHits())
+
+	_, err = client.QueryURL(ctx, &pb.QueryURLRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	batchRes, err := client.QueryBatch(ctx, &pb.QueryBatchRequest{
+		Urls: []string{"https://bad.example/login", "https://clean.example/"},
+		Type: pb.QueryType_QUERY_TYPE_FULL,
+	})
+	require.NoError(t, err)
+	require.Len(t, batchRes.GetResults(), 2)
+	assert.True(t, batchRes.GetResults()[0].GetBlocked())
+	assert.False(t, batchRes.GetResults()[1].GetBlocked())
+
+	_, err = client.QueryBatch(ctx, &pb.QueryBatchRequest{Urls: []string{"a", "b", "c"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "batch over the limit is rejected")
+
+	stream, err := client.StreamEntries(ctx, &pb.StreamEntriesRequest{Source: "urlhaus-online", PageSize: 1})
+	require.NoError(t, err)
+	var got []string
+	for {
+		e, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, e.GetSourceUrl())
+	}
+	assert.ElementsMatch(t, []string{"https://worse.example/payload.exe", "https://worse.example/other"}, got)
+}
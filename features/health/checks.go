@@ -0,0 +1,176 @@
+package health
+
+import (
+	"blacked/internal/config"
+	"time"
+)
+
+// Component names, used as the component label of the health gauges.
+const (
+	ComponentFeeds          = "feeds"
+	ComponentCacheSync      = "cache_sync"
+	ComponentProviderErrors = "provider_errors"
+	ComponentQueues         = "queues"
+)
+
+// FeedDetail reports the freshness of one feed.
+type FeedDetail struct {
+	Provider    string     `json:"provider"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	AgeSeconds  float64    `json:"age_seconds"`
+	MaxAge      string     `json:"max_age"`
+	Stale       bool       `json:"stale"`
+}
+
+// CacheSyncDetail reports the cache sync activity.
+type CacheSyncDetail struct {
+	State         string     `json:"state"`
+	LastCompleted *time.Time `json:"last_completed_at,omitempty"`
+}
+
+// ProviderErrorsDetail reports the provider runs of the error window.
+type ProviderErrorsDetail struct {
+	Window string `json:"window"`
+	Runs   int    `json:"runs"`
+	Failed int    `json:"failed"`
+}
+
+// QueueDetail reports the fill of one async queue.
+type QueueDetail struct {
+	Name       string  `json:"name"`
+	Queued     int     `json:"queued"`
+	Capacity   int     `json:"capacity"`
+	Fill       float64 `json:"fill"`
+	Dropped    int64   `json:"dropped"`
+	NewDropped int64   `json:"new_dropped"` // Since the previous check; any makes the queue saturated
+}
+
+// Checks returns the health checks with the thresholds of cfg.
+func Checks(cfg config.HealthConfig) []Check {
+	cacheMaxAge := cfg.CacheMaxAge.Seconds()
+	return []Check{
+		{
+			Name:        ComponentFeeds,
+			Alert:       "BlackedFeedsStale",
+			Description: "Share of enabled feeds without a successful run within their allowed age",
+			Unit:        "ratio",
+			Weight:      0.35,
+			Warn:        0,
+			Critical:    0.5,
+			For:         30 * time.Minute,
+			measure:     measureFeeds,
+		},
+		{
+			Name:        ComponentCacheSync,
+			Alert:       "BlackedCacheSyncStale",
+			Description: "Seconds since the last completed cache sync",
+			Unit:        "seconds",
+			Weight:      0.25,
+			Warn:        cacheMaxAge,
+			Critical:    2 * cacheMaxAge,
+			For:         15 * time.Minute,
+			measure:     measureCacheSync,
+		},
+		{
+			Name:        ComponentProviderErrors,
+			Alert:       "BlackedProviderErrors",
+			Description: "Share of provider runs that failed within the error window",
+			Unit:        "ratio",
+			Weight:      0.25,
+			Warn:        0.1,
+			Critical:    0.5,
+			For:         15 * time.Minute,
+			measure:     measureProviderErrors,
+		},
+		{
+			Name:        ComponentQueues,
+			Alert:       "BlackedQueueSaturated",
+			Description: "Fill of the fullest async write queue (hit counter, audit log); 1 when one dropped items",
+			Unit:        "ratio",
+			Weight:      0.15,
+			Warn:        0.5,
+			Critical:    0.9,
+			For:         5 * time.Minute,
+			measure:     measureQueues,
+		},
+	}
+}
+
+// measureFeeds returns the share of stale feeds. A feed that has not
+// succeeded since the process started is stale once the process is older
+// than its allowed age.
+func measureFeeds(s *Snapshot) (float64, any, bool) {
+	if len(s.Feeds) == 0 {
+		return 0, nil, false
+	}
+
+	details := make([]FeedDetail, 0, len(s.Feeds))
+	stale := 0
+	for _, f := range s.Feeds {
+		d := FeedDetail{Provider: f.Provider, MaxAge: f.MaxAge.String()}
+		since := s.Started
+		if !f.LastSuccess.IsZero() {
+			last := f.LastSuccess
+			d.LastSuccess = &last
+			since = last
+		}
+		age := s.Now.Sub(since)
+		d.AgeSeconds = age.Seconds()
+		if age > f.MaxAge {
+			d.Stale = true
+			stale++
+		}
+		details = append(details, d)
+	}
+	return float64(stale) / float64(len(s.Feeds)), details, true
+}
+
+// measureCacheSync returns the seconds since the last completed cache sync,
+// or since the process started when none completed yet.
+func measureCacheSync(s *Snapshot) (float64, any, bool) {
+	if s.CacheSync == nil {
+		return 0, nil, false
+	}
+
+	d := CacheSyncDetail{State: s.CacheSync.State}
+	since := s.Started
+	if !s.CacheSync.LastCompleted.IsZero() {
+		last := s.CacheSync.LastCompleted
+		d.LastCompleted = &last
+		since = last
+	}
+	return s.Now.Sub(since).Seconds(), d, true
+}
+
+// measureProviderErrors returns the failed share of the provider runs in
+// the error window.
+func measureProviderErrors(s *Snapshot) (float64, any, bool) {
+	if s.Runs == 0 {
+		return 0, nil, false
+	}
+	d := ProviderErrorsDetail{Window: s.ErrorWindow.String(), Runs: s.Runs, Failed: s.FailedRuns}
+	return float64(s.FailedRuns) / float64(s.Runs), d, true
+}
+
+// measureQueues returns the fill of the fullest queue; a queue that dropped
+// items since the previous check counts as full.
+func measureQueues(s *Snapshot) (float64, any, bool) {
+	if len(s.Queues) == 0 {
+		return 0, nil, false
+	}
+
+	details := make([]QueueDetail, 0, len(s.Queues))
+	var worst float64
+	for _, q := range s.Queues {
+		d := QueueDetail{Name: q.Name, Queued: q.Queued, Capacity: q.Capacity, Dropped: q.Dropped, NewDropped: q.NewDropped}
+		if q.Capacity > 0 {
+			d.Fill = float64(q.Queued) / float64(q.Capacity)
+		}
+		if q.NewDropped > 0 {
+			d.Fill = 1
+		}
+		worst = max(worst, d.Fill)
+		details = append(details, d)
+	}
+	return worst, details, true
+}
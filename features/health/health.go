@@ -0,0 +1,166 @@
+// Package health rolls feed freshness, cache sync age, the provider error
+// rate and queue saturation into one score. The same check definitions
+// drive the score, the /healthz/details report, the health gauges and the
+// Prometheus alert rules, so what pages an operator is what the report shows.
+package health
+
+import (
+	"blacked/features/maintenance"
+	"time"
+)
+
+// Status is the state of a component or of the whole service.
+type Status string
+
+const (
+	StatusHealthy   Status = "healthy"   // Value at or below the warning threshold
+	StatusDegraded  Status = "degraded"  // Value above the warning threshold
+	StatusUnhealthy Status = "unhealthy" // Value at or above the critical threshold
+	StatusUnknown   Status = "unknown"   // Nothing to measure in this process
+)
+
+// Overall score thresholds, used for the status-independent score alerts.
+const (
+	ScoreWarn     = 0.8
+	ScoreCritical = 0.5
+)
+
+// Snapshot is the state the checks measure, gathered by a Monitor.
+type Snapshot struct {
+	Now     time.Time
+	Started time.Time // Start of the process: the grace period of feeds and cache without history
+
+	Feeds     []FeedState
+	CacheSync *CacheSyncState // Nil when the process keeps no cache
+
+	Runs        int // Provider runs in the error window
+	FailedRuns  int
+	ErrorWindow time.Duration
+
+	Queues []QueueState
+}
+
+// FeedState is the last successful run of a provider.
+type FeedState struct {
+	Provider    string
+	LastSuccess time.Time // Zero when unknown
+	MaxAge      time.Duration
+}
+
+// CacheSyncState is the cache sync activity of the process.
+type CacheSyncState struct {
+	State         string    // idle, running or queued
+	LastCompleted time.Time // Zero when no sync completed yet
+}
+
+// QueueState is the fill of an async write queue.
+type QueueState struct {
+	Name       string
+	Queued     int
+	Capacity   int
+	Dropped    int64 // Items dropped by the queue since start
+	NewDropped int64 // Items dropped since the previous snapshot
+}
+
+// Check is the definition of one health component. Values grow as health
+// worsens: a value at or below Warn is healthy, one at or above Critical
+// fails the component, and the score falls linearly in between.
+type Check struct {
+	Name        string
+	Alert       string // Name of the generated alert rule
+	Description string
+	Unit        string // ratio or seconds
+	Weight      float64
+	Warn        float64
+	Critical    float64
+	For         time.Duration // How long a threshold must be crossed before its alert fires
+
+	// measure returns the value and details of the component, or false when
+	// the process has nothing to measure.
+	measure func(s *Snapshot) (float64, any, bool)
+}
+
+// Component is the evaluation of one Check.
+type Component struct {
+	Name     string  `json:"name"`
+	Status   Status  `json:"status"`
+	Score    float64 `json:"score"`
+	Value    float64 `json:"value"`
+	Warn     float64 `json:"warn"`
+	Critical float64 `json:"critical"`
+	Unit     string  `json:"unit"`
+	Detail   any     `json:"detail,omitempty"`
+}
+
+// Report is the evaluation of every check.
+type Report struct {
+	Status     Status      `json:"status"` // Worst component status
+	Score      float64     `json:"score"`  // Weighted mean of the measured component scores
+	CheckedAt  time.Time   `json:"checked_at"`
+	Components []Component `json:"components"`
+
+	// Maintenance is the read-only mode, set while it is on. Feeds age
+	// meanwhile, as ingestion is paused.
+	Maintenance *maintenance.State `json:"maintenance,omitempty"`
+}
+
+// Evaluate scores s against checks. Components with nothing to measure are
+// reported as unknown and left out of the score; with none measured the
+// service counts as healthy, as there is nothing failing.
+func Evaluate(checks []Check, s *Snapshot) *Report {
+	report := &Report{Status: StatusUnknown, Score: 1, CheckedAt: s.Now}
+
+	var weighted, weights float64
+	for _, c := range checks {
+		comp := Component{Name: c.Name, Status: StatusUnknown, Warn: c.Warn, Critical: c.Critical, Unit: c.Unit}
+		value, detail, ok := c.measure(s)
+		if ok {
+			comp.Value = value
+			comp.Detail = detail
+			comp.Score = c.score(value)
+			comp.Status = c.status(value)
+			weighted += comp.Score * c.Weight
+			weights += c.Weight
+			report.Status = worse(report.Status, comp.Status)
+		}
+		report.Components = append(report.Components, comp)
+	}
+	if weights > 0 {
+		report.Score = weighted / weights
+	}
+	if report.Status == StatusUnknown {
+		report.Status = StatusHealthy
+	}
+	return report
+}
+
+func (c Check) score(value float64) float64 {
+	switch {
+	case value <= c.Warn:
+		return 1
+	case value >= c.Critical:
+		return 0
+	default:
+		return 1 - (value-c.Warn)/(c.Critical-c.Warn)
+	}
+}
+
+func (c Check) status(value float64) Status {
+	switch {
+	case value <= c.Warn:
+		return StatusHealthy
+	case value >= c.Critical:
+		return StatusUnhealthy
+	default:
+		return StatusDegraded
+	}
+}
+
+var statusRank = map[Status]int{StatusUnknown: 0, StatusHealthy: 1, StatusDegraded: 2, StatusUnhealthy: 3}
+
+func worse(a, b Status) Status {
+	if statusRank[b] > statusRank[a] {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,135 @@
+package health
+
+import (
+	"blacked/internal/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+var testConfig = config.HealthConfig{
+	FeedStaleFactor: 2,
+	CacheMaxAge:     time.Hour,
+	ErrorWindow:     24 * time.Hour,
+}
+
+func component(t *testing.T, r *Report, name string) Component {
+	t.Helper()
+	for _, c := range r.Components {
+		if c.Name == name {
+			return c
+		}
+	}
+	require.Failf(t, "missing component", name)
+	return Component{}
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Now()
+	s := &Snapshot{
+		Now:     now,
+		Started: now.Add(-48 * time.Hour),
+		Feeds: []FeedState{
+			{Provider: "fresh", LastSuccess: now.Add(-time.Hour), MaxAge: 2 * time.Hour},
+			{Provider: "stale", LastSuccess: now.Add(-3 * time.Hour), MaxAge: 2 * time.Hour},
+			{Provider: "never", MaxAge: 2 * time.Hour}, // Process older than MaxAge
+			{Provider: "ok", LastSuccess: now, MaxAge: time.Hour},
+		},
+		CacheSync:   &CacheSyncState{State: "idle", LastCompleted: now.Add(-90 * time.Minute)},
+		Runs:        10,
+		FailedRuns:  1,
+		ErrorWindow: 24 * time.Hour,
+		Queues: []QueueState{
+			{Name: "hits", Queued: 2, Capacity: 10},
+			{Name: "audit", Queued: 1, Capacity: 10, Dropped: 5, NewDropped: 5},
+		},
+	}
+
+	r := Evaluate(Checks(testConfig), s)
+	require.Len(t, r.Components, 4)
+
+	feeds := component(t, r, ComponentFeeds)
+	assert.Equal(t, 0.5, feeds.Value, "two of four feeds are stale")
+	assert.Equal(t, StatusUnhealthy, feeds.Status)
+	assert.Equal(t, 0.0, feeds.Score)
+	details := feeds.Detail.([]FeedDetail)
+	assert.True(t, details[2].Stale)
+	assert.Nil(t, details[2].LastSuccess)
+
+	cache := component(t, r, ComponentCacheSync)
+	assert.InDelta(t, 5400, cache.Value, 1)
+	assert.Equal(t, StatusDegraded, cache.Status, "older than cache_max_age, younger than twice it")
+	assert.InDelta(t, 0.5, cache.Score, 0.01)
+
+	errs := component(t, r, ComponentProviderErrors)
+	assert.InDelta(t, 0.1, errs.Value, 1e-9)
+	assert.Equal(t, StatusHealthy, errs.Status)
+	assert.Equal(t, 1.0, errs.Score)
+
+	queues := component(t, r, ComponentQueues)
+	assert.Equal(t, 1.0, queues.Value, "a queue that dropped items counts as full")
+	assert.Equal(t, StatusUnhealthy, queues.Status)
+
+	assert.Equal(t, StatusUnhealthy, r.Status)
+	assert.InDelta(t, (0*0.35+0.5*0.25+1*0.25+0*0.15)/1.0, r.Score, 0.01)
+}
+
+func TestEvaluateSkipsUnmeasured(t *testing.T) {
+	now := time.Now()
+	s := &Snapshot{
+		Now:     now,
+		Started: now, // Feeds without a run are within their grace period
+		Feeds:   []FeedState{{Provider: "new", MaxAge: time.Hour}},
+	}
+
+	r := Evaluate(Checks(testConfig), s)
+	assert.Equal(t, StatusHealthy, r.Status)
+	assert.Equal(t, 1.0, r.Score)
+	assert.Equal(t, StatusHealthy, component(t, r, ComponentFeeds).Status)
+	for _, name := range []string{ComponentCacheSync, ComponentProviderErrors, ComponentQueues} {
+		assert.Equal(t, StatusUnknown, component(t, r, name).Status, name)
+	}
+
+	r = Evaluate(Checks(testConfig), &Snapshot{Now: now, Started: now})
+	assert.Equal(t, StatusHealthy, r.Status, "nothing measured, nothing failing")
+	assert.Equal(t, 1.0, r.Score)
+}
+
+func TestAlertRules(t *testing.T) {
+	rules := AlertRules(Checks(testConfig))
+	require.Len(t, rules.Groups, 1)
+	group := rules.Groups[0]
+	assert.Equal(t, RuleGroupName, group.Name)
+	require.Len(t, group.Rules, 2*4+2)
+
+	cache := group.Rules[2:4]
+	assert.Equal(t, "BlackedCacheSyncStale", cache[0].Alert)
+	assert.Equal(t, `blacked_health_component_value{component="cache_sync"} > 3600`, cache[0].Expr)
+	assert.Equal(t, "warning", cache[0].Labels["severity"])
+	assert.Equal(t, `blacked_health_component_value{component="cache_sync"} >= 7200`, cache[1].Expr)
+	assert.Equal(t, "critical", cache[1].Labels["severity"])
+	assert.Equal(t, "15m", cache[0].For)
+
+	score := group.Rules[len(group.Rules)-1]
+	assert.Equal(t, "BlackedHealthScoreLow", score.Alert)
+	assert.Equal(t, "blacked_health_score < 0.5", score.Expr)
+
+	out, err := yaml.Marshal(rules)
+	require.NoError(t, err)
+	var decoded RuleFile
+	require.NoError(t, yaml.Unmarshal(out, &decoded))
+	assert.Equal(t, *rules, decoded)
+}
+
+func TestMonitorCountsNewDrops(t *testing.T) {
+	m := NewMonitor(testConfig)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	assert.Equal(t, int64(3), m.queueState("hits", 0, 10, 3).NewDropped)
+	assert.Equal(t, int64(0), m.queueState("hits", 0, 10, 3).NewDropped)
+	assert.Equal(t, int64(2), m.queueState("hits", 0, 10, 5).NewDropped)
+}
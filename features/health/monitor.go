@@ -0,0 +1,185 @@
+package health
+
+import (
+	"blacked/features/audit"
+	"blacked/features/entry_collector"
+	"blacked/features/hits"
+	"blacked/features/maintenance"
+	"blacked/features/providers"
+	"blacked/internal/collector"
+	"blacked/internal/config"
+	"blacked/internal/utils"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Monitor evaluates the health checks against the running process and
+// publishes the result on the health gauges.
+type Monitor struct {
+	cfg     config.HealthConfig
+	checks  []Check
+	started time.Time
+	pond    *entry_collector.PondCollector // Nil when the process keeps no cache
+
+	mu      sync.Mutex
+	dropped map[string]int64 // Items dropped per queue at the previous snapshot
+}
+
+// NewMonitor creates a Monitor with the checks of cfg.
+func NewMonitor(cfg config.HealthConfig) *Monitor {
+	return &Monitor{
+		cfg:     cfg,
+		checks:  Checks(cfg),
+		started: time.Now(),
+		dropped: make(map[string]int64),
+	}
+}
+
+// SetCacheCollector sets the collector whose cache syncs are checked.
+func (m *Monitor) SetCacheCollector(pond *entry_collector.PondCollector) *Monitor {
+	m.pond = pond
+	return m
+}
+
+// Checks returns the check definitions.
+func (m *Monitor) Checks() []Check {
+	return m.checks
+}
+
+// AlertRules returns the Prometheus alerting rules of the checks.
+func (m *Monitor) AlertRules() *RuleFile {
+	return AlertRules(m.checks)
+}
+
+// Evaluate scores the current state and publishes it on the health gauges.
+func (m *Monitor) Evaluate() *Report {
+	report := Evaluate(m.checks, m.snapshot())
+	if svc := maintenance.Get(); svc != nil {
+		if state := svc.State(context.Background()); state.ReadOnly {
+			report.Maintenance = &state
+		}
+	}
+
+	if mc, err := collector.GetMetricsCollector(); err == nil {
+		mc.SetHealthScore(report.Score)
+		for _, c := range report.Components {
+			if c.Status == StatusUnknown {
+				mc.ClearHealthComponent(c.Name)
+				continue
+			}
+			mc.SetHealthComponent(c.Name, c.Score, c.Value)
+		}
+	}
+	return report
+}
+
+// Run refreshes the health gauges every [Health] interval until ctx is done.
+func (m *Monitor) Run(ctx context.Context) {
+	interval := m.cfg.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	m.Evaluate()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report := m.Evaluate()
+			if report.Status != StatusHealthy {
+				log.Warn().Str("status", string(report.Status)).Float64("score", report.Score).Msg("Service health degraded")
+			}
+		}
+	}
+}
+
+// snapshot gathers the state of the process.
+func (m *Monitor) snapshot() *Snapshot {
+	now := time.Now()
+	s := &Snapshot{Now: now, Started: m.started, ErrorWindow: m.cfg.ErrorWindow}
+
+	pm := providers.GetProcessManager()
+	lastSuccess := make(map[string]time.Time)
+	for _, run := range pm.GetProviderRuns(time.Time{}) {
+		if run.Status == "completed" && run.EndTime.After(lastSuccess[run.Provider]) {
+			lastSuccess[run.Provider] = run.EndTime
+		}
+	}
+	for _, p := range *providers.GetProviders() {
+		name := p.GetName()
+		last := lastSuccess[name]
+		if fetched := storedResponseTime(name); fetched.After(last) {
+			last = fetched
+		}
+		s.Feeds = append(s.Feeds, FeedState{
+			Provider:    name,
+			LastSuccess: last,
+			MaxAge:      time.Duration(m.cfg.FeedStaleFactor * float64(utils.ParseTTLFromCron(p.GetCronSchedule()))),
+		})
+	}
+
+	for _, run := range pm.GetProviderRuns(now.Add(-m.cfg.ErrorWindow)) {
+		s.Runs++
+		if run.Status == "failed" {
+			s.FailedRuns++
+		}
+	}
+
+	if m.pond != nil {
+		status := m.pond.GetCacheSyncStatus()
+		s.CacheSync = &CacheSyncState{State: status.State}
+		if status.LastCompletedAt != nil {
+			s.CacheSync.LastCompleted = *status.LastCompletedAt
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c := hits.GetCounter(); c != nil {
+		queued, capacity := c.Queued()
+		s.Queues = append(s.Queues, m.queueState("hits", queued, capacity, c.Dropped()))
+	}
+	if r := audit.Get(); r != nil {
+		queued, capacity := r.Queued()
+		s.Queues = append(s.Queues, m.queueState("audit", queued, capacity, r.Dropped()))
+	}
+	return s
+}
+
+// queueState returns the state of the queue name, counting the items it
+// dropped since the previous snapshot. m.mu must be held.
+func (m *Monitor) queueState(name string, queued, capacity int, dropped int64) QueueState {
+	q := QueueState{Name: name, Queued: queued, Capacity: capacity, Dropped: dropped, NewDropped: dropped - m.dropped[name]}
+	m.dropped[name] = dropped
+	return q
+}
+
+// storedResponseTime returns when the stored response of provider was
+// fetched, or zero without one. It covers runs of earlier processes and of
+// a separate scheduler process sharing the store path.
+func storedResponseTime(provider string) time.Time {
+	cfg := config.GetConfig().Collector
+	if !cfg.StoreResponses {
+		return time.Time{}
+	}
+
+	_, metaFile := utils.GenerateFilenames(cfg.StorePath, provider)
+	data, err := os.ReadFile(metaFile)
+	if err != nil {
+		return time.Time{}
+	}
+	var meta utils.ResponseMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		log.Debug().Err(err).Str("file", metaFile).Msg("Failed to decode stored response metadata")
+		return time.Time{}
+	}
+	return meta.CreatedAt
+}
@@ -0,0 +1,94 @@
+package health
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// RuleGroupName is the name of the generated Prometheus rule group.
+const RuleGroupName = "blacked-health"
+
+// RuleFile is a Prometheus alerting rule file.
+type RuleFile struct {
+	Groups []RuleGroup `json:"groups" yaml:"groups"`
+}
+
+// RuleGroup is a group of alerting rules evaluated together.
+type RuleGroup struct {
+	Name  string `json:"name" yaml:"name"`
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule is one Prometheus alerting rule.
+type Rule struct {
+	Alert       string            `json:"alert" yaml:"alert"`
+	Expr        string            `json:"expr" yaml:"expr"`
+	For         string            `json:"for,omitempty" yaml:"for,omitempty"`
+	Labels      map[string]string `json:"labels" yaml:"labels"`
+	Annotations map[string]string `json:"annotations" yaml:"annotations"`
+}
+
+// AlertRules returns the alerting rules of checks: a warning and a critical
+// rule per component on blacked_health_component_value, at the thresholds
+// the score uses, plus two rules on the overall blacked_health_score.
+func AlertRules(checks []Check) *RuleFile {
+	group := RuleGroup{Name: RuleGroupName}
+	for _, c := range checks {
+		metric := fmt.Sprintf("blacked_health_component_value{component=%q}", c.Name)
+		group.Rules = append(group.Rules,
+			componentRule(c, "warning", metric+" > "+formatThreshold(c.Warn)),
+			componentRule(c, "critical", metric+" >= "+formatThreshold(c.Critical)),
+		)
+	}
+
+	group.Rules = append(group.Rules,
+		scoreRule("warning", ScoreWarn, 15*time.Minute),
+		scoreRule("critical", ScoreCritical, 5*time.Minute),
+	)
+	return &RuleFile{Groups: []RuleGroup{group}}
+}
+
+func componentRule(c Check, severity, expr string) Rule {
+	return Rule{
+		Alert: c.Alert,
+		Expr:  expr,
+		For:   formatDuration(c.For),
+		Labels: map[string]string{
+			"severity":  severity,
+			"component": c.Name,
+		},
+		Annotations: map[string]string{
+			"summary":     c.Description + " crossed its " + severity + " threshold",
+			"description": fmt.Sprintf("Blacked health component %s measures {{ $value }} (%s; warning above %s, critical at %s); see /healthz/details.", c.Name, c.Unit, formatThreshold(c.Warn), formatThreshold(c.Critical)),
+		},
+	}
+}
+
+func scoreRule(severity string, below float64, pending time.Duration) Rule {
+	return Rule{
+		Alert: "BlackedHealthScoreLow",
+		Expr:  "blacked_health_score < " + formatThreshold(below),
+		For:   formatDuration(pending),
+		Labels: map[string]string{
+			"severity": severity,
+		},
+		Annotations: map[string]string{
+			"summary":     "Blacked health score is below " + formatThreshold(below),
+			"description": "The composite health score is {{ $value }}; see /healthz/details for the failing components.",
+		},
+	}
+}
+
+func formatThreshold(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return model.Duration(d).String()
+}
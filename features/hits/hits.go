@@ -0,0 +1,171 @@
+// Package hits counts how often each blacklist entry matches a query, so
+// feeds can be judged by the traffic they actually block.
+package hits
+
+import (
+	"blacked/internal/config"
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Stat is the hit count of one entry.
+type Stat struct {
+	EntryID    string     `json:"entry_id"`
+	Hits       int64      `json:"hits"`
+	FirstHitAt *time.Time `json:"first_hit_at,omitempty"`
+	LastHitAt  *time.Time `json:"last_hit_at,omitempty"`
+}
+
+// TopEntry is one row of the most-hit entries report.
+type TopEntry struct {
+	Stat
+	Source    string `json:"source"`
+	SourceURL string `json:"source_url"`
+	Category  string `json:"category,omitempty"`
+}
+
+// SourceHits summarizes the hits of one feed. Feeds whose HitEntries stay
+// at zero never matched real traffic.
+type SourceHits struct {
+	Source     string     `json:"source"`
+	Entries    int64      `json:"entries"`     // Active entries
+	HitEntries int64      `json:"hit_entries"` // Active entries hit at least once
+	Hits       int64      `json:"hits"`
+	LastHitAt  *time.Time `json:"last_hit_at,omitempty"`
+}
+
+var (
+	globalCounter *Counter
+	once          sync.Once
+)
+
+// InitCounter starts the global counter on db when [Hits] is enabled and
+// returns it; nil means hit accounting is off.
+func InitCounter(db *sql.DB) *Counter {
+	once.Do(func() {
+		cfg := config.GetConfig().Hits
+		if !cfg.Enabled {
+			log.Debug().Msg("Entry hit accounting disabled")
+			return
+		}
+		globalCounter = NewCounter(NewSQLiteRepository(db), cfg.BufferSize, cfg.FlushInterval)
+		log.Info().Int("buffer_size", cfg.BufferSize).Dur("flush_interval", cfg.FlushInterval).Msg("Entry hit accounting enabled")
+	})
+	return globalCounter
+}
+
+// GetCounter returns the global counter, or nil when hit accounting is off.
+func GetCounter() *Counter {
+	return globalCounter
+}
+
+// Counter records entry hits off the query path: Record only enqueues IDs,
+// and a background loop sums them and writes the totals every flush interval.
+type Counter struct {
+	repo          Repository
+	queue         chan string
+	flushInterval time.Duration
+	dropped       atomic.Int64
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewCounter starts a Counter queueing up to bufferSize IDs between flushes.
+func NewCounter(repo Repository, bufferSize int, flushInterval time.Duration) *Counter {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+
+	c := &Counter{
+		repo:          repo,
+		queue:         make(chan string, bufferSize),
+		flushInterval: flushInterval,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+// Record counts one hit for every id. It never blocks: when the queue is
+// full the hit is dropped, since losing a count beats slowing a query.
+func (c *Counter) Record(ids ...string) {
+	for _, id := range ids {
+		select {
+		case c.queue <- id:
+		default:
+			c.dropped.Add(1)
+		}
+	}
+}
+
+// Dropped returns the number of hits lost to a full queue.
+func (c *Counter) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+// Queued returns the number of hits waiting in the queue and its capacity.
+func (c *Counter) Queued() (queued, capacity int) {
+	return len(c.queue), cap(c.queue)
+}
+
+// Close stops the counter after writing every queued hit.
+func (c *Counter) Close() {
+	c.stopOnce.Do(func() { close(c.stop) })
+	<-c.done
+}
+
+func (c *Counter) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.flushInterval)
+	defer ticker.Stop()
+
+	pending := make(map[string]int64)
+	for {
+		select {
+		case id := <-c.queue:
+			pending[id]++
+		case <-ticker.C:
+			pending = c.flush(pending)
+		case <-c.stop:
+			for {
+				select {
+				case id := <-c.queue:
+					pending[id]++
+				default:
+					c.flush(pending)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush writes pending and returns the map to keep counting into. Counts
+// that fail to save are kept for the next flush.
+func (c *Counter) flush(pending map[string]int64) map[string]int64 {
+	if len(pending) == 0 {
+		return pending
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := c.repo.Increment(ctx, pending, time.Now()); err != nil {
+		log.Warn().Err(err).Int("entries", len(pending)).Msg("Failed to save entry hits, retrying next flush")
+		return pending
+	}
+	log.Trace().Int("entries", len(pending)).Msg("Entry hits saved")
+	return make(map[string]int64)
+}
@@ -0,0 +1,90 @@
+package hits
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	idb "blacked/internal/db"
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterFlushesOnClose(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	entryRepo := repository.NewSQLiteRepository(conn)
+	hot, err := entries.FromURL("https://hot.example.com/", "feed-a", "p1")
+	require.NoError(t, err)
+	cold, err := entries.FromURL("https://cold.example.com/", "feed-a", "p1")
+	require.NoError(t, err)
+	never, err := entries.FromURL("https://never.example.net/", "feed-b", "p1")
+	require.NoError(t, err)
+	require.NoError(t, entryRepo.BatchSaveEntries(ctx, []*entries.Entry{hot, cold, never}))
+
+	repo := NewSQLiteRepository(conn)
+	counter := NewCounter(repo, 100, time.Hour)
+	counter.Record(hot.ID, hot.ID, cold.ID)
+	counter.Record(hot.ID)
+	counter.Close()
+	assert.Zero(t, counter.Dropped())
+
+	svc := NewServiceWithRepository(repo)
+	stat, err := svc.Stat(ctx, hot.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, stat.Hits)
+	assert.NotNil(t, stat.LastHitAt)
+
+	stat, err = svc.Stat(ctx, never.ID)
+	require.NoError(t, err)
+	assert.Zero(t, stat.Hits, "entries never hit report zero")
+	assert.Nil(t, stat.LastHitAt)
+
+	report, err := svc.Report(ctx, "", 0)
+	require.NoError(t, err)
+	require.Len(t, report.Top, 2)
+	assert.Equal(t, hot.ID, report.Top[0].EntryID)
+	assert.Equal(t, "feed-a", report.Top[0].Source)
+
+	require.Len(t, report.Sources, 2)
+	assert.Equal(t, SourceHits{Source: "feed-a", Entries: 2, HitEntries: 2, Hits: 4, LastHitAt: report.Sources[0].LastHitAt}, report.Sources[0])
+	assert.Equal(t, SourceHits{Source: "feed-b", Entries: 1}, report.Sources[1], "feeds that never match show zero hits")
+
+	report, err = svc.Report(ctx, "feed-b", 10)
+	require.NoError(t, err)
+	assert.Empty(t, report.Top)
+}
+
+func TestCounterDropsWhenFull(t *testing.T) {
+	repo := &blockingRepository{release: make(chan struct{})}
+	counter := NewCounter(repo, 1, time.Millisecond)
+
+	// The first flush blocks, so the loop stops draining the one-slot queue.
+	counter.Record("a")
+	require.Eventually(t, func() bool { return repo.calls.Load() > 0 }, time.Second, time.Millisecond)
+	counter.Record("b", "c", "d")
+	assert.Positive(t, counter.Dropped())
+
+	close(repo.release)
+	counter.Close()
+}
+
+// blockingRepository holds every Increment until release is closed.
+type blockingRepository struct {
+	Repository
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (r *blockingRepository) Increment(ctx context.Context, counts map[string]int64, at time.Time) error {
+	r.calls.Add(1)
+	<-r.release
+	return nil
+}
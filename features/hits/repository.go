@@ -0,0 +1,176 @@
+package hits
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrQueryHits = errors.New("failed to query entry hits from SQLite")
+	ErrSaveHits  = errors.New("failed to save entry hits in SQLite")
+)
+
+// Repository stores per-entry hit counts.
+type Repository interface {
+	// Increment adds counts (entry ID → hits) to the stored totals, stamping at as the last hit.
+	Increment(ctx context.Context, counts map[string]int64, at time.Time) error
+	// Get returns the stat of one entry; an entry never hit has zero hits.
+	Get(ctx context.Context, entryID string) (*Stat, error)
+	// Top returns the most-hit entries, optionally of one source.
+	Top(ctx context.Context, source string, limit int) ([]TopEntry, error)
+	// Sources returns the hit summary of every source with active entries.
+	Sources(ctx context.Context) ([]SourceHits, error)
+}
+
+// SQLiteRepository is the SQLite implementation of Repository.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLiteRepository instance.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// Increment upserts every count in one transaction.
+func (r *SQLiteRepository) Increment(ctx context.Context, counts map[string]int64, at time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Err(err).Msg("Failed to begin entry hits transaction")
+		return ErrSaveHits
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO entry_hits (entry_id, hits, first_hit_at, last_hit_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (entry_id) DO UPDATE SET
+			hits = hits + EXCLUDED.hits,
+			last_hit_at = EXCLUDED.last_hit_at`)
+	if err != nil {
+		log.Err(err).Msg("Failed to prepare entry hits upsert")
+		return ErrSaveHits
+	}
+	defer stmt.Close()
+
+	now := at.UnixNano()
+	for id, n := range counts {
+		if _, err := stmt.ExecContext(ctx, id, n, now, now); err != nil {
+			log.Err(err).Str("entry_id", id).Msg("Failed to upsert entry hits")
+			return ErrSaveHits
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Msg("Failed to commit entry hits")
+		return ErrSaveHits
+	}
+	return nil
+}
+
+// Get returns the stat of entryID.
+func (r *SQLiteRepository) Get(ctx context.Context, entryID string) (*Stat, error) {
+	var first, last sql.NullInt64
+	stat := &Stat{EntryID: entryID}
+
+	err := r.db.QueryRowContext(ctx,
+		"SELECT hits, first_hit_at, last_hit_at FROM entry_hits WHERE entry_id = ?", entryID,
+	).Scan(&stat.Hits, &first, &last)
+	if errors.Is(err, sql.ErrNoRows) {
+		return stat, nil
+	}
+	if err != nil {
+		log.Err(err).Str("entry_id", entryID).Msg("Failed to query entry hits")
+		return nil, ErrQueryHits
+	}
+
+	stat.FirstHitAt = unixNano(first)
+	stat.LastHitAt = unixNano(last)
+	return stat, nil
+}
+
+// Top returns up to limit entries by descending hits; deleted entries are
+// included, since their hits still count towards their feed.
+func (r *SQLiteRepository) Top(ctx context.Context, source string, limit int) ([]TopEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT h.entry_id, h.hits, h.first_hit_at, h.last_hit_at, e.source, e.source_url, e.category
+		FROM entry_hits h
+		JOIN entries e ON e.id = h.entry_id
+		WHERE ? = '' OR e.source = ?
+		ORDER BY h.hits DESC, h.last_hit_at DESC
+		LIMIT ?`, source, source, limit)
+	if err != nil {
+		log.Err(err).Str("source", source).Msg("Failed to query top entry hits")
+		return nil, ErrQueryHits
+	}
+	defer rows.Close()
+
+	top := []TopEntry{}
+	for rows.Next() {
+		var (
+			t           TopEntry
+			first, last sql.NullInt64
+			category    sql.NullString
+		)
+		if err := rows.Scan(&t.EntryID, &t.Hits, &first, &last, &t.Source, &t.SourceURL, &category); err != nil {
+			log.Err(err).Msg("Failed to scan top entry hits")
+			return nil, ErrQueryHits
+		}
+		t.FirstHitAt = unixNano(first)
+		t.LastHitAt = unixNano(last)
+		t.Category = category.String
+		top = append(top, t)
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Rows iteration error for top entry hits")
+		return nil, ErrQueryHits
+	}
+	return top, nil
+}
+
+// Sources counts active entries per source against their hits, most-hit source first.
+func (r *SQLiteRepository) Sources(ctx context.Context) ([]SourceHits, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT e.source, COUNT(*), COUNT(h.entry_id), COALESCE(SUM(h.hits), 0), MAX(h.last_hit_at)
+		FROM entries e
+		LEFT JOIN entry_hits h ON h.entry_id = e.id
+		WHERE e.deleted_at IS NULL
+		GROUP BY e.source
+		ORDER BY 4 DESC, e.source`)
+	if err != nil {
+		log.Err(err).Msg("Failed to query source hits")
+		return nil, ErrQueryHits
+	}
+	defer rows.Close()
+
+	sources := []SourceHits{}
+	for rows.Next() {
+		var (
+			s    SourceHits
+			last sql.NullInt64
+		)
+		if err := rows.Scan(&s.Source, &s.Entries, &s.HitEntries, &s.Hits, &last); err != nil {
+			log.Err(err).Msg("Failed to scan source hits")
+			return nil, ErrQueryHits
+		}
+		s.LastHitAt = unixNano(last)
+		sources = append(sources, s)
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Rows iteration error for source hits")
+		return nil, ErrQueryHits
+	}
+	return sources, nil
+}
+
+func unixNano(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := time.Unix(0, v.Int64).UTC()
+	return &t
+}
@@ -0,0 +1,68 @@
+package hits
+
+import (
+	"blacked/internal/db"
+	"context"
+	"errors"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultTopLimit = 50
+	MaxTopLimit     = 1000
+)
+
+var ErrDatabaseConnection = errors.New("failed to connect to the database")
+
+// Report is the most-hit entries report.
+type Report struct {
+	Enabled bool         `json:"enabled"` // False when counting is off and the numbers are stale or empty
+	Top     []TopEntry   `json:"top"`
+	Sources []SourceHits `json:"sources"`
+}
+
+// Service reads hit counts for entry details and reports.
+type Service struct {
+	repo Repository
+}
+
+// NewService creates a Service on the read database pool.
+func NewService() (*Service, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+
+	return NewServiceWithRepository(NewSQLiteRepository(dbConn)), nil
+}
+
+// NewServiceWithRepository creates a Service on the given repository.
+func NewServiceWithRepository(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Stat returns the hit count of one entry.
+func (s *Service) Stat(ctx context.Context, entryID string) (*Stat, error) {
+	return s.repo.Get(ctx, entryID)
+}
+
+// Report returns the limit most-hit entries, optionally of one source, and
+// the per-source summary. A non-positive limit uses DefaultTopLimit.
+func (s *Service) Report(ctx context.Context, source string, limit int) (*Report, error) {
+	if limit <= 0 {
+		limit = DefaultTopLimit
+	}
+
+	top, err := s.repo.Top(ctx, source, min(limit, MaxTopLimit))
+	if err != nil {
+		return nil, err
+	}
+	sources, err := s.repo.Sources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Report{Enabled: GetCounter() != nil, Top: top, Sources: sources}, nil
+}
@@ -0,0 +1,93 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// feedSimulator serves synthetic plain-text feeds over a local HTTP server,
+// standing in for the upstream lists a provider downloads.
+type feedSimulator struct {
+	server *httptest.Server
+
+	mu    sync.Mutex
+	feeds map[string]string // Path → body
+	hits  map[string]int    // Path → requests served
+}
+
+func newFeedSimulator(t *testing.T) *feedSimulator {
+	t.Helper()
+	sim := &feedSimulator{
+		feeds: make(map[string]string),
+		hits:  make(map[string]int),
+	}
+	sim.server = httptest.NewServer(http.HandlerFunc(sim.serve))
+	t.Cleanup(sim.server.Close)
+	return sim
+}
+
+func (s *feedSimulator) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	body, ok := s.feeds[r.URL.Path]
+	if ok {
+		s.hits[r.URL.Path]++
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(body))
+}
+
+// Publish serves lines as the feed of name, replacing any previous version,
+// and returns the feed URL.
+func (s *feedSimulator) Publish(name string, lines []string) string {
+	path := "/feeds/" + name + ".txt"
+
+	s.mu.Lock()
+	s.feeds[path] = "# synthetic feed " + name + "\n" + strings.Join(lines, "\n") + "\n"
+	s.mu.Unlock()
+
+	return s.server.URL + path
+}
+
+// Hits returns how many times the feed of name was downloaded.
+func (s *feedSimulator) Hits(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits["/feeds/"+name+".txt"]
+}
+
+// syntheticFeed returns size distinct URLs for source. Even entries sit
+// under the registered domain alpha-<source>.sim and odd ones under
+// beta-<source>.sim, so either half can be selected by domain. The .sim
+// TLD is undelegated but, unlike .test, not dropped as reserved.
+func syntheticFeed(source string, size int) []string {
+	lines := make([]string, size)
+	for i := range lines {
+		lines[i] = syntheticURL(source, i)
+	}
+	return lines
+}
+
+func syntheticURL(source string, i int) string {
+	return fmt.Sprintf("https://%s/payload/%d", syntheticHost(source, i), i)
+}
+
+func syntheticHost(source string, i int) string {
+	group := "alpha"
+	if i%2 == 1 {
+		group = "beta"
+	}
+	return fmt.Sprintf("host-%d.%s-%s.sim", i, group, source)
+}
@@ -0,0 +1,391 @@
+//go:build integration
+// +build integration
+
+// Pipeline Integration Test Suite
+// ===============================
+// Runs the full provider pipeline against a local feed simulator:
+// process → collector → repository → cache sync → query. Each test serves
+// synthetic feeds from an httptest server, processes them through generic
+// providers and checks entry counts, cache consistency and soft deletes.
+// Nothing leaves the machine; the database, cache and stored files live in
+// a temporary directory.
+//
+// Run: go test -tags=integration ./features/integration/... -v -timeout 300s
+//
+// INTEGRATION_FEED_SIZE sets the entries of the large feed (default 20000).
+
+package integration
+
+import (
+	"blacked/features/cache"
+	"blacked/features/entries"
+	"blacked/features/entries/enums"
+	"blacked/features/entries/repository"
+	"blacked/features/entries/services"
+	"blacked/features/entry_collector"
+	"blacked/features/providers"
+	"blacked/features/providers/base"
+	"blacked/features/providers/generic"
+	ic "blacked/internal/colly"
+	"blacked/internal/config"
+	"blacked/internal/db"
+	"blacked/internal/logger"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	collector   *entry_collector.PondCollector
+	collyClient *colly.Collector
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(runSuite(m))
+}
+
+// runSuite initializes the application singletons inside a temporary
+// working directory, runs the tests and tears everything down.
+func runSuite(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "blacked-integration-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "create work dir:", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chdir(dir); err != nil {
+		fmt.Fprintln(os.Stderr, "enter work dir:", err)
+		return 1
+	}
+
+	logger.InitializeLogger()
+	os.Setenv("CONFIG_FILE", "integration.toml") // Absent: defaults only
+	if err := config.InitConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, "init config:", err)
+		return 1
+	}
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+
+	cfg := config.GetConfig()
+	cfg.Cache.CacheType = "ristretto"
+	cfg.Cache.TTL = nil // Eager cache: every listed URL is synced, so the cache can be checked key by key
+	cfg.Cache.ScrubInterval = 0
+	cfg.Collector.StoreResponses = false // Every run downloads the feed as currently published
+
+	ctx := context.Background()
+	writeDB, err := db.GetWriteDB()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "open database:", err)
+		return 1
+	}
+	defer db.Close()
+
+	if err := cache.InitializeCache(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "init cache:", err)
+		return 1
+	}
+	defer cache.CloseCache()
+
+	collector = entry_collector.InitPondCollector(ctx, writeDB)
+	defer collector.Close()
+
+	collyClient, err = ic.InitCollyClient()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "init colly:", err)
+		return 1
+	}
+
+	return m.Run()
+}
+
+// feedSize returns INTEGRATION_FEED_SIZE, or fallback when unset.
+func feedSize(t *testing.T, fallback int) int {
+	t.Helper()
+	raw := os.Getenv("INTEGRATION_FEED_SIZE")
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	require.NoError(t, err, "INTEGRATION_FEED_SIZE")
+	require.Positive(t, n, "INTEGRATION_FEED_SIZE")
+	return n
+}
+
+// newFeedProvider registers a generic provider reading the feed at url.
+func newFeedProvider(t *testing.T, name, url string) base.Provider {
+	t.Helper()
+	p, err := generic.NewGenericProvider(name, &config.ProviderOptions{
+		Type:      generic.TypeGeneric,
+		SourceURL: url,
+		Format:    generic.FormatPlain,
+		Category:  "malware",
+	}, collyClient)
+	require.NoError(t, err)
+	require.NotNil(t, p)
+	return p
+}
+
+// process runs providers through the pipeline with an immediate full cache sync.
+func process(t *testing.T, list ...base.Provider) {
+	t.Helper()
+	err := providers.Providers(list).Process(context.Background(), providers.ProcessOptions{
+		UpdateCacheMode: providers.UpdateCacheImmediate,
+	})
+	require.NoError(t, err)
+	collector.WaitForCacheSyncCompletion()
+}
+
+func newRepository(t *testing.T) *repository.SQLiteRepository {
+	t.Helper()
+	readDB, err := db.GetDB()
+	require.NoError(t, err)
+	return repository.NewSQLiteRepository(readDB)
+}
+
+// activeEntries returns the active entries of source keyed by source URL.
+func activeEntries(t *testing.T, repo *repository.SQLiteRepository, source string) map[string]string {
+	t.Helper()
+	byURL := make(map[string]string)
+	err := repo.StreamFilteredEntries(context.Background(), repository.Filter{Sources: []string{source}}, func(e *entries.Entry) error {
+		byURL[e.SourceURL] = e.ID
+		return nil
+	})
+	require.NoError(t, err)
+	return byURL
+}
+
+// requireCacheConsistent checks every source URL against the cache: each
+// must map to exactly the IDs of its active entries, and be in the bloom
+// filter, or be absent from the cache when none is active, and a full scrub
+// round must find no drift.
+func requireCacheConsistent(t *testing.T, repo *repository.SQLiteRepository, sourceURLs []string) {
+	t.Helper()
+	ctx := context.Background()
+	cacheProvider, err := cache.GetCacheProvider()
+	require.NoError(t, err)
+
+	want, err := repo.GetIDsBySourceURLs(ctx, sourceURLs)
+	require.NoError(t, err)
+
+	for _, u := range sourceURLs {
+		ids, err := cacheProvider.Get(u)
+		if len(want[u]) == 0 {
+			assert.Error(t, err, "%s is not listed and must not be cached", u)
+			continue
+		}
+		if assert.NoError(t, err, "%s must be cached", u) {
+			assert.ElementsMatch(t, want[u], ids, u)
+		}
+		listed, err := cache.CheckURL(u)
+		require.NoError(t, err)
+		assert.True(t, listed, "%s must be in the bloom filter", u)
+	}
+
+	report, err := collector.ScrubCache(ctx, len(sourceURLs))
+	require.NoError(t, err)
+	assert.Zero(t, report.Drift(), "scrub report: %+v", report)
+}
+
+func TestPipelineCountsAndQueries(t *testing.T) {
+	sim := newFeedSimulator(t)
+	sizes := map[string]int{
+		"sim-small": 100,
+		"sim-large": feedSize(t, 20000),
+	}
+
+	var list []base.Provider
+	for name, size := range sizes {
+		list = append(list, newFeedProvider(t, name, sim.Publish(name, syntheticFeed(name, size))))
+	}
+	process(t, list...)
+
+	ctx := context.Background()
+	repo := newRepository(t)
+	querySvc, err := services.NewQueryService()
+	require.NoError(t, err)
+
+	for name, size := range sizes {
+		assert.Equal(t, 1, sim.Hits(name), "%s is downloaded once", name)
+
+		count, err := repo.StreamEntriesCountBySource(ctx, name)
+		require.NoError(t, err)
+		assert.Equal(t, size, count, name)
+
+		active := activeEntries(t, repo, name)
+		require.Len(t, active, size, name)
+
+		urls := make([]string, 0, len(active))
+		for u := range active {
+			urls = append(urls, u)
+		}
+		requireCacheConsistent(t, repo, urls)
+
+		hostType := enums.QueryTypeHost
+		hits, err := querySvc.Query(ctx, syntheticHost(name, size-1), &hostType)
+		require.NoError(t, err)
+		assert.Len(t, hits, 1, "%s: last entry is found by host", name)
+
+		hits, err = querySvc.Query(ctx, "host-0.unlisted.sim", &hostType)
+		require.NoError(t, err)
+		assert.Empty(t, hits)
+	}
+
+	stats, err := repo.GetEntryStats(ctx, repository.Filter{Sources: []string{"sim-small", "sim-large"}})
+	require.NoError(t, err)
+	assert.Equal(t, sizes["sim-small"]+sizes["sim-large"], stats.Total)
+	assert.Equal(t, stats.Total, stats.ByCategory["malware"])
+}
+
+func TestPipelineSoftDelete(t *testing.T) {
+	const name, size = "sim-delete", 200
+	sim := newFeedSimulator(t)
+	p := newFeedProvider(t, name, sim.Publish(name, syntheticFeed(name, size)))
+	process(t, p)
+
+	ctx := context.Background()
+	repo := newRepository(t)
+	before := activeEntries(t, repo, name)
+	require.Len(t, before, size)
+	urls := make([]string, 0, len(before))
+	for u := range before {
+		urls = append(urls, u)
+	}
+
+	// Bulk delete the beta half; the follow-up sync must drop it from the cache.
+	deleteSvc, err := services.NewDeleteService()
+	require.NoError(t, err)
+	deleteSvc.SetCacheSyncer(collector)
+
+	deleted, err := deleteSvc.Delete(ctx, repository.Filter{Sources: []string{name}, Domain: "beta-" + name + ".sim"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(size/2), deleted)
+	collector.WaitForCacheSyncCompletion()
+
+	after := activeEntries(t, repo, name)
+	assert.Len(t, after, size/2)
+	requireCacheConsistent(t, repo, urls)
+
+	gone := syntheticHost(name, 1)
+	inBloom := 0
+	for u, id := range before {
+		if _, ok := after[u]; ok {
+			continue
+		}
+		entry, err := repo.GetEntryByID(ctx, id)
+		require.NoError(t, err)
+		require.NotNil(t, entry, "soft deleted entries are kept")
+		assert.NotNil(t, entry.DeletedAt, u)
+
+		if listed, _ := cache.CheckURL(u); listed {
+			inBloom++
+		}
+	}
+	// Deleted URLs leave the counting bloom filter but for false positives.
+	assert.Less(t, inBloom, size/20, "deleted URLs still in the bloom filter")
+
+	hostType := enums.QueryTypeHost
+	querySvc, err := services.NewQueryService()
+	require.NoError(t, err)
+	hits, err := querySvc.Query(ctx, gone, &hostType)
+	require.NoError(t, err)
+	assert.Empty(t, hits, "%s is deleted", gone)
+
+	// The feed still lists the deleted half, so the next run reactivates it
+	// under the same IDs.
+	process(t, p)
+	again := activeEntries(t, repo, name)
+	assert.Equal(t, before, again)
+	requireCacheConsistent(t, repo, urls)
+
+	hits, err = querySvc.Query(ctx, gone, &hostType)
+	require.NoError(t, err)
+	assert.Len(t, hits, 1, "%s is listed again", gone)
+}
+
+func TestPipelineImport(t *testing.T) {
+	const size = 150
+	urls := syntheticFeed("partner", size)
+
+	var body strings.Builder
+	for _, u := range urls {
+		fmt.Fprintf(&body, "{\"url\": %q, \"category\": \"phishing\"}\n", u)
+	}
+	fmt.Fprintf(&body, "%q\n{\"url\": \"\"}\n", urls[0]) // A duplicate and an invalid item
+
+	importSvc := services.NewImportService().SetCollector(collector)
+	report, err := importSvc.Import(context.Background(), strings.NewReader(body.String()), services.ImportOptions{Label: "partner"})
+	require.NoError(t, err)
+	collector.WaitForCacheSyncCompletion()
+
+	assert.Equal(t, "import-partner", report.Source)
+	assert.Equal(t, size+2, report.Parsed)
+	assert.Equal(t, size, report.Saved)
+	assert.Equal(t, 2, report.Skipped)
+
+	repo := newRepository(t)
+	assert.Len(t, activeEntries(t, repo, report.Source), size)
+	requireCacheConsistent(t, repo, urls)
+}
+
+func TestPipelineDeltaIngest(t *testing.T) {
+	const name, size = "sim-delta", 200
+	cfg := config.GetConfig()
+	delta := true
+	if cfg.Providers == nil {
+		cfg.Providers = make(map[string]*config.ProviderOptions)
+	}
+	cfg.Providers[name] = &config.ProviderOptions{DeltaIngest: &delta}
+	t.Cleanup(func() { delete(cfg.Providers, name) })
+
+	sim := newFeedSimulator(t)
+	feed := syntheticFeed(name, size)
+	p := newFeedProvider(t, name, sim.Publish(name, feed))
+	process(t, p)
+
+	ctx := context.Background()
+	repo := newRepository(t)
+	before := activeEntries(t, repo, name)
+	require.Len(t, before, size, "the first run has no snapshot and writes the full feed")
+	kept, err := repo.GetEntryByID(ctx, before[syntheticURL(name, 50)])
+	require.NoError(t, err)
+
+	// Drop the first ten lines and list ten new ones.
+	next := append(slices.Clone(feed[10:]), syntheticFeed(name, size+10)[size:]...)
+	sim.Publish(name, next)
+	process(t, p)
+
+	after := activeEntries(t, repo, name)
+	assert.Len(t, after, size)
+	for i := range 10 {
+		assert.NotContains(t, after, syntheticURL(name, i), "lines gone from the feed are soft deleted")
+		assert.Contains(t, after, syntheticURL(name, size+i))
+	}
+	unchanged, err := repo.GetEntryByID(ctx, kept.ID)
+	require.NoError(t, err)
+	assert.Equal(t, kept.UpdatedAt, unchanged.UpdatedAt, "unchanged lines are not rewritten")
+
+	urls := slices.Concat(feed, next[size-10:])
+	requireCacheConsistent(t, repo, urls)
+
+	// A delete outside the feed makes the snapshot disagree with the
+	// database, so the next run writes the full feed and reactivates it.
+	deleteSvc, err := services.NewDeleteService()
+	require.NoError(t, err)
+	deleteSvc.SetCacheSyncer(collector)
+	_, err = deleteSvc.Delete(ctx, repository.Filter{Sources: []string{name}, Host: syntheticHost(name, 50)})
+	require.NoError(t, err)
+	collector.WaitForCacheSyncCompletion()
+
+	process(t, p)
+	assert.Equal(t, after, activeEntries(t, repo, name))
+	requireCacheConsistent(t, repo, urls)
+}
@@ -0,0 +1,161 @@
+// Package maintenance holds the read-only mode of the instance, switched on
+// by an admin for migrations and backups. While it is on, queries are still
+// served, but provider runs, startup ingestion and replication are paused
+// and write requests are rejected. The mode is stored in the database, so it
+// survives restarts and reaches every process sharing it until cleared.
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrReadOnly      = errors.New("instance is in read-only maintenance mode")
+	ErrInvalidReason = errors.New("reason must be at most 256 characters")
+)
+
+// refreshInterval is how long the stored mode is trusted before it is read
+// again, so a toggle made by another process is picked up.
+const refreshInterval = 5 * time.Second
+
+// MaxReasonLength caps the reason given for read-only mode.
+const MaxReasonLength = 256
+
+// State is the maintenance mode of the instance.
+type State struct {
+	ReadOnly bool       `json:"read_only"`
+	Reason   string     `json:"reason,omitempty"`
+	Since    *time.Time `json:"since,omitempty"` // When read-only mode was switched on
+}
+
+var (
+	globalService *Service
+	once          sync.Once
+)
+
+// Init creates the global service keeping the mode in db, loads the stored
+// mode and returns the service.
+func Init(db *sql.DB) *Service {
+	once.Do(func() {
+		globalService = NewServiceWithRepository(NewSQLiteRepository(db))
+		state, err := globalService.Refresh(context.Background())
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to load the maintenance mode, starting writable")
+			return
+		}
+		if state.ReadOnly {
+			log.Warn().Str("reason", state.Reason).Msg("Starting in read-only maintenance mode; ingestion is paused until it is cleared")
+		}
+	})
+	return globalService
+}
+
+// Get returns the global service, or nil before Init.
+func Get() *Service {
+	return globalService
+}
+
+// ReadOnly reports whether the instance is in read-only mode; false before
+// Init.
+func ReadOnly(ctx context.Context) bool {
+	if globalService == nil {
+		return false
+	}
+	return globalService.State(ctx).ReadOnly
+}
+
+// Check returns ErrReadOnly while the instance is in read-only mode, for
+// jobs that write to check before they start.
+func Check(ctx context.Context) error {
+	if ReadOnly(ctx) {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// Service switches read-only mode and caches the stored mode.
+type Service struct {
+	repo Repository
+
+	mu       sync.Mutex
+	state    State
+	loadedAt time.Time
+}
+
+// NewServiceWithRepository creates a Service keeping the mode in repo.
+func NewServiceWithRepository(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// State returns the mode, read again from the database once the cached one
+// is older than a few seconds. When it can't be read the last known mode is
+// kept.
+func (s *Service) State(ctx context.Context) State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.loadedAt) < refreshInterval {
+		return s.state
+	}
+	s.loadedAt = time.Now()
+	if state, err := s.repo.State(ctx); err == nil {
+		s.state = state
+	}
+	return s.state
+}
+
+// Refresh reads the stored mode.
+func (s *Service) Refresh(ctx context.Context) (State, error) {
+	state, err := s.repo.State(ctx)
+	if err != nil {
+		return State{}, err
+	}
+	s.mu.Lock()
+	s.state, s.loadedAt = state, time.Now()
+	s.mu.Unlock()
+	return state, nil
+}
+
+// Enable switches read-only mode on for reason. Switching it on again only
+// updates the reason; Since keeps the first switch.
+func (s *Service) Enable(ctx context.Context, reason string) (State, error) {
+	reason = strings.TrimSpace(reason)
+	if len(reason) > MaxReasonLength {
+		return State{}, ErrInvalidReason
+	}
+	state := State{ReadOnly: true, Reason: reason}
+	if current, err := s.Refresh(ctx); err == nil && current.ReadOnly {
+		state.Since = current.Since
+	} else {
+		now := time.Now().UTC()
+		state.Since = &now
+	}
+	return s.set(ctx, state)
+}
+
+// Disable clears read-only mode.
+func (s *Service) Disable(ctx context.Context) (State, error) {
+	return s.set(ctx, State{})
+}
+
+func (s *Service) set(ctx context.Context, state State) (State, error) {
+	if err := s.repo.SetState(ctx, state); err != nil {
+		return State{}, err
+	}
+	s.mu.Lock()
+	s.state, s.loadedAt = state, time.Now()
+	s.mu.Unlock()
+
+	if state.ReadOnly {
+		log.Warn().Str("reason", state.Reason).Msg("Read-only maintenance mode on: ingestion paused, writes rejected")
+	} else {
+		log.Info().Msg("Read-only maintenance mode cleared")
+	}
+	return state, nil
+}
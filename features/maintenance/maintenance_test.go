@@ -0,0 +1,53 @@
+package maintenance
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	idb "blacked/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyPersists(t *testing.T) {
+	ctx := context.Background()
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	svc := NewServiceWithRepository(NewSQLiteRepository(conn))
+	assert.False(t, svc.State(ctx).ReadOnly, "instances start writable")
+
+	state, err := svc.Enable(ctx, " nightly backup ")
+	require.NoError(t, err)
+	assert.True(t, state.ReadOnly)
+	assert.Equal(t, "nightly backup", state.Reason)
+	require.NotNil(t, state.Since)
+	assert.True(t, svc.State(ctx).ReadOnly)
+
+	// Switching it on again keeps the time it was first switched on.
+	again, err := svc.Enable(ctx, "migration")
+	require.NoError(t, err)
+	assert.Equal(t, "migration", again.Reason)
+	assert.True(t, state.Since.Equal(*again.Since))
+
+	// A restarted process reads the stored mode.
+	restarted := NewServiceWithRepository(NewSQLiteRepository(conn))
+	stored, err := restarted.Refresh(ctx)
+	require.NoError(t, err)
+	assert.True(t, stored.ReadOnly)
+	assert.Equal(t, "migration", stored.Reason)
+
+	_, err = svc.Enable(ctx, strings.Repeat("x", MaxReasonLength+1))
+	assert.ErrorIs(t, err, ErrInvalidReason)
+
+	cleared, err := restarted.Disable(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, State{}, cleared)
+	stored, err = svc.Refresh(ctx)
+	require.NoError(t, err)
+	assert.False(t, stored.ReadOnly)
+}
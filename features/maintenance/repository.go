@@ -0,0 +1,76 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrQueryState = errors.New("failed to query maintenance mode from SQLite")
+	ErrSaveState  = errors.New("failed to save maintenance mode in SQLite")
+)
+
+// Repository stores the maintenance mode.
+type Repository interface {
+	// State returns the stored mode; writable when none was ever saved.
+	State(ctx context.Context) (State, error)
+	SetState(ctx context.Context, state State) error
+}
+
+// SQLiteRepository is the SQLite implementation of Repository.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLiteRepository instance.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// State returns the stored mode; writable when none was ever saved.
+func (r *SQLiteRepository) State(ctx context.Context) (State, error) {
+	var (
+		readOnly bool
+		reason   sql.NullString
+		since    sql.NullInt64
+	)
+	err := r.db.QueryRowContext(ctx, "SELECT read_only, reason, since FROM maintenance_state WHERE id = 1").Scan(&readOnly, &reason, &since)
+	if errors.Is(err, sql.ErrNoRows) {
+		return State{}, nil
+	}
+	if err != nil {
+		log.Err(err).Msg("Failed to query maintenance mode")
+		return State{}, ErrQueryState
+	}
+
+	state := State{ReadOnly: readOnly, Reason: reason.String}
+	if since.Valid {
+		t := time.Unix(0, since.Int64).UTC()
+		state.Since = &t
+	}
+	return state, nil
+}
+
+// SetState saves the mode.
+func (r *SQLiteRepository) SetState(ctx context.Context, state State) error {
+	var reason, since any
+	if state.Reason != "" {
+		reason = state.Reason
+	}
+	if state.Since != nil {
+		since = state.Since.UnixNano()
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO maintenance_state (id, read_only, reason, since, updated_at) VALUES (1, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET read_only = EXCLUDED.read_only, reason = EXCLUDED.reason, since = EXCLUDED.since, updated_at = EXCLUDED.updated_at`,
+		state.ReadOnly, reason, since, time.Now().UnixNano())
+	if err != nil {
+		log.Err(err).Bool("read_only", state.ReadOnly).Msg("Failed to save maintenance mode")
+		return ErrSaveState
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+// Package parsedebug shows how blacked would parse a feed line or URL,
+// without storing anything: the links the line lists in its format, each
+// normalization step, the fields of the resulting entries and whether
+// ingestion keeps them. It backs POST /debug/parse, for writing allowlist
+// rules, reserved domains and new parsers without guesswork.
+package parsedebug
+
+import (
+	"blacked/features/allowlist"
+	"blacked/features/entries"
+	"blacked/features/providers/base"
+	"blacked/features/providers/generic"
+	"blacked/internal/config"
+	"blacked/internal/db"
+	"blacked/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MaxInputLength bounds the line a request may parse.
+const MaxInputLength = 8192
+
+var (
+	ErrDatabaseConnection = errors.New("failed to connect to the database")
+	ErrInvalidRequest     = errors.New("invalid parse request")
+)
+
+// Verdicts of a line and of its entries.
+const (
+	VerdictAccepted  = "accepted"  // Ingested as an entry
+	VerdictSkipped   = "skipped"   // The line lists nothing: a comment, header or unsupported rule
+	VerdictException = "exception" // Adblock exception: allows its target across the list
+	VerdictInvalid   = "invalid"   // The link fails to parse into an entry
+	VerdictReserved  = "reserved"  // Dropped as a reserved name or non-public address
+)
+
+// Request is a line to parse and how to read it.
+type Request struct {
+	Input     string `json:"input"`
+	Provider  string `json:"provider,omitempty"`  // Configured [Providers.<name>] whose format, column, delimiter and wildcard apply
+	Format    string `json:"format,omitempty"`    // plain (default), hosts, adblock or csv
+	Column    int    `json:"column,omitempty"`    // csv: 0-based column holding the URL or domain
+	Delimiter string `json:"delimiter,omitempty"` // csv: field separator, "," by default
+	Wildcard  bool   `json:"wildcard,omitempty"`  // Every listed host covers its subdomains
+}
+
+// Entry is one entry the line would be ingested as.
+type Entry struct {
+	Link    string                `json:"link"` // Link the line lists
+	Verdict string                `json:"verdict"`
+	Reason  string                `json:"reason,omitempty"`
+	Steps   []utils.NormalizeStep `json:"steps"`         // Steps from the line to the entry that changed the link
+	URL     string                `json:"url,omitempty"` // Normalized URL the entry matches
+
+	Scheme     string   `json:"scheme,omitempty"`
+	Host       string   `json:"host,omitempty"`
+	Domain     string   `json:"domain,omitempty"`
+	SubDomains []string `json:"sub_domains,omitempty"`
+	Path       string   `json:"path,omitempty"`
+	RawQuery   string   `json:"raw_query,omitempty"`
+	CIDR       string   `json:"cidr,omitempty"`
+	Wildcard   bool     `json:"wildcard,omitempty"`
+
+	// Allowlisted is the allowlist rule that keeps the entry out of answers
+	// and exports, although it is stored.
+	Allowlisted *allowlist.Rule `json:"allowlisted,omitempty"`
+}
+
+// Result is how a line would be parsed.
+type Result struct {
+	Input   string  `json:"input"`
+	Format  string  `json:"format"`
+	Verdict string  `json:"verdict"` // Accepted when any entry is
+	Reason  string  `json:"reason,omitempty"`
+	Entries []Entry `json:"entries"`
+}
+
+// Allowlist matches links against the allowlist rules.
+type Allowlist interface {
+	Match(ctx context.Context, link string) (*allowlist.Rule, error)
+}
+
+// Service parses lines as the providers do.
+type Service struct {
+	allowlist Allowlist // nil disables allowlist checks
+}
+
+// NewService creates a Service checking the allowlist on the read pool.
+func NewService() (*Service, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+	return NewServiceWithAllowlist(allowlist.NewServiceWithRepository(allowlist.NewSQLiteRepository(dbConn))), nil
+}
+
+// NewServiceWithAllowlist creates a Service on the given allowlist; nil
+// disables allowlist checks.
+func NewServiceWithAllowlist(a Allowlist) *Service {
+	return &Service{allowlist: a}
+}
+
+// Parse returns how req.Input would be parsed and ingested.
+func (s *Service) Parse(ctx context.Context, req Request) (*Result, error) {
+	if err := resolve(&req); err != nil {
+		return nil, err
+	}
+
+	res := &Result{Input: req.Input, Format: req.Format, Entries: []Entry{}}
+	links, verdict, reason, err := lineLinks(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		res.Verdict, res.Reason = VerdictSkipped, reason
+		return res, nil
+	}
+
+	var reserved []string
+	if cfg := config.GetConfig(); cfg != nil {
+		reserved = cfg.Collector.ReservedDomains
+	}
+	guard := base.NewReservedGuard(req.Provider, nil, reserved, 0)
+
+	for _, link := range links {
+		entry := s.parseLink(ctx, req, link, guard)
+		if verdict != "" && entry.Verdict == VerdictAccepted {
+			entry.Verdict, entry.Reason = verdict, reason
+		}
+		res.Entries = append(res.Entries, entry)
+	}
+
+	res.Verdict, res.Reason = res.Entries[0].Verdict, res.Entries[0].Reason
+	for _, e := range res.Entries {
+		if e.Verdict == VerdictAccepted {
+			res.Verdict, res.Reason = VerdictAccepted, ""
+			break
+		}
+	}
+	return res, nil
+}
+
+// resolve validates req and fills its reading options from its provider.
+func resolve(req *Request) error {
+	if strings.TrimSpace(req.Input) == "" {
+		return fmt.Errorf("%w: input is required", ErrInvalidRequest)
+	}
+	if len(req.Input) > MaxInputLength || strings.ContainsAny(req.Input, "\r\n") {
+		return fmt.Errorf("%w: input must be a single line of at most %d bytes", ErrInvalidRequest, MaxInputLength)
+	}
+
+	if req.Provider != "" {
+		var opts *config.ProviderOptions
+		if cfg := config.GetConfig(); cfg != nil {
+			opts = cfg.Providers[req.Provider]
+		}
+		if opts == nil {
+			return fmt.Errorf("%w: no [Providers.%s] is configured", ErrInvalidRequest, req.Provider)
+		}
+		req.Format, req.Column, req.Delimiter = opts.Format, opts.Column, opts.Delimiter
+		req.Wildcard = opts.Wildcard != nil && *opts.Wildcard
+	}
+	if req.Format == "" {
+		req.Format = generic.FormatPlain
+	}
+	return nil
+}
+
+// lineLinks returns the links the line lists in its format. A line listing
+// nothing returns why; an adblock exception returns its target with the
+// exception verdict.
+func lineLinks(req Request) (links []string, verdict, reason string, err error) {
+	switch req.Format {
+	case generic.FormatHosts:
+		if links = base.ParseHostsLine(req.Input); len(links) == 0 {
+			reason = "not a hosts line, or it only maps local names, IP addresses or invalid hostnames"
+		}
+		return links, "", reason, nil
+
+	case generic.FormatAdblock:
+		rule, ok := base.ParseAdblockLine(req.Input)
+		if !ok {
+			return nil, "", "comment, header, cosmetic, regex, wildcard or non-blocking rule: lists no single host", nil
+		}
+		if rule.Exception {
+			return []string{rule.Target}, VerdictException, "the exception allows its target over the blocking rules of the same list", nil
+		}
+		return []string{rule.Target}, "", "", nil
+	}
+
+	parse, err := generic.LineParser(req.Format, req.Column, req.Delimiter)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	link, ok := parse(req.Input)
+	if !ok || link == "" {
+		return nil, "", "blank line, comment or header", nil
+	}
+	return []string{link}, "", "", nil
+}
+
+// parseLink parses one link as the providers do, recording the steps that
+// change it on the way.
+func (s *Service) parseLink(ctx context.Context, req Request, link string, guard *base.ReservedGuard) Entry {
+	out := Entry{Link: link, Verdict: VerdictAccepted, Steps: []utils.NormalizeStep{}}
+	if link != strings.TrimSpace(req.Input) {
+		out.Steps = append(out.Steps, utils.NormalizeStep{Step: req.Format, Result: link})
+	}
+
+	target := link
+	if req.Wildcard {
+		if host := strings.TrimPrefix(strings.TrimSpace(link), "*."); host != target {
+			target = host
+			out.Steps = append(out.Steps, utils.NormalizeStep{Step: "wildcard", Result: target})
+		}
+	}
+
+	raw, _ := entries.LinkURL(target)
+	if raw != target {
+		out.Steps = append(out.Steps, utils.NormalizeStep{Step: "prepare", Result: raw})
+	}
+	if u, err := url.Parse(raw); err == nil {
+		out.Steps = append(out.Steps, utils.DefaultNormalizer().Trace(u)...)
+		if u.Port() != "" {
+			u.Host = u.Hostname()
+			out.Steps = append(out.Steps, utils.NormalizeStep{Step: "strip_port", Result: u.String()})
+		}
+	}
+
+	entry := entries.NewEntry().WithSource(req.Provider)
+	if err := entry.ParseURL(target); err != nil {
+		out.Verdict, out.Reason = VerdictInvalid, err.Error()
+		return out
+	}
+	entry.SourceURL = link
+	entry.Wildcard = req.Wildcard
+
+	stored := entry.GetURL()
+	out.URL = stored.String()
+	out.Scheme, out.Host, out.Domain, out.SubDomains = entry.Scheme, entry.Host, entry.Domain, entry.SubDomains
+	out.Path, out.RawQuery, out.CIDR, out.Wildcard = entry.Path, entry.RawQuery, entry.CIDR, entry.Wildcard
+
+	if guard.Reserved(entry.Host) {
+		out.Verdict, out.Reason = VerdictReserved, "reserved name or non-public address: extend or review [Collector] reserved_domains"
+	}
+
+	if s.allowlist != nil {
+		rule, err := s.allowlist.Match(ctx, out.URL)
+		if err != nil {
+			log.Warn().Err(err).Str("url", out.URL).Msg("Failed to check allowlist for parse debug")
+		}
+		out.Allowlisted = rule
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package parsedebug
+
+import (
+	"blacked/features/allowlist"
+	"blacked/internal/config"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubAllowlist map[string]*allowlist.Rule
+
+func (s stubAllowlist) Match(_ context.Context, link string) (*allowlist.Rule, error) {
+	return s[link], nil
+}
+
+func TestParseURL(t *testing.T) {
+	svc := NewServiceWithAllowlist(nil)
+
+	res, err := svc.Parse(context.Background(), Request{Input: "HTTPS://Login.Bad-Bank.co.uk:443/Verify/?utm_source=mail&id=7"})
+	require.NoError(t, err)
+	assert.Equal(t, VerdictAccepted, res.Verdict)
+	require.Len(t, res.Entries, 1)
+
+	e := res.Entries[0]
+	assert.Equal(t, "https://login.bad-bank.co.uk/verify?id=7", e.URL)
+	assert.Equal(t, "login.bad-bank.co.uk", e.Host)
+	assert.Equal(t, "bad-bank.co.uk", e.Domain)
+	assert.Equal(t, []string{"login"}, e.SubDomains)
+	assert.Equal(t, "/verify", e.Path)
+	assert.Equal(t, "id=7", e.RawQuery)
+
+	var steps []string
+	for _, s := range e.Steps {
+		steps = append(steps, s.Step)
+	}
+	assert.Equal(t, []string{"host", "lowercase_path", "trailing_slash", "query"}, steps)
+}
+
+func TestParseFormats(t *testing.T) {
+	cfg := config.GetConfig()
+	prev := cfg.Collector.ReservedDomains
+	cfg.Collector.ReservedDomains = []string{"corp.example.net"}
+	t.Cleanup(func() { cfg.Collector.ReservedDomains = prev })
+
+	rule := &allowlist.Rule{ID: "r1", Kind: allowlist.KindDomain, Pattern: "tracker.example.org"}
+	svc := NewServiceWithAllowlist(stubAllowlist{"//ads.tracker.example.org": rule})
+	ctx := context.Background()
+
+	res, err := svc.Parse(ctx, Request{Input: "0.0.0.0 Ads.Tracker.Example.org localhost bad.example.io # ads", Format: "hosts"})
+	require.NoError(t, err)
+	require.Len(t, res.Entries, 2, "local names are skipped, aliases kept")
+	assert.Equal(t, VerdictReserved, res.Entries[0].Verdict, "example.org is reserved")
+	assert.Equal(t, VerdictAccepted, res.Entries[1].Verdict)
+	assert.Equal(t, VerdictAccepted, res.Verdict, "a line is accepted when any entry is")
+	assert.Equal(t, "hosts", res.Entries[0].Steps[0].Step)
+
+	res, err = svc.Parse(ctx, Request{Input: "@@||cdn.example.io^$document", Format: "adblock"})
+	require.NoError(t, err)
+	assert.Equal(t, VerdictException, res.Verdict)
+
+	res, err = svc.Parse(ctx, Request{Input: "! Title: list", Format: "adblock"})
+	require.NoError(t, err)
+	assert.Equal(t, VerdictSkipped, res.Verdict)
+	assert.Empty(t, res.Entries)
+
+	res, err = svc.Parse(ctx, Request{Input: "*.shop.corp.example.net", Wildcard: true})
+	require.NoError(t, err)
+	require.Len(t, res.Entries, 1)
+	assert.Equal(t, VerdictReserved, res.Verdict, "[Collector] reserved_domains apply")
+	assert.True(t, res.Entries[0].Wildcard)
+	assert.Equal(t, "shop.corp.example.net", res.Entries[0].Host)
+
+	res, err = svc.Parse(ctx, Request{Input: "7,evil.example.io/login,phishing", Format: "csv", Column: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "/login", res.Entries[0].Path)
+
+	res, err = svc.Parse(ctx, Request{Input: "203.0.113.0/24"})
+	require.NoError(t, err)
+	assert.Equal(t, VerdictReserved, res.Verdict, "documentation range")
+	assert.Equal(t, "203.0.113.0/24", res.Entries[0].CIDR)
+
+	for name, req := range map[string]Request{
+		"empty input":      {Input: " "},
+		"several lines":    {Input: "a.example.io\nb.example.io"},
+		"unknown format":   {Input: "a.example.io", Format: "xml"},
+		"unknown provider": {Input: "a.example.io", Provider: "missing"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.Parse(ctx, req)
+			assert.ErrorIs(t, err, ErrInvalidRequest)
+		})
+	}
+}
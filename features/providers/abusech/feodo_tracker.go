@@ -0,0 +1,20 @@
+package abusech
+
+import (
+	"blacked/features/providers/base"
+	"blacked/internal/config"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// NewFeodoTrackerProvider creates the Feodo Tracker provider: botnet C2
+// server IPs (Dridex, Emotet, QakBot, ...).
+func NewFeodoTrackerProvider(cfg *config.Config, collyClient *colly.Collector) base.Provider {
+	return newIPListProvider(cfg, collyClient, ipList{
+		name:      "feodotracker-ipblocklist",
+		sourceURL: "https://feodotracker.abuse.ch/downloads/ipblocklist.txt",
+		// The list is regenerated every 5 minutes.
+		cron:     "10 * * * *",
+		category: "botnet",
+	})
+}
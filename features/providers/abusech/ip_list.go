@@ -0,0 +1,115 @@
+package abusech
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entry_collector"
+	"blacked/features/providers/base"
+	"blacked/internal/config"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// ipList describes one abuse.ch plain-text IP feed: one address per line,
+// comments starting with #.
+type ipList struct {
+	name      string
+	sourceURL string
+	cron      string
+	category  string
+}
+
+// IPLink returns the IP on line in the form Entry.SetURL parses, bracketing
+// IPv6 addresses; ok is false for comments, blank lines and non-IPs.
+func IPLink(line string) (link string, ok bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", false
+	}
+
+	ip := net.ParseIP(line)
+	if ip == nil {
+		return "", false
+	}
+	if ip.To4() == nil {
+		return "[" + ip.String() + "]", true
+	}
+	return ip.String(), true
+}
+
+// newIPListProvider builds the provider of list, applying its config block
+// over the list defaults.
+func newIPListProvider(cfg *config.Config, collyClient *colly.Collector, list ipList) base.Provider {
+	opts, ok := cfg.Providers[list.name]
+	if !ok || opts == nil {
+		opts = &config.ProviderOptions{}
+	}
+	if opts.Enabled != nil && !*opts.Enabled {
+		log.Info().Str("provider", list.name).Msg("provider disabled — skipping")
+		return nil
+	}
+
+	sourceURL := opts.SourceURL
+	if sourceURL == "" {
+		sourceURL = list.sourceURL
+	}
+	cron := opts.Cron
+	if cron == "" {
+		cron = list.cron
+	}
+	category := opts.Category
+	if category == "" {
+		category = list.category
+	}
+
+	workers := opts.ParserWorkers
+	if workers <= 0 {
+		workers = 4
+	}
+	batchSize := opts.ParserBatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	client := base.BuildCollyClientForProvider(collyClient, opts)
+
+	parseFunc := func(data io.Reader, collector entry_collector.Collector) error {
+		return base.ParseLinesParallel(data, collector, list.name, workers, batchSize, func(line, processID string) (*entries.Entry, error) {
+			link, ok := IPLink(line)
+			if !ok {
+				return nil, nil
+			}
+
+			entry := entries.NewEntry().
+				WithSource(list.name).
+				WithProcessID(processID).
+				WithCategory(category)
+
+			if err := entry.SetURL(link); err != nil {
+				log.Error().Err(err).Msgf("error setting URL: %s", link)
+				return nil, nil
+			}
+
+			return entry, nil
+		})
+	}
+
+	provider := base.NewBaseProvider(
+		list.name,
+		sourceURL,
+		category,
+		client,
+		parseFunc,
+	)
+
+	provider.
+		SetCronSchedule(cron).
+		SetMirrors(opts.Mirrors).
+		SetAllowedDomains(opts.AllowedDomains).
+		Register()
+
+	return provider
+}
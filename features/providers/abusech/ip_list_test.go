@@ -0,0 +1,102 @@
+package abusech
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entry_collector"
+	"blacked/features/providers/base"
+	"blacked/internal/config"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPLink(t *testing.T) {
+	link, ok := IPLink(" 203.0.113.7 ")
+	assert.True(t, ok)
+	assert.Equal(t, "203.0.113.7", link)
+
+	link, ok = IPLink("2001:db8::1")
+	assert.True(t, ok)
+	assert.Equal(t, "[2001:db8::1]", link, "IPv6 is bracketed so it parses as a host")
+
+	for _, line := range []string{"", "# Feodo Tracker Botnet C2 IP Blocklist", "not-an-ip", "203.0.113.7:443"} {
+		_, ok = IPLink(line)
+		assert.False(t, ok, line)
+	}
+}
+
+func TestProvidersParseFeeds(t *testing.T) {
+	feed := `################################################################
+# abuse.ch IP Blocklist                                        #
+################################################################
+#
+# DstIP
+198.51.100.23
+203.0.113.7
+
+# END 2 entries
+`
+	cfg := &config.Config{Providers: map[string]*config.ProviderOptions{
+		"sslbl-ipblacklist": {Cron: "0 * * * *"},
+	}}
+
+	for _, tc := range []struct {
+		provider base.Provider
+		category string
+		cron     string
+	}{
+		{NewFeodoTrackerProvider(cfg, nil), "botnet", "10 * * * *"},
+		{NewSSLBLProvider(cfg, nil), "malware_ssl", "0 * * * *"},
+	} {
+		p := tc.provider.(*base.BaseProvider)
+		assert.Equal(t, tc.cron, p.GetCronSchedule(), p.Name)
+
+		collector := &entryCollector{}
+		require.NoError(t, p.ParseFunction(strings.NewReader(feed), collector))
+
+		hosts := collector.hosts()
+		assert.Equal(t, []string{"198.51.100.23", "203.0.113.7"}, hosts, p.Name)
+		for _, e := range collector.entries {
+			assert.Equal(t, p.Name, e.Source)
+			assert.Equal(t, tc.category, e.Category)
+		}
+	}
+}
+
+// entryCollector records submitted entries.
+type entryCollector struct {
+	mu      sync.Mutex
+	entries []*entries.Entry
+}
+
+func (c *entryCollector) Submit(entry *entries.Entry) {
+	c.mu.Lock()
+	c.entries = append(c.entries, entry)
+	c.mu.Unlock()
+}
+
+func (c *entryCollector) hosts() []string {
+	var hosts []string
+	for _, e := range c.entries {
+		hosts = append(hosts, e.Host)
+	}
+	slices.Sort(hosts)
+	return hosts
+}
+
+func (c *entryCollector) Wait()                                                               {}
+func (c *entryCollector) Close()                                                              {}
+func (c *entryCollector) GetProcessedCount(source string) int                                 { return 0 }
+func (c *entryCollector) StartProviderProcessing(ctx context.Context, name, processID string) {}
+func (c *entryCollector) FinishProviderProcessing(name, processID string) (int, time.Duration, bool) {
+	return 0, 0, true
+}
+func (c *entryCollector) CommitDelta(ctx context.Context, name, processID string) (*entry_collector.DeltaReport, error) {
+	return nil, nil
+}
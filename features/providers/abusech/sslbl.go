@@ -0,0 +1,19 @@
+package abusech
+
+import (
+	"blacked/features/providers/base"
+	"blacked/internal/config"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// NewSSLBLProvider creates the SSL Blacklist provider: IPs of hosts serving
+// SSL certificates seen with malware and botnet C2 traffic.
+func NewSSLBLProvider(cfg *config.Config, collyClient *colly.Collector) base.Provider {
+	return newIPListProvider(cfg, collyClient, ipList{
+		name:      "sslbl-ipblacklist",
+		sourceURL: "https://sslbl.abuse.ch/blacklist/sslipblacklist.txt",
+		cron:      "40 */2 * * *",
+		category:  "malware_ssl",
+	})
+}
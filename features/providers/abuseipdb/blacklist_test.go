@@ -0,0 +1,49 @@
+package abuseipdb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeBlacklist(t *testing.T) {
+	body := `{
+		"meta": {"generatedAt": "2026-10-16T12:00:00+00:00"},
+		"data": [
+			{"ipAddress": "203.0.113.7", "countryCode": "NL", "abuseConfidenceScore": 100, "lastReportedAt": "2026-10-16T11:59:02+00:00"},
+			{"ipAddress": "2001:db8::1", "abuseConfidenceScore": 91},
+			{"ipAddress": "not-an-ip", "abuseConfidenceScore": 95}
+		]
+	}`
+
+	ips, err := DecodeBlacklist(strings.NewReader(body))
+	require.NoError(t, err)
+	require.Len(t, ips, 3)
+
+	link, ok := ips[0].Link()
+	assert.True(t, ok)
+	assert.Equal(t, "203.0.113.7", link)
+	assert.Equal(t, 1.0, ips[0].Confidence())
+
+	link, ok = ips[1].Link()
+	assert.True(t, ok)
+	assert.Equal(t, "[2001:db8::1]", link, "IPv6 is bracketed so it parses as a host")
+	assert.InDelta(t, 0.91, ips[1].Confidence(), 1e-9)
+
+	_, ok = ips[2].Link()
+	assert.False(t, ok)
+}
+
+func TestDecodeBlacklistError(t *testing.T) {
+	body := `{"errors": [{"detail": "Authentication failed. Your API key is either missing, incorrect, or revoked.", "status": 401}]}`
+
+	_, err := DecodeBlacklist(strings.NewReader(body))
+	assert.ErrorIs(t, err, ErrInvalidBlacklist)
+}
+
+func TestCheckURL(t *testing.T) {
+	assert.Equal(t, "https://api.abuseipdb.com/api/v2/check?ipAddress=127.0.0.1&maxAgeInDays=1",
+		CheckURL("https://api.abuseipdb.com/api/v2/blacklist?confidenceMinimum=90"))
+}
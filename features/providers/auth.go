@@ -0,0 +1,68 @@
+package providers
+
+import (
+	"blacked/features/providers/base"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CheckAuth checks the credentials of the named providers, or of every
+// provider sending credentials when names is empty, with one lightweight
+// request each. Named providers that send none are reported as such. The
+// checks run concurrently; the statuses are sorted by provider.
+func (p *Providers) CheckAuth(ctx context.Context, names []string) ([]base.AuthStatus, error) {
+	var checked []base.Provider
+	if len(names) == 0 {
+		for _, prov := range *p {
+			if checker, ok := prov.(base.AuthChecker); ok && checker.RequiresAuth() {
+				checked = append(checked, prov)
+			}
+		}
+	} else {
+		for _, name := range names {
+			i := slices.IndexFunc(*p, func(prov base.Provider) bool { return prov.GetName() == name })
+			if i < 0 {
+				return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+			}
+			checked = append(checked, (*p)[i])
+		}
+	}
+
+	statuses := make([]base.AuthStatus, len(checked))
+	var wg sync.WaitGroup
+	for i, prov := range checked {
+		checker, ok := prov.(base.AuthChecker)
+		if !ok {
+			statuses[i] = base.AuthStatus{Provider: prov.GetName(), Status: base.AuthNone}
+			continue
+		}
+		wg.Go(func() {
+			statuses[i] = checker.CheckAuth(ctx)
+		})
+	}
+	wg.Wait()
+
+	slices.SortFunc(statuses, func(a, b base.AuthStatus) int { return strings.Compare(a.Provider, b.Provider) })
+	for _, s := range statuses {
+		switch s.Status {
+		case base.AuthInvalid, base.AuthError:
+			log.Warn().Str("provider", s.Provider).Str("status", s.Status).Str("error", s.Error).Msg("Provider credential check failed")
+		case base.AuthExpiring:
+			log.Warn().Str("provider", s.Provider).Time("expires_at", *s.ExpiresAt).Msg("Provider credentials expire soon")
+		}
+	}
+	return statuses, nil
+}
+
+// AuthFailed reports whether any status has rejected or unchecked
+// credentials.
+func AuthFailed(statuses []base.AuthStatus) bool {
+	return slices.ContainsFunc(statuses, func(s base.AuthStatus) bool {
+		return s.Status == base.AuthInvalid || s.Status == base.AuthError
+	})
+}
@@ -0,0 +1,190 @@
+package base
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entry_collector"
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// FormatAdblock is the source format of Adblock Plus / uBlock filter lists.
+const FormatAdblock = "adblock"
+
+// adblockIgnoredOptions are rule options that rewrite, redirect or disable
+// a request instead of blocking it, so the rule lists nothing to block.
+var adblockIgnoredOptions = map[string]bool{
+	"badfilter":     true,
+	"csp":           true,
+	"header":        true,
+	"permissions":   true,
+	"redirect":      true,
+	"redirect-rule": true,
+	"removeheader":  true,
+	"removeparam":   true,
+	"replace":       true,
+	"uritransform":  true,
+}
+
+// adblockDocumentOptions are the exception options that allow a whole site;
+// exceptions with any other option only allow some requests to it.
+var adblockDocumentOptions = map[string]bool{
+	"all":      true,
+	"doc":      true,
+	"document": true,
+}
+
+// AdblockRule is a network rule read from a filter list line.
+type AdblockRule struct {
+	Target    string // Host, or host and path prefix, e.g. "ads.example" or "cdn.example/banner/"
+	Exception bool   // @@ rule: Target is allowed rather than blocked
+}
+
+// ParseAdblockLine reads the network rule of one Adblock Plus line:
+// "||host^" and "|https://host/path|" rules, with or without options, and
+// their "@@" exceptions. Comments (!), headers ([Adblock Plus 2.0]),
+// cosmetic rules (##), regex and wildcard rules list no single host and
+// return ok false.
+func ParseAdblockLine(line string) (rule AdblockRule, ok bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '!' || line[0] == '[' || strings.Contains(line, "#") {
+		// Besides comments and headers, ##, #@#, #?# and #$# mark cosmetic
+		// and scriptlet rules.
+		return AdblockRule{}, false
+	}
+
+	line, rule.Exception = strings.CutPrefix(line, "@@")
+
+	pattern, options, _ := strings.Cut(line, "$")
+	for opt := range strings.SplitSeq(options, ",") {
+		name, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(opt), "~"), "=")
+		if adblockIgnoredOptions[strings.ToLower(name)] {
+			return AdblockRule{}, false
+		}
+	}
+	if rule.Exception && options != "" && !hasAdblockOption(options, adblockDocumentOptions) {
+		return AdblockRule{}, false
+	}
+
+	switch {
+	case strings.HasPrefix(pattern, "||"):
+		pattern = pattern[2:]
+	case strings.HasPrefix(pattern, "|"):
+		// Address anchor: |https://host/path
+		pattern = pattern[1:]
+		if i := strings.Index(pattern, "://"); i >= 0 {
+			pattern = pattern[i+3:]
+		} else {
+			return AdblockRule{}, false
+		}
+	default:
+		// Substring rules such as /banner/ads. or /regex/ match no host.
+		return AdblockRule{}, false
+	}
+
+	if i := strings.IndexAny(pattern, "^|"); i >= 0 {
+		pattern = pattern[:i]
+	}
+	if pattern == "" || strings.ContainsAny(pattern, "*") {
+		return AdblockRule{}, false
+	}
+
+	host, path, hasPath := strings.Cut(pattern, "/")
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if !strings.Contains(host, ".") {
+		return AdblockRule{}, false
+	}
+	rule.Target = host
+	if hasPath {
+		rule.Target += "/" + path
+	}
+	return rule, true
+}
+
+func hasAdblockOption(options string, names map[string]bool) bool {
+	for opt := range strings.SplitSeq(options, ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		if names[strings.ToLower(name)] {
+			return true
+		}
+	}
+	return false
+}
+
+// NewAdblockParseFunc returns a parse function for Adblock Plus / uBlock
+// filter lists. Blocking rules become entries of source and category unless
+// an exception in the same list allows their host or a parent domain of it.
+func NewAdblockParseFunc(source, category string, workers, batchSize int) func(io.Reader, entry_collector.Collector) error {
+	return func(data io.Reader, collector entry_collector.Collector) error {
+		// Exceptions may follow the rules they allow, so the list is read
+		// twice; fetched sources are already held in memory.
+		body, err := io.ReadAll(data)
+		if err != nil {
+			return err
+		}
+		allowed := adblockExceptions(body)
+
+		return ParseLinesParallel(bytes.NewReader(body), collector, source, workers, batchSize, func(line, processID string) (*entries.Entry, error) {
+			rule, ok := ParseAdblockLine(line)
+			if !ok || rule.Exception || allowed.allows(rule.Target) {
+				return nil, nil
+			}
+
+			entry := entries.NewEntry().
+				WithSource(source).
+				WithProcessID(processID).
+				WithCategory(category)
+
+			if err := entry.SetURL(rule.Target); err != nil {
+				log.Error().Err(err).Msgf("error setting URL: %s", rule.Target)
+				return nil, nil
+			}
+
+			return entry, nil
+		})
+	}
+}
+
+// adblockAllowlist holds the targets of a list's exception rules.
+type adblockAllowlist map[string]bool
+
+func adblockExceptions(body []byte) adblockAllowlist {
+	allowed := make(adblockAllowlist)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !bytes.HasPrefix(bytes.TrimSpace(line), []byte("@@")) {
+			continue
+		}
+		if rule, ok := ParseAdblockLine(string(line)); ok && rule.Exception {
+			allowed[rule.Target] = true
+		}
+	}
+	return allowed
+}
+
+// allows reports whether target, or a parent domain of its host, is excepted.
+// A path exception only allows that exact target.
+func (a adblockAllowlist) allows(target string) bool {
+	if len(a) == 0 {
+		return false
+	}
+	if a[target] {
+		return true
+	}
+	host, _, _ := strings.Cut(target, "/")
+	for {
+		if a[host] {
+			return true
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return false
+		}
+		host = host[i+1:]
+	}
+}
@@ -0,0 +1,80 @@
+package base
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAdblockLine(t *testing.T) {
+	for line, want := range map[string]AdblockRule{
+		"||ads.example^":                      {Target: "ads.example"},
+		"||Tracker.Example^$third-party":      {Target: "tracker.example"},
+		"||cdn.example/banner/":               {Target: "cdn.example/banner/"},
+		"||ads.example^|":                     {Target: "ads.example"},
+		"|https://bad.example/payload.exe|":   {Target: "bad.example/payload.exe"},
+		"@@||good.example^":                   {Target: "good.example", Exception: true},
+		"@@||good.example^$document":          {Target: "good.example", Exception: true},
+		"||ads.example^$important,all":        {Target: "ads.example"},
+		"  ||spaced.example^  ":               {Target: "spaced.example"},
+		"||popup.example^$popup,domain=a.com": {Target: "popup.example"},
+	} {
+		got, ok := ParseAdblockLine(line)
+		assert.True(t, ok, line)
+		assert.Equal(t, want, got, line)
+	}
+
+	for _, line := range []string{
+		"",
+		"! Title: EasyList",
+		"[Adblock Plus 2.0]",
+		"example.com##.banner",
+		"example.com#@#.banner",
+		"##.ad-slot",
+		"/banner/*/img^",
+		"/^https?:\\/\\/ads\\./",
+		"||*.wild.example^",
+		"||ads.example^$badfilter",
+		"||ads.example^$redirect=noopjs",
+		"||tracker.example^$removeparam=utm_source",
+		"@@||good.example^$script",
+		"@@||good.example^$elemhide",
+		"||localhost^",
+		"&ad_box_",
+	} {
+		_, ok := ParseAdblockLine(line)
+		assert.False(t, ok, line)
+	}
+}
+
+func TestAdblockParseFunc(t *testing.T) {
+	list := strings.Join([]string{
+		"[Adblock Plus 2.0]",
+		"! Title: OISD big",
+		"||ads.example^",
+		"||cdn.partner.example^",
+		"||tracker.example/pixel.gif",
+		"example.com##.banner",
+		"@@||partner.example^",
+		"||safe.example/ads/",
+		"@@||safe.example/ads/",
+		"||safe.example/other/",
+	}, "\n")
+
+	collector := &MockCollector{}
+	parse := NewAdblockParseFunc("oisd-big", "blocklist", 2, 2)
+	require.NoError(t, parse(strings.NewReader(list), collector))
+
+	var links []string
+	for _, e := range collector.GetEntries() {
+		assert.Equal(t, "oisd-big", e.Source)
+		assert.Equal(t, "blocklist", e.Category)
+		links = append(links, e.Host+e.Path)
+	}
+	slices.Sort(links)
+	assert.Equal(t, []string{"ads.example", "safe.example/other", "tracker.example/pixel.gif"}, links,
+		"exceptions allow their host, its subdomains and exact paths, even when listed after the rule")
+}
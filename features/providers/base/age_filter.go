@@ -0,0 +1,63 @@
+package base
+
+import (
+	"sync/atomic"
+	"time"
+
+	"blacked/internal/collector"
+
+	"github.com/rs/zerolog/log"
+)
+
+// AgeFilter drops entries a feed dates before a cutoff, set by the
+// ignore_older_than option of a provider, and counts them. A nil filter
+// keeps everything, so providers use it unconditionally.
+type AgeFilter struct {
+	provider string
+	cutoff   time.Time
+	skipped  atomic.Int64
+}
+
+// NewAgeFilter returns a filter dropping entries older than maxAge as of
+// now; providers create one per parse run. It returns nil when maxAge is
+// unset or not positive.
+func NewAgeFilter(provider string, maxAge *time.Duration) *AgeFilter {
+	if maxAge == nil || *maxAge <= 0 {
+		return nil
+	}
+	return &AgeFilter{provider: provider, cutoff: time.Now().Add(-*maxAge)}
+}
+
+// Keep reports whether an entry dated t is recent enough, counting it as
+// skipped otherwise. Undated entries (zero t) are kept.
+func (f *AgeFilter) Keep(t time.Time) bool {
+	if f == nil || t.IsZero() || !t.Before(f.cutoff) {
+		return true
+	}
+	f.skipped.Add(1)
+	return false
+}
+
+// Skipped returns the number of entries dropped so far.
+func (f *AgeFilter) Skipped() int {
+	if f == nil {
+		return 0
+	}
+	return int(f.skipped.Load())
+}
+
+// Report logs and records the number of entries dropped by the run.
+func (f *AgeFilter) Report() {
+	skipped := f.Skipped()
+	if skipped == 0 {
+		return
+	}
+	log.Info().
+		Str("provider", f.provider).
+		Int("skipped", skipped).
+		Time("cutoff", f.cutoff).
+		Msg("Skipped entries older than ignore_older_than")
+	if mc, err := collector.GetMetricsCollector(); err == nil {
+		mc.IncrementEntriesTooOld(f.provider, skipped)
+	}
+}
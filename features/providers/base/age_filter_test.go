@@ -0,0 +1,26 @@
+package base
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgeFilter(t *testing.T) {
+	var none *AgeFilter
+	assert.True(t, none.Keep(time.Unix(0, 0)), "a nil filter keeps everything")
+	assert.Nil(t, NewAgeFilter("age-test", nil))
+	zero := time.Duration(0)
+	assert.Nil(t, NewAgeFilter("age-test", &zero))
+
+	maxAge := 30 * 24 * time.Hour
+	f := NewAgeFilter("age-test", &maxAge)
+	now := time.Now()
+	assert.True(t, f.Keep(now.Add(-time.Hour)))
+	assert.True(t, f.Keep(time.Time{}), "undated entries are kept")
+	assert.False(t, f.Keep(now.Add(-maxAge-time.Hour)))
+	assert.False(t, f.Keep(now.AddDate(-2, 0, 0)))
+	assert.Equal(t, 2, f.Skipped())
+	f.Report()
+}
@@ -0,0 +1,130 @@
+package base
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// allowlistMu guards the extra domains, the watched collectors and the
+	// AllowedDomains of every collector RebuildAllowedDomains updates.
+	allowlistMu         sync.RWMutex
+	extraAllowedDomains []string
+	allowlistTargets    []*colly.Collector
+)
+
+// SetExtraAllowedDomains sets the hosts every provider may fetch from
+// (e.g. shared CDNs) and rebuilds the allowlist of watched collectors.
+func SetExtraAllowedDomains(domains []string) {
+	allowlistMu.Lock()
+	extraAllowedDomains = NormalizeHosts(domains...)
+	allowlistMu.Unlock()
+
+	RebuildAllowedDomains()
+}
+
+// WatchAllowedDomains registers a collector whose AllowedDomains are kept in
+// sync with the provider registry, so providers added at runtime are allowed.
+func WatchAllowedDomains(c *colly.Collector) {
+	if c == nil {
+		return
+	}
+
+	allowlistMu.Lock()
+	if !slices.Contains(allowlistTargets, c) {
+		allowlistTargets = append(allowlistTargets, c)
+	}
+	allowlistMu.Unlock()
+
+	RebuildAllowedDomains()
+}
+
+// AllowedDomains returns the union of every registered provider's hosts and
+// the configured extra domains.
+func AllowedDomains() []string {
+	result := globalAllowedDomains()
+	for _, p := range GetRegisteredProviders() {
+		result = append(result, p.AllowedDomains()...)
+	}
+
+	return NormalizeHosts(result...)
+}
+
+// RebuildAllowedDomains recomputes the allowlists and applies them to every
+// registered provider's collector and to all watched collectors. Colly
+// reads AllowedDomains unlocked while it fetches, so those collectors are
+// never fetched with directly: fetches use a clone from cloneCollector.
+func RebuildAllowedDomains() {
+	perProvider := make(map[*colly.Collector][]string)
+	for _, p := range GetRegisteredProviders() {
+		if bp, ok := p.(*BaseProvider); ok && bp.CollyClient != nil {
+			perProvider[bp.CollyClient] = bp.AllowedDomains()
+		}
+	}
+	domains := AllowedDomains()
+
+	allowlistMu.Lock()
+	for c, allowed := range perProvider {
+		c.AllowedDomains = allowed
+	}
+	for _, c := range allowlistTargets {
+		c.AllowedDomains = domains
+	}
+	allowlistMu.Unlock()
+
+	log.Trace().Strs("domains", domains).Msg("Rebuilt colly allowed domains")
+}
+
+// cloneCollector returns a copy of c to fetch with, taken while no rebuild
+// writes its AllowedDomains.
+func cloneCollector(c *colly.Collector) *colly.Collector {
+	allowlistMu.RLock()
+	defer allowlistMu.RUnlock()
+	return c.Clone()
+}
+
+// globalAllowedDomains returns a copy of the configured extra domains.
+func globalAllowedDomains() []string {
+	allowlistMu.RLock()
+	defer allowlistMu.RUnlock()
+	return slices.Clone(extraAllowedDomains)
+}
+
+// NormalizeHosts turns URLs or bare hosts into a sorted, de-duplicated list
+// of lowercase hostnames without ports. Invalid values are skipped.
+func NormalizeHosts(values ...string) []string {
+	hosts := make([]string, 0, len(values))
+	for _, v := range values {
+		if h := hostOf(v); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+
+	slices.Sort(hosts)
+	return slices.Compact(hosts)
+}
+
+// hostOf extracts the hostname from a URL or bare host value. Object
+// storage URLs name a bucket, not a host colly visits, and are skipped.
+func hostOf(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" || IsObjectStorageURL(value) {
+		return ""
+	}
+	if !strings.Contains(value, "://") {
+		value = "http://" + value
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		log.Warn().Err(err).Str("value", value).Msg("Skipping invalid allowed domain")
+		return ""
+	}
+
+	return strings.ToLower(u.Hostname())
+}
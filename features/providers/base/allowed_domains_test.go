@@ -0,0 +1,69 @@
+package base
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeHosts(t *testing.T) {
+	got := NormalizeHosts(
+		"https://Example.com/feed.txt",
+		"example.com",
+		"cdn.example.net:8443",
+		"",
+		"  ",
+	)
+	assert.Equal(t, []string{"cdn.example.net", "example.com"}, got)
+}
+
+func TestBaseProviderAllowedDomains(t *testing.T) {
+	SetExtraAllowedDomains([]string{"raw.githubusercontent.com"})
+	defer SetExtraAllowedDomains(nil)
+
+	p := NewBaseProvider("allowlist-test", "https://feed.example.com/list.txt", "test", colly.NewCollector(), nil).
+		SetMirrors([]string{"https://mirror.example.org/list.txt"}).
+		SetAllowedDomains([]string{"cdn.example.net"})
+
+	assert.Equal(t, []string{
+		"cdn.example.net",
+		"feed.example.com",
+		"mirror.example.org",
+		"raw.githubusercontent.com",
+	}, p.AllowedDomains())
+}
+
+func TestRebuildAllowedDomainsOnRegister(t *testing.T) {
+	watched := colly.NewCollector()
+	WatchAllowedDomains(watched)
+
+	p := NewBaseProvider("allowlist-register-test", "https://late.example.com/feed", "test", colly.NewCollector(), nil).
+		SetAllowedDomains([]string{"redirect.example.com"})
+	p.Register()
+
+	assert.Contains(t, watched.AllowedDomains, "late.example.com")
+	assert.Contains(t, watched.AllowedDomains, "redirect.example.com")
+	assert.Equal(t, []string{"late.example.com", "redirect.example.com"}, p.CollyClient.AllowedDomains)
+}
+
+func TestRebuildAllowedDomainsWhileFetching(t *testing.T) {
+	p := NewBaseProvider("allowlist-race-test", "https://race.example.com/feed", "test", colly.NewCollector(), nil)
+	p.Register()
+
+	// Run with -race: rebuilds must not write AllowedDomains while fetches
+	// clone the collector.
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Go(func() {
+			NewBaseProvider(fmt.Sprintf("allowlist-race-test-%d", i), fmt.Sprintf("https://race%d.example.com/feed", i), "test", colly.NewCollector(), nil).Register()
+		})
+		wg.Go(func() {
+			c := cloneCollector(p.CollyClient)
+			assert.Contains(t, c.AllowedDomains, "race.example.com")
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,162 @@
+package base
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Auth check outcomes.
+const (
+	AuthValid    = "valid"    // The credentials were accepted
+	AuthExpiring = "expiring" // Accepted, but they expire within AuthExpiryWarning
+	AuthInvalid  = "invalid"  // Rejected by the source, or expired
+	AuthError    = "error"    // The check failed for another reason, e.g. the source is down
+	AuthNone     = "none"     // The provider needs no credentials
+)
+
+const (
+	// AuthExpiryWarning is how long before their expiry accepted credentials
+	// are reported as expiring.
+	AuthExpiryWarning = 7 * 24 * time.Hour
+	// authCheckTimeout bounds a single auth check request.
+	authCheckTimeout = 30 * time.Second
+)
+
+// AuthCheck is a lightweight authenticated request that tells whether the
+// credentials of a provider are accepted, without downloading its feed.
+type AuthCheck struct {
+	Method string // HEAD when empty
+	URL    string // The source URL when empty
+	Token  string // Credential whose expiry is read when it is a JWT
+}
+
+// AuthStatus is the outcome of an auth check.
+type AuthStatus struct {
+	Provider   string        `json:"provider"`
+	Status     string        `json:"status"`
+	StatusCode int           `json:"status_code,omitempty"` // HTTP status of the check request
+	ExpiresAt  *time.Time    `json:"expires_at,omitempty"`  // Expiry the credential carries, if any
+	CheckedAt  time.Time     `json:"checked_at"`
+	Duration   time.Duration `json:"duration"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// AuthChecker is implemented by providers that can check their credentials.
+type AuthChecker interface {
+	// RequiresAuth reports whether the provider sends credentials.
+	RequiresAuth() bool
+	// CheckAuth sends the auth check request of the provider.
+	CheckAuth(ctx context.Context) AuthStatus
+}
+
+// SetAuthCheck marks the provider as sending credentials, checked with check.
+func (b *BaseProvider) SetAuthCheck(check AuthCheck) *BaseProvider {
+	b.authCheck = &check
+	return b
+}
+
+// RequiresAuth reports whether the provider was given an auth check.
+func (b *BaseProvider) RequiresAuth() bool {
+	return b.authCheck != nil
+}
+
+// CheckAuth sends the auth check request with the provider's headers and
+// user agent. 401 and 403 answers mean the credentials are rejected; other
+// non-2xx answers and network errors leave their validity unknown. A HEAD
+// check the source refuses with 405 is retried as a GET whose body is not
+// read.
+func (b *BaseProvider) CheckAuth(ctx context.Context) AuthStatus {
+	status := AuthStatus{Provider: b.Name, Status: AuthNone, CheckedAt: time.Now().UTC()}
+	if b.authCheck == nil {
+		return status
+	}
+	check := *b.authCheck
+	if check.URL == "" {
+		check.URL = b.SourceURL
+	}
+	if check.Method == "" {
+		check.Method = http.MethodHead
+	}
+	if exp, ok := tokenExpiry(check.Token); ok {
+		status.ExpiresAt = &exp
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, authCheckTimeout)
+	defer cancel()
+
+	started := time.Now()
+	code, err := b.sendAuthCheck(ctx, check.Method, check.URL)
+	if err == nil && code == http.StatusMethodNotAllowed && check.Method == http.MethodHead {
+		code, err = b.sendAuthCheck(ctx, http.MethodGet, check.URL)
+	}
+	status.Duration = time.Since(started)
+	status.StatusCode = code
+
+	expired := status.ExpiresAt != nil && !status.ExpiresAt.After(status.CheckedAt)
+	switch {
+	case err != nil:
+		status.Status, status.Error = AuthError, err.Error()
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		status.Status, status.Error = AuthInvalid, fmt.Sprintf("credentials rejected: HTTP %d", code)
+	case code < 200 || code >= 300:
+		status.Status, status.Error = AuthError, fmt.Sprintf("unexpected answer: HTTP %d", code)
+	case expired:
+		status.Status, status.Error = AuthInvalid, "credentials expired"
+	case status.ExpiresAt != nil && status.ExpiresAt.Sub(status.CheckedAt) < AuthExpiryWarning:
+		status.Status = AuthExpiring
+	default:
+		status.Status = AuthValid
+	}
+	if expired && status.Status == AuthError {
+		status.Status, status.Error = AuthInvalid, "credentials expired; "+status.Error
+	}
+	return status
+}
+
+func (b *BaseProvider) sendAuthCheck(ctx context.Context, method, checkURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, checkURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range b.Headers {
+		req.Header.Set(k, v)
+	}
+	if b.CollyClient != nil && b.CollyClient.UserAgent != "" {
+		req.Header.Set("User-Agent", b.CollyClient.UserAgent)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// tokenExpiry returns the exp claim of token when it is a JWT.
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(strings.TrimPrefix(token, "Bearer "), ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp *json.Number `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == nil {
+		return time.Time{}, false
+	}
+	exp, err := claims.Exp.Float64()
+	if err != nil || exp <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(exp), 0).UTC(), true
+}
@@ -0,0 +1,79 @@
+package base
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jwt(exp time.Time) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	return enc([]byte(`{"alg":"HS256"}`)) + "." + enc(fmt.Appendf(nil, `{"sub":"blacked","exp":%d}`, exp.Unix())) + ".sig"
+}
+
+func TestCheckAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Key") != "good":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/no-head" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/down":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			assert.Equal(t, "blacked-test", r.UserAgent())
+			w.Write([]byte("feed"))
+		}
+	}))
+	defer srv.Close()
+
+	client := colly.NewCollector()
+	client.UserAgent = "blacked-test"
+	provider := func(key, path string, check *AuthCheck) *BaseProvider {
+		p := NewBaseProvider("feed", srv.URL+path, "malware", client, nil)
+		p.SetHeaders(map[string]string{"Key": key})
+		if check != nil {
+			p.SetAuthCheck(*check)
+		}
+		return p
+	}
+	ctx := context.Background()
+
+	s := provider("good", "/feed", nil).CheckAuth(ctx)
+	assert.Equal(t, AuthNone, s.Status)
+
+	s = provider("good", "/feed", &AuthCheck{}).CheckAuth(ctx)
+	assert.Equal(t, AuthValid, s.Status)
+	assert.Equal(t, http.StatusOK, s.StatusCode)
+	assert.Nil(t, s.ExpiresAt)
+
+	s = provider("good", "/no-head", &AuthCheck{}).CheckAuth(ctx)
+	assert.Equal(t, AuthValid, s.Status, "a refused HEAD is retried as a GET")
+
+	s = provider("bad", "/feed", &AuthCheck{Method: http.MethodGet}).CheckAuth(ctx)
+	assert.Equal(t, AuthInvalid, s.Status)
+	assert.Equal(t, http.StatusUnauthorized, s.StatusCode)
+
+	s = provider("good", "/feed", &AuthCheck{URL: srv.URL + "/down"}).CheckAuth(ctx)
+	assert.Equal(t, AuthError, s.Status)
+
+	soon := time.Now().Add(48 * time.Hour).Truncate(time.Second).UTC()
+	s = provider("good", "/feed", &AuthCheck{Token: jwt(soon)}).CheckAuth(ctx)
+	assert.Equal(t, AuthExpiring, s.Status)
+	require.NotNil(t, s.ExpiresAt)
+	assert.True(t, s.ExpiresAt.Equal(soon))
+
+	s = provider("good", "/feed", &AuthCheck{Token: jwt(time.Now().Add(60 * 24 * time.Hour))}).CheckAuth(ctx)
+	assert.Equal(t, AuthValid, s.Status)
+
+	s = provider("good", "/down", &AuthCheck{Token: "Bearer " + jwt(time.Now().Add(-time.Hour))}).CheckAuth(ctx)
+	assert.Equal(t, AuthInvalid, s.Status, "an expired token is invalid even when the source is down")
+}
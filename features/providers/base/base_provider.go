@@ -7,12 +7,15 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"runtime/debug"
 	"strings"
 	"time"
 
 	"blacked/features/entries/repository"
 	"blacked/features/entry_collector"
+	"blacked/internal/collector"
 	"blacked/internal/config"
+	"blacked/internal/utils"
 
 	"github.com/gocolly/colly/v2"
 	"github.com/google/uuid"
@@ -28,6 +31,7 @@ var (
 	// Parse errors
 	ErrParsingData   = errors.New("error parsing source data")
 	ErrInvalidFormat = errors.New("invalid data format from source")
+	ErrParserPanic   = errors.New("parser panicked")
 
 	// Repository errors
 	ErrBatchSaving      = errors.New("error saving batch entries")
@@ -39,6 +43,7 @@ var (
 
 type Provider interface {
 	GetName() string
+	GetCategory() string
 	Source() string
 	Fetch() (io.Reader, error)
 	Parse(data io.Reader) error
@@ -49,6 +54,11 @@ type Provider interface {
 	Register() *BaseProvider
 	GetProcessID() uuid.UUID
 	SetCollyClient(collyClient *colly.Collector)
+	AllowedDomains() []string
+	ChecksumStatus() string
+	ChecksumAttempts() []ChecksumAttempt
+	SetFetchValidators(v utils.FetchValidators)
+	FetchedValidators() utils.FetchValidators
 }
 
 type BaseProvider struct {
@@ -58,9 +68,24 @@ type BaseProvider struct {
 	ProcessID     *uuid.UUID
 	CollyClient   *colly.Collector
 	CronSchedule  string
+	Mirrors       []string
+	ExtraDomains  []string
+	Headers       map[string]string // Sent with every fetch, e.g. API key headers
+	Body          []byte            // Sent with every fetch as a POST, e.g. a search API query; nil fetches with GET
+	Stream        *bool             // Stream the source instead of buffering it; nil follows config
+	Conditional   *bool             // Send the validators of the last imported response; nil follows config
 	RateLimit     time.Duration
 	Repository    repository.BlacklistRepository
 	ParseFunction func(io.Reader, entry_collector.Collector) error
+
+	ChecksumSource string // URL of the published SHA-256 sums; empty follows config
+	checksumStatus string            // Outcome of the last Fetch's verification
+	checksumTries  []ChecksumAttempt // Outcome per URL the last Fetch verified
+
+	validators utils.FetchValidators // Sent with the next Fetch
+	fetched    utils.FetchValidators // Sent by the source with the last Fetch
+
+	authCheck *AuthCheck // Request checking the credentials; nil when the provider sends none
 }
 
 // NewBaseProvider creates a new BaseProvider
@@ -78,6 +103,7 @@ func NewBaseProvider(name, sourceURL, category string, collyClient *colly.Collec
 
 func (b *BaseProvider) Register() *BaseProvider {
 	RegisterProvider(b)
+	RebuildAllowedDomains()
 	return b
 }
 
@@ -131,21 +157,162 @@ func (b *BaseProvider) SetCronSchedule(cron string) *BaseProvider {
 	return b
 }
 
-// Fetch retrieves data from source URL
+// SetMirrors sets fallback source URLs tried in order when the primary source fails.
+func (b *BaseProvider) SetMirrors(mirrors []string) *BaseProvider {
+	b.Mirrors = mirrors
+	return b
+}
+
+// SetAllowedDomains sets extra hosts (CDNs, redirect targets) this provider may fetch from.
+func (b *BaseProvider) SetAllowedDomains(domains []string) *BaseProvider {
+	b.ExtraDomains = domains
+	return b
+}
+
+// SetHeaders sets request headers sent with every fetch of the source and its mirrors.
+func (b *BaseProvider) SetHeaders(headers map[string]string) *BaseProvider {
+	b.Headers = headers
+	return b
+}
+
+// SetRequestBody makes fetches of the source and its mirrors POST body,
+// for sources queried through a search API. Such sources are not streamed.
+func (b *BaseProvider) SetRequestBody(body []byte) *BaseProvider {
+	b.Body = body
+	return b
+}
+
+// AllowedDomains returns the hosts of the source URL, its mirrors, its
+// checksum URL, the provider's extra domains and the globally configured
+// extra domains.
+func (b *BaseProvider) AllowedDomains() []string {
+	values := append(globalAllowedDomains(), b.SourceURL)
+	values = append(values, b.Mirrors...)
+	values = append(values, b.ExtraDomains...)
+	values = append(values, b.ChecksumURL())
+
+	return NormalizeHosts(values...)
+}
+
+// Fetch retrieves data from the source URL, falling back to mirrors in order.
+// Gzip, zstd and single-file zip bodies are decompressed. A streaming
+// provider returns the body unread; the caller closes readers that are
+// io.Closers. With a checksum URL the source is buffered and only returned
+// when its SHA-256 matches the published one. A conditional fetch of the
+// source or a mirror answered with 304 Not Modified returns
+// utils.ErrSourceNotModified. When every URL fails, the errors of all of
+// them are returned.
 func (b *BaseProvider) Fetch() (io.Reader, error) {
+	b.checksumStatus = ""
+	b.checksumTries = nil
+	b.fetched = utils.FetchValidators{}
+	fetch := b.fetchURL
+	if checksumURL := b.ChecksumURL(); checksumURL != "" {
+		want, err := b.publishedChecksum(checksumURL)
+		if err != nil {
+			b.checksumStatus = ChecksumUnavailable
+			return nil, err
+		}
+		fetch = func(sourceURL string) (io.Reader, error) {
+			return b.fetchVerified(sourceURL, want)
+		}
+	} else if b.Streaming() && b.Body == nil {
+		fetch = b.fetchStream
+	}
+
+	reader, err := fetch(b.SourceURL)
+	if err == nil || errors.Is(err, utils.ErrSourceNotModified) {
+		return reader, err
+	}
+	if len(b.Mirrors) == 0 {
+		return nil, err
+	}
+
+	log.Warn().Err(err).Str("provider", b.Name).Strs("mirrors", b.Mirrors).Msg("Source fetch failed, trying mirrors")
+	errs := []error{err}
+	for _, mirror := range b.Mirrors {
+		reader, mirrorErr := fetch(mirror)
+		if mirrorErr == nil || errors.Is(mirrorErr, utils.ErrSourceNotModified) {
+			return reader, mirrorErr
+		}
+		log.Warn().Err(mirrorErr).Str("provider", b.Name).Str("mirror", mirror).Msg("Mirror fetch failed")
+		errs = append(errs, mirrorErr)
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// FetchPage retrieves another page of the source, such as the next page of
+// a paginated API, with the provider's client and headers.
+func (b *BaseProvider) FetchPage(pageURL string) (io.Reader, error) {
+	return b.fetchURL(pageURL)
+}
+
+// PostPage retrieves another page of a search API source, POSTing body
+// with the provider's client and headers.
+func (b *BaseProvider) PostPage(pageURL string, body []byte) (io.Reader, error) {
+	return b.fetch(pageURL, body)
+}
+
+// fetchURL retrieves data from a single URL, POSTing the provider's body
+// when it has one.
+func (b *BaseProvider) fetchURL(sourceURL string) (io.Reader, error) {
+	return b.fetch(sourceURL, b.Body)
+}
+
+func (b *BaseProvider) fetch(sourceURL string, body []byte) (io.Reader, error) {
+	responseBody, contentEncoding, err := b.download(sourceURL, body)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBuffered(responseBody, contentEncoding, sourceURL)
+}
+
+// download retrieves the body of a single URL as sent, still compressed,
+// with its Content-Encoding.
+func (b *BaseProvider) download(sourceURL string, body []byte) ([]byte, string, error) {
+	if IsObjectStorageURL(sourceURL) {
+		responseBody, err := downloadObject(DefaultObjectStorageFetcher(), sourceURL)
+		return responseBody, "", err
+	}
+
 	var responseBody []byte
+	var contentEncoding string
 	var fetchErr error
 
-	c := b.CollyClient.Clone()
+	var notModified bool
+
+	c := cloneCollector(b.CollyClient)
+	conditional := b.conditionalHeaders(sourceURL)
+	if len(b.Headers) > 0 || len(conditional) > 0 {
+		c.OnRequest(func(r *colly.Request) {
+			for k, v := range b.Headers {
+				r.Headers.Set(k, v)
+			}
+			for k, v := range conditional {
+				r.Headers.Set(k, v)
+			}
+		})
+	}
 	c.OnResponse(func(r *colly.Response) {
+		if r.StatusCode == http.StatusNotModified {
+			notModified = true // Only seen when the client parses error responses
+			return
+		}
 		responseBody = r.Body
+		contentEncoding = r.Headers.Get("Content-Encoding")
+		b.keepValidators(sourceURL, *r.Headers)
 		log.Info().
-			Str("source", b.SourceURL).
+			Str("source", sourceURL).
 			Int("bytes", len(responseBody)).
 			Msg("Fetched data from source")
 	})
 
 	c.OnError(func(r *colly.Response, err error) {
+		if r.StatusCode == http.StatusNotModified {
+			notModified = true
+			return
+		}
 		fetchErr = ErrFetchingSource
 		log.Err(err).
 			Str("url", r.Request.URL.String()).
@@ -153,28 +320,103 @@ func (b *BaseProvider) Fetch() (io.Reader, error) {
 			Msg("Colly error when fetching data")
 	})
 
-	log.Info().Msgf("Fetching %s", b.SourceURL)
-	if err := c.Visit(b.SourceURL); err != nil {
-		log.Err(err).Str("url", b.SourceURL).Msg("Failed to visit URL")
-		return nil, ErrVisitingURL
+	log.Info().Msgf("Fetching %s", sourceURL)
+	visit := func() error { return c.Visit(sourceURL) }
+	if body != nil {
+		visit = func() error { return c.PostRaw(sourceURL, body) }
+	}
+	if err := visit(); err != nil && !notModified {
+		log.Err(err).Str("url", sourceURL).Msg("Failed to visit URL")
+		return nil, "", ErrVisitingURL
 	}
 
 	c.Wait()
 
+	if notModified {
+		log.Info().Str("url", sourceURL).Msg("Source not modified")
+		return nil, "", utils.ErrSourceNotModified
+	}
 	if fetchErr != nil {
-		return nil, fetchErr
+		return nil, "", fetchErr
+	}
+
+	if len(responseBody) == 0 {
+		log.Error().Str("url", sourceURL).Msg("Empty response from source")
+		return nil, "", ErrEmptyResponse
+	}
+
+	return responseBody, contentEncoding, nil
+}
+
+// fetchObject retrieves data from an s3:// or gs:// URL.
+func fetchObject(fetcher Fetcher, sourceURL string) (io.Reader, error) {
+	responseBody, err := downloadObject(fetcher, sourceURL)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBuffered(responseBody, "", sourceURL)
+}
+
+// downloadObject reads the object at an s3:// or gs:// URL as stored.
+func downloadObject(fetcher Fetcher, sourceURL string) ([]byte, error) {
+	log.Info().Msgf("Fetching %s", sourceURL)
+	body, err := fetcher.Fetch(sourceURL)
+	if err != nil {
+		log.Err(err).Str("url", sourceURL).Msg("Object storage error when fetching data")
+		return nil, ErrFetchingSource
+	}
+	defer body.Close()
+
+	responseBody, err := io.ReadAll(body)
+	if err != nil {
+		log.Err(err).Str("url", sourceURL).Msg("Failed to read object")
+		return nil, ErrFetchingSource
 	}
 
 	if len(responseBody) == 0 {
-		log.Error().Str("url", b.SourceURL).Msg("Empty response from source")
+		log.Error().Str("url", sourceURL).Msg("Empty response from source")
 		return nil, ErrEmptyResponse
 	}
 
-	return bytes.NewReader(responseBody), nil
+	log.Info().
+		Str("source", sourceURL).
+		Int("bytes", len(responseBody)).
+		Msg("Fetched data from source")
+	return responseBody, nil
+}
+
+// decodeBuffered returns a reader of the fetched body with its compression
+// undone. The reader is an io.Closer when it holds a decoder.
+func decodeBuffered(body []byte, contentEncoding, sourceURL string) (io.Reader, error) {
+	decoded, decoder, err := decodeBody(bytes.NewReader(body), body[:min(len(body), magicLen)], contentEncoding, sourceURL)
+	if err != nil {
+		return nil, err
+	}
+	if decoder == nil {
+		return decoded, nil
+	}
+	return &streamBody{Reader: decoded, Closer: decoder}, nil
 }
 
-// Parse processes the fetched data
-func (b *BaseProvider) Parse(data io.Reader) error {
+// Parse processes the fetched data. A panic in the parse function is
+// recovered and reported as ErrParserPanic for this provider only.
+func (b *BaseProvider) Parse(data io.Reader) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().
+				Str("provider", b.Name).
+				Interface("panic", r).
+				Bytes("stack", debug.Stack()).
+				Msg("Recovered from parse function panic")
+			err = errors.Join(ErrParsingData, ErrParserPanic)
+		}
+		if errors.Is(err, ErrParserPanic) {
+			if mc, mcErr := collector.GetMetricsCollector(); mcErr == nil {
+				mc.IncrementParserPanics(b.Name)
+			}
+		}
+	}()
+
 	if b.Repository == nil {
 		log.Error().Str("provider", b.Name).Msg("Repository not set")
 		return ErrRepositoryNotSet
@@ -191,9 +433,19 @@ func (b *BaseProvider) Parse(data io.Reader) error {
 		return ErrParsingData
 	}
 
-	err := b.ParseFunction(data, collector)
-	if err != nil {
+	var reservedDomains []string
+	var reservedWarnRatio float64
+	if cfg := config.GetConfig(); cfg != nil {
+		reservedDomains, reservedWarnRatio = cfg.Collector.ReservedDomains, cfg.Collector.ReservedWarnRatio
+	}
+	guard := NewReservedGuard(b.Name, collector, reservedDomains, reservedWarnRatio)
+	defer guard.Report()
+
+	if err := b.ParseFunction(data, guard); err != nil {
 		log.Err(err).Str("provider", b.Name).Msg("Error parsing data")
+		if errors.Is(err, ErrParserPanic) {
+			return errors.Join(ErrParsingData, ErrParserPanic)
+		}
 		return ErrParsingData
 	}
 
@@ -206,10 +458,10 @@ func BuildCollyClientForProvider(client *colly.Collector, opts *config.ProviderO
 		return nil
 	}
 	if opts == nil {
-		return client.Clone()
+		return cloneCollector(client)
 	}
 
-	c := client.Clone()
+	c := cloneCollector(client)
 
 	if opts.UserAgent != "" {
 		c.UserAgent = opts.UserAgent
@@ -237,6 +489,11 @@ func ResolveURL(sourceURL, apiKey string) string {
 	return strings.ReplaceAll(sourceURL, "{api_key}", apiKey)
 }
 
+// Fetcher downloads the body at a source URL.
+type Fetcher interface {
+	Fetch(u string) (io.ReadCloser, error)
+}
+
 // HTTPFetcher provides a colly-independent fetcher that uses net/http directly.
 // Useful when colly's overhead isn't needed (plain text feeds).
 type HTTPFetcher struct {
@@ -291,11 +548,12 @@ func (f *HTTPFetcher) Fetch(u string) (io.ReadCloser, error) {
 	return resp.Body, nil
 }
 
-// IsProviderURL checks if a string looks like a valid HTTP/HTTPS URL.
+// IsProviderURL checks if a string looks like a valid HTTP/HTTPS, s3:// or
+// gs:// URL.
 func IsProviderURL(s string) bool {
 	if s == "" || s == "{api_key}" {
 		return false
 	}
 	u, err := url.Parse(s)
-	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https" || u.Scheme == SchemeS3 || u.Scheme == SchemeGCS) && u.Host != ""
 }
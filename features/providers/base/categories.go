@@ -0,0 +1,36 @@
+package base
+
+import (
+	"slices"
+	"strings"
+)
+
+// CategoryMapper maps the tags a feed attaches to an entry to categories,
+// through a per-provider dictionary. Tags are matched case-insensitively.
+type CategoryMapper map[string]string
+
+// NewCategoryMapper builds a mapper from a tag → category dictionary.
+func NewCategoryMapper(dict map[string]string) CategoryMapper {
+	m := make(CategoryMapper, len(dict))
+	for tag, category := range dict {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		category = strings.TrimSpace(category)
+		if tag != "" && category != "" {
+			m[tag] = category
+		}
+	}
+	return m
+}
+
+// Categories returns primary followed by the categories of the mapped tags,
+// without duplicates. Unmapped tags are dropped.
+func (m CategoryMapper) Categories(primary string, tags ...string) []string {
+	categories := []string{primary}
+	for _, tag := range tags {
+		c, ok := m[strings.ToLower(strings.TrimSpace(tag))]
+		if ok && !slices.Contains(categories, c) {
+			categories = append(categories, c)
+		}
+	}
+	return categories
+}
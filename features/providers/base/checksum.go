@@ -0,0 +1,151 @@
+package base
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"blacked/internal/config"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrChecksumFetch    = errors.New("failed to fetch source checksum")
+	ErrChecksumInvalid  = errors.New("no SHA-256 sum for the source in checksum file")
+	ErrChecksumMismatch = errors.New("source checksum mismatch")
+)
+
+// Checksum verification outcomes, as recorded on provider runs.
+const (
+	ChecksumVerified    = "verified"
+	ChecksumMismatch    = "mismatch"
+	ChecksumUnavailable = "unavailable"
+)
+
+// ChecksumAttempt is the verification outcome of one URL tried by Fetch,
+// the source or one of its mirrors.
+type ChecksumAttempt struct {
+	URL    string `json:"url"`
+	Status string `json:"status"`
+}
+
+// SetChecksumURL makes Fetch verify the source against the SHA-256 sums
+// published at checksumURL, overriding the checksum_url option of its config.
+func (b *BaseProvider) SetChecksumURL(checksumURL string) *BaseProvider {
+	b.ChecksumSource = checksumURL
+	return b
+}
+
+// ChecksumURL returns the URL of the source's published SHA-256 sums: the
+// provider's own setting, else the checksum_url option of
+// [providers.<name>]. Empty disables verification.
+func (b *BaseProvider) ChecksumURL() string {
+	if b.ChecksumSource != "" {
+		return b.ChecksumSource
+	}
+	cfg := config.GetConfig()
+	if cfg == nil {
+		return ""
+	}
+	if opts := cfg.Providers[b.Name]; opts != nil {
+		return opts.ChecksumURL
+	}
+	return ""
+}
+
+// ChecksumStatus returns the outcome of the last Fetch's checksum
+// verification, or "" when the source has no checksum URL.
+func (b *BaseProvider) ChecksumStatus() string {
+	return b.checksumStatus
+}
+
+// ChecksumAttempts returns the verification outcome of every URL the last
+// Fetch downloaded, in the order tried; ChecksumStatus is the one of the
+// returned body, or of the last attempt when none was returned.
+func (b *BaseProvider) ChecksumAttempts() []ChecksumAttempt {
+	return b.checksumTries
+}
+
+// publishedChecksum downloads the checksum file and returns the sum of the
+// source.
+func (b *BaseProvider) publishedChecksum(checksumURL string) (string, error) {
+	body, _, err := b.download(checksumURL, nil)
+	if err != nil {
+		return "", errors.Join(ErrChecksumFetch, err)
+	}
+	sum, err := parseChecksum(body, b.SourceURL)
+	if err != nil {
+		log.Err(err).Str("provider", b.Name).Str("url", checksumURL).Msg("Invalid checksum file")
+		return "", err
+	}
+	return sum, nil
+}
+
+// fetchVerified downloads sourceURL and returns it decoded only when the
+// SHA-256 of the body as sent matches want.
+func (b *BaseProvider) fetchVerified(sourceURL, want string) (io.Reader, error) {
+	body, contentEncoding, err := b.download(sourceURL, b.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if got := sha256Hex(string(body)); got != want {
+		b.checksumStatus = ChecksumMismatch
+		b.checksumTries = append(b.checksumTries, ChecksumAttempt{URL: sourceURL, Status: ChecksumMismatch})
+		log.Error().
+			Str("provider", b.Name).
+			Str("url", sourceURL).
+			Str("expected", want).
+			Str("actual", got).
+			Msg("Source checksum mismatch")
+		return nil, ErrChecksumMismatch
+	}
+
+	b.checksumStatus = ChecksumVerified
+	b.checksumTries = append(b.checksumTries, ChecksumAttempt{URL: sourceURL, Status: ChecksumVerified})
+	return decodeBuffered(body, contentEncoding, sourceURL)
+}
+
+// parseChecksum returns the SHA-256 sum for sourceURL from a checksum file:
+// a bare sum, or sha256sum style "<sum>  <file>" lines, where the line naming
+// the source's file wins over the first one.
+func parseChecksum(data []byte, sourceURL string) (string, error) {
+	file := ""
+	if u, err := url.Parse(sourceURL); err == nil {
+		file = path.Base(u.Path)
+	}
+
+	var first string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		sum := strings.ToLower(fields[0])
+		if len(sum) != sha256.Size*2 {
+			continue
+		}
+		if _, err := hex.DecodeString(sum); err != nil {
+			continue
+		}
+		if len(fields) > 1 && path.Base(strings.TrimPrefix(fields[1], "*")) == file {
+			return sum, nil
+		}
+		if first == "" {
+			first = sum
+		}
+	}
+	if first == "" {
+		return "", fmt.Errorf("%w: %s", ErrChecksumInvalid, sourceURL)
+	}
+	return first, nil
+}
@@ -0,0 +1,98 @@
+package base
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChecksum(t *testing.T) {
+	feed, other := sha256Hex("feed"), sha256Hex("other")
+
+	for name, tc := range map[string]struct {
+		file string
+		want string
+	}{
+		"bare sum":       {file: feed + "\n", want: feed},
+		"sha256sum":      {file: other + "  other.txt\n" + feed + "  feed.txt\n", want: feed},
+		"binary marker":  {file: other + " *other.txt\n" + feed + " *dir/feed.txt\n", want: feed},
+		"no file match":  {file: "# sums\n" + other + "  other.txt\n", want: other},
+		"upper-case sum": {file: fmt.Sprintf("%X  feed.txt\n", sha256.Sum256([]byte("feed"))), want: feed},
+	} {
+		got, err := parseChecksum([]byte(tc.file), "https://feeds.example/lists/feed.txt")
+		if assert.NoError(t, err, name) {
+			assert.Equal(t, tc.want, got, name)
+		}
+	}
+
+	_, err := parseChecksum([]byte("<html>not found</html>\n"), "https://feeds.example/feed.txt")
+	assert.ErrorIs(t, err, ErrChecksumInvalid)
+}
+
+func TestFetchVerifiesChecksum(t *testing.T) {
+	const body = "bad.example\n"
+	sums := sha256Hex(body) + "  feed.txt\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.txt", "/mirror/feed.txt":
+			io.WriteString(w, body)
+		case "/tampered/feed.txt":
+			io.WriteString(w, "good.example\n")
+		case "/feed.txt.sha256":
+			io.WriteString(w, sums)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	newProvider := func(source string) *BaseProvider {
+		return NewBaseProvider("checksum-test", server.URL+source, "blocklist", colly.NewCollector(colly.AllowURLRevisit()), nil).
+			SetChecksumURL(server.URL + "/feed.txt.sha256").
+			SetStream(true)
+	}
+
+	p := newProvider("/feed.txt")
+	reader, err := p.Fetch()
+	require.NoError(t, err)
+	got, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(got))
+	assert.Equal(t, ChecksumVerified, p.ChecksumStatus())
+
+	p = newProvider("/tampered/feed.txt")
+	_, err = p.Fetch()
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.Equal(t, ChecksumMismatch, p.ChecksumStatus())
+
+	// A mirror serving the published file still verifies.
+	p.SetMirrors([]string{server.URL + "/mirror/feed.txt"})
+	_, err = p.Fetch()
+	require.NoError(t, err)
+	assert.Equal(t, ChecksumVerified, p.ChecksumStatus())
+	assert.Equal(t, []ChecksumAttempt{
+		{URL: server.URL + "/tampered/feed.txt", Status: ChecksumMismatch},
+		{URL: server.URL + "/mirror/feed.txt", Status: ChecksumVerified},
+	}, p.ChecksumAttempts())
+
+	// Failing mirrors are reported along with the source.
+	p.SetMirrors([]string{server.URL + "/tampered/feed.txt", server.URL + "/missing/feed.txt"})
+	_, err = p.Fetch()
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	joined, ok := err.(interface{ Unwrap() []error })
+	require.True(t, ok)
+	assert.Len(t, joined.Unwrap(), 3, "one error per URL tried")
+	assert.Len(t, p.ChecksumAttempts(), 2, "the missing mirror was not verified")
+
+	p = newProvider("/feed.txt").SetChecksumURL(server.URL + "/missing.sha256")
+	_, err = p.Fetch()
+	assert.ErrorIs(t, err, ErrChecksumFetch)
+	assert.Equal(t, ChecksumUnavailable, p.ChecksumStatus())
+}
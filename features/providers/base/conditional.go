@@ -0,0 +1,76 @@
+package base
+
+import (
+	"net/http"
+	"slices"
+
+	"blacked/internal/config"
+	"blacked/internal/utils"
+)
+
+// SetConditional makes fetches of the source conditional or not, overriding
+// the conditional_fetch option of its config. Sources read page by page
+// turn it off: an unchanged first page says nothing about the others.
+func (b *BaseProvider) SetConditional(conditional bool) *BaseProvider {
+	b.Conditional = &conditional
+	return b
+}
+
+// ConditionalFetch reports whether Fetch sends the validators set with
+// SetFetchValidators: the provider's own setting, else the
+// conditional_fetch option of [providers.<name>], else [Collector]
+// conditional_fetch. Sources fetched with a POST are never conditional.
+func (b *BaseProvider) ConditionalFetch() bool {
+	if b.Body != nil {
+		return false
+	}
+	if b.Conditional != nil {
+		return *b.Conditional
+	}
+	cfg := config.GetConfig()
+	if cfg == nil {
+		return false
+	}
+	return cfg.CollectorSettingsFor(b.Name).Conditional
+}
+
+// SetFetchValidators sets the validators of the last imported response,
+// sent with the next Fetch of the URL they came from.
+func (b *BaseProvider) SetFetchValidators(v utils.FetchValidators) {
+	b.validators = v
+}
+
+// FetchedValidators returns the validators the source sent with the
+// response of the last Fetch, empty when it sent none.
+func (b *BaseProvider) FetchedValidators() utils.FetchValidators {
+	return b.fetched
+}
+
+// conditionalHeaders returns the headers making a fetch of sourceURL
+// conditional, nil when it is not.
+func (b *BaseProvider) conditionalHeaders(sourceURL string) map[string]string {
+	if b.validators.Empty() || b.validators.URL != sourceURL || !b.ConditionalFetch() {
+		return nil
+	}
+	headers := make(map[string]string, 2)
+	if b.validators.ETag != "" {
+		headers["If-None-Match"] = b.validators.ETag
+	}
+	if b.validators.LastModified != "" {
+		headers["If-Modified-Since"] = b.validators.LastModified
+	}
+	return headers
+}
+
+// keepValidators records the validators of a response of sourceURL when it
+// is the source or one of its mirrors, not a checksum file or another page.
+func (b *BaseProvider) keepValidators(sourceURL string, header http.Header) {
+	if !b.ConditionalFetch() || (sourceURL != b.SourceURL && !slices.Contains(b.Mirrors, sourceURL)) {
+		return
+	}
+	b.fetched = utils.FetchValidators{
+		URL:          sourceURL,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+}
@@ -0,0 +1,94 @@
+package base
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blacked/internal/utils"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConditionalFetch checks that the validators of a fetched response are
+// sent back and that a 304 answer skips the body, buffered and streamed.
+func TestConditionalFetch(t *testing.T) {
+	const etag = `"v1"`
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Fri, 16 Oct 2026 12:00:00 GMT")
+		io.WriteString(w, "bad.example\n")
+	}))
+	defer server.Close()
+
+	for _, stream := range []bool{false, true} {
+		requests = 0
+		p := NewBaseProvider("conditional-test", server.URL+"/feed.txt", "blocklist", colly.NewCollector(colly.AllowURLRevisit()), nil).
+			SetStream(stream).
+			SetConditional(true)
+
+		reader, err := p.Fetch()
+		require.NoError(t, err)
+		if c, ok := reader.(io.Closer); ok {
+			c.Close()
+		}
+		fetched := p.FetchedValidators()
+		assert.Equal(t, utils.FetchValidators{URL: server.URL + "/feed.txt", ETag: etag, LastModified: "Fri, 16 Oct 2026 12:00:00 GMT"}, fetched)
+
+		p.SetFetchValidators(fetched)
+		reader, err = p.Fetch()
+		assert.ErrorIs(t, err, utils.ErrSourceNotModified, "stream=%v", stream)
+		assert.Nil(t, reader)
+		assert.True(t, p.FetchedValidators().Empty(), "a 304 imports nothing new")
+
+		p.SetConditional(false)
+		reader, err = p.Fetch()
+		require.NoError(t, err, "stream=%v", stream)
+		if c, ok := reader.(io.Closer); ok {
+			c.Close()
+		}
+		assert.True(t, p.FetchedValidators().Empty(), "validators are only kept by conditional providers")
+		assert.Equal(t, 3, requests)
+	}
+}
+
+// TestConditionalFetchFromMirror checks that a 304 answer of the mirror the
+// last response came from is reported like one of the source.
+func TestConditionalFetchFromMirror(t *testing.T) {
+	const etag = `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path != "/mirror/feed.txt":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		case r.Header.Get("If-None-Match") == etag:
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			w.Header().Set("ETag", etag)
+			io.WriteString(w, "bad.example\n")
+		}
+	}))
+	defer server.Close()
+
+	p := NewBaseProvider("conditional-mirror-test", server.URL+"/feed.txt", "blocklist", colly.NewCollector(colly.AllowURLRevisit()), nil).
+		SetConditional(true)
+	p.SetMirrors([]string{server.URL + "/mirror/feed.txt"})
+
+	_, err := p.Fetch()
+	require.NoError(t, err)
+	fetched := p.FetchedValidators()
+	assert.Equal(t, server.URL+"/mirror/feed.txt", fetched.URL)
+
+	p.SetFetchValidators(fetched)
+	reader, err := p.Fetch()
+	assert.ErrorIs(t, err, utils.ErrSourceNotModified)
+	assert.Nil(t, reader)
+}
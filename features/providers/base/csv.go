@@ -0,0 +1,166 @@
+package base
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entry_collector"
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+)
+
+// FormatCSV is the source format of delimited feeds read by column.
+const FormatCSV = "csv"
+
+// NoColumn marks an optional CSV column as absent.
+const NoColumn = -1
+
+var ErrInvalidCSVOptions = errors.New("invalid CSV options")
+
+// CSVOptions describes where a CSV feed keeps the fields of an entry.
+// Columns are 0-based.
+type CSVOptions struct {
+	URLColumn        int
+	CategoryColumn   int     // NoColumn uses the provider category for every row
+	ConfidenceColumn int     // NoColumn leaves confidence unset
+	ConfidenceScale  float64 // Column value meaning full confidence, e.g. 10 for a 0-10 score; 0 means 1
+	Delimiter        string  // Single character; "," when empty
+	SkipRows         int     // Leading header rows to skip
+
+	// Categories maps category column values; values it lacks are kept as-is.
+	Categories CategoryMapper
+}
+
+// CSVRecord holds the fields read from one CSV line.
+type CSVRecord struct {
+	URL           string
+	Category      string // Empty when the row has no category
+	Confidence    float64
+	HasConfidence bool
+}
+
+// Validate checks the columns and delimiter.
+func (o CSVOptions) Validate() error {
+	if o.URLColumn < 0 {
+		return fmt.Errorf("%w: URL column must not be negative", ErrInvalidCSVOptions)
+	}
+	if o.CategoryColumn < NoColumn || o.ConfidenceColumn < NoColumn {
+		return fmt.Errorf("%w: optional columns must be %d or a column index", ErrInvalidCSVOptions, NoColumn)
+	}
+	if o.ConfidenceScale < 0 {
+		return fmt.Errorf("%w: confidence scale must not be negative", ErrInvalidCSVOptions)
+	}
+	if _, err := o.comma(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o CSVOptions) comma() (rune, error) {
+	if o.Delimiter == "" {
+		return ',', nil
+	}
+	r, size := utf8.DecodeRuneInString(o.Delimiter)
+	if size != len(o.Delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, fmt.Errorf("%w: delimiter must be a single character", ErrInvalidCSVOptions)
+	}
+	return r, nil
+}
+
+// ParseLine reads one CSV line. ok is false for blank and comment (#)
+// lines, unreadable lines and rows without a URL. o must be valid.
+func (o CSVOptions) ParseLine(line string) (rec CSVRecord, ok bool) {
+	if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+		return CSVRecord{}, false
+	}
+	comma, err := o.comma()
+	if err != nil {
+		return CSVRecord{}, false
+	}
+
+	r := csv.NewReader(strings.NewReader(line))
+	r.Comma = comma
+	r.LazyQuotes = true
+	r.FieldsPerRecord = -1
+	record, err := r.Read()
+	if err != nil || o.URLColumn >= len(record) {
+		return CSVRecord{}, false
+	}
+
+	rec.URL = strings.TrimSpace(record[o.URLColumn])
+	if rec.URL == "" {
+		return CSVRecord{}, false
+	}
+
+	if o.CategoryColumn != NoColumn && o.CategoryColumn < len(record) {
+		rec.Category = strings.TrimSpace(record[o.CategoryColumn])
+		if mapped, ok := o.Categories[strings.ToLower(rec.Category)]; ok {
+			rec.Category = mapped
+		}
+	}
+
+	if o.ConfidenceColumn != NoColumn && o.ConfidenceColumn < len(record) {
+		v, err := strconv.ParseFloat(strings.TrimSpace(record[o.ConfidenceColumn]), 64)
+		if err == nil && v >= 0 {
+			scale := o.ConfidenceScale
+			if scale == 0 {
+				scale = 1
+			}
+			rec.Confidence = min(v/scale, 1)
+			rec.HasConfidence = true
+		}
+	}
+	return rec, true
+}
+
+// NewCSVParseFunc returns a parse function reading entries of source from
+// a CSV feed laid out as opts describes. Rows without a category column
+// value get category.
+func NewCSVParseFunc(source, category string, opts CSVOptions, workers, batchSize int) (func(io.Reader, entry_collector.Collector) error, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	return func(data io.Reader, collector entry_collector.Collector) error {
+		if opts.SkipRows > 0 {
+			br := bufio.NewReader(data)
+			for range opts.SkipRows {
+				if _, err := br.ReadString('\n'); err != nil {
+					break
+				}
+			}
+			data = br
+		}
+
+		return ParseLinesParallel(data, collector, source, workers, batchSize, func(line, processID string) (*entries.Entry, error) {
+			rec, ok := opts.ParseLine(line)
+			if !ok {
+				return nil, nil
+			}
+			if rec.Category == "" {
+				rec.Category = category
+			}
+
+			entry := entries.NewEntry().
+				WithSource(source).
+				WithProcessID(processID).
+				WithCategory(rec.Category)
+			if rec.HasConfidence {
+				entry.WithConfidence(rec.Confidence)
+			}
+
+			if err := entry.SetURL(rec.URL); err != nil {
+				log.Error().Err(err).Msgf("error setting URL: %s", rec.URL)
+				return nil, nil
+			}
+
+			return entry, nil
+		})
+	}, nil
+}
@@ -0,0 +1,84 @@
+package base
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVOptionsParseLine(t *testing.T) {
+	opts := CSVOptions{
+		URLColumn:        2,
+		CategoryColumn:   3,
+		ConfidenceColumn: 1,
+		ConfidenceScale:  10,
+		Delimiter:        ";",
+		Categories:       NewCategoryMapper(map[string]string{"Phish": "phishing"}),
+	}
+	require.NoError(t, opts.Validate())
+
+	for line, want := range map[string]CSVRecord{
+		`2026-10-16;8.5;"https://bad.example/a;b";phish`: {URL: "https://bad.example/a;b", Category: "phishing", Confidence: 0.85, HasConfidence: true},
+		`2026-10-16;15;bad.example;malware`:              {URL: "bad.example", Category: "malware", Confidence: 1, HasConfidence: true},
+		`2026-10-16;n/a;bad.example;`:                    {URL: "bad.example"},
+		`2026-10-16;-1;bad.example`:                      {URL: "bad.example"},
+	} {
+		got, ok := opts.ParseLine(line)
+		assert.True(t, ok, line)
+		assert.InDelta(t, want.Confidence, got.Confidence, 1e-9, line)
+		got.Confidence, want.Confidence = 0, 0
+		assert.Equal(t, want, got, line)
+	}
+
+	for _, line := range []string{"", "# date;score;url", "2026-10-16;5", "2026-10-16;5; ;x"} {
+		_, ok := opts.ParseLine(line)
+		assert.False(t, ok, line)
+	}
+
+	for _, bad := range []CSVOptions{
+		{URLColumn: -1, CategoryColumn: NoColumn, ConfidenceColumn: NoColumn},
+		{CategoryColumn: -2, ConfidenceColumn: NoColumn},
+		{CategoryColumn: NoColumn, ConfidenceColumn: NoColumn, ConfidenceScale: -1},
+		{CategoryColumn: NoColumn, ConfidenceColumn: NoColumn, Delimiter: "::"},
+		{CategoryColumn: NoColumn, ConfidenceColumn: NoColumn, Delimiter: `"`},
+	} {
+		assert.ErrorIs(t, bad.Validate(), ErrInvalidCSVOptions, bad)
+	}
+}
+
+func TestCSVParseFunc(t *testing.T) {
+	// PhishStats layout: "date","score","url","ip"
+	feed := strings.Join([]string{
+		`######################################`,
+		`# PhishStats feed`,
+		`"date","score","url","ip"`,
+		`"2026-10-16 10:00:00","7.20","https://login.bad.example/","203.0.113.7"`,
+		`"2026-10-16 10:05:00","2.00","http://phish.example/x","203.0.113.8"`,
+	}, "\n")
+
+	parse, err := NewCSVParseFunc("phishstats", "phishing", CSVOptions{
+		URLColumn:        2,
+		CategoryColumn:   NoColumn,
+		ConfidenceColumn: 1,
+		ConfidenceScale:  10,
+		SkipRows:         3,
+	}, 2, 2)
+	require.NoError(t, err)
+
+	collector := &MockCollector{}
+	require.NoError(t, parse(strings.NewReader(feed), collector))
+
+	got := map[string]float64{}
+	for _, e := range collector.GetEntries() {
+		assert.Equal(t, "phishstats", e.Source)
+		assert.Equal(t, "phishing", e.Category)
+		got[e.Host] = e.Confidence
+	}
+	assert.Equal(t, []string{"login.bad.example", "phish.example"}, slices.Sorted(maps.Keys(got)), "comment and header rows are skipped")
+	assert.InDelta(t, 0.72, got["login.bad.example"], 1e-9)
+	assert.InDelta(t, 0.2, got["phish.example"], 1e-9)
+}
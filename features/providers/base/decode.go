@@ -0,0 +1,220 @@
+package base
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/rs/zerolog/log"
+)
+
+// Encodings decodeBody undoes.
+const (
+	EncodingIdentity = ""
+	EncodingGzip     = "gzip"
+	EncodingZstd     = "zstd"
+	EncodingZip      = "zip" // An archive holding the feed as its only file
+)
+
+var ErrDecodingSource = errors.New("error decoding compressed source data")
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	zipMagic  = []byte{'P', 'K', 0x03, 0x04}
+)
+
+// magicLen is the number of leading bytes DetectEncoding needs.
+const magicLen = 4
+
+// DetectEncoding names the compression of a body from its first bytes. The
+// Content-Encoding it was served with and the extension of its URL are only
+// advisory: HTTP clients gunzip on the way, so a declared encoding without
+// its magic bytes means the body is already plain.
+func DetectEncoding(head []byte, contentEncoding, sourceURL string) string {
+	var sniffed string
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		sniffed = EncodingGzip
+	case bytes.HasPrefix(head, zstdMagic):
+		sniffed = EncodingZstd
+	case bytes.HasPrefix(head, zipMagic):
+		sniffed = EncodingZip
+	}
+
+	if declared := declaredEncoding(contentEncoding, sourceURL); declared != sniffed && declared != EncodingIdentity {
+		log.Debug().
+			Str("url", sourceURL).
+			Str("declared", declared).
+			Str("sniffed", sniffed).
+			Msg("Declared encoding does not match the body, trusting the body")
+	}
+	return sniffed
+}
+
+// declaredEncoding reads the encoding from the Content-Encoding header, else
+// from the extension of the URL path.
+func declaredEncoding(contentEncoding, sourceURL string) string {
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "gzip", "x-gzip":
+		return EncodingGzip
+	case "zstd":
+		return EncodingZstd
+	}
+
+	p := sourceURL
+	if u, err := url.Parse(sourceURL); err == nil {
+		p = u.Path
+	}
+	switch strings.ToLower(path.Ext(p)) {
+	case ".gz", ".tgz":
+		return EncodingGzip
+	case ".zst", ".zstd":
+		return EncodingZstd
+	case ".zip":
+		return EncodingZip
+	}
+	return EncodingIdentity
+}
+
+// decodeBody undoes the compression of body, whose first bytes are head. It
+// returns body itself when it is plain. The closer releases the decoder and
+// is nil when there is nothing to release; it doesn't close body.
+//
+// Gzip and zstd are decoded as the reader is read. A zip archive needs
+// random access: a *bytes.Reader body is read in place, anything else is
+// spooled to a temporary file first.
+func decodeBody(body io.Reader, head []byte, contentEncoding, sourceURL string) (io.Reader, io.Closer, error) {
+	encoding := DetectEncoding(head, contentEncoding, sourceURL)
+	switch encoding {
+	case EncodingGzip:
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			log.Err(err).Str("url", sourceURL).Msg("Failed to open gzip body")
+			return nil, nil, ErrDecodingSource
+		}
+		logDecoding(sourceURL, encoding)
+		return gz, gz, nil
+	case EncodingZstd:
+		zr, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			log.Err(err).Str("url", sourceURL).Msg("Failed to open zstd body")
+			return nil, nil, ErrDecodingSource
+		}
+		logDecoding(sourceURL, encoding)
+		rc := zr.IOReadCloser()
+		return rc, rc, nil
+	case EncodingZip:
+		return decodeZip(body, sourceURL)
+	default:
+		return body, nil, nil
+	}
+}
+
+func logDecoding(sourceURL, encoding string) {
+	log.Info().Str("url", sourceURL).Str("encoding", encoding).Msg("Decoding compressed source")
+}
+
+// decodeZip opens the only file of the zip archive body.
+func decodeZip(body io.Reader, sourceURL string) (io.Reader, io.Closer, error) {
+	var archive io.ReaderAt
+	var size int64
+	var spool *os.File
+
+	if br, ok := body.(*bytes.Reader); ok {
+		archive, size = br, br.Size()
+	} else {
+		f, err := os.CreateTemp("", "blacked-zip-*")
+		if err != nil {
+			log.Err(err).Str("url", sourceURL).Msg("Failed to create zip spool file")
+			return nil, nil, ErrDecodingSource
+		}
+		spool = f
+		if size, err = io.Copy(f, body); err != nil {
+			removeSpool(spool)
+			log.Err(err).Str("url", sourceURL).Msg("Failed to spool zip body")
+			return nil, nil, ErrFetchingSource
+		}
+		archive = f
+	}
+
+	file, err := singleZipFile(archive, size)
+	if err != nil {
+		removeSpool(spool)
+		log.Err(err).Str("url", sourceURL).Msg("Failed to open zip body")
+		return nil, nil, ErrDecodingSource
+	}
+	rc, err := file.Open()
+	if err != nil {
+		removeSpool(spool)
+		log.Err(err).Str("url", sourceURL).Str("file", file.Name).Msg("Failed to open zipped file")
+		return nil, nil, ErrDecodingSource
+	}
+
+	log.Info().Str("url", sourceURL).Str("encoding", EncodingZip).Str("file", file.Name).Msg("Decoding compressed source")
+	return rc, zipBody{file: rc, spool: spool}, nil
+}
+
+// singleZipFile returns the only regular file of an archive, ignoring
+// directories and the __MACOSX metadata macOS adds.
+func singleZipFile(archive io.ReaderAt, size int64) (*zip.File, error) {
+	zr, err := zip.NewReader(archive, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *zip.File
+	var names []string
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") {
+			continue
+		}
+		found = f
+		names = append(names, f.Name)
+	}
+	if len(names) != 1 {
+		return nil, fmt.Errorf("zip archive holds %d files %v, want exactly one", len(names), names)
+	}
+	return found, nil
+}
+
+// zipBody closes the zipped file and removes the spool file, if any.
+type zipBody struct {
+	file  io.Closer
+	spool *os.File
+}
+
+func (z zipBody) Close() error {
+	err := z.file.Close()
+	removeSpool(z.spool)
+	return err
+}
+
+func removeSpool(f *os.File) {
+	if f == nil {
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// multiCloser closes its closers in order, returning the first error.
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() error {
+	var first error
+	for _, c := range m {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
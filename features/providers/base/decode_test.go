@@ -0,0 +1,113 @@
+package base
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const decodeFeed = "bad.example\nworse.example\n"
+
+func gzipped(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := io.WriteString(w, data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func zstded(t *testing.T, data string) []byte {
+	w, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	defer w.Close()
+	return w.EncodeAll([]byte(data), nil)
+}
+
+func zipped(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, data := range files {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = io.WriteString(f, data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func readDecoded(t *testing.T, r io.Reader) string {
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	if c, ok := r.(io.Closer); ok {
+		require.NoError(t, c.Close())
+	}
+	return string(data)
+}
+
+func TestDecodeBuffered(t *testing.T) {
+	for name, body := range map[string][]byte{
+		"plain": []byte(decodeFeed),
+		"gzip":  gzipped(t, decodeFeed),
+		"zstd":  zstded(t, decodeFeed),
+		"zip":   zipped(t, map[string]string{"feed/": "", "feed/domains.txt": decodeFeed, "__MACOSX/feed/._domains.txt": "meta"}),
+	} {
+		r, err := decodeBuffered(body, "", "https://acme.example/feed")
+		require.NoError(t, err, name)
+		assert.Equal(t, decodeFeed, readDecoded(t, r), name)
+	}
+
+	r, err := decodeBuffered([]byte(decodeFeed), "gzip", "https://acme.example/feed.txt.gz")
+	require.NoError(t, err)
+	assert.Equal(t, decodeFeed, readDecoded(t, r), "a body the client already decoded passes through")
+
+	_, err = decodeBuffered(zipped(t, map[string]string{"a.txt": "a", "b.txt": "b"}), "", "https://acme.example/feed.zip")
+	assert.ErrorIs(t, err, ErrDecodingSource, "zip archives hold exactly one file")
+	_, err = decodeBuffered(append([]byte{0x1f, 0x8b}, "not gzip"...), "", "https://acme.example/feed")
+	assert.ErrorIs(t, err, ErrDecodingSource)
+}
+
+func TestDetectEncoding(t *testing.T) {
+	assert.Equal(t, EncodingGzip, DetectEncoding(gzipped(t, "x")[:magicLen], "", ""))
+	assert.Equal(t, EncodingZstd, DetectEncoding(zstded(t, "x")[:magicLen], "", ""))
+	assert.Equal(t, EncodingZip, DetectEncoding([]byte("PK\x03\x04"), "", ""))
+	assert.Equal(t, EncodingIdentity, DetectEncoding([]byte("PK"), "", "https://acme.example/feed.zip"))
+
+	assert.Equal(t, EncodingGzip, declaredEncoding("x-gzip", ""))
+	assert.Equal(t, EncodingZstd, declaredEncoding("", "https://acme.example/feed.csv.zst?key=1"))
+	assert.Equal(t, EncodingZip, declaredEncoding("", "s3://bucket/feeds/feed.ZIP"))
+	assert.Equal(t, EncodingIdentity, declaredEncoding("", "https://acme.example/feed.txt"))
+}
+
+func TestFetchStreamDecodes(t *testing.T) {
+	bodies := map[string][]byte{
+		"/feed.txt.gz":  gzipped(t, decodeFeed),
+		"/feed.txt.zst": zstded(t, decodeFeed),
+		"/feed.zip":     zipped(t, map[string]string{"domains.txt": decodeFeed}),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/feed.txt.zst" {
+			w.Header().Set("Content-Encoding", "zstd")
+		}
+		w.Write(bodies[r.URL.Path])
+	}))
+	defer server.Close()
+
+	for path := range bodies {
+		p := NewBaseProvider("decode-test", server.URL+path, "blocklist", nil, nil).SetStream(true)
+		reader, err := p.Fetch()
+		require.NoError(t, err, path)
+		_, ok := reader.(io.Closer)
+		require.True(t, ok, path)
+		assert.Equal(t, decodeFeed, readDecoded(t, reader), path)
+	}
+}
@@ -0,0 +1,111 @@
+package base
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entry_collector"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// FormatHosts is the source format of /etc/hosts style lists.
+const FormatHosts = "hosts"
+
+// maxHostnameLength is the longest hostname DNS allows, without the root dot.
+const maxHostnameLength = 253
+
+// hostsIgnored are the local names every hosts file maps.
+var hostsIgnored = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"local":                 true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+	"ip6-localnet":          true,
+	"ip6-mcastprefix":       true,
+	"ip6-allnodes":          true,
+	"ip6-allrouters":        true,
+	"ip6-allhosts":          true,
+}
+
+// ParseHostsLine reads the hostnames of one /etc/hosts line, e.g.
+// "0.0.0.0 ads.example tracker.example # comment". The IP column is
+// dropped, hostnames are lowercased, and local names, IP addresses and
+// invalid hostnames are skipped. Comments and blank lines return nil.
+func ParseHostsLine(line string) []string {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 || net.ParseIP(fields[0]) == nil {
+		return nil
+	}
+
+	var hosts []string
+	for _, field := range fields[1:] {
+		host := strings.TrimSuffix(strings.ToLower(field), ".")
+		if hostsIgnored[host] || !ValidHostname(host) {
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// ValidHostname reports whether host is a dotted DNS name: labels of 1-63
+// letters, digits, hyphens or underscores that don't start or end with a
+// hyphen, and no IP address.
+func ValidHostname(host string) bool {
+	if len(host) == 0 || len(host) > maxHostnameLength || net.ParseIP(host) != nil {
+		return false
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// NewHostsParseFunc returns a parse function for /etc/hosts style lists such
+// as StevenBlack/hosts. Every valid hostname of a line becomes an entry of
+// source and category.
+func NewHostsParseFunc(source, category string, workers, batchSize int) func(io.Reader, entry_collector.Collector) error {
+	return func(data io.Reader, collector entry_collector.Collector) error {
+		return ParseLinesParallel(data, collector, source, workers, batchSize, func(line, processID string) (*entries.Entry, error) {
+			var first *entries.Entry
+			for _, host := range ParseHostsLine(line) {
+				entry := entries.NewEntry().
+					WithSource(source).
+					WithProcessID(processID).
+					WithCategory(category)
+
+				if err := entry.SetURL(host); err != nil {
+					log.Error().Err(err).Msgf("error setting URL: %s", host)
+					continue
+				}
+				if first == nil {
+					first = entry
+				} else {
+					// Aliases on the same line are submitted directly.
+					collector.Submit(entry)
+				}
+			}
+			return first, nil
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package base
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHostsLine(t *testing.T) {
+	for line, want := range map[string][]string{
+		"0.0.0.0 ads.example":                             {"ads.example"},
+		"127.0.0.1\tTracker.Example. # telemetry":         {"tracker.example"},
+		"0.0.0.0 a.example b.example":                     {"a.example", "b.example"},
+		"0.0.0.0 _dmarc.mail.example":                     {"_dmarc.mail.example"},
+		"::1 ip6-localhost ip6-loopback":                  nil,
+		"127.0.0.1 localhost localhost.localdomain":       nil,
+		"255.255.255.255 broadcasthost":                   nil,
+		"0.0.0.0 0.0.0.0":                                 nil,
+		"0.0.0.0 bad..example -lead.example x_.example-":  nil,
+		"0.0.0.0 " + strings.Repeat("a", 64) + ".example": nil,
+		"# 0.0.0.0 commented.example":                     nil,
+		"ads.example":                                     nil,
+		"not-an-ip ads.example":                           nil,
+		"":                                                nil,
+	} {
+		assert.Equal(t, want, ParseHostsLine(line), line)
+	}
+
+	assert.True(t, ValidHostname("xn--bcher-kva.example"))
+	assert.False(t, ValidHostname("localhost"))
+	assert.False(t, ValidHostname("192.168.1.1"))
+	assert.False(t, ValidHostname("bad host.example"))
+}
+
+func TestHostsParseFunc(t *testing.T) {
+	list := strings.Join([]string{
+		"# Title: StevenBlack/hosts",
+		"127.0.0.1 localhost",
+		"0.0.0.0 0.0.0.0",
+		"0.0.0.0 ads.example",
+		"0.0.0.0 tracker.example metrics.tracker.example",
+		"0.0.0.0 bad_label-.example",
+	}, "\n")
+
+	collector := &MockCollector{}
+	parse := NewHostsParseFunc("stevenblack-hosts", "ads", 2, 2)
+	require.NoError(t, parse(strings.NewReader(list), collector))
+
+	var hosts []string
+	for _, e := range collector.GetEntries() {
+		assert.Equal(t, "stevenblack-hosts", e.Source)
+		assert.Equal(t, "ads", e.Category)
+		hosts = append(hosts, e.Host)
+	}
+	slices.Sort(hosts)
+	assert.Equal(t, []string{"ads.example", "metrics.tracker.example", "tracker.example"}, hosts)
+}
@@ -5,12 +5,16 @@ import (
 	"blacked/features/entry_collector"
 	"blacked/internal/tracing"
 	"bufio"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
 	"runtime/trace"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -101,6 +105,23 @@ func maybeStartExecTrace(providerName, processID string) (stop func()) {
 	}
 }
 
+// safeProcess runs processor for one item and converts a panic into an
+// ErrParserPanic error, so one malformed record can't crash the whole run.
+func safeProcess[T any](processor func(T, string) (*entries.Entry, error), item T, processID, providerName string) (entry *entries.Entry, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().
+				Str("provider", providerName).
+				Str("process_id", processID).
+				Interface("panic", r).
+				Bytes("stack", debug.Stack()).
+				Msg("Recovered from parser panic")
+			entry, err = nil, fmt.Errorf("%w: %v", ErrParserPanic, r)
+		}
+	}()
+	return processor(item, processID)
+}
+
 // LineProcessor is a function that processes a single line and returns an entry
 // Returns nil entry to skip the line (e.g., for comments or invalid data)
 type LineProcessor func(line string, processID string) (*entries.Entry, error)
@@ -144,6 +165,10 @@ func ParseLinesParallel(
 	// WaitGroup for workers
 	var wg sync.WaitGroup
 
+	// Set when any line panics so the run is reported as failed even if an
+	// ordinary error already occupied errChan.
+	var panicked atomic.Bool
+
 	// Start worker goroutines
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
@@ -152,7 +177,10 @@ func ParseLinesParallel(
 
 			for batch := range lineBatches {
 				for _, line := range batch {
-					entry, err := processor(line, processID)
+					entry, err := safeProcess(processor, line, processID, providerName)
+					if errors.Is(err, ErrParserPanic) {
+						panicked.Store(true)
+					}
 					if err != nil {
 						// Send error but continue processing
 						select {
@@ -219,6 +247,11 @@ func ParseLinesParallel(
 	wg.Wait()
 	close(errChan)
 
+	if panicked.Load() {
+		log.Error().Str("provider", providerName).Msg("Parallel parsing recovered from a parser panic")
+		return ErrParserPanic
+	}
+
 	// Check for errors
 	if err := <-errChan; err != nil {
 		log.Err(err).Str("provider", providerName).Msg("Error during parallel parsing")
@@ -268,6 +301,8 @@ func ProcessEntriesParallel[T any](
 	// WaitGroup for workers
 	var wg sync.WaitGroup
 
+	var panicked atomic.Bool
+
 	// Start worker goroutines
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
@@ -275,7 +310,10 @@ func ProcessEntriesParallel[T any](
 			defer wg.Done()
 
 			for item := range itemChan {
-				entry, err := processor(item, processID)
+				entry, err := safeProcess(processor, item, processID, "")
+				if errors.Is(err, ErrParserPanic) {
+					panicked.Store(true)
+				}
 				if err != nil {
 					// Send error but continue processing
 					select {
@@ -307,6 +345,11 @@ func ProcessEntriesParallel[T any](
 	wg.Wait()
 	close(errChan)
 
+	if panicked.Load() {
+		log.Error().Msg("Parallel entry processing recovered from a parser panic")
+		return ErrParserPanic
+	}
+
 	// Check for errors
 	if err := <-errChan; err != nil {
 		log.Err(err).Msg("Error during parallel entry processing")
@@ -2,7 +2,9 @@ package base
 
 import (
 	"blacked/features/entries"
+	"blacked/features/entry_collector"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"strings"
@@ -39,13 +41,16 @@ func (m *MockCollector) Count() int {
 	return int(atomic.LoadInt32(&m.count))
 }
 
-func (m *MockCollector) Wait()                                          {}
-func (m *MockCollector) Close()                                         {}
-func (m *MockCollector) StartProviderProcessing(name, processID string) {}
+func (m *MockCollector) Wait()                                                               {}
+func (m *MockCollector) Close()                                                              {}
+func (m *MockCollector) StartProviderProcessing(ctx context.Context, name, processID string) {}
 func (m *MockCollector) FinishProviderProcessing(name, processID string) (int, time.Duration, bool) {
 	return 0, 0, true
 }
 func (m *MockCollector) GetProcessedCount(source string) int { return 0 }
+func (m *MockCollector) CommitDelta(ctx context.Context, name, processID string) (*entry_collector.DeltaReport, error) {
+	return nil, nil
+}
 
 // TestParseLinesParallel_BasicFunctionality tests that parallel parsing works correctly
 func TestParseLinesParallel_BasicFunctionality(t *testing.T) {
@@ -295,6 +300,47 @@ func TestProcessEntriesParallel_WithFiltering(t *testing.T) {
 	assert.Equal(t, 2, collector.Count(), "Should only process 2 valid items")
 }
 
+// TestParseLinesParallel_RecoversPanic tests that a panicking line fails the run without stopping other lines
+func TestParseLinesParallel_RecoversPanic(t *testing.T) {
+	collector := &MockCollector{}
+
+	data := strings.NewReader("a.com\nboom\nb.com\nc.com\n")
+
+	processor := func(line string, processID string) (*entries.Entry, error) {
+		if line == "boom" {
+			panic("malformed record")
+		}
+		entry := entries.NewEntry()
+		entry.Domain = line
+		return entry, nil
+	}
+
+	err := ParseLinesParallel(data, collector, "TEST", 2, 1, processor)
+	require.ErrorIs(t, err, ErrParserPanic)
+
+	assert.Equal(t, 3, collector.Count(), "Lines after the panic should still be processed")
+}
+
+// TestProcessEntriesParallel_RecoversPanic tests panic recovery in entry workers
+func TestProcessEntriesParallel_RecoversPanic(t *testing.T) {
+	collector := &MockCollector{}
+
+	items := []int{1, 2, 3, 4}
+
+	processor := func(item int, processID string) (*entries.Entry, error) {
+		if item == 2 {
+			var m map[string]int
+			m["x"] = item // nil map write panics
+		}
+		return entries.NewEntry(), nil
+	}
+
+	err := ProcessEntriesParallel(items, collector, 2, processor, "test-id")
+	require.ErrorIs(t, err, ErrParserPanic)
+
+	assert.Equal(t, 3, collector.Count())
+}
+
 // BenchmarkParseLinesSequential benchmarks sequential line parsing
 func BenchmarkParseLinesSequential(b *testing.B) {
 	// Generate test data
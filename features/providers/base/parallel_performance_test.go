@@ -2,7 +2,9 @@ package base
 
 import (
 	"blacked/features/entries"
+	"blacked/features/entry_collector"
 	"bytes"
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -37,7 +39,7 @@ func (p *PerformanceCollector) EntriesPerSecond() float64 {
 func (p *PerformanceCollector) Wait()  {}
 func (p *PerformanceCollector) Close() {}
 
-func (p *PerformanceCollector) StartProviderProcessing(name, processID string) {
+func (p *PerformanceCollector) StartProviderProcessing(ctx context.Context, name, processID string) {
 	p.startTime = time.Now()
 }
 
@@ -45,6 +47,9 @@ func (p *PerformanceCollector) FinishProviderProcessing(name, processID string)
 	return 0, time.Since(p.startTime), true
 }
 func (p *PerformanceCollector) GetProcessedCount(source string) int { return 0 }
+func (p *PerformanceCollector) CommitDelta(ctx context.Context, name, processID string) (*entry_collector.DeltaReport, error) {
+	return nil, nil
+}
 
 // generateTestData creates realistic test data with comments and domains
 func generateTestData(numLines int) string {
@@ -125,7 +130,7 @@ func testPerformanceComparison(t *testing.T, data string, label string, workerCo
 		reader := strings.NewReader(data)
 
 		start := time.Now()
-		collector.StartProviderProcessing("TEST", "test-id")
+		collector.StartProviderProcessing(context.Background(), "TEST", "test-id")
 
 		err := ParseLinesParallel(reader, collector, "TEST", workers, 1000, processor)
 		if err != nil {
@@ -0,0 +1,136 @@
+package base
+
+import (
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"blacked/features/entries"
+	"blacked/features/entry_collector"
+	"blacked/internal/collector"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ReservedDomains are names never ingested, each matching itself and all of
+// its subdomains: the RFC 2606 test and example names, localhost, and the
+// special-use and de facto private suffixes of local networks.
+var ReservedDomains = []string{
+	"localhost", "localdomain", "invalid", "test", "example",
+	"example.com", "example.net", "example.org",
+	"local",     // mDNS, RFC 6762
+	"home.arpa", // RFC 8375
+	"internal", "lan", "intranet", "corp", "home", "private",
+}
+
+// reservedPrefixes are address ranges never ingested besides the loopback,
+// private, link-local, multicast and unspecified ones: shared address space
+// (RFC 6598) and the documentation ranges (RFC 5737, RFC 3849).
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// minReservedWarn is the number of reserved entries a run must drop before
+// its share is checked against [Collector] reserved_warn_ratio, so a feed
+// listing localhost once does not warn.
+const minReservedWarn = 10
+
+// ReservedGuard drops entries for reserved names and non-public addresses
+// before they reach the collector, and counts them. BaseProvider.Parse
+// wraps the collector of every run in one, extended by [Collector]
+// reserved_domains.
+type ReservedGuard struct {
+	entry_collector.Collector
+	provider  string
+	domains   []string
+	warnRatio float64
+	submitted atomic.Int64
+	dropped   atomic.Int64
+}
+
+// NewReservedGuard returns a guard for a run of provider submitting to
+// next. extra extends the built-in ReservedDomains; the run warns when it
+// drops more than warnRatio of its entries (0 never warns).
+func NewReservedGuard(provider string, next entry_collector.Collector, extra []string, warnRatio float64) *ReservedGuard {
+	g := &ReservedGuard{Collector: next, provider: provider, warnRatio: warnRatio}
+	g.domains = append(g.domains, ReservedDomains...)
+	for _, d := range extra {
+		if d = strings.Trim(strings.ToLower(strings.TrimSpace(d)), "."); d != "" {
+			g.domains = append(g.domains, d)
+		}
+	}
+	return g
+}
+
+// Submit passes entry on unless its host is reserved.
+func (g *ReservedGuard) Submit(entry *entries.Entry) {
+	g.submitted.Add(1)
+	if g.Reserved(entry.Host) {
+		g.dropped.Add(1)
+		log.Debug().Str("provider", g.provider).Str("host", entry.Host).Msg("Skipping reserved host")
+		return
+	}
+	g.Collector.Submit(entry)
+}
+
+// Reserved reports whether host is a reserved name or a loopback, private,
+// link-local, shared, documentation, multicast or unspecified address.
+func (g *ReservedGuard) Reserved(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+	if host == "" {
+		return false
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+			addr.IsMulticast() || addr.IsUnspecified() {
+			return true
+		}
+		for _, p := range reservedPrefixes {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, d := range g.domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dropped returns the number of entries dropped so far.
+func (g *ReservedGuard) Dropped() int {
+	return int(g.dropped.Load())
+}
+
+// Report logs and records the number of entries dropped by the run,
+// warning when they are an unusual share of the feed.
+func (g *ReservedGuard) Report() {
+	dropped, submitted := g.dropped.Load(), g.submitted.Load()
+	if dropped == 0 {
+		return
+	}
+
+	event := log.Info()
+	if g.warnRatio > 0 && dropped >= minReservedWarn && float64(dropped) > g.warnRatio*float64(submitted) {
+		event = log.Warn()
+	}
+	event.
+		Str("provider", g.provider).
+		Int64("skipped", dropped).
+		Int64("entries", submitted).
+		Msg("Skipped entries for reserved or private hosts")
+
+	if mc, err := collector.GetMetricsCollector(); err == nil {
+		mc.IncrementEntriesReserved(g.provider, int(dropped))
+	}
+}
@@ -0,0 +1,40 @@
+package base
+
+import (
+	"testing"
+
+	"blacked/features/entries"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReservedGuard(t *testing.T) {
+	mock := &MockCollector{}
+	g := NewReservedGuard("reserved-test", mock, []string{"Corp-Net.Example.Co."}, 0.01)
+
+	reserved := []string{
+		"localhost", "printer.local", "nas.home.arpa", "example.com", "www.example.org",
+		"evil.test", "db.internal", "git.corp-net.example.co",
+		"127.0.0.1", "10.1.2.3", "192.168.0.1", "172.16.5.5", "169.254.1.1",
+		"100.64.0.1", "203.0.113.7", "0.0.0.0", "[::1]", "[fe80::1]", "[2001:db8::1]",
+	}
+	for _, host := range reserved {
+		assert.True(t, g.Reserved(host), host)
+	}
+
+	public := []string{"evil.com", "example.com.evil.net", "localhost-login.net", "8.8.8.8", "[2606:4700::1111]", "corp-net.co"}
+	for _, host := range public {
+		assert.False(t, g.Reserved(host), host)
+	}
+
+	for _, link := range []string{"http://localhost:8080/admin", "http://10.0.0.1/x", "https://evil.com/login"} {
+		e, err := entries.FromURL(link, "reserved-test", "p1")
+		require.NoError(t, err)
+		g.Submit(e)
+	}
+	require.Equal(t, 1, mock.Count())
+	assert.Equal(t, "evil.com", mock.GetEntries()[0].Host)
+	assert.Equal(t, 2, g.Dropped())
+	g.Report()
+}
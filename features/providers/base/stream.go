@@ -0,0 +1,169 @@
+package base
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"blacked/internal/config"
+	"blacked/internal/utils"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultStreamTimeout bounds a streamed fetch, body included, when the
+	// provider sets no timeout; the body is read as fast as it is parsed.
+	defaultStreamTimeout = 30 * time.Minute
+
+	// maxStreamRedirects matches colly's default redirect limit.
+	maxStreamRedirects = 10
+
+	streamBufferSize = 256 * 1024
+)
+
+var ErrDomainNotAllowed = errors.New("redirect to a domain not allowed for the provider")
+
+// SetStream makes the provider stream its source instead of buffering it,
+// overriding the stream option of its config.
+func (b *BaseProvider) SetStream(stream bool) *BaseProvider {
+	b.Stream = &stream
+	return b
+}
+
+// Streaming reports whether Fetch streams the source: the provider's own
+// setting, else the stream option of [providers.<name>], else
+// [Collector] stream_fetch.
+func (b *BaseProvider) Streaming() bool {
+	if b.Stream != nil {
+		return *b.Stream
+	}
+	cfg := config.GetConfig()
+	if cfg == nil {
+		return false
+	}
+	if opts := cfg.Providers[b.Name]; opts != nil && opts.Stream != nil {
+		return *opts.Stream
+	}
+	return cfg.Collector.StreamFetch
+}
+
+// streamBody is a fetched body read on demand; closing it closes the
+// connection and any decoder.
+type streamBody struct {
+	io.Reader
+	io.Closer
+}
+
+// fetchStream opens sourceURL and returns its body unread. The parser pulls
+// data as it goes and ParseLinesParallel blocks on its bounded batch
+// channel, so the body is read no faster than it is parsed and memory stays
+// flat whatever the feed size. Compressed bodies are decoded on the fly.
+// The returned reader is an io.Closer.
+func (b *BaseProvider) fetchStream(sourceURL string) (io.Reader, error) {
+	log.Info().Msgf("Streaming %s", sourceURL)
+
+	var body io.ReadCloser
+	var contentEncoding string
+	var err error
+	if IsObjectStorageURL(sourceURL) {
+		body, err = DefaultObjectStorageFetcher().Fetch(sourceURL)
+	} else {
+		body, contentEncoding, err = b.openHTTPStream(sourceURL)
+	}
+	if errors.Is(err, utils.ErrSourceNotModified) {
+		log.Info().Str("url", sourceURL).Msg("Source not modified")
+		return nil, err
+	}
+	if err != nil {
+		log.Err(err).Str("url", sourceURL).Msg("Error when streaming data")
+		return nil, ErrFetchingSource
+	}
+
+	br := bufio.NewReaderSize(body, streamBufferSize)
+	if _, err := br.Peek(1); err != nil {
+		body.Close()
+		if errors.Is(err, io.EOF) {
+			log.Error().Str("url", sourceURL).Msg("Empty response from source")
+			return nil, ErrEmptyResponse
+		}
+		log.Err(err).Str("url", sourceURL).Msg("Failed to read streamed response")
+		return nil, ErrFetchingSource
+	}
+
+	head, _ := br.Peek(magicLen)
+	decoded, decoder, err := decodeBody(br, head, contentEncoding, sourceURL)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	if decoder == nil {
+		return &streamBody{Reader: decoded, Closer: body}, nil
+	}
+	return &streamBody{Reader: decoded, Closer: multiCloser{decoder, body}}, nil
+}
+
+// openHTTPStream sends the GET colly would send, with the provider's user
+// agent and headers, following redirects only to its allowed domains. It
+// returns the body and its Content-Encoding, or utils.ErrSourceNotModified
+// when a conditional request is answered with 304.
+func (b *BaseProvider) openHTTPStream(sourceURL string) (io.ReadCloser, string, error) {
+	req, err := http.NewRequest(http.MethodGet, sourceURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if b.CollyClient != nil && b.CollyClient.UserAgent != "" {
+		req.Header.Set("User-Agent", b.CollyClient.UserAgent)
+	}
+	for k, v := range b.Headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range b.conditionalHeaders(sourceURL) {
+		req.Header.Set(k, v)
+	}
+
+	allowed := b.AllowedDomains()
+	client := &http.Client{
+		Timeout: b.streamTimeout(),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxStreamRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxStreamRedirects)
+			}
+			if !slices.Contains(allowed, strings.ToLower(req.URL.Hostname())) {
+				return fmt.Errorf("%w: %s", ErrDomainNotAllowed, req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, "", utils.ErrSourceNotModified
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+	b.keepValidators(sourceURL, resp.Header)
+	return resp.Body, resp.Header.Get("Content-Encoding"), nil
+}
+
+// streamTimeout returns the timeout of [providers.<name>], or
+// defaultStreamTimeout.
+func (b *BaseProvider) streamTimeout() time.Duration {
+	if cfg := config.GetConfig(); cfg != nil {
+		if opts := cfg.Providers[b.Name]; opts != nil && opts.Timeout != nil && *opts.Timeout > 0 {
+			return *opts.Timeout
+		}
+	}
+	return defaultStreamTimeout
+}
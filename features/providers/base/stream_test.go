@@ -0,0 +1,95 @@
+package base
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFetchStream checks that a streaming provider hands over the body
+// before the source finishes sending it.
+func TestFetchStream(t *testing.T) {
+	release := make(chan struct{})
+	var userAgent, apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent, apiKey = r.UserAgent(), r.Header.Get("X-Api-Key")
+		io.WriteString(w, "first.example\n")
+		w.(http.Flusher).Flush()
+		<-release
+		io.WriteString(w, "second.example\n")
+	}))
+	defer server.Close()
+
+	p := NewBaseProvider("stream-test", server.URL+"/feed.txt", "blocklist", nil, nil).
+		SetStream(true).
+		SetHeaders(map[string]string{"X-Api-Key": "k"})
+
+	fetched := make(chan io.Reader, 1)
+	go func() {
+		reader, err := p.Fetch()
+		assert.NoError(t, err)
+		fetched <- reader
+	}()
+
+	var reader io.Reader
+	select {
+	case reader = <-fetched:
+	case <-time.After(5 * time.Second):
+		close(release)
+		t.Fatal("Fetch waited for the whole body")
+	}
+	close(release)
+	require.NotNil(t, reader)
+	closer, ok := reader.(io.Closer)
+	require.True(t, ok, "streamed readers are closed by the caller")
+	defer closer.Close()
+
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "first.example\nsecond.example\n", string(body))
+	assert.Equal(t, "k", apiKey)
+	assert.NotEmpty(t, userAgent)
+}
+
+func TestFetchStreamErrors(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "bad.example\n")
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/empty":
+		case "/missing":
+			http.NotFound(w, r)
+		case "/redirect":
+			// "localhost" is a host the provider doesn't list.
+			http.Redirect(w, r, strings.Replace(other.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	for path, want := range map[string]error{
+		"/empty":    ErrEmptyResponse,
+		"/missing":  ErrFetchingSource,
+		"/redirect": ErrFetchingSource,
+	} {
+		p := NewBaseProvider("stream-test", server.URL+path, "blocklist", nil, nil).SetStream(true)
+		_, err := p.Fetch()
+		assert.ErrorIs(t, err, want, path)
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	p := NewBaseProvider("stream-test", "https://acme.example/feed.txt", "blocklist", nil, nil)
+	p.SetStream(true)
+	assert.True(t, p.Streaming())
+	p.SetStream(false)
+	assert.False(t, p.Streaming(), "the provider setting overrides config")
+}
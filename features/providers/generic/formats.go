@@ -0,0 +1,88 @@
+package generic
+
+import (
+	"blacked/features/providers/base"
+	"fmt"
+	"strings"
+)
+
+// Source line formats a generic provider can read.
+const (
+	FormatPlain   = "plain"            // One URL, domain or CIDR network per line
+	FormatHosts   = base.FormatHosts   // hosts file lines: "0.0.0.0 bad.example"
+	FormatAdblock = base.FormatAdblock // Adblock Plus / uBlock rules: "||bad.example^"
+	FormatCSV     = base.FormatCSV     // One column of a delimited file
+)
+
+// lineParser extracts the URL or domain of one source line; ok is false for
+// lines that list nothing, such as comments and headers.
+type lineParser func(line string) (link string, ok bool)
+
+// newLineParser returns the parser for format; column and delimiter are
+// only used by csv.
+func newLineParser(format string, column int, delimiter string) (lineParser, error) {
+	switch format {
+	case FormatPlain, "":
+		return parsePlainLine, nil
+	case FormatHosts:
+		return parseHostsLine, nil
+	case FormatAdblock:
+		return parseAdblockLine, nil
+	case FormatCSV:
+		opts := base.CSVOptions{
+			URLColumn:        column,
+			CategoryColumn:   base.NoColumn,
+			ConfidenceColumn: base.NoColumn,
+			Delimiter:        delimiter,
+		}
+		if err := opts.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidGenericProvider, err)
+		}
+		return csvLineParser(opts), nil
+	}
+	return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidGenericProvider, format)
+}
+
+func parsePlainLine(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") || strings.HasPrefix(line, ";") {
+		return "", false
+	}
+	return strings.Fields(line)[0], true
+}
+
+// parseHostsLine lists the first hostname of a line; aliases are read by
+// base.NewHostsParseFunc.
+func parseHostsLine(line string) (string, bool) {
+	hosts := base.ParseHostsLine(line)
+	if len(hosts) == 0 {
+		return "", false
+	}
+	return hosts[0], true
+}
+
+// parseAdblockLine lists the target of blocking rules; exceptions are
+// applied by base.NewAdblockParseFunc over the whole list.
+func parseAdblockLine(line string) (string, bool) {
+	rule, ok := base.ParseAdblockLine(line)
+	if !ok || rule.Exception {
+		return "", false
+	}
+	return rule.Target, true
+}
+
+// csvLineParser lists the URL column; category and confidence columns are
+// read by base.NewCSVParseFunc.
+func csvLineParser(opts base.CSVOptions) lineParser {
+	return func(line string) (string, bool) {
+		rec, ok := opts.ParseLine(line)
+		return rec.URL, ok
+	}
+}
+
+// LineParser returns the function a generic provider reading format uses
+// to extract the URL or domain of one source line; ok is false for lines
+// that list nothing. column and delimiter are only used by csv.
+func LineParser(format string, column int, delimiter string) (func(line string) (link string, ok bool), error) {
+	return newLineParser(format, column, delimiter)
+}
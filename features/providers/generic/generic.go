@@ -0,0 +1,190 @@
+package generic
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entry_collector"
+	"blacked/features/providers/base"
+	"blacked/internal/config"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// TypeGeneric marks a [providers.<name>] block as a config-only provider.
+const TypeGeneric = "generic"
+
+const (
+	defaultCron     = "0 */6 * * *"
+	defaultCategory = "blocklist"
+)
+
+var ErrInvalidGenericProvider = errors.New("invalid generic provider")
+
+// NewGenericProviders creates a provider for every enabled [providers.<name>]
+// block with type = "generic". Invalid blocks are logged and skipped, as are
+// names already taken by a built-in provider.
+func NewGenericProviders(cfg *config.Config, collyClient *colly.Collector) []base.Provider {
+	names := make([]string, 0, len(cfg.Providers))
+	for name, opts := range cfg.Providers {
+		if opts != nil && opts.Type == TypeGeneric {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var providers []base.Provider
+	for _, name := range names {
+		if _, taken := base.GetProvider(name); taken {
+			log.Error().Str("provider", name).Msg("generic provider name is taken by a built-in provider — skipping")
+			continue
+		}
+
+		p, err := NewGenericProvider(name, cfg.Providers[name], collyClient)
+		if err != nil {
+			log.Error().Err(err).Str("provider", name).Msg("invalid generic provider — skipping")
+			continue
+		}
+		if p != nil {
+			providers = append(providers, p)
+		}
+	}
+	return providers
+}
+
+// NewGenericProvider creates and registers the generic provider name from
+// opts; it returns nil without an error when the provider is disabled.
+func NewGenericProvider(name string, opts *config.ProviderOptions, collyClient *colly.Collector) (base.Provider, error) {
+	if opts.Enabled != nil && !*opts.Enabled {
+		log.Info().Str("provider", name).Msg("provider disabled — skipping")
+		return nil, nil
+	}
+	if opts.SourceURL == "" {
+		return nil, fmt.Errorf("%w: source_url is required", ErrInvalidGenericProvider)
+	}
+	parseLine, err := newLineParser(opts.Format, opts.Column, opts.Delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceURL := base.ResolveURL(opts.SourceURL, opts.APIKey)
+	mirrors := make([]string, 0, len(opts.Mirrors))
+	for _, m := range opts.Mirrors {
+		mirrors = append(mirrors, base.ResolveURL(m, opts.APIKey))
+	}
+
+	cron := opts.Cron
+	if cron == "" {
+		cron = defaultCron
+	}
+	category := opts.Category
+	if category == "" {
+		category = defaultCategory
+	}
+
+	workers := opts.ParserWorkers
+	if workers <= 0 {
+		workers = 4
+	}
+	batchSize := opts.ParserBatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	skipRows := max(opts.SkipRows, 0)
+	wildcard := opts.Wildcard != nil && *opts.Wildcard
+
+	client := base.BuildCollyClientForProvider(collyClient, opts)
+
+	// Adblock lists are parsed whole, so exception rules can allow hosts;
+	// hosts lines may list several aliases and CSV rows their own category
+	// and confidence.
+	var parseList func(io.Reader, entry_collector.Collector) error
+	switch opts.Format {
+	case FormatAdblock:
+		parseList = base.NewAdblockParseFunc(name, category, workers, batchSize)
+	case FormatHosts:
+		parseList = base.NewHostsParseFunc(name, category, workers, batchSize)
+	case FormatCSV:
+		// Header rows are skipped below, as for every format.
+		csvOpts := base.CSVOptions{
+			URLColumn:        opts.Column,
+			CategoryColumn:   optionalColumn(opts.CategoryColumn),
+			ConfidenceColumn: optionalColumn(opts.ConfidenceColumn),
+			ConfidenceScale:  opts.ConfidenceScale,
+			Delimiter:        opts.Delimiter,
+			Categories:       base.NewCategoryMapper(opts.CategoryMap),
+		}
+		if parseList, err = base.NewCSVParseFunc(name, category, csvOpts, workers, batchSize); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidGenericProvider, err)
+		}
+	}
+
+	parseFunc := func(data io.Reader, collector entry_collector.Collector) error {
+		if skipRows > 0 {
+			br := bufio.NewReader(data)
+			for range skipRows {
+				if _, err := br.ReadString('\n'); err != nil {
+					break
+				}
+			}
+			data = br
+		}
+		if parseList != nil {
+			return parseList(data, collector)
+		}
+
+		return base.ParseLinesParallel(data, collector, name, workers, batchSize, func(line, processID string) (*entries.Entry, error) {
+			link, ok := parseLine(line)
+			if !ok {
+				return nil, nil
+			}
+
+			entry := entries.NewEntry().
+				WithSource(name).
+				WithProcessID(processID).
+				WithCategory(category)
+
+			setURL := entry.SetURL
+			if wildcard {
+				setURL = entry.SetWildcardURL
+			}
+			if err := setURL(link); err != nil {
+				log.Error().Err(err).Msgf("error setting URL: %s", link)
+				return nil, nil
+			}
+
+			return entry, nil
+		})
+	}
+
+	provider := base.NewBaseProvider(
+		name,
+		sourceURL,
+		category,
+		client,
+		parseFunc,
+	)
+
+	if opts.APIKey != "" {
+		provider.SetAuthCheck(base.AuthCheck{Token: opts.APIKey})
+	}
+	provider.
+		SetCronSchedule(cron).
+		SetMirrors(mirrors).
+		SetAllowedDomains(opts.AllowedDomains).
+		Register()
+
+	return provider, nil
+}
+
+// optionalColumn returns the configured column, or base.NoColumn when unset.
+func optionalColumn(column *int) int {
+	if column == nil {
+		return base.NoColumn
+	}
+	return *column
+}
@@ -0,0 +1,178 @@
+package generic
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entry_collector"
+	"blacked/features/providers/base"
+	"blacked/internal/config"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineParsers(t *testing.T) {
+	cases := []struct {
+		format string
+		lines  map[string]string // line → link, "" when skipped
+	}{
+		{FormatPlain, map[string]string{
+			"bad.example":                "bad.example",
+			"  https://bad.example/x  ":  "https://bad.example/x",
+			"# comment":                  "",
+			"":                           "",
+			"bad.example # trailing tag": "bad.example",
+			"; Spamhaus DROP List":       "",
+			"1.10.16.0/20 ; SBL256894":   "1.10.16.0/20",
+		}},
+		{FormatHosts, map[string]string{
+			"0.0.0.0 ads.example":           "ads.example",
+			"127.0.0.1\tTracker.Example #x": "tracker.example",
+			"127.0.0.1 localhost":           "",
+			"::1 ip6-localhost":             "",
+			"# 0.0.0.0 commented.example":   "",
+			"ads.example":                   "",
+		}},
+		{FormatAdblock, map[string]string{
+			"||ads.example^":             "ads.example",
+			"||ads.example^$third-party": "ads.example",
+			"||cdn.example/banner/":      "cdn.example/banner/",
+			"! Title: List":              "",
+			"[Adblock Plus 2.0]":         "",
+			"@@||good.example^":          "",
+			"example.com##.banner":       "",
+			"||*.wild.example^":          "",
+			"/banner/*/img^":             "",
+		}},
+	}
+	for _, tc := range cases {
+		parse, err := newLineParser(tc.format, 0, "")
+		require.NoError(t, err)
+		for line, want := range tc.lines {
+			got, ok := parse(line)
+			assert.Equal(t, want != "", ok, "%s: %q", tc.format, line)
+			assert.Equal(t, want, got, "%s: %q", tc.format, line)
+		}
+	}
+
+	parse, err := newLineParser(FormatCSV, 1, ";")
+	require.NoError(t, err)
+	got, ok := parse(`2026-10-16;"https://bad.example/a;b";malware`)
+	assert.True(t, ok)
+	assert.Equal(t, "https://bad.example/a;b", got)
+	_, ok = parse("2026-10-16")
+	assert.False(t, ok, "missing column")
+
+	for _, bad := range []struct {
+		format, delimiter string
+		column            int
+	}{
+		{"json", "", 0},
+		{FormatCSV, "", -1},
+		{FormatCSV, "::", 0},
+	} {
+		_, err := newLineParser(bad.format, bad.column, bad.delimiter)
+		assert.ErrorIs(t, err, ErrInvalidGenericProvider, bad)
+	}
+}
+
+func TestNewGenericProviders(t *testing.T) {
+	disabled := false
+	cfg := &config.Config{Providers: map[string]*config.ProviderOptions{
+		"acme-csv": {
+			Type:      TypeGeneric,
+			SourceURL: "https://feeds.acme.example/{api_key}/iocs.csv",
+			APIKey:    "k",
+			Format:    FormatCSV,
+			Column:    1,
+			SkipRows:  1,
+			Category:  "c2",
+			Cron:      "5 * * * *",
+		},
+		"acme-off":    {Type: TypeGeneric, SourceURL: "https://acme.example/", Enabled: &disabled},
+		"acme-no-url": {Type: TypeGeneric, Format: FormatHosts},
+		"not-generic": {SourceURL: "https://acme.example/"},
+	}}
+
+	providers := NewGenericProviders(cfg, nil)
+	require.Len(t, providers, 1)
+
+	p := providers[0].(*base.BaseProvider)
+	assert.Equal(t, "acme-csv", p.GetName())
+	assert.Equal(t, "https://feeds.acme.example/k/iocs.csv", p.Source())
+	assert.Equal(t, "c2", p.GetCategory())
+	assert.Equal(t, "5 * * * *", p.GetCronSchedule())
+
+	feed := "first_seen,url,threat\n" +
+		"2026-10-16,https://bad.example/payload.exe,c2\n" +
+		"2026-10-16,evil.example,c2\n"
+	collector := &entryCollector{}
+	require.NoError(t, p.ParseFunction(strings.NewReader(feed), collector))
+
+	var hosts []string
+	for _, e := range collector.entries {
+		assert.Equal(t, "acme-csv", e.Source)
+		assert.Equal(t, "c2", e.Category)
+		hosts = append(hosts, e.Host)
+	}
+	slices.Sort(hosts)
+	assert.Equal(t, []string{"bad.example", "evil.example"}, hosts, "the header row is skipped")
+}
+
+// entryCollector records submitted entries.
+type entryCollector struct {
+	mu      sync.Mutex
+	entries []*entries.Entry
+}
+
+func (c *entryCollector) Submit(entry *entries.Entry) {
+	c.mu.Lock()
+	c.entries = append(c.entries, entry)
+	c.mu.Unlock()
+}
+
+func (c *entryCollector) Wait()                                                               {}
+func (c *entryCollector) Close()                                                              {}
+func (c *entryCollector) GetProcessedCount(source string) int                                 { return 0 }
+func (c *entryCollector) StartProviderProcessing(ctx context.Context, name, processID string) {}
+func (c *entryCollector) FinishProviderProcessing(name, processID string) (int, time.Duration, bool) {
+	return 0, 0, true
+}
+func (c *entryCollector) CommitDelta(ctx context.Context, name, processID string) (*entry_collector.DeltaReport, error) {
+	return nil, nil
+}
+
+func TestGenericCSVColumns(t *testing.T) {
+	categoryColumn, confidenceColumn := 2, 3
+	p, err := NewGenericProvider("acme-scored", &config.ProviderOptions{
+		Type:             TypeGeneric,
+		SourceURL:        "https://feeds.acme.example/scored.csv",
+		Format:           FormatCSV,
+		Column:           0,
+		CategoryColumn:   &categoryColumn,
+		ConfidenceColumn: &confidenceColumn,
+		ConfidenceScale:  100,
+		CategoryMap:      map[string]string{"C&C": "c2"},
+	}, nil)
+	require.NoError(t, err)
+
+	feed := "https://bad.example/,x,C&C,90\n" +
+		"evil.example,x,,40\n"
+	collector := &entryCollector{}
+	require.NoError(t, p.(*base.BaseProvider).ParseFunction(strings.NewReader(feed), collector))
+
+	got := map[string]*entries.Entry{}
+	for _, e := range collector.entries {
+		got[e.Host] = e
+	}
+	require.Len(t, got, 2)
+	assert.Equal(t, "c2", got["bad.example"].Category)
+	assert.InDelta(t, 0.9, got["bad.example"].Confidence, 1e-9)
+	assert.Equal(t, defaultCategory, got["evil.example"].Category, "empty category column falls back")
+	assert.InDelta(t, 0.4, got["evil.example"].Confidence, 1e-9)
+}
@@ -1,10 +1,15 @@
 package providers
 
 import (
+	"blacked/features/providers/abusech"
+	"blacked/features/providers/abuseipdb"
 	"blacked/features/providers/base"
+	"blacked/features/providers/generic"
+	"blacked/features/providers/misp"
 	"blacked/features/providers/oisd"
 	"blacked/features/providers/openphish"
 	"blacked/features/providers/phishtank"
+	"blacked/features/providers/taxii"
 	"blacked/features/providers/urlhaus"
 
 	"github.com/gocolly/colly/v2"
@@ -33,6 +38,24 @@ func getProviders(cfg *config.Config, cc *colly.Collector) Providers {
 	if p := phishtank.NewPhishTankProvider(cfg, cc); p != nil {
 		log.Info().Str("provider", p.GetName()).Msg("registered provider")
 	}
+	if p := abuseipdb.NewAbuseIPDBProvider(cfg, cc); p != nil {
+		log.Info().Str("provider", p.GetName()).Msg("registered provider")
+	}
+	if p := abusech.NewFeodoTrackerProvider(cfg, cc); p != nil {
+		log.Info().Str("provider", p.GetName()).Msg("registered provider")
+	}
+	if p := abusech.NewSSLBLProvider(cfg, cc); p != nil {
+		log.Info().Str("provider", p.GetName()).Msg("registered provider")
+	}
+	for _, p := range generic.NewGenericProviders(cfg, cc) {
+		log.Info().Str("provider", p.GetName()).Msg("registered generic provider")
+	}
+	for _, p := range taxii.NewTAXIIProviders(cfg, cc) {
+		log.Info().Str("provider", p.GetName()).Msg("registered TAXII provider")
+	}
+	for _, p := range misp.NewMISPProviders(cfg, cc) {
+		log.Info().Str("provider", p.GetName()).Msg("registered MISP provider")
+	}
 
 	providers := Providers(base.GetRegisteredProviders())
 	return providers
@@ -49,6 +72,11 @@ func NewProviders() (Providers, error) {
 		return nil, err
 	}
 
+	// Extra domains must be known before providers register so their
+	// collectors pick them up; cc then follows the registry from here on.
+	base.SetExtraAllowedDomains(cfg.Colly.AllowedDomains)
+	base.WatchAllowedDomains(cc)
+
 	providers := getProviders(cfg, cc)
 
 	// Collect their source URLs for logging or metrics
@@ -61,7 +89,6 @@ func NewProviders() (Providers, error) {
 		log.Error().Err(err).Msg("error getting source domains")
 		return providers, err
 	}
-	cc.AllowedDomains = sourceDomains
 	log.Trace().Msgf("initialized provider source domains: %v", sourceDomains)
 
 	// Initialize Prometheus metrics with the source names.
@@ -86,6 +113,16 @@ func (p Providers) GetNames() []string {
 	return result
 }
 
+// Categories maps each provider name, the source of its entries, to its
+// default category.
+func (p Providers) Categories() map[string]string {
+	result := make(map[string]string, len(p))
+	for _, provider := range p {
+		result[provider.GetName()] = provider.GetCategory()
+	}
+	return result
+}
+
 func (p Providers) Sources() []string {
 	var result []string
 	for _, provider := range p {
@@ -94,22 +131,16 @@ func (p Providers) Sources() []string {
 	return result
 }
 
+// SourceDomains returns the hosts of every provider source and mirror, the
+// per-provider allowed domains and the globally configured extra domains.
 func (p Providers) SourceDomains() (result []string, e error) {
 	for _, provider := range p {
-		uri, err := url.Parse(provider.Source())
-		if err != nil {
+		if _, err := url.Parse(provider.Source()); err != nil {
 			log.Error().Err(err).Msg("error parsing source url")
 			return result, err
 		}
-		result = append(result, uri.Host)
+		result = append(result, provider.AllowedDomains()...)
 	}
 
-	// Add an extra domain for possible GitHub raw usage:
-	uri, e := url.Parse("https://raw.githubusercontent.com")
-	if e == nil {
-		result = append(result, uri.Host)
-	} else {
-		log.Error().Err(e).Msg("error parsing fallback url")
-	}
-	return result, nil
+	return base.NormalizeHosts(result...), nil
 }
@@ -0,0 +1,323 @@
+package misp
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entry_collector"
+	"blacked/features/providers/base"
+	"blacked/internal/config"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// TypeMISP marks a [providers.<name>] block as a MISP instance.
+const TypeMISP = "misp"
+
+const (
+	defaultCron     = "20 * * * *"
+	defaultCategory = "threat-intel"
+
+	// searchPath is the attribute search endpoint of the MISP REST API.
+	searchPath = "/attributes/restSearch"
+	// mePath shows the user of the auth key, which checks the key.
+	mePath = "/users/view/me.json"
+
+	// pageSize is the number of attributes asked for per page; maxPages
+	// bounds a poll.
+	pageSize = 5000
+	maxPages = 1000
+)
+
+// Types are the MISP attribute types listed.
+var Types = []string{"url", "domain", "hostname", "ip-dst"}
+
+var (
+	ErrInvalidMISPProvider = errors.New("invalid MISP provider")
+	ErrInvalidResponse     = errors.New("invalid MISP search response")
+)
+
+// Query is the body of an attribute search. Only attributes flagged for
+// detection (to_ids) are asked for, with the tags of their event, skipping
+// values on the instance's warninglists of known benign hosts.
+type Query struct {
+	ReturnFormat       string   `json:"returnFormat"`
+	Type               []string `json:"type"`
+	Tags               []string `json:"tags,omitempty"`
+	Last               string   `json:"last,omitempty"` // Events published within, e.g. "43200m"
+	ToIDs              bool     `json:"to_ids"`
+	IncludeEventTags   bool     `json:"includeEventTags"`
+	EnforceWarninglist bool     `json:"enforceWarninglist"`
+	Limit              int      `json:"limit"`
+	Page               int      `json:"page"`
+}
+
+// NewQuery returns the search for page (from 1) of the attributes carrying
+// tags, in events published within maxAge when it is set.
+func NewQuery(tags []string, maxAge *time.Duration, page int) Query {
+	q := Query{
+		ReturnFormat:       "json",
+		Type:               Types,
+		Tags:               tags,
+		ToIDs:              true,
+		IncludeEventTags:   true,
+		EnforceWarninglist: true,
+		Limit:              pageSize,
+		Page:               page,
+	}
+	if maxAge != nil && *maxAge > 0 {
+		q.Last = fmt.Sprintf("%dm", int64(math.Ceil(maxAge.Minutes())))
+	}
+	return q
+}
+
+// Body returns the query as a request body.
+func (q Query) Body() []byte {
+	b, _ := json.Marshal(q)
+	return b
+}
+
+// Tag is a MISP tag, such as tlp:white or a taxonomy machine tag.
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// Attribute is one attribute of a search response.
+type Attribute struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Tag   []Tag  `json:"Tag"` // Attribute tags, with the event's when includeEventTags is set
+	Event *struct {
+		Tag []Tag `json:"Tag"`
+	} `json:"Event"`
+}
+
+// Tags returns the names of the attribute's tags and its event's.
+func (a Attribute) Tags() []string {
+	tags := make([]string, 0, len(a.Tag))
+	add := func(list []Tag) {
+		for _, t := range list {
+			if t.Name != "" && !slices.Contains(tags, t.Name) {
+				tags = append(tags, t.Name)
+			}
+		}
+	}
+	add(a.Tag)
+	if a.Event != nil {
+		add(a.Event.Tag)
+	}
+	return tags
+}
+
+// Link returns the attribute in the form Entry.SetURL parses; ok is false
+// for values that are not a URL, host name or single IPv4 address.
+func (a Attribute) Link() (link string, ok bool) {
+	value := strings.TrimSpace(a.Value)
+	switch a.Type {
+	case "url":
+		return value, value != ""
+	case "domain", "hostname":
+		value = strings.TrimSuffix(value, ".")
+		return value, value != "" && !strings.ContainsAny(value, "/ ")
+	case "ip-dst":
+		// Only single addresses; ranges would list whole networks.
+		value = strings.TrimSuffix(value, "/32")
+		ip := net.ParseIP(value)
+		if ip == nil || ip.To4() == nil {
+			return "", false
+		}
+		return ip.String(), true
+	}
+	return "", false
+}
+
+// DecodeResponse reads the attributes of a search response.
+func DecodeResponse(r io.Reader) ([]Attribute, error) {
+	var resp struct {
+		Response *struct {
+			Attribute []Attribute `json:"Attribute"`
+		} `json:"response"`
+	}
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return nil, errors.Join(ErrInvalidResponse, err)
+	}
+	if resp.Response == nil {
+		return nil, ErrInvalidResponse
+	}
+	return resp.Response.Attribute, nil
+}
+
+// ReadPages calls fn with the attributes of first, the first page of the
+// search, then posts the following pages while pages come back full.
+func ReadPages(first io.Reader, query func(page int) []byte, post func(body []byte) (io.Reader, error), fn func([]Attribute) error) error {
+	page := first
+	for n := 1; ; n++ {
+		attrs, err := DecodeResponse(page)
+		if err != nil {
+			return err
+		}
+		if err := fn(attrs); err != nil {
+			return err
+		}
+		if len(attrs) < pageSize {
+			return nil
+		}
+		if n == maxPages {
+			log.Warn().Int("pages", n).Msg("MISP search has more pages than read in one poll")
+			return nil
+		}
+		if page, err = post(query(n + 1)); err != nil {
+			return err
+		}
+	}
+}
+
+// NewMISPProviders creates a provider for every enabled [providers.<name>]
+// block with type = "misp". Invalid blocks are logged and skipped, as are
+// names already taken by another provider.
+func NewMISPProviders(cfg *config.Config, collyClient *colly.Collector) []base.Provider {
+	names := make([]string, 0, len(cfg.Providers))
+	for name, opts := range cfg.Providers {
+		if opts != nil && opts.Type == TypeMISP {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var providers []base.Provider
+	for _, name := range names {
+		if _, taken := base.GetProvider(name); taken {
+			log.Error().Str("provider", name).Msg("MISP provider name is taken by another provider — skipping")
+			continue
+		}
+
+		p, err := NewMISPProvider(name, cfg.Providers[name], collyClient)
+		if err != nil {
+			log.Error().Err(err).Str("provider", name).Msg("invalid MISP provider — skipping")
+			continue
+		}
+		if p != nil {
+			providers = append(providers, p)
+		}
+	}
+	return providers
+}
+
+// NewMISPProvider creates and registers the provider name searching the
+// MISP instance at opts.SourceURL, e.g. https://misp.example, with the
+// auth key in opts.APIKey. It returns nil without an error when the
+// provider is disabled.
+func NewMISPProvider(name string, opts *config.ProviderOptions, collyClient *colly.Collector) (base.Provider, error) {
+	if opts.Enabled != nil && !*opts.Enabled {
+		log.Info().Str("provider", name).Msg("provider disabled — skipping")
+		return nil, nil
+	}
+	if opts.SourceURL == "" {
+		return nil, fmt.Errorf("%w: source_url is required", ErrInvalidMISPProvider)
+	}
+	if opts.APIKey == "" {
+		return nil, fmt.Errorf("%w: api_key is required", ErrInvalidMISPProvider)
+	}
+	searchURL, err := SearchURL(opts.SourceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	cron := opts.Cron
+	if cron == "" {
+		cron = defaultCron
+	}
+	category := opts.Category
+	if category == "" {
+		category = defaultCategory
+	}
+
+	workers := opts.ParserWorkers
+	if workers <= 0 {
+		workers = 4
+	}
+
+	mapper := base.NewCategoryMapper(opts.CategoryMap)
+	client := base.BuildCollyClientForProvider(collyClient, opts)
+	query := func(page int) []byte {
+		return NewQuery(opts.Tags, opts.IgnoreOlderThan, page).Body()
+	}
+
+	var provider *base.BaseProvider
+	parseFunc := func(data io.Reader, collector entry_collector.Collector) error {
+		id := uuid.New().String()
+		post := func(body []byte) (io.Reader, error) {
+			return provider.PostPage(searchURL, body)
+		}
+		return ReadPages(data, query, post, func(attrs []Attribute) error {
+			return base.ProcessEntriesParallel(attrs, collector, workers, func(item Attribute, processID string) (*entries.Entry, error) {
+				link, ok := item.Link()
+				if !ok {
+					log.Debug().Str("provider", name).Str("type", item.Type).Str("value", item.Value).Msg("skipping unusable MISP attribute")
+					return nil, nil
+				}
+
+				entry := entries.NewEntry().
+					WithSource(name).
+					WithProcessID(processID).
+					WithCategories(mapper.Categories(category, item.Tags()...)...)
+
+				if err := entry.SetURL(link); err != nil {
+					log.Error().Err(err).Msgf("error setting URL: %s", link)
+					return nil, nil
+				}
+
+				return entry, nil
+			}, id)
+		})
+	}
+
+	provider = base.NewBaseProvider(
+		name,
+		searchURL,
+		category,
+		client,
+		parseFunc,
+	)
+
+	provider.
+		SetCronSchedule(cron).
+		SetAllowedDomains(opts.AllowedDomains).
+		SetHeaders(map[string]string{
+			"Authorization": opts.APIKey,
+			"Accept":        "application/json",
+			"Content-Type":  "application/json",
+		}).
+		SetRequestBody(query(1)).
+		SetAuthCheck(base.AuthCheck{Method: http.MethodGet, URL: strings.TrimSuffix(searchURL, searchPath) + mePath, Token: opts.APIKey}).
+		Register()
+
+	return provider, nil
+}
+
+// SearchURL returns the attribute search endpoint of the MISP instance at
+// sourceURL; a URL already naming the endpoint is kept.
+func SearchURL(sourceURL string) (string, error) {
+	u, err := url.Parse(sourceURL)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidMISPProvider, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("%w: source_url must be an http(s) URL", ErrInvalidMISPProvider)
+	}
+	if !strings.HasSuffix(strings.TrimSuffix(u.Path, "/"), searchPath) {
+		u.Path = strings.TrimSuffix(u.Path, "/") + searchPath
+	}
+	return u.String(), nil
+}
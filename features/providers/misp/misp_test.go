@@ -0,0 +1,161 @@
+package misp
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entry_collector"
+	"blacked/features/providers/base"
+	"blacked/internal/config"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeLink(t *testing.T) {
+	cases := []struct {
+		attr Attribute
+		link string
+	}{
+		{Attribute{Type: "url", Value: " http://evil.example/a "}, "http://evil.example/a"},
+		{Attribute{Type: "domain", Value: "evil.example."}, "evil.example"},
+		{Attribute{Type: "hostname", Value: "www.evil.example"}, "www.evil.example"},
+		{Attribute{Type: "ip-dst", Value: "203.0.113.7/32"}, "203.0.113.7"},
+		{Attribute{Type: "ip-dst", Value: "203.0.113.0/24"}, ""},
+		{Attribute{Type: "md5", Value: "abc"}, ""},
+	}
+	for _, tc := range cases {
+		link, ok := tc.attr.Link()
+		assert.Equal(t, tc.link, link, tc.attr.Value)
+		assert.Equal(t, tc.link != "", ok, tc.attr.Value)
+	}
+}
+
+func TestNewQuery(t *testing.T) {
+	month := 30 * 24 * time.Hour
+	q := NewQuery([]string{"tlp:white", "!tlp:red"}, &month, 2)
+	assert.Equal(t, "43200m", q.Last)
+	assert.Equal(t, Types, q.Type)
+	assert.True(t, q.ToIDs)
+	assert.Equal(t, 2, q.Page)
+
+	assert.Empty(t, NewQuery(nil, nil, 1).Last)
+	assert.NotContains(t, string(NewQuery(nil, nil, 1).Body()), `"tags"`)
+}
+
+func TestSearchURL(t *testing.T) {
+	for _, in := range []string{"https://misp.example", "https://misp.example/", "https://misp.example/attributes/restSearch"} {
+		got, err := SearchURL(in)
+		require.NoError(t, err)
+		assert.Equal(t, "https://misp.example/attributes/restSearch", got, in)
+	}
+	_, err := SearchURL("misp.example")
+	assert.ErrorIs(t, err, ErrInvalidMISPProvider)
+}
+
+func TestMISPProvider(t *testing.T) {
+	var queries []Query
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != searchPath || r.Header.Get("Authorization") != "key" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var q Query
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+		queries = append(queries, q)
+
+		// A full first page, then the remaining attributes.
+		var attrs []map[string]any
+		if q.Page == 1 {
+			for i := range pageSize {
+				attrs = append(attrs, map[string]any{"type": "url", "value": fmt.Sprintf("http://bulk.example/%d", i)})
+			}
+		} else {
+			attrs = []map[string]any{
+				{"type": "url", "value": "http://phish.example/login", "Tag": []map[string]any{{"name": "phishing"}}, "Event": map[string]any{"Tag": []map[string]any{{"name": "Botnet"}}}},
+				{"type": "ip-dst", "value": "198.51.100.4"},
+				{"type": "ip-dst", "value": "198.51.100.0/24"},
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"response": map[string]any{"Attribute": attrs}})
+	}))
+	defer server.Close()
+
+	maxAge := 48 * time.Hour
+	cfg := &config.Config{Providers: map[string]*config.ProviderOptions{
+		"misp-test": {
+			Type:            TypeMISP,
+			SourceURL:       server.URL,
+			APIKey:          "key",
+			Tags:            []string{"tlp:white"},
+			IgnoreOlderThan: &maxAge,
+			CategoryMap:     map[string]string{"phishing": "phishing", "botnet": "botnet"},
+		},
+		"misp-no-key": {Type: TypeMISP, SourceURL: server.URL},
+	}}
+
+	providers := NewMISPProviders(cfg, colly.NewCollector(colly.AllowURLRevisit()))
+	require.Len(t, providers, 1)
+	p := providers[0].(*base.BaseProvider)
+	assert.Equal(t, defaultCategory, p.GetCategory())
+	assert.Equal(t, defaultCron, p.GetCronSchedule())
+
+	data, err := p.Fetch()
+	require.NoError(t, err)
+	collector := &entryCollector{}
+	require.NoError(t, p.ParseFunction(data, collector))
+
+	require.Len(t, queries, 2)
+	assert.Equal(t, []string{"tlp:white"}, queries[0].Tags)
+	assert.Equal(t, "2880m", queries[0].Last)
+	assert.Equal(t, 2, queries[1].Page)
+
+	got := map[string]*entries.Entry{}
+	for _, e := range collector.entries {
+		got[e.Host+e.Path] = e
+	}
+	assert.Len(t, got, pageSize+2)
+	assert.Equal(t, "misp-test", got["phish.example/login"].Source)
+	assert.Equal(t, []string{defaultCategory, "phishing", "botnet"}, got["phish.example/login"].AllCategories())
+	assert.Equal(t, defaultCategory, got["198.51.100.4"].Category)
+}
+
+func TestDecodeResponse(t *testing.T) {
+	_, err := DecodeResponse(strings.NewReader(`{"errors": "Authentication failed."}`))
+	assert.ErrorIs(t, err, ErrInvalidResponse)
+
+	attrs, err := DecodeResponse(strings.NewReader(`{"response": {"Attribute": []}}`))
+	require.NoError(t, err)
+	assert.Empty(t, attrs)
+}
+
+// entryCollector records submitted entries.
+type entryCollector struct {
+	mu      sync.Mutex
+	entries []*entries.Entry
+}
+
+func (c *entryCollector) Submit(entry *entries.Entry) {
+	c.mu.Lock()
+	c.entries = append(c.entries, entry)
+	c.mu.Unlock()
+}
+
+func (c *entryCollector) Wait()                                                               {}
+func (c *entryCollector) Close()                                                              {}
+func (c *entryCollector) GetProcessedCount(source string) int                                 { return 0 }
+func (c *entryCollector) StartProviderProcessing(ctx context.Context, name, processID string) {}
+func (c *entryCollector) FinishProviderProcessing(name, processID string) (int, time.Duration, bool) {
+	return 0, 0, true
+}
+func (c *entryCollector) CommitDelta(ctx context.Context, name, processID string) (*entry_collector.DeltaReport, error) {
+	return nil, nil
+}
@@ -47,6 +47,9 @@ func NewOISDNSFWProvider(cfg *config.Config, collyClient *colly.Collector) base.
 		batchSize = 1000
 	}
 
+	// domainswild lists block every subdomain of a listed host.
+	wildcard := opts.Wildcard == nil || *opts.Wildcard
+
 	client := base.BuildCollyClientForProvider(collyClient, opts)
 
 	parseFunc := func(data io.Reader, collector entry_collector.Collector) error {
@@ -61,7 +64,11 @@ func NewOISDNSFWProvider(cfg *config.Config, collyClient *colly.Collector) base.
 				WithProcessID(processID).
 				WithCategory(category)
 
-			if err := entry.SetURL(line); err != nil {
+			setURL := entry.SetURL
+			if wildcard {
+				setURL = entry.SetWildcardURL
+			}
+			if err := setURL(line); err != nil {
 				log.Error().Err(err).Msgf("error setting URL: %s", line)
 				return nil, nil
 			}
@@ -69,6 +76,10 @@ func NewOISDNSFWProvider(cfg *config.Config, collyClient *colly.Collector) base.
 			return entry, nil
 		})
 	}
+	if opts.Format == base.FormatAdblock {
+		// The ABP variant of the list, e.g. https://nsfw.oisd.nl
+		parseFunc = base.NewAdblockParseFunc(providerName, category, workers, batchSize)
+	}
 
 	provider := base.NewBaseProvider(
 		providerName,
@@ -80,6 +91,8 @@ func NewOISDNSFWProvider(cfg *config.Config, collyClient *colly.Collector) base.
 
 	provider.
 		SetCronSchedule(cron).
+		SetMirrors(opts.Mirrors).
+		SetAllowedDomains(opts.AllowedDomains).
 		Register()
 
 	return provider
@@ -75,6 +75,8 @@ func NewOpenPhishFeedProvider(cfg *config.Config, collyClient *colly.Collector)
 
 	provider.
 		SetCronSchedule(cron).
+		SetMirrors(opts.Mirrors).
+		SetAllowedDomains(opts.AllowedDomains).
 		Register()
 
 	return provider
@@ -5,18 +5,108 @@ import (
 	"blacked/features/entry_collector"
 	"blacked/features/providers/base"
 	"blacked/internal/config"
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"io"
+	"strings"
+	"time"
 
 	"github.com/gocolly/colly/v2"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	// Verified phishes that are still online are the feed's core; PhishTank
+	// takes them down from online-valid once they go offline.
+	ConfidenceVerifiedOnline  = 0.95
+	ConfidenceVerifiedOffline = 0.6
+	ConfidenceUnverified      = 0.3
+
+	// CategoryUnverified tags submissions the community has not confirmed yet.
+	CategoryUnverified = "suspected-phishing"
+)
+
+var ErrInvalidFeed = errors.New("invalid PhishTank feed")
+
+// YesNo decodes PhishTank's "yes"/"no" flags; JSON booleans are accepted too.
+type YesNo bool
+
+func (v *YesNo) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*v = YesNo(b)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*v = YesNo(strings.EqualFold(s, "yes") || strings.EqualFold(s, "true"))
+	return nil
+}
+
+// PhishTankEntry is one phish of the online-valid JSON feed.
 type PhishTankEntry struct {
-	URL        string `json:"url"`
-	Verified   bool   `json:"verified"`
-	VerifyDate string `json:"verification_time"`
+	PhishID          json.Number `json:"phish_id"`
+	URL              string      `json:"url"`
+	PhishDetailURL   string      `json:"phish_detail_url"`
+	SubmissionTime   string      `json:"submission_time"`
+	Verified         YesNo       `json:"verified"`
+	VerificationTime string      `json:"verification_time"`
+	Online           YesNo       `json:"online"`
+	Target           string      `json:"target"`
+}
+
+// Classify maps the verification status of e to an entry confidence and
+// category; category is used for verified phishes.
+func (e PhishTankEntry) Classify(category string) (float64, string) {
+	switch {
+	case !bool(e.Verified):
+		return ConfidenceUnverified, CategoryUnverified
+	case !bool(e.Online):
+		return ConfidenceVerifiedOffline, category
+	default:
+		return ConfidenceVerifiedOnline, category
+	}
+}
+
+// Submitted returns when the phish was submitted, zero when the feed
+// doesn't say.
+func (e PhishTankEntry) Submitted() time.Time {
+	t, err := time.Parse(time.RFC3339, e.SubmissionTime)
+	if err != nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
+
+// DecodeFeed decodes the JSON feed. Fetch gunzips the .json.gz download;
+// responses stored before it did are gunzipped here.
+func DecodeFeed(data io.Reader) ([]PhishTankEntry, error) {
+	br := bufio.NewReader(data)
+	if magic, err := br.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			log.Error().Err(err).Msg("error opening gzipped PhishTank feed")
+			return nil, errors.Join(ErrInvalidFeed, err)
+		}
+		defer gz.Close()
+		data = gz
+	} else {
+		data = br
+	}
+
+	var phishEntries []PhishTankEntry
+	if err := json.NewDecoder(data).Decode(&phishEntries); err != nil {
+		log.Error().Err(err).Msg("error decoding PhishTank JSON")
+		return nil, errors.Join(ErrInvalidFeed, err)
+	}
+	return phishEntries, nil
 }
 
 func NewPhishTankProvider(cfg *config.Config, collyClient *colly.Collector) base.Provider {
@@ -31,24 +121,32 @@ func NewPhishTankProvider(cfg *config.Config, collyClient *colly.Collector) base
 		return nil
 	}
 
+	// The API key is part of the download path, so an unauthenticated
+	// fetch is rate limited to a few downloads a day.
+	if opts.APIKey == "" {
+		log.Warn().Str("provider", providerName).Msg("PhishTank API key not configured — skipping")
+		return nil
+	}
+
 	sourceURL := opts.SourceURL
 	if sourceURL == "" {
-		sourceURL = "https://data.phishtank.com/data/{api_key}/online-valid.json"
+		sourceURL = "https://data.phishtank.com/data/{api_key}/online-valid.json.gz"
 	}
-
-	// Replace {api_key} placeholder if an API key is configured
 	sourceURL = base.ResolveURL(sourceURL, opts.APIKey)
 
-	// If API key is empty, warn and skip (source URL will still contain {api_key} placeholder)
-	if opts.APIKey == "" {
-		log.Warn().Str("provider", providerName).Msg("PhishTank API key not configured — skipping")
-		return nil
+	mirrors := make([]string, 0, len(opts.Mirrors))
+	for _, m := range opts.Mirrors {
+		mirrors = append(mirrors, base.ResolveURL(m, opts.APIKey))
 	}
 
 	cron := opts.Cron
 	if cron == "" {
 		cron = "45 */6 * * *"
 	}
+	category := opts.Category
+	if category == "" {
+		category = "phishing"
+	}
 
 	workers := opts.ParserWorkers
 	if workers <= 0 {
@@ -56,29 +154,36 @@ func NewPhishTankProvider(cfg *config.Config, collyClient *colly.Collector) base
 	}
 
 	client := base.BuildCollyClientForProvider(collyClient, opts)
+	if client != nil && opts.UserAgent == "" {
+		// PhishTank asks clients to identify themselves as phishtank/<username>.
+		client.UserAgent = "phishtank/blacked"
+	}
 
 	parseFunc := func(data io.Reader, collector entry_collector.Collector) error {
-		var phishEntries []PhishTankEntry
-		id := uuid.New().String()
-
-		decoder := json.NewDecoder(data)
-		if err := decoder.Decode(&phishEntries); err != nil {
-			log.Error().Err(err).Msg("error decoding PhishTank JSON")
+		phishEntries, err := DecodeFeed(data)
+		if err != nil {
 			return err
 		}
 
+		ageFilter := base.NewAgeFilter(providerName, opts.IgnoreOlderThan)
+		defer ageFilter.Report()
+
+		id := uuid.New().String()
 		return base.ProcessEntriesParallel(phishEntries, collector, workers, func(phishEntry PhishTankEntry, processID string) (*entries.Entry, error) {
-			if !phishEntry.Verified {
+			if !ageFilter.Keep(phishEntry.Submitted()) {
 				return nil, nil
 			}
 
+			confidence, entryCategory := phishEntry.Classify(category)
+
 			entry := entries.NewEntry().
 				WithSource(providerName).
 				WithProcessID(processID).
-				WithCategory("phishing")
+				WithCategory(entryCategory).
+				WithConfidence(confidence)
 
 			if err := entry.SetURL(phishEntry.URL); err != nil {
-				log.Error().Err(err).Msgf("error setting URL: %s", phishEntry.URL)
+				log.Error().Err(err).Str("phish_id", phishEntry.PhishID.String()).Msgf("error setting URL: %s", phishEntry.URL)
 				return nil, nil
 			}
 
@@ -89,13 +194,17 @@ func NewPhishTankProvider(cfg *config.Config, collyClient *colly.Collector) base
 	provider := base.NewBaseProvider(
 		providerName,
 		sourceURL,
-		"phishing",
+		category,
 		client,
 		parseFunc,
 	)
 
 	provider.
 		SetCronSchedule(cron).
+		SetMirrors(mirrors).
+		SetAllowedDomains(opts.AllowedDomains).
+		// A HEAD of the download path tells whether the key is accepted.
+		SetAuthCheck(base.AuthCheck{Token: opts.APIKey}).
 		Register()
 
 	return provider
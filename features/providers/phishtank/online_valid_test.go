@@ -0,0 +1,64 @@
+package phishtank
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const feedJSON = `[
+	{"phish_id": 8123456, "url": "https://login.paypa1.example/", "verified": "yes", "online": "yes", "target": "PayPal"},
+	{"phish_id": "8123457", "url": "http://old.phish.example/x", "verified": "yes", "online": "no"},
+	{"phish_id": 8123458, "url": "http://maybe.phish.example/", "verified": false, "online": true}
+]`
+
+func TestDecodeFeed(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, err := w.Write([]byte(feedJSON))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	for name, body := range map[string][]byte{"json": []byte(feedJSON), "json.gz": gz.Bytes()} {
+		got, err := DecodeFeed(bytes.NewReader(body))
+		require.NoError(t, err, name)
+		require.Len(t, got, 3, name)
+		assert.Equal(t, "8123456", got[0].PhishID.String(), name)
+		assert.Equal(t, "PayPal", got[0].Target, name)
+		assert.True(t, bool(got[0].Verified), name)
+		assert.False(t, bool(got[1].Online), name)
+		assert.False(t, bool(got[2].Verified), name)
+	}
+
+	_, err = DecodeFeed(strings.NewReader("<html>rate limited</html>"))
+	assert.ErrorIs(t, err, ErrInvalidFeed)
+}
+
+func TestClassify(t *testing.T) {
+	got, err := DecodeFeed(strings.NewReader(feedJSON))
+	require.NoError(t, err)
+
+	for i, want := range []struct {
+		confidence float64
+		category   string
+	}{
+		{ConfidenceVerifiedOnline, "phishing"},
+		{ConfidenceVerifiedOffline, "phishing"},
+		{ConfidenceUnverified, CategoryUnverified},
+	} {
+		confidence, category := got[i].Classify("phishing")
+		assert.Equal(t, want.confidence, confidence, got[i].URL)
+		assert.Equal(t, want.category, category, got[i].URL)
+	}
+}
+
+func TestSubmitted(t *testing.T) {
+	e := PhishTankEntry{SubmissionTime: "2024-12-02T10:42:06+02:00"}
+	assert.Equal(t, time.Date(2024, 12, 2, 8, 42, 6, 0, time.UTC), e.Submitted())
+	assert.True(t, PhishTankEntry{}.Submitted().IsZero())
+}
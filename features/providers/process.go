@@ -1,9 +1,14 @@
 package providers
 
 import (
+	"blacked/features/alerts"
 	"blacked/features/entries/repository"
 	"blacked/features/entry_collector"
+	"blacked/features/eventbus"
+	"blacked/features/maintenance"
 	"blacked/features/providers/base"
+	"blacked/features/watchlist"
+	"blacked/features/webhooks"
 	"blacked/internal/collector"
 	"blacked/internal/config"
 	"blacked/internal/db"
@@ -12,6 +17,7 @@ import (
 
 	"context"
 	"errors"
+	"io"
 	"sync"
 	"time"
 
@@ -44,6 +50,7 @@ const (
 type ProcessOptions struct {
 	UpdateCacheMode UpdateCacheMode
 	TrackMetrics    bool
+	ParentID        string // Process manager process the provider runs belong to, if any
 }
 
 // DefaultProcessOptions provides sensible defaults
@@ -52,6 +59,27 @@ var DefaultProcessOptions = ProcessOptions{
 	TrackMetrics:    true,
 }
 
+// notifications tracks the alerts, watchlist events and webhooks finished
+// runs send in the background.
+var notifications sync.WaitGroup
+
+// WaitNotifications waits for the background notifications of finished
+// runs, or until ctx is done.
+func WaitNotifications(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		notifications.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Process processes all providers with the specified options
 // This is the central method for all provider processing operations
 // and should be the entrypoint for all provider execution.
@@ -60,6 +88,12 @@ func (p Providers) Process(ctx context.Context, opts ...ProcessOptions) error {
 		return ErrNoProvidersSpecified
 	}
 
+	// Runs write entries; read-only maintenance mode pauses them all.
+	if err := maintenance.Check(ctx); err != nil {
+		log.Info().Strs("providers", p.GetNames()).Msg("Skipping provider run in read-only maintenance mode")
+		return err
+	}
+
 	options := DefaultProcessOptions
 	if len(opts) > 0 {
 		options = opts[0]
@@ -67,6 +101,7 @@ func (p Providers) Process(ctx context.Context, opts ...ProcessOptions) error {
 
 	// Generate a unique process ID for this run
 	processID := uuid.New().String()
+	runStarted := time.Now().UnixNano()
 
 	// Start execution trace if enabled (captures all providers in one trace file)
 	if tracing.ShouldStartExecTrace("providers") {
@@ -80,6 +115,18 @@ func (p Providers) Process(ctx context.Context, opts ...ProcessOptions) error {
 		return ErrCollectorNotFound
 	}
 
+	// One trace per run: provider stages, batch saves and the cache sync
+	// are spans under it.
+	tracer := otel.Tracer("blacked/providers")
+	ctx, span := tracer.Start(ctx, "providers.process",
+		trace.WithAttributes(
+			attribute.String("process.id", processID),
+			attribute.Int("providers", len(p)),
+			attribute.String("cache_mode", string(options.UpdateCacheMode)),
+		),
+	)
+	defer span.End()
+
 	log.Info().
 		Int("providers", len(p)).
 		Str("cache_mode", string(options.UpdateCacheMode)).
@@ -88,6 +135,8 @@ func (p Providers) Process(ctx context.Context, opts ...ProcessOptions) error {
 	// Get write database connection (used for provider repository)
 	rwDB, err := db.GetWriteDB()
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to open database")
 		log.Err(err).Msg("Failed to open read-write database")
 		return ErrCreateRepository
 	}
@@ -124,7 +173,7 @@ func (p Providers) Process(ctx context.Context, opts ...ProcessOptions) error {
 			defer func() { <-semaphore }()
 
 			// Process the provider
-			p.processProvider(ctx, prov, repo, pondCollector, options.TrackMetrics, nil, errChan)
+			p.processProvider(ctx, prov, repo, pondCollector, options.TrackMetrics, options.ParentID, nil, errChan)
 		}(provider)
 	}
 
@@ -139,19 +188,27 @@ func (p Providers) Process(ctx context.Context, opts ...ProcessOptions) error {
 		}
 	}
 
+	// The run's URLs are in the bloom filter since their batches were saved;
+	// a sync of the changed URLs caches them and takes the ones delta
+	// ingestion soft deleted out of the cache and the filter, without
+	// rebuilding either. Every write of the run happened after it started,
+	// so that sync covers the other providers too.
+	syncScope := entry_collector.CacheSyncScope{Mode: entry_collector.CacheSyncDelta, Since: runStarted}
+
 	// Handle cache updates based on mode using the integrated cache sync mechanism
 	switch options.UpdateCacheMode {
 	case UpdateCacheImmediate:
-		log.Info().Msg("Performing immediate cache sync after provider processing")
-		success := pondCollector.ScheduleCacheSync(true)
+		log.Info().Str("scope", string(syncScope.Mode)).Msg("Performing immediate cache sync after provider processing")
+		success := pondCollector.ScheduleCacheSyncScope(ctx, syncScope, true)
 		if !success {
+			span.SetStatus(codes.Error, "cache sync busy")
 			log.Warn().Msg("Could not perform immediate cache sync - another sync is in progress")
 			return ErrUpdateCache
 		}
 
 	case UpdateCacheDeferred:
-		log.Info().Msg("Scheduling deferred cache sync")
-		success := pondCollector.ScheduleCacheSync(false)
+		log.Info().Str("scope", string(syncScope.Mode)).Msg("Scheduling deferred cache sync")
+		success := pondCollector.ScheduleCacheSyncScope(ctx, syncScope, false)
 		if !success {
 			log.Debug().Msg("Deferred cache sync not scheduled - sync queue is full")
 		}
@@ -163,10 +220,11 @@ func (p Providers) Process(ctx context.Context, opts ...ProcessOptions) error {
 		log.Warn().
 			Str("update_mode", string(options.UpdateCacheMode)).
 			Msg("Unknown cache update mode, defaulting to deferred")
-		pondCollector.ScheduleCacheSync(false)
+		pondCollector.ScheduleCacheSyncScope(ctx, syncScope, false)
 	}
 
 	if aggregatedError != nil {
+		span.SetStatus(codes.Error, "provider processing failed")
 		log.Err(aggregatedError).Msg("Errors during provider processing")
 		return ErrProcessingProvider
 	}
@@ -185,6 +243,7 @@ func (p Providers) processProvider(
 	repo repository.BlacklistRepository,
 	pondCollector entry_collector.Collector,
 	trackMetrics bool,
+	parentID string,
 	wg *sync.WaitGroup,
 	errChan chan error,
 ) {
@@ -224,6 +283,7 @@ func (p Providers) processProvider(
 		Logger()
 
 	providerLogger.Info().Time("starts", startedAt).Msg("Processing provider")
+	recordEvent(ctx, name, strProcessID, "start", "info", "Processing provider", map[string]any{"source": source})
 
 	provider.SetProcessID(processID)
 
@@ -231,17 +291,36 @@ func (p Providers) processProvider(
 	cronSchedule := provider.GetCronSchedule()
 	ttl := utils.ParseTTLFromCron(cronSchedule)
 
-	fetchSpan := trace.SpanFromContext(ctx)
-	fetchSpan.AddEvent("fetching data from source")
+	// The validators of the last imported response make the fetch
+	// conditional; an unchanged source is not parsed again.
+	if validators, err := utils.LoadValidators(name); err == nil {
+		provider.SetFetchValidators(validators)
+	}
+
+	// A streamed source is only opened here; its download is part of parse.
+	_, fetchSpan := tracer.Start(ctx, "provider.fetch")
 	reader, meta, err := utils.GetResponseReader(source, provider.Fetch, name, strProcessID, ttl)
+	if errors.Is(err, utils.ErrSourceNotModified) {
+		fetchSpan.End()
+		span.SetAttributes(attribute.Bool("source.not_modified", true))
+		finishNotModified(ctx, provider, repo, trackMetrics, parentID, strProcessID, startedAt)
+		return
+	}
 	if err != nil {
-		span.RecordError(err)
+		fetchSpan.RecordError(err)
+		fetchSpan.SetStatus(codes.Error, "failed to fetch data")
+		fetchSpan.End()
 		span.SetStatus(codes.Error, "failed to fetch data")
 		providerLogger.
 			Err(err).
 			Str("source", source).
 			Str("provider", name).
 			Msg("Error fetching data")
+		fields := map[string]any{"source": source}
+		if attempts := provider.ChecksumAttempts(); len(attempts) > 0 {
+			fields["checksum_attempts"] = attempts
+		}
+		recordEvent(ctx, name, strProcessID, "fetch", "error", err.Error(), fields)
 
 		// Update metrics on failure
 		if trackMetrics {
@@ -251,30 +330,53 @@ func (p Providers) processProvider(
 			}
 		}
 
+		recordProviderRun(parentID, name, strProcessID, "", provider.ChecksumStatus(), startedAt, 0, err)
+		notifyRunCompleted(ctx, config.GetConfig(), repo, name, strProcessID, startedAt, 0, err)
 		errChan <- err
 		return
 	}
-	span.AddEvent("data fetched successfully")
+	fetchSpan.End()
+	// Streamed sources hold their connection open until parsed.
+	if c, ok := reader.(io.Closer); ok {
+		defer c.Close()
+	}
 
-	// Handle metadata if present
-	if meta != nil {
-		strProcessID = meta.ProcessID
+	// A reused stored response keeps this run's process ID; only the snapshot
+	// it came from is recorded. It was verified, if at all, by that run.
+	snapshotID := ""
+	checksum := provider.ChecksumStatus()
+	if meta != nil && meta.SnapshotID != strProcessID {
+		snapshotID = meta.SnapshotID
+		checksum = ""
 		providerLogger.Info().
-			Str("new_process_id", strProcessID).
-			Msg("Found metadata, changing process ID")
-		provider.SetProcessID(uuid.MustParse(strProcessID))
+			Str("snapshot_id", snapshotID).
+			Msg("Parsing stored response from an earlier run")
+		recordEvent(ctx, name, strProcessID, "fetch", "info", "Reusing stored response from an earlier run", map[string]any{"snapshot_id": snapshotID})
+	} else {
+		fields := map[string]any{"source": source}
+		if checksum != "" {
+			fields["checksum"] = checksum
+		}
+		if attempts := provider.ChecksumAttempts(); len(attempts) > 1 {
+			fields["checksum_attempts"] = attempts
+		}
+		recordEvent(ctx, name, strProcessID, "fetch", "info", "Fetched source", fields)
 	}
 
 	// Set the repository for the provider
 	provider.SetRepository(repo)
 
+	// Batches saved while parsing are traced under the parse span
+	parseCtx, parseSpan := tracer.Start(ctx, "provider.parse")
+
 	// Start tracking provider metrics in the pond collector
-	pondCollector.StartProviderProcessing(name, strProcessID)
+	pondCollector.StartProviderProcessing(parseCtx, name, strProcessID)
 
 	// Parse the data - this delegates to the provider's implementation
-	span.AddEvent("parsing provider data")
 	if err := provider.Parse(reader); err != nil {
-		span.RecordError(err)
+		parseSpan.RecordError(err)
+		parseSpan.SetStatus(codes.Error, "failed to parse data")
+		parseSpan.End()
 		span.SetStatus(codes.Error, "failed to parse data")
 		providerLogger.
 			Err(err).
@@ -283,7 +385,8 @@ func (p Providers) processProvider(
 			Msg("Error parsing data")
 
 		// Finish tracking in the collector
-		pondCollector.FinishProviderProcessing(name, strProcessID)
+		entriesProcessed, _, _ := pondCollector.FinishProviderProcessing(name, strProcessID)
+		recordEvent(ctx, name, strProcessID, "parse", "error", err.Error(), map[string]any{"entries_processed": entriesProcessed})
 
 		// Update Prometheus metrics on failure
 		if trackMetrics {
@@ -293,17 +396,82 @@ func (p Providers) processProvider(
 			}
 		}
 
+		recordProviderRun(parentID, name, strProcessID, snapshotID, checksum, startedAt, 0, err)
+		notifyRunCompleted(ctx, config.GetConfig(), repo, name, strProcessID, startedAt, 0, err)
 		errChan <- err
 		return
 	}
-	span.AddEvent("parsing completed")
 
-	// Finish tracking provider metrics in the pond collector
+	// Finish tracking provider metrics in the pond collector; this waits for
+	// the last batches, so the parse span covers their saves.
 	entriesProcessed, processingTime, _ := pondCollector.FinishProviderProcessing(name, strProcessID)
-	span.AddEvent("provider processing finished")
+	parseSpan.SetAttributes(attribute.Int("entries_processed", entriesProcessed))
+	parseSpan.End()
+	recordEvent(ctx, name, strProcessID, "parse", "info", "Parsed source", map[string]any{
+		"entries_processed": entriesProcessed,
+		"duration_ms":       processingTime.Milliseconds(),
+	})
 
-	// Cleanup if needed
 	cfg := config.GetConfig()
+
+	// Delta ingestion removes the entries gone from the feed once every
+	// batch is written; a failed commit leaves them for the next run.
+	if cfg.CollectorSettingsFor(name).DeltaIngest {
+		report, err := pondCollector.CommitDelta(ctx, name, strProcessID)
+		if err != nil {
+			providerLogger.Err(err).Msg("Failed to commit delta ingestion")
+			recordEvent(ctx, name, strProcessID, "delta", "warn", err.Error(), nil)
+		} else {
+			span.SetAttributes(
+				attribute.Int("delta.unchanged", report.Unchanged),
+				attribute.Int("delta.removed", report.Removed),
+			)
+			recordEvent(ctx, name, strProcessID, "delta", "info", "Committed delta ingestion", map[string]any{
+				"unchanged": report.Unchanged,
+				"removed":   report.Removed,
+			})
+		}
+	}
+	recordProviderRun(parentID, name, strProcessID, snapshotID, checksum, startedAt, entriesProcessed, nil)
+
+	// Check the run's additions against protected patterns in the background;
+	// entries are flushed by now, and a slow webhook must not hold the run.
+	if alerts.Enabled(cfg.Alerts) {
+		alertCtx := context.WithoutCancel(ctx)
+		notifications.Go(func() {
+			if err := alerts.NotifyProviderAdditions(alertCtx, cfg.Alerts, repo, name, strProcessID, startedAt); err != nil {
+				providerLogger.Err(err).Msg("Failed to raise protected entries alert")
+			}
+		})
+	}
+
+	// Announce the hosts first seen on a watchlist the same way, when any
+	// watchlist is registered.
+	if wl := watchlist.Get(); wl != nil {
+		if active, err := wl.Active(ctx); err != nil {
+			providerLogger.Err(err).Msg("Failed to list watchlists")
+		} else if active {
+			watchCtx := context.WithoutCancel(ctx)
+			notifications.Go(func() {
+				if err := wl.NotifyProviderAdditions(watchCtx, cfg.Watchlist, repo, name, strProcessID, startedAt); err != nil {
+					providerLogger.Err(err).Msg("Failed to raise watchlist events")
+				}
+			})
+		}
+	}
+
+	notifyRunCompleted(ctx, cfg, repo, name, strProcessID, startedAt, entriesProcessed, nil)
+
+	// Only a response fetched and imported by this run is skipped by the
+	// next one when unchanged; a reused stored response says nothing of the
+	// source now.
+	if snapshotID == "" {
+		if err := utils.SaveValidators(name, provider.FetchedValidators()); err != nil {
+			providerLogger.Warn().Err(err).Msg("Failed to save fetch validators")
+		}
+	}
+
+	// Cleanup if needed
 	if cfg.APP.Environment == "development" {
 		utils.RemoveStoredResponse(name)
 	}
@@ -327,4 +495,148 @@ func (p Providers) processProvider(
 		Int("entries_processed", entriesProcessed).
 		Float64("entries_per_second", entriesPerSecond).
 		Msg("Finished processing provider")
+	recordEvent(ctx, name, strProcessID, "finish", "info", "Finished processing provider", map[string]any{
+		"entries_processed":  entriesProcessed,
+		"entries_per_second": entriesPerSecond,
+		"duration_ms":        time.Since(startedAt).Milliseconds(),
+	})
+}
+
+// finishNotModified completes the run of a provider whose source answered
+// 304 Not Modified. Its entries are still listed, so their expiry is
+// restarted as delta ingestion does for unchanged entries, and nothing is
+// parsed or removed.
+func finishNotModified(ctx context.Context, provider base.Provider, repo repository.BlacklistRepository, trackMetrics bool, parentID, processID string, startedAt time.Time) {
+	name := provider.GetName()
+	cfg := config.GetConfig()
+
+	log.Info().
+		Str("process_id", processID).
+		Str("provider", name).
+		Msg("Source not modified since the last import, skipping parse")
+	recordEvent(ctx, name, processID, "fetch", "info", "Source not modified since the last import", map[string]any{"source": provider.Source()})
+
+	if ttl := cfg.CollectorSettingsFor(name).TTL; ttl > 0 {
+		expiresAt := time.Now().Add(ttl).UnixNano()
+		if _, err := repo.SetSourceExpiry(ctx, name, &expiresAt); err != nil {
+			log.Warn().Err(err).Str("provider", name).Msg("Failed to restart the expiry of unchanged entries")
+		}
+	}
+
+	GetProcessManager().RecordProviderRun(ProviderRun{
+		Provider:    name,
+		ProcessID:   processID,
+		ParentID:    parentID,
+		Status:      "completed",
+		NotModified: true,
+		StartTime:   startedAt,
+		EndTime:     time.Now(),
+	})
+	notifyRunCompleted(ctx, cfg, repo, name, processID, startedAt, 0, nil)
+
+	if trackMetrics {
+		if mc, _ := collector.GetMetricsCollector(); mc != nil {
+			mc.SetSyncSuccess(name, time.Since(startedAt))
+		}
+	}
+
+	recordEvent(ctx, name, processID, "finish", "info", "Finished processing provider", map[string]any{
+		"entries_processed": 0,
+		"duration_ms":       time.Since(startedAt).Milliseconds(),
+	})
+}
+
+// recordEvent stores an event of the provider run processID, to be read at
+// GET /provider/processes/:processID/events.
+func recordEvent(ctx context.Context, provider, processID, stage, level, message string, fields map[string]any) {
+	GetProcessManager().RecordEvent(ctx, &ProcessEvent{
+		ProcessID: processID,
+		Provider:  provider,
+		Stage:     stage,
+		Level:     level,
+		Message:   message,
+		Fields:    fields,
+	})
+}
+
+// notifyRunCompleted announces the finished run in the background, like
+// alerts: a sync.completed webhook and, after a successful run, the
+// entries.added webhooks, plus sync.completed (and, after a failure,
+// provider.failed) events on the event bus.
+func notifyRunCompleted(ctx context.Context, cfg *config.Config, repo webhooks.EntryStreamer, name, processID string, startedAt time.Time, entriesProcessed int, runErr error) {
+	bus := eventbus.Get()
+	hooks := cfg != nil && webhooks.Enabled(cfg.Webhooks)
+	if bus == nil && !hooks {
+		return
+	}
+
+	finishedAt := time.Now()
+	sc := eventbus.SyncCompleted{
+		Provider:         name,
+		ProcessID:        processID,
+		Status:           "completed",
+		EntriesProcessed: entriesProcessed,
+		DurationMS:       finishedAt.Sub(startedAt).Milliseconds(),
+		StartedAt:        startedAt.UTC(),
+		FinishedAt:       finishedAt.UTC(),
+	}
+	if runErr != nil {
+		sc.Status = "failed"
+		sc.Error = runErr.Error()
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	notifications.Go(func() {
+		if bus != nil {
+			if err := bus.PublishSync(ctx, sc); err != nil {
+				log.Err(err).Str("provider", name).Str("process_id", processID).Msg("Failed to publish sync event")
+			}
+		}
+		if !hooks {
+			return
+		}
+
+		n := webhooks.NewNotifier(cfg.Webhooks)
+		ev := webhooks.SyncEvent{
+			Provider:         sc.Provider,
+			ProcessID:        sc.ProcessID,
+			Status:           sc.Status,
+			Error:            sc.Error,
+			EntriesProcessed: sc.EntriesProcessed,
+			DurationMS:       sc.DurationMS,
+			StartedAt:        sc.StartedAt,
+			FinishedAt:       sc.FinishedAt,
+		}
+		if err := n.NotifySyncCompleted(ctx, ev); err != nil {
+			log.Err(err).Str("provider", name).Str("process_id", processID).Msg("Failed to send sync webhook")
+		}
+		if runErr != nil {
+			return
+		}
+		if err := n.NotifyEntriesAdded(ctx, repo, name, processID, startedAt); err != nil {
+			log.Err(err).Str("provider", name).Str("process_id", processID).Msg("Failed to send new entries webhook")
+		}
+	})
+}
+
+// recordProviderRun stores the run interval in the process manager's run history.
+// snapshotID names the run whose stored response was parsed, if not this one,
+// and checksum the outcome of the source's checksum verification.
+func recordProviderRun(parentID, name, processID, snapshotID, checksum string, startedAt time.Time, entriesProcessed int, err error) {
+	run := ProviderRun{
+		Provider:   name,
+		ProcessID:  processID,
+		ParentID:   parentID,
+		SnapshotID: snapshotID,
+		Checksum:   checksum,
+		Status:     "completed",
+		StartTime:  startedAt,
+		EndTime:    time.Now(),
+		Entries:    entriesProcessed,
+	}
+	if err != nil {
+		run.Status = "failed"
+		run.Error = err.Error()
+	}
+	GetProcessManager().RecordProviderRun(run)
 }
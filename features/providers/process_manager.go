@@ -24,6 +24,11 @@ type ProcessPersistence interface {
 	UpdateProcessStatus(ctx context.Context, status *ProcessStatus) error
 }
 
+// ProcessEventStore stores the structured events of provider runs.
+type ProcessEventStore interface {
+	InsertProcessEvent(ctx context.Context, event *ProcessEvent) error
+}
+
 // ProcessManager handles centralized process state management.
 // It ensures only one provider processing job can run at a time,
 // whether triggered by startup, cron scheduler, or API endpoint.
@@ -34,7 +39,10 @@ type ProcessManager struct {
 	isRunning      atomic.Bool
 	history        []*ProcessStatus
 	maxHistory     int
+	providerRuns   []ProviderRun // per-provider run intervals, oldest first
+	maxRuns        int
 	persistence    ProcessPersistence // optional DB persistence
+	events         ProcessEventStore  // optional run event storage
 }
 
 var (
@@ -48,6 +56,7 @@ func GetProcessManager() *ProcessManager {
 		globalProcessManager = &ProcessManager{
 			maxHistory: 100, // Keep last 100 process records in memory
 			history:    make([]*ProcessStatus, 0, 100),
+			maxRuns:    1000, // Keep last 1000 provider runs in memory
 		}
 	})
 	return globalProcessManager
@@ -62,10 +71,37 @@ func (pm *ProcessManager) SetPersistence(persistence ProcessPersistence) {
 	log.Info().Msg("Provider process manager DB persistence enabled")
 }
 
+// SetEventStore attaches the store provider run events are written to.
+// Without one, events are only logged.
+func (pm *ProcessManager) SetEventStore(store ProcessEventStore) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.events = store
+}
+
+// RecordEvent stores a provider run event. A failed write is logged and
+// never fails the run.
+func (pm *ProcessManager) RecordEvent(ctx context.Context, event *ProcessEvent) {
+	pm.mu.RLock()
+	store := pm.events
+	pm.mu.RUnlock()
+	if store == nil {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if err := store.InsertProcessEvent(context.WithoutCancel(ctx), event); err != nil {
+		log.Warn().Err(err).Str("process_id", event.ProcessID).Str("stage", event.Stage).Msg("Failed to store process event")
+	}
+}
+
 // TryStartProcess attempts to start a new process.
 // Returns the process ID if successful, or an error if a process is already running.
+// groups are the provider groups providersToProcess was expanded from.
 // Persists to DB if persistence is configured.
-func (pm *ProcessManager) TryStartProcess(ctx context.Context, source string, providersToProcess, providersToRemove []string) (string, error) {
+func (pm *ProcessManager) TryStartProcess(ctx context.Context, source string, providersToProcess, providersToRemove, groups []string) (string, error) {
 	// Fast path: check if already running without lock
 	if pm.isRunning.Load() {
 		return "", ErrProcessAlreadyRunning
@@ -87,6 +123,7 @@ func (pm *ProcessManager) TryStartProcess(ctx context.Context, source string, pr
 		StartTime:          time.Now(),
 		ProvidersProcessed: providersToProcess,
 		ProvidersRemoved:   providersToRemove,
+		Groups:             groups,
 	}
 	pm.isRunning.Store(true)
 
@@ -101,6 +138,7 @@ func (pm *ProcessManager) TryStartProcess(ctx context.Context, source string, pr
 		Str("process_id", processID).
 		Str("source", source).
 		Strs("providers", providersToProcess).
+		Strs("groups", groups).
 		Msg("Process started")
 
 	return processID, nil
@@ -219,6 +257,49 @@ func (pm *ProcessManager) GetRecentProcesses(limit int) []*ProcessStatus {
 	return result
 }
 
+// RecordProviderRun appends a finished provider run to the in-memory run
+// history, and to the runs of the current process when it is its parent.
+func (pm *ProcessManager) RecordProviderRun(run ProviderRun) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if run.ParentID != "" && pm.currentProcess != nil && pm.currentProcess.ID == run.ParentID {
+		pm.currentProcess.Runs = append(pm.currentProcess.Runs, run)
+	}
+	pm.providerRuns = append(pm.providerRuns, run)
+	if len(pm.providerRuns) > pm.maxRuns {
+		pm.providerRuns = pm.providerRuns[len(pm.providerRuns)-pm.maxRuns:]
+	}
+}
+
+// ProcessRuns returns the provider runs of the process processID, oldest first.
+func (pm *ProcessManager) ProcessRuns(processID string) []ProviderRun {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	var result []ProviderRun
+	for _, run := range pm.providerRuns {
+		if run.ParentID == processID {
+			result = append(result, run)
+		}
+	}
+	return result
+}
+
+// GetProviderRuns returns provider runs that ended at or after since, oldest first.
+func (pm *ProcessManager) GetProviderRuns(since time.Time) []ProviderRun {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	result := make([]ProviderRun, 0)
+	for _, run := range pm.providerRuns {
+		if !run.EndTime.Before(since) {
+			result = append(result, run)
+		}
+	}
+	return result
+}
+
 // ResetForTesting resets the process manager state (for tests only)
 func (pm *ProcessManager) ResetForTesting() {
 	pm.mu.Lock()
@@ -227,4 +308,5 @@ func (pm *ProcessManager) ResetForTesting() {
 	pm.currentProcess = nil
 	pm.isRunning.Store(false)
 	pm.history = make([]*ProcessStatus, 0, pm.maxHistory)
+	pm.providerRuns = nil
 }
@@ -6,7 +6,9 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-func (p *Providers) Processor(selectedProviders, providersToRemove []string) error {
+// Processor processes the selected providers, or all of them, as the
+// provider runs of the process parentID.
+func (p *Providers) Processor(parentID string, selectedProviders, providersToRemove []string) error {
 	ctx := context.Background()
 
 	if err := p.RemoveProviders(providersToRemove); err != nil {
@@ -32,6 +34,7 @@ func (p *Providers) Processor(selectedProviders, providersToRemove []string) err
 	return providersToProcess.Process(ctx, ProcessOptions{
 		UpdateCacheMode: UpdateCacheImmediate,
 		TrackMetrics:    true,
+		ParentID:        parentID,
 	})
 }
 
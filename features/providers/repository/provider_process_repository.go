@@ -13,4 +13,9 @@ type ProviderProcessRepository interface {
 	GetProcessByID(ctx context.Context, processID string) (*providers.ProcessStatus, error)
 	ListProcesses(ctx context.Context) ([]*providers.ProcessStatus, error)
 	IsProcessRunning(ctx context.Context, processDeadlineDuration time.Duration) (bool, error)
+
+	InsertProcessEvent(ctx context.Context, event *providers.ProcessEvent) error
+	ListProcessEvents(ctx context.Context, processID string, filter providers.ProcessEventFilter, afterID int64, limit int) ([]*providers.ProcessEvent, error)
+	CountProcessEvents(ctx context.Context, processID string, filter providers.ProcessEventFilter) (int, error)
+	PruneProcessEvents(ctx context.Context, cutoff time.Time) (int64, error)
 }
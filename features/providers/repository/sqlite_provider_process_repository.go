@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -19,6 +20,9 @@ var (
 	ErrScanProcess         = errors.New("failed to scan process row")
 	ErrIterateProcessRows  = errors.New("error iterating process rows")
 	ErrFetchProcessRunning = errors.New("failed to fetch start time of running process")
+	ErrInsertProcessEvent  = errors.New("failed to insert process event")
+	ErrQueryProcessEvents  = errors.New("failed to query process events")
+	ErrPruneProcessEvents  = errors.New("failed to prune process events")
 )
 
 // SQLiteProviderProcessRepository is the concrete implementation of ProviderProcessRepository using SQLite.
@@ -31,15 +35,19 @@ func NewSQLiteProviderProcessRepository(db *sql.DB) *SQLiteProviderProcessReposi
 	return &SQLiteProviderProcessRepository{db: db}
 }
 
+// processColumns are the provider_processes columns scanProcess reads.
+const processColumns = "id, status, start_time, end_time, providers_processed, providers_removed, error, group_names, runs"
+
 func (r *SQLiteProviderProcessRepository) InsertProcess(ctx context.Context, status *providers.ProcessStatus) error {
 	providersProcessedJSON, _ := json.Marshal(status.ProvidersProcessed)
 	providersRemovedJSON, _ := json.Marshal(status.ProvidersRemoved)
 
 	_, err := r.db.ExecContext(ctx, `
 		INSERT INTO provider_processes (
-			id, status, start_time, end_time, providers_processed, providers_removed, error
-		) VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, status.ID, status.Status, status.StartTime, status.EndTime, providersProcessedJSON, providersRemovedJSON, status.Error)
+			id, status, start_time, end_time, providers_processed, providers_removed, error, group_names, runs
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, status.ID, status.Status, status.StartTime, status.EndTime, providersProcessedJSON, providersRemovedJSON, status.Error,
+		nullJSON(status.Groups), nullJSON(status.Runs))
 	if err != nil {
 		return ErrInsertProcess
 	}
@@ -52,9 +60,10 @@ func (r *SQLiteProviderProcessRepository) UpdateProcessStatus(ctx context.Contex
 
 	_, err := r.db.ExecContext(ctx, `
 		UPDATE provider_processes
-		SET status = ?, end_time = ?, providers_processed = ?, providers_removed = ?, error = ?
+		SET status = ?, end_time = ?, providers_processed = ?, providers_removed = ?, error = ?, group_names = ?, runs = ?
 		WHERE id = ?
-	`, status.Status, status.EndTime, providersProcessedJSON, providersRemovedJSON, status.Error, status.ID)
+	`, status.Status, status.EndTime, providersProcessedJSON, providersRemovedJSON, status.Error,
+		nullJSON(status.Groups), nullJSON(status.Runs), status.ID)
 	if err != nil {
 		return ErrUpdateProcess
 	}
@@ -62,26 +71,12 @@ func (r *SQLiteProviderProcessRepository) UpdateProcessStatus(ctx context.Contex
 }
 
 func (r *SQLiteProviderProcessRepository) GetProcessByID(ctx context.Context, processID string) (*providers.ProcessStatus, error) {
-	row := r.db.QueryRowContext(ctx, "SELECT * FROM provider_processes WHERE id = ?", processID)
-	status := &providers.ProcessStatus{}
-	var providersProcessedJSON []byte
-	var providersRemovedJSON []byte
-
-	err := row.Scan(
-		&status.ID, &status.Status, &status.StartTime, &status.EndTime, &providersProcessedJSON, &providersRemovedJSON, &status.Error,
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	_ = json.Unmarshal(providersProcessedJSON, &status.ProvidersProcessed)
-	_ = json.Unmarshal(providersRemovedJSON, &status.ProvidersRemoved)
-
-	return status, nil
+	row := r.db.QueryRowContext(ctx, "SELECT "+processColumns+" FROM provider_processes WHERE id = ?", processID)
+	return scanProcess(row)
 }
 
 func (r *SQLiteProviderProcessRepository) ListProcesses(ctx context.Context) ([]*providers.ProcessStatus, error) {
-	rows, err := r.db.QueryContext(ctx, "SELECT * FROM provider_processes ORDER BY start_time DESC")
+	rows, err := r.db.QueryContext(ctx, "SELECT "+processColumns+" FROM provider_processes ORDER BY start_time DESC")
 	if err != nil {
 		return nil, ErrQueryProcesses
 	}
@@ -89,25 +84,56 @@ func (r *SQLiteProviderProcessRepository) ListProcesses(ctx context.Context) ([]
 
 	var statuses []*providers.ProcessStatus
 	for rows.Next() {
-		status := &providers.ProcessStatus{}
-		var providersProcessedJSON []byte
-		var providersRemovedJSON []byte
-		err := rows.Scan(
-			&status.ID, &status.Status, &status.StartTime, &status.EndTime, &providersProcessedJSON, &providersRemovedJSON, &status.Error,
-		)
+		status, err := scanProcess(rows)
 		if err != nil {
+			log.Err(err).Msg("Failed to scan process row")
 			return nil, ErrScanProcess
 		}
-		_ = json.Unmarshal(providersProcessedJSON, &status.ProvidersProcessed)
-		_ = json.Unmarshal(providersRemovedJSON, &status.ProvidersRemoved)
 		statuses = append(statuses, status)
 	}
 	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Rows iteration error for processes")
 		return nil, ErrIterateProcessRows
 	}
 	return statuses, nil
 }
 
+// scanProcess reads a row of processColumns.
+func scanProcess(row interface{ Scan(...any) error }) (*providers.ProcessStatus, error) {
+	status := &providers.ProcessStatus{}
+	var providersProcessedJSON []byte
+	var providersRemovedJSON []byte
+	var groupsJSON, runsJSON sql.NullString
+
+	err := row.Scan(
+		&status.ID, &status.Status, &status.StartTime, &status.EndTime, &providersProcessedJSON, &providersRemovedJSON, &status.Error,
+		&groupsJSON, &runsJSON,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = json.Unmarshal(providersProcessedJSON, &status.ProvidersProcessed)
+	_ = json.Unmarshal(providersRemovedJSON, &status.ProvidersRemoved)
+	if groupsJSON.Valid {
+		_ = json.Unmarshal([]byte(groupsJSON.String), &status.Groups)
+	}
+	if runsJSON.Valid {
+		_ = json.Unmarshal([]byte(runsJSON.String), &status.Runs)
+	}
+
+	return status, nil
+}
+
+// nullJSON encodes a list column, NULL when the list is empty.
+func nullJSON[T any](values []T) sql.NullString {
+	if len(values) == 0 {
+		return sql.NullString{}
+	}
+	data, _ := json.Marshal(values)
+	return sql.NullString{String: string(data), Valid: true}
+}
+
 func (r *SQLiteProviderProcessRepository) IsProcessRunning(ctx context.Context, processDeadlineDuration time.Duration) (bool, error) {
 	var startTime time.Time
 	err := r.db.QueryRowContext(ctx, `
@@ -133,3 +159,112 @@ func (r *SQLiteProviderProcessRepository) IsProcessRunning(ctx context.Context,
 
 	return deadline.After(time.Now()), nil // Check if deadline is in the future
 }
+
+func (r *SQLiteProviderProcessRepository) InsertProcessEvent(ctx context.Context, event *providers.ProcessEvent) error {
+	var fields sql.NullString // Stored as text, so searches can match it
+	if len(event.Fields) > 0 {
+		b, _ := json.Marshal(event.Fields)
+		fields = sql.NullString{String: string(b), Valid: true}
+	}
+
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO process_events (process_id, provider, stage, level, message, fields, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, event.ProcessID, event.Provider, event.Stage, event.Level, event.Message, fields, event.Time.UnixNano())
+	if err != nil {
+		log.Err(err).Str("process_id", event.ProcessID).Msg("Failed to insert process event")
+		return ErrInsertProcessEvent
+	}
+	event.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// processEventWhere returns the WHERE clause and arguments selecting the
+// events of processID matching filter.
+func processEventWhere(processID string, filter providers.ProcessEventFilter) (string, []any) {
+	where := "process_id = ?"
+	args := []any{processID}
+	if filter.Stage != "" {
+		where += " AND stage = ?"
+		args = append(args, filter.Stage)
+	}
+	if filter.Level != "" {
+		where += " AND level = ?"
+		args = append(args, filter.Level)
+	}
+	if filter.Search != "" {
+		// LIKE is case-insensitive for ASCII; escape its wildcards.
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(filter.Search) + "%"
+		where += ` AND (message LIKE ? ESCAPE '\' OR fields LIKE ? ESCAPE '\')`
+		args = append(args, pattern, pattern)
+	}
+	return where, args
+}
+
+// ListProcessEvents returns up to limit events of processID matching filter
+// after afterID, oldest first.
+func (r *SQLiteProviderProcessRepository) ListProcessEvents(ctx context.Context, processID string, filter providers.ProcessEventFilter, afterID int64, limit int) ([]*providers.ProcessEvent, error) {
+	where, args := processEventWhere(processID, filter)
+	if afterID > 0 {
+		where += " AND id > ?"
+		args = append(args, afterID)
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, process_id, provider, stage, level, message, fields, created_at
+		FROM process_events
+		WHERE `+where+`
+		ORDER BY id
+		LIMIT ?`, args...)
+	if err != nil {
+		log.Err(err).Str("process_id", processID).Msg("Failed to query process events")
+		return nil, ErrQueryProcessEvents
+	}
+	defer rows.Close()
+
+	events := make([]*providers.ProcessEvent, 0)
+	for rows.Next() {
+		event := &providers.ProcessEvent{}
+		var provider sql.NullString
+		var fields []byte
+		var createdAt int64
+		if err := rows.Scan(&event.ID, &event.ProcessID, &provider, &event.Stage, &event.Level, &event.Message, &fields, &createdAt); err != nil {
+			log.Err(err).Str("process_id", processID).Msg("Failed to scan process event")
+			return nil, ErrScanProcess
+		}
+		event.Provider = provider.String
+		event.Time = time.Unix(0, createdAt).UTC()
+		if len(fields) > 0 {
+			_ = json.Unmarshal(fields, &event.Fields)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Str("process_id", processID).Msg("Rows iteration error for process events")
+		return nil, ErrIterateProcessRows
+	}
+	return events, nil
+}
+
+// CountProcessEvents returns the number of events of processID matching filter.
+func (r *SQLiteProviderProcessRepository) CountProcessEvents(ctx context.Context, processID string, filter providers.ProcessEventFilter) (int, error) {
+	where, args := processEventWhere(processID, filter)
+	var n int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM process_events WHERE "+where, args...).Scan(&n); err != nil {
+		log.Err(err).Str("process_id", processID).Msg("Failed to count process events")
+		return 0, ErrQueryProcessEvents
+	}
+	return n, nil
+}
+
+// PruneProcessEvents deletes the events recorded before cutoff.
+func (r *SQLiteProviderProcessRepository) PruneProcessEvents(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM process_events WHERE created_at < ?", cutoff.UnixNano())
+	if err != nil {
+		log.Err(err).Time("cutoff", cutoff).Msg("Failed to prune process events")
+		return 0, ErrPruneProcessEvents
+	}
+	deleted, _ := res.RowsAffected()
+	return deleted, nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"blacked/features/providers"
+	idb "blacked/internal/db"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessGroupRuns(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := NewSQLiteProviderProcessRepository(conn)
+	started := time.Now().UTC().Truncate(time.Second)
+	status := &providers.ProcessStatus{
+		ID:                 "group-run",
+		Status:             "running",
+		StartTime:          started,
+		ProvidersProcessed: []string{"openphish", "phishtank-online-valid"},
+		Groups:             []string{"phishing"},
+	}
+	require.NoError(t, repo.InsertProcess(ctx, status))
+
+	status.Status = "completed"
+	status.EndTime = started.Add(time.Minute)
+	status.Runs = []providers.ProviderRun{
+		{Provider: "openphish", ProcessID: "run-1", ParentID: "group-run", Status: "completed", Entries: 10},
+		{Provider: "phishtank-online-valid", ProcessID: "run-2", ParentID: "group-run", Status: "failed", Error: "fetch failed"},
+	}
+	require.NoError(t, repo.UpdateProcessStatus(ctx, status))
+
+	got, err := repo.GetProcessByID(ctx, "group-run")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"phishing"}, got.Groups)
+	require.Len(t, got.Runs, 2)
+	assert.Equal(t, "openphish", got.Runs[0].Provider)
+	assert.Equal(t, "fetch failed", got.Runs[1].Error)
+
+	require.NoError(t, repo.InsertProcess(ctx, &providers.ProcessStatus{ID: "plain-run", Status: "completed", StartTime: started.Add(-time.Hour)}))
+	list, err := repo.ListProcesses(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "group-run", list[0].ID, "newest first")
+	assert.Empty(t, list[1].Groups)
+	assert.Empty(t, list[1].Runs)
+}
+
+func TestProcessEvents(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := NewSQLiteProviderProcessRepository(conn)
+	pm := providers.GetProcessManager()
+	pm.SetEventStore(repo)
+	defer pm.SetEventStore(nil)
+
+	for _, e := range []*providers.ProcessEvent{
+		{ProcessID: "run-1", Provider: "feed", Stage: "start", Level: "info", Message: "Processing provider", Fields: map[string]any{"source": "https://feed.example/list.txt"}},
+		{ProcessID: "run-1", Provider: "feed", Stage: "fetch", Level: "error", Message: "failed to fetch: 503 Service Unavailable"},
+		{ProcessID: "run-2", Provider: "other", Stage: "parse", Level: "info", Message: "Parsed source", Fields: map[string]any{"entries_processed": 42}},
+	} {
+		pm.RecordEvent(ctx, e)
+	}
+
+	events, err := repo.ListProcessEvents(ctx, "run-1", providers.ProcessEventFilter{}, 0, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "start", events[0].Stage, "oldest first")
+	assert.Equal(t, "https://feed.example/list.txt", events[0].Fields["source"])
+	assert.False(t, events[0].Time.IsZero())
+
+	events, err = repo.ListProcessEvents(ctx, "run-1", providers.ProcessEventFilter{Level: "error"}, 0, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "fetch", events[0].Stage)
+
+	events, err = repo.ListProcessEvents(ctx, "run-1", providers.ProcessEventFilter{Search: "FEED.example"}, 0, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 1, "search covers the fields, case-insensitively")
+	assert.Equal(t, "start", events[0].Stage)
+
+	events, err = repo.ListProcessEvents(ctx, "run-1", providers.ProcessEventFilter{Search: "50%"}, 0, 100)
+	require.NoError(t, err)
+	assert.Empty(t, events, "LIKE wildcards in the search are literal")
+
+	events, err = repo.ListProcessEvents(ctx, "run-2", providers.ProcessEventFilter{Stage: "parse"}, 0, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.EqualValues(t, 42, events[0].Fields["entries_processed"])
+
+	events, err = repo.ListProcessEvents(ctx, "unknown", providers.ProcessEventFilter{}, 0, 100)
+	require.NoError(t, err)
+	assert.NotNil(t, events)
+	assert.Empty(t, events)
+
+	page, err := repo.ListProcessEvents(ctx, "run-1", providers.ProcessEventFilter{}, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	events, err = repo.ListProcessEvents(ctx, "run-1", providers.ProcessEventFilter{}, page[0].ID, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "fetch", events[0].Stage, "events after the cursor")
+
+	n, err := repo.CountProcessEvents(ctx, "run-1", providers.ProcessEventFilter{Search: "feed"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	_, err = conn.Exec("UPDATE process_events SET created_at = ? WHERE process_id = 'run-2'", time.Now().Add(-48*time.Hour).UnixNano())
+	require.NoError(t, err)
+	deleted, err := repo.PruneProcessEvents(ctx, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	n, err = repo.CountProcessEvents(ctx, "run-2", providers.ProcessEventFilter{})
+	require.NoError(t, err)
+	assert.Zero(t, n, "events older than the cutoff are pruned")
+	n, err = repo.CountProcessEvents(ctx, "run-1", providers.ProcessEventFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
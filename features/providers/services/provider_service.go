@@ -3,10 +3,14 @@ package services
 import (
 	"blacked/features/providers"
 	"blacked/features/providers/repository"
+	"blacked/internal/config"
 	"blacked/internal/db"
+	"blacked/internal/pagination"
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -21,6 +25,7 @@ var (
 	ErrGetProcessStatus    = errors.New("failed to get process status")
 	ErrListProcesses       = errors.New("failed to list processes")
 	ErrCheckRunningProcess = errors.New("failed to check for running processes")
+	ErrListProcessEvents   = errors.New("failed to list process events")
 )
 
 type ProviderProcessService struct {
@@ -49,7 +54,8 @@ func NewProviderProcessService() (*ProviderProcessService, error) {
 func (s *ProviderProcessService) StartProcess(ctx context.Context, providersToProcess []string, providersToRemove []string) (processID string, err error) {
 	// Use the centralized process manager to check and acquire lock
 	pm := providers.GetProcessManager()
-	processIDStr, err := pm.TryStartProcess(ctx, "api", providersToProcess, providersToRemove)
+	providersToProcess, groups := config.GetConfig().ExpandProviderGroups(providersToProcess)
+	processIDStr, err := pm.TryStartProcess(ctx, "api", providersToProcess, providersToRemove, groups)
 	if err != nil {
 		if err == providers.ErrProcessAlreadyRunning {
 			log.Info().Msg("Another process is already running")
@@ -65,6 +71,7 @@ func (s *ProviderProcessService) StartProcess(ctx context.Context, providersToPr
 		StartTime:          time.Now(),
 		ProvidersProcessed: providersToProcess,
 		ProvidersRemoved:   providersToRemove,
+		Groups:             groups,
 	}
 
 	// Also persist to database for historical records
@@ -82,7 +89,8 @@ func (s *ProviderProcessService) StartProcess(ctx context.Context, providersToPr
 			pm.FinishProcess(processIDStr, processErr)
 		}()
 
-		processErr = providers.GetProviders().Processor(providersToProcess, providersToRemove)
+		processErr = providers.GetProviders().Processor(processIDStr, providersToProcess, providersToRemove)
+		status.Runs = pm.ProcessRuns(processIDStr)
 		if processErr != nil {
 			status.Status = "failed"
 			status.EndTime = time.Now()
@@ -104,7 +112,8 @@ func (s *ProviderProcessService) StartProcess(ctx context.Context, providersToPr
 func (s *ProviderProcessService) StartProcessAsync(ctx context.Context, providersToProcess []string, providersToRemove []string) (processID string, err error) {
 	// Use the centralized process manager to check and acquire lock
 	pm := providers.GetProcessManager()
-	processIDStr, err := pm.TryStartProcess(ctx, "api-sync", providersToProcess, providersToRemove)
+	providersToProcess, groups := config.GetConfig().ExpandProviderGroups(providersToProcess)
+	processIDStr, err := pm.TryStartProcess(ctx, "api-sync", providersToProcess, providersToRemove, groups)
 	if err != nil {
 		if err == providers.ErrProcessAlreadyRunning {
 			log.Info().Msg("Another process is already running")
@@ -120,6 +129,7 @@ func (s *ProviderProcessService) StartProcessAsync(ctx context.Context, provider
 		StartTime:          time.Now(),
 		ProvidersProcessed: providersToProcess,
 		ProvidersRemoved:   providersToRemove,
+		Groups:             groups,
 	}
 
 	if err := s.repo.InsertProcess(ctx, status); err != nil {
@@ -130,7 +140,8 @@ func (s *ProviderProcessService) StartProcessAsync(ctx context.Context, provider
 
 	// Get the providers and run synchronously (this method blocks)
 	allProviders := providers.GetProviders()
-	processErr := allProviders.Processor(providersToProcess, providersToRemove)
+	processErr := allProviders.Processor(processIDStr, providersToProcess, providersToRemove)
+	status.Runs = pm.ProcessRuns(processIDStr)
 
 	// Finish the process
 	pm.FinishProcess(processIDStr, processErr)
@@ -212,6 +223,35 @@ func (s *ProviderProcessService) ListProcesses(ctx context.Context) ([]*provider
 	return result, nil
 }
 
+// ListProcessEvents returns the page of recorded events of the provider run
+// processID matching filter after the p.After ID, oldest first. The total
+// estimate counts every event matching filter.
+func (s *ProviderProcessService) ListProcessEvents(ctx context.Context, processID string, filter providers.ProcessEventFilter, p pagination.Params) (*pagination.Page[*providers.ProcessEvent], error) {
+	var after int64
+	if p.After != "" {
+		id, err := strconv.ParseInt(p.After, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("%w: pass the next_cursor of the previous page", pagination.ErrInvalidCursor)
+		}
+		after = id
+	}
+
+	// One extra row tells whether another page follows.
+	events, err := s.repo.ListProcessEvents(ctx, processID, filter, after, p.Limit+1)
+	if err != nil {
+		log.Err(err).Str("process_id", processID).Msg("Failed to list process events")
+		return nil, ErrListProcessEvents
+	}
+	total, err := s.repo.CountProcessEvents(ctx, processID, filter)
+	if err != nil {
+		log.Err(err).Str("process_id", processID).Msg("Failed to count process events")
+		return nil, ErrListProcessEvents
+	}
+
+	page := pagination.NewPage(events, p.Limit, total, func(e *providers.ProcessEvent) string { return strconv.FormatInt(e.ID, 10) })
+	return &page, nil
+}
+
 func (s *ProviderProcessService) IsProcessRunning(ctx context.Context) (bool, error) {
 	// Use the centralized process manager for real-time status
 	pm := providers.GetProcessManager()
@@ -4,11 +4,50 @@ import "time"
 
 // ProcessStatus holds the status of a provider processing task.
 type ProcessStatus struct {
-	ID                 string    `json:"id"`
-	Status             string    `json:"status"` // "running", "completed", "failed"
-	StartTime          time.Time `json:"start_time"`
-	EndTime            time.Time `json:"end_time"`
-	ProvidersProcessed []string  `json:"providers_processed,omitempty"`
-	ProvidersRemoved   []string  `json:"providers_removed,omitempty"`
-	Error              string    `json:"error,omitempty"`
+	ID                 string        `json:"id"`
+	Status             string        `json:"status"` // "running", "completed", "failed"
+	StartTime          time.Time     `json:"start_time"`
+	EndTime            time.Time     `json:"end_time"`
+	ProvidersProcessed []string      `json:"providers_processed,omitempty"`
+	ProvidersRemoved   []string      `json:"providers_removed,omitempty"`
+	Groups             []string      `json:"groups,omitempty"` // [provider_groups] the process was started for
+	Runs               []ProviderRun `json:"runs,omitempty"`   // Runs of the providers processed, as they finish
+	Error              string        `json:"error,omitempty"`
+}
+
+// ProviderRun records a single provider's fetch-and-parse run, independent of
+// the process (startup, cron or API) that triggered it. ParentID names that
+// process when it is tracked by the process manager.
+type ProviderRun struct {
+	Provider    string    `json:"provider"`
+	ProcessID   string    `json:"process_id"`
+	ParentID    string    `json:"parent_id,omitempty"`
+	SnapshotID  string    `json:"snapshot_id,omitempty"` // Run that fetched the reused stored response
+	Status      string    `json:"status"`                // "completed", "failed"
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Entries     int       `json:"entries,omitempty"`
+	NotModified bool      `json:"not_modified,omitempty"` // The source answered 304 and was not parsed again
+	Checksum    string    `json:"checksum,omitempty"`     // "verified", "mismatch" or "unavailable" with a checksum URL
+	Error       string    `json:"error,omitempty"`
+}
+
+// ProcessEvent is a structured event of a provider run, such as its fetch
+// result, parse counters or an error, stored with the run's process ID.
+type ProcessEvent struct {
+	ID        int64          `json:"id"`
+	ProcessID string         `json:"process_id"`
+	Provider  string         `json:"provider,omitempty"`
+	Stage     string         `json:"stage"` // "start", "fetch", "parse", "delta", "finish"
+	Level     string         `json:"level"` // "info", "warn", "error"
+	Message   string         `json:"message"`
+	Fields    map[string]any `json:"fields,omitempty"`
+	Time      time.Time      `json:"time"`
+}
+
+// ProcessEventFilter narrows the events of a process; empty fields match all.
+type ProcessEventFilter struct {
+	Stage  string
+	Level  string
+	Search string // Substring of the message or fields, case-insensitive
 }
@@ -0,0 +1,170 @@
+package taxii
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var ErrInvalidEnvelope = errors.New("invalid TAXII envelope or STIX bundle")
+
+// STIX object and observable types the provider reads.
+const (
+	TypeIndicator  = "indicator"
+	TypeURL        = "url"
+	TypeDomainName = "domain-name"
+	TypeIPv4Addr   = "ipv4-addr"
+)
+
+// Envelope is a page of a TAXII 2.1 collection's objects. A plain STIX
+// bundle decodes to an envelope without more pages.
+type Envelope struct {
+	More    bool     `json:"more"`
+	Next    string   `json:"next"`
+	Objects []Object `json:"objects"`
+}
+
+// Object holds the fields of a STIX 2.1 object the provider reads.
+type Object struct {
+	Type           string   `json:"type"`
+	ID             string   `json:"id"`
+	Pattern        string   `json:"pattern"`      // Indicators
+	PatternType    string   `json:"pattern_type"` // Indicators; "stix" when empty
+	Value          string   `json:"value"`        // Observables
+	Confidence     *int     `json:"confidence"`   // 0-100
+	IndicatorTypes []string `json:"indicator_types"`
+	Labels         []string `json:"labels"`
+	Revoked        bool     `json:"revoked"`
+	ValidFrom      string   `json:"valid_from"`
+	ValidUntil     string   `json:"valid_until"`
+	Modified       string   `json:"modified"`
+}
+
+// Indicator is a URL, domain or IPv4 address listed by a STIX object.
+type Indicator struct {
+	Type       string    // TypeURL, TypeDomainName or TypeIPv4Addr
+	Value      string    // URL, domain or address
+	Confidence *float64  // 0-1; nil when the object has none
+	Tags       []string  // indicator_types then labels
+	Date       time.Time // valid_from, else modified; zero when undated
+}
+
+// DecodeEnvelope decodes a TAXII envelope or STIX bundle.
+func DecodeEnvelope(data io.Reader) (*Envelope, error) {
+	var env Envelope
+	if err := json.NewDecoder(data).Decode(&env); err != nil {
+		log.Error().Err(err).Msg("error decoding TAXII JSON")
+		return nil, errors.Join(ErrInvalidEnvelope, err)
+	}
+	return &env, nil
+}
+
+var (
+	// comparison matches the equality comparisons of a STIX pattern on the
+	// observable types the provider reads.
+	comparison = regexp.MustCompile(`(url|domain-name|ipv4-addr):value\s*=\s*'((?:[^'\\]|\\.)*)'`)
+
+	// literal matches a quoted STIX string, so operators can be looked for
+	// outside of them.
+	literal = regexp.MustCompile(`'(?:[^'\\]|\\.)*'`)
+
+	// narrowing matches operators under which a comparison alone doesn't
+	// identify a threat: "[url:value = 'x' AND ...]" only lists x with
+	// something else.
+	narrowing = regexp.MustCompile(`\b(AND|NOT|FOLLOWEDBY|LIKE|MATCHES|ISSUBSET|ISSUPERSET)\b`)
+)
+
+// Indicators returns what o lists as of now: the value of a url, domain-name
+// or ipv4-addr observable, or every such equality comparison of an indicator
+// pattern joined by OR. Revoked and expired indicators list nothing, nor do
+// patterns that narrow a comparison with AND, NOT or sequences.
+func (o Object) Indicators(now time.Time) []Indicator {
+	base := Indicator{Tags: slices.Concat(o.IndicatorTypes, o.Labels)}
+	if o.Confidence != nil {
+		c := float64(min(max(*o.Confidence, 0), 100)) / 100
+		base.Confidence = &c
+	}
+	base.Date = parseTime(o.ValidFrom)
+	if base.Date.IsZero() {
+		base.Date = parseTime(o.Modified)
+	}
+
+	switch o.Type {
+	case TypeURL, TypeDomainName, TypeIPv4Addr:
+		if o.Value == "" {
+			return nil
+		}
+		base.Type, base.Value = o.Type, o.Value
+		return []Indicator{base}
+	case TypeIndicator:
+	default:
+		return nil
+	}
+
+	if o.Revoked || (o.PatternType != "" && o.PatternType != "stix") {
+		return nil
+	}
+	if until := parseTime(o.ValidUntil); !until.IsZero() && !until.After(now) {
+		return nil
+	}
+	if narrowing.MatchString(literal.ReplaceAllString(o.Pattern, "''")) {
+		return nil
+	}
+
+	var out []Indicator
+	for _, m := range comparison.FindAllStringSubmatch(o.Pattern, -1) {
+		ind := base
+		ind.Type, ind.Value = m[1], unescape(m[2])
+		out = append(out, ind)
+	}
+	return out
+}
+
+// Link returns the indicator in the form Entry.SetURL parses; ok is false
+// for values that are not a URL, domain or single IPv4 address.
+func (i Indicator) Link() (link string, ok bool) {
+	value := strings.TrimSpace(i.Value)
+	switch i.Type {
+	case TypeURL:
+		return value, value != ""
+	case TypeDomainName:
+		value = strings.TrimSuffix(value, ".")
+		return value, value != "" && !strings.ContainsAny(value, "/ ")
+	case TypeIPv4Addr:
+		// Only single addresses; ranges would list whole networks.
+		value = strings.TrimSuffix(value, "/32")
+		ip := net.ParseIP(value)
+		if ip == nil || ip.To4() == nil {
+			return "", false
+		}
+		return ip.String(), true
+	}
+	return "", false
+}
+
+// unescape resolves the \' and \\ escapes of a STIX string literal.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	return strings.NewReplacer(`\'`, `'`, `\\`, `\`).Replace(s)
+}
+
+// parseTime parses a STIX timestamp; zero when empty or malformed.
+func parseTime(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,235 @@
+package taxii
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entry_collector"
+	"blacked/features/providers/base"
+	"blacked/internal/config"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// TypeTAXII marks a [providers.<name>] block as a TAXII 2.1 collection.
+const TypeTAXII = "taxii"
+
+const (
+	defaultCron     = "15 * * * *"
+	defaultCategory = "threat-intel"
+
+	// mediaType is the TAXII 2.1 media type servers answer with.
+	mediaType = "application/taxii+json;version=2.1"
+
+	// maxPages bounds a poll, in case a server keeps answering more = true.
+	maxPages = 1000
+)
+
+var ErrInvalidTAXIIProvider = errors.New("invalid TAXII provider")
+
+// NewTAXIIProviders creates a provider for every enabled [providers.<name>]
+// block with type = "taxii". Invalid blocks are logged and skipped, as are
+// names already taken by another provider.
+func NewTAXIIProviders(cfg *config.Config, collyClient *colly.Collector) []base.Provider {
+	names := make([]string, 0, len(cfg.Providers))
+	for name, opts := range cfg.Providers {
+		if opts != nil && opts.Type == TypeTAXII {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var providers []base.Provider
+	for _, name := range names {
+		if _, taken := base.GetProvider(name); taken {
+			log.Error().Str("provider", name).Msg("TAXII provider name is taken by another provider — skipping")
+			continue
+		}
+
+		p, err := NewTAXIIProvider(name, cfg.Providers[name], collyClient)
+		if err != nil {
+			log.Error().Err(err).Str("provider", name).Msg("invalid TAXII provider — skipping")
+			continue
+		}
+		if p != nil {
+			providers = append(providers, p)
+		}
+	}
+	return providers
+}
+
+// NewTAXIIProvider creates and registers the provider name polling the
+// TAXII 2.1 collection objects URL in opts.SourceURL, e.g.
+// https://taxii.example/api1/collections/<id>/objects/. It returns nil
+// without an error when the provider is disabled.
+func NewTAXIIProvider(name string, opts *config.ProviderOptions, collyClient *colly.Collector) (base.Provider, error) {
+	if opts.Enabled != nil && !*opts.Enabled {
+		log.Info().Str("provider", name).Msg("provider disabled — skipping")
+		return nil, nil
+	}
+	if opts.SourceURL == "" {
+		return nil, fmt.Errorf("%w: source_url is required", ErrInvalidTAXIIProvider)
+	}
+	if _, err := url.Parse(opts.SourceURL); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTAXIIProvider, err)
+	}
+
+	cron := opts.Cron
+	if cron == "" {
+		cron = defaultCron
+	}
+	category := opts.Category
+	if category == "" {
+		category = defaultCategory
+	}
+
+	workers := opts.ParserWorkers
+	if workers <= 0 {
+		workers = 4
+	}
+
+	mapper := base.NewCategoryMapper(opts.CategoryMap)
+	client := base.BuildCollyClientForProvider(collyClient, opts)
+
+	var provider *base.BaseProvider
+	parseFunc := func(data io.Reader, collector entry_collector.Collector) error {
+		ageFilter := base.NewAgeFilter(name, opts.IgnoreOlderThan)
+		defer ageFilter.Report()
+
+		now := time.Now()
+		id := uuid.New().String()
+		return ReadPages(data, opts.SourceURL, provider.FetchPage, func(objects []Object) error {
+			var indicators []Indicator
+			for _, o := range objects {
+				for _, ind := range o.Indicators(now) {
+					if ageFilter.Keep(ind.Date) {
+						indicators = append(indicators, ind)
+					}
+				}
+			}
+
+			return base.ProcessEntriesParallel(indicators, collector, workers, func(item Indicator, processID string) (*entries.Entry, error) {
+				link, ok := item.Link()
+				if !ok {
+					log.Debug().Str("provider", name).Str("type", item.Type).Str("value", item.Value).Msg("skipping unusable STIX indicator")
+					return nil, nil
+				}
+
+				entry := entries.NewEntry().
+					WithSource(name).
+					WithProcessID(processID).
+					WithCategories(mapper.Categories(category, item.Tags...)...)
+				if item.Confidence != nil {
+					entry.WithConfidence(*item.Confidence)
+				}
+
+				if err := entry.SetURL(link); err != nil {
+					log.Error().Err(err).Msgf("error setting URL: %s", link)
+					return nil, nil
+				}
+
+				return entry, nil
+			}, id)
+		})
+	}
+
+	provider = base.NewBaseProvider(
+		name,
+		opts.SourceURL,
+		category,
+		client,
+		parseFunc,
+	)
+
+	provider.
+		SetCronSchedule(cron).
+		SetMirrors(opts.Mirrors).
+		SetAllowedDomains(opts.AllowedDomains).
+		SetHeaders(headers(opts)).
+		SetConditional(false) // Objects added later land on later pages
+	if opts.APIKey != "" || opts.Username != "" {
+		provider.SetAuthCheck(base.AuthCheck{Method: http.MethodGet, URL: CollectionURL(opts.SourceURL), Token: opts.APIKey})
+	}
+	provider.Register()
+
+	return provider, nil
+}
+
+// CollectionURL returns the collection resource of the objects URL
+// sourceURL, a small document the server only shows to authorized clients.
+func CollectionURL(sourceURL string) string {
+	u, err := url.Parse(sourceURL)
+	if err != nil {
+		return sourceURL
+	}
+	if trimmed := strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/objects"); trimmed != strings.TrimSuffix(u.Path, "/") {
+		u.Path = trimmed + "/"
+		u.RawQuery = ""
+	}
+	return u.String()
+}
+
+// headers returns the TAXII Accept header and the configured credentials:
+// api_key as a bearer token (OpenCTI), else username and password (MISP).
+func headers(opts *config.ProviderOptions) map[string]string {
+	h := map[string]string{"Accept": mediaType}
+	switch {
+	case opts.APIKey != "":
+		h["Authorization"] = "Bearer " + opts.APIKey
+	case opts.Username != "":
+		h["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(opts.Username+":"+opts.Password))
+	}
+	return h
+}
+
+// ReadPages calls fn with the objects of first, the first page of the
+// collection at sourceURL, then fetches and reads the following pages while
+// the server reports more.
+func ReadPages(first io.Reader, sourceURL string, fetch func(pageURL string) (io.Reader, error), fn func([]Object) error) error {
+	page := first
+	for n := 1; ; n++ {
+		env, err := DecodeEnvelope(page)
+		if err != nil {
+			return err
+		}
+		if err := fn(env.Objects); err != nil {
+			return err
+		}
+		if !env.More || env.Next == "" {
+			return nil
+		}
+		if n == maxPages {
+			log.Warn().Str("source", sourceURL).Int("pages", n).Msg("TAXII collection has more pages than read in one poll")
+			return nil
+		}
+
+		next, err := NextPageURL(sourceURL, env.Next)
+		if err != nil {
+			return err
+		}
+		if page, err = fetch(next); err != nil {
+			return err
+		}
+	}
+}
+
+// NextPageURL returns sourceURL asking for the page after the next token.
+func NextPageURL(sourceURL, next string) (string, error) {
+	u, err := url.Parse(sourceURL)
+	if err != nil {
+		return "", errors.Join(ErrInvalidTAXIIProvider, err)
+	}
+	q := u.Query()
+	q.Set("next", next)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
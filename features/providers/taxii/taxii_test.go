@@ -0,0 +1,185 @@
+package taxii
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entry_collector"
+	"blacked/features/providers/base"
+	"blacked/internal/config"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndicators(t *testing.T) {
+	now := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	confidence := 85
+
+	cases := []struct {
+		name   string
+		object Object
+		want   []string // type=value
+	}{
+		{"url", Object{Type: TypeIndicator, Pattern: "[url:value = 'http://evil.example/a?b=1']"}, []string{"url=http://evil.example/a?b=1"}},
+		{"or", Object{Type: TypeIndicator, Pattern: "[domain-name:value = 'evil.example' OR ipv4-addr:value = '203.0.113.7']"}, []string{"domain-name=evil.example", "ipv4-addr=203.0.113.7"}},
+		{"escaped", Object{Type: TypeIndicator, Pattern: `[url:value = 'http://evil.example/it\'s']`}, []string{"url=http://evil.example/it's"}},
+		{"and", Object{Type: TypeIndicator, Pattern: "[url:value = 'http://evil.example/' AND url:value = 'http://other.example/']"}, nil},
+		{"and in literal", Object{Type: TypeIndicator, Pattern: "[url:value = 'http://evil.example/AND']"}, []string{"url=http://evil.example/AND"}},
+		{"file hash", Object{Type: TypeIndicator, Pattern: "[file:hashes.'SHA-256' = 'abc']"}, nil},
+		{"revoked", Object{Type: TypeIndicator, Pattern: "[url:value = 'http://evil.example/']", Revoked: true}, nil},
+		{"expired", Object{Type: TypeIndicator, Pattern: "[url:value = 'http://evil.example/']", ValidUntil: "2026-10-01T00:00:00Z"}, nil},
+		{"sigma", Object{Type: TypeIndicator, Pattern: "title: x", PatternType: "sigma"}, nil},
+		{"observable", Object{Type: TypeDomainName, Value: "evil.example"}, []string{"domain-name=evil.example"}},
+		{"malware", Object{Type: "malware", Value: "x"}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, ind := range tc.object.Indicators(now) {
+				got = append(got, ind.Type+"="+ind.Value)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	inds := Object{
+		Type:           TypeIndicator,
+		Pattern:        "[url:value = 'http://evil.example/']",
+		Confidence:     &confidence,
+		IndicatorTypes: []string{"malicious-activity"},
+		Labels:         []string{"phishing"},
+		ValidFrom:      "2026-10-16T08:00:00.000Z",
+	}.Indicators(now)
+	require.Len(t, inds, 1)
+	require.NotNil(t, inds[0].Confidence)
+	assert.InDelta(t, 0.85, *inds[0].Confidence, 1e-9)
+	assert.Equal(t, []string{"malicious-activity", "phishing"}, inds[0].Tags)
+	assert.Equal(t, time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC), inds[0].Date)
+}
+
+func TestIndicatorLink(t *testing.T) {
+	link, ok := Indicator{Type: TypeIPv4Addr, Value: "203.0.113.7/32"}.Link()
+	assert.True(t, ok)
+	assert.Equal(t, "203.0.113.7", link)
+
+	_, ok = Indicator{Type: TypeIPv4Addr, Value: "203.0.113.0/24"}.Link()
+	assert.False(t, ok, "ranges are not listed")
+
+	link, ok = Indicator{Type: TypeDomainName, Value: "evil.example."}.Link()
+	assert.True(t, ok)
+	assert.Equal(t, "evil.example", link)
+}
+
+func TestReadPages(t *testing.T) {
+	pages := map[string]string{
+		"https://taxii.example/api1/collections/c1/objects/?match%5Btype%5D=indicator&next=p2": `{"more": true, "next": "p3", "objects": [{"type": "domain-name", "value": "two.example"}]}`,
+		"https://taxii.example/api1/collections/c1/objects/?match%5Btype%5D=indicator&next=p3": `{"more": false, "objects": [{"type": "domain-name", "value": "three.example"}]}`,
+	}
+	var fetched []string
+	fetch := func(pageURL string) (io.Reader, error) {
+		fetched = append(fetched, pageURL)
+		body, ok := pages[pageURL]
+		require.True(t, ok, pageURL)
+		return strings.NewReader(body), nil
+	}
+
+	var values []string
+	first := `{"more": true, "next": "p2", "objects": [{"type": "domain-name", "value": "one.example"}]}`
+	err := ReadPages(strings.NewReader(first), "https://taxii.example/api1/collections/c1/objects/?match[type]=indicator", fetch, func(objects []Object) error {
+		for _, o := range objects {
+			values = append(values, o.Value)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one.example", "two.example", "three.example"}, values)
+	assert.Len(t, fetched, 2)
+
+	err = ReadPages(strings.NewReader("<html>"), "https://taxii.example/", fetch, func([]Object) error { return nil })
+	assert.ErrorIs(t, err, ErrInvalidEnvelope)
+}
+
+func TestNewTAXIIProviders(t *testing.T) {
+	cfg := &config.Config{Providers: map[string]*config.ProviderOptions{
+		"opencti": {
+			Type:        TypeTAXII,
+			SourceURL:   "https://opencti.example/taxii2/root/collections/c1/objects/",
+			APIKey:      "token",
+			CategoryMap: map[string]string{"phishing": "phishing"},
+		},
+		"misp": {
+			Type:      TypeTAXII,
+			SourceURL: "https://misp.example/taxii2/collections/c2/objects/",
+			Username:  "user",
+			Password:  "pass",
+		},
+		"taxii-no-url": {Type: TypeTAXII},
+	}}
+
+	providers := NewTAXIIProviders(cfg, nil)
+	require.Len(t, providers, 2)
+
+	misp := providers[0].(*base.BaseProvider)
+	assert.Equal(t, "misp", misp.GetName())
+	assert.Equal(t, "Basic dXNlcjpwYXNz", misp.Headers["Authorization"])
+
+	p := providers[1].(*base.BaseProvider)
+	assert.Equal(t, "opencti", p.GetName())
+	assert.Equal(t, defaultCategory, p.GetCategory())
+	assert.Equal(t, defaultCron, p.GetCronSchedule())
+	assert.Equal(t, "Bearer token", p.Headers["Authorization"])
+	assert.Equal(t, mediaType, p.Headers["Accept"])
+	assert.True(t, p.RequiresAuth())
+
+	bundle := `{"type": "bundle", "id": "bundle--1", "objects": [
+		{"type": "indicator", "pattern": "[url:value = 'http://phish.example/login']", "pattern_type": "stix", "confidence": 90, "labels": ["phishing"]},
+		{"type": "indicator", "pattern": "[ipv4-addr:value = '198.51.100.4']"},
+		{"type": "indicator", "pattern": "[ipv4-addr:value = '198.51.100.0/24']"},
+		{"type": "malware", "name": "x"}
+	]}`
+	collector := &entryCollector{}
+	require.NoError(t, p.ParseFunction(strings.NewReader(bundle), collector))
+
+	got := map[string]*entries.Entry{}
+	for _, e := range collector.entries {
+		got[e.Host] = e
+	}
+	require.Len(t, got, 2)
+	assert.Equal(t, "opencti", got["phish.example"].Source)
+	assert.Equal(t, []string{defaultCategory, "phishing"}, got["phish.example"].AllCategories())
+	assert.InDelta(t, 0.9, got["phish.example"].Confidence, 1e-9)
+	assert.Equal(t, defaultCategory, got["198.51.100.4"].Category)
+}
+
+// entryCollector records submitted entries.
+type entryCollector struct {
+	mu      sync.Mutex
+	entries []*entries.Entry
+}
+
+func (c *entryCollector) Submit(entry *entries.Entry) {
+	c.mu.Lock()
+	c.entries = append(c.entries, entry)
+	c.mu.Unlock()
+}
+
+func (c *entryCollector) Wait()                                                               {}
+func (c *entryCollector) Close()                                                              {}
+func (c *entryCollector) GetProcessedCount(source string) int                                 { return 0 }
+func (c *entryCollector) StartProviderProcessing(ctx context.Context, name, processID string) {}
+func (c *entryCollector) FinishProviderProcessing(name, processID string) (int, time.Duration, bool) {
+	return 0, 0, true
+}
+func (c *entryCollector) CommitDelta(ctx context.Context, name, processID string) (*entry_collector.DeltaReport, error) {
+	return nil, nil
+}
+
+func TestCollectionURL(t *testing.T) {
+	assert.Equal(t, "https://opencti.example/taxii2/root/collections/c1/", CollectionURL("https://opencti.example/taxii2/root/collections/c1/objects/?limit=100"))
+	assert.Equal(t, "https://taxii.example/feed", CollectionURL("https://taxii.example/feed"))
+}
@@ -5,8 +5,10 @@ import (
 	"blacked/features/entry_collector"
 	"blacked/features/providers/base"
 	"blacked/internal/config"
+	"encoding/csv"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/gocolly/colly/v2"
 	"github.com/rs/zerolog/log"
@@ -48,21 +50,25 @@ func NewURLHausProvider(cfg *config.Config, collyClient *colly.Collector) base.P
 	}
 
 	client := base.BuildCollyClientForProvider(collyClient, opts)
+	mapper := base.NewCategoryMapper(opts.CategoryMap)
 
 	parseFunc := func(data io.Reader, collector entry_collector.Collector) error {
+		ageFilter := base.NewAgeFilter(providerName, opts.IgnoreOlderThan)
+		defer ageFilter.Report()
+
 		return base.ParseLinesParallel(data, collector, providerName, workers, batchSize, func(line, processID string) (*entries.Entry, error) {
-			line = strings.TrimSpace(line)
-			if line == "" || strings.HasPrefix(line, "#") {
+			link, tags, added, ok := parseLine(line)
+			if !ok || !ageFilter.Keep(added) {
 				return nil, nil
 			}
 
 			entry := entries.NewEntry().
 				WithSource(providerName).
 				WithProcessID(processID).
-				WithCategory(category)
+				WithCategories(mapper.Categories(category, tags...)...)
 
-			if err := entry.SetURL(line); err != nil {
-				log.Error().Err(err).Msgf("error setting URL: %s", line)
+			if err := entry.SetURL(link); err != nil {
+				log.Error().Err(err).Msgf("error setting URL: %s", link)
 				return nil, nil
 			}
 
@@ -80,7 +86,55 @@ func NewURLHausProvider(cfg *config.Config, collyClient *colly.Collector) base.P
 
 	provider.
 		SetCronSchedule(cron).
+		SetMirrors(opts.Mirrors).
+		SetAllowedDomains(opts.AllowedDomains).
 		Register()
 
 	return provider
 }
+
+// Columns of the URLhaus CSV exports (csv_online, csv_recent):
+// id, dateadded, url, url_status, last_online, threat, tags, urlhaus_link, reporter.
+const (
+	csvDateAddedColumn = 1
+	csvURLColumn       = 2
+	csvThreatColumn    = 5
+	csvTagsColumn      = 6
+)
+
+// csvDateLayout is the UTC timestamp layout of the dateadded column.
+const csvDateLayout = "2006-01-02 15:04:05"
+
+// parseLine reads a line of the plain text export, one URL per line, or of
+// a CSV export, whose threat type and comma-separated tags are returned for
+// category mapping and whose dateadded is returned as added. Plain lines
+// carry no date, so added is zero.
+func parseLine(line string) (link string, tags []string, added time.Time, ok bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil, time.Time{}, false
+	}
+	if !strings.HasPrefix(line, `"`) {
+		return line, nil, time.Time{}, true
+	}
+
+	r := csv.NewReader(strings.NewReader(line))
+	r.FieldsPerRecord = -1
+	record, err := r.Read()
+	if err != nil || len(record) <= csvURLColumn {
+		return "", nil, time.Time{}, false
+	}
+	link = strings.TrimSpace(record[csvURLColumn])
+	added, _ = time.Parse(csvDateLayout, record[csvDateAddedColumn])
+	if len(record) > csvThreatColumn && record[csvThreatColumn] != "" {
+		tags = append(tags, record[csvThreatColumn])
+	}
+	if len(record) > csvTagsColumn && record[csvTagsColumn] != "None" {
+		for tag := range strings.SplitSeq(record[csvTagsColumn], ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return link, tags, added, link != ""
+}
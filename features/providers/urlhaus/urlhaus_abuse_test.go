@@ -2,6 +2,7 @@ package urlhaus
 
 import (
 	"blacked/features/entries/repository"
+	"blacked/features/providers/base"
 	"blacked/internal/config"
 	testutil "blacked/internal/testutil"
 	"blacked/internal/utils"
@@ -56,3 +57,26 @@ func TestParse(t *testing.T) {
 
 	log.Info().Str("process_id", strProcessID).Str("source", source).Str("name", name).TimeDiff("duration", time.Now(), startedAt).Msg("finished processing data")
 }
+
+func TestParseLine(t *testing.T) {
+	link, tags, added, ok := parseLine("http://1.2.3.4/bins/x86")
+	assert.True(t, ok)
+	assert.Equal(t, "http://1.2.3.4/bins/x86", link)
+	assert.Empty(t, tags)
+	assert.True(t, added.IsZero(), "the plain export carries no date")
+
+	link, tags, added, ok = parseLine(`"3335113","2024-12-02 10:42:06","http://1.2.3.4/Mozi.m","online","2024-12-02 10:42:06","malware_download","elf,Mozi","https://urlhaus.abuse.ch/url/3335113/","geenensp"`)
+	assert.True(t, ok)
+	assert.Equal(t, "http://1.2.3.4/Mozi.m", link)
+	assert.Equal(t, []string{"malware_download", "elf", "Mozi"}, tags)
+	assert.Equal(t, time.Date(2024, 12, 2, 10, 42, 6, 0, time.UTC), added)
+
+	mapper := base.NewCategoryMapper(map[string]string{"mozi": "botnet", "elf": "iot", "malware_download": "malware"})
+	assert.Equal(t, []string{"malware", "iot", "botnet"}, mapper.Categories("malware", tags...))
+
+	_, _, _, ok = parseLine(`# id,dateadded,url,url_status,last_online,threat,tags,urlhaus_link,reporter`)
+	assert.False(t, ok)
+	_, tags, _, ok = parseLine(`"1","2024-12-02 10:42:06","http://bad.example/a","offline","","malware_download","None","",""`)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"malware_download"}, tags)
+}
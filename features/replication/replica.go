@@ -0,0 +1,272 @@
+package replication
+
+import (
+	"blacked/features/entries"
+	"blacked/features/maintenance"
+	"blacked/internal/collector"
+	"blacked/internal/config"
+	"blacked/internal/db"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var ErrInvalidReplica = errors.New("invalid replication configuration")
+
+// Refresher updates the query path after changes are applied; implemented
+// by entry_collector.PondCollector.
+type Refresher interface {
+	PopulateBloom(batch []*entries.Entry)
+	ScheduleChangedCacheSync(since int64) bool
+}
+
+// Replica polls a primary's change feed and applies it locally.
+type Replica struct {
+	cfg        config.ReplicationConfig
+	changesURL string
+	repo       Repository
+	refresher  Refresher // nil leaves the cache and bloom alone
+	client     *http.Client
+
+	mu     sync.RWMutex
+	cursor int64 // -1 until read from the repository
+	status Status
+}
+
+// NewReplica creates a Replica of cfg.PrimaryURL writing through the write
+// database connection.
+func NewReplica(cfg config.ReplicationConfig, refresher Refresher) (*Replica, error) {
+	dbConn, err := db.GetWriteDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+	return NewReplicaWithRepository(cfg, NewSQLiteRepository(dbConn), refresher)
+}
+
+// NewReplicaWithRepository creates a Replica of cfg.PrimaryURL on the given
+// repository.
+func NewReplicaWithRepository(cfg config.ReplicationConfig, repo Repository, refresher Refresher) (*Replica, error) {
+	primary, err := url.Parse(strings.TrimSuffix(cfg.PrimaryURL, "/"))
+	if err != nil || (primary.Scheme != "http" && primary.Scheme != "https") || primary.Host == "" {
+		return nil, errors.Join(ErrInvalidReplica, fmt.Errorf("primary_url must be an http(s) URL, got %q", cfg.PrimaryURL))
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultLimit
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	return &Replica{
+		cfg:        cfg,
+		changesURL: primary.String() + "/replication/changes",
+		repo:       repo,
+		refresher:  refresher,
+		client:     &http.Client{Timeout: cfg.Timeout},
+		cursor:     -1,
+		status:     Status{Role: "replica", PrimaryURL: primary.String()},
+	}, nil
+}
+
+// Run polls the primary until ctx is done: back to back while behind, every
+// Interval once caught up or after a failed poll.
+func (r *Replica) Run(ctx context.Context) {
+	log.Info().Str("primary", r.status.PrimaryURL).Dur("interval", r.cfg.Interval).Msg("Replica started")
+
+	for {
+		// Applying changes writes entries, so read-only maintenance mode
+		// pauses polling.
+		if !maintenance.ReadOnly(ctx) {
+			caughtUp, err := r.Poll(ctx)
+			if err != nil {
+				log.Warn().Err(err).Str("primary", r.status.PrimaryURL).Msg("Replication poll failed")
+			}
+			if err == nil && !caughtUp {
+				if ctx.Err() != nil {
+					return
+				}
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Replica stopped")
+			return
+		case <-time.After(r.cfg.Interval):
+		}
+	}
+}
+
+// Poll fetches and applies one page of the primary's changes, then
+// refreshes the cache and bloom. It reports whether the replica has caught
+// up with the primary.
+func (r *Replica) Poll(ctx context.Context) (caughtUp bool, err error) {
+	defer func() {
+		if err != nil {
+			if mc, _ := collector.GetMetricsCollector(); mc != nil {
+				mc.IncrementReplicationErrors()
+			}
+		}
+		r.mu.Lock()
+		if err != nil {
+			r.status.LastError = err.Error()
+		} else {
+			r.status.LastError = ""
+		}
+		r.mu.Unlock()
+	}()
+
+	cursor, err := r.loadCursor(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	feed, err := r.fetch(ctx, cursor)
+	if err != nil {
+		return false, err
+	}
+
+	if feed.Head.Seq < cursor {
+		// The primary's feed restarted, e.g. from a fresh database: copy it again.
+		log.Warn().Int64("cursor", cursor).Int64("primary_head", feed.Head.Seq).Msg("Primary change feed is behind the replica cursor — replicating from the start")
+		r.setCursor(0)
+		return false, nil
+	}
+
+	appliedAt := time.Now().UnixNano()
+	if len(feed.Changes) > 0 {
+		if err := r.repo.Apply(ctx, r.status.PrimaryURL, feed.Changes, feed.Next, appliedAt); err != nil {
+			return false, err
+		}
+		r.setCursor(feed.Next)
+	}
+	r.refresh(feed.Changes, appliedAt)
+
+	lagChanges := max(feed.Head.Seq-feed.Next, 0)
+	var lagSeconds float64
+	if lagChanges > 0 && len(feed.Changes) > 0 {
+		lagSeconds = max(time.Duration(feed.Head.ChangedAt-feed.Changes[len(feed.Changes)-1].ChangedAt).Seconds(), 0)
+	}
+
+	r.mu.Lock()
+	r.status.PrimaryHead = &feed.Head
+	r.status.LagChanges = lagChanges
+	r.status.LagSeconds = lagSeconds
+	r.status.LastPollAt = appliedAt
+	r.status.AppliedTotal += int64(len(feed.Changes))
+	r.mu.Unlock()
+
+	if mc, _ := collector.GetMetricsCollector(); mc != nil {
+		mc.ObserveReplication(len(feed.Changes), lagChanges, lagSeconds)
+	}
+
+	if len(feed.Changes) > 0 {
+		log.Debug().Int("applied", len(feed.Changes)).Int64("cursor", feed.Next).Int64("lag_changes", lagChanges).Msg("Applied primary changes")
+	}
+	return lagChanges == 0, nil
+}
+
+// Status returns the replica's state as of the last poll.
+func (r *Replica) Status() Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	status := r.status
+	status.Cursor = max(r.cursor, 0)
+	return status
+}
+
+func (r *Replica) loadCursor(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	cursor := r.cursor
+	r.mu.RUnlock()
+	if cursor >= 0 {
+		return cursor, nil
+	}
+
+	cursor, err := r.repo.Cursor(ctx, r.status.PrimaryURL)
+	if err != nil {
+		return 0, err
+	}
+	r.setCursor(cursor)
+	return cursor, nil
+}
+
+func (r *Replica) setCursor(cursor int64) {
+	r.mu.Lock()
+	r.cursor = cursor
+	r.mu.Unlock()
+}
+
+// refresh adds the applied entries to the bloom and resyncs the cache keys
+// they touched.
+func (r *Replica) refresh(changes []Change, appliedAt int64) {
+	if r.refresher == nil || len(changes) == 0 {
+		return
+	}
+
+	batch := make([]*entries.Entry, 0, len(changes))
+	for _, ch := range changes {
+		if ch.Entry != nil {
+			batch = append(batch, ch.Entry)
+		}
+	}
+	r.refresher.PopulateBloom(batch)
+
+	if !r.refresher.ScheduleChangedCacheSync(appliedAt) {
+		log.Warn().Msg("Cache sync after replication not scheduled - sync queue is full")
+	}
+}
+
+// fetch reads the page of the primary's feed after cursor.
+func (r *Replica) fetch(ctx context.Context, cursor int64) (*ChangeFeed, error) {
+	q := url.Values{}
+	q.Set("after", strconv.FormatInt(cursor, 10))
+	q.Set("limit", strconv.Itoa(r.cfg.BatchSize))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.changesURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, errors.Join(ErrFetchChanges, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if r.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.cfg.Token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, errors.Join(ErrFetchChanges, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, errors.Join(ErrFetchChanges, fmt.Errorf("primary answered %s: %s", resp.Status, strings.TrimSpace(string(body))))
+	}
+
+	var envelope struct {
+		Data *ChangeFeed `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, errors.Join(ErrFetchChanges, err)
+	}
+	if envelope.Data == nil {
+		return nil, errors.Join(ErrFetchChanges, errors.New("primary answered without a change feed"))
+	}
+	return envelope.Data, nil
+}
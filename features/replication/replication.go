@@ -0,0 +1,58 @@
+package replication
+
+import (
+	"blacked/features/entries"
+	"errors"
+)
+
+var (
+	ErrDatabaseConnection = errors.New("failed to connect to the database")
+	ErrInvalidCursor      = errors.New("invalid replication cursor")
+	ErrUnauthorized       = errors.New("replication token missing or wrong")
+	ErrFetchChanges       = errors.New("failed to fetch changes from the primary")
+)
+
+const (
+	// DefaultLimit and MaxLimit bound the changes of one feed page.
+	DefaultLimit = 1000
+	MaxLimit     = 10000
+)
+
+// Change is one entry of the change feed: the entry as it is now, or nil
+// when it was removed from the primary.
+type Change struct {
+	Seq       int64          `json:"seq"`
+	EntryID   string         `json:"entry_id"`
+	ChangedAt int64          `json:"changed_at"` // Unix nanos, primary clock
+	Entry     *entries.Entry `json:"entry"`
+}
+
+// Head is the newest change of a feed.
+type Head struct {
+	Seq       int64 `json:"seq"`
+	ChangedAt int64 `json:"changed_at"` // Unix nanos, primary clock; 0 when the feed is empty
+}
+
+// ChangeFeed is a page of changes after a cursor. Next is the cursor of the
+// following page; it equals Head.Seq once the reader has caught up.
+type ChangeFeed struct {
+	Changes []Change `json:"changes"`
+	Next    int64    `json:"next"`
+	Head    Head     `json:"head"`
+}
+
+// Status reports the replication role of an instance.
+type Status struct {
+	Role       string `json:"role"` // "primary" or "replica"
+	Head       Head   `json:"head"` // Newest change of the local feed
+	PrimaryURL string `json:"primary_url,omitempty"`
+
+	// Replica only.
+	Cursor       int64   `json:"cursor,omitempty"`        // Last primary seq applied
+	PrimaryHead  *Head   `json:"primary_head,omitempty"`  // Primary head at the last poll
+	LagChanges   int64   `json:"lag_changes"`             // Primary changes not applied yet
+	LagSeconds   float64 `json:"lag_seconds"`             // See blacked_replication_lag_seconds
+	LastPollAt   int64   `json:"last_poll_at,omitempty"`  // Unix nanos of the last successful poll
+	LastError    string  `json:"last_error,omitempty"`    // Error of the last poll, if it failed
+	AppliedTotal int64   `json:"applied_total,omitempty"` // Changes applied since startup
+}
@@ -0,0 +1,207 @@
+package replication
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/internal/config"
+	idb "blacked/internal/db"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	require.NoError(t, idb.MigrateSchema(conn))
+	return conn
+}
+
+func saveEntries(t *testing.T, conn *sql.DB, processID string, links ...string) []*entries.Entry {
+	t.Helper()
+	var batch []*entries.Entry
+	for _, link := range links {
+		e, err := entries.FromURL(link, "feed", processID)
+		require.NoError(t, err)
+		e.WithCategory("phishing")
+		batch = append(batch, e)
+	}
+	require.NoError(t, repository.NewSQLiteRepository(conn).BatchSaveEntries(context.Background(), batch))
+	return batch
+}
+
+func TestChangeFeed(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	repo := NewSQLiteRepository(conn)
+	svc := NewServiceWithRepository(repo)
+
+	saved := saveEntries(t, conn, "p1", "https://one.example/a", "https://two.example/b")
+
+	feed, err := svc.Changes(ctx, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, feed.Changes, 2)
+	assert.Equal(t, saved[0].ID, feed.Changes[0].Entry.ID)
+	assert.Equal(t, "phishing", feed.Changes[0].Entry.Category)
+	assert.Equal(t, feed.Head.Seq, feed.Next)
+
+	// A provider refresh that changes nothing but the process is not a change.
+	saveEntries(t, conn, "p2", "https://one.example/a", "https://two.example/b")
+	feed, err = svc.Changes(ctx, feed.Next, 0)
+	require.NoError(t, err)
+	assert.Empty(t, feed.Changes)
+
+	// A refresh changing the category is.
+	recategorized, err := entries.FromURL("https://two.example/b", "feed", "p3")
+	require.NoError(t, err)
+	recategorized.WithCategory("malware")
+	require.NoError(t, repository.NewSQLiteRepository(conn).BatchSaveEntries(ctx, []*entries.Entry{recategorized}))
+	feed, err = svc.Changes(ctx, feed.Next, 0)
+	require.NoError(t, err)
+	require.Len(t, feed.Changes, 1)
+	assert.Equal(t, saved[1].ID, feed.Changes[0].EntryID)
+	assert.Equal(t, "malware", feed.Changes[0].Entry.Category)
+	cursor := feed.Next
+
+	// Soft and hard deletes move the entry to the end of the feed.
+	require.NoError(t, repository.NewSQLiteRepository(conn).SoftDeleteEntryByID(ctx, saved[0].ID))
+	_, err = conn.Exec("DELETE FROM entries WHERE id = ?", saved[1].ID)
+	require.NoError(t, err)
+
+	feed, err = svc.Changes(ctx, cursor, 1)
+	require.NoError(t, err)
+	require.Len(t, feed.Changes, 1)
+	require.NotNil(t, feed.Changes[0].Entry.DeletedAt)
+	assert.Less(t, feed.Next, feed.Head.Seq, "one change left")
+
+	feed, err = svc.Changes(ctx, feed.Next, 1)
+	require.NoError(t, err)
+	require.Len(t, feed.Changes, 1)
+	assert.Equal(t, saved[1].ID, feed.Changes[0].EntryID)
+	assert.Nil(t, feed.Changes[0].Entry)
+
+	_, err = svc.Changes(ctx, -1, 0)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	status, err := svc.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "primary", status.Role)
+	assert.Equal(t, feed.Head, status.Head)
+}
+
+func TestMigrateSeedsChangeFeed(t *testing.T) {
+	conn := newTestDB(t)
+	saveEntries(t, conn, "p1", "https://one.example/", "https://two.example/")
+
+	// Recreate the feed as on a database migrated from before it existed.
+	_, err := conn.Exec("DROP TABLE entry_changes")
+	require.NoError(t, err)
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	var count int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM entry_changes").Scan(&count))
+	assert.Equal(t, 2, count)
+}
+
+func TestReplica(t *testing.T) {
+	ctx := context.Background()
+	primary := newTestDB(t)
+	replicaDB := newTestDB(t)
+	svc := NewServiceWithRepository(NewSQLiteRepository(primary))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		after, _ := strconv.ParseInt(r.URL.Query().Get("after"), 10, 64)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		feed, err := svc.Changes(r.Context(), after, limit)
+		require.NoError(t, err)
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "data": feed})
+	}))
+	defer srv.Close()
+
+	refresher := &refresherStub{}
+	replica, err := NewReplicaWithRepository(config.ReplicationConfig{PrimaryURL: srv.URL + "/", Token: "secret", BatchSize: 2}, NewSQLiteRepository(replicaDB), refresher)
+	require.NoError(t, err)
+
+	saved := saveEntries(t, primary, "p1", "https://one.example/a", "https://two.example/b", "https://three.example/c")
+
+	caughtUp, err := replica.Poll(ctx)
+	require.NoError(t, err)
+	assert.False(t, caughtUp)
+	assert.EqualValues(t, 1, replica.Status().LagChanges)
+
+	caughtUp, err = replica.Poll(ctx)
+	require.NoError(t, err)
+	assert.True(t, caughtUp)
+	assert.EqualValues(t, 0, replica.Status().LagChanges)
+	assert.Len(t, refresher.populated, 3)
+	assert.Equal(t, 2, refresher.syncs)
+
+	got, err := repository.NewSQLiteRepository(replicaDB).GetAllEntries(ctx)
+	require.NoError(t, err)
+	assert.Len(t, got, 3)
+
+	// Deletes on the primary reach the replica, and the cursor survives a restart.
+	require.NoError(t, repository.NewSQLiteRepository(primary).SoftDeleteEntryByID(ctx, saved[0].ID))
+	_, err = primary.Exec("DELETE FROM entries WHERE id = ?", saved[1].ID)
+	require.NoError(t, err)
+
+	restarted, err := NewReplicaWithRepository(config.ReplicationConfig{PrimaryURL: srv.URL, Token: "secret"}, NewSQLiteRepository(replicaDB), nil)
+	require.NoError(t, err)
+	caughtUp, err = restarted.Poll(ctx)
+	require.NoError(t, err)
+	assert.True(t, caughtUp)
+	assert.EqualValues(t, 2, restarted.Status().AppliedTotal, "only the changes after the saved cursor")
+
+	got, err = repository.NewSQLiteRepository(replicaDB).GetAllEntries(ctx)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, saved[2].ID, got[0].ID)
+
+	var deletedAt sql.NullInt64
+	require.NoError(t, replicaDB.QueryRow("SELECT deleted_at FROM entries WHERE id = ?", saved[1].ID).Scan(&deletedAt))
+	assert.True(t, deletedAt.Valid, "an active entry removed on the primary is soft-deleted so the cache drops it")
+
+	// A wrong token fails the poll without moving the cursor.
+	denied, err := NewReplicaWithRepository(config.ReplicationConfig{PrimaryURL: srv.URL, Token: "wrong"}, NewSQLiteRepository(replicaDB), nil)
+	require.NoError(t, err)
+	_, err = denied.Poll(ctx)
+	assert.ErrorIs(t, err, ErrFetchChanges)
+	assert.NotEmpty(t, denied.Status().LastError)
+
+	_, err = NewReplicaWithRepository(config.ReplicationConfig{PrimaryURL: "primary:8082"}, nil, nil)
+	assert.ErrorIs(t, err, ErrInvalidReplica)
+}
+
+// refresherStub records refreshes of the query path.
+type refresherStub struct {
+	mu        sync.Mutex
+	populated []*entries.Entry
+	syncs     int
+}
+
+func (r *refresherStub) PopulateBloom(batch []*entries.Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.populated = append(r.populated, batch...)
+}
+
+func (r *refresherStub) ScheduleChangedCacheSync(since int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.syncs++
+	return true
+}
@@ -0,0 +1,265 @@
+package replication
+
+import (
+	"blacked/features/entries"
+	"blacked/internal/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrQueryChanges = errors.New("failed to query entry changes from SQLite")
+	ErrApplyChanges = errors.New("failed to apply entry changes in SQLite")
+	ErrQueryCursor  = errors.New("failed to query replication cursor from SQLite")
+)
+
+// Repository reads the local change feed and applies a primary's changes.
+type Repository interface {
+	// Changes returns up to limit changes with a seq above after, oldest first.
+	Changes(ctx context.Context, after int64, limit int) (*ChangeFeed, error)
+	Head(ctx context.Context) (Head, error)
+
+	// Apply writes changes read from primaryURL and moves its cursor to
+	// next, in one transaction. Rows written are stamped updated_at =
+	// appliedAt so a changed cache sync from appliedAt picks them up.
+	Apply(ctx context.Context, primaryURL string, changes []Change, next, appliedAt int64) error
+	// Cursor returns the last seq of primaryURL applied; 0 before the first.
+	Cursor(ctx context.Context, primaryURL string) (int64, error)
+}
+
+// SQLiteRepository is the SQLite implementation of Repository.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLiteRepository instance.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// changeColumns is the column list scanned by scanChange. Entry columns are
+// NULL for entries removed since the change.
+const changeColumns = `c.seq, c.entry_id, c.changed_at,
+	e.id, e.process_id, e.scheme, e.domain, e.host, e.sub_domains, e.path, e.raw_query,
+	e.source_url, e.source, e.category, e.categories, e.confidence,
+	e.created_at, e.updated_at, e.deleted_at, e.activated_at, e.cidr, e.wildcard`
+
+func scanChange(row interface{ Scan(...any) error }) (*Change, error) {
+	var (
+		ch                                           Change
+		id, processID, scheme, domain, host, subs    sql.NullString
+		path, rawQuery, sourceURL, source, category  sql.NullString
+		categories, cidr                             sql.NullString
+		confidence                                   sql.NullFloat64
+		wildcard                                     sql.NullBool
+		createdAt, updatedAt, deletedAt, activatedAt sql.NullInt64
+	)
+	err := row.Scan(&ch.Seq, &ch.EntryID, &ch.ChangedAt,
+		&id, &processID, &scheme, &domain, &host, &subs, &path, &rawQuery,
+		&sourceURL, &source, &category, &categories, &confidence,
+		&createdAt, &updatedAt, &deletedAt, &activatedAt, &cidr, &wildcard)
+	if err != nil {
+		return nil, err
+	}
+	if !id.Valid {
+		return &ch, nil
+	}
+
+	e := &entries.Entry{
+		ID:          id.String,
+		ProcessID:   processID.String,
+		Scheme:      scheme.String,
+		Domain:      domain.String,
+		Host:        host.String,
+		Path:        path.String,
+		RawQuery:    rawQuery.String,
+		CIDR:        cidr.String,
+		Wildcard:    wildcard.Bool,
+		SourceURL:   sourceURL.String,
+		Source:      source.String,
+		Category:    category.String,
+		Confidence:  confidence.Float64,
+		CreatedAt:   createdAt.Int64,
+		UpdatedAt:   updatedAt.Int64,
+		ActivatedAt: activatedAt.Int64,
+	}
+	if subs.String != "" {
+		e.SubDomains = strings.Split(subs.String, ",")
+	}
+	if categories.Valid && categories.String != "" {
+		if err := json.Unmarshal([]byte(categories.String), &e.Categories); err != nil {
+			log.Warn().Err(err).Str("entry_id", e.ID).Msg("Skipping malformed entry categories")
+		}
+	}
+	if deletedAt.Valid {
+		e.DeletedAt = &deletedAt.Int64
+	}
+	ch.Entry = e
+	return &ch, nil
+}
+
+// Changes returns up to limit changes with a seq above after, oldest first.
+func (r *SQLiteRepository) Changes(ctx context.Context, after int64, limit int) (*ChangeFeed, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+changeColumns+`
+		FROM entry_changes c LEFT JOIN entries e ON e.id = c.entry_id
+		WHERE c.seq > ? ORDER BY c.seq LIMIT ?`, after, limit)
+	if err != nil {
+		log.Err(err).Int64("after", after).Msg("Failed to query entry changes")
+		return nil, ErrQueryChanges
+	}
+	defer rows.Close()
+
+	feed := &ChangeFeed{Changes: []Change{}, Next: after}
+	for rows.Next() {
+		ch, err := scanChange(rows)
+		if err != nil {
+			log.Err(err).Msg("Failed to scan entry change")
+			return nil, ErrQueryChanges
+		}
+		feed.Changes = append(feed.Changes, *ch)
+		feed.Next = ch.Seq
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Rows iteration error for entry changes")
+		return nil, ErrQueryChanges
+	}
+
+	// Read after the page, so Head is never behind Next.
+	if feed.Head, err = r.Head(ctx); err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// Head returns the newest change of the feed; zero when it is empty.
+func (r *SQLiteRepository) Head(ctx context.Context) (Head, error) {
+	var head Head
+	err := r.db.QueryRowContext(ctx, "SELECT seq, changed_at FROM entry_changes ORDER BY seq DESC LIMIT 1").Scan(&head.Seq, &head.ChangedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Err(err).Msg("Failed to query entry change feed head")
+		return Head{}, ErrQueryChanges
+	}
+	return head, nil
+}
+
+// Apply writes changes read from primaryURL and moves its cursor to next.
+// An entry is upserted by ID, replacing a local row listing the same URL
+// for the same source under another ID. A removed entry is soft-deleted
+// when still active, so the cache drops it, and deleted otherwise.
+func (r *SQLiteRepository) Apply(ctx context.Context, primaryURL string, changes []Change, next, appliedAt int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Err(err).Msg("Failed to begin transaction for replication apply")
+		return ErrApplyChanges
+	}
+	defer tx.Rollback()
+
+	for _, ch := range changes {
+		if ch.Entry == nil {
+			err = removeEntry(ctx, tx, ch.EntryID, appliedAt)
+		} else {
+			err = upsertEntry(ctx, tx, ch.Entry, appliedAt)
+		}
+		if err != nil {
+			log.Err(err).Int64("seq", ch.Seq).Str("entry_id", ch.EntryID).Msg("Failed to apply entry change")
+			return ErrApplyChanges
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO replication_state (primary_url, cursor, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (primary_url) DO UPDATE SET cursor = EXCLUDED.cursor, updated_at = EXCLUDED.updated_at`,
+		primaryURL, next, appliedAt)
+	if err != nil {
+		log.Err(err).Str("primary", primaryURL).Int64("cursor", next).Msg("Failed to save replication cursor")
+		return ErrApplyChanges
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Msg("Failed to commit replication apply")
+		return ErrApplyChanges
+	}
+	return nil
+}
+
+func upsertEntry(ctx context.Context, tx *sql.Tx, e *entries.Entry, appliedAt int64) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM entries WHERE source_url = ? AND source = ? AND id != ?", e.SourceURL, e.Source, e.ID); err != nil {
+		return err
+	}
+
+	activatedAt := e.ActivatedAt
+	if activatedAt == 0 {
+		activatedAt = e.CreatedAt
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO entries (
+			id, process_id, scheme, domain, host, sub_domains, path, raw_query, source_url, source, category, confidence, created_at, updated_at, deleted_at, activated_at, reversed_host, ip, categories, cidr, wildcard
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			process_id = EXCLUDED.process_id,
+			scheme = EXCLUDED.scheme,
+			domain = EXCLUDED.domain,
+			host = EXCLUDED.host,
+			sub_domains = EXCLUDED.sub_domains,
+			path = EXCLUDED.path,
+			raw_query = EXCLUDED.raw_query,
+			source_url = EXCLUDED.source_url,
+			source = EXCLUDED.source,
+			category = EXCLUDED.category,
+			confidence = EXCLUDED.confidence,
+			created_at = EXCLUDED.created_at,
+			updated_at = EXCLUDED.updated_at,
+			deleted_at = EXCLUDED.deleted_at,
+			activated_at = EXCLUDED.activated_at,
+			reversed_host = EXCLUDED.reversed_host,
+			ip = EXCLUDED.ip,
+			categories = EXCLUDED.categories,
+			cidr = EXCLUDED.cidr,
+			wildcard = EXCLUDED.wildcard`,
+		e.ID, e.ProcessID, e.Scheme, e.Domain, e.Host, strings.Join(e.SubDomains, ","), e.Path, e.RawQuery,
+		e.SourceURL, e.Source, e.Category, e.Confidence, e.CreatedAt, appliedAt, e.DeletedAt, activatedAt,
+		utils.ReverseHost(e.Host), utils.HostIP(e.Host), encodeCategories(e.Categories), sql.NullString{String: e.CIDR, Valid: e.CIDR != ""}, e.Wildcard,
+	)
+	return err
+}
+
+func removeEntry(ctx context.Context, tx *sql.Tx, id string, appliedAt int64) error {
+	res, err := tx.ExecContext(ctx, "UPDATE entries SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL", appliedAt, appliedAt, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM entries WHERE id = ?", id)
+	return err
+}
+
+// encodeCategories stores categories as the entries repository does: a JSON
+// array for multi-category entries, NULL otherwise.
+func encodeCategories(categories []string) any {
+	if len(categories) < 2 {
+		return nil
+	}
+	b, err := json.Marshal(categories)
+	if err != nil {
+		return nil
+	}
+	return string(b)
+}
+
+// Cursor returns the last seq of primaryURL applied; 0 before the first.
+func (r *SQLiteRepository) Cursor(ctx context.Context, primaryURL string) (int64, error) {
+	var cursor int64
+	err := r.db.QueryRowContext(ctx, "SELECT cursor FROM replication_state WHERE primary_url = ?", primaryURL).Scan(&cursor)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Err(err).Str("primary", primaryURL).Msg("Failed to query replication cursor")
+		return 0, ErrQueryCursor
+	}
+	return cursor, nil
+}
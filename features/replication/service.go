@@ -0,0 +1,76 @@
+package replication
+
+import (
+	"blacked/internal/db"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Service serves the local change feed and, on a replica, reports the
+// state of the replica applying its primary's feed.
+type Service struct {
+	repo Repository
+
+	mu      sync.RWMutex
+	replica *Replica // nil on a primary
+}
+
+// NewService creates a Service on the read database pool.
+func NewService() (*Service, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+
+	return NewServiceWithRepository(NewSQLiteRepository(dbConn)), nil
+}
+
+// NewServiceWithRepository creates a Service on the given repository.
+func NewServiceWithRepository(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// SetReplica sets the replica whose state Status reports.
+func (s *Service) SetReplica(r *Replica) *Service {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replica = r
+	return s
+}
+
+// Changes returns up to limit changes after the cursor after; limit <= 0
+// uses DefaultLimit and larger limits are capped at MaxLimit.
+func (s *Service) Changes(ctx context.Context, after int64, limit int) (*ChangeFeed, error) {
+	if after < 0 {
+		return nil, errors.Join(ErrInvalidCursor, fmt.Errorf("after must not be negative, got %d", after))
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	return s.repo.Changes(ctx, after, min(limit, MaxLimit))
+}
+
+// Status reports whether this instance is a primary or a replica, with the
+// head of its own feed and, on a replica, its lag behind the primary.
+func (s *Service) Status(ctx context.Context) (*Status, error) {
+	head, err := s.repo.Head(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	replica := s.replica
+	s.mu.RUnlock()
+
+	if replica == nil {
+		return &Status{Role: "primary", Head: head}, nil
+	}
+	status := replica.Status()
+	status.Head = head
+	return &status, nil
+}
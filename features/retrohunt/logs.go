@@ -0,0 +1,298 @@
+package retrohunt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Log formats ParseLog reads.
+const (
+	FormatAuto  = ""      // Detected from the first bytes of the log
+	FormatHAR   = "har"   // Browser HTTP Archive (JSON)
+	FormatZeek  = "zeek"  // Zeek http.log, TSV with #fields header or JSON lines
+	FormatSquid = "squid" // Squid native access.log
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown log format")
+	ErrInvalidLog    = errors.New("invalid log")
+)
+
+// Visit is one request read from a traffic log.
+type Visit struct {
+	Time   time.Time `json:"time"`
+	URL    string    `json:"url"`
+	Client string    `json:"client,omitempty"` // Requesting IP, when the log has one
+	Method string    `json:"method,omitempty"`
+}
+
+// maxLogLine caps one line of a Zeek or Squid log.
+const maxLogLine = 1024 * 1024
+
+// ParseLog reads the visits of a HAR, Zeek or Squid log; FormatAuto detects
+// the format. Lines without a URL, such as Zeek headers, are skipped. It
+// returns the format read.
+func ParseLog(r io.Reader, format string) ([]Visit, string, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	if format == FormatAuto {
+		format = detectFormat(br)
+	}
+
+	var visits []Visit
+	var err error
+	switch format {
+	case FormatHAR:
+		visits, err = parseHAR(br)
+	case FormatZeek:
+		visits, err = parseZeek(br)
+	case FormatSquid:
+		visits, err = parseSquid(br)
+	default:
+		return nil, format, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	return visits, format, err
+}
+
+// detectFormat tells the formats apart by their first line: Zeek TSV logs
+// open with #separator or #fields, Zeek JSON lines are objects with a ts
+// field, HAR is one JSON document and anything else is read as Squid.
+func detectFormat(br *bufio.Reader) string {
+	head, _ := br.Peek(4096)
+	head = bytes.TrimLeft(head, " \t\r\n\ufeff")
+	line, _, _ := bytes.Cut(head, []byte("\n"))
+	switch {
+	case bytes.HasPrefix(line, []byte("#separator")), bytes.HasPrefix(line, []byte("#fields")):
+		return FormatZeek
+	case bytes.HasPrefix(line, []byte("{")):
+		var probe struct {
+			TS any `json:"ts"`
+		}
+		if json.Unmarshal(bytes.TrimSpace(line), &probe) == nil && probe.TS != nil {
+			return FormatZeek
+		}
+		return FormatHAR
+	default:
+		return FormatSquid
+	}
+}
+
+// harLog is the part of a HAR document ParseLog reads.
+type harLog struct {
+	Log struct {
+		Entries []struct {
+			StartedDateTime time.Time `json:"startedDateTime"`
+			Request         struct {
+				Method string `json:"method"`
+				URL    string `json:"url"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+func parseHAR(r io.Reader) ([]Visit, error) {
+	var har harLog
+	if err := json.NewDecoder(r).Decode(&har); err != nil {
+		return nil, fmt.Errorf("%w: HAR: %w", ErrInvalidLog, err)
+	}
+
+	visits := make([]Visit, 0, len(har.Log.Entries))
+	for _, e := range har.Log.Entries {
+		if !isWebURL(e.Request.URL) {
+			continue
+		}
+		visits = append(visits, Visit{
+			Time:   e.StartedDateTime.UTC(),
+			URL:    e.Request.URL,
+			Method: e.Request.Method,
+		})
+	}
+	return visits, nil
+}
+
+// parseZeek reads a Zeek http.log as TSV, with its columns named by the
+// #fields header, or as JSON lines.
+func parseZeek(r io.Reader) ([]Visit, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLine)
+
+	separator := "\t"
+	var fields map[string]int
+	var visits []Visit
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			continue
+		case strings.HasPrefix(line, "#separator "):
+			if sep, err := strconv.Unquote(`"` + strings.TrimPrefix(line, "#separator ") + `"`); err == nil && sep != "" {
+				separator = sep
+			}
+			continue
+		case strings.HasPrefix(line, "#fields"):
+			names := strings.Split(line, separator)[1:]
+			fields = make(map[string]int, len(names))
+			for i, name := range names {
+				fields[name] = i
+			}
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		}
+
+		var rec zeekRecord
+		if strings.HasPrefix(line, "{") {
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				continue
+			}
+		} else if fields != nil {
+			rec = zeekRecordFromTSV(strings.Split(line, separator), fields)
+		} else {
+			return nil, fmt.Errorf("%w: Zeek TSV log without a #fields header", ErrInvalidLog)
+		}
+
+		if v, ok := rec.visit(); ok {
+			visits = append(visits, v)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLog, err)
+	}
+	return visits, nil
+}
+
+// zeekRecord holds the http.log fields of one request.
+type zeekRecord struct {
+	TS       any    `json:"ts"` // Epoch seconds, or an ISO 8601 string with JSON timestamps
+	OrigHost string `json:"id.orig_h"`
+	RespHost string `json:"id.resp_h"`
+	RespPort any    `json:"id.resp_p"`
+	Method   string `json:"method"`
+	Host     string `json:"host"`
+	URI      string `json:"uri"`
+}
+
+func zeekRecordFromTSV(values []string, fields map[string]int) zeekRecord {
+	get := func(name string) string {
+		i, ok := fields[name]
+		if !ok || i >= len(values) || values[i] == "-" || values[i] == "(empty)" {
+			return ""
+		}
+		return values[i]
+	}
+	return zeekRecord{
+		TS:       get("ts"),
+		OrigHost: get("id.orig_h"),
+		RespHost: get("id.resp_h"),
+		RespPort: get("id.resp_p"),
+		Method:   get("method"),
+		Host:     get("host"),
+		URI:      get("uri"),
+	}
+}
+
+func (r zeekRecord) visit() (Visit, bool) {
+	if r.URI == "" || r.URI == "-" {
+		return Visit{}, false
+	}
+
+	link := r.URI
+	if !isWebURL(link) {
+		host := r.Host
+		if host == "" || host == "-" {
+			host = r.RespHost
+		}
+		if host == "" {
+			return Visit{}, false
+		}
+		scheme := "http://"
+		if fmt.Sprint(r.RespPort) == "443" {
+			scheme = "https://"
+		}
+		if !strings.HasPrefix(link, "/") {
+			link = "/" + link
+		}
+		link = scheme + host + link
+	}
+
+	return Visit{
+		Time:   zeekTime(r.TS),
+		URL:    link,
+		Client: r.OrigHost,
+		Method: r.Method,
+	}, true
+}
+
+// zeekTime reads ts as epoch seconds or an RFC 3339 timestamp.
+func zeekTime(ts any) time.Time {
+	switch v := ts.(type) {
+	case float64:
+		return epochTime(v)
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return epochTime(f)
+		}
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+func epochTime(seconds float64) time.Time {
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC().Truncate(time.Microsecond)
+}
+
+// parseSquid reads a Squid native access.log:
+//
+//	time elapsed client code/status bytes method URL user hierarchy/peer type
+//
+// CONNECT requests log "host:port" and are read as https://host/.
+func parseSquid(r io.Reader) ([]Visit, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLine)
+
+	var visits []Visit
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 7 {
+			continue
+		}
+		ts, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+
+		method, link := fields[5], fields[6]
+		if method == "CONNECT" && !strings.Contains(link, "://") {
+			host, _, _ := strings.Cut(link, ":")
+			link = "https://" + host + "/"
+		}
+		if !isWebURL(link) {
+			continue
+		}
+
+		visits = append(visits, Visit{
+			Time:   epochTime(ts),
+			URL:    link,
+			Client: fields[2],
+			Method: method,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLog, err)
+	}
+	return visits, nil
+}
+
+func isWebURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
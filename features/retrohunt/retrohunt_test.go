@@ -0,0 +1,142 @@
+package retrohunt
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	idb "blacked/internal/db"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const harLogData = `{"log": {"version": "1.2", "entries": [
+	{"startedDateTime": "2026-10-01T09:00:00.000Z", "request": {"method": "GET", "url": "https://login.phish.example/signin"}},
+	{"startedDateTime": "2026-10-01T09:00:01.000Z", "request": {"method": "GET", "url": "data:image/png;base64,AAAA"}},
+	{"startedDateTime": "2026-10-01T09:05:00.000Z", "request": {"method": "POST", "url": "https://good.example/"}}
+]}}`
+
+const zeekTSVLog = "#separator \\x09\n" +
+	"#set_separator\t,\n" +
+	"#fields\tts\tuid\tid.orig_h\tid.orig_p\tid.resp_h\tid.resp_p\tmethod\thost\turi\n" +
+	"#types\ttime\tstring\taddr\tport\taddr\tport\tstring\tstring\tstring\n" +
+	"1790845200.500000\tC1\t10.0.0.5\t50000\t203.0.113.7\t80\tGET\tcdn.bad.example\t/payload.exe\n" +
+	"1790845300.000000\tC2\t10.0.0.6\t50001\t203.0.113.8\t443\tGET\t-\t/x\n" +
+	"1790845400.000000\tC3\t10.0.0.7\t50002\t203.0.113.9\t80\t-\t-\t-\n"
+
+const zeekJSONLog = `{"ts": 1790845200.5, "id.orig_h": "10.0.0.5", "id.resp_h": "203.0.113.7", "id.resp_p": 80, "method": "GET", "host": "cdn.bad.example", "uri": "/payload.exe"}
+{"ts": "2026-10-01T09:01:40Z", "id.orig_h": "10.0.0.6", "id.resp_h": "203.0.113.8", "id.resp_p": 443, "method": "GET", "uri": "/x"}
+`
+
+const squidLog = `1790845200.123    156 10.0.0.5 TCP_MISS/200 1234 GET http://cdn.bad.example/payload.exe - HIER_DIRECT/203.0.113.7 application/octet-stream
+1790845260.000     12 10.0.0.6 TCP_TUNNEL/200 5000 CONNECT login.phish.example:443 - HIER_DIRECT/203.0.113.8 -
+1790845300.000     10 10.0.0.6 TCP_DENIED/403 0 GET ftp://files.example/x - HIER_NONE/- -
+garbage line
+`
+
+func TestParseLog(t *testing.T) {
+	visits, format, err := ParseLog(strings.NewReader(harLogData), FormatAuto)
+	require.NoError(t, err)
+	assert.Equal(t, FormatHAR, format)
+	assert.Equal(t, []Visit{
+		{Time: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC), URL: "https://login.phish.example/signin", Method: "GET"},
+		{Time: time.Date(2026, 10, 1, 9, 5, 0, 0, time.UTC), URL: "https://good.example/", Method: "POST"},
+	}, visits)
+
+	for _, log := range []string{zeekTSVLog, zeekJSONLog} {
+		visits, format, err = ParseLog(strings.NewReader(log), FormatAuto)
+		require.NoError(t, err)
+		assert.Equal(t, FormatZeek, format)
+		require.Len(t, visits, 2)
+		assert.Equal(t, Visit{
+			Time:   time.Unix(1790845200, 500_000_000).UTC(),
+			URL:    "http://cdn.bad.example/payload.exe",
+			Client: "10.0.0.5",
+			Method: "GET",
+		}, visits[0])
+		assert.Equal(t, "https://203.0.113.8/x", visits[1].URL, "requests without a host use the responder and its port")
+	}
+
+	visits, format, err = ParseLog(strings.NewReader(squidLog), FormatAuto)
+	require.NoError(t, err)
+	assert.Equal(t, FormatSquid, format)
+	require.Len(t, visits, 2)
+	assert.Equal(t, "http://cdn.bad.example/payload.exe", visits[0].URL)
+	assert.Equal(t, "10.0.0.5", visits[0].Client)
+	assert.Equal(t, Visit{Time: time.Unix(1790845260, 0).UTC(), URL: "https://login.phish.example/", Client: "10.0.0.6", Method: "CONNECT"}, visits[1])
+
+	_, _, err = ParseLog(strings.NewReader(harLogData), "pcap")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+	_, _, err = ParseLog(strings.NewReader(`{"log": [`), FormatHAR)
+	assert.ErrorIs(t, err, ErrInvalidLog)
+	_, _, err = ParseLog(strings.NewReader("1790845200.5\tC1\n"), FormatZeek)
+	assert.ErrorIs(t, err, ErrInvalidLog, "TSV needs #fields")
+}
+
+type stubAllowlist map[string]bool
+
+func (a stubAllowlist) Allowed(ctx context.Context, link string) (bool, error) {
+	return a[link], nil
+}
+
+func TestHunt(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+
+	var batch []*entries.Entry
+	for _, e := range []struct{ link, source string }{
+		{"cdn.bad.example", "urlhaus-online"},
+		{"https://login.phish.example/signin", "openphish-feed"},
+		{"https://allowed.example/", "oisd-big"},
+	} {
+		entry, err := entries.FromURL(e.link, e.source, "p1")
+		require.NoError(t, err)
+		batch = append(batch, entry.WithCategory("malware"))
+	}
+	require.NoError(t, repo.BatchSaveEntries(ctx, batch))
+
+	t0 := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	visits := []Visit{
+		{Time: t0.Add(2 * time.Hour), URL: "https://login.phish.example/signin", Client: "10.0.0.6"},
+		{Time: t0, URL: "http://cdn.bad.example/payload.exe", Client: "10.0.0.5"},
+		{Time: t0.Add(time.Hour), URL: "http://cdn.bad.example/payload.exe", Client: "10.0.0.7"},
+		{Time: t0.Add(30 * time.Minute), URL: "http://cdn.bad.example/payload.exe", Client: "10.0.0.5"},
+		{Time: t0, URL: "https://good.example/"},
+		{Time: t0, URL: "https://allowed.example/"},
+	}
+
+	svc := NewServiceWithRepository(repo).SetAllowlist(stubAllowlist{"https://allowed.example/": true})
+	report, err := svc.Hunt(ctx, visits)
+	require.NoError(t, err)
+
+	assert.Equal(t, 6, report.Visits)
+	assert.Equal(t, 4, report.URLs)
+	assert.Equal(t, 2, report.Matched)
+	require.Len(t, report.Findings, 2)
+
+	first := report.Findings[0]
+	assert.Equal(t, "http://cdn.bad.example/payload.exe", first.URL, "findings are ordered by first visit")
+	assert.Equal(t, 3, first.Visits)
+	assert.Equal(t, t0, first.FirstSeen)
+	assert.Equal(t, t0.Add(time.Hour), first.LastSeen)
+	assert.Equal(t, []string{"10.0.0.5", "10.0.0.7"}, first.Clients)
+	require.Len(t, first.Matches, 1)
+	assert.Equal(t, "urlhaus-online", first.Matches[0].Source)
+	assert.Equal(t, "malware", first.Matches[0].Category)
+
+	assert.Equal(t, "https://login.phish.example/signin", report.Findings[1].URL)
+	assert.Equal(t, "openphish-feed", report.Findings[1].Matches[0].Source)
+
+	report, err = svc.HuntLog(ctx, strings.NewReader(squidLog), FormatAuto)
+	require.NoError(t, err)
+	assert.Equal(t, FormatSquid, report.Format)
+	assert.Equal(t, 2, report.Matched, "the CONNECT host matches the listed login host")
+}
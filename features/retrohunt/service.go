@@ -0,0 +1,252 @@
+package retrohunt
+
+import (
+	"blacked/features/allowlist"
+	"blacked/features/entries"
+	"blacked/features/entries/enums"
+	"blacked/features/entries/repository"
+	"blacked/internal/db"
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrDatabaseConnection = errors.New("failed to connect to the database")
+	ErrQueryBlacklist     = errors.New("failed to query blacklist entries")
+)
+
+const (
+	huntWorkers   = 8
+	idLookupChunk = 500
+)
+
+// Allowlist reports whether a link must never be reported; implemented by
+// allowlist.Service.
+type Allowlist interface {
+	Allowed(ctx context.Context, link string) (bool, error)
+}
+
+// Match is an active entry listing a visited URL.
+type Match struct {
+	ID         string   `json:"id"`
+	Source     string   `json:"source"`
+	Category   string   `json:"category,omitempty"`
+	Categories []string `json:"categories,omitempty"` // Every category of a multi-category entry
+	Confidence float64  `json:"confidence"`
+	MatchType  string   `json:"match_type"`
+}
+
+// Finding is a visited URL the blacklist lists now.
+type Finding struct {
+	URL       string    `json:"url"`
+	Visits    int       `json:"visits"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Clients   []string  `json:"clients,omitempty"` // Sorted; empty when the log names none
+	Matches   []Match   `json:"matches"`
+}
+
+// Report is the outcome of checking a traffic log against the blacklist.
+type Report struct {
+	Format   string    `json:"format"`
+	Visits   int       `json:"visits"`  // Requests read from the log
+	URLs     int       `json:"urls"`    // Distinct URLs checked
+	Matched  int       `json:"matched"` // Distinct URLs listed now
+	Findings []Finding `json:"findings"`
+}
+
+// Service checks the visits of traffic logs against the current blacklist.
+// Unlike live queries it records no entry hits: the visits are historical.
+type Service struct {
+	repo      repository.BlacklistRepository
+	allowlist Allowlist // nil disables allowlist checks
+}
+
+// NewService creates a Service on the read database pool.
+func NewService() (*Service, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+
+	return NewServiceWithRepository(repository.NewSQLiteRepository(dbConn)).
+		SetAllowlist(allowlist.NewServiceWithRepository(allowlist.NewSQLiteRepository(dbConn))), nil
+}
+
+// NewServiceWithRepository creates a Service on the given repository.
+func NewServiceWithRepository(repo repository.BlacklistRepository) *Service {
+	return &Service{repo: repo}
+}
+
+// SetAllowlist sets the allowlist whose URLs are never reported; nil disables it.
+func (s *Service) SetAllowlist(a Allowlist) *Service {
+	s.allowlist = a
+	return s
+}
+
+// HuntLog parses a log of format (FormatAuto detects it) and hunts its visits.
+func (s *Service) HuntLog(ctx context.Context, r io.Reader, format string) (*Report, error) {
+	visits, format, err := ParseLog(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.Hunt(ctx, visits)
+	if err != nil {
+		return nil, err
+	}
+	report.Format = format
+	return report, nil
+}
+
+// Hunt checks every distinct visited URL against the blacklist, with the
+// mixed match of the query command, and reports the listed ones with their
+// visits, oldest first.
+func (s *Service) Hunt(ctx context.Context, visits []Visit) (*Report, error) {
+	byURL := make(map[string]*Finding)
+	var urls []string
+	for _, v := range visits {
+		f, ok := byURL[v.URL]
+		if !ok {
+			f = &Finding{URL: v.URL, FirstSeen: v.Time, LastSeen: v.Time}
+			byURL[v.URL] = f
+			urls = append(urls, v.URL)
+		}
+		f.Visits++
+		if !v.Time.IsZero() && (f.FirstSeen.IsZero() || v.Time.Before(f.FirstSeen)) {
+			f.FirstSeen = v.Time
+		}
+		if v.Time.After(f.LastSeen) {
+			f.LastSeen = v.Time
+		}
+		if v.Client != "" && !slices.Contains(f.Clients, v.Client) {
+			f.Clients = append(f.Clients, v.Client)
+		}
+	}
+
+	hits, err := s.queryAll(ctx, urls)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, h := range hits {
+		for _, hit := range h {
+			ids = append(ids, hit.ID)
+		}
+	}
+	active, err := s.activeEntries(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Visits: len(visits), URLs: len(urls), Findings: []Finding{}}
+	for i, link := range urls {
+		f := byURL[link]
+		for _, hit := range hits[i] {
+			if e, ok := active[hit.ID]; ok {
+				f.Matches = append(f.Matches, Match{
+					ID:         e.ID,
+					Source:     e.Source,
+					Category:   e.Category,
+					Categories: e.Categories,
+					Confidence: e.Confidence,
+					MatchType:  hit.MatchType,
+				})
+			}
+		}
+		if len(f.Matches) == 0 || s.allowlisted(ctx, link) {
+			continue
+		}
+		slices.Sort(f.Clients)
+		report.Findings = append(report.Findings, *f)
+	}
+	report.Matched = len(report.Findings)
+
+	slices.SortFunc(report.Findings, func(a, b Finding) int {
+		if c := a.FirstSeen.Compare(b.FirstSeen); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.URL, b.URL)
+	})
+	return report, nil
+}
+
+// queryAll returns the hits of every URL, in order, querying huntWorkers
+// URLs at a time.
+func (s *Service) queryAll(ctx context.Context, urls []string) ([][]entries.Hit, error) {
+	mixed := enums.QueryTypeMixed
+	hits := make([][]entries.Hit, len(urls))
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var queryErr error
+	for range min(huntWorkers, len(urls)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				h, err := s.repo.QueryLinkByType(ctx, urls[i], &mixed)
+				if err != nil {
+					once.Do(func() { queryErr = err })
+					continue
+				}
+				hits[i] = h
+			}
+		}()
+	}
+	for i := range urls {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	if queryErr != nil {
+		log.Error().Err(queryErr).Int("urls", len(urls)).Msg("Failed to query blacklist entries for retrohunt")
+		return nil, ErrQueryBlacklist
+	}
+	return hits, nil
+}
+
+// activeEntries loads the entries with ids, skipping deleted ones.
+func (s *Service) activeEntries(ctx context.Context, ids []string) (map[string]*entries.Entry, error) {
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	active := make(map[string]*entries.Entry, len(ids))
+	for chunk := range slices.Chunk(ids, idLookupChunk) {
+		found, err := s.repo.GetEntriesByIDs(ctx, chunk)
+		if err != nil {
+			log.Error().Err(err).Int("ids", len(chunk)).Msg("Failed to load entries for retrohunt")
+			return nil, ErrQueryBlacklist
+		}
+		for _, e := range found {
+			if e.DeletedAt == nil {
+				active[e.ID] = e
+			}
+		}
+	}
+	return active, nil
+}
+
+// allowlisted reports whether link is exempt; a failing allowlist keeps the finding.
+func (s *Service) allowlisted(ctx context.Context, link string) bool {
+	if s.allowlist == nil {
+		return false
+	}
+	allowed, err := s.allowlist.Allowed(ctx, link)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to check allowlist, keeping finding")
+		return false
+	}
+	return allowed
+}
@@ -0,0 +1,153 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrQueryStats = errors.New("failed to query stats rollups from SQLite")
+	ErrSaveStats  = errors.New("failed to save stats rollups in SQLite")
+)
+
+// Repository stores the rollups.
+type Repository interface {
+	// AddCounts adds points to the stored points of the same metric, label
+	// and time, so processes sharing the database sum their counts.
+	AddCounts(ctx context.Context, points []Point) error
+	// SetGauges stores points, replacing those of the same metric, label and time.
+	SetGauges(ctx context.Context, points []Point) error
+	// Series returns the points of metric in [since, until), by label, then oldest first.
+	Series(ctx context.Context, metric string, since, until time.Time) ([]Point, error)
+	// CountEntries returns the number of active entries per source.
+	CountEntries(ctx context.Context) (map[string]int64, error)
+	// Prune deletes the points older than cutoff and returns how many.
+	Prune(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// SQLiteRepository is the SQLite implementation of Repository.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLiteRepository instance.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// AddCounts adds points to the stored ones in one transaction.
+func (r *SQLiteRepository) AddCounts(ctx context.Context, points []Point) error {
+	return r.save(ctx, points, `
+		INSERT INTO stats_timeseries (metric, label, bucket, value) VALUES (?, ?, ?, ?)
+		ON CONFLICT (metric, label, bucket) DO UPDATE SET value = value + excluded.value`)
+}
+
+// SetGauges stores points in one transaction, replacing the stored ones.
+func (r *SQLiteRepository) SetGauges(ctx context.Context, points []Point) error {
+	return r.save(ctx, points, `
+		INSERT INTO stats_timeseries (metric, label, bucket, value) VALUES (?, ?, ?, ?)
+		ON CONFLICT (metric, label, bucket) DO UPDATE SET value = excluded.value`)
+}
+
+func (r *SQLiteRepository) save(ctx context.Context, points []Point, query string) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Err(err).Msg("Failed to begin stats transaction")
+		return ErrSaveStats
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		log.Err(err).Msg("Failed to prepare stats upsert")
+		return ErrSaveStats
+	}
+	defer stmt.Close()
+
+	for _, p := range points {
+		if _, err := stmt.ExecContext(ctx, p.Metric, p.Label, p.At.Unix(), p.Value); err != nil {
+			log.Err(err).Str("metric", p.Metric).Msg("Failed to save stats point")
+			return ErrSaveStats
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Msg("Failed to commit stats points")
+		return ErrSaveStats
+	}
+	return nil
+}
+
+// Series returns the points of metric in [since, until).
+func (r *SQLiteRepository) Series(ctx context.Context, metric string, since, until time.Time) ([]Point, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT label, bucket, value FROM stats_timeseries
+		WHERE metric = ? AND bucket >= ? AND bucket < ?
+		ORDER BY label, bucket`, metric, since.Unix(), until.Unix())
+	if err != nil {
+		log.Err(err).Str("metric", metric).Msg("Failed to query stats series")
+		return nil, ErrQueryStats
+	}
+	defer rows.Close()
+
+	var points []Point
+	for rows.Next() {
+		p := Point{Metric: metric}
+		var bucket int64
+		if err := rows.Scan(&p.Label, &bucket, &p.Value); err != nil {
+			log.Err(err).Msg("Failed to scan stats point")
+			return nil, ErrQueryStats
+		}
+		p.At = time.Unix(bucket, 0).UTC()
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Failed to iterate stats series")
+		return nil, ErrQueryStats
+	}
+	return points, nil
+}
+
+// CountEntries returns the number of active entries per source.
+func (r *SQLiteRepository) CountEntries(ctx context.Context) (map[string]int64, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT source, COUNT(*) FROM entries WHERE deleted_at IS NULL GROUP BY source")
+	if err != nil {
+		log.Err(err).Msg("Failed to count entries per source")
+		return nil, ErrQueryStats
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var source string
+		var n int64
+		if err := rows.Scan(&source, &n); err != nil {
+			log.Err(err).Msg("Failed to scan entry count")
+			return nil, ErrQueryStats
+		}
+		counts[source] = n
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Failed to iterate entry counts")
+		return nil, ErrQueryStats
+	}
+	return counts, nil
+}
+
+// Prune deletes the points older than cutoff.
+func (r *SQLiteRepository) Prune(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM stats_timeseries WHERE bucket < ?", cutoff.Unix())
+	if err != nil {
+		log.Err(err).Msg("Failed to prune stats rollups")
+		return 0, ErrSaveStats
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,254 @@
+package stats
+
+import (
+	"blacked/internal/db"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultRange is the span of a query without since.
+	DefaultRange = 24 * time.Hour
+	// MaxPoints caps the points per series a step may ask for.
+	MaxPoints = 10000
+)
+
+var (
+	ErrDatabaseConnection = errors.New("failed to connect to the database")
+	ErrInvalidQuery       = errors.New("invalid timeseries query")
+	ErrReadSeries         = errors.New("failed to read stats timeseries")
+)
+
+// Metrics lists the metrics served, in the order they are returned.
+var Metrics = []string{MetricQueries, MetricHits, MetricHitRate, MetricEntries}
+
+// Query selects timeseries.
+type Query struct {
+	Metrics []string // Empty is every metric
+	Sources []string // Sources of the entries series; empty is every source
+	Since   time.Time
+	Until   time.Time
+	Step    time.Duration // Width of a returned point; 0 returns the stored points
+}
+
+// Series is one timeseries in the format of Grafana JSON datasources.
+type Series struct {
+	Target     string       `json:"target"`     // Metric; entries:<source> for the entries of a source
+	Datapoints [][2]float64 `json:"datapoints"` // [value, unix milliseconds] pairs, oldest first
+}
+
+// ParseQuery reads a Query from the metric, source, since, until and step
+// query parameters, as of now. Metrics and sources are comma-separated;
+// times are RFC3339 or Unix milliseconds, as Grafana's $__from and $__to.
+func ParseQuery(values url.Values, now time.Time) (Query, error) {
+	q := Query{Until: now}
+	for _, metric := range splitList(values.Get("metric")) {
+		if !slices.Contains(Metrics, metric) {
+			return Query{}, fmt.Errorf("%w: metric must be one of %s", ErrInvalidQuery, strings.Join(Metrics, ", "))
+		}
+		q.Metrics = append(q.Metrics, metric)
+	}
+	q.Sources = splitList(values.Get("source"))
+
+	for key, dst := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		raw := strings.TrimSpace(values.Get(key))
+		if raw == "" {
+			continue
+		}
+		t, err := parseTime(raw)
+		if err != nil {
+			return Query{}, fmt.Errorf("%w: %s must be an RFC3339 time or Unix milliseconds", ErrInvalidQuery, key)
+		}
+		*dst = t
+	}
+	if q.Since.IsZero() {
+		q.Since = q.Until.Add(-DefaultRange)
+	}
+	if !q.Since.Before(q.Until) {
+		return Query{}, fmt.Errorf("%w: since must be before until", ErrInvalidQuery)
+	}
+
+	if raw := strings.TrimSpace(values.Get("step")); raw != "" {
+		step, err := time.ParseDuration(raw)
+		if err != nil || step <= 0 {
+			return Query{}, fmt.Errorf("%w: step must be a positive duration, e.g. 1h", ErrInvalidQuery)
+		}
+		if q.Until.Sub(q.Since)/step > MaxPoints {
+			return Query{}, fmt.Errorf("%w: step gives more than %d points; raise it", ErrInvalidQuery, MaxPoints)
+		}
+		q.Step = step
+	}
+	return q, nil
+}
+
+func splitList(raw string) []string {
+	var list []string
+	for item := range strings.SplitSeq(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func parseTime(raw string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+// Service reads the rollups.
+type Service struct {
+	repo Repository
+}
+
+// NewService creates a Service on the database connection.
+func NewService() (*Service, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+	return NewServiceWithRepository(NewSQLiteRepository(dbConn)), nil
+}
+
+// NewServiceWithRepository creates a Service on the given repository.
+func NewServiceWithRepository(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Timeseries returns the series q selects. With a step, counts are summed
+// per step, the hit rate is computed from the sums and entries keep the last
+// count of each step.
+func (s *Service) Timeseries(ctx context.Context, q Query) ([]Series, error) {
+	metrics := q.Metrics
+	if len(metrics) == 0 {
+		metrics = Metrics
+	}
+
+	stored := make(map[string][]Point)
+	read := func(metric string) ([]Point, error) {
+		if points, ok := stored[metric]; ok {
+			return points, nil
+		}
+		points, err := s.repo.Series(ctx, metric, q.Since, q.Until)
+		if err != nil {
+			return nil, ErrReadSeries
+		}
+		stored[metric] = points
+		return points, nil
+	}
+
+	series := []Series{}
+	for _, metric := range metrics {
+		switch metric {
+		case MetricQueries, MetricHits:
+			points, err := read(metric)
+			if err != nil {
+				return nil, err
+			}
+			series = append(series, Series{Target: metric, Datapoints: datapoints(sumSteps(points, q.Step))})
+
+		case MetricHitRate:
+			queries, err := read(MetricQueries)
+			if err != nil {
+				return nil, err
+			}
+			hits, err := read(MetricHits)
+			if err != nil {
+				return nil, err
+			}
+			series = append(series, Series{Target: metric, Datapoints: datapoints(hitRate(sumSteps(queries, q.Step), sumSteps(hits, q.Step)))})
+
+		case MetricEntries:
+			points, err := read(metric)
+			if err != nil {
+				return nil, err
+			}
+			bySource := make(map[string][]Point)
+			var sources []string
+			for _, p := range points {
+				if len(q.Sources) > 0 && !slices.Contains(q.Sources, p.Label) {
+					continue
+				}
+				if _, ok := bySource[p.Label]; !ok {
+					sources = append(sources, p.Label)
+				}
+				bySource[p.Label] = append(bySource[p.Label], p)
+			}
+			for _, source := range sources {
+				series = append(series, Series{Target: metric + ":" + source, Datapoints: datapoints(lastSteps(bySource[source], q.Step))})
+			}
+		}
+	}
+	return series, nil
+}
+
+// sumSteps sums points, oldest first, per step; a zero step keeps them.
+func sumSteps(points []Point, step time.Duration) []Point {
+	if step <= 0 {
+		return points
+	}
+	var out []Point
+	for _, p := range points {
+		at := p.At.Truncate(step)
+		if n := len(out); n > 0 && out[n-1].At.Equal(at) {
+			out[n-1].Value += p.Value
+			continue
+		}
+		out = append(out, Point{Metric: p.Metric, Label: p.Label, At: at, Value: p.Value})
+	}
+	return out
+}
+
+// lastSteps keeps the last of the points, oldest first, of every step; a
+// zero step keeps them all.
+func lastSteps(points []Point, step time.Duration) []Point {
+	if step <= 0 {
+		return points
+	}
+	var out []Point
+	for _, p := range points {
+		at := p.At.Truncate(step)
+		if n := len(out); n > 0 && out[n-1].At.Equal(at) {
+			out[n-1].Value = p.Value
+			continue
+		}
+		out = append(out, Point{Metric: p.Metric, Label: p.Label, At: at, Value: p.Value})
+	}
+	return out
+}
+
+// hitRate divides the hits by the queries of the same time; times without
+// queries have no rate.
+func hitRate(queries, hits []Point) []Point {
+	hitsAt := make(map[int64]float64, len(hits))
+	for _, p := range hits {
+		hitsAt[p.At.Unix()] = p.Value
+	}
+	var out []Point
+	for _, p := range queries {
+		if p.Value == 0 {
+			continue
+		}
+		out = append(out, Point{Metric: MetricHitRate, At: p.At, Value: hitsAt[p.At.Unix()] / p.Value})
+	}
+	return out
+}
+
+func datapoints(points []Point) [][2]float64 {
+	out := make([][2]float64, len(points))
+	for i, p := range points {
+		out[i] = [2]float64{p.Value, float64(p.At.UnixMilli())}
+	}
+	return out
+}
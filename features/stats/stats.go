@@ -0,0 +1,211 @@
+// Package stats keeps rollups of the queries the query API answers, their
+// hits and the active entries per source in a small timeseries table, so
+// deployments without Prometheus can chart them in Grafana straight from
+// /stats/timeseries. Rollups are written off the query path and deleted once
+// older than the configured retention.
+package stats
+
+import (
+	"blacked/internal/config"
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Metrics of the timeseries table.
+const (
+	MetricQueries = "queries"  // URLs answered by the query API per interval
+	MetricHits    = "hits"     // Answered URLs that were listed, per interval
+	MetricHitRate = "hit_rate" // Hits / queries per interval; computed when read
+	MetricEntries = "entries"  // Active entries, labelled with their source
+)
+
+// pruneInterval is how often rollups past the retention are deleted.
+const pruneInterval = time.Hour
+
+// Point is one stored value of a metric: a count over the interval starting
+// At, or for entries the count taken At.
+type Point struct {
+	Metric string
+	Label  string // Source of an entries point; empty for the others
+	At     time.Time
+	Value  float64
+}
+
+var (
+	globalRecorder *Recorder
+	once           sync.Once
+)
+
+// Init starts the global recorder on db when [Stats] is enabled and returns
+// it; nil means rollups are off.
+func Init(db *sql.DB) *Recorder {
+	once.Do(func() {
+		cfg := config.GetConfig().Stats
+		if !cfg.Enabled {
+			log.Debug().Msg("Stats rollups disabled")
+			return
+		}
+		globalRecorder = NewRecorder(NewSQLiteRepository(db), cfg)
+		log.Info().
+			Dur("interval", cfg.Interval).
+			Dur("entries_interval", cfg.EntriesInterval).
+			Dur("retention", cfg.Retention).
+			Msg("Stats rollups enabled")
+	})
+	return globalRecorder
+}
+
+// Get returns the global recorder, or nil when rollups are off.
+func Get() *Recorder {
+	return globalRecorder
+}
+
+// Recorder counts answered queries and hits in memory and writes them as one
+// point per interval; a background loop also counts the active entries per
+// source and prunes old rollups.
+type Recorder struct {
+	repo            Repository
+	interval        time.Duration
+	entriesInterval time.Duration
+	retention       time.Duration
+
+	queries atomic.Int64
+	hits    atomic.Int64
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewRecorder starts a Recorder with the intervals and retention of cfg.
+func NewRecorder(repo Repository, cfg config.StatsConfig) *Recorder {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.EntriesInterval <= 0 {
+		cfg.EntriesInterval = 15 * time.Minute
+	}
+
+	r := &Recorder{
+		repo:            repo,
+		interval:        cfg.Interval,
+		entriesInterval: cfg.EntriesInterval,
+		retention:       max(cfg.Retention, 0),
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Record counts queries answered URLs, hits of them listed. It never blocks.
+func (r *Recorder) Record(queries, hits int) {
+	r.queries.Add(int64(queries))
+	r.hits.Add(int64(hits))
+}
+
+// Close stops the recorder after writing the counts of the current interval.
+func (r *Recorder) Close() {
+	r.stopOnce.Do(func() { close(r.stop) })
+	<-r.done
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	// Intervals are aligned to the clock, so processes sharing the database
+	// add their counts to the same points.
+	timer := time.NewTimer(untilNext(time.Now(), r.interval))
+	defer timer.Stop()
+	entries := time.NewTicker(r.entriesInterval)
+	defer entries.Stop()
+	pruner := time.NewTicker(pruneInterval)
+	defer pruner.Stop()
+
+	r.prune()
+	r.countEntries(time.Now())
+	for {
+		select {
+		case now := <-timer.C:
+			r.flush(now.Truncate(r.interval).Add(-r.interval))
+			timer.Reset(untilNext(time.Now(), r.interval))
+		case now := <-entries.C:
+			r.countEntries(now)
+		case <-pruner.C:
+			r.prune()
+		case <-r.stop:
+			r.flush(time.Now().Truncate(r.interval))
+			return
+		}
+	}
+}
+
+// untilNext returns the time from now to the next multiple of interval.
+func untilNext(now time.Time, interval time.Duration) time.Duration {
+	return now.Truncate(interval).Add(interval).Sub(now)
+}
+
+// flush writes the counts since the last flush as the points of the interval
+// starting at bucket. Counts that fail to save are kept for the next flush.
+func (r *Recorder) flush(bucket time.Time) {
+	queries, hits := r.queries.Swap(0), r.hits.Swap(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := r.repo.AddCounts(ctx, []Point{
+		{Metric: MetricQueries, At: bucket, Value: float64(queries)},
+		{Metric: MetricHits, At: bucket, Value: float64(hits)},
+	})
+	if err != nil {
+		log.Warn().Err(err).Int64("queries", queries).Msg("Failed to save query rollups, retrying next interval")
+		r.Record(int(queries), int(hits))
+		return
+	}
+	log.Trace().Int64("queries", queries).Int64("hits", hits).Time("bucket", bucket).Msg("Query rollups saved")
+}
+
+// countEntries stores the active entries per source as of now.
+func (r *Recorder) countEntries(now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	counts, err := r.repo.CountEntries(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to count entries per source")
+		return
+	}
+
+	at := now.Truncate(r.entriesInterval)
+	points := make([]Point, 0, len(counts))
+	for source, n := range counts {
+		points = append(points, Point{Metric: MetricEntries, Label: source, At: at, Value: float64(n)})
+	}
+	if err := r.repo.SetGauges(ctx, points); err != nil {
+		log.Warn().Err(err).Msg("Failed to save entry rollups")
+	}
+}
+
+// prune deletes the rollups older than the retention.
+func (r *Recorder) prune() {
+	if r.retention == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	deleted, err := r.repo.Prune(ctx, time.Now().Add(-r.retention))
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to prune stats rollups")
+		return
+	}
+	if deleted > 0 {
+		log.Debug().Int64("deleted", deleted).Dur("retention", r.retention).Msg("Pruned stats rollups")
+	}
+}
@@ -0,0 +1,115 @@
+package stats
+
+import (
+	"blacked/internal/config"
+	idb "blacked/internal/db"
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorderFlushesOnClose(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	_, err = conn.Exec(`INSERT INTO entries (id, source, source_url) VALUES
+		('1', 'oisd-big', 'https://a.example/'), ('2', 'oisd-big', 'https://b.example/'), ('3', 'urlhaus', 'https://c.example/')`)
+	require.NoError(t, err)
+
+	repo := NewSQLiteRepository(conn)
+	recorder := NewRecorder(repo, config.StatsConfig{Interval: time.Hour, EntriesInterval: time.Hour, Retention: 24 * time.Hour})
+	recorder.Record(3, 1)
+	recorder.Record(1, 1)
+	recorder.Close()
+
+	svc := NewServiceWithRepository(repo)
+	now := time.Now()
+	series, err := svc.Timeseries(ctx, Query{Since: now.Add(-2 * time.Hour), Until: now.Add(time.Hour)})
+	require.NoError(t, err)
+
+	targets := make(map[string][][2]float64)
+	for _, s := range series {
+		targets[s.Target] = s.Datapoints
+	}
+	require.Len(t, targets, 5, "queries, hits, hit_rate and the entries of two sources")
+	require.Len(t, targets[MetricQueries], 1)
+	assert.Equal(t, 4.0, targets[MetricQueries][0][0])
+	assert.Equal(t, 2.0, targets[MetricHits][0][0])
+	assert.Equal(t, 0.5, targets[MetricHitRate][0][0])
+	assert.Equal(t, float64(now.Truncate(time.Hour).UnixMilli()), targets[MetricQueries][0][1])
+	assert.Equal(t, 2.0, targets["entries:oisd-big"][0][0])
+	assert.Equal(t, 1.0, targets["entries:urlhaus"][0][0])
+}
+
+func TestTimeseriesSteps(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := NewSQLiteRepository(conn)
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		at := start.Add(time.Duration(i) * 30 * time.Minute)
+		require.NoError(t, repo.AddCounts(ctx, []Point{
+			{Metric: MetricQueries, At: at, Value: 10},
+			{Metric: MetricHits, At: at, Value: float64(i)},
+		}))
+		require.NoError(t, repo.SetGauges(ctx, []Point{{Metric: MetricEntries, Label: "oisd-big", At: at, Value: float64(100 + i)}}))
+	}
+	// A second process adds to the same interval.
+	require.NoError(t, repo.AddCounts(ctx, []Point{{Metric: MetricQueries, At: start, Value: 10}}))
+
+	svc := NewServiceWithRepository(repo)
+	q, err := ParseQuery(url.Values{
+		"metric": {"queries,hit_rate,entries"},
+		"since":  {"1717200000000"}, // 2024-06-01T00:00:00Z in milliseconds
+		"until":  {"2024-06-01T02:00:00Z"},
+		"step":   {"1h"},
+	}, time.Now())
+	require.NoError(t, err)
+
+	series, err := svc.Timeseries(ctx, q)
+	require.NoError(t, err)
+	require.Len(t, series, 3)
+
+	assert.Equal(t, MetricQueries, series[0].Target)
+	assert.Equal(t, [][2]float64{{30, float64(start.UnixMilli())}, {20, float64(start.Add(time.Hour).UnixMilli())}}, series[0].Datapoints)
+	assert.Equal(t, [][2]float64{{1.0 / 30, float64(start.UnixMilli())}, {5.0 / 20, float64(start.Add(time.Hour).UnixMilli())}}, series[1].Datapoints)
+	assert.Equal(t, "entries:oisd-big", series[2].Target)
+	assert.Equal(t, [][2]float64{{101, float64(start.UnixMilli())}, {103, float64(start.Add(time.Hour).UnixMilli())}}, series[2].Datapoints, "entries keep the last count of a step")
+
+	deleted, err := repo.Prune(ctx, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.EqualValues(t, 6, deleted)
+}
+
+func TestParseQuery(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	q, err := ParseQuery(url.Values{}, now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-DefaultRange), q.Since)
+	assert.Equal(t, now, q.Until)
+	assert.Empty(t, q.Metrics)
+
+	for name, values := range map[string]url.Values{
+		"unknown metric":  {"metric": {"latency"}},
+		"invalid since":   {"since": {"yesterday"}},
+		"since not first": {"since": {"2024-06-02T00:00:00Z"}},
+		"invalid step":    {"step": {"-1m"}},
+		"too many points": {"since": {"2024-01-01T00:00:00Z"}, "step": {"1s"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseQuery(values, now)
+			assert.ErrorIs(t, err, ErrInvalidQuery)
+		})
+	}
+}
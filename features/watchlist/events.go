@@ -0,0 +1,96 @@
+package watchlist
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventWatchlistMatch is the type of events raised when a newly ingested
+// entry is the first on its host to match a watchlist.
+const EventWatchlistMatch = "watchlist_match"
+
+// subscriberBuffer is how many events a slow subscriber may lag behind
+// before it misses some.
+const subscriberBuffer = 64
+
+// Event is the webhook payload and SSE data of one watchlist match.
+type Event struct {
+	Type        string     `json:"type"`
+	WatchlistID string     `json:"watchlist_id"`
+	Watchlist   string     `json:"watchlist"`
+	MatchKind   string     `json:"match_kind"` // suffix, brand or keyword
+	Pattern     string     `json:"pattern"`
+	Provider    string     `json:"provider"`
+	ProcessID   string     `json:"process_id"`
+	FirstSeenAt time.Time  `json:"first_seen_at"`
+	Entry       EventEntry `json:"entry"`
+}
+
+// EventEntry is the entry that matched.
+type EventEntry struct {
+	ID         string   `json:"id"`
+	SourceURL  string   `json:"source_url"`
+	Host       string   `json:"host"`
+	Domain     string   `json:"domain"`
+	Source     string   `json:"source"`
+	Category   string   `json:"category,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Confidence float64  `json:"confidence"`
+}
+
+// Broker fans events out to the SSE subscribers of this process.
+type Broker struct {
+	mu      sync.Mutex
+	subs    map[chan Event]struct{}
+	dropped atomic.Int64
+}
+
+var events = NewBroker()
+
+// Events returns the broker the ingestion pipeline publishes to.
+func Events() *Broker {
+	return events
+}
+
+// NewBroker creates a Broker without subscribers.
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel receiving every event published from now on
+// and a function ending the subscription.
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// Publish sends ev to every subscriber. It never blocks: subscribers whose
+// buffer is full miss the event, since ingestion must not wait on clients.
+func (b *Broker) Publish(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+			b.dropped.Add(1)
+		}
+	}
+}
+
+// Dropped returns the number of events subscribers missed.
+func (b *Broker) Dropped() int64 {
+	return b.dropped.Load()
+}
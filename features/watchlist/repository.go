@@ -0,0 +1,183 @@
+package watchlist
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrQueryWatchlists  = errors.New("failed to query watchlists from SQLite")
+	ErrSaveWatchlist    = errors.New("failed to save watchlist in SQLite")
+	ErrDeleteWatchlist  = errors.New("failed to delete watchlist in SQLite")
+	ErrMarkWatchlistHit = errors.New("failed to record watchlist match in SQLite")
+)
+
+// Repository stores watchlists and the hosts already reported for each.
+type Repository interface {
+	List(ctx context.Context) ([]Watchlist, error)
+	Get(ctx context.Context, id string) (*Watchlist, error)
+	Add(ctx context.Context, w Watchlist) error // ErrWatchlistExists when the name is taken
+	Delete(ctx context.Context, id string) error
+	// MarkSeen records host as reported for the watchlist and reports
+	// whether it is the first time.
+	MarkSeen(ctx context.Context, watchlistID, host, entryID string, at time.Time) (bool, error)
+}
+
+// SQLiteRepository is the SQLite implementation of Repository.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLiteRepository instance.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+const watchlistColumns = "id, name, suffixes, keywords, brands, webhook_url, created_at"
+
+func scanWatchlist(row interface{ Scan(...any) error }) (*Watchlist, error) {
+	var (
+		w                          Watchlist
+		suffixes, keywords, brands sql.NullString
+		webhookURL                 sql.NullString
+		createdAt                  int64
+	)
+	if err := row.Scan(&w.ID, &w.Name, &suffixes, &keywords, &brands, &webhookURL, &createdAt); err != nil {
+		return nil, err
+	}
+	for _, col := range []struct {
+		raw sql.NullString
+		dst *[]string
+	}{{suffixes, &w.Suffixes}, {keywords, &w.Keywords}, {brands, &w.Brands}} {
+		if col.raw.Valid && col.raw.String != "" {
+			if err := json.Unmarshal([]byte(col.raw.String), col.dst); err != nil {
+				return nil, err
+			}
+		}
+	}
+	w.WebhookURL = webhookURL.String
+	w.CreatedAt = time.Unix(0, createdAt).UTC()
+	return &w, nil
+}
+
+// encodePatterns stores patterns as a JSON array; NULL when empty.
+func encodePatterns(patterns []string) any {
+	if len(patterns) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(patterns)
+	return string(b)
+}
+
+// List returns every watchlist, oldest first.
+func (r *SQLiteRepository) List(ctx context.Context) ([]Watchlist, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+watchlistColumns+" FROM watchlists ORDER BY created_at, id")
+	if err != nil {
+		log.Err(err).Msg("Failed to query watchlists")
+		return nil, ErrQueryWatchlists
+	}
+	defer rows.Close()
+
+	lists := []Watchlist{}
+	for rows.Next() {
+		w, err := scanWatchlist(rows)
+		if err != nil {
+			log.Err(err).Msg("Failed to scan watchlist")
+			return nil, ErrQueryWatchlists
+		}
+		lists = append(lists, *w)
+	}
+	if err := rows.Err(); err != nil {
+		log.Err(err).Msg("Rows iteration error for watchlists")
+		return nil, ErrQueryWatchlists
+	}
+	return lists, nil
+}
+
+// Get returns the watchlist with id, or ErrWatchlistNotFound.
+func (r *SQLiteRepository) Get(ctx context.Context, id string) (*Watchlist, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+watchlistColumns+" FROM watchlists WHERE id = ?", id)
+	w, err := scanWatchlist(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWatchlistNotFound
+	}
+	if err != nil {
+		log.Err(err).Str("id", id).Msg("Failed to query watchlist")
+		return nil, ErrQueryWatchlists
+	}
+	return w, nil
+}
+
+// Add inserts w; an existing watchlist with the same name is left untouched.
+func (r *SQLiteRepository) Add(ctx context.Context, w Watchlist) error {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO watchlists (id, name, suffixes, keywords, brands, webhook_url, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (name) DO NOTHING`,
+		w.ID, w.Name, encodePatterns(w.Suffixes), encodePatterns(w.Keywords), encodePatterns(w.Brands),
+		w.WebhookURL, w.CreatedAt.UnixNano(),
+	)
+	if err != nil {
+		log.Err(err).Str("name", w.Name).Msg("Failed to insert watchlist")
+		return ErrSaveWatchlist
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrWatchlistExists
+	}
+	return nil
+}
+
+// Delete removes the watchlist with id and its reported hosts, or returns
+// ErrWatchlistNotFound.
+func (r *SQLiteRepository) Delete(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Err(err).Str("id", id).Msg("Failed to begin watchlist delete")
+		return ErrDeleteWatchlist
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM watchlists WHERE id = ?", id)
+	if err != nil {
+		log.Err(err).Str("id", id).Msg("Failed to delete watchlist")
+		return ErrDeleteWatchlist
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrWatchlistNotFound
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM watchlist_seen WHERE watchlist_id = ?", id); err != nil {
+		log.Err(err).Str("id", id).Msg("Failed to delete watchlist matches")
+		return ErrDeleteWatchlist
+	}
+	if err := tx.Commit(); err != nil {
+		log.Err(err).Str("id", id).Msg("Failed to commit watchlist delete")
+		return ErrDeleteWatchlist
+	}
+	return nil
+}
+
+// MarkSeen inserts the (watchlist, host) pair; an existing pair means the
+// host was reported before.
+func (r *SQLiteRepository) MarkSeen(ctx context.Context, watchlistID, host, entryID string, at time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO watchlist_seen (watchlist_id, host, entry_id, first_seen_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (watchlist_id, host) DO NOTHING`,
+		watchlistID, host, entryID, at.UnixNano(),
+	)
+	if err != nil {
+		log.Err(err).Str("watchlist_id", watchlistID).Msg("Failed to record watchlist match")
+		return false, ErrMarkWatchlistHit
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		log.Err(err).Str("watchlist_id", watchlistID).Msg("Failed to read watchlist match result")
+		return false, ErrMarkWatchlistHit
+	}
+	return n == 1, nil
+}
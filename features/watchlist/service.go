@@ -0,0 +1,221 @@
+package watchlist
+
+import (
+	"blacked/features/alerts"
+	"blacked/features/entries"
+	"blacked/internal/config"
+	"blacked/internal/db"
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrDatabaseConnection = errors.New("failed to connect to the database")
+	ErrScanAdditions      = errors.New("failed to scan provider additions for watchlists")
+)
+
+// EntryStreamer is the repository method NotifyProviderAdditions needs;
+// implemented by the entries SQLite repository.
+type EntryStreamer interface {
+	StreamEntriesActivatedSince(ctx context.Context, source string, since int64, fn func(*entries.Entry) error) error
+}
+
+// Service manages watchlists and raises their events.
+type Service struct {
+	repo   Repository
+	broker *Broker
+}
+
+var (
+	globalService *Service
+	once          sync.Once
+)
+
+// Init creates the global service on db, the one provider runs raise their
+// watchlist events through, and returns it.
+func Init(db *sql.DB) *Service {
+	once.Do(func() {
+		globalService = NewServiceWithRepository(NewSQLiteRepository(db), Events())
+	})
+	return globalService
+}
+
+// Get returns the global service, or nil before Init.
+func Get() *Service {
+	return globalService
+}
+
+// NewService creates a Service on the write database connection,
+// publishing to the process-wide broker.
+func NewService() (*Service, error) {
+	dbConn, err := db.GetWriteDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database connection")
+		return nil, ErrDatabaseConnection
+	}
+
+	return NewServiceWithRepository(NewSQLiteRepository(dbConn), Events()), nil
+}
+
+// NewServiceWithRepository creates a Service on the given repository and broker.
+func NewServiceWithRepository(repo Repository, broker *Broker) *Service {
+	return &Service{repo: repo, broker: broker}
+}
+
+// Broker returns the broker the service publishes events to.
+func (s *Service) Broker() *Broker {
+	return s.broker
+}
+
+// List returns every watchlist.
+func (s *Service) List(ctx context.Context) ([]Watchlist, error) {
+	return s.repo.List(ctx)
+}
+
+// Active reports whether any watchlist is registered; provider runs skip
+// the scan of their additions otherwise.
+func (s *Service) Active(ctx context.Context) (bool, error) {
+	lists, err := s.repo.List(ctx)
+	return len(lists) > 0, err
+}
+
+// Get returns the watchlist with id.
+func (s *Service) Get(ctx context.Context, id string) (*Watchlist, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// Add normalizes and stores a new watchlist.
+func (s *Service) Add(ctx context.Context, w Watchlist) (*Watchlist, error) {
+	if err := w.Normalize(); err != nil {
+		return nil, err
+	}
+	w.ID = uuid.New().String()
+	w.CreatedAt = time.Now().UTC()
+
+	if err := s.repo.Add(ctx, w); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("id", w.ID).Str("name", w.Name).
+		Int("suffixes", len(w.Suffixes)).Int("keywords", len(w.Keywords)).Int("brands", len(w.Brands)).
+		Msg("Watchlist added")
+	return &w, nil
+}
+
+// Delete removes the watchlist with id.
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	log.Info().Str("id", id).Msg("Watchlist removed")
+	return nil
+}
+
+// candidate is a match found while scanning, not yet checked for first-seen.
+type candidate struct {
+	list  *Watchlist
+	kind  string
+	match string
+	entry *entries.Entry
+}
+
+// NotifyProviderAdditions matches the entries provider added or reactivated
+// since the run started against every watchlist. Each host matching a
+// watchlist for the first time raises an event, published to the SSE
+// subscribers and posted to the watchlist's webhook, or the configured one.
+// At most cfg.MaxEvents events are raised per run.
+func (s *Service) NotifyProviderAdditions(ctx context.Context, cfg config.WatchlistConfig, repo EntryStreamer, provider, processID string, since time.Time) error {
+	lists, err := s.repo.List(ctx)
+	if err != nil || len(lists) == 0 {
+		return err
+	}
+
+	// Hosts are checked once the scan is done, so the stream never waits
+	// on writes to the same database.
+	var candidates []candidate
+	seen := make(map[[2]string]bool)
+	err = repo.StreamEntriesActivatedSince(ctx, provider, since.UnixNano(), func(e *entries.Entry) error {
+		for i := range lists {
+			kind, pattern, ok := lists[i].Match(e)
+			if !ok || seen[[2]string{lists[i].ID, e.Host}] {
+				continue
+			}
+			seen[[2]string{lists[i].ID, e.Host}] = true
+			entry := *e
+			candidates = append(candidates, candidate{list: &lists[i], kind: kind, match: pattern, entry: &entry})
+		}
+		return nil
+	})
+	if err != nil {
+		log.Err(err).Str("provider", provider).Msg("Failed to scan provider additions for watchlists")
+		return ErrScanAdditions
+	}
+
+	var raised, dropped int
+	var sendErr error
+	for _, c := range candidates {
+		if cfg.MaxEvents > 0 && raised >= cfg.MaxEvents {
+			dropped++
+			continue
+		}
+
+		now := time.Now().UTC()
+		first, err := s.repo.MarkSeen(ctx, c.list.ID, c.entry.Host, c.entry.ID, now)
+		if err != nil {
+			return err
+		}
+		if !first {
+			continue
+		}
+		raised++
+
+		ev := Event{
+			Type:        EventWatchlistMatch,
+			WatchlistID: c.list.ID,
+			Watchlist:   c.list.Name,
+			MatchKind:   c.kind,
+			Pattern:     c.match,
+			Provider:    provider,
+			ProcessID:   processID,
+			FirstSeenAt: now,
+			Entry: EventEntry{
+				ID:         c.entry.ID,
+				SourceURL:  c.entry.SourceURL,
+				Host:       c.entry.Host,
+				Domain:     c.entry.Domain,
+				Source:     c.entry.Source,
+				Category:   c.entry.Category,
+				Categories: c.entry.Categories,
+				Confidence: c.entry.Confidence,
+			},
+		}
+		s.broker.Publish(ev)
+
+		webhookURL := c.list.WebhookURL
+		if webhookURL == "" {
+			webhookURL = cfg.WebhookURL
+		}
+		if webhookURL != "" {
+			if err := alerts.NewWebhook(webhookURL, cfg.Timeout).Post(ctx, ev.Type, ev); err != nil && sendErr == nil {
+				sendErr = err
+			}
+		}
+	}
+
+	if raised > 0 || dropped > 0 {
+		log.Warn().
+			Str("provider", provider).
+			Str("process_id", processID).
+			Int("events", raised).
+			Int("dropped", dropped).
+			Msg("Provider added entries matching watchlists")
+	}
+	return sendErr
+}
@@ -0,0 +1,149 @@
+// Package watchlist notifies brand-protection teams as soon as newly
+// ingested entries match the domain suffixes, keywords or brand names they
+// watch, by webhook and server-sent events.
+package watchlist
+
+import (
+	"blacked/features/entries"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidWatchlist  = errors.New("invalid watchlist")
+	ErrWatchlistExists   = errors.New("watchlist already exists")
+	ErrWatchlistNotFound = errors.New("watchlist not found")
+)
+
+// How an entry matched a watchlist.
+const (
+	MatchSuffix  = "suffix"  // The host is the domain or one of its subdomains
+	MatchBrand   = "brand"   // The host spells the brand, ignoring separators and lookalike digits
+	MatchKeyword = "keyword" // The host or path contains the keyword
+)
+
+// maxPatterns caps the patterns of one watchlist, over all kinds.
+const maxPatterns = 1000
+
+// Watchlist is one set of patterns watched for in newly ingested entries.
+type Watchlist struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Suffixes   []string  `json:"suffixes,omitempty"` // Lowercase domains, e.g. "example.com"
+	Keywords   []string  `json:"keywords,omitempty"` // Lowercase substrings of the host or path
+	Brands     []string  `json:"brands,omitempty"`   // Brand names, e.g. "Example Bank"
+	WebhookURL string    `json:"webhook_url,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Normalize validates w and puts its patterns in stored form: trimmed,
+// lowercase and without duplicates.
+func (w *Watchlist) Normalize() error {
+	w.Name = strings.TrimSpace(w.Name)
+	if w.Name == "" {
+		return errors.Join(ErrInvalidWatchlist, errors.New("name is required"))
+	}
+
+	w.Suffixes = normalizePatterns(w.Suffixes, func(s string) string {
+		return strings.Trim(strings.TrimPrefix(strings.ToLower(s), "*."), ".")
+	})
+	w.Keywords = normalizePatterns(w.Keywords, strings.ToLower)
+	w.Brands = normalizePatterns(w.Brands, func(s string) string {
+		if brandKey(s) == "" {
+			return "" // Nothing left to match
+		}
+		return s
+	})
+
+	total := len(w.Suffixes) + len(w.Keywords) + len(w.Brands)
+	if total == 0 {
+		return errors.Join(ErrInvalidWatchlist, errors.New("at least one suffix, keyword or brand is required"))
+	}
+	if total > maxPatterns {
+		return errors.Join(ErrInvalidWatchlist, errors.New("a watchlist holds at most 1000 patterns"))
+	}
+	for _, s := range w.Suffixes {
+		if strings.ContainsAny(s, "/:*? ") {
+			return errors.Join(ErrInvalidWatchlist, errors.New("suffixes must be domain names, e.g. example.com"))
+		}
+	}
+
+	if w.WebhookURL != "" {
+		u, err := url.Parse(w.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.Join(ErrInvalidWatchlist, errors.New("webhook_url must be an http or https URL"))
+		}
+	}
+	return nil
+}
+
+func normalizePatterns(patterns []string, normalize func(string) string) []string {
+	var out []string
+	for _, p := range patterns {
+		p = normalize(strings.TrimSpace(p))
+		if p != "" && !slices.Contains(out, p) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Match returns how e matches w and the pattern it matched. Suffixes are
+// tried first, then brands, then keywords.
+func (w *Watchlist) Match(e *entries.Entry) (kind, pattern string, ok bool) {
+	host := strings.TrimSuffix(strings.ToLower(e.Host), ".")
+	if host == "" {
+		return "", "", false
+	}
+
+	for _, s := range w.Suffixes {
+		if host == s || strings.HasSuffix(host, "."+s) {
+			return MatchSuffix, s, true
+		}
+	}
+
+	hostKey := brandKey(host)
+	for _, b := range w.Brands {
+		if strings.Contains(hostKey, brandKey(b)) {
+			return MatchBrand, b, true
+		}
+	}
+
+	target := host + strings.ToLower(e.Path)
+	for _, k := range w.Keywords {
+		if strings.Contains(target, k) {
+			return MatchKeyword, k, true
+		}
+	}
+	return "", "", false
+}
+
+// lookalikes maps the characters phishing hosts swap into brand names.
+var lookalikes = map[rune]rune{
+	'0': 'o',
+	'1': 'l',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'@': 'a',
+	'$': 's',
+}
+
+// brandKey folds s for brand matching: lowercase letters and digits only,
+// with lookalike characters replaced, so "ex4mple-bank" matches "Example Bank".
+func brandKey(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if l, ok := lookalikes[r]; ok {
+			r = l
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package watchlist
+
+import (
+	"blacked/features/entries"
+	"blacked/features/entries/repository"
+	"blacked/internal/config"
+	idb "blacked/internal/db"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	w := Watchlist{
+		Name:     " bank ",
+		Suffixes: []string{"*.Example.com.", "example.com", " "},
+		Keywords: []string{"Secure-Login"},
+		Brands:   []string{"Example Bank", "!!"},
+	}
+	require.NoError(t, w.Normalize())
+	assert.Equal(t, "bank", w.Name)
+	assert.Equal(t, []string{"example.com"}, w.Suffixes)
+	assert.Equal(t, []string{"secure-login"}, w.Keywords)
+	assert.Equal(t, []string{"Example Bank"}, w.Brands, "brands without letters are dropped")
+
+	for link, want := range map[string]string{
+		"https://login.example.com/":                MatchSuffix,
+		"https://ex4mple-bank.verify.test/":         MatchBrand,
+		"https://examp1ebank.test/":                 MatchBrand,
+		"https://mail.test/account/secure-login/?a": MatchKeyword,
+		"https://notexample.com/":                   "",
+		"https://bank.example.net/":                 "",
+	} {
+		e, err := entries.FromURL(link, "feed", "p1")
+		require.NoError(t, err)
+		kind, _, ok := w.Match(e)
+		assert.Equal(t, want != "", ok, link)
+		assert.Equal(t, want, kind, link)
+	}
+
+	bad := Watchlist{Name: "x"}
+	assert.ErrorIs(t, bad.Normalize(), ErrInvalidWatchlist)
+	bad = Watchlist{Name: "x", Suffixes: []string{"example.com/path"}}
+	assert.ErrorIs(t, bad.Normalize(), ErrInvalidWatchlist)
+	bad = Watchlist{Name: "x", Keywords: []string{"a"}, WebhookURL: "ftp://hooks.test"}
+	assert.ErrorIs(t, bad.Normalize(), ErrInvalidWatchlist)
+}
+
+func TestNotifyProviderAdditions(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+
+	ctx := context.Background()
+	repo := repository.NewSQLiteRepository(conn)
+	save := func(source string, links ...string) {
+		var batch []*entries.Entry
+		for _, l := range links {
+			e, err := entries.FromURL(l, source, "p1")
+			require.NoError(t, err)
+			batch = append(batch, e)
+		}
+		require.NoError(t, repo.BatchSaveEntries(ctx, batch))
+	}
+
+	var (
+		mu    sync.Mutex
+		posts []Event
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&ev))
+		mu.Lock()
+		posts = append(posts, ev)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	svc := NewServiceWithRepository(NewSQLiteRepository(conn), NewBroker())
+	active, err := svc.Active(ctx)
+	require.NoError(t, err)
+	assert.False(t, active, "runs skip the scan without watchlists")
+	_, err = svc.Add(ctx, Watchlist{Name: "bank", Suffixes: []string{"example.com"}, Brands: []string{"Example Bank"}})
+	require.NoError(t, err)
+	active, err = svc.Active(ctx)
+	require.NoError(t, err)
+	assert.True(t, active)
+	_, err = svc.Add(ctx, Watchlist{Name: "bank"})
+	assert.ErrorIs(t, err, ErrInvalidWatchlist)
+	_, err = svc.Add(ctx, Watchlist{Name: "bank", Keywords: []string{"x"}})
+	assert.ErrorIs(t, err, ErrWatchlistExists)
+
+	events, cancel := svc.Broker().Subscribe()
+	defer cancel()
+
+	save("feed", "https://old.example.com/") // Listed before the run started
+	time.Sleep(time.Millisecond)
+	since := time.Now()
+	save("feed",
+		"https://login.example.com/a",
+		"https://login.example.com/b", // Same host: one event
+		"https://examplebank-verify.test/",
+		"https://unrelated.test/",
+	)
+
+	cfg := config.WatchlistConfig{WebhookURL: srv.URL, MaxEvents: 10, Timeout: time.Second}
+	require.NoError(t, svc.NotifyProviderAdditions(ctx, cfg, repo, "feed", "p1", since))
+
+	require.Len(t, posts, 2)
+	hosts := map[string]string{}
+	for _, ev := range posts {
+		assert.Equal(t, EventWatchlistMatch, ev.Type)
+		assert.Equal(t, "bank", ev.Watchlist)
+		assert.Equal(t, "feed", ev.Provider)
+		hosts[ev.Entry.Host] = ev.MatchKind
+	}
+	assert.Equal(t, map[string]string{"login.example.com": MatchSuffix, "examplebank-verify.test": MatchBrand}, hosts)
+	assert.Len(t, events, 2, "events are published to SSE subscribers")
+
+	// Hosts already reported stay quiet on later runs.
+	require.NoError(t, svc.NotifyProviderAdditions(ctx, cfg, repo, "feed", "p2", since))
+	assert.Len(t, posts, 2)
+}
@@ -0,0 +1,46 @@
+package web
+
+import (
+	v2 "blacked/features/web/handlers/v2"
+	"blacked/internal/config"
+	"errors"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrInvalidResponseTemplate = errors.New("invalid query API response template")
+
+// mapQueryAPI mounts the query API once per response schema version, at
+// /api/v1, /api/v2, ..., each reshaped by its [Server.response_templates]
+// entry, and the unversioned edge check. Old versions keep their shape as
+// the schema evolves, so existing integrations pin the version they were
+// written against.
+func mapQueryAPI(e *echo.Echo, handler *v2.QueryHandler, templates map[string]config.ResponseTemplate) error {
+	for version := range templates {
+		if _, err := v2.NewSchema(version, config.ResponseTemplate{}); err != nil {
+			log.Err(err).Str("version", version).Msg("Response template for an unknown query API version")
+			return ErrInvalidResponseTemplate
+		}
+	}
+
+	for _, version := range v2.Versions {
+		schema, err := v2.NewSchema(version, templates[version])
+		if err != nil {
+			log.Err(err).Str("version", version).Msg("Invalid query API response template")
+			return ErrInvalidResponseTemplate
+		}
+		v2.MapQueryRoutes(e.Group("/api/"+version), handler.WithSchema(schema))
+
+		log.Info().
+			Str("check", "GET /api/"+version+"/check?url=").
+			Str("hit", "GET /api/"+version+"/hit?url=").
+			Str("bulk-check", "POST /api/"+version+"/bulk-check").
+			Str("bulk-hit", "POST /api/"+version+"/bulk-hit").
+			Bool("template", len(templates[version].Rename) > 0 || len(templates[version].Omit) > 0).
+			Msg("Query API routes mapped successfully.")
+	}
+
+	v2.MapEdgeCheckRoute(e, handler)
+	return nil
+}
@@ -1,10 +1,12 @@
 package web
 
 import (
+	"blacked/features/maintenance"
 	"blacked/features/providers"
 	"blacked/features/web/middlewares"
 	"blacked/internal/collector"
 	"blacked/internal/config"
+	"blacked/internal/logger"
 	"errors"
 	"strconv"
 	"sync"
@@ -30,6 +32,7 @@ var (
 	ErrServiceInitFailed         = errors.New("services initialization failed")
 	ErrRoutesMapFailed           = errors.New("routes configuration failed")
 	ErrMetricCollectorFailed     = errors.New("metric collector configuration failed")
+	ErrTrustedProxiesFailed      = errors.New("trusted proxies configuration failed")
 )
 
 // Global variables (singleton pattern)
@@ -51,6 +54,11 @@ func (app *Application) GetProviders() *providers.Providers {
 	return app.providers
 }
 
+// Services returns the services the routes are mapped on.
+func (app *Application) Services() *Services {
+	return app.services
+}
+
 // GetApplication retrieves the singleton instance of Application.
 func GetApplication() (*Application, error) {
 	if application == nil {
@@ -74,6 +82,11 @@ func NewApplication(cfg *config.ServerConfig) (*Application, error) {
 
 		app.configureLogger()
 
+		if err := app.configureIPExtractor(); err != nil {
+			initErr = err
+			return
+		}
+
 		// Initialize all services
 		svcs, err := NewServices()
 		if err != nil {
@@ -150,7 +163,14 @@ func (app *Application) configureMiddleware() {
 	})
 	e.Use(echo.WrapMiddleware(secureMiddleware.Handler))
 
-	e.Use(lecho.Middleware(lecho.Config{Logger: app.logger}))
+	e.Use(lecho.Middleware(lecho.Config{
+		Logger: app.logger,
+		// The access log records the raw URI; in privacy mode requests with a
+		// query string are left to RequestLogger, which redacts it.
+		AfterNextSkipper: func(c echo.Context) bool {
+			return c.Request().URL.RawQuery != "" && logger.URLPrivacyEnabled()
+		},
+	}))
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins:     app.config.AllowOrigins,
 		AllowCredentials: true,
@@ -164,11 +184,61 @@ func (app *Application) configureMiddleware() {
 	}))
 
 	e.Use(middlewares.RequestLogger())
+	app.configureAuth()
+	app.configureReadOnlyMode()
+	e.Use(middlewares.BodyLimitWithConfig(middlewares.BodyLimitConfig{
+		Limit: app.config.MaxBodySize,
+		Routes: map[string]int64{
+			"/entries/import":      app.config.MaxBulkBodySize,
+			"/entries/query/batch": app.config.MaxBulkBodySize,
+			"/retrohunt":           app.config.MaxBulkBodySize,
+		},
+	}))
 	e.Pre(middleware.RemoveTrailingSlash())
 
 	middlewares.ConfigureValidator(e)
 }
 
+// configureAuth requires API keys on every non-public route when [Auth] is
+// enabled. Servers without services, such as the edge server, cannot look
+// keys up and stay open.
+func (app *Application) configureAuth() {
+	cfg := config.GetConfig()
+	if !cfg.Auth.Enabled {
+		return
+	}
+	if app.services == nil || app.services.APIKeyService == nil {
+		log.Warn().Msg("API key authentication needs the database, serving without it")
+		return
+	}
+	app.Echo.Use(middlewares.RequireAPIKey(app.services.APIKeyService, routeRole(cfg.Replication.Token)))
+}
+
+// configureReadOnlyMode rejects writes while the instance is in read-only
+// maintenance mode. Servers without services, such as the edge server,
+// write nothing and skip it.
+func (app *Application) configureReadOnlyMode() {
+	if app.services == nil || app.services.MaintenanceService == nil {
+		return
+	}
+	app.Echo.Use(middlewares.ReadOnlyMode(maintenance.ReadOnly, readOnlyAllowed))
+}
+
+// configureIPExtractor sets how c.RealIP finds the client address behind
+// the configured trusted proxies.
+func (app *Application) configureIPExtractor() error {
+	extractor, err := middlewares.IPExtractor(app.config.TrustedProxies)
+	if err != nil {
+		log.Err(err).Strs("trusted_proxies", app.config.TrustedProxies).Msg("Invalid trusted proxies")
+		return ErrTrustedProxiesFailed
+	}
+	app.Echo.IPExtractor = extractor
+	if len(app.config.TrustedProxies) > 0 {
+		log.Info().Strs("trusted_proxies", app.config.TrustedProxies).Msg("Client IPs read from forwarding headers of trusted proxies")
+	}
+	return nil
+}
+
 func (a Application) configureLogger() {
 	lechoLogger := lecho.From(log.Logger, lecho.WithTimestamp())
 	a.Echo.Logger = lechoLogger
@@ -0,0 +1,75 @@
+package web
+
+import (
+	"blacked/features/apikeys"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// publicRoutes need no API key: the landing page, health checks for load
+// balancers and metrics for scrapers.
+var publicRoutes = map[string]bool{
+	"/":                    true,
+	"/health/status":       true,
+	"/healthz/details":     true,
+	"/healthz/alert-rules": true,
+	"/healthz/ready":       true,
+	"/metrics":             true,
+	"/otel-metrics":        true,
+}
+
+// readerRoutes are the routes a reader may call, besides the versioned query
+// API under /api/, and the stats rollups, so a dashboard needs no admin key.
+var readerRoutes = map[string]bool{
+	"/check":               true,
+	"/entries/query":       true,
+	"/entries/query/batch": true,
+	"/stats/timeseries":    true,
+}
+
+// importerRoutes are the routes that feed the blacklist — imports, provider
+// runs and cache syncs, with the status of the jobs they start — which an
+// importer may call too.
+var importerRoutes = map[string]bool{
+	"/entries/import":                       true,
+	"/provider/process":                     true,
+	"/provider/process/status/:processID":   true,
+	"/provider/processes/:processID/events": true,
+	"/cache/sync":                           true,
+	"/cache/sync/:jobID":                    true,
+}
+
+// routeRole returns the role RequireAPIKey demands for the route of a
+// request; routes not listed need an admin. The replication change feed
+// keeps its own bearer token when one is configured; without it the feed
+// needs an admin key, which a replica sends as its [Replication] token.
+func routeRole(replicationToken string) func(c echo.Context) (apikeys.Role, bool) {
+	return func(c echo.Context) (apikeys.Role, bool) {
+		path := c.Path()
+		switch {
+		case publicRoutes[path]:
+			return "", true
+		case path == "/replication/changes" && replicationToken != "":
+			return "", true
+		case readerRoutes[path], strings.HasPrefix(path, "/api/"):
+			return apikeys.RoleReader, false
+		case importerRoutes[path]:
+			return apikeys.RoleImporter, false
+		default:
+			return apikeys.RoleAdmin, false
+		}
+	}
+}
+
+// readOnlyAllowed reports whether a write request is still served in
+// read-only maintenance mode: queries sent as POST, which write nothing, and
+// the toggle that clears the mode.
+func readOnlyAllowed(c echo.Context) bool {
+	path := c.Path()
+	return readerRoutes[path] ||
+		strings.HasPrefix(path, "/api/") ||
+		strings.HasPrefix(path, "/benchmark/") ||
+		path == "/retrohunt" ||
+		path == "/maintenance/read-only"
+}
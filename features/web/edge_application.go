@@ -0,0 +1,60 @@
+package web
+
+import (
+	"blacked/features/edgeset"
+	"blacked/features/web/handlers/edge"
+	"blacked/features/web/handlers/health"
+	v2 "blacked/features/web/handlers/v2"
+	"blacked/internal/collector"
+	"blacked/internal/config"
+	"blacked/internal/query"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// NewEdgeApplication builds a query-only server backed by a memory-mapped
+// edge dataset. It needs neither SQLite nor Badger, so unlike NewApplication
+// it creates no services and is not registered as the global application.
+func NewEdgeApplication(cfg *config.ServerConfig, ds *edgeset.Dataset, trustConfig map[string]float64) (*Application, error) {
+	e := echo.New()
+	e.Server.Addr = ":" + strconv.Itoa(cfg.Port)
+	log.Info().Str("address", e.Server.Addr).Msg("Edge server address")
+
+	app := &Application{
+		Echo:   e,
+		config: cfg,
+	}
+
+	app.configureLogger()
+	if err := app.configureIPExtractor(); err != nil {
+		return nil, err
+	}
+	app.configureMiddleware()
+
+	health.MapHealth(e, *cfg)
+
+	// The dataset index is exact, so Hit needs no DB confirmation.
+	svc := query.NewQueryService(ds, nil, query.NewScorer(trustConfig))
+	handler := v2.NewQueryHandlerWithDeps(svc).SetMaxBulkURLs(cfg.MaxBulkURLs)
+	if err := mapQueryAPI(e, handler, cfg.ResponseTemplates); err != nil {
+		log.Err(err).Msg("Routes configuration error")
+		return nil, ErrRoutesMapFailed
+	}
+
+	if err := edge.MapEdgeInfoRoutes(e, ds); err != nil {
+		log.Err(err).Msg("Routes configuration error")
+		return nil, ErrRoutesMapFailed
+	}
+
+	collector.NewMetricsCollector(ds.Info().Sources)
+	mc, err := collector.GetMetricsCollector()
+	if err != nil {
+		log.Err(err).Msg("Failed to get metrics collector")
+		return nil, ErrMetricCollectorFailed
+	}
+	mc.ExposeWebMetrics(e)
+
+	return app, nil
+}
@@ -0,0 +1,132 @@
+package allowlist
+
+import (
+	"blacked/features/allowlist"
+	"blacked/features/web/handlers/response"
+	"blacked/internal/pagination"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+// rulePaging pages GET /allowlist; rules list oldest first by default.
+var rulePaging = pagination.Options{DefaultLimit: 100, MaxLimit: 1000, Sorts: []string{"created_at", "pattern", "kind"}}
+
+var ruleSorts = map[string]func(a, b allowlist.Rule) int{
+	"created_at": pagination.Compare(func(r allowlist.Rule) int64 { return r.CreatedAt.UnixNano() }),
+	"pattern":    pagination.Compare(func(r allowlist.Rule) string { return r.Pattern }),
+	"kind":       pagination.Compare(func(r allowlist.Rule) string { return string(r.Kind) }),
+}
+
+// RuleInput is the body of an allowlist rule creation request.
+type RuleInput struct {
+	Kind    string `json:"kind"` // domain or url; inferred from pattern when empty
+	Pattern string `json:"pattern"`
+	Reason  string `json:"reason"`
+}
+
+type AllowlistHandler struct {
+	svc *allowlist.Service
+}
+
+func NewAllowlistHandler(svc *allowlist.Service) *AllowlistHandler {
+	return &AllowlistHandler{svc: svc}
+}
+
+// List returns one page of allowlist rules, optionally of one kind.
+// GET /allowlist?kind=domain&sort=-created_at&cursor=<next_cursor>&limit=100
+func (h *AllowlistHandler) List(c echo.Context) error {
+	p, err := pagination.Parse(c.QueryParams(), rulePaging)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	rules, err := h.svc.List(c.Request().Context())
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to list allowlist rules")
+	}
+	rules = slices.DeleteFunc(rules, func(r allowlist.Rule) bool {
+		return !pagination.Match(c.QueryParams(), "kind", string(r.Kind))
+	})
+
+	page, err := pagination.Slice(rules, p, ruleSorts)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, page)
+}
+
+// Get returns one allowlist rule.
+// GET /allowlist/:id
+func (h *AllowlistHandler) Get(c echo.Context) error {
+	id := c.Param("id")
+
+	rule, err := h.svc.Get(c.Request().Context(), id)
+	if errors.Is(err, allowlist.ErrRuleNotFound) {
+		return response.NotFound(c, "Allowlist rule not found", id)
+	}
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to get allowlist rule")
+	}
+	return response.Success(c, rule)
+}
+
+// Create adds an allowlist rule.
+// POST /allowlist {"kind": "domain" | "url", "pattern": "example.com", "reason": "..."}
+func (h *AllowlistHandler) Create(c echo.Context) error {
+	req := &RuleInput{}
+	if err := c.Bind(req); err != nil {
+		return response.BadRequest(c, "Invalid request body: "+err.Error())
+	}
+
+	rule, err := h.svc.Add(c.Request().Context(), allowlist.Kind(req.Kind), req.Pattern, req.Reason)
+	switch {
+	case errors.Is(err, allowlist.ErrInvalidRule):
+		return response.BadRequest(c, err.Error())
+	case errors.Is(err, allowlist.ErrRuleExists):
+		return response.Error(c, http.StatusConflict, "Allowlist rule already exists")
+	case err != nil:
+		return response.Error(c, http.StatusInternalServerError, "Failed to add allowlist rule")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]any{
+		"success": true,
+		"data":    rule,
+	})
+}
+
+// Delete removes an allowlist rule.
+// DELETE /allowlist/:id
+func (h *AllowlistHandler) Delete(c echo.Context) error {
+	id := c.Param("id")
+
+	err := h.svc.Delete(c.Request().Context(), id)
+	if errors.Is(err, allowlist.ErrRuleNotFound) {
+		return response.NotFound(c, "Allowlist rule not found", id)
+	}
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to delete allowlist rule")
+	}
+	return response.Success(c, map[string]string{"id": id})
+}
+
+// Check returns the rule allowlisting a URL, if any.
+// GET /allowlist/check?url=https://login.example.com/
+func (h *AllowlistHandler) Check(c echo.Context) error {
+	link := c.QueryParam("url")
+	if link == "" {
+		return response.BadRequest(c, "url is required")
+	}
+
+	rule, err := h.svc.Match(c.Request().Context(), link)
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to check allowlist")
+	}
+	return response.Success(c, map[string]any{
+		"url":         link,
+		"allowlisted": rule != nil,
+		"rule":        rule,
+	})
+}
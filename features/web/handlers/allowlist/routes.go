@@ -0,0 +1,27 @@
+package allowlist
+
+import (
+	"blacked/features/allowlist"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func MapAllowlistRoutes(e *echo.Echo, svc *allowlist.Service) error {
+	handler := NewAllowlistHandler(svc)
+
+	g := e.Group("/allowlist")
+	g.GET("", handler.List)
+	g.POST("", handler.Create)
+	g.GET("/check", handler.Check)
+	g.GET("/:id", handler.Get)
+	g.DELETE("/:id", handler.Delete)
+
+	log.Info().
+		Str("allowlist", "/allowlist").
+		Str("allowlist check", "/allowlist/check").
+		Str("allowlist rule", "/allowlist/:id").
+		Msg("Allowlist routes mapped successfully.")
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package apikeys
+
+import (
+	"blacked/features/apikeys"
+	"blacked/features/web/handlers/response"
+	"blacked/internal/pagination"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+// keyPaging pages GET /auth/keys; keys list oldest first by default.
+var keyPaging = pagination.Options{DefaultLimit: 100, MaxLimit: 1000, Sorts: []string{"created_at", "name", "last_used_at"}}
+
+var keySorts = map[string]func(a, b apikeys.Key) int{
+	"created_at": pagination.Compare(func(k apikeys.Key) int64 { return k.CreatedAt.UnixNano() }),
+	"name":       pagination.Compare(func(k apikeys.Key) string { return k.Name }),
+	"last_used_at": pagination.Compare(func(k apikeys.Key) int64 {
+		if k.LastUsedAt == nil {
+			return 0
+		}
+		return k.LastUsedAt.UnixNano()
+	}),
+}
+
+// KeyInput is the body of an API key creation request.
+type KeyInput struct {
+	Name      string  `json:"name"`
+	Role      string  `json:"role"`       // reader, importer or admin; reader when empty
+	RateLimit float64 `json:"rate_limit"` // Requests per second; 0 uses the default
+}
+
+// CreatedKey is a new key with its token, shown only once.
+type CreatedKey struct {
+	*apikeys.Key
+	Token string `json:"token"`
+}
+
+type APIKeyHandler struct {
+	svc *apikeys.Service
+}
+
+func NewAPIKeyHandler(svc *apikeys.Service) *APIKeyHandler {
+	return &APIKeyHandler{svc: svc}
+}
+
+// List returns one page of API keys, optionally of some roles. Tokens are never listed.
+// GET /auth/keys?role=importer,admin&sort=-last_used_at&cursor=<next_cursor>&limit=100
+func (h *APIKeyHandler) List(c echo.Context) error {
+	p, err := pagination.Parse(c.QueryParams(), keyPaging)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	keys, err := h.svc.List(c.Request().Context())
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to list API keys")
+	}
+	keys = slices.DeleteFunc(keys, func(k apikeys.Key) bool {
+		return !pagination.Match(c.QueryParams(), "role", string(k.Role))
+	})
+
+	page, err := pagination.Slice(keys, p, keySorts)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, page)
+}
+
+// Get returns one API key.
+// GET /auth/keys/:id
+func (h *APIKeyHandler) Get(c echo.Context) error {
+	id := c.Param("id")
+
+	key, err := h.svc.Get(c.Request().Context(), id)
+	if errors.Is(err, apikeys.ErrKeyNotFound) {
+		return response.NotFound(c, "API key not found", id)
+	}
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to get API key")
+	}
+	return response.Success(c, key)
+}
+
+// Create adds an API key and returns its token, which cannot be shown again.
+// POST /auth/keys {"name": "soc-proxy", "role": "reader" | "importer" | "admin", "rate_limit": 50}
+func (h *APIKeyHandler) Create(c echo.Context) error {
+	req := &KeyInput{}
+	if err := c.Bind(req); err != nil {
+		return response.BadRequest(c, "Invalid request body: "+err.Error())
+	}
+
+	key, token, err := h.svc.Create(c.Request().Context(), req.Name, apikeys.Role(req.Role), req.RateLimit)
+	switch {
+	case errors.Is(err, apikeys.ErrInvalidKey):
+		return response.BadRequest(c, err.Error())
+	case err != nil:
+		return response.Error(c, http.StatusInternalServerError, "Failed to create API key")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]any{
+		"success": true,
+		"data":    CreatedKey{Key: key, Token: token},
+	})
+}
+
+// Delete removes an API key; requests with it are rejected from then on.
+// DELETE /auth/keys/:id
+func (h *APIKeyHandler) Delete(c echo.Context) error {
+	id := c.Param("id")
+
+	err := h.svc.Delete(c.Request().Context(), id)
+	if errors.Is(err, apikeys.ErrKeyNotFound) {
+		return response.NotFound(c, "API key not found", id)
+	}
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to delete API key")
+	}
+	return response.Success(c, map[string]string{"id": id})
+}
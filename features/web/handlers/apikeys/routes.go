@@ -0,0 +1,25 @@
+package apikeys
+
+import (
+	"blacked/features/apikeys"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func MapAPIKeyRoutes(e *echo.Echo, svc *apikeys.Service) error {
+	handler := NewAPIKeyHandler(svc)
+
+	g := e.Group("/auth/keys")
+	g.GET("", handler.List)
+	g.POST("", handler.Create)
+	g.GET("/:id", handler.Get)
+	g.DELETE("/:id", handler.Delete)
+
+	log.Info().
+		Str("api keys", "/auth/keys").
+		Str("api key", "/auth/keys/:id").
+		Msg("API key routes mapped successfully.")
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package audit
+
+import (
+	"blacked/features/audit"
+	"blacked/features/web/handlers/response"
+	"blacked/internal/pagination"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+var queryPaging = pagination.Options{DefaultLimit: audit.DefaultListLimit, MaxLimit: audit.MaxListLimit}
+
+type AuditHandler struct {
+	svc *audit.Service
+}
+
+func NewAuditHandler(svc *audit.Service) *AuditHandler {
+	return &AuditHandler{svc: svc}
+}
+
+// Queries returns one page of audited queries, newest first.
+// GET /audit/queries?since=2024-06-01T00:00:00Z&hit=true&caller=soc-proxy&url=evil.com&cursor=<next_cursor>&limit=100
+func (h *AuditHandler) Queries(c echo.Context) error {
+	f, err := audit.ParseFilter(c.QueryParams())
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	p, err := pagination.Parse(c.QueryParams(), queryPaging)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	page, err := h.svc.List(c.Request().Context(), f, p)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return response.BadRequest(c, err.Error())
+	}
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to list audited queries")
+	}
+	return response.Success(c, page)
+}
@@ -0,0 +1,21 @@
+package audit
+
+import (
+	"blacked/features/audit"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func MapAuditRoutes(e *echo.Echo, svc *audit.Service) error {
+	handler := NewAuditHandler(svc)
+
+	g := e.Group("/audit")
+	g.GET("/queries", handler.Queries)
+
+	log.Info().
+		Str("query audit log", "/audit/queries").
+		Msg("Audit routes mapped successfully.")
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"blacked/features/cache/cache_errors"
 	"blacked/features/entries/enums"
 	"blacked/features/web/handlers/response"
+	"blacked/features/web/middlewares"
 	"context"
 	"fmt"
 	"time"
@@ -71,7 +72,7 @@ func (h *BenchmarkHandler) CompareAllMethods(c echo.Context) error {
 
 	if err := c.Validate(input); err != nil {
 		log.Err(err).Msg("Benchmark input validation failed")
-		return response.BadRequest(c, "Validation error: "+err.Error())
+		return middlewares.RejectInvalid(c, err)
 	}
 
 	ctx := c.Request().Context()
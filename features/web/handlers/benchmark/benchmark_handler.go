@@ -8,6 +8,8 @@ import (
 	"blacked/features/entries/enums"
 	"blacked/features/entries/services"
 	"blacked/features/web/handlers/response"
+	"blacked/features/web/middlewares"
+	"blacked/internal/logger"
 	"context"
 	"errors"
 	"time"
@@ -36,7 +38,7 @@ func NewBenchmarkHandler(service *services.QueryService) *BenchmarkHandler {
 }
 
 type BenchmarkInput struct {
-	URLs       []string `json:"urls" validate:"required"`
+	URLs       []string `json:"urls" validate:"required,min=1,max=100,dive,required"`
 	Iterations int      `json:"iterations" validate:"required,min=1,max=1000"`
 }
 
@@ -71,7 +73,7 @@ func (h *BenchmarkHandler) BenchmarkURL(c echo.Context) error {
 
 	if err := c.Validate(input); err != nil {
 		log.Trace().Err(err).Msg("Validation error")
-		return response.BadRequest(c, "Validation failed: ")
+		return middlewares.RejectInvalid(c, err)
 	}
 
 	if len(input.URLs) == 0 {
@@ -106,7 +108,7 @@ func (h *BenchmarkHandler) benchmarkSingleURL(ctx context.Context, url string, i
 		var err error
 		isLikely, err = cache.CheckURL(url)
 		if err != nil {
-			log.Error().Err(err).Str("url", url).Msg("Error checking bloom filter")
+			log.Error().Err(err).Str("url", logger.RedactURL(url)).Msg("Error checking bloom filter")
 		}
 		bloomTotalTime += time.Since(start).Nanoseconds()
 	}
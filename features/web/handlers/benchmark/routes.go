@@ -4,6 +4,7 @@ package benchmark
 
 import (
 	"blacked/features/entries/services"
+	"blacked/features/web/middlewares"
 
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog/log"
@@ -13,8 +14,8 @@ func MapBenchmarkRoutes(e *echo.Echo, svc *services.QueryService) error {
 	handler := NewBenchmarkHandler(svc)
 
 	g := e.Group("/benchmark")
-	g.POST("/query", handler.BenchmarkURL)
-	g.POST("/compare", handler.CompareAllMethods)
+	g.POST("/query", handler.BenchmarkURL, middlewares.RequireJSON())
+	g.POST("/compare", handler.CompareAllMethods, middlewares.RequireJSON())
 
 	log.Info().
 		Str("benchmark a URL's", "/benchmark/query").
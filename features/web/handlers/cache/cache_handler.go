@@ -0,0 +1,118 @@
+package cache
+
+import (
+	entrycache "blacked/features/cache"
+	"blacked/features/entries/repository"
+	"blacked/features/entry_collector"
+	"blacked/features/web/handlers/response"
+	"blacked/internal/config"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CacheSyncInput selects the scope of an ad-hoc cache sync.
+type CacheSyncInput struct {
+	Mode      string `json:"mode"`       // full (default), delta or source
+	ProcessID string `json:"process_id"` // Required for delta
+	Source    string `json:"source"`     // Required for source
+}
+
+type CacheHandler struct {
+	collector *entry_collector.PondCollector
+}
+
+func NewCacheHandler(collector *entry_collector.PondCollector) *CacheHandler {
+	return &CacheHandler{
+		collector: collector,
+	}
+}
+
+// Sync schedules a cache sync and returns its job for polling.
+// POST /cache/sync {"mode": "full" | "delta" | "source", "process_id": "...", "source": "..."}
+func (h *CacheHandler) Sync(c echo.Context) error {
+	req := &CacheSyncInput{}
+	if err := c.Bind(req); err != nil {
+		return response.BadRequest(c, "Invalid request body: "+err.Error())
+	}
+
+	scope := entry_collector.CacheSyncScope{
+		Mode:      entry_collector.CacheSyncMode(req.Mode),
+		ProcessID: req.ProcessID,
+		Source:    req.Source,
+	}
+	if scope.Mode == "" {
+		scope.Mode = entry_collector.CacheSyncFull
+	}
+
+	job, err := h.collector.RequestCacheSync(c.Request().Context(), scope)
+	switch {
+	case errors.Is(err, entry_collector.ErrInvalidCacheSyncScope):
+		return response.BadRequest(c, err.Error())
+	case errors.Is(err, repository.ErrProcessNotFound):
+		return response.NotFound(c, "Process not found", req.ProcessID)
+	case errors.Is(err, entry_collector.ErrCacheSyncBusy):
+		return response.Error(c, http.StatusConflict,
+			"A cache sync is already running and another is queued. Please retry later.")
+	case err != nil:
+		return response.ErrorWithDetails(c, http.StatusInternalServerError,
+			"Failed to schedule cache sync", err.Error())
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]any{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// SyncStatus returns the state and progress of a cache sync job.
+// GET /cache/sync/:jobID
+func (h *CacheHandler) SyncStatus(c echo.Context) error {
+	jobID := c.Param("jobID")
+
+	job, err := h.collector.GetCacheSyncJob(jobID)
+	if err != nil {
+		return response.NotFound(c, "Cache sync job not found", jobID)
+	}
+
+	return response.Success(c, job)
+}
+
+// BloomStats describes the URL filter built by cache syncs, of the kind
+// set by [Cache] probabilistic_filter.
+type BloomStats struct {
+	Kind          string `json:"kind"`     // bloom, cuckoo or none
+	Capacity      uint   `json:"capacity"` // Counters of a bloom filter, fingerprint slots of a cuckoo filter
+	HashFunctions uint   `json:"hash_functions"`
+	ApproxKeys    uint32 `json:"approx_keys"`
+}
+
+// CacheStats is the response of the cache stats endpoint.
+type CacheStats struct {
+	CacheType string                          `json:"cache_type"`
+	Bloom     *BloomStats                     `json:"bloom,omitempty"`
+	Sync      entry_collector.CacheSyncStatus `json:"sync"`
+	Scrub     *entry_collector.ScrubReport    `json:"scrub,omitempty"` // Last background revalidation round
+}
+
+// Stats returns the cache backend, bloom filter size and cache sync progress.
+// GET /cache/stats
+func (h *CacheHandler) Stats(c echo.Context) error {
+	stats := CacheStats{
+		CacheType: config.GetConfig().Cache.CacheType,
+		Sync:      h.collector.GetCacheSyncStatus(),
+		Scrub:     h.collector.LastCacheScrub(),
+	}
+
+	if bf, err := entrycache.GetBloomFilter(); err == nil {
+		stats.Bloom = &BloomStats{
+			Kind:          bf.Kind(),
+			Capacity:      bf.Cap(),
+			HashFunctions: bf.K(),
+			ApproxKeys:    bf.ApproximatedSize(),
+		}
+	}
+
+	return response.Success(c, stats)
+}
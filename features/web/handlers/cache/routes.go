@@ -0,0 +1,25 @@
+package cache
+
+import (
+	"blacked/features/entry_collector"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func MapCacheRoutes(e *echo.Echo, collector *entry_collector.PondCollector) error {
+	handler := NewCacheHandler(collector)
+
+	g := e.Group("/cache")
+	g.POST("/sync", handler.Sync)
+	g.GET("/sync/:jobID", handler.SyncStatus)
+	g.GET("/stats", handler.Stats)
+
+	log.Info().
+		Str("cache sync", "/cache/sync").
+		Str("cache sync status", "/cache/sync/:jobID").
+		Str("cache stats", "/cache/stats").
+		Msg("Cache routes mapped successfully.")
+
+	return nil
+}
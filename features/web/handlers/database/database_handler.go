@@ -0,0 +1,41 @@
+package database
+
+import (
+	"blacked/features/web/handlers/response"
+	"blacked/internal/db"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type DatabaseHandler struct {
+	writeDB *sql.DB
+}
+
+func NewDatabaseHandler(writeDB *sql.DB) *DatabaseHandler {
+	return &DatabaseHandler{
+		writeDB: writeDB,
+	}
+}
+
+// Maintain returns free pages to the file system and truncates the WAL,
+// reporting file sizes before and after; dry_run only estimates the result.
+// Writers wait for the run to finish.
+// POST /db/maintain?dry_run=true
+func (h *DatabaseHandler) Maintain(c echo.Context) error {
+	dryRun := false
+	if param := c.QueryParam("dry_run"); param != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(param); err != nil {
+			return response.BadRequest(c, "dry_run must be a boolean")
+		}
+	}
+
+	report, err := db.Maintain(c.Request().Context(), h.writeDB, dryRun)
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, err.Error())
+	}
+	return response.Success(c, report)
+}
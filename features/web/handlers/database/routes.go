@@ -0,0 +1,26 @@
+package database
+
+import (
+	"blacked/internal/db"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func MapDatabaseRoutes(e *echo.Echo) error {
+	writeDB, err := db.GetWriteDB()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get write database connection")
+		return err
+	}
+	handler := NewDatabaseHandler(writeDB)
+
+	g := e.Group("/db")
+	g.POST("/maintain", handler.Maintain)
+
+	log.Info().
+		Str("database maintenance", "/db/maintain").
+		Msg("Database routes mapped successfully.")
+
+	return nil
+}
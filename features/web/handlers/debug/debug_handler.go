@@ -0,0 +1,37 @@
+package debug
+
+import (
+	"blacked/features/parsedebug"
+	"blacked/features/web/handlers/response"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type DebugHandler struct {
+	svc *parsedebug.Service
+}
+
+func NewDebugHandler(svc *parsedebug.Service) *DebugHandler {
+	return &DebugHandler{svc: svc}
+}
+
+// Parse returns how a feed line or URL would be parsed, without storing it.
+// POST /debug/parse {"input": "0.0.0.0 ads.example.com", "format": "hosts"}
+// POST /debug/parse {"input": "...", "provider": "my-feed"}
+func (h *DebugHandler) Parse(c echo.Context) error {
+	req := &parsedebug.Request{}
+	if err := c.Bind(req); err != nil {
+		return response.BadRequest(c, "Invalid request body: "+err.Error())
+	}
+
+	res, err := h.svc.Parse(c.Request().Context(), *req)
+	switch {
+	case errors.Is(err, parsedebug.ErrInvalidRequest):
+		return response.BadRequest(c, err.Error())
+	case err != nil:
+		return response.Error(c, http.StatusInternalServerError, "Failed to parse input")
+	}
+	return response.Success(c, res)
+}
@@ -0,0 +1,21 @@
+package debug
+
+import (
+	"blacked/features/parsedebug"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func MapDebugRoutes(e *echo.Echo, svc *parsedebug.Service) error {
+	handler := NewDebugHandler(svc)
+
+	g := e.Group("/debug")
+	g.POST("/parse", handler.Parse)
+
+	log.Info().
+		Str("debug parse", "/debug/parse").
+		Msg("Debug routes mapped successfully.")
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package edge
+
+import (
+	"blacked/features/edgeset"
+	"blacked/features/web/handlers/response"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+)
+
+type EdgeHandler struct {
+	datasetPath string
+	dataset     *edgeset.Dataset
+}
+
+func NewEdgeHandler(datasetPath string, ds *edgeset.Dataset) *EdgeHandler {
+	return &EdgeHandler{
+		datasetPath: datasetPath,
+		dataset:     ds,
+	}
+}
+
+// Dataset serves the latest compiled edge dataset.
+// GET /edge/dataset
+func (h *EdgeHandler) Dataset(c echo.Context) error {
+	if _, err := os.Stat(h.datasetPath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return response.Error(c, http.StatusNotFound, "Edge dataset not compiled yet")
+		}
+		return response.Error(c, http.StatusInternalServerError, "Edge dataset unavailable")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMEOctetStream)
+	return c.File(h.datasetPath)
+}
+
+// Info returns the header details of the dataset being served.
+// GET /edge/info
+func (h *EdgeHandler) Info(c echo.Context) error {
+	return response.Success(c, h.dataset.Info())
+}
@@ -0,0 +1,36 @@
+package edge
+
+import (
+	"blacked/features/edgeset"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// MapEdgeDatasetRoutes exposes the compiled dataset so edge nodes can pull it from the primary.
+func MapEdgeDatasetRoutes(e *echo.Echo, datasetPath string) error {
+	handler := NewEdgeHandler(datasetPath, nil)
+
+	g := e.Group("/edge")
+	g.GET("/dataset", handler.Dataset)
+
+	log.Info().
+		Str("edge dataset", "/edge/dataset").
+		Msg("Edge dataset routes mapped successfully.")
+
+	return nil
+}
+
+// MapEdgeInfoRoutes exposes the dataset an edge node is serving.
+func MapEdgeInfoRoutes(e *echo.Echo, ds *edgeset.Dataset) error {
+	handler := NewEdgeHandler("", ds)
+
+	g := e.Group("/edge")
+	g.GET("/info", handler.Info)
+
+	log.Info().
+		Str("edge info", "/edge/info").
+		Msg("Edge info routes mapped successfully.")
+
+	return nil
+}
@@ -0,0 +1,433 @@
+package entries
+
+import (
+	"blacked/features/audit"
+	"blacked/features/cache"
+	"blacked/features/entries"
+	"blacked/features/entries/enums"
+	"blacked/features/entries/repository"
+	"blacked/features/entries/services"
+	"blacked/features/hits"
+	"blacked/features/web/handlers/response"
+	"blacked/features/web/middlewares"
+	"blacked/internal/pagination"
+	"blacked/internal/query"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MaxBatchQueryURLs caps the URLs accepted by one batch query.
+	MaxBatchQueryURLs = 10000
+
+	batchQueryWorkers = 32
+)
+
+var (
+	searchPaging  = pagination.Options{DefaultLimit: services.DefaultSearchLimit, MaxLimit: services.MaxSearchLimit}
+	hitsPaging    = pagination.Options{DefaultLimit: hits.DefaultTopLimit, MaxLimit: hits.MaxTopLimit}
+	relatedPaging = pagination.Options{DefaultLimit: services.DefaultRelatedLimit, MaxLimit: services.MaxRelatedLimit}
+)
+
+type EntriesHandler struct {
+	relatedService *services.RelatedService
+	queryService   *services.QueryService
+	deleteService  *services.DeleteService
+	mergeService   *services.MergeService
+	reclassifySvc  *services.ReclassifyService
+	importService  *services.ImportService
+	hitsService    *hits.Service
+	policy         *query.Policy // nil leaves batch actions unset
+}
+
+func NewEntriesHandler(relatedSvc *services.RelatedService, querySvc *services.QueryService, deleteSvc *services.DeleteService, mergeSvc *services.MergeService, reclassifySvc *services.ReclassifyService, importSvc *services.ImportService, hitsSvc *hits.Service, policy *query.Policy) *EntriesHandler {
+	return &EntriesHandler{
+		relatedService: relatedSvc,
+		queryService:   querySvc,
+		deleteService:  deleteSvc,
+		mergeService:   mergeSvc,
+		reclassifySvc:  reclassifySvc,
+		importService:  importSvc,
+		hitsService:    hitsSvc,
+		policy:         policy,
+	}
+}
+
+// EntryDetails is an entry with its hit count.
+type EntryDetails struct {
+	*entries.Entry
+	Hits *hits.Stat `json:"hits"`
+}
+
+// Get returns one entry, deleted or not, with its hit count.
+// GET /entries/:id
+func (h *EntriesHandler) Get(c echo.Context) error {
+	ctx := c.Request().Context()
+	id := c.Param("id")
+
+	entry, err := h.queryService.GetEntryByID(ctx, id)
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to get entry")
+	}
+	if entry == nil {
+		return response.NotFound(c, "Entry not found", id)
+	}
+
+	stat, err := h.hitsService.Stat(ctx, id)
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to get entry hits")
+	}
+
+	return response.Success(c, EntryDetails{Entry: entry, Hits: stat})
+}
+
+// Hits reports the most-hit entries and the hits of every source.
+// GET /entries/hits?source=oisd-big&limit=50
+func (h *EntriesHandler) Hits(c echo.Context) error {
+	p, err := pagination.Parse(c.QueryParams(), hitsPaging)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	report, err := h.hitsService.Report(c.Request().Context(), c.QueryParam("source"), p.Limit)
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to build hits report")
+	}
+	return response.Success(c, report)
+}
+
+// Search returns one page of active entries matching the shared entry filter,
+// in ID order.
+// GET /entries/search?source=oisd-big&category=phishing&min_confidence=0.8&since=2024-06-01T00:00:00Z&cursor=<next_cursor>&limit=100
+func (h *EntriesHandler) Search(c echo.Context) error {
+	f, err := repository.ParseFilter(c.QueryParams())
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	p, err := pagination.Parse(c.QueryParams(), searchPaging)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	page, err := h.queryService.Search(c.Request().Context(), f, p)
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to search entries")
+	}
+	return response.Success(c, page)
+}
+
+// Stats counts the active entries matching the shared entry filter, in total
+// and per source and category.
+// GET /entries/stats?category=phishing&since=2024-06-01T00:00:00Z
+func (h *EntriesHandler) Stats(c echo.Context) error {
+	f, err := repository.ParseFilter(c.QueryParams())
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	stats, err := h.queryService.Stats(c.Request().Context(), f)
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to count entries")
+	}
+	return response.Success(c, stats)
+}
+
+// Delete soft deletes every active entry matching the shared entry filter.
+// At least one filter parameter is required.
+// DELETE /entries?source=oisd-big&max_confidence=0.3
+func (h *EntriesHandler) Delete(c echo.Context) error {
+	f, err := repository.ParseFilter(c.QueryParams())
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	deleted, err := h.deleteService.Delete(c.Request().Context(), f)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmptyFilter) {
+			return response.ErrorWithDetails(c, http.StatusBadRequest,
+				"At least one filter parameter is required", repository.FilterParams)
+		}
+		return response.Error(c, http.StatusInternalServerError, "Failed to delete entries")
+	}
+	return response.Success(c, map[string]int64{"deleted": deleted})
+}
+
+// Merge folds entries of a source that differ only in scheme, host case or
+// trailing slashes into the oldest one and soft deletes the rest; dry_run only
+// reports the groups, at most max_groups of them (default 50, 0 for all).
+// POST /entries/merge?dry_run=true&source=urlhaus&max_groups=50
+func (h *EntriesHandler) Merge(c echo.Context) error {
+	opts := services.MergeOptions{Source: c.QueryParam("source"), Apply: true, MaxGroups: 50}
+	if param := c.QueryParam("dry_run"); param != "" {
+		dryRun, err := strconv.ParseBool(param)
+		if err != nil {
+			return response.BadRequest(c, "dry_run must be a boolean")
+		}
+		opts.Apply = !dryRun
+	}
+	if param := c.QueryParam("max_groups"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 0 {
+			return response.BadRequest(c, "max_groups must be a non-negative integer")
+		}
+		opts.MaxGroups = n
+	}
+
+	report, err := h.mergeService.Run(c.Request().Context(), opts)
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to merge entries")
+	}
+	return response.Success(c, report)
+}
+
+// Reclassify moves every active entry matching the shared entry filter to
+// the category to, in the background: the filter's categories are replaced,
+// or without a category filter all of them. Returns the job to poll.
+// POST /entries/reclassify?category=malware&source=urlhaus&to=malware-distribution
+func (h *EntriesHandler) Reclassify(c echo.Context) error {
+	f, err := repository.ParseFilter(c.QueryParams())
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	job, err := h.reclassifySvc.Start(c.Request().Context(), services.ReclassifyRequest{Filter: f, To: c.QueryParam("to")})
+	switch {
+	case errors.Is(err, repository.ErrEmptyFilter):
+		return response.ErrorWithDetails(c, http.StatusBadRequest,
+			"At least one filter parameter is required", repository.FilterParams)
+	case errors.Is(err, services.ErrInvalidReclassify):
+		return response.BadRequest(c, err.Error())
+	case errors.Is(err, services.ErrReclassifyBusy):
+		return response.Error(c, http.StatusConflict, "A reclassification is already running. Please retry later.")
+	case err != nil:
+		return response.Error(c, http.StatusInternalServerError, "Failed to start reclassification")
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]any{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// ReclassifyStatus returns the state and progress of a reclassification.
+// GET /entries/reclassify/:jobID
+func (h *EntriesHandler) ReclassifyStatus(c echo.Context) error {
+	jobID := c.Param("jobID")
+
+	job, err := h.reclassifySvc.Get(jobID)
+	if err != nil {
+		return response.NotFound(c, "Reclassification not found", jobID)
+	}
+	return response.Success(c, job)
+}
+
+// Import writes the entries of a JSON array or NDJSON body of URLs or
+// {"url", "category", "categories", "confidence"} objects under the import
+// source (import-<source> when named), skipping invalid and repeated URLs.
+// POST /entries/import?source=partner&category=phishing
+func (h *EntriesHandler) Import(c echo.Context) error {
+	report, err := h.importService.Import(c.Request().Context(), c.Request().Body, services.ImportOptions{
+		Label:    c.QueryParam("source"),
+		Category: c.QueryParam("category"),
+	})
+	switch {
+	case middlewares.IsBodyTooLarge(err):
+		return middlewares.RejectOversizedBody(c, err)
+	case errors.Is(err, services.ErrImportUnavailable):
+		return response.Error(c, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, services.ErrInvalidImport):
+		middlewares.RecordRejection(c, middlewares.RejectInvalidPayload)
+		if report == nil {
+			return response.BadRequest(c, err.Error())
+		}
+		return response.ErrorWithDetails(c, http.StatusBadRequest, err.Error(), report)
+	case err != nil:
+		return response.Error(c, http.StatusInternalServerError, "Failed to import entries")
+	}
+	return response.Success(c, report)
+}
+
+// Related returns all entries sharing the host, registered domain or IP of the query.
+// GET /entries/related?domain=bad.com&ip=1.2.3.4&resolve=true&limit=500
+func (h *EntriesHandler) Related(c echo.Context) error {
+	q := services.RelatedQuery{
+		Domain: c.QueryParam("domain"),
+		IP:     c.QueryParam("ip"),
+	}
+
+	if param := c.QueryParam("resolve"); param != "" {
+		resolve, err := strconv.ParseBool(param)
+		if err != nil {
+			return response.BadRequest(c, "resolve must be a boolean")
+		}
+		q.Resolve = resolve
+	}
+
+	p, err := pagination.Parse(c.QueryParams(), relatedPaging)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	q.Limit = p.Limit
+
+	res, err := h.relatedService.Related(c.Request().Context(), q)
+	if err != nil {
+		if errors.Is(err, services.ErrRelatedInput) {
+			return response.BadRequest(c, "domain or ip is required")
+		}
+		return response.Error(c, http.StatusInternalServerError, "Failed to look up related entries")
+	}
+
+	return response.Success(c, res)
+}
+
+// BatchMatch is one entry listing a queried URL.
+type BatchMatch struct {
+	ID         string   `json:"id"`
+	Source     string   `json:"source"`
+	Category   string   `json:"category,omitempty"`
+	Categories []string `json:"categories,omitempty"` // Every category of a multi-category entry
+	Confidence float64  `json:"confidence"`
+}
+
+// BatchResult is the outcome for one URL of a batch query.
+type BatchResult struct {
+	URL         string       `json:"url"`
+	Listed      bool         `json:"listed"`
+	Allowlisted bool         `json:"allowlisted,omitempty"` // Listed by a provider but exempted by the allowlist
+	Action      query.Action `json:"action,omitempty"`      // Policy decision; scored by the highest match confidence
+	Matches     []BatchMatch `json:"matches,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// decide sets the policy action of r from its matches.
+func (h *EntriesHandler) decide(r *BatchResult) {
+	if h.policy == nil {
+		return
+	}
+
+	v := query.Verdict{Listed: r.Listed, Allowlisted: r.Allowlisted}
+	for _, m := range r.Matches {
+		v.Score = max(v.Score, m.Confidence)
+		v.Sources = append(v.Sources, m.Source)
+		v.Categories = append(v.Categories, m.Category)
+		v.Categories = append(v.Categories, m.Categories...)
+	}
+	r.Action = h.policy.Decide(v)
+}
+
+// Query looks url up in the database, narrowed to the entries of the
+// source and category filters, and scores the hits by the configured trust
+// of their sources.
+// GET /entries/query?url=https://a.com/x&type=mixed&source=urlhaus-online&category=phishing
+func (h *EntriesHandler) Query(c echo.Context) error {
+	link := c.QueryParam("url")
+	if link == "" {
+		return response.BadRequest(c, "url is required")
+	}
+	queryType := enums.QueryTypeMixed
+	if param := c.QueryParam("type"); param != "" {
+		var err error
+		if queryType, err = enums.QueryTypeString(param); err != nil {
+			return response.BadRequest(c, "type must be one of full, host, domain, path, mixed, subdomain, wildcard")
+		}
+	}
+	f, err := repository.ParseFilter(c.QueryParams())
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	result, err := h.queryService.QueryScored(c.Request().Context(), link, &queryType, services.QueryOptions{
+		Sources:    f.Sources,
+		Categories: f.Categories,
+	})
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to query entries")
+	}
+	return response.Success(c, result)
+}
+
+// QueryBatch looks up every URL through the bloom → cache → repository flow
+// concurrently and returns one result per URL, in request order.
+// POST /entries/query/batch ["https://a.com/x", "https://b.com/"]
+func (h *EntriesHandler) QueryBatch(c echo.Context) error {
+	var urls []string
+	if err := json.NewDecoder(c.Request().Body).Decode(&urls); err != nil {
+		if middlewares.IsBodyTooLarge(err) {
+			return middlewares.RejectOversizedBody(c, err)
+		}
+		return response.BadRequest(c, "Request body must be a JSON array of URLs")
+	}
+	if len(urls) == 0 {
+		return response.BadRequest(c, "At least one URL is required")
+	}
+	if len(urls) > MaxBatchQueryURLs {
+		return middlewares.RejectTooMany(c, "urls", len(urls), MaxBatchQueryURLs)
+	}
+
+	ctx := c.Request().Context()
+	start := time.Now()
+	lookups := cache.GetEntryStreams(ctx, urls, batchQueryWorkers)
+
+	var ids []string
+	for _, l := range lookups {
+		ids = append(ids, l.IDs...)
+	}
+	found, err := h.queryService.GetEntriesByIDs(ctx, ids)
+	if err != nil {
+		log.Error().Err(err).Int("ids", len(ids)).Msg("Failed to load entries for batch query")
+		return response.Error(c, http.StatusInternalServerError, "Failed to load matched entries")
+	}
+	byID := make(map[string]BatchMatch, len(found))
+	for _, e := range found {
+		if e.DeletedAt == nil {
+			byID[e.ID] = BatchMatch{ID: e.ID, Source: e.Source, Category: e.Category, Categories: e.Categories, Confidence: e.Confidence}
+		}
+	}
+
+	results := make([]BatchResult, len(lookups))
+	listed := 0
+	for i, l := range lookups {
+		r := BatchResult{URL: urls[i]}
+		if l.Err != nil {
+			r.Error = "lookup failed"
+		}
+		for _, id := range l.IDs {
+			if m, ok := byID[id]; ok {
+				r.Matches = append(r.Matches, m)
+			}
+		}
+		if len(r.Matches) > 0 {
+			allowed, err := h.queryService.Allowlisted(ctx, urls[i])
+			if err != nil {
+				log.Warn().Err(err).Msg("Failed to check allowlist, returning matches")
+			} else if allowed {
+				r.Matches = nil
+				r.Allowlisted = true
+			}
+		}
+		r.Listed = len(r.Matches) > 0
+		if r.Listed {
+			listed++
+			for _, m := range r.Matches {
+				h.queryService.RecordHits(m.ID)
+			}
+		}
+		h.decide(&r)
+		results[i] = r
+		middlewares.AuditResult(c, audit.Result{URL: r.URL, Hit: r.Listed})
+	}
+
+	return response.Success(c, map[string]any{
+		"total":       len(results),
+		"listed":      listed,
+		"duration_ms": time.Since(start).Milliseconds(),
+		"results":     results,
+	})
+}
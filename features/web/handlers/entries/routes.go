@@ -0,0 +1,47 @@
+package entries
+
+import (
+	"blacked/features/entries/services"
+	"blacked/features/hits"
+	"blacked/features/web/middlewares"
+	"blacked/internal/query"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+const mimeNDJSON = "application/x-ndjson"
+
+func MapEntriesRoutes(e *echo.Echo, relatedSvc *services.RelatedService, querySvc *services.QueryService, deleteSvc *services.DeleteService, mergeSvc *services.MergeService, reclassifySvc *services.ReclassifyService, importSvc *services.ImportService, hitsSvc *hits.Service, policy *query.Policy) error {
+	handler := NewEntriesHandler(relatedSvc, querySvc, deleteSvc, mergeSvc, reclassifySvc, importSvc, hitsSvc, policy)
+
+	g := e.Group("/entries")
+	g.DELETE("", handler.Delete)
+	g.GET("/search", handler.Search)
+	g.GET("/stats", handler.Stats)
+	g.GET("/related", handler.Related)
+	g.GET("/hits", handler.Hits)
+	g.GET("/query", handler.Query)
+	g.GET("/:id", handler.Get)
+	g.POST("/query/batch", handler.QueryBatch, middlewares.RequireJSON(), middlewares.AuditQueries())
+	g.POST("/import", handler.Import, middlewares.RequireJSON(mimeNDJSON))
+	g.POST("/merge", handler.Merge)
+	g.POST("/reclassify", handler.Reclassify)
+	g.GET("/reclassify/:jobID", handler.ReclassifyStatus)
+
+	log.Info().
+		Str("bulk delete", "/entries").
+		Str("search entries", "/entries/search").
+		Str("entry stats", "/entries/stats").
+		Str("related entries", "/entries/related").
+		Str("hits report", "/entries/hits").
+		Str("scored query", "/entries/query").
+		Str("entry details", "/entries/:id").
+		Str("batch query", "/entries/query/batch").
+		Str("import entries", "/entries/import").
+		Str("merge near-duplicates", "/entries/merge").
+		Str("reclassify entries", "/entries/reclassify").
+		Msg("Entries routes mapped successfully.")
+
+	return nil
+}
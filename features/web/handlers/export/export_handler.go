@@ -0,0 +1,164 @@
+package export
+
+import (
+	"blacked/features/entries/repository"
+	"blacked/features/export"
+	"blacked/features/web/handlers/response"
+	"blacked/internal/config"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+type ExportHandler struct {
+	exportService *export.Service
+	rpz           *export.RPZ
+}
+
+func NewExportHandler(svc *export.Service, rpz *export.RPZ) *ExportHandler {
+	return &ExportHandler{
+		exportService: svc,
+		rpz:           rpz,
+	}
+}
+
+// parseFilter reads the shared entry filter parameters; a provider group in
+// source stands for its providers.
+func parseFilter(c echo.Context) (repository.Filter, error) {
+	f, err := repository.ParseFilter(c.QueryParams())
+	if err != nil {
+		return repository.Filter{}, err
+	}
+	f.Sources, _ = config.GetConfig().ExpandProviderGroups(f.Sources)
+	return f, nil
+}
+
+// Delta streams entries created or reactivated since a timestamp, narrowed by
+// the shared entry filter parameters.
+// GET /export/delta?since=2024-06-01T00:00:00Z&source=openphish-feed&category=phishing&format=plain
+func (h *ExportHandler) Delta(c echo.Context) error {
+	f, err := parseFilter(c)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	if f.Since == nil {
+		return response.BadRequest(c, "since is required (RFC3339 timestamp)")
+	}
+
+	format, err := export.ParseFormat(c.QueryParam("format"))
+	if err != nil {
+		return response.ErrorWithDetails(c, http.StatusBadRequest, "Unsupported format", export.Formats)
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, format.ContentType())
+	res.Header().Set("X-Export-Since", f.Since.UTC().Format(time.RFC3339))
+	res.WriteHeader(http.StatusOK)
+
+	w, err := export.NewWriter(format, res)
+	if err != nil {
+		return err
+	}
+
+	count, err := h.exportService.Delta(c.Request().Context(), f, w)
+	if err != nil {
+		// Headers are already sent; the truncated body is all we can signal.
+		log.Err(err).Interface("filter", f).Int("written", count).Msg("Delta export aborted")
+		return nil
+	}
+
+	log.Debug().
+		Interface("filter", f).
+		Str("format", string(format)).
+		Int("count", count).
+		Msg("Delta export completed")
+	return nil
+}
+
+// Entries streams the active entries matching the shared entry filter
+// parameters in a list format downstream blockers consume.
+// GET /entries/export?format=hosts&source=oisd-big&category=malware
+func (h *ExportHandler) Entries(c echo.Context) error {
+	format, err := export.ParseFormat(c.QueryParam("format"))
+	if err != nil {
+		return response.ErrorWithDetails(c, http.StatusBadRequest, "Unsupported format", export.Formats)
+	}
+	return h.export(c, format)
+}
+
+// STIX streams the active entries matching the shared entry filter
+// parameters as a STIX 2.1 bundle of indicators, for threat intelligence
+// platforms; the same as /entries/export?format=stix.
+// GET /entries/export/stix?category=phishing&min_confidence=0.8
+func (h *ExportHandler) STIX(c echo.Context) error {
+	return h.export(c, export.FormatSTIX)
+}
+
+func (h *ExportHandler) export(c echo.Context, format export.Format) error {
+	f, err := parseFilter(c)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, format.ContentType())
+	res.WriteHeader(http.StatusOK)
+
+	w, err := export.NewWriter(format, res)
+	if err != nil {
+		return err
+	}
+
+	count, err := h.exportService.Export(c.Request().Context(), f, w)
+	if err != nil {
+		// Headers are already sent; the truncated body is all we can signal.
+		log.Err(err).Interface("filter", f).Int("written", count).Msg("Entries export aborted")
+		return nil
+	}
+
+	log.Debug().
+		Interface("filter", f).
+		Str("format", string(format)).
+		Int("count", count).
+		Msg("Entries export completed")
+	return nil
+}
+
+// RPZ streams a BIND Response Policy Zone file of the active entries matching
+// the shared entry filter parameters, for DNS resolvers.
+// GET /export/rpz?category=phishing&min_confidence=0.8
+func (h *ExportHandler) RPZ(c echo.Context) error {
+	f, err := parseFilter(c)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	ctx := c.Request().Context()
+	serial, err := h.exportService.RPZSerial(ctx)
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to export RPZ")
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/dns")
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+h.rpz.Zone()+`.zone"`)
+	res.Header().Set("X-RPZ-Serial", strconv.FormatUint(uint64(serial), 10))
+	res.WriteHeader(http.StatusOK)
+
+	count, err := h.exportService.WriteRPZ(ctx, h.rpz, f, res)
+	if err != nil {
+		// Headers are already sent; the truncated body is all we can signal.
+		log.Err(err).Interface("filter", f).Int("written", count).Msg("RPZ export aborted")
+		return nil
+	}
+
+	log.Debug().
+		Interface("filter", f).
+		Str("zone", h.rpz.Zone()).
+		Int("records", count).
+		Msg("RPZ export completed")
+	return nil
+}
@@ -0,0 +1,33 @@
+package export
+
+import (
+	"blacked/features/export"
+	"blacked/internal/config"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func MapExportRoutes(e *echo.Echo, svc *export.Service, rpzCfg config.RPZConfig) error {
+	rpz, err := export.NewRPZ(rpzCfg)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid RPZ configuration")
+		return err
+	}
+	handler := NewExportHandler(svc, rpz)
+
+	g := e.Group("/export")
+	g.GET("/delta", handler.Delta)
+	g.GET("/rpz", handler.RPZ)
+	e.GET("/entries/export", handler.Entries)
+	e.GET("/entries/export/stix", handler.STIX)
+
+	log.Info().
+		Str("delta export", "/export/delta").
+		Str("rpz export", "/export/rpz").
+		Str("entries export", "/entries/export").
+		Str("stix export", "/entries/export/stix").
+		Msg("Export routes mapped successfully.")
+
+	return nil
+}
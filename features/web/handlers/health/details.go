@@ -0,0 +1,71 @@
+package health
+
+import (
+	"blacked/features/health"
+	"blacked/features/web/handlers/response"
+	"blacked/internal/config"
+	"bytes"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// MapHealthDetails sets up the composite health report and the alert rules
+// generated from its checks, if health checks are enabled in config.
+func MapHealthDetails(e *echo.Echo, cfg config.ServerConfig, monitor *health.Monitor) {
+	if !cfg.HealthCheck {
+		return
+	}
+	h := &DetailsHandler{monitor: monitor}
+
+	g := e.Group("/healthz")
+	g.GET("/details", h.Details)
+	g.GET("/alert-rules", h.AlertRules)
+
+	log.Info().
+		Str("details", "/healthz/details").
+		Str("alert rules", "/healthz/alert-rules").
+		Msg("Health detail routes mapped successfully.")
+}
+
+// DetailsHandler serves the composite health score.
+type DetailsHandler struct {
+	monitor *health.Monitor
+}
+
+// Details returns the health score with every component, 503 when one fails.
+//
+// GET /healthz/details
+func (h *DetailsHandler) Details(c echo.Context) error {
+	report := h.monitor.Evaluate()
+	code := http.StatusOK
+	if report.Status == health.StatusUnhealthy {
+		code = http.StatusServiceUnavailable
+	}
+	return c.JSON(code, report)
+}
+
+// AlertRules returns the Prometheus alerting rules of the health checks, as
+// a rule file ready for rule_files, or as JSON with format=json.
+//
+// GET /healthz/alert-rules?format=yaml|json
+func (h *DetailsHandler) AlertRules(c echo.Context) error {
+	rules := h.monitor.AlertRules()
+	switch c.QueryParam("format") {
+	case "", "yaml":
+		var out bytes.Buffer
+		enc := yaml.NewEncoder(&out)
+		enc.SetIndent(2)
+		if err := enc.Encode(rules); err != nil {
+			log.Err(err).Msg("Failed to marshal alert rules")
+			return response.Error(c, http.StatusInternalServerError, "Failed to render alert rules")
+		}
+		return c.Blob(http.StatusOK, "application/yaml", out.Bytes())
+	case "json":
+		return c.JSON(http.StatusOK, rules)
+	default:
+		return response.BadRequest(c, "format must be yaml or json")
+	}
+}
@@ -1,6 +1,7 @@
 package health
 
 import (
+	"blacked/features/maintenance"
 	"blacked/internal/config"
 	"net/http"
 
@@ -19,9 +20,19 @@ func MapHealth(e *echo.Echo, cfg config.ServerConfig) {
 	log.Info().Msg("Health check enabled at /health/status")
 }
 
-// StatusCheck returns a simple JSON indicating “ok” status.
+// StatusCheck returns a simple JSON indicating “ok” status, with read_only
+// set while the instance is in read-only maintenance mode. Queries are
+// served meanwhile, so the instance stays ready.
 func StatusCheck(c echo.Context) error {
-	return c.JSON(http.StatusOK, map[string]any{
-		"status": "ok",
-	})
+	body := map[string]any{
+		"status":    "ok",
+		"read_only": false,
+	}
+	if svc := maintenance.Get(); svc != nil {
+		if state := svc.State(c.Request().Context()); state.ReadOnly {
+			body["read_only"] = true
+			body["maintenance"] = state
+		}
+	}
+	return c.JSON(http.StatusOK, body)
 }
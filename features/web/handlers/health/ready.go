@@ -0,0 +1,46 @@
+package health
+
+import (
+	"blacked/features/entry_collector"
+	"blacked/internal/config"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// Warmup reports the startup cache warm-up.
+type Warmup interface {
+	WarmupStatus() entry_collector.WarmupStatus
+}
+
+// MapReady sets up the readiness probe, if health checks are enabled in
+// config. A nil warmup is always ready.
+func MapReady(e *echo.Echo, cfg config.ServerConfig, warmup Warmup) {
+	if !cfg.HealthCheck {
+		return
+	}
+	h := &ReadyHandler{warmup: warmup}
+	e.GET("/healthz/ready", h.Ready)
+	log.Info().Msg("Readiness probe enabled at /healthz/ready")
+}
+
+// ReadyHandler serves the readiness probe.
+type ReadyHandler struct {
+	warmup Warmup
+}
+
+// Ready returns 200 once the startup cache warm-up completed, and 503 with
+// the progress of its sync before.
+//
+// GET /healthz/ready
+func (h *ReadyHandler) Ready(c echo.Context) error {
+	status := entry_collector.WarmupStatus{Ready: true}
+	if h.warmup != nil {
+		status = h.warmup.WarmupStatus()
+	}
+	if !status.Ready {
+		return c.JSON(http.StatusServiceUnavailable, map[string]any{"status": "warming", "warmup": status})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"status": "ready", "warmup": status})
+}
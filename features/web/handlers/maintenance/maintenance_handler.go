@@ -0,0 +1,65 @@
+package maintenance
+
+import (
+	"blacked/features/maintenance"
+	"blacked/features/web/handlers/response"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ReadOnlyInput is the body of a request switching read-only mode on.
+type ReadOnlyInput struct {
+	Reason string `json:"reason"` // Shown in the health output while the mode is on
+}
+
+type MaintenanceHandler struct {
+	svc *maintenance.Service
+}
+
+func NewMaintenanceHandler(svc *maintenance.Service) *MaintenanceHandler {
+	return &MaintenanceHandler{svc: svc}
+}
+
+// Status returns the maintenance mode as stored.
+// GET /maintenance
+func (h *MaintenanceHandler) Status(c echo.Context) error {
+	state, err := h.svc.Refresh(c.Request().Context())
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to read maintenance mode")
+	}
+	return response.Success(c, state)
+}
+
+// EnableReadOnly pauses ingestion and rejects writes until read-only mode is
+// cleared, across restarts.
+// POST /maintenance/read-only {"reason": "backup"}
+func (h *MaintenanceHandler) EnableReadOnly(c echo.Context) error {
+	req := &ReadOnlyInput{}
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(req); err != nil {
+			return response.BadRequest(c, "Invalid request body: "+err.Error())
+		}
+	}
+
+	state, err := h.svc.Enable(c.Request().Context(), req.Reason)
+	switch {
+	case errors.Is(err, maintenance.ErrInvalidReason):
+		return response.BadRequest(c, err.Error())
+	case err != nil:
+		return response.Error(c, http.StatusInternalServerError, "Failed to switch read-only mode on")
+	}
+	return response.Success(c, state)
+}
+
+// DisableReadOnly clears read-only mode; scheduled runs resume at their next
+// tick.
+// DELETE /maintenance/read-only
+func (h *MaintenanceHandler) DisableReadOnly(c echo.Context) error {
+	state, err := h.svc.Disable(c.Request().Context())
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to clear read-only mode")
+	}
+	return response.Success(c, state)
+}
@@ -0,0 +1,24 @@
+package maintenance
+
+import (
+	"blacked/features/maintenance"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func MapMaintenanceRoutes(e *echo.Echo, svc *maintenance.Service) error {
+	handler := NewMaintenanceHandler(svc)
+
+	g := e.Group("/maintenance")
+	g.GET("", handler.Status)
+	g.POST("/read-only", handler.EnableReadOnly)
+	g.DELETE("/read-only", handler.DisableReadOnly)
+
+	log.Info().
+		Str("maintenance mode", "/maintenance").
+		Str("read-only toggle", "/maintenance/read-only").
+		Msg("Maintenance routes mapped successfully.")
+
+	return nil
+}
@@ -1,9 +1,17 @@
 package provider
 
 import (
+	"blacked/features/providers"
 	"blacked/features/providers/services"
 	"blacked/features/web/handlers/response"
+	"blacked/internal/pagination"
+	"blacked/internal/runner"
+	"blacked/internal/utils"
+	"errors"
+	"math"
 	"net/http"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/labstack/echo/v4"
@@ -17,6 +25,32 @@ var processStatuses sync.Map // Use sync.Map for concurrent access
 var isProcessRunning bool
 var processRunningMutex sync.Mutex
 
+// processPaging pages GET /provider/processes; newest first by default.
+var processPaging = pagination.Options{DefaultLimit: 50, MaxLimit: 500, Sorts: []string{"-start_time", "end_time", "status"}}
+
+var processSorts = map[string]func(a, b *providers.ProcessStatus) int{
+	"start_time": pagination.Compare(func(p *providers.ProcessStatus) int64 { return p.StartTime.UnixNano() }),
+	"end_time":   pagination.Compare(func(p *providers.ProcessStatus) int64 { return p.EndTime.UnixNano() }),
+	"status":     pagination.Compare(func(p *providers.ProcessStatus) string { return p.Status }),
+}
+
+// eventPaging pages GET /provider/processes/:processID/events; events keep
+// their recording order.
+var eventPaging = pagination.Options{DefaultLimit: 100, MaxLimit: 1000}
+
+// schedulePaging pages GET /provider/schedules.
+var schedulePaging = pagination.Options{DefaultLimit: 100, MaxLimit: 1000, Sorts: []string{"provider", "next_run"}}
+
+var scheduleSorts = map[string]func(a, b runner.ProviderSchedule) int{
+	"provider": pagination.Compare(func(s runner.ProviderSchedule) string { return s.Provider }),
+	"next_run": pagination.Compare(func(s runner.ProviderSchedule) int64 {
+		if s.NextRun == nil {
+			return math.MaxInt64
+		}
+		return s.NextRun.UnixNano()
+	}),
+}
+
 type ProviderProcessInput struct {
 	ProvidersToProcess []string `json:"providers_to_process"`
 	ProvidersToRemove  []string `json:"providers_to_remove"`
@@ -61,13 +95,28 @@ func (h *ProviderHandler) ProcessProviders(c echo.Context) error {
 	})
 }
 
+// ListProcesses returns one page of provider processes, optionally of one status.
+// GET /provider/processes?status=failed&sort=-start_time&cursor=<next_cursor>&limit=50
 func (h *ProviderHandler) ListProcesses(c echo.Context) error {
+	p, err := pagination.Parse(c.QueryParams(), processPaging)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
 	statuses, err := h.providerProcessService.ListProcesses(c.Request().Context())
 	if err != nil {
 		return response.ErrorWithDetails(c, http.StatusInternalServerError,
 			"Failed to list processes", err.Error())
 	}
-	return response.Success(c, statuses)
+	statuses = slices.DeleteFunc(statuses, func(s *providers.ProcessStatus) bool {
+		return !pagination.Match(c.QueryParams(), "status", s.Status)
+	})
+
+	page, err := pagination.Slice(statuses, p, processSorts)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, page)
 }
 
 func (h *ProviderHandler) GetProcessStatus(c echo.Context) error {
@@ -88,3 +137,120 @@ func (h *ProviderHandler) GetProcessStatus(c echo.Context) error {
 
 	return response.Success(c, status)
 }
+
+// ListProcessEvents returns one page of the events a provider run recorded:
+// fetch result, parse counters, delta commit and errors, oldest first,
+// optionally narrowed to a stage, a level and a search string.
+// GET /provider/processes/:processID/events?stage=fetch&level=error&q=timeout&cursor=<next_cursor>&limit=100
+func (h *ProviderHandler) ListProcessEvents(c echo.Context) error {
+	p, err := pagination.Parse(c.QueryParams(), eventPaging)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	processID := c.Param("processID")
+	filter := providers.ProcessEventFilter{
+		Stage:  c.QueryParam("stage"),
+		Level:  c.QueryParam("level"),
+		Search: c.QueryParam("q"),
+	}
+
+	page, err := h.providerProcessService.ListProcessEvents(c.Request().Context(), processID, filter, p)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return response.BadRequest(c, err.Error())
+	}
+	if err != nil {
+		return response.ErrorWithDetails(c, http.StatusInternalServerError,
+			"Failed to list process events", err.Error())
+	}
+	return response.Success(c, page)
+}
+
+// ListSchedules returns one page of provider schedules: the cron, the
+// timezone it is read in and the next run in UTC and in that timezone.
+// GET /provider/schedules?provider=oisd-big&sort=next_run&cursor=<next_cursor>&limit=100
+func (h *ProviderHandler) ListSchedules(c echo.Context) error {
+	p, err := pagination.Parse(c.QueryParams(), schedulePaging)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	r, err := runner.GetRunner()
+	if err != nil {
+		return response.Error(c, http.StatusServiceUnavailable, "Scheduler not initialized")
+	}
+
+	schedules := slices.DeleteFunc(r.Schedules(), func(s runner.ProviderSchedule) bool {
+		return !pagination.Match(c.QueryParams(), "provider", s.Provider)
+	})
+	page, err := pagination.Slice(schedules, p, scheduleSorts)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, page)
+}
+
+// ListResponses lists the stored provider responses, current and archived.
+// GET /provider/responses?provider=urlhaus-online
+func (h *ProviderHandler) ListResponses(c echo.Context) error {
+	responses, err := utils.ListStoredResponses(c.QueryParam("provider"))
+	if err != nil {
+		return response.ErrorWithDetails(c, http.StatusInternalServerError,
+			"Failed to list stored responses", err.Error())
+	}
+
+	var total int64
+	for _, r := range responses {
+		total += r.Size
+	}
+	return response.Success(c, map[string]any{
+		"count":       len(responses),
+		"total_bytes": total,
+		"responses":   responses,
+	})
+}
+
+// DeleteResponses deletes the stored responses of a provider, or one of them
+// when file is given. Deleting the current response makes the next run fetch.
+// DELETE /provider/responses/:provider?file=urlhaus-online_response.20261016T120000.000Z.dat
+func (h *ProviderHandler) DeleteResponses(c echo.Context) error {
+	provider := c.Param("provider")
+	file := c.QueryParam("file")
+
+	removed, err := utils.DeleteStoredResponses(provider, file)
+	if err != nil {
+		if errors.Is(err, utils.ErrStoredResponseNotFound) {
+			return response.NotFound(c, "Stored response not found", provider)
+		}
+		return response.ErrorWithDetails(c, http.StatusInternalServerError,
+			"Failed to delete stored responses", err.Error())
+	}
+
+	return response.Success(c, map[string]any{
+		"provider": provider,
+		"removed":  removed,
+	})
+}
+
+// CheckAuth checks that the credentials of the providers are accepted, with
+// one lightweight request each: every provider sending credentials, or the
+// comma-separated provider list.
+// GET /provider/auth?provider=phishtank-online-valid,abuseipdb-blacklist
+func (h *ProviderHandler) CheckAuth(c echo.Context) error {
+	var names []string
+	if list := c.QueryParam("provider"); list != "" {
+		names = strings.Split(list, ",")
+	}
+
+	statuses, err := providers.GetProviders().CheckAuth(c.Request().Context(), names)
+	if err != nil {
+		if errors.Is(err, providers.ErrProviderNotFound) {
+			return response.NotFound(c, "Provider not found", err.Error())
+		}
+		return response.Error(c, http.StatusInternalServerError, "Failed to check provider credentials")
+	}
+	return response.Success(c, map[string]any{
+		"ok":        !providers.AuthFailed(statuses),
+		"providers": statuses,
+	})
+}
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"blacked/features/providers/services"
+	"blacked/features/web/middlewares"
 
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog/log"
@@ -11,14 +12,23 @@ func MapProviderRoutes(e *echo.Echo, svc *services.ProviderProcessService) error
 	handler := NewProviderHandler(svc)
 
 	g := e.Group("/provider")
-	g.POST("/process", handler.ProcessProviders)
+	g.POST("/process", handler.ProcessProviders, middlewares.RequireJSON())
 	g.GET("/process/status/:processID", handler.GetProcessStatus)
 	g.GET("/processes", handler.ListProcesses) // Add list processes endpoint
+	g.GET("/processes/:processID/events", handler.ListProcessEvents)
+	g.GET("/schedules", handler.ListSchedules)
+	g.GET("/responses", handler.ListResponses)
+	g.DELETE("/responses/:provider", handler.DeleteResponses)
+	g.GET("/auth", handler.CheckAuth)
 
 	log.Info().
 		Str("new processing", "/provider/process").
 		Str("get process status", "/provider/process/status/:processID").
 		Str("list processes", "/provider/processes").
+		Str("process events", "/provider/processes/:processID/events").
+		Str("provider schedules", "/provider/schedules").
+		Str("stored responses", "/provider/responses").
+		Str("credential check", "/provider/auth").
 		Msg("Provider routes mapped successfully.")
 
 	return nil
@@ -0,0 +1,75 @@
+package replication
+
+import (
+	"blacked/features/replication"
+	"blacked/features/web/handlers/response"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ReplicationHandler struct {
+	svc   *replication.Service
+	token string // Bearer token required on the change feed; empty leaves it open
+}
+
+func NewReplicationHandler(svc *replication.Service, token string) *ReplicationHandler {
+	return &ReplicationHandler{svc: svc, token: token}
+}
+
+// Changes returns the entry changes after a cursor, oldest first, each with
+// the entry as it is now (null once removed). Replicas poll it with the
+// next cursor of the previous page until next reaches head.seq.
+// GET /replication/changes?after=0&limit=1000
+func (h *ReplicationHandler) Changes(c echo.Context) error {
+	if !h.authorized(c) {
+		return response.Error(c, http.StatusUnauthorized, replication.ErrUnauthorized.Error())
+	}
+
+	var after int64
+	if param := c.QueryParam("after"); param != "" {
+		var err error
+		if after, err = strconv.ParseInt(param, 10, 64); err != nil {
+			return response.BadRequest(c, "after must be an integer")
+		}
+	}
+	limit := 0
+	if param := c.QueryParam("limit"); param != "" {
+		var err error
+		if limit, err = strconv.Atoi(param); err != nil {
+			return response.BadRequest(c, "limit must be an integer")
+		}
+	}
+
+	feed, err := h.svc.Changes(c.Request().Context(), after, limit)
+	if errors.Is(err, replication.ErrInvalidCursor) {
+		return response.BadRequest(c, err.Error())
+	}
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to read entry changes")
+	}
+	return response.Success(c, feed)
+}
+
+// Status reports the replication role of this instance, the head of its
+// change feed and, on a replica, its lag behind the primary.
+// GET /replication/status
+func (h *ReplicationHandler) Status(c echo.Context) error {
+	status, err := h.svc.Status(c.Request().Context())
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to read replication status")
+	}
+	return response.Success(c, status)
+}
+
+func (h *ReplicationHandler) authorized(c echo.Context) bool {
+	if h.token == "" {
+		return true
+	}
+	given, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(h.token)) == 1
+}
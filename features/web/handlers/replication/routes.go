@@ -0,0 +1,24 @@
+package replication
+
+import (
+	"blacked/features/replication"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func MapReplicationRoutes(e *echo.Echo, svc *replication.Service, token string) error {
+	handler := NewReplicationHandler(svc, token)
+
+	g := e.Group("/replication")
+	g.GET("/changes", handler.Changes)
+	g.GET("/status", handler.Status)
+
+	log.Info().
+		Str("replication changes", "/replication/changes").
+		Str("replication status", "/replication/status").
+		Bool("token", token != "").
+		Msg("Replication routes mapped successfully.")
+
+	return nil
+}
@@ -43,3 +43,21 @@ func BadRequest(c echo.Context, message string) error {
 		"error":   message,
 	})
 }
+
+// TooLarge returns a standardized 413 response for oversized request bodies
+func TooLarge(c echo.Context, limit int64) error {
+	return c.JSON(413, map[string]any{
+		"success": false,
+		"error":   "Request body too large",
+		"details": map[string]any{"limit_bytes": limit},
+	})
+}
+
+// Unprocessable returns a standardized 422 response for well-formed but invalid input
+func Unprocessable(c echo.Context, message string, details any) error {
+	return c.JSON(422, map[string]any{
+		"success": false,
+		"error":   message,
+		"details": details,
+	})
+}
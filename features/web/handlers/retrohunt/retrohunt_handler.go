@@ -0,0 +1,36 @@
+package retrohunt
+
+import (
+	"blacked/features/retrohunt"
+	"blacked/features/web/handlers/response"
+	"blacked/features/web/middlewares"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type RetrohuntHandler struct {
+	svc *retrohunt.Service
+}
+
+func NewRetrohuntHandler(svc *retrohunt.Service) *RetrohuntHandler {
+	return &RetrohuntHandler{svc: svc}
+}
+
+// Hunt reports the visits of the uploaded traffic log to URLs listed now.
+// The body is the raw log; format is detected when omitted.
+// POST /retrohunt?format=har|zeek|squid
+func (h *RetrohuntHandler) Hunt(c echo.Context) error {
+	report, err := h.svc.HuntLog(c.Request().Context(), c.Request().Body, c.QueryParam("format"))
+	switch {
+	case middlewares.IsBodyTooLarge(err):
+		return middlewares.RejectOversizedBody(c, err)
+	case errors.Is(err, retrohunt.ErrUnknownFormat), errors.Is(err, retrohunt.ErrInvalidLog):
+		return response.BadRequest(c, err.Error())
+	case err != nil:
+		return response.Error(c, http.StatusInternalServerError, "Failed to check traffic log")
+	}
+
+	return response.Success(c, report)
+}
@@ -0,0 +1,20 @@
+package retrohunt
+
+import (
+	"blacked/features/retrohunt"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func MapRetrohuntRoutes(e *echo.Echo, svc *retrohunt.Service) error {
+	handler := NewRetrohuntHandler(svc)
+
+	e.POST("/retrohunt", handler.Hunt)
+
+	log.Info().
+		Str("retrohunt", "/retrohunt").
+		Msg("Retrohunt routes mapped successfully.")
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package scheduler
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func MapSchedulerRoutes(e *echo.Echo) error {
+	handler := NewSchedulerHandler()
+
+	g := e.Group("/scheduler")
+	g.GET("/timeline", handler.Timeline)
+	g.GET("/exports", handler.Exports)
+
+	log.Info().
+		Str("schedule timeline", "/scheduler/timeline").
+		Str("export prefetch plans", "/scheduler/exports").
+		Msg("Scheduler routes mapped successfully.")
+
+	return nil
+}
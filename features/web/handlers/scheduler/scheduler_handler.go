@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"blacked/features/web/handlers/response"
+	"blacked/internal/pagination"
+	"blacked/internal/runner"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultTimelineWindow = 24 * time.Hour
+	maxTimelineWindow     = 7 * 24 * time.Hour
+)
+
+// timelinePaging pages the providers of GET /scheduler/timeline.
+var timelinePaging = pagination.Options{DefaultLimit: 100, MaxLimit: 1000, Sorts: []string{"provider", "estimated_duration"}}
+
+var timelineSorts = map[string]func(a, b runner.ProviderTimeline) int{
+	"provider":           pagination.Compare(func(t runner.ProviderTimeline) string { return t.Provider }),
+	"estimated_duration": pagination.Compare(func(t runner.ProviderTimeline) int64 { return t.EstimatedDurationMs }),
+}
+
+// TimelinePage is a timeline holding one page of its providers.
+type TimelinePage struct {
+	*runner.Timeline
+	NextCursor    string `json:"next_cursor,omitempty"`
+	TotalEstimate int    `json:"total_estimate"`
+}
+
+type SchedulerHandler struct{}
+
+func NewSchedulerHandler() *SchedulerHandler {
+	return &SchedulerHandler{}
+}
+
+// Timeline returns planned and historical run intervals for one page of providers.
+// GET /scheduler/timeline?window=24h&provider=oisd-big,urlhaus-online&cursor=<next_cursor>&limit=100
+func (h *SchedulerHandler) Timeline(c echo.Context) error {
+	p, err := pagination.Parse(c.QueryParams(), timelinePaging)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	window, err := parseWindow(c)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	r, err := runner.GetRunner()
+	if err != nil {
+		return response.Error(c, http.StatusServiceUnavailable, "Scheduler not initialized")
+	}
+
+	timeline := r.Timeline(window, time.Now().UTC())
+	timeline.Providers = slices.DeleteFunc(timeline.Providers, func(t runner.ProviderTimeline) bool {
+		return !pagination.Match(c.QueryParams(), "provider", t.Provider)
+	})
+
+	page, err := pagination.Slice(timeline.Providers, p, timelineSorts)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	timeline.Providers = page.Items
+	return response.Success(c, TimelinePage{Timeline: timeline, NextCursor: page.NextCursor, TotalEstimate: page.TotalEstimate})
+}
+
+// Exports returns the prefetch plan of every export schedule over the window
+// ahead, with the conflicts between its prefetches and the provider schedules.
+// GET /scheduler/exports?window=24h
+func (h *SchedulerHandler) Exports(c echo.Context) error {
+	window, err := parseWindow(c)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	r, err := runner.GetRunner()
+	if err != nil {
+		return response.Error(c, http.StatusServiceUnavailable, "Scheduler not initialized")
+	}
+	return response.Success(c, r.ExportPlans(window, time.Now().UTC()))
+}
+
+// parseWindow reads the window query parameter, 24h when unset.
+func parseWindow(c echo.Context) (time.Duration, error) {
+	param := c.QueryParam("window")
+	if param == "" {
+		return defaultTimelineWindow, nil
+	}
+	window, err := time.ParseDuration(param)
+	if err != nil || window <= 0 {
+		return 0, errors.New("window must be a positive duration, e.g. 24h")
+	}
+	if window > maxTimelineWindow {
+		return 0, errors.New("window must not exceed " + maxTimelineWindow.String())
+	}
+	return window, nil
+}
@@ -0,0 +1,21 @@
+package stats
+
+import (
+	"blacked/features/stats"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func MapStatsRoutes(e *echo.Echo, svc *stats.Service) error {
+	handler := NewStatsHandler(svc)
+
+	g := e.Group("/stats")
+	g.GET("/timeseries", handler.Timeseries)
+
+	log.Info().
+		Str("stats timeseries", "/stats/timeseries").
+		Msg("Stats routes mapped successfully.")
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package stats
+
+import (
+	"blacked/features/stats"
+	"blacked/features/web/handlers/response"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type StatsHandler struct {
+	svc *stats.Service
+}
+
+func NewStatsHandler(svc *stats.Service) *StatsHandler {
+	return &StatsHandler{svc: svc}
+}
+
+// Timeseries returns the stored rollups as a bare array of Grafana JSON
+// datasource series, [{"target": "queries", "datapoints": [[value, ms], ...]}].
+// GET /stats/timeseries?metric=queries,hit_rate&since=${__from}&until=${__to}&step=5m&source=oisd-big
+func (h *StatsHandler) Timeseries(c echo.Context) error {
+	q, err := stats.ParseQuery(c.QueryParams(), time.Now().UTC())
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	series, err := h.svc.Timeseries(c.Request().Context(), q)
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to read stats timeseries")
+	}
+	return c.JSON(http.StatusOK, series)
+}
@@ -1,19 +1,31 @@
 package v2
 
 import (
+	"blacked/features/allowlist"
+	"blacked/features/audit"
 	"blacked/features/bloom"
 	"blacked/features/web/handlers/response"
+	"blacked/features/web/middlewares"
 	"blacked/internal/db"
+	"blacked/internal/logger"
 	"blacked/internal/query"
+	"blacked/internal/utils"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog/log"
 )
 
+// DefaultMaxBulkURLs caps bulk requests when no limit is configured.
+const DefaultMaxBulkURLs = 1000
+
 // QueryHandler wraps the new HTTP-agnostic QueryService for v2 API endpoints.
 type QueryHandler struct {
-	svc *query.QueryService
+	svc         *query.QueryService
+	maxBulkURLs int
+	schema      Schema // Response shape; the zero value is v1
 }
 
 // NewQueryHandler constructs a QueryHandler with the shared BloomManager.
@@ -30,16 +42,57 @@ func NewQueryHandler(mgr *bloom.BloomManager, trustConfig map[string]float64) (*
 
 	scorer := query.NewScorer(trustConfig)
 
-	svc := query.NewQueryService(checker, repo, scorer)
-	return &QueryHandler{svc: svc}, nil
+	svc := query.NewQueryService(checker, repo, scorer).
+		SetAllowlist(allowlist.NewServiceWithRepository(allowlist.NewSQLiteRepository(database))).
+		SetNegativeCache(query.SharedNegativeCache())
+	return &QueryHandler{svc: svc, maxBulkURLs: DefaultMaxBulkURLs}, nil
 }
 
 // NewQueryHandlerWithDeps allows injecting dependencies for testing.
 func NewQueryHandlerWithDeps(svc *query.QueryService) *QueryHandler {
-	return &QueryHandler{svc: svc}
+	return &QueryHandler{svc: svc, maxBulkURLs: DefaultMaxBulkURLs}
+}
+
+// SetMaxBulkURLs sets the maximum number of URLs accepted per bulk request.
+// Non-positive values keep the current limit.
+func (h *QueryHandler) SetMaxBulkURLs(n int) *QueryHandler {
+	if n > 0 {
+		h.maxBulkURLs = n
+	}
+	return h
+}
+
+// WithSchema returns a copy of the handler rendering results with schema,
+// sharing its query service.
+func (h *QueryHandler) WithSchema(schema Schema) *QueryHandler {
+	clone := *h
+	clone.schema = schema
+	return &clone
 }
 
-// Check handles GET /api/v1/check?url= — fast bloom-only check (~0.4ms).
+// SetPolicy sets the policy deciding the action of hit results;
+// sourceCategories maps source IDs to their category.
+func (h *QueryHandler) SetPolicy(p *query.Policy, sourceCategories map[string]string) *QueryHandler {
+	h.svc.SetPolicy(p, sourceCategories)
+	return h
+}
+
+// bindBulkInput binds and validates a bulk request, writing a structured
+// 400/422 response on failure. ok is false when a response was written.
+func (h *QueryHandler) bindBulkInput(c echo.Context) (input bulkInput, ok bool, err error) {
+	if err := c.Bind(&input); err != nil {
+		return input, false, response.BadRequest(c, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&input); err != nil {
+		return input, false, middlewares.RejectInvalid(c, err)
+	}
+	if len(input.URLs) > h.maxBulkURLs {
+		return input, false, middlewares.RejectTooMany(c, "urls", len(input.URLs), h.maxBulkURLs)
+	}
+	return input, true, nil
+}
+
+// Check handles GET /api/<version>/check?url= — fast bloom-only check (~0.4ms).
 // Returns 204 No Content if no match, 200 with body if matched.
 func (h *QueryHandler) Check(c echo.Context) error {
 	urlStr := c.QueryParam("url")
@@ -49,18 +102,22 @@ func (h *QueryHandler) Check(c echo.Context) error {
 
 	result, err := h.svc.Likely(c.Request().Context(), urlStr)
 	if err != nil {
-		log.Error().Err(err).Str("url", urlStr).Msg("v2 check failed")
+		if errors.Is(err, utils.ErrUnsupportedScheme) {
+			return middlewares.RejectScheme(c, err)
+		}
+		log.Error().Err(err).Str("url", logger.RedactURL(urlStr)).Msg("v2 check failed")
 		return response.ErrorWithDetails(c, http.StatusInternalServerError,
 			"Bloom check failed", err.Error())
 	}
 
+	middlewares.AuditResult(c, auditLikely(*result))
 	if !result.Likely {
 		return c.NoContent(http.StatusNoContent)
 	}
-	return c.JSON(http.StatusOK, result)
+	return c.JSON(http.StatusOK, h.schema.Likely(result))
 }
 
-// Hit handles GET /api/v1/hit?url= — full check (bloom + DB + score ~5-15ms).
+// Hit handles GET /api/<version>/hit?url= — full check (bloom + DB + score ~5-15ms).
 // Returns 204 No Content if no match, 200 with body if matched.
 func (h *QueryHandler) Hit(c echo.Context) error {
 	urlStr := c.QueryParam("url")
@@ -70,30 +127,75 @@ func (h *QueryHandler) Hit(c echo.Context) error {
 
 	result, err := h.svc.Hit(c.Request().Context(), urlStr)
 	if err != nil {
-		log.Error().Err(err).Str("url", urlStr).Msg("v2 hit failed")
+		if errors.Is(err, utils.ErrUnsupportedScheme) {
+			return middlewares.RejectScheme(c, err)
+		}
+		log.Error().Err(err).Str("url", logger.RedactURL(urlStr)).Msg("v2 hit failed")
 		return response.ErrorWithDetails(c, http.StatusInternalServerError,
 			"Hit check failed", err.Error())
 	}
 
+	middlewares.AuditResult(c, auditHit(*result))
 	if !result.Blocked {
 		return c.NoContent(http.StatusNoContent)
 	}
-	return c.JSON(http.StatusOK, result)
+	return c.JSON(http.StatusOK, h.schema.Hit(result))
+}
+
+// Response headers of EdgeCheck.
+const (
+	HeaderMatchType = "X-Blacked-Match-Type"
+	HeaderScore     = "X-Blacked-Score"
+	HeaderLevel     = "X-Blacked-Level"
+	HeaderAction    = "X-Blacked-Action"
+)
+
+// EdgeCheck handles GET /check?url= — the full check of Hit for CDN and edge
+// callers that only need the verdict. Returns 204 No Content if the URL is
+// clean or the policy allows it, 200 with a "blocked" body otherwise; the
+// match type, score, level and policy action are sent as headers and the
+// matches are never marshaled.
+func (h *QueryHandler) EdgeCheck(c echo.Context) error {
+	urlStr := c.QueryParam("url")
+	if urlStr == "" {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	result, err := h.svc.Hit(c.Request().Context(), urlStr)
+	if err != nil {
+		if errors.Is(err, utils.ErrUnsupportedScheme) {
+			middlewares.RecordRejection(c, middlewares.RejectUnsupportedScheme)
+			return c.NoContent(http.StatusBadRequest)
+		}
+		log.Error().Err(err).Str("url", logger.RedactURL(urlStr)).Msg("edge check failed")
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	middlewares.AuditResult(c, auditHit(*result))
+	if !result.Blocked || result.Action == query.ActionAllow {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	header := c.Response().Header()
+	header.Set(HeaderMatchType, result.MatchType)
+	header.Set(HeaderScore, strconv.FormatFloat(result.Confidence, 'f', 4, 64))
+	header.Set(HeaderLevel, result.Level)
+	if result.Action != "" {
+		header.Set(HeaderAction, string(result.Action))
+	}
+	return c.String(http.StatusOK, "blocked")
 }
 
 // bulkInput is the request body for bulk endpoints.
 type bulkInput struct {
-	URLs []string `json:"urls" validate:"required,min=1"`
+	URLs []string `json:"urls" validate:"required,min=1,dive,required"`
 }
 
-// BulkCheck handles POST /api/v1/bulk-check — bloom-only batch check (~0.4ms per URL).
+// BulkCheck handles POST /api/<version>/bulk-check — bloom-only batch check (~0.4ms per URL).
 func (h *QueryHandler) BulkCheck(c echo.Context) error {
-	var input bulkInput
-	if err := c.Bind(&input); err != nil {
-		return response.BadRequest(c, "Invalid request body: "+err.Error())
-	}
-	if err := c.Validate(&input); err != nil {
-		return response.BadRequest(c, "Validation error: "+err.Error())
+	input, ok, err := h.bindBulkInput(c)
+	if !ok {
+		return err
 	}
 
 	results, err := h.svc.BulkCheck(c.Request().Context(), input.URLs)
@@ -102,18 +204,20 @@ func (h *QueryHandler) BulkCheck(c echo.Context) error {
 		return response.ErrorWithDetails(c, http.StatusInternalServerError,
 			"Bulk check failed", err.Error())
 	}
+	for _, r := range results {
+		if r.Error == "" { // Rejected URLs were never checked
+			middlewares.AuditResult(c, auditLikely(r))
+		}
+	}
 
-	return c.JSON(http.StatusOK, results)
+	return c.JSON(http.StatusOK, h.schema.BulkLikely(results))
 }
 
-// BulkHit handles POST /api/v1/bulk-hit — full batch check (bloom + DB + score).
+// BulkHit handles POST /api/<version>/bulk-hit — full batch check (bloom + DB + score).
 func (h *QueryHandler) BulkHit(c echo.Context) error {
-	var input bulkInput
-	if err := c.Bind(&input); err != nil {
-		return response.BadRequest(c, "Invalid request body: "+err.Error())
-	}
-	if err := c.Validate(&input); err != nil {
-		return response.BadRequest(c, "Validation error: "+err.Error())
+	input, ok, err := h.bindBulkInput(c)
+	if !ok {
+		return err
 	}
 
 	results, err := h.svc.BulkHit(c.Request().Context(), input.URLs)
@@ -122,6 +226,25 @@ func (h *QueryHandler) BulkHit(c echo.Context) error {
 		return response.ErrorWithDetails(c, http.StatusInternalServerError,
 			"Bulk hit failed", err.Error())
 	}
+	for _, r := range results {
+		if r.Error == "" { // Rejected URLs were never checked
+			middlewares.AuditResult(c, auditHit(r))
+		}
+	}
 
-	return c.JSON(http.StatusOK, results)
+	return c.JSON(http.StatusOK, h.schema.BulkHit(results))
+}
+
+// auditHit is the audit record of a full check: a hit when blocked.
+func auditHit(r query.QueryResponse) audit.Result {
+	return audit.Result{URL: r.URL, Hit: r.Blocked, MatchType: r.MatchType}
+}
+
+// auditLikely is the audit record of a bloom-only check, typed by its first match.
+func auditLikely(r query.LikelyResponse) audit.Result {
+	res := audit.Result{URL: r.URL, Hit: r.Likely}
+	if len(r.Matches) > 0 {
+		res.MatchType = r.Matches[0].Type
+	}
+	return res
 }
@@ -1,30 +1,32 @@
 package v2
 
 import (
+	"blacked/features/web/middlewares"
+
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog/log"
 )
 
-// MapV2Routes registers the v2 API routes on the given Echo group.
-// Group at /api/v1 is expected — routes are:
-//   GET  /api/v1/check?url=   → QueryHandler.Check (bloom only)
-//   GET  /api/v1/hit?url=     → QueryHandler.Hit   (bloom + DB + score)
-//   POST /api/v1/bulk-check    → QueryHandler.BulkCheck (bloom-only batch)
-//   POST /api/v1/bulk-hit      → QueryHandler.BulkHit   (full batch: bloom + DB + score)
-func MapV2Routes(e *echo.Echo, handler *QueryHandler) error {
-	g := e.Group("/api/v1")
-
-	g.GET("/check", handler.Check)
-	g.GET("/hit", handler.Hit)
-	g.POST("/bulk-check", handler.BulkCheck)
-	g.POST("/bulk-hit", handler.BulkHit)
+// MapQueryRoutes registers the query API on g, a group at /api/<version>
+// whose handler renders that version's schema — routes are:
+//
+//	GET  <group>/check?url=    → QueryHandler.Check (bloom only)
+//	GET  <group>/hit?url=      → QueryHandler.Hit   (bloom + DB + score)
+//	POST <group>/bulk-check    → QueryHandler.BulkCheck (bloom-only batch)
+//	POST <group>/bulk-hit      → QueryHandler.BulkHit   (full batch: bloom + DB + score)
+//
+// Every answered URL is recorded in the query audit log when it is on.
+func MapQueryRoutes(g *echo.Group, handler *QueryHandler) {
+	g.GET("/check", handler.Check, middlewares.AuditQueries())
+	g.GET("/hit", handler.Hit, middlewares.AuditQueries())
+	g.POST("/bulk-check", handler.BulkCheck, middlewares.RequireJSON(), middlewares.AuditQueries())
+	g.POST("/bulk-hit", handler.BulkHit, middlewares.RequireJSON(), middlewares.AuditQueries())
+}
 
-	log.Info().
-		Str("check", "GET /api/v1/check?url=").
-		Str("hit", "GET /api/v1/hit?url=").
-		Str("bulk-check", "POST /api/v1/bulk-check").
-		Str("bulk-hit", "POST /api/v1/bulk-hit").
-		Msg("V2 API routes mapped successfully.")
+// MapEdgeCheckRoute registers GET /check?url= → QueryHandler.EdgeCheck, the
+// verdict as status and headers, which has no versioned body.
+func MapEdgeCheckRoute(e *echo.Echo, handler *QueryHandler) {
+	e.GET("/check", handler.EdgeCheck, middlewares.AuditQueries())
 
-	return nil
+	log.Info().Str("edge check", "GET /check?url=").Msg("Edge check route mapped successfully.")
 }
@@ -0,0 +1,228 @@
+package v2
+
+import (
+	"blacked/internal/config"
+	"blacked/internal/query"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Response schema versions of the query API, each mounted at /api/<version>.
+// V1 is the original flat shape; V2 separates the verdict from the evidence
+// and aggregates matches per source.
+const (
+	V1 = "v1"
+	V2 = "v2"
+)
+
+// Versions lists the response schema versions, oldest first.
+var Versions = []string{V1, V2}
+
+var (
+	ErrUnknownVersion  = errors.New("unknown query API version")
+	ErrInvalidTemplate = errors.New("invalid response template")
+)
+
+// Schema renders query results in the response shape of one API version,
+// reshaped by the deployment's response template.
+type Schema struct {
+	version string
+	rename  []rename // Deepest paths first, so renaming a parent keeps child paths valid
+	omit    []string // Field paths
+}
+
+type rename struct {
+	path []string
+	to   string
+}
+
+// NewSchema returns the schema of version reshaped by tmpl. Template fields
+// are paths of JSON keys joined by "/", such as "verdict/score"; a path
+// through an array applies to every element.
+func NewSchema(version string, tmpl config.ResponseTemplate) (Schema, error) {
+	if !slices.Contains(Versions, version) {
+		return Schema{}, errors.Join(ErrUnknownVersion, errors.New(version))
+	}
+
+	s := Schema{version: version, omit: tmpl.Omit}
+	for from, to := range tmpl.Rename {
+		if from == "" || to == "" || strings.Contains(to, "/") {
+			return Schema{}, errors.Join(ErrInvalidTemplate, errors.New("rename needs a field path and a new field name: "+from))
+		}
+		s.rename = append(s.rename, rename{path: strings.Split(from, "/"), to: to})
+	}
+	slices.SortFunc(s.rename, func(a, b rename) int {
+		if len(a.path) != len(b.path) {
+			return len(b.path) - len(a.path)
+		}
+		return strings.Compare(strings.Join(a.path, "/"), strings.Join(b.path, "/"))
+	})
+	for _, path := range tmpl.Omit {
+		if path == "" {
+			return Schema{}, errors.Join(ErrInvalidTemplate, errors.New("omit needs field paths"))
+		}
+	}
+	return s, nil
+}
+
+// Version returns the API version of the schema.
+func (s Schema) Version() string {
+	if s.version == "" {
+		return V1
+	}
+	return s.version
+}
+
+// HitV2 is the v2 shape of a full check result.
+type HitV2 struct {
+	URL     string     `json:"url"`
+	Verdict VerdictV2  `json:"verdict"`
+	Sources []SourceV2 `json:"sources"`
+	Error   string     `json:"error,omitempty"` // Why a URL of a bulk check was not checked
+}
+
+// VerdictV2 is the decision of a full check.
+type VerdictV2 struct {
+	Blocked     bool         `json:"blocked"`
+	Allowlisted bool         `json:"allowlisted,omitempty"`
+	Action      query.Action `json:"action,omitempty"`
+	Score       float64      `json:"score"`
+	Level       string       `json:"level"`
+	MatchType   string       `json:"match_type,omitempty"`
+}
+
+// CheckV2 is the v2 shape of a bloom-only check result.
+type CheckV2 struct {
+	URL     string     `json:"url"`
+	Likely  bool       `json:"likely"`
+	Depth   int        `json:"depth"` // 0-100 scale
+	Sources []SourceV2 `json:"sources"`
+	Error   string     `json:"error,omitempty"` // Why a URL of a bulk check was not checked
+}
+
+// SourceV2 groups the matches of one source.
+type SourceV2 struct {
+	SourceID   string    `json:"source_id"`
+	TrustScore float64   `json:"trust_score,omitempty"`
+	Matches    []MatchV2 `json:"matches"`
+}
+
+// MatchV2 is one matching key of a source.
+type MatchV2 struct {
+	Type string `json:"type"`
+	Key  string `json:"key"`
+}
+
+// aggregate groups matches per source, in order of first match.
+func aggregate(matches []query.Match) []SourceV2 {
+	sources := []SourceV2{}
+	index := map[string]int{}
+	for _, m := range matches {
+		i, ok := index[m.SourceID]
+		if !ok {
+			i = len(sources)
+			index[m.SourceID] = i
+			sources = append(sources, SourceV2{SourceID: m.SourceID, TrustScore: m.TrustScore})
+		}
+		sources[i].Matches = append(sources[i].Matches, MatchV2{Type: m.Type, Key: m.Key})
+	}
+	return sources
+}
+
+// Hit renders a full check result.
+func (s Schema) Hit(r *query.QueryResponse) any {
+	if s.Version() == V1 {
+		return s.apply(r)
+	}
+	return s.apply(HitV2{
+		URL: r.URL,
+		Verdict: VerdictV2{
+			Blocked:     r.Blocked,
+			Allowlisted: r.Allowlisted,
+			Action:      r.Action,
+			Score:       r.Confidence,
+			Level:       r.Level,
+			MatchType:   r.MatchType,
+		},
+		Sources: aggregate(r.Matches),
+		Error:   r.Error,
+	})
+}
+
+// Likely renders a bloom-only check result.
+func (s Schema) Likely(r *query.LikelyResponse) any {
+	if s.Version() == V1 {
+		return s.apply(r)
+	}
+	return s.apply(CheckV2{URL: r.URL, Likely: r.Likely, Depth: r.MaxDepth, Sources: aggregate(r.Matches), Error: r.Error})
+}
+
+// BulkHit renders the results of a bulk full check.
+func (s Schema) BulkHit(results []query.QueryResponse) []any {
+	out := make([]any, len(results))
+	for i := range results {
+		out[i] = s.Hit(&results[i])
+	}
+	return out
+}
+
+// BulkLikely renders the results of a bulk bloom-only check.
+func (s Schema) BulkLikely(results []query.LikelyResponse) []any {
+	out := make([]any, len(results))
+	for i := range results {
+		out[i] = s.Likely(&results[i])
+	}
+	return out
+}
+
+// apply reshapes v by the template; v is returned as is without one.
+func (s Schema) apply(v any) any {
+	if len(s.rename) == 0 && len(s.omit) == 0 {
+		return v
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return v
+	}
+
+	for _, path := range s.omit {
+		walk(doc, strings.Split(path, "/"), func(m map[string]any, key string) {
+			delete(m, key)
+		})
+	}
+	for _, r := range s.rename {
+		walk(doc, r.path, func(m map[string]any, key string) {
+			if value, ok := m[key]; ok {
+				delete(m, key)
+				m[r.to] = value
+			}
+		})
+	}
+	return doc
+}
+
+// walk calls fn with every object holding the last key of path.
+func walk(v any, path []string, fn func(m map[string]any, key string)) {
+	switch v := v.(type) {
+	case map[string]any:
+		if len(path) == 1 {
+			fn(v, path[0])
+			return
+		}
+		walk(v[path[0]], path[1:], fn)
+	case []any:
+		for _, elem := range v {
+			walk(elem, path, fn)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package v2
+
+import (
+	"blacked/internal/config"
+	"blacked/internal/query"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hitResult() *query.QueryResponse {
+	return &query.QueryResponse{
+		URL:        "https://evil.example.net/login",
+		Blocked:    true,
+		Confidence: 0.8,
+		Level:      "high",
+		Action:     query.ActionBlock,
+		MatchType:  "domain",
+		Matches: []query.Match{
+			{SourceID: "oisd", Type: "domain", Key: "evil.example.net", TrustScore: 0.6},
+			{SourceID: "openphish", Type: "host_path", Key: "evil.example.net/login", TrustScore: 0.9},
+			{SourceID: "oisd", Type: "host_path", Key: "evil.example.net/login", TrustScore: 0.6},
+		},
+	}
+}
+
+func render(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestSchemaVersions(t *testing.T) {
+	v1, err := NewSchema(V1, config.ResponseTemplate{})
+	require.NoError(t, err)
+	assert.Equal(t, render(t, hitResult()), render(t, v1.Hit(hitResult())), "v1 is the original shape")
+	assert.Equal(t, V1, Schema{}.Version())
+
+	v2, err := NewSchema(V2, config.ResponseTemplate{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"url": "https://evil.example.net/login",
+		"verdict": {"blocked": true, "action": "block", "score": 0.8, "level": "high", "match_type": "domain"},
+		"sources": [
+			{"source_id": "oisd", "trust_score": 0.6, "matches": [{"type": "domain", "key": "evil.example.net"}, {"type": "host_path", "key": "evil.example.net/login"}]},
+			{"source_id": "openphish", "trust_score": 0.9, "matches": [{"type": "host_path", "key": "evil.example.net/login"}]}
+		]
+	}`, render(t, v2.Hit(hitResult())))
+
+	assert.JSONEq(t, `[{"url": "https://clean.example.net/", "likely": false, "depth": 0, "sources": []}]`,
+		render(t, v2.BulkLikely([]query.LikelyResponse{{URL: "https://clean.example.net/"}})))
+
+	_, err = NewSchema("v9", config.ResponseTemplate{})
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+}
+
+func TestSchemaTemplate(t *testing.T) {
+	legacy, err := NewSchema(V1, config.ResponseTemplate{
+		Rename: map[string]string{"blocked": "malicious", "confidence": "score", "matches/source_id": "feed"},
+		Omit:   []string{"level", "matches/trust_score"},
+	})
+	require.NoError(t, err)
+	out := render(t, legacy.BulkHit([]query.QueryResponse{*hitResult()}))
+	assert.JSONEq(t, `[{
+		"url": "https://evil.example.net/login", "malicious": true, "score": 0.8, "action": "block", "match_type": "domain",
+		"matches": [
+			{"feed": "oisd", "type": "domain", "key": "evil.example.net"},
+			{"feed": "openphish", "type": "host_path", "key": "evil.example.net/login"},
+			{"feed": "oisd", "type": "host_path", "key": "evil.example.net/login"}
+		]
+	}]`, out)
+
+	// Renaming a parent keeps renames below it working.
+	v2, err := NewSchema(V2, config.ResponseTemplate{Rename: map[string]string{"verdict": "decision", "verdict/score": "risk"}})
+	require.NoError(t, err)
+	assert.Contains(t, render(t, v2.Hit(hitResult())), `"decision":{"action":"block","blocked":true,"level":"high","match_type":"domain","risk":0.8}`)
+
+	_, err = NewSchema(V1, config.ResponseTemplate{Rename: map[string]string{"blocked": "a/b"}})
+	assert.ErrorIs(t, err, ErrInvalidTemplate)
+}
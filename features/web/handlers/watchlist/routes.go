@@ -0,0 +1,27 @@
+package watchlist
+
+import (
+	"blacked/features/watchlist"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func MapWatchlistRoutes(e *echo.Echo, svc *watchlist.Service) error {
+	handler := NewWatchlistHandler(svc)
+
+	g := e.Group("/watchlists")
+	g.GET("", handler.List)
+	g.POST("", handler.Create)
+	g.GET("/events", handler.Events)
+	g.GET("/:id", handler.Get)
+	g.DELETE("/:id", handler.Delete)
+
+	log.Info().
+		Str("watchlists", "/watchlists").
+		Str("watchlist events", "/watchlists/events").
+		Str("watchlist", "/watchlists/:id").
+		Msg("Watchlist routes mapped successfully.")
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package watchlist
+
+import (
+	"blacked/features/watchlist"
+	"blacked/features/web/handlers/response"
+	"blacked/internal/pagination"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// eventsHeartbeat keeps idle event streams open through proxies.
+const eventsHeartbeat = 15 * time.Second
+
+// watchlistPaging pages GET /watchlists; oldest first by default.
+var watchlistPaging = pagination.Options{DefaultLimit: 100, MaxLimit: 1000, Sorts: []string{"created_at", "name"}}
+
+var watchlistSorts = map[string]func(a, b watchlist.Watchlist) int{
+	"created_at": pagination.Compare(func(w watchlist.Watchlist) int64 { return w.CreatedAt.UnixNano() }),
+	"name":       pagination.Compare(func(w watchlist.Watchlist) string { return w.Name }),
+}
+
+// WatchlistInput is the body of a watchlist creation request.
+type WatchlistInput struct {
+	Name       string   `json:"name"`
+	Suffixes   []string `json:"suffixes"`
+	Keywords   []string `json:"keywords"`
+	Brands     []string `json:"brands"`
+	WebhookURL string   `json:"webhook_url"` // Empty uses [Watchlist] webhook_url
+}
+
+type WatchlistHandler struct {
+	svc *watchlist.Service
+}
+
+func NewWatchlistHandler(svc *watchlist.Service) *WatchlistHandler {
+	return &WatchlistHandler{svc: svc}
+}
+
+// List returns one page of watchlists.
+// GET /watchlists?sort=name&cursor=<next_cursor>&limit=100
+func (h *WatchlistHandler) List(c echo.Context) error {
+	p, err := pagination.Parse(c.QueryParams(), watchlistPaging)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	lists, err := h.svc.List(c.Request().Context())
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to list watchlists")
+	}
+
+	page, err := pagination.Slice(lists, p, watchlistSorts)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, page)
+}
+
+// Get returns one watchlist.
+// GET /watchlists/:id
+func (h *WatchlistHandler) Get(c echo.Context) error {
+	id := c.Param("id")
+
+	w, err := h.svc.Get(c.Request().Context(), id)
+	if errors.Is(err, watchlist.ErrWatchlistNotFound) {
+		return response.NotFound(c, "Watchlist not found", id)
+	}
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to get watchlist")
+	}
+	return response.Success(c, w)
+}
+
+// Create adds a watchlist.
+// POST /watchlists {"name": "example-brand", "suffixes": ["example.com"], "keywords": ["example-login"], "brands": ["Example Bank"], "webhook_url": "..."}
+func (h *WatchlistHandler) Create(c echo.Context) error {
+	req := &WatchlistInput{}
+	if err := c.Bind(req); err != nil {
+		return response.BadRequest(c, "Invalid request body: "+err.Error())
+	}
+
+	w, err := h.svc.Add(c.Request().Context(), watchlist.Watchlist{
+		Name:       req.Name,
+		Suffixes:   req.Suffixes,
+		Keywords:   req.Keywords,
+		Brands:     req.Brands,
+		WebhookURL: req.WebhookURL,
+	})
+	switch {
+	case errors.Is(err, watchlist.ErrInvalidWatchlist):
+		return response.BadRequest(c, err.Error())
+	case errors.Is(err, watchlist.ErrWatchlistExists):
+		return response.Error(c, http.StatusConflict, "Watchlist already exists")
+	case err != nil:
+		return response.Error(c, http.StatusInternalServerError, "Failed to add watchlist")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]any{
+		"success": true,
+		"data":    w,
+	})
+}
+
+// Delete removes a watchlist and forgets the hosts it reported.
+// DELETE /watchlists/:id
+func (h *WatchlistHandler) Delete(c echo.Context) error {
+	id := c.Param("id")
+
+	err := h.svc.Delete(c.Request().Context(), id)
+	if errors.Is(err, watchlist.ErrWatchlistNotFound) {
+		return response.NotFound(c, "Watchlist not found", id)
+	}
+	if err != nil {
+		return response.Error(c, http.StatusInternalServerError, "Failed to delete watchlist")
+	}
+	return response.Success(c, map[string]string{"id": id})
+}
+
+// Events streams watchlist matches as server-sent events until the client
+// disconnects, optionally only those of some watchlists (by name or ID).
+// GET /watchlists/events?watchlist=example-brand
+func (h *WatchlistHandler) Events(c echo.Context) error {
+	events, cancel := h.svc.Broker().Subscribe()
+	defer cancel()
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	ctx := c.Request().Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return nil
+			}
+			w.Flush()
+		case ev := <-events:
+			if !pagination.Match(c.QueryParams(), "watchlist", ev.Watchlist) &&
+				!pagination.Match(c.QueryParams(), "watchlist", ev.WatchlistID) {
+				continue
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+				return nil
+			}
+			w.Flush()
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"blacked/features/audit"
+	"blacked/features/stats"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	auditResultsKey = "audit_results"
+
+	// maxCallerLength caps the caller header kept in an audit record.
+	maxCallerLength = 256
+)
+
+// AuditQueries records the URLs a query handler answered, reported with
+// AuditResult, in the query audit log and the stats rollups once the
+// response is written. It does nothing while both are off.
+func AuditQueries() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			recorder, rollups := audit.Get(), stats.Get()
+			if recorder == nil && rollups == nil {
+				return next(c)
+			}
+
+			start := time.Now()
+			err := next(c)
+
+			results, _ := c.Get(auditResultsKey).([]audit.Result)
+			if len(results) == 0 {
+				return err
+			}
+			if rollups != nil {
+				rollups.Record(len(results), countHits(results))
+			}
+			if recorder == nil {
+				return err
+			}
+			req := c.Request()
+			var caller string
+			if key := APIKey(c); key != nil {
+				caller = key.Caller()
+			} else if header := recorder.CallerHeader(); header != "" {
+				caller = req.Header.Get(header)
+				if len(caller) > maxCallerLength {
+					caller = caller[:maxCallerLength]
+				}
+			}
+			recorder.Record(audit.Request{
+				Endpoint: req.Method + " " + c.Path(),
+				Caller:   caller,
+				RemoteIP: c.RealIP(),
+				Start:    start,
+				Latency:  time.Since(start),
+			}, results...)
+			return err
+		}
+	}
+}
+
+// AuditResult reports the answer to queried URLs to AuditQueries.
+func AuditResult(c echo.Context, results ...audit.Result) {
+	existing, _ := c.Get(auditResultsKey).([]audit.Result)
+	c.Set(auditResultsKey, append(existing, results...))
+}
+
+func countHits(results []audit.Result) int {
+	n := 0
+	for _, r := range results {
+		if r.Hit {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"blacked/features/apikeys"
+	"blacked/features/web/handlers/response"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// HeaderAPIKey carries the API key of a request; "Authorization: Bearer
+// <key>" is accepted too.
+const HeaderAPIKey = "X-API-Key"
+
+const apiKeyContextKey = "api_key"
+
+// Authenticator resolves API keys and enforces their rate limits;
+// implemented by apikeys.Service.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*apikeys.Key, error)
+	Allow(key *apikeys.Key) bool
+}
+
+// RequireAPIKey rejects requests to routes roleOf does not report as public
+// unless they carry a key or JWT with the role the route requires: 401
+// without a known key, 403 when the key's role is too low and 429 once the
+// key is over its rate limit.
+func RequireAPIKey(auth Authenticator, roleOf func(c echo.Context) (role apikeys.Role, public bool)) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, public := roleOf(c)
+			if public {
+				return next(c)
+			}
+
+			key, err := auth.Authenticate(c.Request().Context(), requestToken(c.Request()))
+			if err != nil {
+				RecordRejection(c, RejectUnauthorized)
+				if !errors.Is(err, apikeys.ErrUnauthenticated) {
+					log.Err(err).Msg("Failed to authenticate API key")
+					return response.Error(c, http.StatusInternalServerError, "Failed to authenticate API key")
+				}
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="blacked"`)
+				return response.Error(c, http.StatusUnauthorized, "A valid API key is required in the "+HeaderAPIKey+" header")
+			}
+			c.Set(apiKeyContextKey, key)
+
+			if !key.Allows(role) {
+				RecordRejection(c, RejectForbidden)
+				return response.Error(c, http.StatusForbidden, "This endpoint requires the "+string(role)+" role; the API key has "+string(key.Role))
+			}
+			if !auth.Allow(key) {
+				RecordRejection(c, RejectRateLimited)
+				c.Response().Header().Set("Retry-After", "1")
+				return response.Error(c, http.StatusTooManyRequests, "API key rate limit exceeded")
+			}
+			return next(c)
+		}
+	}
+}
+
+// APIKey returns the key a request was authenticated with, or nil.
+func APIKey(c echo.Context) *apikeys.Key {
+	key, _ := c.Get(apiKeyContextKey).(*apikeys.Key)
+	return key
+}
+
+func requestToken(req *http.Request) string {
+	if token := req.Header.Get(HeaderAPIKey); token != "" {
+		return strings.TrimSpace(token)
+	}
+	token, _ := strings.CutPrefix(req.Header.Get(echo.HeaderAuthorization), "Bearer ")
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,83 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blacked/features/apikeys"
+	idb "blacked/internal/db"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	conn, err := idb.Connect(idb.WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, idb.MigrateSchema(conn))
+	svc := apikeys.NewServiceWithRepository(apikeys.NewSQLiteRepository(conn))
+
+	ctx := context.Background()
+	_, queryToken, err := svc.Create(ctx, "query", apikeys.RoleReader, 1)
+	require.NoError(t, err)
+	_, adminToken, err := svc.Create(ctx, "admin", apikeys.RoleAdmin, 0)
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Use(RequireAPIKey(svc, func(c echo.Context) (apikeys.Role, bool) {
+		switch c.Path() {
+		case "/health":
+			return "", true
+		case "/check":
+			return apikeys.RoleReader, false
+		}
+		return apikeys.RoleAdmin, false
+	}))
+	ok := func(c echo.Context) error {
+		name := ""
+		if key := APIKey(c); key != nil {
+			name = key.Name
+		}
+		return c.String(http.StatusOK, name)
+	}
+	e.GET("/health", ok)
+	e.GET("/check", ok)
+	e.GET("/providers", ok)
+
+	do := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, do("/health", nil).Code)
+
+	rec := do("/check", nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get(echo.HeaderWWWAuthenticate))
+	assert.Equal(t, http.StatusUnauthorized, do("/check", map[string]string{HeaderAPIKey: "blk_unknown"}).Code)
+
+	rec = do("/check", map[string]string{HeaderAPIKey: queryToken})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "query", rec.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, do("/providers", map[string]string{HeaderAPIKey: queryToken}).Code)
+
+	// One request per second with a burst of one; the forbidden request
+	// above did not spend it, the check did.
+	rec = do("/check", map[string]string{HeaderAPIKey: queryToken})
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	rec = do("/providers", map[string]string{echo.HeaderAuthorization: "Bearer " + adminToken})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "admin", rec.Body.String())
+}
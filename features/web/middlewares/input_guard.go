@@ -0,0 +1,158 @@
+package middlewares
+
+import (
+	"blacked/features/web/handlers/response"
+	"blacked/internal/collector"
+	"errors"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// Rejection reasons used as the "reason" label of blacked_http_rejected_requests_total.
+const (
+	RejectBodyTooLarge   = "body_too_large"
+	RejectContentType    = "content_type"
+	RejectTooManyItems   = "too_many_items"
+	RejectInvalidPayload = "invalid_payload"
+	RejectUnauthorized   = "unauthorized"
+	RejectForbidden      = "forbidden"
+	RejectRateLimited    = "rate_limited"
+	RejectReadOnly       = "read_only"
+
+	RejectUnsupportedScheme = "unsupported_scheme"
+)
+
+// BodyLimitConfig sets the request body limit of BodyLimitWithConfig:
+// Limit bytes, or the limit of the request's route in Routes, keyed by route
+// path. A limit <= 0 lifts it.
+type BodyLimitConfig struct {
+	Limit  int64
+	Routes map[string]int64
+}
+
+// BodyLimit rejects request bodies larger than maxBytes with a structured
+// 413: at once when Content-Length announces more, otherwise when a read
+// passes the limit. Bodies are streamed through http.MaxBytesReader, not
+// buffered.
+func BodyLimit(maxBytes int64) echo.MiddlewareFunc {
+	return BodyLimitWithConfig(BodyLimitConfig{Limit: maxBytes})
+}
+
+// BodyLimitWithConfig is BodyLimit with per-route limits, so bulk uploads
+// can take larger bodies than the rest of the API.
+func BodyLimitWithConfig(cfg BodyLimitConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			maxBytes := cfg.Limit
+			if limit, ok := cfg.Routes[c.Path()]; ok {
+				maxBytes = limit
+			}
+
+			req := c.Request()
+			if maxBytes <= 0 || req.Body == nil || req.Body == http.NoBody {
+				return next(c)
+			}
+			if req.ContentLength > maxBytes {
+				return rejectTooLarge(c, maxBytes)
+			}
+
+			req.Body = http.MaxBytesReader(c.Response(), req.Body, maxBytes)
+			err := next(c)
+			if err != nil && IsBodyTooLarge(err) && !c.Response().Committed {
+				return rejectTooLarge(c, maxBytes)
+			}
+			return err
+		}
+	}
+}
+
+// IsBodyTooLarge reports whether err comes from reading a request body past
+// the limit of BodyLimit.
+func IsBodyTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
+
+// RejectOversizedBody returns the structured 413 of BodyLimit for err, a
+// read past its limit, for handlers that answer read errors themselves.
+func RejectOversizedBody(c echo.Context, err error) error {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return response.BadRequest(c, "Failed to read request body")
+	}
+	return rejectTooLarge(c, tooLarge.Limit)
+}
+
+// RequireJSON rejects non-empty request bodies whose Content-Type is not
+// application/json, or one of also, with a structured 415.
+func RequireJSON(also ...string) echo.MiddlewareFunc {
+	expected := append([]string{echo.MIMEApplicationJSON}, also...)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.ContentLength == 0 {
+				return next(c)
+			}
+
+			mediaType, _, err := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+			if err != nil || !slices.Contains(expected, mediaType) {
+				RecordRejection(c, RejectContentType)
+				return response.ErrorWithDetails(c, http.StatusUnsupportedMediaType,
+					"Unsupported content type", map[string]any{"expected": strings.Join(expected, ", ")})
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// RejectTooMany returns a structured 422 when a request carries more than
+// limit items, recording the rejection.
+func RejectTooMany(c echo.Context, field string, got, limit int) error {
+	RecordRejection(c, RejectTooManyItems)
+	return response.Unprocessable(c, "Too many items in "+field, map[string]any{
+		"field": field,
+		"count": got,
+		"limit": limit,
+	})
+}
+
+// RejectInvalid returns a structured 422 for a payload that failed validation,
+// recording the rejection.
+func RejectInvalid(c echo.Context, err error) error {
+	RecordRejection(c, RejectInvalidPayload)
+	return response.Unprocessable(c, "Validation error", err.Error())
+}
+
+// RejectScheme returns a structured 400 for a link of a scheme the route
+// cannot look up, such as javascript: on a bloom-only check. err names the scheme.
+func RejectScheme(c echo.Context, err error) error {
+	RecordRejection(c, RejectUnsupportedScheme)
+	return response.ErrorWithDetails(c, http.StatusBadRequest, "Unsupported URL scheme", map[string]any{
+		"code":   RejectUnsupportedScheme,
+		"reason": err.Error(),
+	})
+}
+
+// RecordRejection increments the rejected requests metric and logs the rejection.
+func RecordRejection(c echo.Context, reason string) {
+	route := c.Path()
+	if mc, err := collector.GetMetricsCollector(); err == nil {
+		mc.IncrementRejectedRequests(reason, route)
+	}
+	log.Warn().
+		Str("reason", reason).
+		Str("route", route).
+		Str("remote_ip", c.RealIP()).
+		Msg("Request rejected by input guard")
+}
+
+func rejectTooLarge(c echo.Context, maxBytes int64) error {
+	RecordRejection(c, RejectBodyTooLarge)
+	return response.TooLarge(c, maxBytes)
+}
@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newGuardedEcho() *echo.Echo {
+	e := echo.New()
+	e.Use(BodyLimitWithConfig(BodyLimitConfig{Limit: 16, Routes: map[string]int64{"/bulk": 32}}))
+	e.POST("/echo", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, string(body))
+	}, RequireJSON())
+	e.POST("/bulk", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return RejectOversizedBody(c, err)
+		}
+		return c.String(http.StatusOK, string(body))
+	})
+	return e
+}
+
+func TestBodyLimit(t *testing.T) {
+	e := newGuardedEcho()
+
+	req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"a":1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"a":1}`, rec.Body.String())
+
+	// Chunked body without Content-Length is still capped.
+	req = httptest.NewRequest(http.MethodPost, "/echo", io.NopCloser(strings.NewReader(`{"a":"0123456789abcdef"}`)))
+	req.ContentLength = -1
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"limit_bytes":16`)
+
+	// Bulk routes take their own limit.
+	bulk := `{"a":"0123456789abcdef"}`
+	req = httptest.NewRequest(http.MethodPost, "/bulk", strings.NewReader(bulk))
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, bulk, rec.Body.String())
+
+	for _, contentLength := range []int64{-1, 40} {
+		req = httptest.NewRequest(http.MethodPost, "/bulk", io.NopCloser(strings.NewReader(bulk+bulk)))
+		req.ContentLength = contentLength
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"limit_bytes":32`)
+	}
+}
+
+func TestRequireJSON(t *testing.T) {
+	e := newGuardedEcho()
+
+	req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`a=1`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{}`))
+	req.Header.Set(echo.HeaderContentType, "application/json; charset=utf-8")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"blacked/features/web/handlers/response"
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ReadOnlyMode rejects write requests with 503 while readOnly reports the
+// instance in read-only maintenance mode. GET, HEAD and OPTIONS requests
+// pass, as do the routes allowed reports safe: queries sent as POST and the
+// toggle that clears the mode.
+func ReadOnlyMode(readOnly func(ctx context.Context) bool, allowed func(c echo.Context) bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+			if allowed(c) || !readOnly(c.Request().Context()) {
+				return next(c)
+			}
+			RecordRejection(c, RejectReadOnly)
+			return response.Error(c, http.StatusServiceUnavailable, "The instance is in read-only maintenance mode; writes are rejected until it is cleared")
+		}
+	}
+}
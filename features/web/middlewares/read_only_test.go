@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyMode(t *testing.T) {
+	readOnly := true
+	e := echo.New()
+	e.Use(ReadOnlyMode(
+		func(context.Context) bool { return readOnly },
+		func(c echo.Context) bool { return c.Path() == "/query" },
+	))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/entries", ok)
+	e.POST("/entries", ok)
+	e.POST("/query", ok)
+
+	do := func(method, path string) int {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/entries"), "reads are served")
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/query"), "allowed routes are served")
+	assert.Equal(t, http.StatusServiceUnavailable, do(http.MethodPost, "/entries"))
+
+	readOnly = false
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/entries"))
+}
@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+var ErrInvalidTrustedProxy = errors.New("invalid trusted proxy")
+
+// IPExtractor returns the extractor behind c.RealIP. Requests from a
+// trusted proxy — an address or CIDR of trustedProxies — are attributed to
+// the client named by X-Forwarded-For, walked from the right past every
+// trusted hop, or else by X-Real-IP. Requests from anyone else are
+// attributed to their peer address, so clients cannot pick their own IP by
+// sending the headers. Without trusted proxies the headers are ignored.
+func IPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	opts := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		ipNet, err := parseProxy(proxy)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, echo.TrustIPRange(ipNet))
+	}
+
+	fromXFF := echo.ExtractIPFromXFFHeader(opts...)
+	fromRealIP := echo.ExtractIPFromRealIPHeader(opts...)
+	return func(req *http.Request) string {
+		if req.Header.Get(echo.HeaderXForwardedFor) != "" {
+			return fromXFF(req)
+		}
+		return fromRealIP(req)
+	}, nil
+}
+
+// parseProxy reads a CIDR, or a single address as its own /32 or /128.
+func parseProxy(proxy string) (*net.IPNet, error) {
+	proxy = strings.TrimSpace(proxy)
+	if strings.Contains(proxy, "/") {
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not a CIDR", ErrInvalidTrustedProxy, proxy)
+		}
+		return ipNet, nil
+	}
+
+	ip := net.ParseIP(proxy)
+	if ip == nil {
+		return nil, fmt.Errorf("%w: %q is not an IP address or CIDR", ErrInvalidTrustedProxy, proxy)
+	}
+	bits := 128
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPExtractor(t *testing.T) {
+	request := func(remote string, headers map[string]string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/check", nil)
+		req.RemoteAddr = remote
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return req
+	}
+
+	untrusting, err := IPExtractor(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.5", untrusting(request("10.0.0.5:4711", map[string]string{echo.HeaderXForwardedFor: "203.0.113.7"})),
+		"without trusted proxies the headers are ignored")
+
+	extract, err := IPExtractor([]string{"10.0.0.0/24", "192.0.2.1", "2001:db8::/32"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"direct client", "198.51.100.9:4711", nil, "198.51.100.9"},
+		{"spoofed header from an untrusted peer", "198.51.100.9:4711", map[string]string{echo.HeaderXForwardedFor: "203.0.113.7"}, "198.51.100.9"},
+		{"load balancer", "10.0.0.5:4711", map[string]string{echo.HeaderXForwardedFor: "203.0.113.7"}, "203.0.113.7"},
+		{"trusted hops skipped from the right", "10.0.0.5:4711", map[string]string{echo.HeaderXForwardedFor: "1.1.1.1, 203.0.113.7, 192.0.2.1"}, "203.0.113.7"},
+		{"single trusted address", "192.0.2.1:4711", map[string]string{echo.HeaderXRealIP: "203.0.113.8"}, "203.0.113.8"},
+		{"ipv6 proxy", "[2001:db8::1]:4711", map[string]string{echo.HeaderXRealIP: "2001:db9::7"}, "2001:db9::7"},
+		{"proxy without headers", "10.0.0.5:4711", nil, "10.0.0.5"},
+		{"private address not trusted by default", "10.0.1.5:4711", map[string]string{echo.HeaderXRealIP: "203.0.113.8"}, "10.0.1.5"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, extract(request(tc.remote, tc.headers)))
+		})
+	}
+
+	_, err = IPExtractor([]string{"10.0.0.0/33"})
+	assert.ErrorIs(t, err, ErrInvalidTrustedProxy)
+	_, err = IPExtractor([]string{"lb.internal"})
+	assert.ErrorIs(t, err, ErrInvalidTrustedProxy)
+}
@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"blacked/internal/logger"
 	"strconv"
 	"time"
 
@@ -30,8 +31,8 @@ func RequestLogger() echo.MiddlewareFunc {
 				Str("path", req.URL.Path).
 				Str("remote_ip", c.RealIP())
 
-			// Log the incoming request (create logger from context)
-			logCtx.Str("query", req.URL.RawQuery).
+			// Query strings carry the URLs clients look up; redacted in privacy mode
+			logCtx = logCtx.Str("query", logger.RedactQuery(req.URL.RawQuery)).
 				Str("user_agent", req.UserAgent())
 
 			// Process the request
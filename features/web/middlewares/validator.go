@@ -2,6 +2,7 @@ package middlewares
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -36,7 +37,7 @@ func (v *Validator) Validate(i any) error {
 
 	msg := strings.Join(errorMsgs, ", ")
 	log.Error().Str("validation_errors", msg).Msg("Request validation failed")
-	return ErrValidationFailed
+	return fmt.Errorf("%w: %s", ErrValidationFailed, msg)
 }
 
 func ConfigureValidator(e *echo.Echo) {
@@ -2,9 +2,25 @@ package web
 
 import (
 	"blacked/features/entry_collector"
+	"blacked/features/providers"
+	"blacked/features/web/handlers/allowlist"
+	"blacked/features/web/handlers/apikeys"
+	"blacked/features/web/handlers/audit"
+	"blacked/features/web/handlers/cache"
+	"blacked/features/web/handlers/database"
+	"blacked/features/web/handlers/debug"
+	"blacked/features/web/handlers/edge"
+	"blacked/features/web/handlers/entries"
+	"blacked/features/web/handlers/export"
 	"blacked/features/web/handlers/health"
+	"blacked/features/web/handlers/maintenance"
 	"blacked/features/web/handlers/provider"
+	"blacked/features/web/handlers/replication"
+	"blacked/features/web/handlers/retrohunt"
+	"blacked/features/web/handlers/scheduler"
+	"blacked/features/web/handlers/stats"
 	v2 "blacked/features/web/handlers/v2"
+	"blacked/features/web/handlers/watchlist"
 	"blacked/internal/config"
 
 	"github.com/labstack/echo/v4"
@@ -21,19 +37,93 @@ func (app *Application) ConfigureRoutes() error {
 	}
 
 	health.MapHealth(e, *app.config)
+	health.MapHealthDetails(e, *app.config, app.services.HealthMonitor)
+	if collector := entry_collector.GetPondCollector(); collector != nil {
+		health.MapReady(e, *app.config, collector)
+	} else {
+		health.MapReady(e, *app.config, nil)
+	}
+
+	if err := export.MapExportRoutes(e, app.services.ExportService, config.GetConfig().RPZ); err != nil {
+		return err
+	}
+
+	if err := entries.MapEntriesRoutes(e, app.services.RelatedService, app.services.EntryQueryService, app.services.EntryDeleteService, app.services.EntryMergeService, app.services.EntryReclassifyService, app.services.EntryImportService, app.services.HitsService, app.services.Policy); err != nil {
+		return err
+	}
+
+	if err := allowlist.MapAllowlistRoutes(e, app.services.AllowlistService); err != nil {
+		return err
+	}
+
+	if err := apikeys.MapAPIKeyRoutes(e, app.services.APIKeyService); err != nil {
+		return err
+	}
+
+	if err := audit.MapAuditRoutes(e, app.services.AuditService); err != nil {
+		return err
+	}
+
+	if err := stats.MapStatsRoutes(e, app.services.StatsService); err != nil {
+		return err
+	}
+
+	if err := debug.MapDebugRoutes(e, app.services.ParseDebugService); err != nil {
+		return err
+	}
 
-	// V2 API routes — inject the singleton BloomManager from PondCollector
+	if err := retrohunt.MapRetrohuntRoutes(e, app.services.RetrohuntService); err != nil {
+		return err
+	}
+
+	if err := watchlist.MapWatchlistRoutes(e, app.services.WatchlistService); err != nil {
+		return err
+	}
+
+	if err := scheduler.MapSchedulerRoutes(e); err != nil {
+		return err
+	}
+
+	if err := database.MapDatabaseRoutes(e); err != nil {
+		return err
+	}
+
+	if err := maintenance.MapMaintenanceRoutes(e, app.services.MaintenanceService); err != nil {
+		return err
+	}
+
+	if err := replication.MapReplicationRoutes(e, app.services.ReplicationService, config.GetConfig().Replication.Token); err != nil {
+		return err
+	}
+
+	if path := config.GetConfig().Edge.DatasetPath; path != "" {
+		if err := edge.MapEdgeDatasetRoutes(e, path); err != nil {
+			return err
+		}
+	}
+
+	// Cache sync and V2 API routes — both drive the singleton PondCollector
 	collector := entry_collector.GetPondCollector()
 	if collector == nil {
-		log.Warn().Msg("PondCollector not ready — v2 routes skipped")
+		log.Warn().Msg("PondCollector not ready — cache and v2 routes skipped")
 	} else {
+		if err := cache.MapCacheRoutes(e, collector); err != nil {
+			return err
+		}
+		app.services.EntryDeleteService.SetCacheSyncer(collector)
+		app.services.EntryMergeService.SetCacheSyncer(collector)
+		app.services.EntryImportService.SetCollector(collector)
+		app.services.HealthMonitor.SetCacheCollector(collector)
+
 		bloomMgr := collector.GetBloomManager()
 		trustConfig := config.LoadScoringConfig()
 		v2Handler, err := v2.NewQueryHandler(bloomMgr, trustConfig)
 		if err != nil {
 			log.Warn().Err(err).Msg("V2 query handler init failed — skipping v2 routes")
 		} else {
-			if err := v2.MapV2Routes(e, v2Handler); err != nil {
+			v2Handler.SetMaxBulkURLs(app.config.MaxBulkURLs).
+				SetPolicy(app.services.Policy, providers.GetProviders().Categories())
+			if err := mapQueryAPI(e, v2Handler, app.config.ResponseTemplates); err != nil {
 				return err
 			}
 		}
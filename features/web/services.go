@@ -1,13 +1,46 @@
 package web
 
 import (
+	"blacked/features/allowlist"
+	"blacked/features/apikeys"
+	"blacked/features/audit"
 	"blacked/features/entries/services"
+	"blacked/features/export"
+	"blacked/features/health"
+	"blacked/features/hits"
+	"blacked/features/maintenance"
+	"blacked/features/parsedebug"
 	provider_processor "blacked/features/providers/services"
+	"blacked/features/replication"
+	"blacked/features/retrohunt"
+	"blacked/features/stats"
+	"blacked/features/watchlist"
+	"blacked/internal/config"
+	"blacked/internal/db"
+	"blacked/internal/query"
 )
 
 type Services struct {
 	EntryQueryService      *services.QueryService
+	EntryDeleteService     *services.DeleteService
+	EntryMergeService      *services.MergeService
+	EntryReclassifyService *services.ReclassifyService
+	EntryImportService     *services.ImportService
+	RelatedService         *services.RelatedService
 	ProviderProcessService *provider_processor.ProviderProcessService
+	ExportService          *export.Service
+	AllowlistService       *allowlist.Service
+	HitsService            *hits.Service
+	AuditService           *audit.Service
+	StatsService           *stats.Service
+	ParseDebugService      *parsedebug.Service
+	APIKeyService          *apikeys.Service
+	RetrohuntService       *retrohunt.Service
+	WatchlistService       *watchlist.Service
+	ReplicationService     *replication.Service
+	MaintenanceService     *maintenance.Service
+	HealthMonitor          *health.Monitor
+	Policy                 *query.Policy
 }
 
 func NewServices() (*Services, error) {
@@ -16,13 +49,118 @@ func NewServices() (*Services, error) {
 		return nil, err
 	}
 
+	deleteService, err := services.NewDeleteService()
+	if err != nil {
+		return nil, err
+	}
+
+	mergeService, err := services.NewMergeService()
+	if err != nil {
+		return nil, err
+	}
+
+	reclassifyService, err := services.NewReclassifyService()
+	if err != nil {
+		return nil, err
+	}
+
+	relatedService, err := services.NewRelatedService()
+	if err != nil {
+		return nil, err
+	}
+
 	providerProcessService, err := provider_processor.NewProviderProcessService()
 	if err != nil {
 		return nil, err
 	}
 
+	exportService, err := export.NewService()
+	if err != nil {
+		return nil, err
+	}
+
+	allowlistService, err := allowlist.NewService()
+	if err != nil {
+		return nil, err
+	}
+
+	hitsService, err := hits.NewService()
+	if err != nil {
+		return nil, err
+	}
+
+	auditService, err := audit.NewService()
+	if err != nil {
+		return nil, err
+	}
+
+	statsService, err := stats.NewService()
+	if err != nil {
+		return nil, err
+	}
+
+	// The served API authenticates through the global service, so deleted
+	// keys leave its cache at once.
+	apiKeyService := apikeys.Get()
+	if apiKeyService == nil {
+		if apiKeyService, err = apikeys.NewService(); err != nil {
+			return nil, err
+		}
+	}
+
+	retrohuntService, err := retrohunt.NewService()
+	if err != nil {
+		return nil, err
+	}
+
+	watchlistService, err := watchlist.NewService()
+	if err != nil {
+		return nil, err
+	}
+
+	replicationService, err := replication.NewService()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read-only mode is switched through the global service, which the
+	// scheduler and replica of this process check too.
+	maintenanceService := maintenance.Get()
+	if maintenanceService == nil {
+		writeDB, err := db.GetWriteDB()
+		if err != nil {
+			return nil, err
+		}
+		maintenanceService = maintenance.Init(writeDB)
+	}
+
+	policy, err := query.NewPolicy(config.GetConfig().Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	exportService.SetAllowlist(allowlistService)
+
 	return &Services{
 		EntryQueryService:      queryService,
+		EntryDeleteService:     deleteService,
+		EntryMergeService:      mergeService,
+		EntryReclassifyService: reclassifyService,
+		EntryImportService:     services.NewImportService(),
+		RelatedService:         relatedService,
 		ProviderProcessService: providerProcessService,
+		ExportService:          exportService,
+		AllowlistService:       allowlistService,
+		HitsService:            hitsService,
+		AuditService:           auditService,
+		StatsService:           statsService,
+		ParseDebugService:      parsedebug.NewServiceWithAllowlist(allowlistService),
+		APIKeyService:          apiKeyService,
+		RetrohuntService:       retrohuntService,
+		WatchlistService:       watchlistService,
+		ReplicationService:     replicationService,
+		MaintenanceService:     maintenanceService,
+		HealthMonitor:          health.NewMonitor(config.GetConfig().Health),
+		Policy:                 policy,
 	}, nil
 }
@@ -0,0 +1,136 @@
+package webhooks
+
+import (
+	"blacked/internal/config"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrDelivery = errors.New("failed to deliver webhook")
+	ErrRejected = errors.New("webhook endpoint rejected the event")
+)
+
+// Headers of a delivery. The signature is the hex HMAC-SHA256, keyed with
+// the endpoint secret, of the timestamp, a ".", and the body; receivers
+// recompute it and reject stale timestamps to stop replays.
+const (
+	HeaderEvent     = "X-Blacked-Event"
+	HeaderDelivery  = "X-Blacked-Delivery"
+	HeaderTimestamp = "X-Blacked-Timestamp"
+	HeaderSignature = "X-Blacked-Signature"
+)
+
+// Sender POSTs events, retrying failed attempts with exponential backoff.
+type Sender struct {
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// NewSender creates a Sender making up to maxAttempts attempts per
+// delivery, each with timeout, waiting backoff before the first retry and
+// twice as long before each next one.
+func NewSender(timeout time.Duration, maxAttempts int, backoff time.Duration) *Sender {
+	return &Sender{
+		client:      &http.Client{Timeout: timeout},
+		maxAttempts: max(maxAttempts, 1),
+		backoff:     backoff,
+	}
+}
+
+// Sign returns the signature header value of body sent at timestamp.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliver posts payload as event id to ep. Network errors, 429 and 5xx
+// responses are retried; other non-2xx responses fail at once with
+// ErrRejected.
+func (s *Sender) Deliver(ctx context.Context, ep config.WebhookEndpoint, event, id string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Err(err).Str("event", event).Msg("Failed to marshal webhook event")
+		return ErrDelivery
+	}
+
+	wait := s.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := s.attempt(ctx, ep, event, id, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= s.maxAttempts {
+			log.Error().
+				Str("event", event).
+				Str("delivery", id).
+				Str("url", ep.URL).
+				Int("attempts", attempt).
+				Msg("Webhook delivery failed")
+			return err
+		}
+
+		log.Warn().
+			Str("event", event).
+			Str("delivery", id).
+			Int("attempt", attempt).
+			Dur("retry_in", wait).
+			Msg("Webhook attempt failed, retrying")
+		select {
+		case <-ctx.Done():
+			return ErrDelivery
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// attempt makes one delivery attempt; retry reports whether a failure is
+// worth retrying.
+func (s *Sender) attempt(ctx context.Context, ep config.WebhookEndpoint, event, id string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		log.Err(err).Str("url", ep.URL).Msg("Failed to build webhook request")
+		return false, ErrDelivery
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, id)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if ep.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(ep.Secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		log.Debug().Err(err).Str("url", ep.URL).Msg("Webhook request failed")
+		return true, ErrDelivery
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, ErrDelivery
+	default:
+		log.Error().Int("status", resp.StatusCode).Str("url", ep.URL).Str("event", event).Msg("Webhook rejected")
+		return false, ErrRejected
+	}
+}
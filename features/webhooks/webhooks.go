@@ -0,0 +1,202 @@
+// Package webhooks notifies configured endpoints of provider runs: one
+// sync.completed event per run and, for endpoints subscribing to them,
+// batched entries.added events listing the entries the run added.
+package webhooks
+
+import (
+	"blacked/features/entries"
+	"blacked/internal/config"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Event types.
+const (
+	EventSyncCompleted = "sync.completed"
+	EventEntriesAdded  = "entries.added"
+)
+
+var ErrScanAdditions = errors.New("failed to scan provider additions for webhooks")
+
+// SyncEvent reports a finished provider run.
+type SyncEvent struct {
+	Type             string    `json:"type"`
+	ID               string    `json:"id"`
+	Provider         string    `json:"provider"`
+	ProcessID        string    `json:"process_id"`
+	Status           string    `json:"status"` // "completed" or "failed"
+	Error            string    `json:"error,omitempty"`
+	EntriesProcessed int       `json:"entries_processed"`
+	DurationMS       int64     `json:"duration_ms"`
+	StartedAt        time.Time `json:"started_at"`
+	FinishedAt       time.Time `json:"finished_at"`
+}
+
+// EntriesEvent lists a batch of the entries a run added or reactivated.
+// Batches of a run share the process ID and are numbered from 1; Last is
+// set on the final one, whose Count holds the run's total.
+type EntriesEvent struct {
+	Type      string       `json:"type"`
+	ID        string       `json:"id"`
+	Provider  string       `json:"provider"`
+	ProcessID string       `json:"process_id"`
+	Batch     int          `json:"batch"`
+	Last      bool         `json:"last"`
+	Count     int          `json:"count,omitempty"`     // Entries added by the run; final batch only
+	Truncated bool         `json:"truncated,omitempty"` // Entries beyond max_entries were counted only
+	Entries   []EventEntry `json:"entries"`
+}
+
+// EventEntry is an entry listed by an entries.added event.
+type EventEntry struct {
+	ID         string   `json:"id"`
+	SourceURL  string   `json:"source_url"`
+	Host       string   `json:"host"`
+	Category   string   `json:"category,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+}
+
+// EntryStreamer is the repository method NotifyEntriesAdded needs;
+// implemented by the entries SQLite repository.
+type EntryStreamer interface {
+	StreamEntriesActivatedSince(ctx context.Context, source string, since int64, fn func(*entries.Entry) error) error
+}
+
+// Notifier delivers events to the endpoints subscribing to them.
+type Notifier struct {
+	cfg    config.WebhooksConfig
+	sender *Sender
+}
+
+// NewNotifier creates a Notifier for the configured endpoints.
+func NewNotifier(cfg config.WebhooksConfig) *Notifier {
+	return &Notifier{cfg: cfg, sender: NewSender(cfg.Timeout, cfg.MaxAttempts, cfg.Backoff)}
+}
+
+// Enabled reports whether any webhook endpoint is configured.
+func Enabled(cfg config.WebhooksConfig) bool {
+	return len(cfg.Endpoints) > 0
+}
+
+// subscribers returns the endpoints receiving event for provider.
+func (n *Notifier) subscribers(event, provider string) []config.WebhookEndpoint {
+	var out []config.WebhookEndpoint
+	for _, ep := range n.cfg.Endpoints {
+		if ep.URL == "" {
+			continue
+		}
+		events := ep.Events
+		if len(events) == 0 {
+			events = []string{EventSyncCompleted}
+		}
+		if !slices.Contains(events, event) {
+			continue
+		}
+		if len(ep.Providers) > 0 && !slices.ContainsFunc(ep.Providers, func(p string) bool { return strings.EqualFold(p, provider) }) {
+			continue
+		}
+		out = append(out, ep)
+	}
+	return out
+}
+
+// NotifySyncCompleted posts ev to every endpoint subscribing to it. Every
+// endpoint is attempted; the first delivery error is returned.
+func (n *Notifier) NotifySyncCompleted(ctx context.Context, ev SyncEvent) error {
+	ev.Type = EventSyncCompleted
+	if ev.ID == "" {
+		ev.ID = uuid.NewString()
+	}
+
+	var firstErr error
+	for _, ep := range n.subscribers(EventSyncCompleted, ev.Provider) {
+		if err := n.sender.Deliver(ctx, ep, ev.Type, ev.ID, ev); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// NotifyEntriesAdded posts the entries provider added or reactivated since
+// the run started, in batches of batch_size, to every endpoint subscribing
+// to them. At most max_entries are listed; the final batch counts all.
+func (n *Notifier) NotifyEntriesAdded(ctx context.Context, repo EntryStreamer, provider, processID string, since time.Time) error {
+	endpoints := n.subscribers(EventEntriesAdded, provider)
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	// Batches are sent once the scan is done, so a slow endpoint never
+	// holds the read.
+	var listed []EventEntry
+	count := 0
+	err := repo.StreamEntriesActivatedSince(ctx, provider, since.UnixNano(), func(e *entries.Entry) error {
+		count++
+		if n.cfg.MaxEntries > 0 && len(listed) >= n.cfg.MaxEntries {
+			return nil
+		}
+		ee := EventEntry{ID: e.ID, SourceURL: e.SourceURL, Host: e.Host, Category: e.Category}
+		if len(e.Categories) > 1 {
+			ee.Categories = e.Categories
+		}
+		listed = append(listed, ee)
+		return nil
+	})
+	if err != nil {
+		log.Err(err).Str("provider", provider).Msg("Failed to scan provider additions for webhooks")
+		return ErrScanAdditions
+	}
+	if count == 0 {
+		return nil
+	}
+
+	size := n.cfg.BatchSize
+	if size <= 0 {
+		size = len(listed)
+	}
+	batches := batch(listed, size)
+	events := make([]EntriesEvent, len(batches))
+	for i, b := range batches {
+		events[i] = EntriesEvent{
+			Type:      EventEntriesAdded,
+			ID:        uuid.NewString(),
+			Provider:  provider,
+			ProcessID: processID,
+			Batch:     i + 1,
+			Entries:   b,
+		}
+	}
+	last := &events[len(events)-1]
+	last.Last, last.Count, last.Truncated = true, count, count > len(listed)
+
+	var firstErr error
+	for _, ep := range endpoints {
+		for _, ev := range events {
+			if err := n.sender.Deliver(ctx, ep, ev.Type, ev.ID, ev); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				// Later batches would arrive out of order; skip the endpoint.
+				break
+			}
+		}
+	}
+	return firstErr
+}
+
+// batch splits listed into slices of at most size entries.
+func batch(listed []EventEntry, size int) [][]EventEntry {
+	var out [][]EventEntry
+	for len(listed) > 0 {
+		n := min(size, len(listed))
+		out = append(out, listed[:n])
+		listed = listed[n:]
+	}
+	return out
+}
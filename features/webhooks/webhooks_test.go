@@ -0,0 +1,159 @@
+package webhooks
+
+import (
+	"blacked/features/entries"
+	"blacked/internal/config"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubStreamer []*entries.Entry
+
+func (s stubStreamer) StreamEntriesActivatedSince(ctx context.Context, source string, since int64, fn func(*entries.Entry) error) error {
+	for _, e := range s {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receiver records the deliveries it accepts, answering the first fail
+// requests with status.
+type receiver struct {
+	mu       sync.Mutex
+	fail     int
+	status   int
+	attempts int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.attempts++
+	if rc.attempts <= rc.fail {
+		w.WriteHeader(rc.status)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	rc.bodies = append(rc.bodies, body)
+	rc.headers = append(rc.headers, r.Header.Clone())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func testConfig(endpoints ...config.WebhookEndpoint) config.WebhooksConfig {
+	return config.WebhooksConfig{
+		Endpoints:   endpoints,
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		BatchSize:   2,
+		MaxEntries:  3,
+	}
+}
+
+func TestNotifySyncCompleted(t *testing.T) {
+	rc := &receiver{fail: 1, status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	skipped := &receiver{}
+	other := httptest.NewServer(skipped)
+	defer other.Close()
+
+	n := NewNotifier(testConfig(
+		config.WebhookEndpoint{URL: srv.URL, Secret: "s3cret"},
+		config.WebhookEndpoint{URL: other.URL, Providers: []string{"urlhaus"}},
+	))
+	ev := SyncEvent{Provider: "OISD", ProcessID: "p1", Status: "completed", EntriesProcessed: 42, DurationMS: 1500}
+	require.NoError(t, n.NotifySyncCompleted(context.Background(), ev))
+
+	assert.Equal(t, 2, rc.attempts, "retried after a 503")
+	require.Len(t, rc.bodies, 1)
+	h := rc.headers[0]
+	assert.Equal(t, EventSyncCompleted, h.Get(HeaderEvent))
+	assert.NotEmpty(t, h.Get(HeaderDelivery))
+	assert.Equal(t, Sign("s3cret", h.Get(HeaderTimestamp), rc.bodies[0]), h.Get(HeaderSignature))
+
+	var got SyncEvent
+	require.NoError(t, json.Unmarshal(rc.bodies[0], &got))
+	assert.Equal(t, EventSyncCompleted, got.Type)
+	assert.Equal(t, "OISD", got.Provider)
+	assert.Equal(t, 42, got.EntriesProcessed)
+	assert.Equal(t, int64(1500), got.DurationMS)
+
+	assert.Zero(t, skipped.attempts, "endpoint filtered to another provider")
+}
+
+func TestDeliverRejected(t *testing.T) {
+	rc := &receiver{fail: 10, status: http.StatusBadRequest}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	s := NewSender(time.Second, 5, time.Millisecond)
+	err := s.Deliver(context.Background(), config.WebhookEndpoint{URL: srv.URL}, EventSyncCompleted, "d1", SyncEvent{})
+	assert.ErrorIs(t, err, ErrRejected)
+	assert.Equal(t, 1, rc.attempts, "4xx responses are not retried")
+
+	rc = &receiver{fail: 10, status: http.StatusInternalServerError}
+	srv2 := httptest.NewServer(rc)
+	defer srv2.Close()
+	err = s.Deliver(context.Background(), config.WebhookEndpoint{URL: srv2.URL}, EventSyncCompleted, "d2", SyncEvent{})
+	assert.ErrorIs(t, err, ErrDelivery)
+	assert.Equal(t, 5, rc.attempts)
+}
+
+func TestNotifyEntriesAdded(t *testing.T) {
+	var added stubStreamer
+	for _, link := range []string{"https://a.com/1", "https://b.com/2", "https://c.com/3", "https://d.com/4"} {
+		e, err := entries.FromURL(link, "feed", "p1")
+		require.NoError(t, err)
+		added = append(added, e)
+	}
+
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	syncOnly := &receiver{}
+	other := httptest.NewServer(syncOnly)
+	defer other.Close()
+
+	n := NewNotifier(testConfig(
+		config.WebhookEndpoint{URL: srv.URL, Events: []string{EventSyncCompleted, EventEntriesAdded}},
+		config.WebhookEndpoint{URL: other.URL},
+	))
+	require.NoError(t, n.NotifyEntriesAdded(context.Background(), added, "feed", "p1", time.Now()))
+
+	require.Len(t, rc.bodies, 2)
+	var first, last EntriesEvent
+	require.NoError(t, json.Unmarshal(rc.bodies[0], &first))
+	require.NoError(t, json.Unmarshal(rc.bodies[1], &last))
+
+	assert.Equal(t, 1, first.Batch)
+	assert.False(t, first.Last)
+	assert.Len(t, first.Entries, 2)
+	assert.Equal(t, "a.com", first.Entries[0].Host)
+
+	assert.Equal(t, 2, last.Batch)
+	assert.True(t, last.Last)
+	assert.Equal(t, 4, last.Count)
+	assert.True(t, last.Truncated, "max_entries lists three of four")
+	assert.Len(t, last.Entries, 1)
+	assert.Equal(t, "p1", last.ProcessID)
+
+	assert.Zero(t, syncOnly.attempts, "endpoint not subscribed to entries.added")
+
+	// Nothing added: no delivery.
+	require.NoError(t, n.NotifyEntriesAdded(context.Background(), stubStreamer{}, "feed", "p2", time.Now()))
+	assert.Len(t, rc.bodies, 2)
+}
@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// latencySlots is how many recent query durations a LatencyWindow keeps.
+const latencySlots = 1024
+
+// LatencyWindow keeps the most recent durations in a ring, without locks, so
+// it can be fed from every query. Concurrent writers may tear a slot's pair
+// of timestamp and duration; a quantile estimate tolerates that.
+type LatencyWindow struct {
+	next  atomic.Uint64
+	at    [latencySlots]atomic.Int64 // Unix nanos of the sample
+	durNs [latencySlots]atomic.Int64
+}
+
+// Observe records one duration.
+func (w *LatencyWindow) Observe(d time.Duration) {
+	i := (w.next.Add(1) - 1) % latencySlots
+	w.durNs[i].Store(int64(d))
+	w.at[i].Store(time.Now().UnixNano())
+}
+
+// Quantile returns the q quantile (0–1) of the durations observed within the
+// last window, or false when there were none.
+func (w *LatencyWindow) Quantile(q float64, window time.Duration) (time.Duration, bool) {
+	since := time.Now().Add(-window).UnixNano()
+	samples := make([]int64, 0, latencySlots)
+	for i := range latencySlots {
+		if w.at[i].Load() >= since {
+			samples = append(samples, w.durNs[i].Load())
+		}
+	}
+	if len(samples) == 0 {
+		return 0, false
+	}
+	slices.Sort(samples)
+	idx := int(q * float64(len(samples)-1))
+	return time.Duration(samples[max(0, min(idx, len(samples)-1))]), true
+}
+
+var queryLatency LatencyWindow
+
+// ObserveQueryLatency records the duration of one URL lookup, for the cache
+// sync throttle to back off while queries slow down.
+func ObserveQueryLatency(d time.Duration) {
+	queryLatency.Observe(d)
+}
+
+// QueryLatencyQuantile returns the q quantile of the URL lookups of the last
+// window, or false when there were none.
+func QueryLatencyQuantile(q float64, window time.Duration) (time.Duration, bool) {
+	return queryLatency.Quantile(q, window)
+}
@@ -36,6 +36,45 @@ type MetricsCollector struct {
 	EntriesParsedTotal  *prometheus.CounterVec // Counter for total blacklist entries parsed from
 	EntriesSavedTotal   *prometheus.CounterVec // Counter for total blacklist entries saved from import
 	ImportErrorsTotal   *prometheus.CounterVec // Counter for total import requests that resulted in errors
+
+	RejectedRequestsTotal *prometheus.CounterVec // Counter for HTTP requests rejected by input guards
+	ParserPanicsTotal     *prometheus.CounterVec // Counter for provider runs whose parser panicked
+	EntriesTooOldTotal    *prometheus.CounterVec // Counter for feed entries dropped by ignore_older_than
+	EntriesReservedTotal  *prometheus.CounterVec // Counter for feed entries dropped for reserved or private hosts
+	UnusualSchemesTotal   *prometheus.CounterVec // Counter for feed entries listed with a scheme other than http(s)
+
+	CacheSyncRunning    prometheus.Gauge // 1 while a cache sync runs
+	CacheSyncExpected   prometheus.Gauge // Keys the running cache sync expects to process
+	CacheSyncProcessed  prometheus.Gauge // Keys the running cache sync has processed
+	CacheSyncPercent    prometheus.Gauge // Percent complete of the running cache sync
+	CacheSyncKeysPerSec prometheus.Gauge // Average keys/sec of the running cache sync
+	CacheSyncETASeconds prometheus.Gauge // Estimated seconds until the running cache sync completes
+	CacheSyncRateLimit  prometheus.Gauge // Keys/sec the throttle holds the running cache sync to
+
+	CacheScrubCheckedTotal prometheus.Counter     // Cache keys compared against the repository by the scrubber
+	CacheScrubDriftTotal   *prometheus.CounterVec // Drifted cache keys found by the scrubber, by kind
+	CacheScrubDriftRatio   prometheus.Gauge       // Share of drifted keys in the last scrub round
+
+	NegativeCacheLookupsTotal *prometheus.CounterVec // Lookups checked against the negative cache, by result
+
+	StatementCacheLookupsTotal *prometheus.CounterVec // Match queries run from the prepared statement cache, by result
+
+	ReplicationLagChanges   prometheus.Gauge   // Changes of the primary's feed the replica has yet to apply
+	ReplicationLagSeconds   prometheus.Gauge   // Age of the newest primary change relative to the last applied one
+	ReplicationAppliedTotal prometheus.Counter // Changes applied by the replica
+	ReplicationErrorsTotal  prometheus.Counter // Failed replica polls
+
+	EventBusPublishedTotal *prometheus.CounterVec // Events published to the event bus, by type
+	EventBusErrorsTotal    prometheus.Counter     // Failed event bus publishes
+	EventBusLagChanges     prometheus.Gauge       // Entry changes not published yet
+
+	HealthScore          prometheus.Gauge     // Composite health score, 0 (failing) to 1 (healthy)
+	HealthComponentScore *prometheus.GaugeVec // Score of each health component
+	HealthComponentValue *prometheus.GaugeVec // Measured value of each health component, compared by the alert rules
+
+	RetentionPurgedTotal         prometheus.Counter // Soft-deleted entries removed for good by the retention job
+	RetentionReclaimedBytesTotal prometheus.Counter // Bytes the retention job returned to the file system
+	RetentionLastRunTimestamp    prometheus.Gauge   // Unix time of the last successful retention run
 }
 
 func GetMetricsCollector() (*MetricsCollector, error) {
@@ -105,6 +144,156 @@ func NewMetricsCollector(providerNames []string) *MetricsCollector {
 				Name: "blacklist_json_import_errors_total",
 				Help: "Total number of import requests that resulted in errors.",
 			}, []string{"provider"}),
+
+			RejectedRequestsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "blacked_http_rejected_requests_total",
+				Help: "Total number of HTTP requests rejected by body size, content type or input limits.",
+			}, []string{"reason", "route"}),
+
+			ParserPanicsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "blacklist_provider_parser_panics_total",
+				Help: "Total number of provider runs failed by a recovered parser panic.",
+			}, []string{"provider"}),
+
+			EntriesTooOldTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "blacklist_provider_entries_too_old_total",
+				Help: "Total number of feed entries skipped for being older than the provider's ignore_older_than.",
+			}, []string{"provider"}),
+
+			EntriesReservedTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "blacklist_provider_entries_reserved_total",
+				Help: "Total number of feed entries skipped for reserved names (localhost, RFC 2606) or private addresses.",
+			}, []string{"provider"}),
+
+			UnusualSchemesTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "blacklist_provider_entries_unusual_scheme_total",
+				Help: "Total number of feed entries listed with a scheme other than http or https, such as javascript: or data:.",
+			}, []string{"provider", "scheme"}),
+
+			CacheSyncRunning: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "blacked_cache_sync_running",
+				Help: "1 while a cache sync is running, 0 otherwise.",
+			}),
+
+			CacheSyncExpected: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "blacked_cache_sync_keys_expected",
+				Help: "Number of keys the current or last cache sync expected to process.",
+			}),
+
+			CacheSyncProcessed: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "blacked_cache_sync_keys_processed",
+				Help: "Number of keys the current or last cache sync has processed.",
+			}),
+
+			CacheSyncPercent: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "blacked_cache_sync_progress_percent",
+				Help: "Percent complete of the current or last cache sync.",
+			}),
+
+			CacheSyncKeysPerSec: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "blacked_cache_sync_keys_per_second",
+				Help: "Average keys processed per second by the current or last cache sync.",
+			}),
+
+			CacheSyncETASeconds: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "blacked_cache_sync_eta_seconds",
+				Help: "Estimated seconds until the current cache sync completes.",
+			}),
+
+			CacheSyncRateLimit: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "blacked_cache_sync_rate_limit",
+				Help: "Keys per second the throttle holds the current cache sync to, 0 when unthrottled.",
+			}),
+
+			CacheScrubCheckedTotal: promauto.NewCounter(prometheus.CounterOpts{
+				Name: "blacked_cache_scrub_checked_total",
+				Help: "Total number of cache keys compared against the repository by the scrubber.",
+			}),
+
+			CacheScrubDriftTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "blacked_cache_scrub_drift_total",
+				Help: "Total number of drifted cache keys found and repaired by the scrubber.",
+			}, []string{"kind"}),
+
+			CacheScrubDriftRatio: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "blacked_cache_scrub_drift_ratio",
+				Help: "Share of checked keys that had drifted in the last scrub round.",
+			}),
+
+			ReplicationLagChanges: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "blacked_replication_lag_changes",
+				Help: "Number of changes in the primary's feed the replica has not applied yet.",
+			}),
+
+			ReplicationLagSeconds: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "blacked_replication_lag_seconds",
+				Help: "Seconds between the primary's newest change and the last change the replica applied; 0 when caught up.",
+			}),
+
+			NegativeCacheLookupsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "blacked_negative_cache_lookups_total",
+				Help: "Total number of lookups checked against the negative cache, by result (hit skips the database).",
+			}, []string{"result"}),
+
+			StatementCacheLookupsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "blacked_statement_cache_lookups_total",
+				Help: "Total number of match queries looked up in the prepared statement cache, by result (hit, miss prepares it, full runs unprepared).",
+			}, []string{"result"}),
+
+			ReplicationAppliedTotal: promauto.NewCounter(prometheus.CounterOpts{
+				Name: "blacked_replication_applied_total",
+				Help: "Total number of primary changes applied by the replica.",
+			}),
+
+			ReplicationErrorsTotal: promauto.NewCounter(prometheus.CounterOpts{
+				Name: "blacked_replication_errors_total",
+				Help: "Total number of replica polls of the primary that failed.",
+			}),
+
+			EventBusPublishedTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "blacked_eventbus_published_total",
+				Help: "Total number of events published to the event bus, by type.",
+			}, []string{"type"}),
+
+			EventBusErrorsTotal: promauto.NewCounter(prometheus.CounterOpts{
+				Name: "blacked_eventbus_errors_total",
+				Help: "Total number of event bus publishes that failed.",
+			}),
+
+			EventBusLagChanges: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "blacked_eventbus_lag_changes",
+				Help: "Number of entry changes not yet published to the event bus.",
+			}),
+
+			HealthScore: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "blacked_health_score",
+				Help: "Composite health score from 0 (failing) to 1 (healthy): feed freshness, cache sync age, provider error rate and queue saturation.",
+			}),
+
+			HealthComponentScore: promauto.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "blacked_health_component_score",
+				Help: "Health score of one component, from 0 (failing) to 1 (healthy).",
+			}, []string{"component"}),
+
+			HealthComponentValue: promauto.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "blacked_health_component_value",
+				Help: "Value measured by one health component; higher is worse.",
+			}, []string{"component"}),
+
+			RetentionPurgedTotal: promauto.NewCounter(prometheus.CounterOpts{
+				Name: "blacked_retention_purged_entries_total",
+				Help: "Total soft-deleted entries removed for good by the retention job.",
+			}),
+
+			RetentionReclaimedBytesTotal: promauto.NewCounter(prometheus.CounterOpts{
+				Name: "blacked_retention_reclaimed_bytes_total",
+				Help: "Total bytes of database and WAL the retention job returned to the file system.",
+			}),
+
+			RetentionLastRunTimestamp: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "blacked_retention_last_run_timestamp_seconds",
+				Help: "Unix time of the last successful retention run.",
+			}),
 		}
 		// Populate _mc’s providerMetrics
 		for _, name := range providerNames {
@@ -184,6 +373,56 @@ func (mc *MetricsCollector) IncrementImportErrors(providerName string) {
 	mc.ImportErrorsTotal.With(prometheus.Labels{"provider": providerName}).Inc()
 }
 
+// IncrementRejectedRequests counts a request rejected for reason on route (the route pattern, not the raw path).
+func (mc *MetricsCollector) IncrementRejectedRequests(reason, route string) {
+	mc.RejectedRequestsTotal.With(prometheus.Labels{"reason": reason, "route": route}).Inc()
+}
+
+// IncrementParserPanics counts a provider run that failed because its parser panicked.
+func (mc *MetricsCollector) IncrementParserPanics(providerName string) {
+	mc.ParserPanicsTotal.With(prometheus.Labels{"provider": providerName}).Inc()
+}
+
+// IncrementEntriesTooOld counts feed entries skipped for being older than ignore_older_than.
+func (mc *MetricsCollector) IncrementEntriesTooOld(providerName string, count int) {
+	mc.EntriesTooOldTotal.With(prometheus.Labels{"provider": providerName}).Add(float64(count))
+}
+
+// IncrementEntriesReserved counts feed entries skipped for reserved or private hosts.
+func (mc *MetricsCollector) IncrementEntriesReserved(providerName string, count int) {
+	mc.EntriesReservedTotal.With(prometheus.Labels{"provider": providerName}).Add(float64(count))
+}
+
+// IncrementUnusualSchemes counts a feed entry listed with a scheme other than http or https.
+func (mc *MetricsCollector) IncrementUnusualSchemes(providerName, scheme string) {
+	mc.UnusualSchemesTotal.With(prometheus.Labels{"provider": providerName, "scheme": scheme}).Inc()
+}
+
+// SetCacheSyncRunning flags whether a cache sync is in progress.
+func (mc *MetricsCollector) SetCacheSyncRunning(running bool) {
+	if running {
+		mc.CacheSyncRunning.Set(1)
+		return
+	}
+	mc.CacheSyncRunning.Set(0)
+	mc.CacheSyncETASeconds.Set(0)
+	mc.CacheSyncRateLimit.Set(0)
+}
+
+// SetCacheSyncProgress publishes the progress of the running cache sync.
+func (mc *MetricsCollector) SetCacheSyncProgress(expected, processed int, percent, keysPerSec, etaSeconds float64) {
+	mc.CacheSyncExpected.Set(float64(expected))
+	mc.CacheSyncProcessed.Set(float64(processed))
+	mc.CacheSyncPercent.Set(percent)
+	mc.CacheSyncKeysPerSec.Set(keysPerSec)
+	mc.CacheSyncETASeconds.Set(etaSeconds)
+}
+
+// SetCacheSyncRateLimit publishes the keys/sec ceiling of the running cache sync.
+func (mc *MetricsCollector) SetCacheSyncRateLimit(limit float64) {
+	mc.CacheSyncRateLimit.Set(limit)
+}
+
 // GetAllProviderMetrics - For status tracking (optional, Prometheus has aggregated data directly). Can return less info now.
 func (mc *MetricsCollector) GetAllProviderMetrics() map[string]*ProviderMetrics {
 	// Returning less detailed metrics here - Prometheus is intended for detailed metrics access now.
@@ -201,3 +440,73 @@ func (mc *MetricsCollector) GetAllProviderMetrics() map[string]*ProviderMetrics
 func (pm *ProviderMetrics) String() string {
 	return "Provider: " + pm.ProviderName + ", Status: " + pm.SyncStatus + " (Detailed metrics in Prometheus)"
 }
+
+// ObserveCacheScrub records one scrub round: keys checked and drift by kind.
+func (mc *MetricsCollector) ObserveCacheScrub(checked, stale, mismatched, missing int) {
+	mc.CacheScrubCheckedTotal.Add(float64(checked))
+	mc.CacheScrubDriftTotal.With(prometheus.Labels{"kind": "stale"}).Add(float64(stale))
+	mc.CacheScrubDriftTotal.With(prometheus.Labels{"kind": "mismatched"}).Add(float64(mismatched))
+	mc.CacheScrubDriftTotal.With(prometheus.Labels{"kind": "missing"}).Add(float64(missing))
+	if checked > 0 {
+		mc.CacheScrubDriftRatio.Set(float64(stale+mismatched+missing) / float64(checked))
+	}
+}
+
+// ObserveReplication records a replica poll: changes applied and the lag left.
+func (mc *MetricsCollector) ObserveReplication(applied int, lagChanges int64, lagSeconds float64) {
+	mc.ReplicationAppliedTotal.Add(float64(applied))
+	mc.ReplicationLagChanges.Set(float64(lagChanges))
+	mc.ReplicationLagSeconds.Set(lagSeconds)
+}
+
+// IncrementNegativeCacheLookup counts a negative cache lookup that hit or missed.
+func (mc *MetricsCollector) IncrementNegativeCacheLookup(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	mc.NegativeCacheLookupsTotal.With(prometheus.Labels{"result": result}).Inc()
+}
+
+// IncrementStatementCacheLookup counts a prepared statement cache lookup
+// with result hit, miss or full.
+func (mc *MetricsCollector) IncrementStatementCacheLookup(result string) {
+	mc.StatementCacheLookupsTotal.With(prometheus.Labels{"result": result}).Inc()
+}
+
+// IncrementReplicationErrors counts a failed replica poll.
+func (mc *MetricsCollector) IncrementReplicationErrors() {
+	mc.ReplicationErrorsTotal.Inc()
+}
+
+// IncrementEventBusPublished counts count events of eventType published.
+func (mc *MetricsCollector) IncrementEventBusPublished(eventType string, count int) {
+	mc.EventBusPublishedTotal.With(prometheus.Labels{"type": eventType}).Add(float64(count))
+}
+
+// SetEventBusLag records the entry changes not published yet.
+func (mc *MetricsCollector) SetEventBusLag(changes int64) {
+	mc.EventBusLagChanges.Set(float64(changes))
+}
+
+// IncrementEventBusErrors counts a failed event bus publish.
+func (mc *MetricsCollector) IncrementEventBusErrors() {
+	mc.EventBusErrorsTotal.Inc()
+}
+
+// SetHealthScore records the composite health score.
+func (mc *MetricsCollector) SetHealthScore(score float64) {
+	mc.HealthScore.Set(score)
+}
+
+// SetHealthComponent records the score and measured value of a health component.
+func (mc *MetricsCollector) SetHealthComponent(component string, score, value float64) {
+	mc.HealthComponentScore.With(prometheus.Labels{"component": component}).Set(score)
+	mc.HealthComponentValue.With(prometheus.Labels{"component": component}).Set(value)
+}
+
+// ClearHealthComponent drops the series of a component that cannot be measured.
+func (mc *MetricsCollector) ClearHealthComponent(component string) {
+	mc.HealthComponentScore.DeleteLabelValues(component)
+	mc.HealthComponentValue.DeleteLabelValues(component)
+}
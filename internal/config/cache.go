@@ -0,0 +1,49 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidCacheConfig = errors.New("invalid cache config")
+
+// Kinds of [Cache] probabilistic_filter.
+const (
+	FilterBloom  = "bloom"  // Counting bloom filter
+	FilterCuckoo = "cuckoo" // Cuckoo filter: smaller per key and exact deletions
+	FilterNone   = "none"   // No filter: every lookup goes to the cache
+)
+
+// Filter returns the kind of filter checked before the cache; none when
+// use_bloom is off.
+func (c CacheSettings) Filter() string {
+	if !c.UseBloom {
+		return FilterNone
+	}
+	kind := strings.ToLower(strings.TrimSpace(c.ProbabilisticFilter))
+	if kind == "" {
+		return FilterBloom
+	}
+	return kind
+}
+
+// ValidateCache checks the [Cache] settings read at startup.
+func (c *Config) ValidateCache() error {
+	switch c.Cache.Filter() {
+	case FilterBloom, FilterCuckoo, FilterNone:
+	default:
+		return fmt.Errorf("%w: Cache.probabilistic_filter must be bloom, cuckoo or none, got %q", ErrInvalidCacheConfig, c.Cache.ProbabilisticFilter)
+	}
+
+	if c.Cache.BadgerPath == "" {
+		return nil
+	}
+	if r := c.Cache.BadgerGCDiscardRatio; r <= 0 || r >= 1 {
+		return fmt.Errorf("%w: Cache.badger_gc_discard_ratio must be between 0 and 1, got %v", ErrInvalidCacheConfig, r)
+	}
+	if d := c.Cache.BadgerWarmMaxDrift; d < 0 || d > 1 {
+		return fmt.Errorf("%w: Cache.badger_warm_max_drift must be between 0 and 1, got %v", ErrInvalidCacheConfig, d)
+	}
+	return nil
+}
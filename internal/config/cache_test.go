@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheFilter(t *testing.T) {
+	tests := map[string]struct {
+		cache CacheSettings
+		want  string
+	}{
+		"default":      {CacheSettings{UseBloom: true}, FilterBloom},
+		"cuckoo":       {CacheSettings{UseBloom: true, ProbabilisticFilter: " Cuckoo "}, FilterCuckoo},
+		"none":         {CacheSettings{UseBloom: true, ProbabilisticFilter: "none"}, FilterNone},
+		"bloom is off": {CacheSettings{UseBloom: false, ProbabilisticFilter: "cuckoo"}, FilterNone},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.cache.Filter())
+			assert.NoError(t, (&Config{Cache: tc.cache}).ValidateCache())
+		})
+	}
+
+	cfg := &Config{Cache: CacheSettings{UseBloom: true, ProbabilisticFilter: "quotient"}}
+	assert.ErrorIs(t, cfg.ValidateCache(), ErrInvalidCacheConfig)
+}
+
+func TestValidateCacheBadger(t *testing.T) {
+	valid := CacheSettings{BadgerPath: "./cache", BadgerGCDiscardRatio: 0.5, BadgerWarmMaxDrift: 0.01}
+	assert.NoError(t, (&Config{Cache: valid}).ValidateCache())
+
+	for name, mutate := range map[string]func(*CacheSettings){
+		"zero discard ratio":  func(c *CacheSettings) { c.BadgerGCDiscardRatio = 0 },
+		"whole discard ratio": func(c *CacheSettings) { c.BadgerGCDiscardRatio = 1 },
+		"negative drift":      func(c *CacheSettings) { c.BadgerWarmMaxDrift = -0.1 },
+		"drift above one":     func(c *CacheSettings) { c.BadgerWarmMaxDrift = 2 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache := valid
+			mutate(&cache)
+			assert.ErrorIs(t, (&Config{Cache: cache}).ValidateCache(), ErrInvalidCacheConfig)
+		})
+	}
+
+	// Settings of the on-disk cache are ignored while it is in memory.
+	assert.NoError(t, (&Config{Cache: CacheSettings{}}).ValidateCache())
+}
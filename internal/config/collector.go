@@ -0,0 +1,109 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidCollectorConfig = errors.New("invalid collector config")
+
+// Collector setting bounds enforced at startup.
+const (
+	MinFlushInterval = 100 * time.Millisecond
+	MaxFlushInterval = 10 * time.Minute
+	MinSaveTimeout   = time.Second
+	MaxSaveTimeout   = 30 * time.Minute
+	MaxBatchSize     = 100_000
+	MinEntryTTL      = time.Minute
+)
+
+// CollectorSettings are the collector batching settings resolved for one source.
+type CollectorSettings struct {
+	BatchSize     int
+	FlushInterval time.Duration
+	SaveTimeout   time.Duration
+	DeltaIngest   bool          // Write only entries changed since the last snapshot
+	Conditional   bool          // Send the validators of the last imported response
+	TTL           time.Duration // Entries expire this long after they were last listed; 0 never
+}
+
+// CollectorSettingsFor returns the [Collector] settings with the source's
+// provider overrides applied.
+func (c *Config) CollectorSettingsFor(source string) CollectorSettings {
+	s := CollectorSettings{
+		BatchSize:     c.Collector.BatchSize,
+		FlushInterval: c.Collector.FlushInterval,
+		SaveTimeout:   c.Collector.SaveTimeout,
+		DeltaIngest:   c.Collector.DeltaIngest,
+		Conditional:   c.Collector.ConditionalFetch,
+		TTL:           c.Collector.EntryTTL,
+	}
+
+	opts, ok := c.Providers[source]
+	if !ok || opts == nil {
+		return s
+	}
+	if opts.CollectorBatchSize > 0 {
+		s.BatchSize = opts.CollectorBatchSize
+	}
+	if opts.FlushInterval != nil {
+		s.FlushInterval = *opts.FlushInterval
+	}
+	if opts.SaveTimeout != nil {
+		s.SaveTimeout = *opts.SaveTimeout
+	}
+	if opts.DeltaIngest != nil {
+		s.DeltaIngest = *opts.DeltaIngest
+	}
+	if opts.ConditionalFetch != nil {
+		s.Conditional = *opts.ConditionalFetch
+	}
+	if opts.TTL != nil {
+		s.TTL = *opts.TTL
+	}
+	return s
+}
+
+// ValidateCollector checks the global collector settings and every
+// per-provider override against the startup bounds.
+func (c *Config) ValidateCollector() error {
+	if c.Collector.Concurrency <= 0 {
+		return fmt.Errorf("%w: Collector.concurrency must be positive", ErrInvalidCollectorConfig)
+	}
+	if err := validateCollectorSettings("Collector", c.CollectorSettingsFor("")); err != nil {
+		return err
+	}
+	if c.Collector.ExpiryInterval < 0 {
+		return fmt.Errorf("%w: Collector.expiry_interval must not be negative", ErrInvalidCollectorConfig)
+	}
+
+	for name, opts := range c.Providers {
+		if opts == nil {
+			continue
+		}
+		if opts.CollectorBatchSize < 0 {
+			return fmt.Errorf("%w: providers.%s.collector_batch_size must not be negative", ErrInvalidCollectorConfig, name)
+		}
+		if err := validateCollectorSettings("providers."+name, c.CollectorSettingsFor(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateCollectorSettings(section string, s CollectorSettings) error {
+	if s.BatchSize <= 0 || s.BatchSize > MaxBatchSize {
+		return fmt.Errorf("%w: %s batch size %d must be between 1 and %d", ErrInvalidCollectorConfig, section, s.BatchSize, MaxBatchSize)
+	}
+	if s.FlushInterval < MinFlushInterval || s.FlushInterval > MaxFlushInterval {
+		return fmt.Errorf("%w: %s.flush_interval %s must be between %s and %s", ErrInvalidCollectorConfig, section, s.FlushInterval, MinFlushInterval, MaxFlushInterval)
+	}
+	if s.SaveTimeout < MinSaveTimeout || s.SaveTimeout > MaxSaveTimeout {
+		return fmt.Errorf("%w: %s.save_timeout %s must be between %s and %s", ErrInvalidCollectorConfig, section, s.SaveTimeout, MinSaveTimeout, MaxSaveTimeout)
+	}
+	if s.TTL != 0 && s.TTL < MinEntryTTL {
+		return fmt.Errorf("%w: %s TTL %s must be 0 or at least %s", ErrInvalidCollectorConfig, section, s.TTL, MinEntryTTL)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectorSettingsFor(t *testing.T) {
+	flush := 10 * time.Second
+	delta := true
+	week := 7 * 24 * time.Hour
+	cfg := &Config{
+		Collector: CollectorConfig{Concurrency: 4, BatchSize: 100, FlushInterval: 5 * time.Second, SaveTimeout: 30 * time.Second},
+		Providers: map[string]*ProviderOptions{
+			"oisd-big":       {CollectorBatchSize: 5000, FlushInterval: &flush, DeltaIngest: &delta},
+			"openphish-feed": {TTL: &week},
+		},
+	}
+
+	assert.Equal(t, CollectorSettings{BatchSize: 5000, FlushInterval: flush, SaveTimeout: 30 * time.Second, DeltaIngest: true}, cfg.CollectorSettingsFor("oisd-big"))
+	assert.Equal(t, CollectorSettings{BatchSize: 100, FlushInterval: 5 * time.Second, SaveTimeout: 30 * time.Second, TTL: week}, cfg.CollectorSettingsFor("openphish-feed"))
+	assert.Equal(t, cfg.CollectorSettingsFor(""), cfg.CollectorSettingsFor("unknown"))
+	require.NoError(t, cfg.ValidateCollector())
+}
+
+func TestValidateCollectorRejectsOutOfRange(t *testing.T) {
+	tooShort := 10 * time.Millisecond
+	negative := -time.Hour
+	base := CollectorConfig{Concurrency: 4, BatchSize: 100, FlushInterval: 5 * time.Second, SaveTimeout: 30 * time.Second}
+
+	tests := map[string]*Config{
+		"zero batch":     {Collector: CollectorConfig{Concurrency: 4, FlushInterval: time.Second, SaveTimeout: time.Minute}},
+		"zero timeout":   {Collector: CollectorConfig{Concurrency: 4, BatchSize: 10, FlushInterval: time.Second}},
+		"short override": {Collector: base, Providers: map[string]*ProviderOptions{"oisd-big": {FlushInterval: &tooShort}}},
+		"negative batch": {Collector: base, Providers: map[string]*ProviderOptions{"oisd-big": {CollectorBatchSize: -1}}},
+		"short ttl":      {Collector: base, Providers: map[string]*ProviderOptions{"openphish-feed": {TTL: &tooShort}}},
+		"negative ttl":   {Collector: base, Providers: map[string]*ProviderOptions{"openphish-feed": {TTL: &negative}}},
+	}
+
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, cfg.ValidateCollector(), ErrInvalidCollectorConfig)
+		})
+	}
+}
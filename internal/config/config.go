@@ -18,6 +18,29 @@ type ServerConfig struct {
 
 	AllowOrigins []string `koanf:"alloworigins" default:"[]"`
 	HealthCheck  bool     `koanf:"health_check" default:"true"`
+
+	MaxBodySize     int64 `koanf:"max_body_size" default:"1048576"`        // Bytes; larger request bodies get 413
+	MaxBulkBodySize int64 `koanf:"max_bulk_body_size" default:"104857600"` // Bytes, for imports, batch queries and retrohunt logs
+	MaxBulkURLs     int   `koanf:"max_bulk_urls" default:"1000"`           // URLs per bulk request; more get 422
+
+	GRPCPort int `koanf:"grpc_port"` // gRPC query API port; 0 disables it
+
+	// TrustedProxies are the addresses or CIDRs of load balancers whose
+	// X-Forwarded-For / X-Real-IP headers name the client; empty ignores the
+	// headers and attributes requests to their peer address.
+	TrustedProxies []string `koanf:"trusted_proxies"`
+
+	// ResponseTemplates reshape the query API results of a version (v1,
+	// v2) for consumers expecting other field names.
+	ResponseTemplates map[string]ResponseTemplate `koanf:"response_templates"`
+}
+
+// ResponseTemplate renames or drops fields of query API results. Fields are
+// paths of JSON keys joined by "/", e.g. "verdict/score"; a path through an
+// array applies to every element.
+type ResponseTemplate struct {
+	Rename map[string]string `koanf:"rename"` // Field path → new name at the same level
+	Omit   []string          `koanf:"omit"`
 }
 
 func (s *ServerConfig) GetServerURL() string {
@@ -26,13 +49,73 @@ func (s *ServerConfig) GetServerURL() string {
 
 type CacheSettings struct {
 	UseBloom  bool           `koanf:"use_bloom" default:"true"`
-	CacheType string         `koanf:"cache_type" default:"badger"` // Options: "badger"
+	CacheType string         `koanf:"cache_type" default:"badger"` // Options: "badger", "ristretto"
 	TTL       *time.Duration `kaonf:"ttl" default:"5m"`
+
+	// Ristretto only: RAM cap in bytes and admission counters (0 derives them from MaxMemory).
+	MaxMemory   int64 `koanf:"max_memory" default:"268435456"`
+	NumCounters int64 `koanf:"num_counters"`
+
+	// Background revalidation: every ScrubInterval, ScrubSample keys are
+	// compared with the DB and repaired. A zero interval disables it.
+	ScrubInterval time.Duration `koanf:"scrub_interval" default:"15m"`
+	ScrubSample   int           `koanf:"scrub_sample" default:"1000"`
+
+	// Cache sync throttling: a ceiling on the keys a sync writes per second
+	// and, with a target, pacing that halves the rate while the p99 of queries
+	// exceeds it and recovers once they are back under. Zero disables either.
+	SyncMaxKeysPerSec int           `koanf:"sync_max_keys_per_sec"`
+	SyncTargetP99     time.Duration `koanf:"sync_target_p99"`
+	SyncMinKeysPerSec int           `koanf:"sync_min_keys_per_sec" default:"1000"` // Floor of the adaptive rate
+
+	// Negative cache: lookups that found nothing are answered from memory for
+	// NegativeTTL instead of the database. A zero TTL disables it.
+	NegativeTTL        time.Duration `koanf:"negative_ttl" default:"30s"`
+	NegativeMaxEntries int           `koanf:"negative_max_entries" default:"100000"`
+
+	// Bloom filter: split into BloomShards filters by key hash and, with
+	// BloomPath set, saved there after every rebuild and loaded at startup,
+	// when only the entries changed since it was built are synced.
+	BloomShards int    `koanf:"bloom_shards" default:"16"`
+	BloomPath   string `koanf:"bloom_path"`
+
+	// ProbabilisticFilter is the kind of filter checked before the cache:
+	// bloom, cuckoo or none.
+	ProbabilisticFilter string `koanf:"probabilistic_filter" default:"bloom"`
+
+	// Badger on disk: with BadgerPath set the Badger cache is kept in that
+	// directory across restarts, and its value log is garbage collected every
+	// BadgerGCInterval, rewriting files with at least BadgerGCDiscardRatio of
+	// stale data. A start on a cache synced before only syncs the entries
+	// changed since, then checks ScrubSample keys against the database and
+	// rebuilds the cache when more than BadgerWarmMaxDrift of them drifted.
+	BadgerPath           string        `koanf:"badger_path"`
+	BadgerGCInterval     time.Duration `koanf:"badger_gc_interval" default:"10m"`
+	BadgerGCDiscardRatio float64       `koanf:"badger_gc_discard_ratio" default:"0.5"`
+	BadgerWarmMaxDrift   float64       `koanf:"badger_warm_max_drift" default:"0.01"`
+
+	// WarmupGate starts the server before the startup cache sync instead of
+	// after it; /healthz/ready fails until the sync completed, so load
+	// balancers only send traffic to a warm node.
+	WarmupGate bool `koanf:"warmup_gate"`
 }
 
 type APPConfig struct {
 	Environment string        `koanf:"environment" default:"development"`
 	LogLevel     zerolog.Level `koanf:"log_level" default:"debug"`
+
+	// LogPrivacy replaces queried URLs in logs and traces with their eTLD+1
+	// and a salted hash. Unset means on in production, off otherwise.
+	LogPrivacy *bool  `koanf:"log_privacy"`
+	LogSalt    string `koanf:"log_salt"` // Hash salt; empty picks a random salt per process
+}
+
+// LogPrivacyEnabled reports whether queried URLs must be redacted in logs.
+func (a APPConfig) LogPrivacyEnabled() bool {
+	if a.LogPrivacy != nil {
+		return *a.LogPrivacy
+	}
+	return a.Environment == "production"
 }
 
 type CollectorConfig struct {
@@ -41,12 +124,236 @@ type CollectorConfig struct {
 	CronSchedule   string `koanf:"cron_schedule" default:"0 0 0 * * *"`
 	StoreResponses bool   `koanf:"store_responses" default:"true"`
 	StorePath      string `koanf:"store_path" default:"./responses"`
+
+	// Stored response retention: a new response archives the previous one,
+	// keeping MaxStoredResponses per provider (the current one included) and
+	// pruning the oldest archives beyond MaxStoredBytes in total. 0 disables a limit.
+	MaxStoredResponses int   `koanf:"max_stored_responses" default:"3"`
+	MaxStoredBytes     int64 `koanf:"max_stored_bytes" default:"1073741824"`
+
+	FlushInterval time.Duration `koanf:"flush_interval" default:"5s"` // Max time a partial batch waits before it is written
+	SaveTimeout   time.Duration `koanf:"save_timeout" default:"30s"`  // Deadline for writing one batch to the DB
+
+	// StreamFetch parses provider sources as they download instead of
+	// buffering whole responses; [providers.<name>] stream overrides it.
+	StreamFetch bool `koanf:"stream_fetch" default:"false"`
+
+	// DeltaIngest keeps a snapshot of each feed under StorePath and writes
+	// only entries added or changed since it, soft-deleting the ones gone
+	// from the feed; [providers.<name>] delta_ingest overrides it.
+	DeltaIngest bool `koanf:"delta_ingest" default:"false"`
+
+	// ConditionalFetch sends the ETag and Last-Modified of the last imported
+	// response with each fetch; a source answering 304 Not Modified is not
+	// parsed again. [providers.<name>] conditional_fetch overrides it.
+	ConditionalFetch bool `koanf:"conditional_fetch" default:"true"`
+
+	// ReservedDomains extends the built-in names never ingested (localhost,
+	// RFC 2606 and local network suffixes), each with its subdomains.
+	// A run dropping more than ReservedWarnRatio of its entries for
+	// reserved names or private addresses logs a warning; 0 never warns.
+	ReservedDomains   []string `koanf:"reserved_domains"`
+	ReservedWarnRatio float64  `koanf:"reserved_warn_ratio" default:"0.01"`
+
+	// EntryTTL expires entries this long after their feed last listed them,
+	// unless a run lists them again; [providers.<name>] ttl overrides it and
+	// 0 never expires. Every ExpiryInterval the scheduler soft deletes the
+	// expired entries; 0 stops purging.
+	EntryTTL       time.Duration `koanf:"entry_ttl" default:"0"`
+	ExpiryInterval time.Duration `koanf:"expiry_interval" default:"5m"`
+}
+
+// EdgeConfig controls the compact read-only dataset served by edge nodes.
+type EdgeConfig struct {
+	// DatasetPath is rewritten by the primary after every sync when set,
+	// and is the file an edge node memory-maps at startup.
+	DatasetPath string `koanf:"dataset_path"`
+}
+
+// AlertsConfig controls the webhook raised when a provider run adds entries
+// for protected hosts, e.g. our own domains showing up in a poisoned feed.
+type AlertsConfig struct {
+	WebhookURL string `koanf:"webhook_url"` // Empty disables alerting
+
+	// Protected lists domains or hosts, each matching itself and all its
+	// subdomains, and host globs such as "*.corp-*.example".
+	Protected  []string      `koanf:"protected"`
+	MaxEntries int           `koanf:"max_entries" default:"100"` // Offending entries attached per alert
+	Timeout    time.Duration `koanf:"timeout" default:"10s"`     // Webhook request deadline
+}
+
+// WatchlistConfig controls the events raised when newly ingested entries
+// match a watchlist. Watchlists themselves are managed at /watchlists.
+type WatchlistConfig struct {
+	WebhookURL string        `koanf:"webhook_url"`               // Used by watchlists without their own; empty leaves them SSE-only
+	MaxEvents  int           `koanf:"max_events" default:"1000"` // Events raised per provider run; later matches are dropped
+	Timeout    time.Duration `koanf:"timeout" default:"10s"`     // Webhook request deadline
+}
+
+// WebhooksConfig lists the endpoints notified of provider runs. Deliveries
+// are signed per endpoint and retried with exponential backoff.
+type WebhooksConfig struct {
+	Endpoints   []WebhookEndpoint `koanf:"endpoints"`
+	Timeout     time.Duration     `koanf:"timeout" default:"10s"`       // Request deadline of one attempt
+	MaxAttempts int               `koanf:"max_attempts" default:"5"`    // Attempts per delivery, the first included
+	Backoff     time.Duration     `koanf:"backoff" default:"2s"`        // Delay before the first retry, doubled after each
+	BatchSize   int               `koanf:"batch_size" default:"500"`    // Entries per entries.added event
+	MaxEntries  int               `koanf:"max_entries" default:"50000"` // Entries announced per run; more are counted only
+}
+
+// WebhookEndpoint is one receiver of webhook events.
+type WebhookEndpoint struct {
+	URL       string   `koanf:"url"`
+	Secret    string   `koanf:"secret"`    // HMAC-SHA256 signing key; empty sends unsigned events
+	Events    []string `koanf:"events"`    // sync.completed, entries.added; empty is sync.completed only
+	Providers []string `koanf:"providers"` // Providers whose runs are sent; empty is all
+}
+
+// HitsConfig controls per-entry hit accounting, counted asynchronously
+// whenever a query matches an entry.
+type HitsConfig struct {
+	Enabled       bool          `koanf:"enabled"`
+	BufferSize    int           `koanf:"buffer_size" default:"10000"`  // Hits queued between flushes; more are dropped
+	FlushInterval time.Duration `koanf:"flush_interval" default:"10s"` // How often queued hits are written to the DB
+}
+
+// AuthConfig controls API key and JWT authentication of the web server and
+// gRPC API.
+type AuthConfig struct {
+	Enabled          bool          `koanf:"enabled"`
+	DefaultRateLimit float64       `koanf:"default_rate_limit"`      // Requests per second of keys without their own limit; 0 is unlimited
+	CacheTTL         time.Duration `koanf:"cache_ttl" default:"30s"` // How long a looked-up key is trusted before it is read again
+
+	// JWTs signed with JWTSecret (HMAC) are accepted besides API keys, with
+	// the caller in the sub claim and its role in the role claim.
+	JWTSecret   string `koanf:"jwt_secret"`   // Empty disables JWTs
+	JWTIssuer   string `koanf:"jwt_issuer"`   // Required iss claim, if set
+	JWTAudience string `koanf:"jwt_audience"` // Required aud claim, if set
+}
+
+// AuditConfig controls the query audit log: every URL the query API answers,
+// with its verdict, caller and latency, written asynchronously.
+type AuditConfig struct {
+	Enabled       bool          `koanf:"enabled"`
+	BufferSize    int           `koanf:"buffer_size" default:"10000"`         // Queries queued between flushes; more are dropped
+	FlushInterval time.Duration `koanf:"flush_interval" default:"5s"`         // How often queued queries are written to the DB
+	Retention     time.Duration `koanf:"retention" default:"720h"`            // Age after which records are deleted; 0 keeps them
+	CallerHeader  string        `koanf:"caller_header" default:"X-Client-ID"` // Request header naming the caller
+}
+
+// StatsConfig keeps rollups of the queries the query API answers, their hit
+// rate and the active entries per source in small timeseries tables, served
+// at /stats/timeseries for dashboards without Prometheus.
+type StatsConfig struct {
+	Enabled         bool          `koanf:"enabled"`
+	Interval        time.Duration `koanf:"interval" default:"1m"`          // Resolution of the query and hit rollups
+	EntriesInterval time.Duration `koanf:"entries_interval" default:"15m"` // How often active entries are counted per source
+	Retention       time.Duration `koanf:"retention" default:"720h"`       // Age after which rollups are deleted; 0 keeps them
+}
+
+// PolicyConfig maps listed query results to a block, warn or allow action.
+// Rules are evaluated in order and the first match wins; listed results no
+// rule matches get DefaultAction.
+type PolicyConfig struct {
+	DefaultAction string       `koanf:"default_action" default:"block"`
+	Rules         []PolicyRule `koanf:"rules"`
+}
+
+// PolicyRule matches when every condition set on it holds.
+type PolicyRule struct {
+	Category string   `koanf:"category"`  // One of the matched categories; empty or "*" matches any
+	Source   string   `koanf:"source"`    // One of the matched sources; empty or "*" matches any
+	MinScore *float64 `koanf:"min_score"` // Score >= MinScore
+	MaxScore *float64 `koanf:"max_score"` // Score < MaxScore
+	Action   string   `koanf:"action"`    // block, warn or allow
+}
+
+// DNSBLConfig serves listed hosts and IPs as a DNS blocklist zone, for mail
+// servers and firewalls that query DNSBLs rather than HTTP.
+type DNSBLConfig struct {
+	Port   int           `koanf:"port"`                       // UDP and TCP port; 0 disables the DNSBL server
+	Zone   string        `koanf:"zone"`                       // Zone queries are answered under, e.g. bl.example.com
+	Answer string        `koanf:"answer" default:"127.0.0.2"` // A record returned for listed names
+	TTL    time.Duration `koanf:"ttl" default:"5m"`           // TTL of answers and of negative responses
+}
+
+// RPZConfig shapes the Response Policy Zone built from active entries,
+// served at /export/rpz and optionally over AXFR, so DNS resolvers can
+// load the blacklist directly.
+type RPZConfig struct {
+	Zone string `koanf:"zone" default:"rpz.blacked.local"`
+	// Action is nxdomain, nodata, drop, passthru, tcp-only, or a host name
+	// listed names are redirected to, e.g. a walled-garden page.
+	Action       string        `koanf:"action" default:"nxdomain"`
+	TTL          time.Duration `koanf:"ttl" default:"5m"`
+	Wildcard     bool          `koanf:"wildcard" default:"true"` // Also cover the subdomains of listed hosts
+	IncludePaths bool          `koanf:"include_paths"`           // Also list the hosts of URL entries; blocks the whole host
+
+	AXFRPort  int      `koanf:"axfr_port"`  // TCP port serving zone transfers; 0 disables AXFR
+	AXFRAllow []string `koanf:"axfr_allow"` // CIDRs allowed to transfer; empty allows loopback only
+}
+
+// ReplicationConfig controls active/passive replication between instances.
+// Every instance serves its change feed at /replication/changes; setting
+// PrimaryURL makes this one a replica that polls it and applies the changes
+// locally, so it needs no shared storage and no providers of its own.
+type ReplicationConfig struct {
+	PrimaryURL string        `koanf:"primary_url"`               // Base URL of the primary, e.g. http://primary:8082; empty disables the replica
+	Token      string        `koanf:"token"`                     // Shared bearer token; required on /replication/changes when set
+	Interval   time.Duration `koanf:"interval" default:"5s"`     // Pause between polls once caught up
+	BatchSize  int           `koanf:"batch_size" default:"1000"` // Changes fetched per request
+	Timeout    time.Duration `koanf:"timeout" default:"30s"`     // Feed request deadline
+}
+
+// EventBusConfig publishes entry changes and provider runs to Kafka or NATS.
+// Entry events are read from the change feed replicas poll, so they survive
+// restarts and are published at least once.
+type EventBusConfig struct {
+	Driver    string        `koanf:"driver"`                         // "kafka" or "nats"; empty disables publishing
+	Brokers   []string      `koanf:"brokers"`                        // Kafka bootstrap brokers, host:port
+	URL       string        `koanf:"url"`                            // NATS server URL, e.g. nats://localhost:4222
+	Topic     string        `koanf:"topic" default:"blacked.events"` // Kafka topic; NATS subject prefix, suffixed with the event type
+	Source    string        `koanf:"source" default:"/blacked"`      // CloudEvents source of every event, e.g. one per instance
+	Interval  time.Duration `koanf:"interval" default:"2s"`          // Pause between polls of the change feed once caught up
+	BatchSize int           `koanf:"batch_size" default:"1000"`      // Changes published per poll
+	Timeout   time.Duration `koanf:"timeout" default:"10s"`          // Publish deadline of one batch
+}
+
+// HealthConfig tunes the composite health score of /healthz/details. The
+// alert rules of /healthz/alert-rules are generated with the same thresholds.
+type HealthConfig struct {
+	Interval        time.Duration `koanf:"interval" default:"30s"`        // How often the health gauges are refreshed
+	FeedStaleFactor float64       `koanf:"feed_stale_factor" default:"2"` // Cron intervals without a successful run after which a feed is stale
+	CacheMaxAge     time.Duration `koanf:"cache_max_age" default:"25h"`   // Age of the last completed cache sync that degrades the score; twice that fails it
+	ErrorWindow     time.Duration `koanf:"error_window" default:"24h"`    // Provider runs counted in the error rate
+}
+
+// NormalizeConfig is the URL normalization applied to feed entries at ingest
+// and to queried links, so that equivalent spellings of a URL match. Scheme
+// and host are always lower-cased.
+type NormalizeConfig struct {
+	LowercasePath    bool     `koanf:"lowercase_path" default:"true"`                           // Also lower-case path and query
+	Punycode         bool     `koanf:"punycode" default:"true"`                                 // Convert internationalized hosts to their xn-- form
+	StripDefaultPort bool     `koanf:"strip_default_port" default:"true"`                       // Drop :80 from http and :443 from https links
+	TrailingSlash    string   `koanf:"trailing_slash" default:"strip"`                          // strip or keep
+	StripParams      []string `koanf:"strip_params" default:"[\"utm_*\",\"fbclid\",\"gclid\"]"` // Query parameters removed; a trailing * matches a prefix
+	DecodePercent    bool     `koanf:"decode_percent" default:"true"`                           // Decode needless percent-escapes (%7E → ~) and upper-case the rest
+}
+
+// PSLConfig refreshes the Public Suffix List that splits hosts into domain
+// and subdomains. Without a URL the list compiled into the binary is used.
+type PSLConfig struct {
+	URL      string        `koanf:"url" default:"https://publicsuffix.org/list/public_suffix_list.dat"` // Source of the list; empty disables updates
+	Interval time.Duration `koanf:"interval" default:"24h"`                                             // How often the list is refreshed
+	Path     string        `koanf:"path" default:"./psl/public_suffix_list.dat"`                        // Last downloaded list, loaded at startup; empty keeps it in memory only
+	Timeout  time.Duration `koanf:"timeout" default:"30s"`                                              // Download timeout
 }
 
 type ProviderOptions struct {
 	Enabled         *bool          `koanf:"enabled"`
 	SourceURL       string         `koanf:"source_url"`
 	Cron            string         `koanf:"cron"`
+	Timezone        string         `koanf:"timezone"` // IANA zone the cron is read in, e.g. "America/New_York"; empty = UTC
 	Category        string         `koanf:"category"`
 	APIKey          string         `koanf:"api_key"`
 	UserAgent       string         `koanf:"user_agent"`
@@ -55,6 +362,66 @@ type ProviderOptions struct {
 	ParserBatchSize int            `koanf:"parser_batch_size"`
 	MaxRedirects    int            `koanf:"max_redirects"`
 	MaxSize         int64          `koanf:"max_size"`
+	Mirrors         []string       `koanf:"mirrors"`           // Fallback source URLs tried in order when source_url fails
+	Stream          *bool          `koanf:"stream"`            // Parse the source as it downloads; unset follows [Collector] stream_fetch
+	AllowedDomains  []string       `koanf:"allowed_domains"`   // Extra hosts (CDNs, redirect targets) the fetcher may visit
+	IgnoreOlderThan *time.Duration `koanf:"ignore_older_than"` // Skip entries the feed dates earlier (URLhaus CSV, PhishTank)
+	Wildcard        *bool          `koanf:"wildcard"`          // Every listed host covers its subdomains; unset is on for OISD, off for generic plain feeds
+	ChecksumURL     string         `koanf:"checksum_url"`      // Published SHA-256 sums the download must match, else the run fails
+
+	// CategoryMap maps feed tags (e.g. URLhaus threat and tags columns) to
+	// extra categories of an entry; tags missing from it are dropped.
+	CategoryMap map[string]string `koanf:"category_map"`
+
+	// Generic providers are declared in config only: set Type to "generic"
+	// and Format to how source lines are read (hosts, plain, adblock, csv).
+	// Type "taxii" declares a TAXII 2.1 collection instead, "misp" a MISP
+	// instance searched through its REST API.
+	Type      string `koanf:"type"`
+	Format    string `koanf:"format"`
+	Column    int    `koanf:"column"`    // csv: 0-based column holding the URL or domain
+	Delimiter string `koanf:"delimiter"` // csv: field separator, "," by default
+	SkipRows  int    `koanf:"skip_rows"` // csv: leading header rows to skip
+
+	// csv: optional columns holding the category (mapped through CategoryMap,
+	// the provider category when empty) and a confidence score divided by
+	// ConfidenceScale, e.g. 10 for PhishStats' 0-10 score.
+	CategoryColumn   *int    `koanf:"category_column"`
+	ConfidenceColumn *int    `koanf:"confidence_column"`
+	ConfidenceScale  float64 `koanf:"confidence_scale"`
+
+	// taxii: basic auth credentials of the TAXII server. When api_key is set
+	// it is sent as a bearer token instead.
+	Username string `koanf:"username"`
+	Password string `koanf:"password"`
+
+	// misp: tags attributes must carry (or, prefixed with "!", must not)
+	// to be listed; empty lists every tag.
+	Tags []string `koanf:"tags"`
+
+	// Collector overrides for this source; zero/nil falls back to [Collector].
+	CollectorBatchSize int            `koanf:"collector_batch_size"`
+	FlushInterval      *time.Duration `koanf:"flush_interval"`
+	SaveTimeout        *time.Duration `koanf:"save_timeout"`
+	DeltaIngest        *bool          `koanf:"delta_ingest"`
+	ConditionalFetch   *bool          `koanf:"conditional_fetch"`
+	TTL                *time.Duration `koanf:"ttl"` // Entries expire this long after the feed last listed them; unset follows [Collector] entry_ttl
+}
+
+// ObjectStorageConfig holds the credentials used for s3:// and gs:// provider
+// source URLs. A store without an access key is read anonymously.
+type ObjectStorageConfig struct {
+	S3      ObjectStoreCredentials `koanf:"s3"`
+	GCS     ObjectStoreCredentials `koanf:"gcs"` // HMAC interoperability keys
+	Timeout time.Duration          `koanf:"timeout" default:"5m"`
+}
+
+type ObjectStoreCredentials struct {
+	AccessKeyID     string `koanf:"access_key_id"`
+	SecretAccessKey string `koanf:"secret_access_key"`
+	SessionToken    string `koanf:"session_token"`
+	Region          string `koanf:"region"`   // S3 defaults to us-east-1
+	Endpoint        string `koanf:"endpoint"` // S3-compatible endpoint (e.g. MinIO), addressed path-style
 }
 
 type CollyConfig struct {
@@ -63,6 +430,10 @@ type CollyConfig struct {
 	MaxDepth     int           `koanf:"max_depth" default:"1"`
 	UserAgent    string        `koanf:"user_agent" default:"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.3"`
 	TimeOut      time.Duration `koanf:"timeout" default:"5m"`
+
+	// AllowedDomains are hosts every provider may fetch from in addition to
+	// its own source, mirror and allowed_domains hosts.
+	AllowedDomains []string `koanf:"allowed_domains" default:"[\"raw.githubusercontent.com\"]"`
 }
 
 type Config struct {
@@ -71,5 +442,29 @@ type Config struct {
 	Cache     CacheSettings
 	Collector CollectorConfig
 	Colly     CollyConfig
+	Edge      EdgeConfig
+	Alerts    AlertsConfig
+	Watchlist WatchlistConfig
+	Hits      HitsConfig
+	Audit     AuditConfig
+	Stats     StatsConfig
+	Auth      AuthConfig
+	Policy    PolicyConfig
+	DNSBL     DNSBLConfig
+	RPZ       RPZConfig
 	Providers map[string]*ProviderOptions `koanf:"providers"`
+	Exports   map[string]*ExportSchedule  `koanf:"exports"`
+
+	ProviderGroups map[string]*ProviderGroup `koanf:"provider_groups"`
+
+	Retention RetentionConfig
+	Database  DatabaseConfig
+
+	ObjectStorage ObjectStorageConfig
+	Replication   ReplicationConfig
+	Webhooks      WebhooksConfig
+	EventBus      EventBusConfig
+	Health        HealthConfig
+	Normalize     NormalizeConfig
+	PSL           PSLConfig
 }
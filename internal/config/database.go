@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidDatabaseConfig = errors.New("invalid database config")
+
+// DatabaseConfig tunes the SQLite connections: the size of the read pool
+// and how the write-ahead log is checkpointed back into the database.
+type DatabaseConfig struct {
+	MaxReadConns     int           `koanf:"max_read_conns" default:"10"`     // Concurrent readers; 0 is 10
+	MaxIdleReadConns int           `koanf:"max_idle_read_conns" default:"5"` // Readers kept open between queries; 0 is 5
+	ConnMaxIdleTime  time.Duration `koanf:"conn_max_idle_time"`              // Idle readers are closed after this; 0 keeps them
+
+	// WALAutocheckpoint is the WAL size in pages after which a commit
+	// checkpoints it; 0 leaves it to CheckpointInterval. Every
+	// CheckpointInterval the WAL is checkpointed and truncated; 0 disables it.
+	WALAutocheckpoint  int           `koanf:"wal_autocheckpoint" default:"1000"`
+	CheckpointInterval time.Duration `koanf:"checkpoint_interval"`
+}
+
+// ValidateDatabase checks the [Database] settings read at startup.
+func (c *Config) ValidateDatabase() error {
+	d := c.Database
+	switch {
+	case d.MaxReadConns < 0:
+		return fmt.Errorf("%w: Database.max_read_conns must not be negative, got %d", ErrInvalidDatabaseConfig, d.MaxReadConns)
+	case d.MaxIdleReadConns < 0:
+		return fmt.Errorf("%w: Database.max_idle_read_conns must not be negative, got %d", ErrInvalidDatabaseConfig, d.MaxIdleReadConns)
+	case d.MaxReadConns > 0 && d.MaxIdleReadConns > d.MaxReadConns:
+		return fmt.Errorf("%w: Database.max_idle_read_conns (%d) must not exceed max_read_conns (%d)", ErrInvalidDatabaseConfig, d.MaxIdleReadConns, d.MaxReadConns)
+	case d.ConnMaxIdleTime < 0:
+		return fmt.Errorf("%w: Database.conn_max_idle_time must not be negative, got %s", ErrInvalidDatabaseConfig, d.ConnMaxIdleTime)
+	case d.WALAutocheckpoint < 0:
+		return fmt.Errorf("%w: Database.wal_autocheckpoint must not be negative, got %d", ErrInvalidDatabaseConfig, d.WALAutocheckpoint)
+	case d.CheckpointInterval < 0:
+		return fmt.Errorf("%w: Database.checkpoint_interval must not be negative, got %s", ErrInvalidDatabaseConfig, d.CheckpointInterval)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDatabase(t *testing.T) {
+	assert.NoError(t, (&Config{}).ValidateDatabase(), "zero values keep the built-in pool")
+	assert.NoError(t, (&Config{Database: DatabaseConfig{MaxReadConns: 4, MaxIdleReadConns: 4, WALAutocheckpoint: 1000, CheckpointInterval: time.Minute}}).ValidateDatabase())
+
+	tests := map[string]DatabaseConfig{
+		"negative readers":        {MaxReadConns: -1},
+		"negative idle readers":   {MaxIdleReadConns: -1},
+		"more idle than open":     {MaxReadConns: 2, MaxIdleReadConns: 3},
+		"negative idle time":      {ConnMaxIdleTime: -time.Second},
+		"negative autocheckpoint": {WALAutocheckpoint: -1},
+		"negative interval":       {CheckpointInterval: -time.Minute},
+	}
+	for name, database := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, (&Config{Database: database}).ValidateDatabase(), ErrInvalidDatabaseConfig)
+		})
+	}
+}
@@ -39,6 +39,12 @@ func GetEnv(key, fallback string) string {
 	return fallback
 }
 func InitConfig() error {
+	return InitConfigFile("./" + GetEnv("CONFIG_FILE", ".env.toml"))
+}
+
+// InitConfigFile loads the configuration once from the TOML file at path,
+// over the defaults; a missing file leaves the defaults.
+func InitConfigFile(path string) error {
 	var err error
 	once.Do(func() {
 		_k = koanf.New(".")
@@ -46,14 +52,12 @@ func InitConfig() error {
 		_config = &Config{}
 		emptyConfig := &Config{}
 
-		configFile := GetEnv("CONFIG_FILE", ".env.toml")
-
 		if _err := defaults.Set(_config); _err != nil {
 			err = _err
 			return
 		}
 
-		if err := _k.Load(file.Provider("./"+configFile), toml.Parser()); err != nil {
+		if err := _k.Load(file.Provider(path), toml.Parser()); err != nil {
 			log.Error().Msg("error loading config [TOML]")
 		} else {
 			log.Info().Msg("config loaded from file")
@@ -81,6 +85,36 @@ func InitConfig() error {
 			}
 		}
 
+		if vErr := _config.ValidateCollector(); vErr != nil {
+			err = vErr
+			return
+		}
+
+		if vErr := _config.ValidateCache(); vErr != nil {
+			err = vErr
+			return
+		}
+
+		if vErr := _config.ValidateExports(); vErr != nil {
+			err = vErr
+			return
+		}
+
+		if vErr := _config.ValidateProviderGroups(); vErr != nil {
+			err = vErr
+			return
+		}
+
+		if vErr := _config.ValidateRetention(); vErr != nil {
+			err = vErr
+			return
+		}
+
+		if vErr := _config.ValidateDatabase(); vErr != nil {
+			err = vErr
+			return
+		}
+
 		zerolog.SetGlobalLevel(_config.APP.LogLevel)
 	})
 
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidExportConfig = errors.New("invalid export config")
+
+// DefaultExportLead is how long before an export is applied its providers
+// are synced when [exports.<name>] lead is unset.
+const DefaultExportLead = 30 * time.Minute
+
+// ExportSchedule is a fixed time a consumer applies a generated export,
+// e.g. a firewall reloading /export/rpz at 06:00. The providers the export
+// depends on are synced Lead before every apply time, so it carries their
+// latest entries.
+type ExportSchedule struct {
+	Cron      string        `koanf:"cron"`      // When the export is applied
+	Timezone  string        `koanf:"timezone"`  // IANA zone the cron is read in; empty = UTC
+	Providers []string      `koanf:"providers"` // Providers synced before every apply time
+	Lead      time.Duration `koanf:"lead"`      // How long before the apply time the sync starts; 0 = 30m
+}
+
+// LeadTime returns Lead, or DefaultExportLead when it is unset.
+func (e *ExportSchedule) LeadTime() time.Duration {
+	if e.Lead <= 0 {
+		return DefaultExportLead
+	}
+	return e.Lead
+}
+
+// ValidateExports checks the [exports.<name>] schedules read at startup.
+func (c *Config) ValidateExports() error {
+	for name, export := range c.Exports {
+		switch {
+		case export == nil:
+			continue
+		case strings.TrimSpace(export.Cron) == "":
+			return fmt.Errorf("%w: exports.%s.cron is required", ErrInvalidExportConfig, name)
+		case len(export.Providers) == 0:
+			return fmt.Errorf("%w: exports.%s.providers must name at least one provider", ErrInvalidExportConfig, name)
+		case export.Lead < 0:
+			return fmt.Errorf("%w: exports.%s.lead must not be negative, got %s", ErrInvalidExportConfig, name, export.Lead)
+		}
+	}
+	return nil
+}
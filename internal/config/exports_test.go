@@ -0,0 +1,26 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateExports(t *testing.T) {
+	valid := &ExportSchedule{Cron: "0 6 * * *", Providers: []string{"oisd-big"}}
+	assert.NoError(t, (&Config{Exports: map[string]*ExportSchedule{"firewall": valid}}).ValidateExports())
+	assert.Equal(t, DefaultExportLead, valid.LeadTime())
+
+	tests := map[string]*ExportSchedule{
+		"no cron":       {Providers: []string{"oisd-big"}},
+		"no providers":  {Cron: "0 6 * * *"},
+		"negative lead": {Cron: "0 6 * * *", Providers: []string{"oisd-big"}, Lead: -time.Minute},
+	}
+	for name, export := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := &Config{Exports: map[string]*ExportSchedule{"firewall": export}}
+			assert.ErrorIs(t, cfg.ValidateExports(), ErrInvalidExportConfig)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrInvalidProviderGroup = errors.New("invalid provider group")
+
+// ProviderGroup is a named set of providers, e.g. "phishing" for OpenPhish
+// and PhishTank. Processing a group, from the API, the CLI or its own Cron,
+// runs its providers as one process whose history lists each provider's
+// run; exports may name a group instead of its providers.
+type ProviderGroup struct {
+	Providers []string `koanf:"providers"`
+	Cron      string   `koanf:"cron"`     // Runs the group as one process; the providers keep their own crons
+	Timezone  string   `koanf:"timezone"` // IANA zone the cron is read in; empty = UTC
+}
+
+// ValidateProviderGroups checks the [provider_groups.<name>] read at
+// startup. Whether the providers are registered is only known once they
+// are, so unknown ones are reported when the group is processed.
+func (c *Config) ValidateProviderGroups() error {
+	for name, group := range c.ProviderGroups {
+		switch {
+		case group == nil:
+			continue
+		case len(group.Providers) == 0:
+			return fmt.Errorf("%w: provider_groups.%s.providers must name at least one provider", ErrInvalidProviderGroup, name)
+		case c.Providers[name] != nil:
+			return fmt.Errorf("%w: provider_groups.%s has the name of a provider", ErrInvalidProviderGroup, name)
+		}
+		for _, provider := range group.Providers {
+			if strings.TrimSpace(provider) == "" {
+				return fmt.Errorf("%w: provider_groups.%s.providers has an empty name", ErrInvalidProviderGroup, name)
+			}
+			if c.ProviderGroups[provider] != nil {
+				return fmt.Errorf("%w: provider_groups.%s names the group %s; groups don't nest", ErrInvalidProviderGroup, name, provider)
+			}
+		}
+	}
+	return nil
+}
+
+// ExpandProviderGroups returns names with every group replaced by its
+// providers, in order and without duplicates, and the groups names held.
+func (c *Config) ExpandProviderGroups(names []string) (providers []string, groups []string) {
+	for _, name := range names {
+		members := []string{name}
+		if group := c.ProviderGroups[name]; group != nil {
+			members = group.Providers
+			groups = append(groups, name)
+		}
+		for _, member := range members {
+			if !slices.Contains(providers, member) {
+				providers = append(providers, member)
+			}
+		}
+	}
+	return providers, groups
+}
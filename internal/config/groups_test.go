@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateProviderGroups(t *testing.T) {
+	phishing := &ProviderGroup{Providers: []string{"openphish", "phishtank-online-valid"}}
+	assert.NoError(t, (&Config{ProviderGroups: map[string]*ProviderGroup{"phishing": phishing}}).ValidateProviderGroups())
+
+	tests := map[string]*Config{
+		"no providers": {ProviderGroups: map[string]*ProviderGroup{"phishing": {}}},
+		"empty name":   {ProviderGroups: map[string]*ProviderGroup{"phishing": {Providers: []string{"openphish", " "}}}},
+		"provider name": {
+			Providers:      map[string]*ProviderOptions{"phishing": {}},
+			ProviderGroups: map[string]*ProviderGroup{"phishing": phishing},
+		},
+		"nested": {ProviderGroups: map[string]*ProviderGroup{
+			"phishing": phishing,
+			"all":      {Providers: []string{"phishing", "oisd-big"}},
+		}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, cfg.ValidateProviderGroups(), ErrInvalidProviderGroup)
+		})
+	}
+}
+
+func TestExpandProviderGroups(t *testing.T) {
+	cfg := &Config{ProviderGroups: map[string]*ProviderGroup{
+		"phishing": {Providers: []string{"openphish", "phishtank-online-valid"}},
+	}}
+
+	providers, groups := cfg.ExpandProviderGroups([]string{"openphish", "phishing", "oisd-big"})
+	assert.Equal(t, []string{"openphish", "phishtank-online-valid", "oisd-big"}, providers)
+	assert.Equal(t, []string{"phishing"}, groups)
+
+	providers, groups = cfg.ExpandProviderGroups([]string{"oisd-big"})
+	assert.Equal(t, []string{"oisd-big"}, providers)
+	assert.Empty(t, groups)
+}
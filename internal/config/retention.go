@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidRetentionConfig = errors.New("invalid retention config")
+
+// RetentionConfig controls the retention job: soft-deleted entries are kept
+// for reactivation, change feeds and delta exports, and removed for good
+// once they have been deleted for longer than Deleted. The events of
+// provider runs are removed once older than ProcessEvents.
+type RetentionConfig struct {
+	Deleted       time.Duration `koanf:"deleted" default:"0"`           // Age of a soft delete after which the row is removed; 0 keeps them
+	ProcessEvents time.Duration `koanf:"process_events" default:"720h"` // Age of a provider run event after which it is removed; 0 keeps them
+	Interval      time.Duration `koanf:"interval" default:"24h"`        // How often the retention job runs
+	Vacuum        bool          `koanf:"vacuum" default:"true"`         // Return the freed pages to the file system after removing rows
+}
+
+// Enabled reports whether soft-deleted entries are ever removed.
+func (r RetentionConfig) Enabled() bool {
+	return r.Deleted > 0
+}
+
+// Scheduled reports whether the retention job has anything to remove.
+func (r RetentionConfig) Scheduled() bool {
+	return r.Enabled() || r.ProcessEvents > 0
+}
+
+// ValidateRetention checks the [Retention] settings read at startup.
+func (c *Config) ValidateRetention() error {
+	switch {
+	case c.Retention.Deleted < 0:
+		return fmt.Errorf("%w: Retention.deleted must not be negative, got %s", ErrInvalidRetentionConfig, c.Retention.Deleted)
+	case c.Retention.ProcessEvents < 0:
+		return fmt.Errorf("%w: Retention.process_events must not be negative, got %s", ErrInvalidRetentionConfig, c.Retention.ProcessEvents)
+	case c.Retention.Scheduled() && c.Retention.Interval <= 0:
+		return fmt.Errorf("%w: Retention.interval must be positive, got %s", ErrInvalidRetentionConfig, c.Retention.Interval)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRetention(t *testing.T) {
+	assert.NoError(t, (&Config{}).ValidateRetention(), "retention is off by default")
+	assert.NoError(t, (&Config{Retention: RetentionConfig{Deleted: 30 * 24 * time.Hour, Interval: 24 * time.Hour}}).ValidateRetention())
+
+	tests := map[string]RetentionConfig{
+		"negative age":       {Deleted: -time.Hour, Interval: time.Hour},
+		"no interval":        {Deleted: time.Hour},
+		"negative interval":  {Deleted: time.Hour, Interval: -time.Hour},
+		"negative events":    {ProcessEvents: -time.Hour, Interval: time.Hour},
+		"events no interval": {ProcessEvents: time.Hour},
+	}
+	for name, retention := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, (&Config{Retention: retention}).ValidateRetention(), ErrInvalidRetentionConfig)
+		})
+	}
+}
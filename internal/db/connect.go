@@ -48,7 +48,7 @@ func EnsureDBSchemaExists(opts ...Option) error {
 	useRW := true // Schema needs to be checked/created always if not in memory
 
 	if !baseOpts.inMemory && !baseOpts.isTesting {
-		if _, err := os.Stat(dbName); os.IsNotExist(err) { // Check if DB file does not exist
+		if _, err := os.Stat(baseOpts.dsn()); os.IsNotExist(err) { // Check if DB file does not exist
 			useRW = true // Create schema if file doesn't exist
 			log.Debug().Msg("Database file does not exist, will create and initialize schema.")
 		} else {
@@ -65,7 +65,7 @@ func EnsureDBSchemaExists(opts ...Option) error {
 		return nil
 	}
 
-	dbRW, err := Connect(WithInMemory(baseOpts.inMemory), WithTesting(baseOpts.isTesting))
+	dbRW, err := Connect(WithInMemory(baseOpts.inMemory), WithTesting(baseOpts.isTesting), WithPath(baseOpts.path))
 	if err != nil {
 		log.Error().Err(err).Stack().Msg("Failed to open RW connection for schema creation.")
 		return err
@@ -84,31 +84,15 @@ func EnsureDBSchemaExists(opts ...Option) error {
 	return nil
 }
 
-func Connect(options ...Option) (*sql.DB, error) {
-	opts := dbOptions{
-		isTesting:   false,
-		inMemory:    false,
-		isInWALMode: true,
-	}
-	for _, opt := range options {
-		opt(&opts)
-	}
-
-	var dsn string
-	switch {
-	case opts.inMemory:
-		dsn = memoryDB
-	case opts.isTesting:
-		dsn = testDB
-	default:
-		dsn = dbName
-	}
+const (
+	defaultReadConns     = 10
+	defaultReadIdleConns = 5
+)
 
-	if opts.isInWALMode && !opts.inMemory {
-		dsn = dsn + "?_journal_mode=WAL"
-	}
+func Connect(options ...Option) (*sql.DB, error) {
+	opts := newOptions(options)
 
-	db, err := connectSQLite(dsn, 1, 1) // Default: single connection
+	db, err := connectSQLite(opts.connectionString(), 1, 1) // Default: single connection
 	if err != nil {
 		return nil, err
 	}
@@ -118,70 +102,37 @@ func Connect(options ...Option) (*sql.DB, error) {
 
 // ConnectReadOnly creates a read-only connection pool optimized for concurrent reads.
 // In WAL mode, multiple readers can read simultaneously without blocking.
+// File databases are opened with mode=ro, so its connections can't write.
 func ConnectReadOnly(options ...Option) (*sql.DB, error) {
-	opts := dbOptions{
-		isTesting:   false,
-		inMemory:    false,
-		isInWALMode: true,
-	}
-	for _, opt := range options {
-		opt(&opts)
-	}
+	opts := newOptions(options)
+	opts.readOnly = true
 
-	var dsn string
-	switch {
-	case opts.inMemory:
-		dsn = memoryDB
-	case opts.isTesting:
-		dsn = testDB
-	default:
-		dsn = dbName
+	maxOpen, maxIdle := opts.readConns, opts.readIdleConns
+	if maxOpen <= 0 {
+		maxOpen = defaultReadConns
 	}
-
-	// Add read-only mode and WAL mode for file-based databases
-	if !opts.inMemory {
-		dsn = dsn + "?_journal_mode=WAL&mode=ro"
+	if maxIdle <= 0 {
+		maxIdle = min(defaultReadIdleConns, maxOpen)
 	}
 
 	// Allow multiple concurrent readers (e.g., 10 connections for parallel query handling)
-	db, err := connectSQLite(dsn, 10, 5)
+	db, err := connectSQLite(opts.connectionString(), maxOpen, maxIdle)
 	if err != nil {
 		return nil, err
 	}
+	db.SetConnMaxIdleTime(opts.connMaxIdleTime)
 
-	log.Debug().Int("max_open", 10).Int("max_idle", 5).Msg("Read-only connection pool created")
+	log.Debug().Int("max_open", maxOpen).Int("max_idle", maxIdle).Dur("max_idle_time", opts.connMaxIdleTime).Msg("Read-only connection pool created")
 	return db, nil
 }
 
 // ConnectReadWrite creates a write connection optimized for single-writer pattern.
 // SQLite only allows one writer at a time, so we use a single connection.
 func ConnectReadWrite(options ...Option) (*sql.DB, error) {
-	opts := dbOptions{
-		isTesting:   false,
-		inMemory:    false,
-		isInWALMode: true,
-	}
-	for _, opt := range options {
-		opt(&opts)
-	}
-
-	var dsn string
-	switch {
-	case opts.inMemory:
-		dsn = memoryDB
-	case opts.isTesting:
-		dsn = testDB
-	default:
-		dsn = dbName
-	}
-
-	// Add WAL mode for file-based databases (read-write is default)
-	if opts.isInWALMode && !opts.inMemory {
-		dsn = dsn + "?_journal_mode=WAL"
-	}
+	opts := newOptions(options)
 
 	// Single connection for writes to prevent contention
-	db, err := connectSQLite(dsn, 1, 1)
+	db, err := connectSQLite(opts.connectionString(), 1, 1)
 	if err != nil {
 		return nil, err
 	}
@@ -197,7 +148,7 @@ func connectSQLite(dataSourceName string, maxOpen, maxIdle int) (*sql.DB, error)
 		return nil, ErrOpenDatabase
 	}
 
-	// Test the connection
+	// Test the connection; it also runs the PRAGMAs of the DSN
 	if err := db.Ping(); err != nil {
 		log.Err(err).Str("dsn", dataSourceName).Msg("Failed to ping SQLite database")
 		return nil, ErrPingDatabase
@@ -207,25 +158,5 @@ func connectSQLite(dataSourceName string, maxOpen, maxIdle int) (*sql.DB, error)
 	db.SetMaxOpenConns(maxOpen)
 	db.SetMaxIdleConns(maxIdle)
 
-	// Enable foreign keys
-	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
-		log.Warn().Err(err).Msg("Failed to enable foreign keys")
-	}
-
-	// Optimize SQLite for better write performance with WAL mode
-	if _, err := db.Exec("PRAGMA synchronous = NORMAL"); err != nil {
-		log.Warn().Err(err).Msg("Failed to set synchronous mode")
-	}
-
-	// Increase cache size for better performance (10MB)
-	if _, err := db.Exec("PRAGMA cache_size = -10000"); err != nil {
-		log.Warn().Err(err).Msg("Failed to set cache size")
-	}
-
-	// Set busy timeout to handle any remaining contention
-	if _, err := db.Exec("PRAGMA busy_timeout = 5000"); err != nil {
-		log.Warn().Err(err).Msg("Failed to set busy timeout")
-	}
-
 	return db, nil
 }
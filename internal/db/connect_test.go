@@ -1,9 +1,13 @@
 package db
 
 import (
+	"context"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConnect(t *testing.T) {
@@ -25,3 +29,60 @@ func TestConnectTest(t *testing.T) {
 
 	assert.NotEmpty(t, s)
 }
+
+func TestConnectionPragmas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blacked.db")
+	rw, err := ConnectReadWrite(WithPath(path), WithWALAutocheckpoint(200))
+	require.NoError(t, err)
+	defer rw.Close()
+
+	var mode string
+	require.NoError(t, rw.QueryRow("PRAGMA journal_mode").Scan(&mode))
+	assert.Equal(t, "wal", mode)
+
+	ro, err := ConnectReadOnly(WithPath(path), WithReadPool(3, 2), WithConnMaxIdleTime(time.Minute), WithWALAutocheckpoint(200))
+	require.NoError(t, err)
+	defer ro.Close()
+	assert.Equal(t, 3, ro.Stats().MaxOpenConnections)
+
+	// Every connection of the pool gets the PRAGMAs, not only the first.
+	ctx := context.Background()
+	for range 3 {
+		c, err := ro.Conn(ctx)
+		require.NoError(t, err)
+		defer c.Close()
+
+		var timeout, pages, foreignKeys int
+		require.NoError(t, c.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&timeout))
+		require.NoError(t, c.QueryRowContext(ctx, "PRAGMA wal_autocheckpoint").Scan(&pages))
+		require.NoError(t, c.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys))
+		assert.Equal(t, 5000, timeout)
+		assert.Equal(t, 200, pages)
+		assert.Equal(t, 1, foreignKeys)
+	}
+}
+
+func TestConnectReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "black listed.db")
+	require.NoError(t, EnsureDBSchemaExists(WithPath(path)))
+
+	// The schema connection is closed, so the pool starts without a WAL.
+	ro, err := ConnectReadOnly(WithPath(path))
+	require.NoError(t, err)
+	defer ro.Close()
+
+	var seeded int
+	require.NoError(t, ro.QueryRow("SELECT COUNT(*) FROM providers").Scan(&seeded))
+	_, err = ro.Exec("DELETE FROM providers")
+	assert.Error(t, err, "the read pool can't write")
+
+	// Writes of the read-write connection show up in the pool.
+	rw, err := ConnectReadWrite(WithPath(path))
+	require.NoError(t, err)
+	defer rw.Close()
+	_, err = rw.Exec("INSERT INTO providers (id, name) VALUES ('p1', 'feed')")
+	require.NoError(t, err)
+	var providers int
+	require.NoError(t, ro.QueryRow("SELECT COUNT(*) FROM providers").Scan(&providers))
+	assert.Equal(t, seeded+1, providers)
+}
@@ -35,10 +35,11 @@ func (r *entryRepository) SearchEntries(ctx context.Context, filter query.Search
 	addFilter("path", filter.Path)
 	addFilter("source", filter.SourceID)
 
-	// Category uses LIKE for partial match
+	// Category uses LIKE for partial match, on the primary category or the
+	// JSON array of a multi-category entry
 	if filter.Category != "" {
-		conditions = append(conditions, "category LIKE ?")
-		args = append(args, "%"+filter.Category+"%")
+		conditions = append(conditions, "(category LIKE ? OR categories LIKE ?)")
+		args = append(args, "%"+filter.Category+"%", "%"+filter.Category+"%")
 	}
 
 	where := ""
@@ -179,6 +180,30 @@ func (r *entryRepository) ExistsByBloomType(ctx context.Context, matchType, key
 	}
 }
 
+// SourcesBySourceURL returns the sources with a non-deleted entry listing link as is.
+func (r *entryRepository) SourcesBySourceURL(ctx context.Context, link string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT source FROM entries WHERE source_url = ? AND deleted_at IS NULL ORDER BY source
+	`, link)
+	if err != nil {
+		return nil, fmt.Errorf("sources by source_url: %w", err)
+	}
+	defer rows.Close()
+
+	var sources []string
+	for rows.Next() {
+		var source string
+		if err := rows.Scan(&source); err != nil {
+			return nil, fmt.Errorf("scan source: %w", err)
+		}
+		sources = append(sources, source)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration: %w", err)
+	}
+	return sources, nil
+}
+
 // GetEntryByFullURL looks up an exact source_url match (used by Hit after bloom positive).
 func (r *entryRepository) GetEntryByFullURL(ctx context.Context, fullURL string) (*query.Entry, error) {
 	row := r.db.QueryRowContext(ctx, `
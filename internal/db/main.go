@@ -19,6 +19,9 @@ type dbInstance struct {
 	readDB  *sql.DB // Read-only connection pool (multiple readers allowed)
 	writeDB *sql.DB // Write connection (single writer)
 	err     error
+
+	readStmts  *Statements // Prepared statements of readDB
+	writeStmts *Statements // Prepared statements of writeDB
 }
 
 var (
@@ -48,7 +51,7 @@ func GetWriteDB() (*sql.DB, error) {
 
 func InitializeDB(options ...Option) {
 	initOnce.Do(func() {
-		if err := EnsureDBSchemaExists(); err != nil {
+		if err := EnsureDBSchemaExists(options...); err != nil {
 			log.Error().Err(err).Stack().Msg("Failed to ensure schema exists")
 			instance.err = err
 			return
@@ -62,16 +65,19 @@ func InitializeDB(options ...Option) {
 			return
 		}
 		instance.readDB = readDB
+		instance.readStmts = NewStatements(readDB)
 
 		// Create write connection (single writer)
 		writeDB, err := ConnectReadWrite(options...)
 		if err != nil {
 			log.Error().Err(err).Stack().Msg("Failed to open read-write database connection")
+			closeStatements()
 			_ = readDB.Close()
 			instance.err = err
 			return
 		}
 		instance.writeDB = writeDB
+		instance.writeStmts = NewStatements(writeDB)
 
 		log.Info().
 			Msg("Database connections initialized (separate read/write pools)")
@@ -80,6 +86,7 @@ func InitializeDB(options ...Option) {
 
 func Close() error {
 	var errs []error
+	closeStatements()
 
 	if instance.readDB != nil {
 		if err := instance.readDB.Close(); err != nil {
@@ -107,6 +114,7 @@ func Close() error {
 
 func ResetForTesting() {
 	// Close any existing open DB connections
+	closeStatements()
 	if instance.readDB != nil {
 		_ = instance.readDB.Close()
 		instance.readDB = nil
@@ -122,6 +130,23 @@ func ResetForTesting() {
 	log.Info().Msg("Database connections reset for testing.")
 }
 
+// closeStatements closes the prepared statements of both pools, which
+// must go before the pools themselves.
+func closeStatements() {
+	if instance.readStmts != nil {
+		if err := instance.readStmts.Close(); err != nil {
+			log.Warn().Err(err).Msg("Failed to close prepared statements of the read-only connection")
+		}
+		instance.readStmts = nil
+	}
+	if instance.writeStmts != nil {
+		if err := instance.writeStmts.Close(); err != nil {
+			log.Warn().Err(err).Msg("Failed to close prepared statements of the read-write connection")
+		}
+		instance.writeStmts = nil
+	}
+}
+
 func GetTestDB() (*sql.DB, error) {
 	dbTest, err := Connect(WithTesting(true))
 	if err != nil {
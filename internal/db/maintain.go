@@ -0,0 +1,241 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var ErrMaintainDatabase = errors.New("failed to maintain database")
+
+// autoVacuumIncremental is the PRAGMA auto_vacuum value that lets
+// incremental_vacuum return free pages to the file system.
+const autoVacuumIncremental = 2
+
+// FileStats describes the size and fragmentation of the database files.
+type FileStats struct {
+	DBBytes   int64 `json:"db_bytes"`
+	WALBytes  int64 `json:"wal_bytes"`
+	PageSize  int64 `json:"page_size"`
+	Pages     int64 `json:"pages"`
+	FreePages int64 `json:"free_pages"`
+
+	// Fragmentation is the share of pages on the free list, left behind by
+	// deleted rows. SQLite reuses them but never shrinks the file on its own.
+	Fragmentation float64 `json:"fragmentation"`
+}
+
+// TotalBytes returns the size of the database and its WAL.
+func (s FileStats) TotalBytes() int64 {
+	return s.DBBytes + s.WALBytes
+}
+
+// MaintenanceReport is the outcome of Maintain.
+type MaintenanceReport struct {
+	Path   string    `json:"path"`
+	DryRun bool      `json:"dry_run"`
+	Before FileStats `json:"before"`
+	After  FileStats `json:"after"` // Estimated on dry runs
+
+	// Converted is set when the database was switched to incremental auto
+	// vacuum, which takes one full VACUUM; later runs are incremental.
+	Converted bool `json:"converted"`
+
+	ReclaimedBytes int64         `json:"reclaimed_bytes"`
+	Duration       time.Duration `json:"duration"`
+}
+
+// Maintain returns the free pages of conn's database to the file system and
+// truncates its WAL after checkpointing it, reporting file sizes before and
+// after. A dry run only estimates the result. conn should be the write
+// connection: writers wait for maintenance to finish.
+func Maintain(ctx context.Context, conn *sql.DB, dryRun bool) (*MaintenanceReport, error) {
+	started := time.Now()
+
+	// PRAGMAs are per connection; pin one for the whole run.
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		log.Err(err).Msg("Failed to get a connection for maintenance")
+		return nil, ErrMaintainDatabase
+	}
+	defer c.Close()
+
+	report := &MaintenanceReport{DryRun: dryRun}
+	if report.Path, err = mainFile(ctx, c); err != nil {
+		return nil, err
+	}
+	if report.Before, err = fileStats(ctx, c, report.Path); err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		report.After = report.Before
+		report.After.DBBytes -= report.Before.FreePages * report.Before.PageSize
+		report.After.WALBytes = 0
+		report.After.Pages -= report.Before.FreePages
+		report.After.FreePages = 0
+		report.After.Fragmentation = 0
+	} else {
+		var mode int
+		if err := c.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&mode); err != nil {
+			log.Err(err).Msg("Failed to read auto_vacuum mode")
+			return nil, ErrMaintainDatabase
+		}
+
+		if mode != autoVacuumIncremental {
+			// Changing the mode only takes effect through a full VACUUM.
+			if _, err := c.ExecContext(ctx, "PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+				log.Err(err).Msg("Failed to enable incremental auto_vacuum")
+				return nil, ErrMaintainDatabase
+			}
+			if _, err := c.ExecContext(ctx, "VACUUM"); err != nil {
+				log.Err(err).Msg("Failed to vacuum database")
+				return nil, ErrMaintainDatabase
+			}
+			report.Converted = true
+		} else if err := incrementalVacuum(ctx, c); err != nil {
+			log.Err(err).Msg("Failed to run incremental vacuum")
+			return nil, ErrMaintainDatabase
+		}
+
+		if report.Path != "" {
+			cp, err := walCheckpoint(ctx, c)
+			if err != nil {
+				log.Err(err).Msg("Failed to checkpoint WAL")
+				return nil, ErrMaintainDatabase
+			}
+			if cp.Busy {
+				log.Warn().Int("wal_pages", cp.WALPages).Int("checkpointed", cp.Checkpointed).Msg("WAL checkpoint blocked by readers; WAL not truncated")
+			}
+		}
+
+		if report.After, err = fileStats(ctx, c, report.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	report.ReclaimedBytes = report.Before.TotalBytes() - report.After.TotalBytes()
+	report.Duration = time.Since(started)
+
+	log.Info().
+		Str("path", report.Path).
+		Bool("dry_run", dryRun).
+		Bool("converted", report.Converted).
+		Int64("before_bytes", report.Before.TotalBytes()).
+		Int64("after_bytes", report.After.TotalBytes()).
+		Float64("fragmentation", report.Before.Fragmentation).
+		Dur("duration", report.Duration).
+		Msg("Database maintenance completed")
+	return report, nil
+}
+
+// CheckpointReport is the outcome of a WAL checkpoint.
+type CheckpointReport struct {
+	// Busy is set when readers still used the WAL, so it was checkpointed
+	// only up to them and not truncated; WALPages and Checkpointed are then
+	// the pages left in it and those copied. A truncated WAL reports zero.
+	Busy         bool `json:"busy"`
+	WALPages     int  `json:"wal_pages"`
+	Checkpointed int  `json:"checkpointed"`
+}
+
+// Checkpoint copies the WAL of conn's database back into it and truncates
+// the WAL, so it does not grow between automatic checkpoints that readers
+// kept from completing. conn should be the write connection.
+func Checkpoint(ctx context.Context, conn *sql.DB) (*CheckpointReport, error) {
+	cp, err := walCheckpoint(ctx, conn)
+	if err != nil {
+		log.Err(err).Msg("Failed to checkpoint WAL")
+		return nil, ErrMaintainDatabase
+	}
+	log.Debug().Bool("busy", cp.Busy).Int("wal_pages", cp.WALPages).Int("checkpointed", cp.Checkpointed).Msg("WAL checkpointed")
+	return cp, nil
+}
+
+// RunCheckpoints checkpoints conn every interval until ctx is done.
+func RunCheckpoints(ctx context.Context, conn *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Info().Dur("interval", interval).Msg("WAL checkpoint job started")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			Checkpoint(ctx, conn)
+		}
+	}
+}
+
+func walCheckpoint(ctx context.Context, q interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}) (*CheckpointReport, error) {
+	var busy int
+	cp := &CheckpointReport{}
+	if err := q.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &cp.WALPages, &cp.Checkpointed); err != nil {
+		return nil, err
+	}
+	cp.Busy = busy != 0
+	return cp, nil
+}
+
+// incrementalVacuum frees every page on the free list. SQLite frees one page
+// per step of the statement, so its rows are drained.
+func incrementalVacuum(ctx context.Context, c *sql.Conn) error {
+	rows, err := c.QueryContext(ctx, "PRAGMA incremental_vacuum")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	return rows.Err()
+}
+
+// mainFile returns the file of the main database; empty when in memory.
+func mainFile(ctx context.Context, c *sql.Conn) (string, error) {
+	var seq int
+	var name, file string
+	if err := c.QueryRowContext(ctx, "PRAGMA database_list").Scan(&seq, &name, &file); err != nil {
+		log.Err(err).Msg("Failed to read database file")
+		return "", ErrMaintainDatabase
+	}
+	return file, nil
+}
+
+func fileStats(ctx context.Context, c *sql.Conn, path string) (FileStats, error) {
+	var s FileStats
+	for pragma, dst := range map[string]*int64{
+		"PRAGMA page_size":      &s.PageSize,
+		"PRAGMA page_count":     &s.Pages,
+		"PRAGMA freelist_count": &s.FreePages,
+	} {
+		if err := c.QueryRowContext(ctx, pragma).Scan(dst); err != nil {
+			log.Err(err).Str("pragma", pragma).Msg("Failed to read database page stats")
+			return FileStats{}, ErrMaintainDatabase
+		}
+	}
+	if s.Pages > 0 {
+		s.Fragmentation = float64(s.FreePages) / float64(s.Pages)
+	}
+
+	if path != "" {
+		s.DBBytes = fileSize(path)
+		s.WALBytes = fileSize(path + "-wal")
+	}
+	return s, nil
+}
+
+// fileSize returns the size of path, or 0 when it does not exist.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintain(t *testing.T) {
+	conn, err := ConnectReadWrite(WithPath(filepath.Join(t.TempDir(), "maintain.db")))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	_, err = conn.Exec("CREATE TABLE blobs (data TEXT)")
+	require.NoError(t, err)
+	for range 200 {
+		_, err = conn.Exec("INSERT INTO blobs (data) VALUES (?)", strings.Repeat("x", 4000))
+		require.NoError(t, err)
+	}
+	_, err = conn.Exec("DELETE FROM blobs")
+	require.NoError(t, err)
+
+	dry, err := Maintain(ctx, conn, true)
+	require.NoError(t, err)
+	assert.True(t, dry.DryRun)
+	assert.Positive(t, dry.Before.FreePages)
+	assert.Positive(t, dry.Before.Fragmentation)
+	assert.Positive(t, dry.ReclaimedBytes)
+
+	report, err := Maintain(ctx, conn, false)
+	require.NoError(t, err)
+	assert.True(t, report.Converted, "the first run switches to incremental auto_vacuum")
+	assert.Zero(t, report.After.FreePages)
+	assert.Zero(t, report.After.WALBytes)
+	assert.Less(t, report.After.TotalBytes(), dry.Before.TotalBytes())
+
+	// Later runs free pages incrementally, without a full VACUUM.
+	_, err = conn.Exec("INSERT INTO blobs (data) VALUES (?)", strings.Repeat("y", 40000))
+	require.NoError(t, err)
+	_, err = conn.Exec("DELETE FROM blobs")
+	require.NoError(t, err)
+	report, err = Maintain(ctx, conn, false)
+	require.NoError(t, err)
+	assert.False(t, report.Converted)
+	assert.Zero(t, report.After.FreePages)
+
+	mem, err := Connect(WithInMemory(true))
+	require.NoError(t, err)
+	defer mem.Close()
+	report, err = Maintain(ctx, mem, false)
+	require.NoError(t, err)
+	assert.Empty(t, report.Path)
+	assert.Zero(t, report.Before.DBBytes)
+}
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.db")
+	conn, err := ConnectReadWrite(WithPath(path), WithWALAutocheckpoint(0))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Exec("CREATE TABLE blobs (data TEXT)")
+	require.NoError(t, err)
+	for range 50 {
+		_, err = conn.Exec("INSERT INTO blobs (data) VALUES (?)", strings.Repeat("x", 4000))
+		require.NoError(t, err)
+	}
+	assert.Positive(t, fileSize(path+"-wal"), "without automatic checkpoints the WAL grows")
+
+	cp, err := Checkpoint(context.Background(), conn)
+	require.NoError(t, err)
+	assert.False(t, cp.Busy)
+	assert.Zero(t, fileSize(path+"-wal"), "the WAL is truncated")
+}
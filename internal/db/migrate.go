@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"blacked/internal/db/models"
+	"blacked/internal/utils"
 
 	"github.com/rs/zerolog/log"
 )
@@ -54,6 +55,13 @@ CREATE TABLE IF NOT EXISTS entries (
     created_at  INTEGER,
     updated_at  INTEGER,
     deleted_at  INTEGER,
+    activated_at INTEGER,
+    reversed_host TEXT,
+    ip          TEXT,
+    categories  TEXT,
+    cidr        TEXT,
+    wildcard    INTEGER NOT NULL DEFAULT 0,
+    expires_at  INTEGER,
     UNIQUE (source_url, source)
 );
 
@@ -67,14 +75,129 @@ CREATE TABLE IF NOT EXISTS provider_processes (
     error       TEXT
 );
 
+CREATE TABLE IF NOT EXISTS allowlist (
+    id          TEXT PRIMARY KEY,
+    kind        TEXT NOT NULL,
+    pattern     TEXT NOT NULL,
+    reason      TEXT,
+    created_at  INTEGER,
+    UNIQUE (kind, pattern)
+);
+
+-- Regex and glob entries, matched in memory rather than by index.
+CREATE TABLE IF NOT EXISTS entry_patterns (
+    id          TEXT PRIMARY KEY,
+    process_id  TEXT,
+    source      TEXT NOT NULL,
+    kind        TEXT NOT NULL,
+    pattern     TEXT NOT NULL,
+    category    TEXT,
+    created_at  INTEGER,
+    updated_at  INTEGER,
+    deleted_at  INTEGER,
+    UNIQUE (source, kind, pattern)
+);
+
+CREATE TABLE IF NOT EXISTS watchlists (
+    id          TEXT PRIMARY KEY,
+    name        TEXT NOT NULL UNIQUE,
+    suffixes    TEXT,
+    keywords    TEXT,
+    brands      TEXT,
+    webhook_url TEXT,
+    created_at  INTEGER
+);
+
+-- Hosts already reported per watchlist, so each is only announced once.
+CREATE TABLE IF NOT EXISTS watchlist_seen (
+    watchlist_id  TEXT NOT NULL,
+    host          TEXT NOT NULL,
+    entry_id      TEXT,
+    first_seen_at INTEGER,
+    PRIMARY KEY (watchlist_id, host)
+);
+
+CREATE TABLE IF NOT EXISTS entry_hits (
+    entry_id     TEXT PRIMARY KEY,
+    hits         INTEGER NOT NULL DEFAULT 0,
+    first_hit_at INTEGER,
+    last_hit_at  INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           TEXT PRIMARY KEY,
+    name         TEXT NOT NULL,
+    prefix       TEXT NOT NULL,
+    key_hash     TEXT NOT NULL UNIQUE,
+    role         TEXT NOT NULL,
+    rate_limit   REAL NOT NULL DEFAULT 0,
+    created_at   INTEGER NOT NULL,
+    last_used_at INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS query_audit (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    queried_at INTEGER NOT NULL,
+    url        TEXT NOT NULL,
+    endpoint   TEXT NOT NULL,
+    hit        INTEGER NOT NULL DEFAULT 0,
+    match_type TEXT,
+    caller     TEXT,
+    remote_ip  TEXT,
+    latency_us INTEGER NOT NULL DEFAULT 0
+);
+
+-- Rollups served at /stats/timeseries: query and hit counts per interval,
+-- and active entries per source labelled with the source.
+CREATE TABLE IF NOT EXISTS stats_timeseries (
+    metric TEXT NOT NULL,
+    label  TEXT NOT NULL DEFAULT '',
+    bucket INTEGER NOT NULL, -- Unix seconds of the start of the interval
+    value  REAL NOT NULL,
+    PRIMARY KEY (metric, label, bucket)
+) WITHOUT ROWID;
+
+-- Single row holding the read-only maintenance mode of the instance.
+CREATE TABLE IF NOT EXISTS maintenance_state (
+    id         INTEGER PRIMARY KEY CHECK (id = 1),
+    read_only  INTEGER NOT NULL DEFAULT 0,
+    reason     TEXT,
+    since      INTEGER,
+    updated_at INTEGER
+);
+
+-- Structured events of provider runs (fetch result, parse counters,
+-- errors), keyed by the run's process ID.
+CREATE TABLE IF NOT EXISTS process_events (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    process_id TEXT NOT NULL,
+    provider   TEXT,
+    stage      TEXT NOT NULL,
+    level      TEXT NOT NULL,
+    message    TEXT NOT NULL,
+    fields     TEXT, -- JSON object
+    created_at INTEGER NOT NULL
+);
+
 -- Indexes for entries table
 CREATE INDEX IF NOT EXISTS idx_entries_domain ON entries(domain);
 CREATE INDEX IF NOT EXISTS idx_entries_host ON entries(host);
 CREATE INDEX IF NOT EXISTS idx_entries_source ON entries(source);
 CREATE INDEX IF NOT EXISTS idx_entries_source_url ON entries(source_url);
+-- Path lookups only ever want active entries, and most paths are "/" or
+-- empty on host feeds; the partial index leaves deleted rows out.
+CREATE INDEX IF NOT EXISTS idx_entries_path ON entries(path) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_entries_process_id ON entries(process_id);
+CREATE INDEX IF NOT EXISTS idx_entries_deleted_at ON entries(deleted_at) WHERE deleted_at IS NOT NULL;
 
 -- Indexes for sources
 CREATE INDEX IF NOT EXISTS idx_sources_provider ON sources(provider_id);
+
+CREATE INDEX IF NOT EXISTS idx_entry_hits_hits ON entry_hits(hits);
+CREATE INDEX IF NOT EXISTS idx_query_audit_queried_at ON query_audit(queried_at);
+CREATE INDEX IF NOT EXISTS idx_stats_timeseries_bucket ON stats_timeseries(bucket);
+CREATE INDEX IF NOT EXISTS idx_process_events_process_id ON process_events(process_id, id);
+CREATE INDEX IF NOT EXISTS idx_process_events_created_at ON process_events(created_at);
 `
 
 // MigrateSchema creates the new tables if they don't exist.
@@ -88,7 +211,316 @@ func MigrateSchema(db *sql.DB) error {
 		return fmt.Errorf("failed to execute new schema DDL: %w", err)
 	}
 
-	log.Trace().Msg("New schema tables ensured (providers, sources, entries, provider_processes)")
+	if err := migrateColumns(db, "entries", entryColumnMigrations); err != nil {
+		return err
+	}
+	if err := migrateColumns(db, "provider_processes", processColumnMigrations); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(entryIndexesDDL); err != nil {
+		return fmt.Errorf("failed to create entry indexes: %w", err)
+	}
+
+	if err := migrateEntryChanges(db); err != nil {
+		return err
+	}
+
+	log.Trace().Msg("New schema tables ensured (providers, sources, entries, entry_categories, entry_changes, provider_processes, allowlist, entry_patterns, watchlists, entry_hits, api_keys, query_audit, stats_timeseries, maintenance_state, process_events)")
+	return nil
+}
+
+// columnMigration adds a column to a table created by an older schema version.
+type columnMigration struct {
+	Column     string
+	Definition string
+	Backfill   string // Optional UPDATE run once after the column is added
+
+	// BackfillFunc is run once after the column is added, for values SQL can't compute.
+	BackfillFunc func(db *sql.DB) error
+}
+
+// entryColumnMigrations lists columns added to entries after the initial schema.
+var entryColumnMigrations = []columnMigration{
+	{
+		Column:     "activated_at",
+		Definition: "INTEGER",
+		Backfill:   "UPDATE entries SET activated_at = created_at WHERE activated_at IS NULL",
+	},
+	{
+		Column:       "reversed_host",
+		Definition:   "TEXT",
+		BackfillFunc: backfillHostKeys,
+	},
+	{
+		Column:       "ip",
+		Definition:   "TEXT",
+		BackfillFunc: backfillHostKeys,
+	},
+	{
+		Column:     "categories",
+		Definition: "TEXT", // JSON array; NULL for single-category entries
+	},
+	{
+		Column:     "cidr",
+		Definition: "TEXT", // Network of CIDR entries; NULL for URL entries
+	},
+	{
+		Column:     "wildcard",
+		Definition: "INTEGER NOT NULL DEFAULT 0", // 1 when the entry covers every subdomain of its host
+	},
+	{
+		Column:     "expires_at",
+		Definition: "INTEGER", // Unix nanos after which the purger deletes the entry; NULL never expires
+	},
+}
+
+// processColumnMigrations lists columns added to provider_processes after
+// the initial schema.
+var processColumnMigrations = []columnMigration{
+	{
+		Column:     "group_names",
+		Definition: "TEXT", // JSON array of the provider groups the process was started for
+	},
+	{
+		Column:     "runs",
+		Definition: "TEXT", // JSON array of the provider runs of the process
+	},
+}
+
+// entryIndexesDDL holds indexes on columns that may only exist after migrateColumns.
+const entryIndexesDDL = `
+CREATE INDEX IF NOT EXISTS idx_entries_source_activated ON entries(source, activated_at);
+CREATE INDEX IF NOT EXISTS idx_entries_reversed_host ON entries(reversed_host);
+CREATE INDEX IF NOT EXISTS idx_entries_ip ON entries(ip);
+CREATE INDEX IF NOT EXISTS idx_entries_cidr ON entries(cidr) WHERE cidr IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_entries_expires_at ON entries(expires_at) WHERE expires_at IS NOT NULL;
+
+-- entry_categories indexes the categories JSON array of multi-category
+-- entries; the triggers keep it in step with every write of the column.
+CREATE TABLE IF NOT EXISTS entry_categories (
+    entry_id TEXT NOT NULL,
+    category TEXT NOT NULL COLLATE NOCASE,
+    PRIMARY KEY (entry_id, category)
+);
+CREATE INDEX IF NOT EXISTS idx_entry_categories_category ON entry_categories(category);
+
+CREATE TRIGGER IF NOT EXISTS trg_entries_categories_insert AFTER INSERT ON entries
+WHEN NEW.categories IS NOT NULL
+BEGIN
+    INSERT OR IGNORE INTO entry_categories (entry_id, category)
+    SELECT NEW.id, value FROM json_each(NEW.categories);
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_entries_categories_update AFTER UPDATE OF categories ON entries
+WHEN NEW.categories IS NOT OLD.categories
+BEGIN
+    DELETE FROM entry_categories WHERE entry_id = NEW.id;
+    INSERT OR IGNORE INTO entry_categories (entry_id, category)
+    SELECT NEW.id, value FROM json_each(NEW.categories);
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_entries_categories_delete AFTER DELETE ON entries
+BEGIN
+    DELETE FROM entry_categories WHERE entry_id = OLD.id;
+END;
+`
+
+// entryChangesDDL holds the change feed replicas poll: one row per entry,
+// moved to a new seq whenever a replicated column of the entry changes or
+// the entry is removed. Refreshes that only bump updated_at, process_id and
+// expires_at are not changes.
+const entryChangesDDL = `
+CREATE TABLE IF NOT EXISTS entry_changes (
+    seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+    entry_id   TEXT NOT NULL UNIQUE,
+    changed_at INTEGER NOT NULL
+);
+
+-- Cursor of an event bus sink into the local change feed.
+CREATE TABLE IF NOT EXISTS eventbus_state (
+    sink       TEXT PRIMARY KEY,
+    cursor     INTEGER NOT NULL DEFAULT 0,
+    watermark  INTEGER NOT NULL DEFAULT 0,
+    updated_at INTEGER
+);
+
+-- Cursor of a replica into its primary's change feed.
+CREATE TABLE IF NOT EXISTS replication_state (
+    primary_url TEXT PRIMARY KEY,
+    cursor      INTEGER NOT NULL DEFAULT 0,
+    updated_at  INTEGER
+);
+
+CREATE TRIGGER IF NOT EXISTS trg_entries_changes_insert AFTER INSERT ON entries
+BEGIN
+    DELETE FROM entry_changes WHERE entry_id = NEW.id;
+    INSERT INTO entry_changes (entry_id, changed_at)
+    VALUES (NEW.id, CAST(unixepoch('subsec') * 1000000000 AS INTEGER));
+END;
+
+-- Recreated on every start, so databases of older versions watch the
+-- columns added since.
+DROP TRIGGER IF EXISTS trg_entries_changes_update;
+CREATE TRIGGER trg_entries_changes_update AFTER UPDATE ON entries
+WHEN NEW.deleted_at IS NOT OLD.deleted_at
+  OR NEW.category IS NOT OLD.category
+  OR NEW.categories IS NOT OLD.categories
+  OR NEW.confidence IS NOT OLD.confidence
+  OR NEW.activated_at IS NOT OLD.activated_at
+  OR NEW.source_url IS NOT OLD.source_url
+  OR NEW.scheme IS NOT OLD.scheme
+  OR NEW.host IS NOT OLD.host
+  OR NEW.path IS NOT OLD.path
+  OR NEW.raw_query IS NOT OLD.raw_query
+  OR NEW.wildcard IS NOT OLD.wildcard
+BEGIN
+    DELETE FROM entry_changes WHERE entry_id = NEW.id;
+    INSERT INTO entry_changes (entry_id, changed_at)
+    VALUES (NEW.id, CAST(unixepoch('subsec') * 1000000000 AS INTEGER));
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_entries_changes_delete AFTER DELETE ON entries
+BEGIN
+    DELETE FROM entry_changes WHERE entry_id = OLD.id;
+    INSERT INTO entry_changes (entry_id, changed_at)
+    VALUES (OLD.id, CAST(unixepoch('subsec') * 1000000000 AS INTEGER));
+END;
+`
+
+// migrateEntryChanges creates the change feed, seeding it with every
+// existing entry, oldest change first, when the table is new.
+func migrateEntryChanges(db *sql.DB) error {
+	var exists int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'entry_changes'").Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up entry_changes: %w", err)
+	}
+
+	if _, err := db.Exec(entryChangesDDL); err != nil {
+		return fmt.Errorf("failed to create entry change feed: %w", err)
+	}
+	if exists > 0 {
+		return nil
+	}
+
+	res, err := db.Exec(`
+		INSERT OR IGNORE INTO entry_changes (entry_id, changed_at)
+		SELECT id, MAX(COALESCE(updated_at, 0), COALESCE(deleted_at, 0)) AS changed_at
+		FROM entries ORDER BY changed_at, id`)
+	if err != nil {
+		return fmt.Errorf("failed to backfill entry change feed: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Info().Int64("entries", n).Msg("Seeded entry change feed")
+	}
+	return nil
+}
+
+// backfillHostKeys fills reversed_host and ip from host for rows written
+// before those columns existed. Columns that don't exist yet are left alone.
+func backfillHostKeys(db *sql.DB) error {
+	columns, err := tableColumns(db, "entries")
+	if err != nil {
+		return err
+	}
+	if !columns["reversed_host"] || !columns["ip"] {
+		// The other column is added next and runs this backfill again.
+		return nil
+	}
+
+	rows, err := db.Query("SELECT DISTINCT host FROM entries WHERE host IS NOT NULL AND host != '' AND reversed_host IS NULL")
+	if err != nil {
+		return err
+	}
+	var hosts []string
+	for rows.Next() {
+		var host string
+		if err := rows.Scan(&host); err != nil {
+			rows.Close()
+			return err
+		}
+		hosts = append(hosts, host)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("UPDATE entries SET reversed_host = ?, ip = ? WHERE host = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, host := range hosts {
+		if _, err := stmt.Exec(utils.ReverseHost(host), utils.HostIP(host), host); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// tableColumns returns the set of column names of table.
+func tableColumns(db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s columns: %w", table, err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid        int
+			name, typ  string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &defaultVal, &pk); err != nil {
+			return nil, fmt.Errorf("failed to scan %s columns: %w", table, err)
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate %s columns: %w", table, err)
+	}
+	return existing, nil
+}
+
+// migrateColumns adds any missing columns to table, running their backfill once.
+func migrateColumns(db *sql.DB, table string, migrations []columnMigration) error {
+	existing, err := tableColumns(db, table)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if existing[m.Column] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, m.Column, m.Definition)); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", table, m.Column, err)
+		}
+		if m.Backfill != "" {
+			if _, err := db.Exec(m.Backfill); err != nil {
+				return fmt.Errorf("failed to backfill column %s.%s: %w", table, m.Column, err)
+			}
+		}
+		if m.BackfillFunc != nil {
+			if err := m.BackfillFunc(db); err != nil {
+				return fmt.Errorf("failed to backfill column %s.%s: %w", table, m.Column, err)
+			}
+		}
+		log.Info().Str("table", table).Str("column", m.Column).Msg("Added missing column")
+	}
+
 	return nil
 }
 
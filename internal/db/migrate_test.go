@@ -15,7 +15,7 @@ func TestMigrateSchema(t *testing.T) {
 	err = MigrateSchema(db)
 	require.NoError(t, err)
 
-	tables := []string{"providers", "sources", "entries", "provider_processes"}
+	tables := []string{"providers", "sources", "entries", "provider_processes", "process_events"}
 	for _, tbl := range tables {
 		var name string
 		err := db.QueryRow(`SELECT name FROM sqlite_master WHERE type='table' AND name=?`, tbl).Scan(&name)
@@ -29,7 +29,11 @@ func TestMigrateSchema(t *testing.T) {
 		"idx_entries_host",
 		"idx_entries_source",
 		"idx_entries_source_url",
+		"idx_entries_path",
+		"idx_entries_process_id",
+		"idx_entries_deleted_at",
 		"idx_sources_provider",
+		"idx_process_events_process_id",
 	}
 	for _, idx := range indexes {
 		var name string
@@ -88,3 +92,35 @@ func TestFullMigration(t *testing.T) {
 	assert.GreaterOrEqual(t, providerCount, 5)
 	assert.GreaterOrEqual(t, sourceCount, 3)
 }
+
+func TestMigrateSchemaAddsMissingEntryColumns(t *testing.T) {
+	db, err := Connect(WithInMemory(true))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Entries table as created before activated_at existed.
+	_, err = db.Exec(`CREATE TABLE entries (
+		id TEXT PRIMARY KEY, process_id TEXT, scheme TEXT, domain TEXT, host TEXT,
+		sub_domains TEXT, path TEXT, raw_query TEXT, source_url TEXT, source TEXT NOT NULL,
+		category TEXT, confidence REAL DEFAULT 1.0, created_at INTEGER, updated_at INTEGER,
+		deleted_at INTEGER, UNIQUE (source_url, source))`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO entries (id, host, source_url, source, created_at, updated_at) VALUES ('a', 'sub.x.com', 'sub.x.com', 'test', 42, 42)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO entries (id, host, source_url, source, created_at, updated_at) VALUES ('b', '10.0.0.1', '10.0.0.1/a', 'test', 42, 42)`)
+	require.NoError(t, err)
+
+	require.NoError(t, MigrateSchema(db))
+	require.NoError(t, MigrateSchema(db), "migration must be idempotent")
+
+	var activatedAt int64
+	require.NoError(t, db.QueryRow(`SELECT activated_at FROM entries WHERE id = 'a'`).Scan(&activatedAt))
+	assert.Equal(t, int64(42), activatedAt, "existing rows are backfilled from created_at")
+
+	var reversedHost, ip string
+	require.NoError(t, db.QueryRow(`SELECT reversed_host, ip FROM entries WHERE id = 'a'`).Scan(&reversedHost, &ip))
+	assert.Equal(t, "com.x.sub.", reversedHost)
+	assert.Empty(t, ip)
+	require.NoError(t, db.QueryRow(`SELECT ip FROM entries WHERE id = 'b'`).Scan(&ip))
+	assert.Equal(t, "10.0.0.1", ip, "IP literal hosts are backfilled into ip")
+}
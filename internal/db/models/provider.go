@@ -26,5 +26,6 @@ var ProviderSeed = []Provider{
 	{ID: "openphish", Name: "OpenPhish", Description: "OpenPhish phishing feed", TrustScore: 0.75},
 	{ID: "alienvault", Name: "AlienVault OTX", Description: "Open Threat Exchange", TrustScore: 0.80},
 	{ID: "phishtank", Name: "PhishTank", Description: "Community phishing verification", TrustScore: 0.70},
+	{ID: "abuseipdb", Name: "AbuseIPDB", Description: "Community-reported abusive IPs", TrustScore: 0.70},
 	{ID: "oisd", Name: "OISD", Description: "One Unified Hosts Blocklist", TrustScore: 0.65},
 }
@@ -45,5 +45,8 @@ var SourceSeed = []Source{
 	{ID: "urlhaus-online", ProviderID: "abuse-ch", Name: "URLHaus Online", SourceURL: "https://urlhaus.abuse.ch/downloads/text/", Type: SourceTypeFlat, Enabled: true, UpdateInterval: sql.NullInt64{Int64: 7200, Valid: true}},    // 2 hours
 	{ID: "openphish-feed", ProviderID: "openphish", Name: "OpenPhish Feed", SourceURL: "https://openphish.com/feed.txt", Type: SourceTypeFlat, Enabled: true, UpdateInterval: sql.NullInt64{Int64: 14400, Valid: true}},     // 4 hours
 	{ID: "phishtank-online-valid", ProviderID: "phishtank", Name: "PhishTank Online Valid", SourceURL: "https://data.phishtank.com/data/online-valid.json", Type: SourceTypeJSON, Enabled: true, UpdateInterval: sql.NullInt64{Int64: 14400, Valid: true}}, // 4 hours
+	{ID: "abuseipdb-blacklist", ProviderID: "abuseipdb", Name: "AbuseIPDB Blacklist", SourceURL: "https://api.abuseipdb.com/api/v2/blacklist", Type: SourceTypeAPI, Enabled: true, UpdateInterval: sql.NullInt64{Int64: 21600, Valid: true}}, // 6 hours
+	{ID: "feodotracker-ipblocklist", ProviderID: "abuse-ch", Name: "Feodo Tracker IP Blocklist", SourceURL: "https://feodotracker.abuse.ch/downloads/ipblocklist.txt", Type: SourceTypeFlat, Enabled: true, UpdateInterval: sql.NullInt64{Int64: 3600, Valid: true}}, // hourly
+	{ID: "sslbl-ipblacklist", ProviderID: "abuse-ch", Name: "SSLBL IP Blacklist", SourceURL: "https://sslbl.abuse.ch/blacklist/sslipblacklist.txt", Type: SourceTypeFlat, Enabled: true, UpdateInterval: sql.NullInt64{Int64: 7200, Valid: true}}, // 2 hours
 	{ID: "spamhaus-drop", ProviderID: "spamhaus", Name: "Spamhaus DROP", SourceURL: "https://www.spamhaus.org/drop/drop.txt", Type: SourceTypeFlat, Enabled: true, UpdateInterval: sql.NullInt64{Int64: 86400, Valid: true}}, // daily
 }
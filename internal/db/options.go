@@ -1,9 +1,75 @@
 package db
 
+import (
+	"blacked/internal/config"
+	"fmt"
+	"net/url"
+	"time"
+)
+
 type dbOptions struct {
 	isTesting   bool
 	isInWALMode bool
 	inMemory    bool
+	readOnly    bool   // Open the file with mode=ro; ignored in memory
+	path        string // Database file; empty is blacked.db in the working directory
+
+	readConns       int           // Read pool size; 0 is defaultReadConns
+	readIdleConns   int           // Idle readers kept; 0 is defaultReadIdleConns
+	connMaxIdleTime time.Duration // 0 keeps idle readers open
+
+	// walAutocheckpoint is the WAL size in pages that triggers a checkpoint
+	// on commit, 0 disabling it; nil leaves SQLite's default of 1000.
+	walAutocheckpoint *int
+}
+
+// newOptions applies options over the defaults: a file database in WAL mode.
+func newOptions(options []Option) dbOptions {
+	opts := dbOptions{isInWALMode: true}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	return opts
+}
+
+// dsn is the database the options select, without connection parameters.
+func (o *dbOptions) dsn() string {
+	switch {
+	case o.inMemory:
+		return memoryDB
+	case o.isTesting:
+		return testDB
+	case o.path != "":
+		return o.path
+	default:
+		return dbName
+	}
+}
+
+// connectionString returns the DSN of the options' database with the
+// PRAGMAs the driver runs on every connection it opens. Setting them with a
+// single Exec would only reach whichever connection of the pool ran it.
+func (o *dbOptions) connectionString() string {
+	pragmas := []string{
+		"busy_timeout(5000)", // Wait out the remaining write contention
+		"foreign_keys(1)",
+		"synchronous(NORMAL)",
+		"cache_size(-10000)", // 10MB page cache
+	}
+	if o.isInWALMode && !o.inMemory {
+		pragmas = append(pragmas, "journal_mode(WAL)")
+		if o.walAutocheckpoint != nil {
+			pragmas = append(pragmas, fmt.Sprintf("wal_autocheckpoint(%d)", *o.walAutocheckpoint))
+		}
+	}
+	dsn, params := o.dsn(), url.Values{"_pragma": pragmas}
+	if o.readOnly && !o.inMemory {
+		// SQLite only reads the mode of a file: URI; the driver keeps the
+		// parameters of those and hands them to SQLite.
+		dsn = "file:" + (&url.URL{Path: dsn}).EscapedPath()
+		params.Set("mode", "ro")
+	}
+	return dsn + "?" + params.Encode()
 }
 
 func (o *dbOptions) GetIsTesting() bool {
@@ -28,8 +94,51 @@ func WithInMemory(state bool) Option {
 	}
 }
 
+// WithPath opens the database file at path instead of blacked.db in the
+// working directory.
+func WithPath(path string) Option {
+	return func(opts *dbOptions) {
+		opts.path = path
+	}
+}
+
 func WithWALMode(state bool) Option {
 	return func(opts *dbOptions) {
 		opts.isInWALMode = state
 	}
 }
+
+// WithReadPool sizes the read-only connection pool: at most maxOpen
+// concurrent readers, maxIdle of them kept open between queries. Zero keeps
+// the default of either.
+func WithReadPool(maxOpen, maxIdle int) Option {
+	return func(opts *dbOptions) {
+		opts.readConns = maxOpen
+		opts.readIdleConns = maxIdle
+	}
+}
+
+// WithConnMaxIdleTime closes pooled connections idle for longer than d.
+func WithConnMaxIdleTime(d time.Duration) Option {
+	return func(opts *dbOptions) {
+		opts.connMaxIdleTime = d
+	}
+}
+
+// WithWALAutocheckpoint checkpoints the WAL on the commit that grows it past
+// pages pages; 0 turns automatic checkpoints off.
+func WithWALAutocheckpoint(pages int) Option {
+	return func(opts *dbOptions) {
+		opts.walAutocheckpoint = &pages
+	}
+}
+
+// WithConfig applies the pool and checkpoint settings of a [Database]
+// config section.
+func WithConfig(cfg config.DatabaseConfig) Option {
+	return func(opts *dbOptions) {
+		WithReadPool(cfg.MaxReadConns, cfg.MaxIdleReadConns)(opts)
+		WithConnMaxIdleTime(cfg.ConnMaxIdleTime)(opts)
+		WithWALAutocheckpoint(cfg.WALAutocheckpoint)(opts)
+	}
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"blacked/internal/collector"
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// maxCachedStatements bounds the statements kept per database. The match
+// queries come in a few dozen shapes (query type, enrichment, URL variants,
+// host depth); statements past the bound run unprepared.
+const maxCachedStatements = 256
+
+// Statements holds the prepared statements of the hot match queries of one
+// connection pool, keyed by their SQL. database/sql prepares a statement
+// once per pool connection and reuses it, instead of SQLite compiling the
+// SQL again on every query. The pools of InitializeDB own one each, closed
+// by Close before the pool.
+type Statements struct {
+	db     *sql.DB
+	mu     sync.RWMutex
+	stmts  map[string]*sql.Stmt
+	closed bool
+}
+
+// NewStatements returns an empty statement cache of db. The caller closes
+// it before db.
+func NewStatements(db *sql.DB) *Statements {
+	return &Statements{db: db, stmts: make(map[string]*sql.Stmt)}
+}
+
+// GetStatements returns the statement cache of conn when it is one of the
+// pools of InitializeDB, nil otherwise.
+func GetStatements(conn *sql.DB) *Statements {
+	switch {
+	case conn == nil:
+		return nil
+	case conn == instance.readDB:
+		return instance.readStmts
+	case conn == instance.writeDB:
+		return instance.writeStmts
+	default:
+		return nil
+	}
+}
+
+// QueryContext runs query with its prepared statement, preparing it on
+// first use. A closed or full cache runs query unprepared.
+func (s *Statements) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	stmt, err := s.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if stmt == nil {
+		return s.db.QueryContext(ctx, query, args...)
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+// prepare returns the statement of query, nil when the cache is full or
+// closed.
+func (s *Statements) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	s.mu.RLock()
+	stmt, closed := s.stmts[query], s.closed
+	s.mu.RUnlock()
+	if stmt != nil {
+		countStatementLookup("hit")
+		return stmt, nil
+	}
+	if closed {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stmt = s.stmts[query]; stmt != nil {
+		countStatementLookup("hit") // Prepared while we waited
+		return stmt, nil
+	}
+	if s.closed {
+		return nil, nil
+	}
+	if len(s.stmts) >= maxCachedStatements {
+		countStatementLookup("full")
+		return nil, nil
+	}
+
+	// The context only bounds the preparation; the statement outlives it.
+	stmt, err := s.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	s.stmts[query] = stmt
+	countStatementLookup("miss")
+	return stmt, nil
+}
+
+// Len returns the number of cached statements.
+func (s *Statements) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.stmts)
+}
+
+// Close closes every cached statement; later queries run unprepared.
+func (s *Statements) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for query, stmt := range s.stmts {
+		if stmt != nil {
+			if err := stmt.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		delete(s.stmts, query)
+	}
+	s.closed = true
+	return errors.Join(errs...)
+}
+
+func countStatementLookup(result string) {
+	if mc, _ := collector.GetMetricsCollector(); mc != nil {
+		mc.IncrementStatementCacheLookup(result)
+	}
+}
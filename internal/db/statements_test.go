@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatements(t *testing.T) {
+	conn, err := Connect(WithInMemory(true))
+	require.NoError(t, err)
+	defer conn.Close()
+	stmts := NewStatements(conn)
+	defer stmts.Close()
+
+	ctx := context.Background()
+	count := func(query string) int {
+		rows, err := stmts.QueryContext(ctx, query)
+		require.NoError(t, err)
+		defer rows.Close()
+		n := 0
+		for rows.Next() {
+			n++
+		}
+		require.NoError(t, rows.Err())
+		return n
+	}
+
+	assert.Equal(t, 1, count("SELECT 1"))
+	assert.Equal(t, 1, count("SELECT 1"))
+	assert.Equal(t, 1, stmts.Len(), "a repeated query is prepared once")
+
+	// A full cache runs new statements unprepared.
+	stmts.mu.Lock()
+	for i := range maxCachedStatements {
+		stmts.stmts[fmt.Sprintf("filler %d", i)] = nil
+	}
+	stmts.mu.Unlock()
+	assert.Equal(t, 1, count("SELECT 2"))
+	assert.Equal(t, maxCachedStatements+1, stmts.Len())
+
+	require.NoError(t, stmts.Close())
+	assert.Zero(t, stmts.Len())
+	assert.Equal(t, 1, count("SELECT 1"), "a closed cache runs queries unprepared")
+	assert.Zero(t, stmts.Len())
+}
+
+func TestGetStatements(t *testing.T) {
+	ResetForTesting()
+	t.Cleanup(ResetForTesting)
+	InitializeDB(WithPath(t.TempDir() + "/blacked.db"))
+
+	readDB, err := GetReadDB()
+	require.NoError(t, err)
+	writeDB, err := GetWriteDB()
+	require.NoError(t, err)
+	assert.NotNil(t, GetStatements(readDB))
+	assert.NotNil(t, GetStatements(writeDB))
+	assert.NotSame(t, GetStatements(readDB), GetStatements(writeDB))
+
+	other, err := Connect(WithInMemory(true))
+	require.NoError(t, err)
+	defer other.Close()
+	assert.Nil(t, GetStatements(other), "only the pools of InitializeDB have a cache")
+
+	stmts := GetStatements(readDB)
+	rows, err := stmts.QueryContext(context.Background(), "SELECT COUNT(*) FROM entries")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+	require.Equal(t, 1, stmts.Len())
+
+	require.NoError(t, Close())
+	assert.Zero(t, stmts.Len(), "closing the pools closes their statements")
+	assert.Nil(t, GetStatements(readDB))
+}
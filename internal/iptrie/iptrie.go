@@ -0,0 +1,100 @@
+// Package iptrie indexes IP networks in a binary radix tree, so the networks
+// containing an address are found in at most 32 (IPv4) or 128 (IPv6) steps
+// however many networks are stored.
+package iptrie
+
+import "net/netip"
+
+type node[V any] struct {
+	children [2]*node[V]
+	values   []V // Values of the network ending at this node
+}
+
+// Trie maps IP networks to values. IPv4 and IPv4-mapped IPv6 addresses share
+// the IPv4 tree. It is not safe for concurrent writes; build it, then share
+// it read-only.
+type Trie[V any] struct {
+	v4, v6 node[V]
+	size   int
+}
+
+// New returns an empty Trie.
+func New[V any]() *Trie[V] {
+	return &Trie[V]{}
+}
+
+// Len returns the number of values inserted.
+func (t *Trie[V]) Len() int {
+	return t.size
+}
+
+// Insert adds value under prefix; host bits of prefix are ignored and
+// invalid prefixes are skipped.
+func (t *Trie[V]) Insert(prefix netip.Prefix, value V) {
+	if !prefix.IsValid() {
+		return
+	}
+	addr, bits := unmap(prefix)
+	n := t.root(addr)
+	b := addr.AsSlice()
+	for i := range bits {
+		bit := bitAt(b, i)
+		if n.children[bit] == nil {
+			n.children[bit] = &node[V]{}
+		}
+		n = n.children[bit]
+	}
+	n.values = append(n.values, value)
+	t.size++
+}
+
+// Lookup returns the values of every network containing addr, the most
+// specific network first.
+func (t *Trie[V]) Lookup(addr netip.Addr) []V {
+	addr = addr.Unmap()
+	if !addr.IsValid() {
+		return nil
+	}
+
+	n := t.root(addr)
+	b := addr.AsSlice()
+	var found [][]V
+	for i := 0; n != nil; i++ {
+		if len(n.values) > 0 {
+			found = append(found, n.values)
+		}
+		if i == addr.BitLen() {
+			break
+		}
+		n = n.children[bitAt(b, i)]
+	}
+
+	var values []V
+	for i := len(found) - 1; i >= 0; i-- {
+		values = append(values, found[i]...)
+	}
+	return values
+}
+
+func (t *Trie[V]) root(addr netip.Addr) *node[V] {
+	if addr.Is4() {
+		return &t.v4
+	}
+	return &t.v6
+}
+
+// unmap returns the masked address of prefix and its length, with
+// IPv4-mapped IPv6 networks turned into their IPv4 form.
+func unmap(prefix netip.Prefix) (netip.Addr, int) {
+	addr, bits := prefix.Addr(), prefix.Bits()
+	if addr.Is4In6() {
+		addr, bits = addr.Unmap(), max(bits-96, 0)
+	}
+	masked, _ := addr.Prefix(bits)
+	return masked.Addr(), bits
+}
+
+// bitAt returns bit i of b, most significant first.
+func bitAt(b []byte, i int) int {
+	return int(b[i/8]>>(7-i%8)) & 1
+}
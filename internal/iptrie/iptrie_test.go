@@ -0,0 +1,27 @@
+package iptrie
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	trie := New[string]()
+	trie.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trie.Insert(netip.MustParsePrefix("10.1.2.99/24"), "b") // Host bits are ignored
+	trie.Insert(netip.MustParsePrefix("10.1.2.0/24"), "c")
+	trie.Insert(netip.MustParsePrefix("::ffff:192.0.2.0/120"), "d")
+	trie.Insert(netip.MustParsePrefix("2001:db8::/32"), "e")
+	trie.Insert(netip.Prefix{}, "skipped")
+	assert.Equal(t, 5, trie.Len())
+
+	assert.Equal(t, []string{"b", "c", "a"}, trie.Lookup(netip.MustParseAddr("10.1.2.3")), "most specific first")
+	assert.Equal(t, []string{"a"}, trie.Lookup(netip.MustParseAddr("10.200.0.1")))
+	assert.Empty(t, trie.Lookup(netip.MustParseAddr("11.0.0.1")))
+	assert.Equal(t, []string{"d"}, trie.Lookup(netip.MustParseAddr("192.0.2.10")), "mapped networks share the IPv4 tree")
+	assert.Equal(t, []string{"d"}, trie.Lookup(netip.MustParseAddr("::ffff:192.0.2.10")))
+	assert.Equal(t, []string{"e"}, trie.Lookup(netip.MustParseAddr("2001:db8:1::1")))
+	assert.Empty(t, trie.Lookup(netip.Addr{}))
+}
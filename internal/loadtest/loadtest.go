@@ -0,0 +1,273 @@
+package loadtest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrInvalidRPS      = errors.New("rps must be greater than zero")
+	ErrInvalidDuration = errors.New("duration must be greater than zero")
+	ErrInvalidMissMix  = errors.New("miss ratio must be between 0 and 1")
+	ErrNoURLs          = errors.New("no URLs to query")
+	ErrReadURLsFile    = errors.New("failed to read URLs file")
+)
+
+// Options configures a load test run against the query API.
+type Options struct {
+	Target      string        // Base server URL, e.g. http://localhost:8082
+	Endpoint    string        // Query endpoint under /api/v1: "check" or "hit"
+	RPS         int           // Target requests per second
+	Duration    time.Duration // Total run time
+	Concurrency int           // Number of request workers
+	MissRatio   float64       // Fraction of requests sent with a synthetic, never-listed URL
+	URLs        []string      // URLs expected to hit
+	Timeout     time.Duration // Per-request timeout
+}
+
+// Report summarises a finished load test.
+type Report struct {
+	Requests     int64          `json:"requests"`
+	Errors       int64          `json:"errors"`
+	Dropped      int64          `json:"dropped"`
+	HitRequests  int64          `json:"hit_requests"`
+	MissRequests int64          `json:"miss_requests"`
+	StatusCodes  map[int]int64  `json:"status_codes"`
+	Elapsed      time.Duration  `json:"elapsed"`
+	AchievedRPS  float64        `json:"achieved_rps"`
+	ErrorRate    float64        `json:"error_rate"`
+	Latency      LatencySummary `json:"latency"`
+}
+
+// LatencySummary holds latency percentiles of successful and failed requests.
+type LatencySummary struct {
+	Min  time.Duration `json:"min"`
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P95  time.Duration `json:"p95"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+}
+
+type result struct {
+	latency   time.Duration
+	status    int
+	failed    bool
+	cancelled bool // cut off by the end of the run, not counted
+}
+
+// LoadURLs reads one URL per line, skipping blanks and # comments.
+func LoadURLs(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Err(err).Str("path", path).Msg("Failed to open URLs file")
+		return nil, ErrReadURLsFile
+	}
+	defer f.Close()
+
+	return readURLs(f)
+}
+
+func readURLs(r io.Reader) ([]string, error) {
+	var urls []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Err(err).Msg("Failed to scan URLs file")
+		return nil, ErrReadURLsFile
+	}
+	return urls, nil
+}
+
+// Run drives the query API at the configured rate until the duration elapses
+// or ctx is cancelled. Ticks that find every worker busy are counted as dropped
+// rather than queued, so the reported latency is not skewed by client backlog.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &http.Transport{
+			MaxIdleConns:        opts.Concurrency,
+			MaxIdleConnsPerHost: opts.Concurrency,
+		},
+	}
+	endpoint := strings.TrimRight(opts.Target, "/") + "/api/v1/" + opts.Endpoint + "?url="
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	jobs := make(chan bool, opts.Concurrency) // true = miss request
+	results := make([][]result, opts.Concurrency)
+	var hits, misses, dropped atomic.Int64
+
+	var wg sync.WaitGroup
+	for i := range opts.Concurrency {
+		wg.Go(func() {
+			rng := rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), uint64(i)))
+			for miss := range jobs {
+				var target string
+				if miss {
+					target = fmt.Sprintf("https://loadtest-miss-%016x.invalid/%x", rng.Uint64(), rng.Uint32())
+				} else {
+					target = opts.URLs[rng.IntN(len(opts.URLs))]
+				}
+				results[i] = append(results[i], doRequest(ctx, client, endpoint+url.QueryEscape(target)))
+			}
+		})
+	}
+
+	rng := rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), 0))
+	ticker := time.NewTicker(time.Second / time.Duration(opts.RPS))
+	defer ticker.Stop()
+
+	start := time.Now()
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+			miss := rng.Float64() < opts.MissRatio
+			select {
+			case jobs <- miss:
+				if miss {
+					misses.Add(1)
+				} else {
+					hits.Add(1)
+				}
+			default:
+				dropped.Add(1)
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	report := buildReport(slices.Concat(results...), time.Since(start))
+	report.HitRequests = hits.Load()
+	report.MissRequests = misses.Load()
+	report.Dropped = dropped.Load()
+	return report, nil
+}
+
+func (o *Options) validate() error {
+	if o.RPS <= 0 {
+		return ErrInvalidRPS
+	}
+	if o.Duration <= 0 {
+		return ErrInvalidDuration
+	}
+	if o.MissRatio < 0 || o.MissRatio > 1 {
+		return ErrInvalidMissMix
+	}
+	if len(o.URLs) == 0 && o.MissRatio < 1 {
+		return ErrNoURLs
+	}
+	if o.Endpoint == "" {
+		o.Endpoint = "check"
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 64
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 5 * time.Second
+	}
+	return nil
+}
+
+func doRequest(ctx context.Context, client *http.Client, target string) result {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return result{failed: true}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		if ctx.Err() != nil {
+			return result{latency: latency, cancelled: true}
+		}
+		return result{latency: latency, failed: true}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	// 404 is the API's "not listed" answer for hit queries, not a failure.
+	failed := resp.StatusCode >= 500 || (resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound)
+	return result{latency: latency, status: resp.StatusCode, failed: failed}
+}
+
+func buildReport(results []result, elapsed time.Duration) *Report {
+	report := &Report{
+		StatusCodes: make(map[int]int64),
+		Elapsed:     elapsed,
+	}
+
+	latencies := make([]time.Duration, 0, len(results))
+	var total time.Duration
+	for _, r := range results {
+		if r.cancelled {
+			continue
+		}
+		report.Requests++
+		if r.failed {
+			report.Errors++
+		}
+		report.StatusCodes[r.status]++
+		latencies = append(latencies, r.latency)
+		total += r.latency
+	}
+
+	if report.Requests == 0 {
+		return report
+	}
+
+	slices.Sort(latencies)
+	report.AchievedRPS = float64(report.Requests) / elapsed.Seconds()
+	report.ErrorRate = float64(report.Errors) / float64(report.Requests)
+	report.Latency = LatencySummary{
+		Min:  latencies[0],
+		Mean: total / time.Duration(len(latencies)),
+		P50:  percentile(latencies, 50),
+		P90:  percentile(latencies, 90),
+		P95:  percentile(latencies, 95),
+		P99:  percentile(latencies, 99),
+		Max:  latencies[len(latencies)-1],
+	}
+	return report
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	rank = max(0, min(rank, len(sorted)-1))
+	return sorted[rank]
+}
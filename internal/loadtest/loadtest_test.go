@@ -0,0 +1,72 @@
+package loadtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	assert.Equal(t, 50*time.Millisecond, percentile(sorted, 50))
+	assert.Equal(t, 99*time.Millisecond, percentile(sorted, 99))
+	assert.Equal(t, 100*time.Millisecond, percentile(sorted, 100))
+	assert.Equal(t, time.Duration(0), percentile(nil, 50))
+}
+
+func TestReadURLs(t *testing.T) {
+	urls, err := readURLs(strings.NewReader("# comment\nhttps://a.example\n\n  b.example  \n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://a.example", "b.example"}, urls)
+}
+
+func TestRunHitMissMix(t *testing.T) {
+	var served atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served.Add(1)
+		if strings.Contains(r.URL.Query().Get("url"), "loadtest-miss") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	report, err := Run(context.Background(), Options{
+		Target:      srv.URL,
+		Endpoint:    "hit",
+		RPS:         200,
+		Duration:    300 * time.Millisecond,
+		Concurrency: 4,
+		MissRatio:   0.5,
+		URLs:        []string{"https://listed.example/path"},
+	})
+	require.NoError(t, err)
+
+	assert.Positive(t, report.Requests)
+	assert.Zero(t, report.Errors, "404 misses must not count as errors")
+	assert.Equal(t, report.Requests, report.StatusCodes[http.StatusOK]+report.StatusCodes[http.StatusNotFound])
+	assert.LessOrEqual(t, report.Latency.P50, report.Latency.P99)
+}
+
+func TestRunValidation(t *testing.T) {
+	_, err := Run(context.Background(), Options{RPS: 0, Duration: time.Second})
+	assert.ErrorIs(t, err, ErrInvalidRPS)
+
+	_, err = Run(context.Background(), Options{RPS: 1, Duration: time.Second})
+	assert.ErrorIs(t, err, ErrNoURLs)
+
+	_, err = Run(context.Background(), Options{RPS: 1, Duration: time.Second, MissRatio: 2})
+	assert.ErrorIs(t, err, ErrInvalidMissMix)
+}
@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"blacked/internal/config"
+	"blacked/internal/psl"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	privacyMu      sync.RWMutex
+	privacyLoaded  bool
+	privacyEnabled bool
+	privacySalt    []byte
+)
+
+// SetURLPrivacy overrides the [APP] log_privacy and log_salt settings.
+// An empty salt picks a random one.
+func SetURLPrivacy(enabled bool, salt string) {
+	privacyMu.Lock()
+	defer privacyMu.Unlock()
+	privacyEnabled = enabled
+	privacySalt = saltBytes(salt)
+	privacyLoaded = true
+}
+
+// URLPrivacyEnabled reports whether RedactURL hides queried URLs.
+func URLPrivacyEnabled() bool {
+	enabled, _ := urlPrivacy()
+	return enabled
+}
+
+// RedactURL returns what may be logged for a URL a client queried. In
+// privacy mode that is the registered domain (eTLD+1) and a salted hash of
+// the full URL, e.g. "bad.com#9f86d081884c7d65": enough to correlate
+// repeated lookups without recording browsing data. Otherwise raw is returned.
+func RedactURL(raw string) string {
+	enabled, salt := urlPrivacy()
+	if !enabled || raw == "" {
+		return raw
+	}
+
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(raw))
+	return redactedHost(raw) + "#" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// RedactQuery redacts every value of a raw query string, keeping its keys.
+func RedactQuery(rawQuery string) string {
+	if rawQuery == "" || !URLPrivacyEnabled() {
+		return rawQuery
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return RedactURL(rawQuery)
+	}
+	for key, vals := range values {
+		for i, v := range vals {
+			vals[i] = RedactURL(v)
+		}
+		values[key] = vals
+	}
+	return values.Encode()
+}
+
+func urlPrivacy() (bool, []byte) {
+	privacyMu.RLock()
+	if privacyLoaded {
+		defer privacyMu.RUnlock()
+		return privacyEnabled, privacySalt
+	}
+	privacyMu.RUnlock()
+
+	privacyMu.Lock()
+	defer privacyMu.Unlock()
+	if !privacyLoaded {
+		app := config.GetConfig().APP
+		privacyEnabled = app.LogPrivacyEnabled()
+		privacySalt = saltBytes(app.LogSalt)
+		privacyLoaded = true
+	}
+	return privacyEnabled, privacySalt
+}
+
+func saltBytes(salt string) []byte {
+	if salt != "" {
+		return []byte(salt)
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Warn().Err(err).Msg("Failed to generate log salt, hashes are unsalted")
+	}
+	return b
+}
+
+// redactedHost returns the registered domain of raw, "ip" for IP hosts and
+// "-" when no host can be parsed.
+func redactedHost(raw string) string {
+	link := strings.TrimSpace(raw)
+	if !strings.Contains(link, "://") && !strings.HasPrefix(link, "//") {
+		link = "//" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.Hostname() == "" {
+		return "-"
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if net.ParseIP(host) != nil {
+		return "ip"
+	}
+	if domain, err := psl.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return "-"
+}
@@ -0,0 +1,38 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactURL(t *testing.T) {
+	SetURLPrivacy(false, "")
+	assert.Equal(t, "https://login.bad.co.uk/a?b=c", RedactURL("https://login.bad.co.uk/a?b=c"))
+
+	SetURLPrivacy(true, "pepper")
+	defer SetURLPrivacy(false, "")
+
+	redacted := RedactURL("https://login.bad.co.uk/a?b=c")
+	assert.True(t, strings.HasPrefix(redacted, "bad.co.uk#"), redacted)
+	assert.NotContains(t, redacted, "login")
+	assert.Equal(t, redacted, RedactURL("https://login.bad.co.uk/a?b=c"), "hash is stable for a salt")
+	assert.NotEqual(t, redacted, RedactURL("https://login.bad.co.uk/other"))
+
+	assert.True(t, strings.HasPrefix(RedactURL("http://10.0.0.1/x"), "ip#"))
+	assert.True(t, strings.HasPrefix(RedactURL("evil.com"), "evil.com#"))
+	assert.Equal(t, "", RedactURL(""))
+
+	SetURLPrivacy(true, "other-salt")
+	assert.NotEqual(t, redacted, RedactURL("https://login.bad.co.uk/a?b=c"), "hash depends on the salt")
+}
+
+func TestRedactQuery(t *testing.T) {
+	SetURLPrivacy(true, "pepper")
+	defer SetURLPrivacy(false, "")
+
+	q := RedactQuery("url=https%3A%2F%2Fsecret.example.com%2Fpath")
+	assert.NotContains(t, q, "secret")
+	assert.True(t, strings.HasPrefix(q, "url=example.com%23"), q)
+}
@@ -0,0 +1,184 @@
+// Package pagination parses the paging, sorting and filtering query
+// parameters shared by every list endpoint and builds their pages.
+//
+// A list takes limit, cursor and sort: cursor is the opaque next_cursor of the
+// previous page, and sort names a field, prefixed with "-" for descending
+// order. Every page carries its items, the next_cursor of the following page
+// (omitted on the last one) and an estimate of the total matching items.
+package pagination
+
+import (
+	"cmp"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidLimit  = errors.New("invalid limit")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort")
+)
+
+// Options are the paging rules of one list endpoint.
+type Options struct {
+	DefaultLimit int
+	MaxLimit     int
+	// Sorts are the accepted sort fields; the first, optionally prefixed
+	// with "-", is the default. Empty lists reject any sort parameter.
+	Sorts []string
+}
+
+// Params are the paging parameters of one request.
+type Params struct {
+	Limit int
+	After string // Decoded cursor; the key the page starts after
+	Sort  string
+	Desc  bool
+}
+
+// Page is one page of a list.
+type Page[T any] struct {
+	Items         []T    `json:"items"`
+	NextCursor    string `json:"next_cursor,omitempty"`
+	TotalEstimate int    `json:"total_estimate"`
+}
+
+// Parse reads limit, cursor and sort from values. The legacy after
+// parameter is taken as an already decoded cursor.
+func Parse(values url.Values, opts Options) (Params, error) {
+	p := Params{Limit: opts.DefaultLimit}
+
+	if param := values.Get("limit"); param != "" {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit <= 0 || limit > opts.MaxLimit {
+			return Params{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidLimit, opts.MaxLimit)
+		}
+		p.Limit = limit
+	}
+
+	if param := values.Get("cursor"); param != "" {
+		after, err := DecodeCursor(param)
+		if err != nil {
+			return Params{}, err
+		}
+		p.After = after
+	} else {
+		p.After = values.Get("after")
+	}
+
+	if len(opts.Sorts) > 0 {
+		p.Sort, p.Desc = strings.CutPrefix(opts.Sorts[0], "-")
+	}
+	if param := values.Get("sort"); param != "" {
+		field, desc := strings.CutPrefix(param, "-")
+		if !slices.ContainsFunc(opts.Sorts, func(s string) bool { return strings.TrimPrefix(s, "-") == field }) {
+			return Params{}, fmt.Errorf("%w: sort must be one of %s", ErrInvalidSort, sortNames(opts.Sorts))
+		}
+		p.Sort, p.Desc = field, desc
+	}
+	return p, nil
+}
+
+func sortNames(sorts []string) string {
+	if len(sorts) == 0 {
+		return "none"
+	}
+	names := make([]string, len(sorts))
+	for i, s := range sorts {
+		names[i] = strings.TrimPrefix(s, "-")
+	}
+	return strings.Join(names, ", ")
+}
+
+// EncodeCursor turns a page key into the opaque cursor clients pass back.
+func EncodeCursor(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// DecodeCursor returns the page key of a cursor made by EncodeCursor.
+func DecodeCursor(cursor string) (string, error) {
+	key, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(key) == 0 {
+		return "", fmt.Errorf("%w: pass the next_cursor of the previous page", ErrInvalidCursor)
+	}
+	return string(key), nil
+}
+
+// NewPage builds a page from rows fetched with limit+1 by keyset, the extra
+// row only telling whether another page follows. key returns the keyset
+// value a row is fetched after.
+func NewPage[T any](rows []T, limit, total int, key func(T) string) Page[T] {
+	page := Page[T]{Items: rows, TotalEstimate: total}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if len(rows) > limit {
+		page.Items = rows[:limit]
+		page.NextCursor = EncodeCursor(key(rows[limit-1]))
+	}
+	return page
+}
+
+// Slice sorts an in-memory list by the fields of p and returns the page
+// after p.After. sorts compares two items by each sort field; ties keep the
+// list order. The cursor is an offset, so lists changing between requests
+// may repeat or skip items.
+func Slice[T any](items []T, p Params, sorts map[string]func(a, b T) int) (Page[T], error) {
+	if compare, ok := sorts[p.Sort]; ok {
+		items = slices.Clone(items)
+		slices.SortStableFunc(items, func(a, b T) int {
+			if p.Desc {
+				return compare(b, a)
+			}
+			return compare(a, b)
+		})
+	}
+
+	start := 0
+	if p.After != "" {
+		offset, err := strconv.Atoi(p.After)
+		if err != nil || offset < 0 {
+			return Page[T]{}, fmt.Errorf("%w: pass the next_cursor of the previous page", ErrInvalidCursor)
+		}
+		start = min(offset, len(items))
+	}
+	end := len(items)
+	if p.Limit > 0 {
+		end = min(start+p.Limit, len(items))
+	}
+
+	page := Page[T]{Items: slices.Clip(items[start:end]), TotalEstimate: len(items)}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if end < len(items) {
+		page.NextCursor = EncodeCursor(strconv.Itoa(end))
+	}
+	return page, nil
+}
+
+// Match reports whether value passes the equality filter param of values:
+// true when param is unset, else when value equals one of its
+// comma-separated values, compared case-insensitively.
+func Match(values url.Values, param, value string) bool {
+	filter := values.Get(param)
+	if filter == "" {
+		return true
+	}
+	for v := range strings.SplitSeq(filter, ",") {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare orders two items by a field of a cmp.Ordered type.
+func Compare[T any, F cmp.Ordered](field func(T) F) func(a, b T) int {
+	return func(a, b T) int { return cmp.Compare(field(a), field(b)) }
+}
@@ -0,0 +1,108 @@
+package pagination
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testOptions = Options{DefaultLimit: 2, MaxLimit: 10, Sorts: []string{"-name", "size"}}
+
+func TestParse(t *testing.T) {
+	p, err := Parse(url.Values{}, testOptions)
+	require.NoError(t, err)
+	assert.Equal(t, Params{Limit: 2, Sort: "name", Desc: true}, p)
+
+	p, err = Parse(url.Values{"limit": {"5"}, "sort": {"size"}, "cursor": {EncodeCursor("abc")}}, testOptions)
+	require.NoError(t, err)
+	assert.Equal(t, Params{Limit: 5, After: "abc", Sort: "size"}, p)
+
+	p, err = Parse(url.Values{"after": {"raw-id"}}, testOptions)
+	require.NoError(t, err)
+	assert.Equal(t, "raw-id", p.After, "legacy after is a decoded cursor")
+
+	for _, values := range []url.Values{
+		{"limit": {"0"}},
+		{"limit": {"11"}},
+		{"limit": {"ten"}},
+	} {
+		_, err := Parse(values, testOptions)
+		assert.ErrorIs(t, err, ErrInvalidLimit, values.Encode())
+	}
+	_, err = Parse(url.Values{"cursor": {"!!"}}, testOptions)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	_, err = Parse(url.Values{"sort": {"-color"}}, testOptions)
+	assert.ErrorIs(t, err, ErrInvalidSort)
+	_, err = Parse(url.Values{"sort": {"id"}}, Options{MaxLimit: 10})
+	assert.ErrorIs(t, err, ErrInvalidSort, "lists without sorts reject sort")
+}
+
+type item struct {
+	name string
+	size int
+}
+
+var itemSorts = map[string]func(a, b item) int{
+	"name": Compare(func(i item) string { return i.name }),
+	"size": Compare(func(i item) int { return i.size }),
+}
+
+func TestSliceWalksEveryPage(t *testing.T) {
+	items := []item{{"b", 3}, {"d", 1}, {"a", 2}, {"c", 2}, {"e", 5}}
+
+	var names []string
+	values := url.Values{"sort": {"size"}}
+	for range len(items) {
+		p, err := Parse(values, testOptions)
+		require.NoError(t, err)
+		page, err := Slice(items, p, itemSorts)
+		require.NoError(t, err)
+		assert.Equal(t, len(items), page.TotalEstimate)
+		for _, i := range page.Items {
+			names = append(names, i.name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		values.Set("cursor", page.NextCursor)
+	}
+	assert.Equal(t, "dacbe", strings.Join(names, ""), "ties keep the list order")
+	assert.Equal(t, "b", items[0].name, "the input is not sorted in place")
+
+	page, err := Slice(items, Params{Limit: 10, Sort: "name", Desc: true}, itemSorts)
+	require.NoError(t, err)
+	assert.Equal(t, item{"e", 5}, page.Items[0])
+	assert.Empty(t, page.NextCursor)
+
+	page, err = Slice([]item(nil), Params{Limit: 10}, itemSorts)
+	require.NoError(t, err)
+	assert.NotNil(t, page.Items, "empty pages encode as []")
+
+	_, err = Slice(items, Params{After: "-1"}, itemSorts)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestNewPage(t *testing.T) {
+	key := func(i item) string { return i.name }
+
+	page := NewPage([]item{{"a", 1}, {"b", 2}, {"c", 3}}, 2, 7, key)
+	assert.Len(t, page.Items, 2)
+	assert.Equal(t, 7, page.TotalEstimate)
+	after, err := DecodeCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, "b", after)
+
+	page = NewPage([]item{{"a", 1}}, 2, 1, key)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestMatch(t *testing.T) {
+	values := url.Values{"status": {"failed, Running"}}
+	assert.True(t, Match(values, "status", "running"))
+	assert.True(t, Match(values, "status", "failed"))
+	assert.False(t, Match(values, "status", "completed"))
+	assert.True(t, Match(values, "kind", "domain"), "unset filters match everything")
+}
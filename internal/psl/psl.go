@@ -0,0 +1,163 @@
+// Package psl answers public suffix lookups from the Public Suffix List. A
+// list downloaded by the Updater replaces the one compiled into
+// golang.org/x/net/publicsuffix once it is loaded, so suffixes added after
+// the build still split hosts into domain and subdomains; until then, and
+// whenever no valid list was fetched, the compiled copy answers.
+package psl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// minRules is the fewest rules a list must have to replace the current one;
+// a truncated or error page download has far fewer.
+const minRules = 1000
+
+var (
+	ErrInvalidList = errors.New("invalid public suffix list")
+	ErrNoDomain    = errors.New("cannot derive eTLD+1")
+)
+
+// List is a parsed Public Suffix List.
+type List struct {
+	rules      map[string]struct{} // Normal rules, e.g. "co.uk"
+	wildcards  map[string]struct{} // Parents of wildcard rules: "*.ck" is stored as "ck"
+	exceptions map[string]struct{} // Exception rules without their "!"
+}
+
+// Parse reads a list in the publicsuffix.org format: one rule per line,
+// "//" comments, "*." wildcards and "!" exceptions. Internationalized rules
+// are stored in their punycode form, as hosts are looked up.
+func Parse(r io.Reader) (*List, error) {
+	l := &List{
+		rules:      make(map[string]struct{}),
+		wildcards:  make(map[string]struct{}),
+		exceptions: make(map[string]struct{}),
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		rule, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if rule == "" || strings.HasPrefix(rule, "//") {
+			continue
+		}
+
+		exception := strings.HasPrefix(rule, "!")
+		rule = strings.TrimPrefix(rule, "!")
+		wildcard := strings.HasPrefix(rule, "*.")
+		rule = strings.TrimPrefix(rule, "*.")
+
+		ascii, err := idna.ToASCII(strings.ToLower(rule))
+		if err != nil || ascii == "" {
+			continue
+		}
+		switch {
+		case exception:
+			l.exceptions[ascii] = struct{}{}
+		case wildcard:
+			l.wildcards[ascii] = struct{}{}
+		default:
+			l.rules[ascii] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Join(ErrInvalidList, err)
+	}
+	if n := l.Len(); n < minRules {
+		return nil, fmt.Errorf("%w: %d rules", ErrInvalidList, n)
+	}
+	return l, nil
+}
+
+// Len returns the number of rules in l.
+func (l *List) Len() int {
+	return len(l.rules) + len(l.wildcards) + len(l.exceptions)
+}
+
+// PublicSuffix returns the public suffix of the lower-case domain. An
+// exception rule prevails over every other match, then the rule with the
+// most labels; an unlisted TLD is its own public suffix.
+func (l *List) PublicSuffix(domain string) string {
+	for i := 0; i >= 0 && i < len(domain); {
+		suffix := domain[i:]
+		if _, ok := l.exceptions[suffix]; ok {
+			if dot := strings.IndexByte(suffix, '.'); dot >= 0 {
+				return suffix[dot+1:]
+			}
+			return suffix
+		}
+		i = nextLabel(domain, i)
+	}
+
+	for i := 0; i >= 0 && i < len(domain); {
+		suffix := domain[i:]
+		if _, ok := l.rules[suffix]; ok {
+			return suffix
+		}
+		next := nextLabel(domain, i)
+		if next >= 0 {
+			if _, ok := l.wildcards[domain[next:]]; ok {
+				return suffix
+			}
+		}
+		i = next
+	}
+
+	return domain[strings.LastIndexByte(domain, '.')+1:]
+}
+
+// EffectiveTLDPlusOne returns the public suffix of domain and the label
+// before it, as publicsuffix.EffectiveTLDPlusOne does.
+func (l *List) EffectiveTLDPlusOne(domain string) (string, error) {
+	if strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") || strings.Contains(domain, "..") {
+		return "", fmt.Errorf("%w: empty label in domain %q", ErrNoDomain, domain)
+	}
+
+	suffix := l.PublicSuffix(domain)
+	if len(domain) <= len(suffix) {
+		return "", fmt.Errorf("%w: domain %q is a public suffix", ErrNoDomain, domain)
+	}
+	i := len(domain) - len(suffix) - 1
+	if domain[i] != '.' {
+		return "", fmt.Errorf("%w: invalid public suffix %q for domain %q", ErrNoDomain, suffix, domain)
+	}
+	return domain[1+strings.LastIndexByte(domain[:i], '.'):], nil
+}
+
+// nextLabel returns the index of the label after the one at i, or -1.
+func nextLabel(domain string, i int) int {
+	dot := strings.IndexByte(domain[i:], '.')
+	if dot < 0 {
+		return -1
+	}
+	return i + dot + 1
+}
+
+var current atomic.Pointer[List]
+
+// Current returns the list in use, or nil while the compiled copy answers.
+func Current() *List {
+	return current.Load()
+}
+
+// Set swaps in l for every lookup; nil reverts to the compiled copy.
+func Set(l *List) {
+	current.Store(l)
+}
+
+// EffectiveTLDPlusOne returns the registered domain of domain from the
+// current list, or from the compiled copy when none was loaded.
+func EffectiveTLDPlusOne(domain string) (string, error) {
+	if l := current.Load(); l != nil {
+		return l.EffectiveTLDPlusOne(domain)
+	}
+	return publicsuffix.EffectiveTLDPlusOne(domain)
+}
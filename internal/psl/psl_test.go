@@ -0,0 +1,142 @@
+package psl
+
+import (
+	"blacked/internal/config"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testList returns a list with rules for the test domains, padded past
+// minRules.
+func testList(extra ...string) string {
+	var b strings.Builder
+	b.WriteString("// ===BEGIN ICANN DOMAINS===\n\ncom\nuk\nco.uk\n*.ck\n!www.ck\n*.kawasaki.jp\n!city.kawasaki.jp\nmünchen\n")
+	for _, rule := range extra {
+		b.WriteString(rule + "\n")
+	}
+	for i := range minRules {
+		fmt.Fprintf(&b, "filler%d\n", i)
+	}
+	return b.String()
+}
+
+func TestListEffectiveTLDPlusOne(t *testing.T) {
+	l, err := Parse(strings.NewReader(testList("pages.com")))
+	require.NoError(t, err)
+
+	for domain, want := range map[string]string{
+		"example.com":            "example.com",
+		"a.b.example.com":        "example.com",
+		"www.example.co.uk":      "example.co.uk",
+		"a.b.ck":                 "a.b.ck",
+		"www.ck":                 "www.ck",
+		"a.www.ck":               "www.ck",
+		"x.city.kawasaki.jp":     "city.kawasaki.jp",
+		"x.y.kawasaki.jp":        "x.y.kawasaki.jp",
+		"shop.xn--mnchen-3ya":    "shop.xn--mnchen-3ya",
+		"login.brand.pages.com":  "brand.pages.com",
+		"sub.example.unlistedtl": "example.unlistedtl",
+	} {
+		got, err := l.EffectiveTLDPlusOne(domain)
+		if assert.NoError(t, err, domain) {
+			assert.Equal(t, want, got, domain)
+		}
+	}
+
+	for _, domain := range []string{"com", "co.uk", "b.ck", "", ".example.com", "example.com.", "a..com"} {
+		_, err := l.EffectiveTLDPlusOne(domain)
+		assert.ErrorIs(t, err, ErrNoDomain, domain)
+	}
+}
+
+func TestParseRejectsShortList(t *testing.T) {
+	_, err := Parse(strings.NewReader("<html>Service Unavailable</html>\n"))
+	assert.ErrorIs(t, err, ErrInvalidList)
+}
+
+func TestEffectiveTLDPlusOneFallsBack(t *testing.T) {
+	t.Cleanup(func() { Set(nil) })
+
+	got, err := EffectiveTLDPlusOne("login.brand.pages.com")
+	require.NoError(t, err)
+	assert.Equal(t, "pages.com", got, "the compiled copy does not know the suffix")
+
+	l, err := Parse(strings.NewReader(testList("pages.com")))
+	require.NoError(t, err)
+	Set(l)
+	got, err = EffectiveTLDPlusOne("login.brand.pages.com")
+	require.NoError(t, err)
+	assert.Equal(t, "brand.pages.com", got)
+
+	Set(nil)
+	got, err = EffectiveTLDPlusOne("www.example.co.uk")
+	require.NoError(t, err)
+	assert.Equal(t, "example.co.uk", got)
+}
+
+func TestUpdaterRefresh(t *testing.T) {
+	t.Cleanup(func() { Set(nil) })
+
+	body := testList("pages.com")
+	var requests, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` && body == testList("pages.com") {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, body)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "psl", "list.dat")
+	u := NewUpdater(config.PSLConfig{URL: srv.URL, Path: path})
+
+	changed, err := u.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	got, err := EffectiveTLDPlusOne("a.brand.pages.com")
+	require.NoError(t, err)
+	assert.Equal(t, "brand.pages.com", got)
+
+	stored, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(stored))
+
+	changed, err = u.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, 1, notModified)
+
+	// A broken download keeps the list in use.
+	body = "<html>oops</html>"
+	current := Current()
+	_, err = u.Refresh(context.Background())
+	assert.ErrorIs(t, err, ErrInvalidList)
+	assert.Same(t, current, Current())
+	assert.Equal(t, 3, requests)
+
+	// A restart picks up the stored copy.
+	Set(nil)
+	require.NoError(t, NewUpdater(config.PSLConfig{URL: srv.URL, Path: path}).Load())
+	assert.NotNil(t, Current())
+}
+
+func TestUpdaterLoadMissingFile(t *testing.T) {
+	t.Cleanup(func() { Set(nil) })
+
+	u := NewUpdater(config.PSLConfig{Path: filepath.Join(t.TempDir(), "missing.dat")})
+	require.NoError(t, u.Load())
+	assert.Nil(t, Current())
+}
@@ -0,0 +1,156 @@
+package psl
+
+import (
+	"blacked/internal/config"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxListSize bounds a download; the list is about 300 KB.
+const maxListSize = 16 << 20
+
+var (
+	ErrFetch = errors.New("failed to download public suffix list")
+	ErrStore = errors.New("failed to store public suffix list")
+)
+
+// Updater keeps the current list fresh from the [PSL] source.
+type Updater struct {
+	cfg    config.PSLConfig
+	client *http.Client
+	etag   string // Validator of the list in use, sent as If-None-Match
+}
+
+// NewUpdater creates an Updater for cfg.
+func NewUpdater(cfg config.PSLConfig) *Updater {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &Updater{cfg: cfg, client: &http.Client{Timeout: timeout}}
+}
+
+// Load makes the stored list current. A missing or invalid file leaves the
+// compiled copy in use.
+func (u *Updater) Load() error {
+	if u.cfg.Path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(u.cfg.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	l, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	Set(l)
+	log.Info().Str("path", u.cfg.Path).Int("rules", l.Len()).Msg("Public suffix list loaded")
+	return nil
+}
+
+// Refresh downloads the list and swaps it in. It reports whether the list
+// changed; an unchanged or invalid download keeps the list in use.
+func (u *Updater) Refresh(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.cfg.URL, nil)
+	if err != nil {
+		return false, errors.Join(ErrFetch, err)
+	}
+	if u.etag != "" && Current() != nil {
+		req.Header.Set("If-None-Match", u.etag)
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return false, errors.Join(ErrFetch, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return false, nil
+	case http.StatusOK:
+	default:
+		return false, fmt.Errorf("%w: unexpected status %s", ErrFetch, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxListSize))
+	if err != nil {
+		return false, errors.Join(ErrFetch, err)
+	}
+	l, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return false, err
+	}
+
+	Set(l)
+	u.etag = resp.Header.Get("ETag")
+	if err := u.store(data); err != nil {
+		log.Warn().Err(err).Str("path", u.cfg.Path).Msg("Public suffix list updated but not stored")
+	}
+	log.Info().Int("rules", l.Len()).Msg("Public suffix list updated")
+	return true, nil
+}
+
+// Run refreshes the list at once and then every [PSL] interval until ctx is
+// done. Failed refreshes keep the list in use.
+func (u *Updater) Run(ctx context.Context) {
+	interval := u.cfg.Interval
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := u.Refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Warn().Err(err).Str("url", u.cfg.URL).Msg("Public suffix list refresh failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// store writes data to the configured path atomically.
+func (u *Updater) store(data []byte) error {
+	if u.cfg.Path == "" {
+		return nil
+	}
+	dir := filepath.Dir(u.cfg.Path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.Join(ErrStore, err)
+	}
+	tmp, err := os.CreateTemp(dir, ".psl-*")
+	if err != nil {
+		return errors.Join(ErrStore, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Join(ErrStore, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Join(ErrStore, err)
+	}
+	if err := os.Rename(tmp.Name(), u.cfg.Path); err != nil {
+		return errors.Join(ErrStore, err)
+	}
+	return nil
+}
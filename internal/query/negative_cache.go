@@ -0,0 +1,125 @@
+package query
+
+import (
+	"blacked/internal/collector"
+	"blacked/internal/config"
+	"sync"
+	"time"
+)
+
+// NegativeCache remembers for a short TTL the lookups that found nothing, so
+// clean URLs re-queried many times a minute, above all those the bloom
+// filters report as false positives, skip the database. Writes through this
+// process purge the SharedNegativeCache; writes by other processes show once
+// the TTL lapses.
+type NegativeCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+// NewNegativeCache creates a NegativeCache of at most maxEntries keys, each
+// kept for ttl. A non-positive ttl returns nil, which caches nothing.
+func NewNegativeCache(ttl time.Duration, maxEntries int) *NegativeCache {
+	if ttl <= 0 {
+		return nil
+	}
+	if maxEntries <= 0 {
+		maxEntries = 100_000
+	}
+	return &NegativeCache{ttl: ttl, maxEntries: maxEntries, expires: make(map[string]time.Time)}
+}
+
+// Contains reports whether key found nothing within the TTL.
+func (c *NegativeCache) Contains(key string) bool {
+	if c == nil {
+		return false
+	}
+	hit := c.contains(key)
+	if mc, err := collector.GetMetricsCollector(); err == nil {
+		mc.IncrementNegativeCacheLookup(hit)
+	}
+	return hit
+}
+
+func (c *NegativeCache) contains(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires, ok := c.expires[key]
+	if !ok {
+		return false
+	}
+	if time.Now().After(expires) {
+		delete(c.expires, key)
+		return false
+	}
+	return true
+}
+
+// Add records that key found nothing. When the cache is full, expired keys
+// are dropped first and, if none were, every key.
+func (c *NegativeCache) Add(key string) {
+	if c == nil {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.expires[key]; !ok && len(c.expires) >= c.maxEntries {
+		for k, expires := range c.expires {
+			if now.After(expires) {
+				delete(c.expires, k)
+			}
+		}
+		if len(c.expires) >= c.maxEntries {
+			clear(c.expires)
+		}
+	}
+	c.expires[key] = now.Add(c.ttl)
+}
+
+// Purge forgets every key, after entries were written.
+func (c *NegativeCache) Purge() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	clear(c.expires)
+	c.mu.Unlock()
+}
+
+// Len returns the number of keys held, expired ones included.
+func (c *NegativeCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.expires)
+}
+
+var (
+	sharedNegative     *NegativeCache
+	sharedNegativeOnce sync.Once
+)
+
+// SharedNegativeCache returns the process-wide NegativeCache of the [Cache]
+// section, or nil when negative_ttl is zero.
+func SharedNegativeCache() *NegativeCache {
+	sharedNegativeOnce.Do(func() {
+		if cfg := config.GetConfig(); cfg != nil {
+			sharedNegative = NewNegativeCache(cfg.Cache.NegativeTTL, cfg.Cache.NegativeMaxEntries)
+		}
+	})
+	return sharedNegative
+}
+
+// PurgeNegativeCache purges the process-wide NegativeCache; called whenever
+// this process writes entries or adds bloom keys.
+func PurgeNegativeCache() {
+	SharedNegativeCache().Purge()
+}
@@ -0,0 +1,112 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNegativeCache_ExpiresAndPurges(t *testing.T) {
+	c := NewNegativeCache(20*time.Millisecond, 10)
+	c.Add("a")
+	if !c.Contains("a") || c.Contains("b") {
+		t.Fatal("expected only a to be cached")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if c.Contains("a") {
+		t.Fatal("expected a to expire after the TTL")
+	}
+
+	c.Add("b")
+	c.Purge()
+	if c.Contains("b") || c.Len() != 0 {
+		t.Fatal("expected Purge to forget every key")
+	}
+}
+
+func TestNegativeCache_Bounded(t *testing.T) {
+	c := NewNegativeCache(time.Minute, 3)
+	for i := range 5 {
+		c.Add(fmt.Sprint(i))
+	}
+	if n := c.Len(); n > 3 {
+		t.Fatalf("expected at most 3 keys, got %d", n)
+	}
+	if !c.Contains("4") {
+		t.Fatal("expected the newest key to be kept")
+	}
+}
+
+func TestNegativeCache_Disabled(t *testing.T) {
+	c := NewNegativeCache(0, 10)
+	if c != nil {
+		t.Fatal("expected a zero TTL to disable the cache")
+	}
+	c.Add("a")
+	c.Purge()
+	if c.Contains("a") || c.Len() != 0 {
+		t.Fatal("expected a nil cache to hold nothing")
+	}
+}
+
+// countingRepo answers every existence check with exists, or err.
+type countingRepo struct {
+	EntryRepository
+	exists bool
+	err    error
+	calls  int
+}
+
+func (r *countingRepo) ExistsByDomain(context.Context, string) (bool, error) {
+	r.calls++
+	return r.exists, r.err
+}
+
+func TestQueryService_Hit_NegativeCache(t *testing.T) {
+	ctx := context.Background()
+	bloom := stubBloom{matches: []Match{{SourceID: "feed", Type: "domain", Key: "clean.example"}}}
+	repo := &countingRepo{}
+	cache := NewNegativeCache(time.Minute, 10)
+	qs := NewQueryService(bloom, repo, NewScorer(nil)).SetNegativeCache(cache)
+
+	for range 3 {
+		resp, err := qs.Hit(ctx, "https://clean.example/")
+		if err != nil {
+			t.Fatalf("Hit: %v", err)
+		}
+		if resp.Blocked {
+			t.Fatal("expected a bloom false positive not to block")
+		}
+	}
+	if repo.calls != 1 {
+		t.Fatalf("expected one database check for a repeated false positive, got %d", repo.calls)
+	}
+
+	cache.Purge()
+	repo.exists = true
+	resp, err := qs.Hit(ctx, "https://clean.example/")
+	if err != nil {
+		t.Fatalf("Hit: %v", err)
+	}
+	if !resp.Blocked {
+		t.Fatal("expected an entry written after a purge to block")
+	}
+}
+
+func TestQueryService_Hit_NegativeCacheSkipsFailures(t *testing.T) {
+	bloom := stubBloom{matches: []Match{{SourceID: "feed", Type: "domain", Key: "clean.example"}}}
+	repo := &countingRepo{err: errors.New("database is locked")}
+	qs := NewQueryService(bloom, repo, NewScorer(nil)).SetNegativeCache(NewNegativeCache(time.Minute, 10))
+
+	for range 2 {
+		if _, err := qs.Hit(context.Background(), "https://clean.example/"); err != nil {
+			t.Fatalf("Hit: %v", err)
+		}
+	}
+	if repo.calls != 2 {
+		t.Fatalf("expected failed checks not to be cached, got %d calls", repo.calls)
+	}
+}
@@ -0,0 +1,113 @@
+package query
+
+import (
+	"blacked/internal/config"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Action is the decision a policy takes for a query result.
+type Action string
+
+const (
+	ActionBlock Action = "block"
+	ActionWarn  Action = "warn"
+	ActionAllow Action = "allow"
+)
+
+var ErrInvalidPolicy = errors.New("invalid query policy")
+
+// ParseAction validates an action name.
+func ParseAction(s string) (Action, error) {
+	switch a := Action(s); a {
+	case ActionBlock, ActionWarn, ActionAllow:
+		return a, nil
+	}
+	return "", fmt.Errorf("%w: unknown action %q", ErrInvalidPolicy, s)
+}
+
+// Verdict is what a policy decides on: the outcome of one URL lookup.
+type Verdict struct {
+	Listed      bool
+	Allowlisted bool
+	Score       float64
+	Categories  []string
+	Sources     []string
+}
+
+// PolicyRule is a validated config.PolicyRule.
+type PolicyRule struct {
+	Category string
+	Source   string
+	MinScore *float64
+	MaxScore *float64
+	Action   Action
+}
+
+func (r PolicyRule) matches(v Verdict) bool {
+	if r.Category != "" && r.Category != "*" && !slices.Contains(v.Categories, r.Category) {
+		return false
+	}
+	if r.Source != "" && r.Source != "*" && !slices.Contains(v.Sources, r.Source) {
+		return false
+	}
+	if r.MinScore != nil && v.Score < *r.MinScore {
+		return false
+	}
+	if r.MaxScore != nil && v.Score >= *r.MaxScore {
+		return false
+	}
+	return true
+}
+
+// Policy turns query results into actions, so downstream systems do not each
+// re-encode what a category or score means for them.
+type Policy struct {
+	rules         []PolicyRule
+	defaultAction Action
+}
+
+// NewPolicy validates cfg. An empty config blocks every listed result.
+func NewPolicy(cfg config.PolicyConfig) (*Policy, error) {
+	p := &Policy{defaultAction: ActionBlock}
+	if cfg.DefaultAction != "" {
+		a, err := ParseAction(cfg.DefaultAction)
+		if err != nil {
+			return nil, err
+		}
+		p.defaultAction = a
+	}
+
+	for i, r := range cfg.Rules {
+		a, err := ParseAction(r.Action)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if r.MinScore != nil && r.MaxScore != nil && *r.MinScore >= *r.MaxScore {
+			return nil, fmt.Errorf("%w: rule %d: min_score must be below max_score", ErrInvalidPolicy, i+1)
+		}
+		p.rules = append(p.rules, PolicyRule{
+			Category: r.Category,
+			Source:   r.Source,
+			MinScore: r.MinScore,
+			MaxScore: r.MaxScore,
+			Action:   a,
+		})
+	}
+	return p, nil
+}
+
+// Decide returns the action for v. Unlisted and allowlisted results are
+// always allowed; listed ones take the action of the first matching rule.
+func (p *Policy) Decide(v Verdict) Action {
+	if !v.Listed || v.Allowlisted {
+		return ActionAllow
+	}
+	for _, r := range p.rules {
+		if r.matches(v) {
+			return r.Action
+		}
+	}
+	return p.defaultAction
+}
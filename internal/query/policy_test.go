@@ -0,0 +1,98 @@
+package query
+
+import (
+	"blacked/internal/config"
+	"context"
+	"errors"
+	"testing"
+)
+
+func score(f float64) *float64 { return &f }
+
+func TestPolicy_Decide(t *testing.T) {
+	p, err := NewPolicy(config.PolicyConfig{
+		DefaultAction: "warn",
+		Rules: []config.PolicyRule{
+			{MaxScore: score(0.3), Action: "allow"},
+			{Category: "phishing", Action: "block"},
+			{Category: "nsfw", Action: "warn"},
+			{Source: "urlhaus-online", MinScore: score(0.8), Action: "block"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		v    Verdict
+		want Action
+	}{
+		{"unlisted", Verdict{Score: 0.9, Categories: []string{"phishing"}}, ActionAllow},
+		{"allowlisted", Verdict{Listed: true, Allowlisted: true, Categories: []string{"phishing"}}, ActionAllow},
+		{"low score wins over category", Verdict{Listed: true, Score: 0.2, Categories: []string{"phishing"}}, ActionAllow},
+		{"score at max is not below it", Verdict{Listed: true, Score: 0.3, Categories: []string{"phishing"}}, ActionBlock},
+		{"any matched category", Verdict{Listed: true, Score: 0.9, Categories: []string{"nsfw", "phishing"}}, ActionBlock},
+		{"nsfw", Verdict{Listed: true, Score: 0.9, Categories: []string{"nsfw"}}, ActionWarn},
+		{"source and score", Verdict{Listed: true, Score: 0.85, Sources: []string{"urlhaus-online"}}, ActionBlock},
+		{"default", Verdict{Listed: true, Score: 0.5, Sources: []string{"urlhaus-online"}}, ActionWarn},
+	}
+	for _, tc := range cases {
+		if got := p.Decide(tc.v); got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestNewPolicy_Invalid(t *testing.T) {
+	p, err := NewPolicy(config.PolicyConfig{})
+	if err != nil || p.Decide(Verdict{Listed: true}) != ActionBlock {
+		t.Fatalf("empty policy should block listed results, got %v", err)
+	}
+
+	invalid := []config.PolicyConfig{
+		{DefaultAction: "drop"},
+		{Rules: []config.PolicyRule{{Category: "nsfw"}}},
+		{Rules: []config.PolicyRule{{MinScore: score(0.8), MaxScore: score(0.5), Action: "warn"}}},
+	}
+	for i, cfg := range invalid {
+		if _, err := NewPolicy(cfg); !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("config %d: expected ErrInvalidPolicy, got %v", i, err)
+		}
+	}
+}
+
+type stubBloom struct{ matches []Match }
+
+func (b stubBloom) Check(string) (bool, []Match, error) {
+	return len(b.matches) > 0, b.matches, nil
+}
+
+func TestQueryService_Hit_Action(t *testing.T) {
+	p, err := NewPolicy(config.PolicyConfig{
+		Rules: []config.PolicyRule{{Category: "nsfw", Action: "warn"}},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+
+	bloom := stubBloom{matches: []Match{{SourceID: "oisd-nsfw", Type: "domain", Key: "adult.example"}}}
+	qs := NewQueryService(bloom, nil, NewScorer(nil)).
+		SetPolicy(p, map[string]string{"oisd-nsfw": "nsfw"})
+
+	resp, err := qs.Hit(context.Background(), "https://adult.example/")
+	if err != nil {
+		t.Fatalf("Hit: %v", err)
+	}
+	if !resp.Blocked || resp.Action != ActionWarn {
+		t.Fatalf("expected listed with warn, got blocked=%v action=%s", resp.Blocked, resp.Action)
+	}
+
+	resp, err = NewQueryService(stubBloom{}, nil, nil).SetPolicy(p, nil).Hit(context.Background(), "https://clean.example/")
+	if err != nil {
+		t.Fatalf("Hit: %v", err)
+	}
+	if resp.Action != ActionAllow {
+		t.Fatalf("expected allow for unlisted URL, got %s", resp.Action)
+	}
+}
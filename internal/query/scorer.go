@@ -34,7 +34,7 @@ func (s *Scorer) Score(matches []Match) (float64, string) {
 		if t, ok := s.trust[matches[0].SourceID]; ok {
 			trust = t
 		}
-		level := confidenceLevel(trust)
+		level := ConfidenceLevel(trust)
 		log.Trace().
 			Float64("score", trust).
 			Str("level", level).
@@ -65,7 +65,7 @@ func (s *Scorer) Score(matches []Match) (float64, string) {
 	}
 
 	score := totalWeighted / totalTrust
-	level := confidenceLevel(score)
+	level := ConfidenceLevel(score)
 
 	log.Trace().
 		Float64("score", score).
@@ -98,7 +98,7 @@ func (s *Scorer) ScoreWithResult(sourceIDs []string) (float64, string) {
 	}
 
 	score := totalTrust / count
-	level := confidenceLevel(score)
+	level := ConfidenceLevel(score)
 
 	log.Trace().
 		Float64("score", score).
@@ -121,7 +121,9 @@ var depthWeight = map[string]float64{
 	"ip":        0.8,
 }
 
-func confidenceLevel(score float64) string {
+// ConfidenceLevel names the band of a 0–1 score: critical, high, medium,
+// low or informational.
+func ConfidenceLevel(score float64) string {
 	switch {
 	case score >= 0.90:
 		return "critical"
@@ -145,9 +145,9 @@ func TestConfidenceLevel_AllBands(t *testing.T) {
 		{0.00, "informational"},
 	}
 	for _, tc := range tests {
-		got := confidenceLevel(tc.score)
+		got := ConfidenceLevel(tc.score)
 		if got != tc.want {
-			t.Errorf("confidenceLevel(%.2f) = %s, want %s", tc.score, got, tc.want)
+			t.Errorf("ConfidenceLevel(%.2f) = %s, want %s", tc.score, got, tc.want)
 		}
 	}
 }
@@ -1,9 +1,14 @@
 package query
 
 import (
+	"blacked/internal/collector"
+	"blacked/internal/utils"
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"slices"
+	"time"
 )
 
 // BloomChecker is the minimal interface the query service needs from the bloom engine.
@@ -19,11 +24,22 @@ type ScorerIface interface {
 	ScoreWithResult(sourceIDs []string) (float64, string)
 }
 
+// Allowlist reports whether a URL must never be reported as blocked.
+// Implemented by features/allowlist.Service.
+type Allowlist interface {
+	Allowed(ctx context.Context, urlStr string) (bool, error)
+}
+
 // QueryService is the HTTP-agnostic core for all URL lookups.
 type QueryService struct {
-	bloom  BloomChecker
-	repo   EntryRepository
-	scorer ScorerIface
+	bloom     BloomChecker
+	repo      EntryRepository
+	scorer    ScorerIface
+	allowlist Allowlist      // nil disables allowlist checks
+	negative  *NegativeCache // nil disables negative caching
+
+	policy     *Policy           // nil leaves Action unset
+	categories map[string]string // source ID → category, for policy rules on categories
 }
 
 // NewQueryService creates a QueryService.
@@ -35,8 +51,53 @@ func NewQueryService(bloom BloomChecker, repo EntryRepository, scorer ScorerIfac
 	}
 }
 
-// Likely performs a fast bloom-only check.
+// SetAllowlist sets the allowlist Hit consults before blocking; nil disables it.
+func (qs *QueryService) SetAllowlist(a Allowlist) *QueryService {
+	qs.allowlist = a
+	return qs
+}
+
+// SetNegativeCache sets the cache of URLs whose bloom matches the database
+// did not confirm; nil disables it.
+func (qs *QueryService) SetNegativeCache(c *NegativeCache) *QueryService {
+	qs.negative = c
+	return qs
+}
+
+// SetPolicy sets the policy deciding the Action of Hit and Search results.
+// sourceCategories maps source IDs to their category, since bloom matches
+// only carry the source; nil disables the policy.
+func (qs *QueryService) SetPolicy(p *Policy, sourceCategories map[string]string) *QueryService {
+	qs.policy = p
+	qs.categories = sourceCategories
+	return qs
+}
+
+// decide sets the policy action of resp.
+func (qs *QueryService) decide(resp *QueryResponse) {
+	if qs.policy == nil {
+		return
+	}
+
+	v := Verdict{Listed: resp.Blocked, Allowlisted: resp.Allowlisted, Score: resp.Confidence}
+	for _, m := range resp.Matches {
+		if !slices.Contains(v.Sources, m.SourceID) {
+			v.Sources = append(v.Sources, m.SourceID)
+		}
+		if c := qs.categories[m.SourceID]; c != "" && !slices.Contains(v.Categories, c) {
+			v.Categories = append(v.Categories, c)
+		}
+	}
+	resp.Action = qs.policy.Decide(v)
+}
+
+// Likely performs a fast bloom-only check. Links of opaque schemes, such as
+// javascript: and data:, have no host for the bloom filters to hold: they
+// fail with utils.ErrUnsupportedScheme instead of passing as clean.
 func (qs *QueryService) Likely(ctx context.Context, urlStr string) (*LikelyResponse, error) {
+	if scheme := utils.OpaqueScheme(urlStr); scheme != "" {
+		return nil, fmt.Errorf("%w: %s", utils.ErrUnsupportedScheme, scheme)
+	}
 	likely, matches, err := qs.bloom.Check(urlStr)
 	if err != nil {
 		return nil, fmt.Errorf("bloom likely: %w", err)
@@ -55,11 +116,23 @@ func (qs *QueryService) Likely(ctx context.Context, urlStr string) (*LikelyRespo
 	return resp, nil
 }
 
-// Hit performs a full check: bloom → DB confirmation → scorer.
+// Hit performs a full check: bloom → DB confirmation → scorer. Links of
+// opaque schemes skip the bloom and are looked up by their exact source
+// URL; without a repository they fail with utils.ErrUnsupportedScheme.
 func (qs *QueryService) Hit(ctx context.Context, urlStr string) (*QueryResponse, error) {
-	likely, matches, err := qs.bloom.Check(urlStr)
+	defer func(start time.Time) { collector.ObserveQueryLatency(time.Since(start)) }(time.Now())
+
+	opaque := utils.OpaqueScheme(urlStr) != ""
+	var likely bool
+	var matches []Match
+	var err error
+	if opaque {
+		likely, matches, err = qs.sourceURLCheck(ctx, urlStr)
+	} else if likely, matches, err = qs.bloom.Check(urlStr); err != nil {
+		err = fmt.Errorf("bloom hit: %w", err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("bloom hit: %w", err)
+		return nil, err
 	}
 
 	resp := &QueryResponse{
@@ -77,8 +150,16 @@ func (qs *QueryService) Hit(ctx context.Context, urlStr string) (*QueryResponse,
 		//   ip     → ExistsByIP
 		//   other  → ExistsByHost (hostname from URL)
 		confirmed := true
-		if qs.repo != nil {
+		matchType := ""
+		if len(matches) > 0 {
+			matchType = matches[0].Type
+		}
+		// Source URL matches come from the DB already.
+		if qs.repo != nil && !opaque {
 			confirmed = false
+		}
+		if qs.repo != nil && !opaque && !qs.negative.Contains(negativeHitKey+urlStr) {
+			failed := false
 			for _, m := range matches {
 				var exists bool
 				var err error
@@ -102,13 +183,28 @@ func (qs *QueryService) Hit(ctx context.Context, urlStr string) (*QueryResponse,
 				}
 				if err == nil && exists {
 					confirmed = true
+					matchType = m.Type
 					break
 				}
+				failed = failed || err != nil
+			}
+			// A failed check may have missed a match, so only clean answers are cached.
+			if !confirmed && !failed {
+				qs.negative.Add(negativeHitKey + urlStr)
+			}
+		}
+
+		// Allowlisted URLs are never blocked. A failing allowlist keeps the verdict.
+		if confirmed && qs.allowlist != nil {
+			if allowed, err := qs.allowlist.Allowed(ctx, urlStr); err == nil && allowed {
+				confirmed = false
+				resp.Allowlisted = true
 			}
 		}
 
 		if confirmed {
 			resp.Blocked = true
+			resp.MatchType = matchType
 
 			if qs.scorer != nil {
 				// Use Score(matches) for full depth-weighted formula:
@@ -121,7 +217,7 @@ func (qs *QueryService) Hit(ctx context.Context, urlStr string) (*QueryResponse,
 				resp.Level = "medium"
 			}
 		} else {
-			// Bloom positive, DB negative → false positive. Not blocked.
+			// Bloom positive, DB negative (false positive) or allowlisted. Not blocked.
 			resp.Blocked = false
 			resp.Confidence = 0.0
 			resp.Level = "informational"
@@ -131,9 +227,31 @@ func (qs *QueryService) Hit(ctx context.Context, urlStr string) (*QueryResponse,
 		resp.Level = "informational"
 	}
 
+	qs.decide(resp)
 	return resp, nil
 }
 
+// sourceURLCheck looks up a link of an opaque scheme by its exact source
+// URL, with a full_url match per source listing it.
+func (qs *QueryService) sourceURLCheck(ctx context.Context, urlStr string) (bool, []Match, error) {
+	if qs.repo == nil {
+		return false, nil, fmt.Errorf("%w: %s", utils.ErrUnsupportedScheme, utils.OpaqueScheme(urlStr))
+	}
+	link := utils.OpaqueLink(urlStr)
+	sources, err := qs.repo.SourcesBySourceURL(ctx, link)
+	if err != nil {
+		return false, nil, fmt.Errorf("source url hit: %w", err)
+	}
+	matches := make([]Match, 0, len(sources))
+	for _, source := range sources {
+		matches = append(matches, Match{SourceID: source, Type: "full_url", Key: link})
+	}
+	return len(matches) > 0, matches, nil
+}
+
+// negativeHitKey prefixes the NegativeCache keys of Hit.
+const negativeHitKey = "hit:"
+
 // hostname extracts the hostname from a URL string.
 func hostname(urlStr string) string {
 	u, err := url.Parse(urlStr)
@@ -144,10 +262,16 @@ func hostname(urlStr string) string {
 }
 
 // BulkCheck performs fast bloom-only checks for multiple URLs (~0.4ms per URL).
+// A URL of an opaque scheme gets a result with its rejection in Error, and
+// the other URLs are still checked.
 func (qs *QueryService) BulkCheck(ctx context.Context, urls []string) ([]LikelyResponse, error) {
 	results := make([]LikelyResponse, len(urls))
 	for i, u := range urls {
 		resp, err := qs.Likely(ctx, u)
+		if errors.Is(err, utils.ErrUnsupportedScheme) {
+			results[i] = LikelyResponse{URL: u, Error: err.Error()}
+			continue
+		}
 		if err != nil {
 			return nil, fmt.Errorf("bulk check url=%s: %w", u, err)
 		}
@@ -157,10 +281,16 @@ func (qs *QueryService) BulkCheck(ctx context.Context, urls []string) ([]LikelyR
 }
 
 // BulkHit performs full lookups (bloom + DB + score) for multiple URLs.
+// Without a repository, a URL of an opaque scheme gets a result with its
+// rejection in Error, as in BulkCheck.
 func (qs *QueryService) BulkHit(ctx context.Context, urls []string) ([]QueryResponse, error) {
 	results := make([]QueryResponse, len(urls))
 	for i, u := range urls {
 		resp, err := qs.Hit(ctx, u)
+		if errors.Is(err, utils.ErrUnsupportedScheme) {
+			results[i] = QueryResponse{URL: u, Error: err.Error()}
+			continue
+		}
 		if err != nil {
 			return nil, fmt.Errorf("bulk hit url=%s: %w", u, err)
 		}
@@ -183,13 +313,22 @@ func (qs *QueryService) Search(ctx context.Context, filter SearchFilter) ([]Quer
 			URL:        fullURL,
 			Blocked:    true,
 			Confidence: e.Confidence,
-			Level:      confidenceLevel(e.Confidence),
+			Level:      ConfidenceLevel(e.Confidence),
 			Matches: []Match{{
 				SourceID: e.SourceID,
 				Type:     "domain",
 				Key:      e.Domain,
 			}},
 		}
+		if qs.policy != nil {
+			// Search rows carry their own category.
+			results[i].Action = qs.policy.Decide(Verdict{
+				Listed:     true,
+				Score:      e.Confidence,
+				Categories: []string{e.Category},
+				Sources:    []string{e.SourceID},
+			})
+		}
 	}
 	return results, nil
 }
@@ -0,0 +1,38 @@
+package query
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQueryService_Bulk_OpaqueScheme(t *testing.T) {
+	ctx := context.Background()
+	bloom := stubBloom{matches: []Match{{SourceID: "feed", Type: "domain", Key: "bad.example"}}}
+	qs := NewQueryService(bloom, nil, NewScorer(nil))
+	urls := []string{"https://bad.example/", "javascript:alert(1)", "https://bad.example/x"}
+
+	likely, err := qs.BulkCheck(ctx, urls)
+	if err != nil {
+		t.Fatalf("BulkCheck: %v", err)
+	}
+	if len(likely) != len(urls) {
+		t.Fatalf("expected %d results, got %d", len(urls), len(likely))
+	}
+	if likely[1].URL != urls[1] || likely[1].Error == "" || likely[1].Likely {
+		t.Fatalf("expected the javascript: link to carry its rejection, got %+v", likely[1])
+	}
+	if !likely[0].Likely || !likely[2].Likely || likely[0].Error != "" || likely[2].Error != "" {
+		t.Fatal("expected the other URLs to be checked")
+	}
+
+	hits, err := qs.BulkHit(ctx, urls)
+	if err != nil {
+		t.Fatalf("BulkHit: %v", err)
+	}
+	if hits[1].URL != urls[1] || hits[1].Error == "" || hits[1].Blocked {
+		t.Fatalf("expected the javascript: link to carry its rejection, got %+v", hits[1])
+	}
+	if !hits[0].Blocked || !hits[2].Blocked {
+		t.Fatal("expected the other URLs to be checked")
+	}
+}
@@ -13,11 +13,15 @@ type Match struct {
 
 // QueryResponse is the full result from a Hit check (bloom + DB + score).
 type QueryResponse struct {
-	URL        string  `json:"url"`
-	Blocked    bool    `json:"blocked"`
-	Confidence float64 `json:"confidence"`
-	Level      string  `json:"level"` // critical, high, medium, low, informational
-	Matches    []Match `json:"matches"`
+	URL         string  `json:"url"`
+	Blocked     bool    `json:"blocked"`
+	Allowlisted bool    `json:"allowlisted,omitempty"` // Listed, but exempted by the allowlist
+	Confidence  float64 `json:"confidence"`
+	Level       string  `json:"level"`                // critical, high, medium, low, informational
+	Action      Action  `json:"action,omitempty"`     // Policy decision; empty without a policy
+	MatchType   string  `json:"match_type,omitempty"` // Bloom type of the match confirming the block, e.g. "domain"
+	Matches     []Match `json:"matches"`
+	Error       string  `json:"error,omitempty"` // Why a URL of a bulk check was not checked, e.g. its scheme
 }
 
 // LikelyResponse is the fast bloom-only result (~0.4ms).
@@ -26,6 +30,7 @@ type LikelyResponse struct {
 	Likely   bool    `json:"likely"`
 	MaxDepth int     `json:"max_depth"` // 0-100 scale
 	Matches  []Match `json:"matches,omitempty"`
+	Error    string  `json:"error,omitempty"` // Why a URL of a bulk check was not checked, e.g. its scheme
 }
 
 // SearchFilter holds parameters for filtered search.
@@ -75,4 +80,8 @@ type EntryRepository interface {
 	// domain → ExistsByDomain, host → ExistsByHost, ip → ExistsByIP.
 	// file → path column suffix, host_path → source_url contains, full_url → source_url exact.
 	ExistsByBloomType(ctx context.Context, matchType, key string) (bool, error)
+
+	// SourcesBySourceURL returns the sources listing the exact link, for
+	// links of opaque schemes the bloom filters cannot hold.
+	SourcesBySourceURL(ctx context.Context, link string) ([]string, error)
 }
@@ -25,8 +25,9 @@ func ExecuteProvider(ctx context.Context, provider base.Provider, opts ...provid
 	})
 }
 
-// ExecuteProviders is now just a wrapper around the providers' process method
-func ExecuteProviders(ctx context.Context, providersList []base.Provider) error {
+// ExecuteProviders is now just a wrapper around the providers' process method.
+// parentID is the process manager process the runs belong to, if any.
+func ExecuteProviders(ctx context.Context, providersList []base.Provider, parentID string) error {
 	// Convert to Providers type
 	p := providers.Providers(providersList)
 
@@ -34,6 +35,7 @@ func ExecuteProviders(ctx context.Context, providersList []base.Provider) error
 	err := p.Process(ctx, providers.ProcessOptions{
 		UpdateCacheMode: providers.UpdateCacheNone,
 		TrackMetrics:    true,
+		ParentID:        parentID,
 	})
 
 	entryCollector := entry_collector.GetPondCollector()
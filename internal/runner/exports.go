@@ -0,0 +1,401 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"blacked/features/maintenance"
+	"blacked/features/providers"
+	"blacked/internal/config"
+	"blacked/internal/utils"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrExportAlreadyExists = errors.New("export already registered")
+	ErrExportRegister      = errors.New("failed to register export schedules")
+)
+
+// Conflicts found between an export schedule and the provider schedules.
+const (
+	// ConflictUnknownProvider: a provider the export depends on is not
+	// registered, because it is disabled or not configured.
+	ConflictUnknownProvider = "unknown_provider"
+	// ConflictLeadTooShort: the provider's runs take longer on average than
+	// the lead, so its sync may not be done when the export is applied.
+	ConflictLeadTooShort = "lead_too_short"
+	// ConflictProviderRun: a cron run of the provider starts between the
+	// prefetch and the apply time, and may be in flight when the export is
+	// applied.
+	ConflictProviderRun = "provider_run_in_window"
+	// ConflictExportOverlap: another export syncs the same provider in an
+	// overlapping window; the provider is synced once, by the first.
+	ConflictExportOverlap = "export_overlap"
+)
+
+// exportSchedule is a registered [exports.<name>] schedule.
+type exportSchedule struct {
+	name      string
+	cron      string
+	loc       *time.Location
+	providers []string
+	lead      time.Duration
+	schedule  cron.Schedule // The apply times, read in loc
+	job       gocron.Job    // Fires lead before every apply time
+}
+
+// applyTimes returns the apply times in (from, until], at most maxPlannedRuns.
+func (e *exportSchedule) applyTimes(from, until time.Time) []time.Time {
+	var times []time.Time
+	for t := e.schedule.Next(from); !t.IsZero() && !t.After(until) && len(times) < maxPlannedRuns; t = e.schedule.Next(t) {
+		times = append(times, t)
+	}
+	return times
+}
+
+// parseCron parses crontab like gocron does, in loc unless it names its own zone.
+func parseCron(crontab string, loc *time.Location) (cron.Schedule, error) {
+	if !strings.HasPrefix(crontab, "TZ=") && !strings.HasPrefix(crontab, "CRON_TZ=") {
+		crontab = "CRON_TZ=" + loc.String() + " " + crontab
+	}
+	schedule, err := cron.ParseStandard(crontab)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidCronSchedule, err)
+	}
+	return schedule, nil
+}
+
+// leadCron is a gocron cron firing lead before every time its crontab names.
+type leadCron struct {
+	lead     time.Duration
+	schedule cron.Schedule
+}
+
+func (c *leadCron) IsValid(crontab string, loc *time.Location, now time.Time) error {
+	schedule, err := parseCron(crontab, loc)
+	if err != nil {
+		return err
+	}
+	if schedule.Next(now).IsZero() {
+		return fmt.Errorf("%w: %q never fires", ErrInvalidCronSchedule, crontab)
+	}
+	c.schedule = schedule
+	return nil
+}
+
+func (c *leadCron) Next(lastRun time.Time) time.Time {
+	next := c.schedule.Next(lastRun.Add(c.lead))
+	if next.IsZero() {
+		return next
+	}
+	return next.Add(-c.lead)
+}
+
+// RegisterExport schedules the prefetch of an export: its providers are
+// synced opts.Lead before every time opts.Cron names.
+func (r *Runner) RegisterExport(name string, opts *config.ExportSchedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.exports[name]; exists {
+		log.Error().Str("export", name).Msg("Export already registered")
+		return ErrExportAlreadyExists
+	}
+
+	loc := time.UTC
+	if opts.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(opts.Timezone); err != nil {
+			log.Error().Err(err).Str("export", name).Msg("Invalid export timezone")
+			return errors.Join(ErrInvalidTimezone, err)
+		}
+	}
+
+	crontab := cronInLocation(opts.Cron, loc)
+	schedule, err := parseCron(crontab, loc)
+	if err != nil {
+		log.Error().Err(err).Str("export", name).Str("cron", opts.Cron).Msg("Invalid export cron")
+		return err
+	}
+
+	// A group stands for its providers.
+	exportProviders, _ := config.GetConfig().ExpandProviderGroups(opts.Providers)
+
+	export := &exportSchedule{
+		name:      name,
+		cron:      opts.Cron,
+		loc:       loc,
+		providers: exportProviders,
+		lead:      opts.LeadTime(),
+		schedule:  schedule,
+	}
+
+	for _, provider := range export.providers {
+		if _, ok := r.providers[provider]; !ok {
+			log.Warn().Str("export", name).Str("provider", provider).Msg("Export depends on a provider that is not registered; it won't be synced")
+		}
+	}
+
+	job, err := r.scheduler.NewJob(
+		gocron.CronJob(crontab, false),
+		gocron.NewTask(r.prefetchExport, name),
+		gocron.WithCronImplementation(&leadCron{lead: export.lead}),
+		gocron.WithName(strings.Join([]string{"export", name}, "_")),
+		gocron.WithTags([]string{"export", name}...),
+	)
+	if err != nil {
+		log.Error().Err(err).Str("export", name).Msg("Failed to schedule export prefetch")
+		return ErrFailedToCreateJob
+	}
+	export.job = job
+	r.exports[name] = export
+
+	nextPrefetch, err := job.NextRun()
+	if err != nil {
+		log.Error().Err(err).Str("export", name).Msg("Failed to get next prefetch time")
+		return ErrFailedToGetNextRun
+	}
+
+	log.Info().
+		Str("export", name).
+		Str("cron", opts.Cron).
+		Str("timezone", loc.String()).
+		Strs("providers", export.providers).
+		Dur("lead", export.lead).
+		Time("next_prefetch", nextPrefetch.UTC()).
+		Msg("Export registered with scheduler")
+
+	return nil
+}
+
+// prefetchExport syncs the providers of an export ahead of its apply time.
+// Providers that completed a run within the lead are fresh enough and
+// skipped, so exports sharing a provider at the same time sync it once.
+func (r *Runner) prefetchExport(name string) {
+	r.mu.RLock()
+	export, ok := r.exports[name]
+	if !ok {
+		r.mu.RUnlock()
+		log.Error().Str("export", name).Msg("Export not found in registry")
+		return
+	}
+	now := time.Now()
+	applyAt := export.schedule.Next(now)
+
+	fresh := make(map[string]bool)
+	for _, run := range providers.GetProcessManager().GetProviderRuns(now.Add(-export.lead)) {
+		if run.Status == "completed" {
+			fresh[run.Provider] = true
+		}
+	}
+
+	var due []string
+	for _, provider := range export.providers {
+		if _, registered := r.providers[provider]; registered && !fresh[provider] {
+			due = append(due, provider)
+		}
+	}
+	r.mu.RUnlock()
+
+	if maintenance.ReadOnly(context.Background()) {
+		log.Info().Str("export", name).Msg("Skipping export prefetch in read-only maintenance mode")
+		return
+	}
+	if len(due) == 0 {
+		log.Info().Str("export", name).Msg("Providers of export are fresh, skipping prefetch")
+		return
+	}
+
+	log.Info().
+		Str("export", name).
+		Strs("providers", due).
+		Time("apply_at", applyAt.UTC()).
+		Msg("Prefetching providers of export")
+
+	for _, providerName := range due {
+		r.mu.RLock()
+		provider, ok := r.providers[providerName]
+		r.mu.RUnlock()
+		if !ok {
+			continue
+		}
+
+		// Fetch the feed again instead of replaying the stored response
+		utils.RemoveStoredResponse(providerName)
+
+		if err := ExecuteProvider(context.Background(), provider, providers.ProcessOptions{
+			UpdateCacheMode: providers.UpdateCacheDeferred,
+			TrackMetrics:    true,
+		}); err != nil {
+			log.Error().Err(err).Str("export", name).Str("provider", providerName).Msg("Error prefetching provider of export")
+		}
+	}
+
+	if time.Now().After(applyAt) {
+		log.Warn().
+			Str("export", name).
+			Time("apply_at", applyAt.UTC()).
+			Msg("Export prefetch finished after the apply time; raise its lead")
+		return
+	}
+	log.Info().Str("export", name).Msg("Export prefetch completed")
+}
+
+// ExportConflict is a reason the providers of an export may not be freshly
+// synced when it is applied.
+type ExportConflict struct {
+	Kind        string     `json:"kind"`
+	Provider    string     `json:"provider"`
+	Export      string     `json:"export,omitempty"` // The other export, for export_overlap
+	At          *time.Time `json:"at,omitempty"`     // First affected apply time
+	Occurrences int        `json:"occurrences"`      // Apply times affected within the window
+	Message     string     `json:"message"`
+}
+
+// ExportPlan is the prefetch plan of one export over a window.
+type ExportPlan struct {
+	Export       string           `json:"export"`
+	Cron         string           `json:"cron"`
+	Timezone     string           `json:"timezone"`
+	Providers    []string         `json:"providers"`
+	LeadMs       int64            `json:"lead_ms"`
+	NextApply    *time.Time       `json:"next_apply,omitempty"`
+	NextPrefetch *time.Time       `json:"next_prefetch,omitempty"`
+	Conflicts    []ExportConflict `json:"conflicts"`
+}
+
+// ExportPlans returns the plan of every registered export over the window
+// after now, by name, with the conflicts between its prefetches and the
+// provider schedules.
+func (r *Runner) ExportPlans(window time.Duration, now time.Time) []ExportPlan {
+	r.mu.RLock()
+	exports := make([]*exportSchedule, 0, len(r.exports))
+	for _, export := range r.exports {
+		exports = append(exports, export)
+	}
+	schedules := r.providerSchedules()
+	r.mu.RUnlock()
+
+	history := providers.GetProcessManager().GetProviderRuns(time.Time{})
+	return planExports(now, window, exports, schedules, history)
+}
+
+func planExports(now time.Time, window time.Duration, exports []*exportSchedule, schedules []providerSchedule, history []providers.ProviderRun) []ExportPlan {
+	until := now.Add(window)
+
+	schedulesByName := make(map[string]providerSchedule, len(schedules))
+	for _, s := range schedules {
+		schedulesByName[s.name] = s
+	}
+	runsByProvider := make(map[string][]providers.ProviderRun)
+	for _, run := range history {
+		runsByProvider[run.Provider] = append(runsByProvider[run.Provider], run)
+	}
+
+	applies := make(map[string][]time.Time, len(exports))
+	for _, export := range exports {
+		applies[export.name] = export.applyTimes(now, until)
+	}
+
+	plans := make([]ExportPlan, 0, len(exports))
+	for _, export := range exports {
+		plan := ExportPlan{
+			Export:    export.name,
+			Cron:      export.cron,
+			Timezone:  export.loc.String(),
+			Providers: export.providers,
+			LeadMs:    export.lead.Milliseconds(),
+			Conflicts: []ExportConflict{},
+		}
+		if times := applies[export.name]; len(times) > 0 {
+			apply, prefetch := times[0].UTC(), times[0].Add(-export.lead).UTC()
+			plan.NextApply, plan.NextPrefetch = &apply, &prefetch
+		}
+
+		for _, provider := range export.providers {
+			s, ok := schedulesByName[provider]
+			if !ok {
+				plan.Conflicts = append(plan.Conflicts, ExportConflict{
+					Kind:     ConflictUnknownProvider,
+					Provider: provider,
+					Message:  fmt.Sprintf("provider %s is not registered with the scheduler; it is disabled or not configured", provider),
+				})
+				continue
+			}
+
+			if estimate := averageDuration(runsByProvider[provider]); estimate > export.lead {
+				plan.Conflicts = append(plan.Conflicts, ExportConflict{
+					Kind:     ConflictLeadTooShort,
+					Provider: provider,
+					Message:  fmt.Sprintf("runs of %s take %s on average, longer than the %s lead", provider, estimate.Round(time.Second), export.lead),
+				})
+			}
+
+			if c, ok := windowConflict(applies[export.name], export.lead, func(from, to time.Time) bool {
+				return slices.ContainsFunc(s.nextRuns, func(t time.Time) bool { return !t.Before(from) && t.Before(to) })
+			}); ok {
+				c.Kind, c.Provider = ConflictProviderRun, provider
+				c.Message = fmt.Sprintf("a scheduled run of %s starts between the prefetch and the apply time", provider)
+				plan.Conflicts = append(plan.Conflicts, c)
+			}
+
+			for _, other := range exports {
+				if other == export || !slices.Contains(other.providers, provider) {
+					continue
+				}
+				if c, ok := windowConflict(applies[export.name], export.lead, func(from, to time.Time) bool {
+					return slices.ContainsFunc(applies[other.name], func(t time.Time) bool {
+						return t.Add(-other.lead).Before(to) && from.Before(t)
+					})
+				}); ok {
+					c.Kind, c.Provider, c.Export = ConflictExportOverlap, provider, other.name
+					c.Message = fmt.Sprintf("export %s also syncs %s in an overlapping window", other.name, provider)
+					plan.Conflicts = append(plan.Conflicts, c)
+				}
+			}
+		}
+		plans = append(plans, plan)
+	}
+
+	sort.Slice(plans, func(i, j int) bool { return plans[i].Export < plans[j].Export })
+	return plans
+}
+
+// windowConflict counts the prefetch windows [apply-lead, apply) for which
+// conflicts reports true, and reports whether there was any.
+func windowConflict(applies []time.Time, lead time.Duration, conflicts func(from, to time.Time) bool) (ExportConflict, bool) {
+	var c ExportConflict
+	for _, apply := range applies {
+		if !conflicts(apply.Add(-lead), apply) {
+			continue
+		}
+		if c.At == nil {
+			at := apply.UTC()
+			c.At = &at
+		}
+		c.Occurrences++
+	}
+	return c, c.Occurrences > 0
+}
+
+// registerAllExports adds the [exports.<name>] schedules to the runner.
+func registerAllExports(runner *Runner, exports map[string]*config.ExportSchedule) error {
+	for name, opts := range exports {
+		if opts == nil {
+			continue
+		}
+		if err := runner.RegisterExport(name, opts); err != nil {
+			log.Err(err).Str("export", name).Msg("Failed to register export")
+			return errors.Join(ErrExportRegister, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"blacked/features/providers"
+	"blacked/internal/config"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLeadCron(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &leadCron{lead: 30 * time.Minute}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err := c.IsValid(cronInLocation("0 6 * * *", amsterdam), time.UTC, now); err != nil {
+		t.Fatal(err)
+	}
+
+	// 06:00 in Amsterdam is 04:00 UTC in summer; the prefetch fires at 03:30.
+	first := c.Next(now)
+	if want := time.Date(2024, 6, 2, 3, 30, 0, 0, time.UTC); !first.Equal(want) {
+		t.Fatalf("first prefetch at %v, want %v", first.UTC(), want)
+	}
+	if second := c.Next(first); !second.Equal(first.Add(24 * time.Hour)) {
+		t.Errorf("second prefetch at %v, want a day after the first", second.UTC())
+	}
+
+	if err := (&leadCron{}).IsValid("not a cron", time.UTC, now); err == nil {
+		t.Error("expected an invalid crontab to be rejected")
+	}
+}
+
+func TestPlanExports(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	schedule := func(crontab string) *exportSchedule {
+		s, err := parseCron(crontab, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &exportSchedule{cron: crontab, loc: time.UTC, schedule: s}
+	}
+
+	firewall := schedule("0 6 * * *")
+	firewall.name, firewall.lead = "firewall", 30*time.Minute
+	firewall.providers = []string{"oisd-big", "urlhaus", "removed"}
+
+	proxy := schedule("15 6 * * *")
+	proxy.name, proxy.lead = "proxy", 20*time.Minute
+	proxy.providers = []string{"urlhaus"}
+
+	schedules := []providerSchedule{
+		// 05:45 falls between the 05:30 prefetch and the 06:00 apply.
+		{name: "oisd-big", scheduled: true, nextRuns: []time.Time{now.Add(5*time.Hour + 45*time.Minute), now.Add(12 * time.Hour)}},
+		{name: "urlhaus"},
+	}
+	history := []providers.ProviderRun{
+		{Provider: "oisd-big", Status: "completed", StartTime: now.Add(-2 * time.Hour), EndTime: now.Add(-2*time.Hour + 5*time.Minute)},
+		{Provider: "urlhaus", Status: "completed", StartTime: now.Add(-time.Hour), EndTime: now.Add(-time.Hour + 40*time.Minute)},
+	}
+
+	plans := planExports(now, 24*time.Hour, []*exportSchedule{proxy, firewall}, schedules, history)
+	if len(plans) != 2 || plans[0].Export != "firewall" {
+		t.Fatalf("expected plans sorted by export, got %+v", plans)
+	}
+
+	fw := plans[0]
+	if want := now.Add(6 * time.Hour); fw.NextApply == nil || !fw.NextApply.Equal(want) || !fw.NextPrefetch.Equal(want.Add(-30*time.Minute)) {
+		t.Errorf("unexpected next apply %v and prefetch %v", fw.NextApply, fw.NextPrefetch)
+	}
+
+	kinds := make(map[string]ExportConflict)
+	for _, c := range fw.Conflicts {
+		kinds[c.Kind+"/"+c.Provider] = c
+	}
+	for _, want := range []string{
+		ConflictUnknownProvider + "/removed",
+		ConflictProviderRun + "/oisd-big",
+		ConflictLeadTooShort + "/urlhaus",
+		ConflictExportOverlap + "/urlhaus",
+	} {
+		if _, ok := kinds[want]; !ok {
+			t.Errorf("missing conflict %s in %+v", want, fw.Conflicts)
+		}
+	}
+	if len(fw.Conflicts) != 4 {
+		t.Errorf("expected 4 conflicts, got %+v", fw.Conflicts)
+	}
+	if c := kinds[ConflictProviderRun+"/oisd-big"]; c.Occurrences != 1 || !c.At.Equal(now.Add(6*time.Hour)) {
+		t.Errorf("unexpected provider run conflict %+v", c)
+	}
+	if c := kinds[ConflictExportOverlap+"/urlhaus"]; c.Export != "proxy" {
+		t.Errorf("overlap should name the other export, got %+v", c)
+	}
+}
+
+func TestRegisterExport(t *testing.T) {
+	r, err := NewRunner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Stop(t.Context()) })
+	r.Start()
+
+	opts := &config.ExportSchedule{Cron: "0 6 * * *", Timezone: "America/New_York", Providers: []string{"oisd-big"}, Lead: time.Hour}
+	if err := r.RegisterExport("firewall", opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RegisterExport("firewall", opts); !errors.Is(err, ErrExportAlreadyExists) {
+		t.Fatalf("expected ErrExportAlreadyExists, got %v", err)
+	}
+
+	next, err := r.exports["firewall"].job.NextRun()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if local := next.In(r.exports["firewall"].loc); local.Hour() != 5 || local.Minute() != 0 {
+		t.Errorf("expected the prefetch at 05:00 New York time, got %v", local)
+	}
+
+	invalid := &config.ExportSchedule{Cron: "0 6 * * *", Timezone: "Mars/Olympus_Mons", Providers: []string{"oisd-big"}}
+	if err := r.RegisterExport("invalid", invalid); !errors.Is(err, ErrInvalidTimezone) {
+		t.Fatalf("expected ErrInvalidTimezone, got %v", err)
+	}
+}
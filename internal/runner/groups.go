@@ -0,0 +1,169 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"blacked/features/maintenance"
+	"blacked/features/providers"
+	"blacked/features/providers/base"
+	"blacked/internal/config"
+	"blacked/internal/utils"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrGroupAlreadyExists = errors.New("provider group already registered")
+	ErrGroupRegister      = errors.New("failed to register provider group schedules")
+)
+
+// groupSchedule is a registered [provider_groups.<name>] with a cron.
+type groupSchedule struct {
+	providers []string
+	job       gocron.Job
+}
+
+// RegisterGroup schedules the runs of a provider group: on every time
+// opts.Cron names, its providers run as one process.
+func (r *Runner) RegisterGroup(name string, opts *config.ProviderGroup) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.groups[name]; exists {
+		log.Error().Str("group", name).Msg("Provider group already registered")
+		return ErrGroupAlreadyExists
+	}
+
+	loc := time.UTC
+	if opts.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(opts.Timezone); err != nil {
+			log.Error().Err(err).Str("group", name).Msg("Invalid provider group timezone")
+			return errors.Join(ErrInvalidTimezone, err)
+		}
+	}
+
+	crontab := cronInLocation(opts.Cron, loc)
+	if _, err := parseCron(crontab, loc); err != nil {
+		log.Error().Err(err).Str("group", name).Str("cron", opts.Cron).Msg("Invalid provider group cron")
+		return err
+	}
+
+	group := &groupSchedule{providers: slices.Clone(opts.Providers)}
+	for _, provider := range group.providers {
+		if _, ok := r.providers[provider]; !ok {
+			log.Warn().Str("group", name).Str("provider", provider).Msg("Provider group names a provider that is not registered; it won't be run")
+		}
+	}
+
+	job, err := r.scheduler.NewJob(
+		gocron.CronJob(crontab, false),
+		gocron.NewTask(r.executeGroup, name),
+		gocron.WithName(strings.Join([]string{"group", name}, "_")),
+		gocron.WithTags([]string{"group", name}...),
+	)
+	if err != nil {
+		log.Error().Err(err).Str("group", name).Msg("Failed to schedule provider group")
+		return ErrFailedToCreateJob
+	}
+	group.job = job
+	r.groups[name] = group
+
+	nextRun, err := job.NextRun()
+	if err != nil {
+		log.Error().Err(err).Str("group", name).Msg("Failed to get next run time")
+		return ErrFailedToGetNextRun
+	}
+
+	log.Info().
+		Str("group", name).
+		Str("cron", opts.Cron).
+		Str("timezone", loc.String()).
+		Strs("providers", group.providers).
+		Time("next_run", nextRun.UTC()).
+		Msg("Provider group registered with scheduler")
+
+	return nil
+}
+
+// executeGroup runs the registered providers of a group as one process,
+// whose history lists the run of each. It is skipped while another process
+// runs.
+func (r *Runner) executeGroup(name string) {
+	r.mu.RLock()
+	group, ok := r.groups[name]
+	var members []base.Provider
+	if ok {
+		for _, providerName := range group.providers {
+			if provider, registered := r.providers[providerName]; registered {
+				members = append(members, provider)
+			}
+		}
+	}
+	r.mu.RUnlock()
+
+	if !ok {
+		log.Error().Str("group", name).Msg("Provider group not found in registry")
+		return
+	}
+	if maintenance.ReadOnly(context.Background()) {
+		log.Info().Str("group", name).Msg("Skipping scheduled group run in read-only maintenance mode")
+		return
+	}
+	if len(members) == 0 {
+		log.Warn().Str("group", name).Msg("No provider of the group is registered, skipping run")
+		return
+	}
+
+	names := make([]string, len(members))
+	for i, provider := range members {
+		names[i] = provider.GetName()
+	}
+
+	pm := providers.GetProcessManager()
+	processID, err := pm.TryStartProcess(context.Background(), "schedule", names, nil, []string{name})
+	if err != nil {
+		log.Warn().Err(err).Str("group", name).Msg("Cannot run provider group - another process is running")
+		return
+	}
+
+	log.Info().
+		Str("group", name).
+		Str("process_id", processID).
+		Strs("providers", names).
+		Msg("Starting scheduled execution of provider group")
+
+	// CRON triggered — invalidate cached responses so fresh data is fetched
+	for _, providerName := range names {
+		utils.RemoveStoredResponse(providerName)
+	}
+
+	processErr := providers.Providers(members).Process(context.Background(), providers.ProcessOptions{
+		UpdateCacheMode: providers.UpdateCacheDeferred,
+		TrackMetrics:    true,
+		ParentID:        processID,
+	})
+	if processErr != nil {
+		log.Error().Err(processErr).Str("group", name).Msg("Error executing provider group")
+	}
+	pm.FinishProcess(processID, processErr)
+}
+
+// registerAllGroups adds the [provider_groups.<name>] with a cron to the runner.
+func registerAllGroups(runner *Runner, groups map[string]*config.ProviderGroup) error {
+	for name, opts := range groups {
+		if opts == nil || strings.TrimSpace(opts.Cron) == "" {
+			continue
+		}
+		if err := runner.RegisterGroup(name, opts); err != nil {
+			log.Err(err).Str("group", name).Msg("Failed to register provider group")
+			return errors.Join(ErrGroupRegister, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"blacked/features/entry_collector"
+	"blacked/features/providers"
+	"blacked/features/providers/base"
+	"blacked/internal/config"
+	"blacked/internal/db"
+)
+
+// groupTestProvider serves an empty feed, or fails its fetch with err.
+type groupTestProvider struct {
+	*base.BaseProvider
+	err error
+}
+
+func (p *groupTestProvider) Fetch() (io.Reader, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return strings.NewReader(""), nil
+}
+
+func newGroupTestProvider(name string, err error) *groupTestProvider {
+	parse := func(io.Reader, entry_collector.Collector) error { return nil }
+	return &groupTestProvider{
+		BaseProvider: base.NewBaseProvider(name, "https://example.com/"+name, "phishing", nil, parse),
+		err:          err,
+	}
+}
+
+func TestRegisterGroup(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg := config.GetConfig()
+	prev := cfg.Collector.StoreResponses
+	cfg.Collector.StoreResponses = false
+	t.Cleanup(func() { cfg.Collector.StoreResponses = prev })
+
+	db.ResetForTesting()
+	t.Cleanup(db.ResetForTesting)
+	db.InitializeDB(db.WithPath(t.TempDir() + "/blacked.db"))
+	writeDB, err := db.GetWriteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry_collector.InitPondCollector(t.Context(), writeDB)
+
+	pm := providers.GetProcessManager()
+	pm.ResetForTesting()
+	t.Cleanup(pm.ResetForTesting)
+
+	r, err := NewRunner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Stop(t.Context()) })
+
+	for _, p := range []*groupTestProvider{
+		newGroupTestProvider("group-openphish", nil),
+		newGroupTestProvider("group-phishtank", errors.New("feed unavailable")),
+	} {
+		if err := r.RegisterProvider(p, ""); err != nil {
+			t.Fatalf("register %s: %v", p.GetName(), err)
+		}
+	}
+
+	opts := &config.ProviderGroup{Providers: []string{"group-openphish", "group-phishtank", "group-missing"}, Cron: "0 */2 * * *"}
+	if err := r.RegisterGroup("phishing", opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RegisterGroup("phishing", opts); !errors.Is(err, ErrGroupAlreadyExists) {
+		t.Fatalf("expected ErrGroupAlreadyExists, got %v", err)
+	}
+	if err := r.RegisterGroup("invalid", &config.ProviderGroup{Providers: opts.Providers, Cron: "61 * * * *"}); err == nil {
+		t.Fatal("expected an invalid cron to fail")
+	}
+
+	r.executeGroup("phishing")
+
+	processes := pm.GetRecentProcesses(10)
+	if len(processes) != 1 {
+		t.Fatalf("expected one process for the group, got %d", len(processes))
+	}
+	process := processes[0]
+	if len(process.Groups) != 1 || process.Groups[0] != "phishing" {
+		t.Errorf("expected the process to list its group, got %v", process.Groups)
+	}
+	if len(process.ProvidersProcessed) != 2 {
+		t.Errorf("expected the registered members only, got %v", process.ProvidersProcessed)
+	}
+	if process.Status != "failed" || process.Error == "" {
+		t.Errorf("expected the failed member to fail the process, got %q (%q)", process.Status, process.Error)
+	}
+
+	runs := pm.ProcessRuns(process.ID)
+	if len(runs) != 2 || len(process.Runs) != 2 {
+		t.Fatalf("expected a run per member, got %+v", runs)
+	}
+	status := make(map[string]string)
+	for _, run := range runs {
+		if run.ProcessID == process.ID {
+			t.Errorf("run of %s shares the ID of its parent", run.Provider)
+		}
+		status[run.Provider] = run.Status
+	}
+	if status["group-openphish"] != "completed" || status["group-phishtank"] != "failed" {
+		t.Errorf("unexpected member runs %v", status)
+	}
+}
@@ -1,10 +1,13 @@
 package runner
 
 import (
+	"blacked/features/providers"
 	"blacked/features/providers/base"
+	"blacked/internal/config"
 	"context"
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
@@ -41,6 +44,20 @@ func InitializeRunner(providers []base.Provider) (*Runner, error) {
 			return
 		}
 
+		// Schedule the prefetch of exports once their providers are known
+		if err := registerAllExports(_globalRunner, config.GetConfig().Exports); err != nil {
+			log.Err(err).Msg("Failed to register exports")
+			initError = ErrExportRegister
+			return
+		}
+
+		// Schedule the provider groups with a cron of their own
+		if err := registerAllGroups(_globalRunner, config.GetConfig().ProviderGroups); err != nil {
+			log.Err(err).Msg("Failed to register provider groups")
+			initError = ErrGroupRegister
+			return
+		}
+
 		// Start the scheduler
 		globalRunner = _globalRunner
 		globalRunner.Start()
@@ -71,10 +88,24 @@ func GetRunner() (*Runner, error) {
 	return globalRunner, nil
 }
 
-// ShutdownRunner stops the global runner
+// notificationsTimeout bounds the wait of ShutdownRunner for the
+// notifications of finished runs.
+const notificationsTimeout = 30 * time.Second
+
+// ShutdownRunner stops the global runner, then waits for the alerts,
+// watchlist events and webhooks of finished runs, before the database
+// they read closes.
 func ShutdownRunner(ctx context.Context) error {
-	if globalRunner == nil {
-		return nil
+	var err error
+	if globalRunner != nil {
+		err = globalRunner.Stop(ctx)
+	}
+
+	// ctx is usually the one the shutdown signal cancelled.
+	waitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notificationsTimeout)
+	defer cancel()
+	if werr := providers.WaitNotifications(waitCtx); werr != nil {
+		log.Warn().Err(werr).Msg("Stopped waiting for the notifications of finished provider runs")
 	}
-	return globalRunner.Stop(ctx)
+	return err
 }
@@ -3,16 +3,22 @@ package runner
 import (
 	"context"
 	"errors"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"blacked/features/maintenance"
 	"blacked/features/providers"
 	"blacked/features/providers/base"
+	"blacked/internal/config"
 	"blacked/internal/utils"
 
 	"github.com/go-co-op/gocron/v2"
 	"github.com/rs/zerolog/log"
+
+	// Provider timezones must resolve on images without zoneinfo.
+	_ "time/tzdata"
 )
 
 // Error variables for runner package
@@ -24,6 +30,7 @@ var (
 	ErrNoProvidersSpecified    = errors.New("no providers specified for scheduling")
 	ErrRunnerNotInitialized    = errors.New("cron runner not initialized")
 	ErrInvalidCronSchedule     = errors.New("invalid cron schedule")
+	ErrInvalidTimezone         = errors.New("invalid provider timezone")
 )
 
 // Runner manages scheduled provider executions
@@ -31,6 +38,9 @@ type Runner struct {
 	scheduler gocron.Scheduler
 	jobs      map[string]gocron.Job
 	providers map[string]base.Provider
+	locations map[string]*time.Location // Timezone each provider's cron is read in
+	exports   map[string]*exportSchedule
+	groups    map[string]*groupSchedule
 	mu        sync.RWMutex
 }
 
@@ -52,9 +62,26 @@ func NewRunner() (*Runner, error) {
 		scheduler: scheduler,
 		jobs:      make(map[string]gocron.Job),
 		providers: make(map[string]base.Provider),
+		locations: make(map[string]*time.Location),
+		exports:   make(map[string]*exportSchedule),
+		groups:    make(map[string]*groupSchedule),
 	}, nil
 }
 
+// ProviderLocation returns the timezone of [Providers.<name>] timezone, the
+// IANA name the provider's cron is read in; UTC when unset.
+func ProviderLocation(providerName string) (*time.Location, error) {
+	opts := config.GetConfig().Providers[providerName]
+	if opts == nil || opts.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(opts.Timezone)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidTimezone, err)
+	}
+	return loc, nil
+}
+
 // RegisterProvider adds a provider to the runner with optional cron schedule
 func (r *Runner) RegisterProvider(provider base.Provider, cronSchedule string) error {
 	r.mu.Lock()
@@ -68,8 +95,15 @@ func (r *Runner) RegisterProvider(provider base.Provider, cronSchedule string) e
 		return ErrProviderAlreadyExists
 	}
 
+	loc, err := ProviderLocation(providerName)
+	if err != nil {
+		log.Error().Err(err).Str("provider", providerName).Msg("Invalid provider timezone")
+		return err
+	}
+
 	// Add provider to registry
 	r.providers[providerName] = provider
+	r.locations[providerName] = loc
 
 	// Use provided cron schedule or default from config
 	if cronSchedule == "" {
@@ -84,9 +118,9 @@ func (r *Runner) RegisterProvider(provider base.Provider, cronSchedule string) e
 
 	// Create a job for this provider
 	job, err := r.scheduler.NewJob(
-		// Use the provided cron schedule
+		// Use the provided cron schedule, read in the provider's timezone
 		gocron.CronJob(
-			cronSchedule,
+			cronInLocation(cronSchedule, loc),
 			false,
 		),
 
@@ -117,12 +151,22 @@ func (r *Runner) RegisterProvider(provider base.Provider, cronSchedule string) e
 	log.Info().
 		Str("provider", providerName).
 		Str("cron", cronSchedule).
-		Time("next_run", nextRun).
+		Str("timezone", loc.String()).
+		Time("next_run", nextRun.UTC()).
 		Msg("Provider registered with scheduler")
 
 	return nil
 }
 
+// cronInLocation prefixes crontab with the CRON_TZ of loc, unless it is UTC,
+// the scheduler's own location, or the crontab names its own zone.
+func cronInLocation(crontab string, loc *time.Location) string {
+	if loc == time.UTC || strings.HasPrefix(crontab, "TZ=") || strings.HasPrefix(crontab, "CRON_TZ=") {
+		return crontab
+	}
+	return "CRON_TZ=" + loc.String() + " " + crontab
+}
+
 // executeProvider is the function that gets called on schedule
 func (r *Runner) executeProvider(providerName string) {
 	r.mu.RLock()
@@ -135,6 +179,11 @@ func (r *Runner) executeProvider(providerName string) {
 		return
 	}
 
+	if maintenance.ReadOnly(context.Background()) {
+		log.Info().Str("provider", providerName).Msg("Skipping scheduled run in read-only maintenance mode")
+		return
+	}
+
 	log.Info().
 		Str("provider", providerName).
 		Msg("Starting scheduled execution of provider")
@@ -214,7 +263,7 @@ func (r *Runner) RunProviderJobsNow() {
 	log.Info().Int("count", len(providersList)).Msg("Running all providers at startup in bulk mode")
 
 	// Execute all providers in bulk with a single cache update at the end
-	if err := ExecuteProviders(context.Background(), providersList); err != nil {
+	if err := ExecuteProviders(context.Background(), providersList, ""); err != nil {
 		log.Error().Err(err).Msg("Error executing providers in bulk at startup")
 	} else {
 		log.Info().Msg("Successfully executed all providers at startup")
@@ -244,7 +293,7 @@ func (r *Runner) RunProviderJobsAsync() {
 
 	// Try to acquire process lock via the process manager
 	pm := providers.GetProcessManager()
-	processID, err := pm.TryStartProcess(context.Background(), "startup", providerNames, nil)
+	processID, err := pm.TryStartProcess(context.Background(), "startup", providerNames, nil, nil)
 	if err != nil {
 		log.Warn().Err(err).Msg("Cannot run startup providers - another process is running")
 		return
@@ -262,7 +311,7 @@ func (r *Runner) RunProviderJobsAsync() {
 			pm.FinishProcess(processID, processErr)
 		}()
 
-		processErr = ExecuteProviders(context.Background(), providersList)
+		processErr = ExecuteProviders(context.Background(), providersList, processID)
 		if processErr != nil {
 			log.Error().Err(processErr).Msg("Error executing providers in bulk at startup")
 		} else {
@@ -271,6 +320,42 @@ func (r *Runner) RunProviderJobsAsync() {
 	}()
 }
 
+// ProviderSchedule is the cron schedule of one provider and its next run,
+// in UTC and in the provider's timezone.
+type ProviderSchedule struct {
+	Provider     string     `json:"provider"`
+	Cron         string     `json:"cron,omitempty"`
+	Timezone     string     `json:"timezone"`
+	Scheduled    bool       `json:"scheduled"`
+	NextRun      *time.Time `json:"next_run,omitempty"`
+	NextRunLocal *time.Time `json:"next_run_local,omitempty"`
+}
+
+// Schedules returns the schedule of every registered provider, by name.
+func (r *Runner) Schedules() []ProviderSchedule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schedules := make([]ProviderSchedule, 0, len(r.providers))
+	for name, provider := range r.providers {
+		loc := r.locations[name]
+		s := ProviderSchedule{Provider: name, Cron: provider.GetCronSchedule(), Timezone: loc.String()}
+		if job, ok := r.jobs[name]; ok {
+			s.Scheduled = true
+			if next, err := job.NextRun(); err != nil {
+				log.Error().Err(err).Str("provider", name).Msg("Error getting next run time")
+			} else if !next.IsZero() {
+				utc, local := next.UTC(), next.In(loc)
+				s.NextRun, s.NextRunLocal = &utc, &local
+			}
+		}
+		schedules = append(schedules, s)
+	}
+
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].Provider < schedules[j].Provider })
+	return schedules
+}
+
 // GetAllNextRunTimes returns all scheduled run times by provider
 func (r *Runner) GetAllNextRunTimes() map[string]time.Time {
 	result := make(map[string]time.Time)
@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"blacked/features/providers/base"
+	"blacked/internal/config"
+)
+
+func TestRegisterProviderTimezone(t *testing.T) {
+	cfg := config.GetConfig()
+	prev := cfg.Providers
+	cfg.Providers = map[string]*config.ProviderOptions{
+		"tz-new-york": {Timezone: "America/New_York"},
+		"tz-invalid":  {Timezone: "Mars/Olympus_Mons"},
+	}
+	t.Cleanup(func() { cfg.Providers = prev })
+
+	r, err := NewRunner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Stop(t.Context()) })
+
+	for _, name := range []string{"tz-new-york", "tz-utc"} {
+		p := base.NewBaseProvider(name, "https://example.com/"+name, "malware", nil, nil).SetCronSchedule("0 6 * * *")
+		base.RegisterProvider(p)
+		if err := r.RegisterProvider(p, p.GetCronSchedule()); err != nil {
+			t.Fatalf("register %s: %v", name, err)
+		}
+	}
+
+	invalid := base.NewBaseProvider("tz-invalid", "https://example.com/tz-invalid", "malware", nil, nil).SetCronSchedule("0 6 * * *")
+	base.RegisterProvider(invalid)
+	if err := r.RegisterProvider(invalid, invalid.GetCronSchedule()); !errors.Is(err, ErrInvalidTimezone) {
+		t.Fatalf("expected ErrInvalidTimezone, got %v", err)
+	}
+
+	r.Start()
+	schedules := r.Schedules()
+	if len(schedules) != 2 || schedules[0].Provider != "tz-new-york" || schedules[1].Provider != "tz-utc" {
+		t.Fatalf("unexpected schedules %+v", schedules)
+	}
+
+	ny := schedules[0]
+	if ny.Timezone != "America/New_York" || ny.NextRun == nil || ny.NextRunLocal == nil {
+		t.Fatalf("unexpected schedule %+v", ny)
+	}
+	if h := ny.NextRunLocal.Hour(); h != 6 {
+		t.Errorf("expected the cron read in New York time, next local run at %v", ny.NextRunLocal)
+	}
+	if !ny.NextRun.Equal(*ny.NextRunLocal) || ny.NextRun.Location() != time.UTC {
+		t.Errorf("next run %v should be the UTC form of %v", ny.NextRun, ny.NextRunLocal)
+	}
+
+	utc := schedules[1]
+	if utc.Timezone != "UTC" || utc.NextRun.Hour() != 6 {
+		t.Errorf("providers without a timezone keep UTC, got %+v", utc)
+	}
+}
+
+func TestCronInLocation(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		crontab string
+		loc     *time.Location
+		want    string
+	}{
+		{"0 6 * * *", time.UTC, "0 6 * * *"},
+		{"0 6 * * *", ny, "CRON_TZ=America/New_York 0 6 * * *"},
+		{"CRON_TZ=Europe/Berlin 0 6 * * *", ny, "CRON_TZ=Europe/Berlin 0 6 * * *"},
+	}
+	for _, tc := range cases {
+		if got := cronInLocation(tc.crontab, tc.loc); got != tc.want {
+			t.Errorf("cronInLocation(%q, %v) = %q, want %q", tc.crontab, tc.loc, got, tc.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package runner
 import (
 	"blacked/features/entry_collector"
 	"blacked/features/entries/repository"
+	"blacked/features/maintenance"
 	"blacked/features/providers"
 	"blacked/features/providers/base"
 	"blacked/internal/config"
@@ -249,6 +250,11 @@ func RunStartupProviders(ctx context.Context, providers []base.Provider) error {
 		return nil
 	}
 
+	if maintenance.ReadOnly(ctx) {
+		log.Warn().Int("providers", len(providers)).Msg("startup ingestion skipped in read-only maintenance mode")
+		return nil
+	}
+
 	log.Info().Int("providers", len(providers)).Msg("evaluating startup state for providers")
 
 	decisions, err := EvaluateStartupState(ctx, providers)
@@ -0,0 +1,185 @@
+package runner
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"blacked/features/providers"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	IntervalPlanned    = "planned"
+	IntervalHistorical = "historical"
+
+	// maxPlannedRuns caps how many future runs are expanded per provider,
+	// so a per-minute cron over a long window stays bounded.
+	maxPlannedRuns = 1000
+)
+
+// TimelineInterval is one bar on a provider's timeline.
+// Planned intervals use the provider's average historical duration as width.
+type TimelineInterval struct {
+	Kind      string    `json:"kind"` // "planned" or "historical"
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Status    string    `json:"status,omitempty"`
+	ProcessID string    `json:"process_id,omitempty"`
+	Entries   int       `json:"entries,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// ProviderTimeline holds the planned and historical runs of one provider.
+type ProviderTimeline struct {
+	Provider            string             `json:"provider"`
+	Cron                string             `json:"cron,omitempty"`
+	Timezone            string             `json:"timezone,omitempty"` // Zone the cron is read in
+	Scheduled           bool               `json:"scheduled"`
+	EstimatedDurationMs int64              `json:"estimated_duration_ms"`
+	Intervals           []TimelineInterval `json:"intervals"`
+}
+
+// Timeline covers [Now-Window, Now+Window]: history before Now, plans after it.
+type Timeline struct {
+	Now         time.Time          `json:"now"`
+	WindowStart time.Time          `json:"window_start"`
+	WindowEnd   time.Time          `json:"window_end"`
+	Window      string             `json:"window"`
+	Providers   []ProviderTimeline `json:"providers"`
+}
+
+// providerSchedule is the scheduling snapshot of one provider taken under the runner lock.
+type providerSchedule struct {
+	name      string
+	cron      string
+	timezone  string
+	scheduled bool
+	nextRuns  []time.Time
+}
+
+// Timeline builds the scheduler timeline for the given window around now,
+// combining upcoming cron runs with the process manager's run history.
+func (r *Runner) Timeline(window time.Duration, now time.Time) *Timeline {
+	r.mu.RLock()
+	schedules := r.providerSchedules()
+	r.mu.RUnlock()
+
+	history := providers.GetProcessManager().GetProviderRuns(now.Add(-window))
+	return buildTimeline(now, window, schedules, history)
+}
+
+// providerSchedules snapshots the schedule of every registered provider;
+// the caller holds r.mu.
+func (r *Runner) providerSchedules() []providerSchedule {
+	schedules := make([]providerSchedule, 0, len(r.providers))
+	for name, provider := range r.providers {
+		s := providerSchedule{name: name, cron: provider.GetCronSchedule()}
+		if loc, ok := r.locations[name]; ok {
+			s.timezone = loc.String()
+		}
+		if job, ok := r.jobs[name]; ok {
+			s.scheduled = true
+			runs, err := job.NextRuns(maxPlannedRuns)
+			if err != nil {
+				log.Error().Err(err).Str("provider", name).Msg("Error getting next run times")
+			}
+			s.nextRuns = runs
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules
+}
+
+func buildTimeline(now time.Time, window time.Duration, schedules []providerSchedule, history []providers.ProviderRun) *Timeline {
+	windowStart, windowEnd := now.Add(-window), now.Add(window)
+
+	runsByProvider := make(map[string][]providers.ProviderRun)
+	for _, run := range history {
+		runsByProvider[run.Provider] = append(runsByProvider[run.Provider], run)
+	}
+
+	// Providers that ran but are no longer registered with the runner still get a row.
+	for name := range runsByProvider {
+		if !slices.ContainsFunc(schedules, func(s providerSchedule) bool { return s.name == name }) {
+			schedules = append(schedules, providerSchedule{name: name})
+		}
+	}
+
+	timeline := &Timeline{
+		Now:         now,
+		WindowStart: windowStart,
+		WindowEnd:   windowEnd,
+		Window:      window.String(),
+		Providers:   make([]ProviderTimeline, 0, len(schedules)),
+	}
+
+	for _, s := range schedules {
+		runs := runsByProvider[s.name]
+		estimate := averageDuration(runs)
+
+		pt := ProviderTimeline{
+			Provider:            s.name,
+			Cron:                s.cron,
+			Timezone:            s.timezone,
+			Scheduled:           s.scheduled,
+			EstimatedDurationMs: estimate.Milliseconds(),
+			Intervals:           make([]TimelineInterval, 0, len(runs)),
+		}
+
+		for _, run := range runs {
+			if run.StartTime.After(windowEnd) {
+				continue
+			}
+			pt.Intervals = append(pt.Intervals, TimelineInterval{
+				Kind:      IntervalHistorical,
+				Start:     run.StartTime,
+				End:       run.EndTime,
+				Status:    run.Status,
+				ProcessID: run.ProcessID,
+				Entries:   run.Entries,
+				Error:     run.Error,
+			})
+		}
+
+		for _, start := range s.nextRuns {
+			if start.Before(now) || start.After(windowEnd) {
+				continue
+			}
+			pt.Intervals = append(pt.Intervals, TimelineInterval{
+				Kind:  IntervalPlanned,
+				Start: start,
+				End:   start.Add(estimate),
+			})
+		}
+
+		sort.SliceStable(pt.Intervals, func(i, j int) bool {
+			return pt.Intervals[i].Start.Before(pt.Intervals[j].Start)
+		})
+		timeline.Providers = append(timeline.Providers, pt)
+	}
+
+	sort.Slice(timeline.Providers, func(i, j int) bool {
+		return timeline.Providers[i].Provider < timeline.Providers[j].Provider
+	})
+
+	return timeline
+}
+
+// averageDuration returns the mean duration of completed runs, or zero if none.
+func averageDuration(runs []providers.ProviderRun) time.Duration {
+	var total time.Duration
+	var n int
+	for _, run := range runs {
+		if run.Status != "completed" {
+			continue
+		}
+		total += run.EndTime.Sub(run.StartTime)
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return total / time.Duration(n)
+}
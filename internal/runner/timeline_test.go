@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"blacked/features/providers"
+	"testing"
+	"time"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	window := 6 * time.Hour
+
+	schedules := []providerSchedule{
+		{
+			name:      "OISD-Big",
+			cron:      "0 */4 * * *",
+			scheduled: true,
+			nextRuns: []time.Time{
+				now.Add(4 * time.Hour),
+				now.Add(8 * time.Hour), // outside the window
+			},
+		},
+		{name: "PhishTank", cron: "", scheduled: false},
+	}
+
+	history := []providers.ProviderRun{
+		{Provider: "OISD-Big", Status: "completed", StartTime: now.Add(-4 * time.Hour), EndTime: now.Add(-4*time.Hour + 2*time.Minute)},
+		{Provider: "OISD-Big", Status: "failed", StartTime: now.Add(-time.Hour), EndTime: now.Add(-time.Hour + 10*time.Second), Error: "boom"},
+		{Provider: "Removed", Status: "completed", StartTime: now.Add(-2 * time.Hour), EndTime: now.Add(-2*time.Hour + time.Minute)},
+	}
+
+	tl := buildTimeline(now, window, schedules, history)
+
+	if !tl.WindowStart.Equal(now.Add(-window)) || !tl.WindowEnd.Equal(now.Add(window)) {
+		t.Fatalf("unexpected window [%v, %v]", tl.WindowStart, tl.WindowEnd)
+	}
+	if len(tl.Providers) != 3 {
+		t.Fatalf("expected 3 provider rows, got %d", len(tl.Providers))
+	}
+
+	oisd := tl.Providers[0]
+	if oisd.Provider != "OISD-Big" {
+		t.Fatalf("expected providers sorted by name, got %q first", oisd.Provider)
+	}
+	if oisd.EstimatedDurationMs != (2 * time.Minute).Milliseconds() {
+		t.Errorf("estimate should only use completed runs, got %dms", oisd.EstimatedDurationMs)
+	}
+	if len(oisd.Intervals) != 3 {
+		t.Fatalf("expected 2 historical + 1 planned interval, got %d", len(oisd.Intervals))
+	}
+
+	planned := oisd.Intervals[2]
+	if planned.Kind != IntervalPlanned || !planned.End.Equal(planned.Start.Add(2*time.Minute)) {
+		t.Errorf("unexpected planned interval %+v", planned)
+	}
+	if oisd.Intervals[1].Status != "failed" || oisd.Intervals[1].Kind != IntervalHistorical {
+		t.Errorf("unexpected historical interval %+v", oisd.Intervals[1])
+	}
+
+	if tl.Providers[1].Provider != "PhishTank" || len(tl.Providers[1].Intervals) != 0 {
+		t.Errorf("unscheduled provider without history should have no intervals: %+v", tl.Providers[1])
+	}
+	if tl.Providers[2].Provider != "Removed" || len(tl.Providers[2].Intervals) != 1 {
+		t.Errorf("history of unregistered provider should be kept: %+v", tl.Providers[2])
+	}
+}
@@ -0,0 +1,400 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"blacked/features/entries"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrInvalidEntries  = errors.New("entries must be greater than zero")
+	ErrInvalidSources  = errors.New("sources must be between 1 and 100")
+	ErrInvalidRatio    = errors.New("duplicate and path ratios must be between 0 and 1")
+	ErrInvalidPrefix   = errors.New("source prefix must not be empty")
+	ErrSeedInterrupted = errors.New("seeding interrupted")
+	ErrSeedUnavailable = errors.New("seeding unavailable: entry collector not ready")
+)
+
+const (
+	maxSources = 100
+	poolSize   = 100_000 // URLs kept for cross-source duplicates
+	ipRatio    = 0.04    // Hosts that are IPv4 addresses
+	portRatio  = 0.02    // URLs with an explicit port
+)
+
+// Collector writes seeded entries; implemented by
+// entry_collector.PondCollector.
+type Collector interface {
+	Submit(entry *entries.Entry)
+	StartProviderProcessing(ctx context.Context, providerName, processID string)
+	FinishProviderProcessing(providerName, processID string) (count int, duration time.Duration, ok bool)
+}
+
+// Options configures a seeding run.
+type Options struct {
+	Entries        int     // Entries generated across all sources
+	Sources        int     // Number of synthetic sources, named <Prefix>-1..n
+	Prefix         string  // Source name prefix
+	Seed           uint64  // Random seed; the same seed generates the same entries
+	DuplicateRatio float64 // Fraction of entries repeating a URL another source listed
+	PathRatio      float64 // Fraction of URLs with a path beyond "/"
+}
+
+// SourceReport summarises the entries of one seeded source.
+type SourceReport struct {
+	Name      string `json:"name"`
+	ProcessID string `json:"process_id"`
+	Category  string `json:"category"`
+	Generated int    `json:"generated"`
+	Saved     int    `json:"saved"`
+}
+
+// Report summarises a seeding run.
+type Report struct {
+	Generated  int            `json:"generated"`
+	Saved      int            `json:"saved"`
+	Duplicates int            `json:"duplicates"` // Entries repeating another source's URL
+	WithPath   int            `json:"with_path"`
+	IPHosts    int            `json:"ip_hosts"`
+	Sources    []SourceReport `json:"sources"`
+	Duration   time.Duration  `json:"duration"`
+}
+
+// Run generates opts.Entries synthetic entries and writes them through c,
+// one provider run per source. Sources are sized along a Zipf-like curve,
+// so the first is the largest, as with real feed mixes.
+func Run(ctx context.Context, c Collector, opts Options) (*Report, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrSeedUnavailable
+	}
+
+	started := time.Now()
+	g := NewGenerator(opts)
+	report := &Report{Sources: make([]SourceReport, opts.Sources)}
+	for i := range report.Sources {
+		report.Sources[i] = SourceReport{
+			Name:      g.SourceName(i),
+			ProcessID: uuid.New().String(),
+			Category:  categories[i%len(categories)],
+		}
+		c.StartProviderProcessing(ctx, report.Sources[i].Name, report.Sources[i].ProcessID)
+	}
+
+	var err error
+	for n := range opts.Entries {
+		if n%10_000 == 0 && ctx.Err() != nil {
+			err = ErrSeedInterrupted
+			break
+		}
+
+		u := g.Next()
+		src := &report.Sources[u.Source]
+		entry, parseErr := entries.FromURL(u.URL, src.Name, src.ProcessID)
+		if parseErr != nil {
+			log.Debug().Err(parseErr).Str("url", u.URL).Msg("Skipping generated URL")
+			continue
+		}
+		entry.Category = src.Category
+		c.Submit(entry)
+
+		src.Generated++
+		report.Generated++
+		if u.Duplicate {
+			report.Duplicates++
+		}
+		if u.Path {
+			report.WithPath++
+		}
+		if u.IP {
+			report.IPHosts++
+		}
+	}
+
+	// Entries submitted before an interruption are still written.
+	for i := range report.Sources {
+		src := &report.Sources[i]
+		src.Saved, _, _ = c.FinishProviderProcessing(src.Name, src.ProcessID)
+		report.Saved += src.Saved
+	}
+	report.Duration = time.Since(started)
+	return report, err
+}
+
+func (o *Options) validate() error {
+	if o.Entries <= 0 {
+		return ErrInvalidEntries
+	}
+	if o.Sources <= 0 || o.Sources > maxSources {
+		return ErrInvalidSources
+	}
+	if o.DuplicateRatio < 0 || o.DuplicateRatio > 1 || o.PathRatio < 0 || o.PathRatio > 1 {
+		return ErrInvalidRatio
+	}
+	if strings.TrimSpace(o.Prefix) == "" {
+		return ErrInvalidPrefix
+	}
+	return nil
+}
+
+// URL is one generated URL and the source it is listed by.
+type URL struct {
+	Source    int
+	URL       string
+	Duplicate bool // Listed by another source before
+	Path      bool
+	IP        bool
+}
+
+type pooled struct {
+	url    string
+	source int
+	path   bool
+}
+
+// Generator produces a deterministic stream of realistic blacklist URLs.
+type Generator struct {
+	opts    Options
+	rng     *rand.Rand
+	weights []float64 // Cumulative source weights
+	pool    []pooled
+	seen    map[uint64]struct{} // Hashes of source and URL pairs generated
+}
+
+// NewGenerator creates a Generator for opts.
+func NewGenerator(opts Options) *Generator {
+	g := &Generator{
+		opts: opts,
+		rng:  rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15)),
+		seen: make(map[uint64]struct{}),
+	}
+	total := 0.0
+	for i := range opts.Sources {
+		total += 1 / float64(i+1)
+		g.weights = append(g.weights, total)
+	}
+	for i := range g.weights {
+		g.weights[i] /= total
+	}
+	return g
+}
+
+// SourceName returns the name of source i.
+func (g *Generator) SourceName(i int) string {
+	return g.opts.Prefix + "-" + strconv.Itoa(i+1)
+}
+
+// Next returns the next URL, never one its source listed before.
+func (g *Generator) Next() URL {
+	source := g.source()
+
+	// Repeat a URL another source listed, trying a few pooled ones.
+	if g.opts.Sources > 1 && g.rng.Float64() < g.opts.DuplicateRatio {
+		for range 8 {
+			if len(g.pool) == 0 {
+				break
+			}
+			i := g.rng.IntN(len(g.pool))
+			if p := g.pool[i]; p.source != source && g.claim(source, p.url) {
+				g.pool[i] = g.pool[len(g.pool)-1]
+				g.pool = g.pool[:len(g.pool)-1]
+				return URL{Source: source, URL: p.url, Duplicate: true, Path: p.path}
+			}
+		}
+	}
+
+	u := g.fresh(source)
+	for !g.claim(source, u.URL) {
+		u = g.fresh(source)
+	}
+	if len(g.pool) < poolSize {
+		g.pool = append(g.pool, pooled{url: u.URL, source: source, path: u.Path})
+	} else {
+		g.pool[g.rng.IntN(poolSize)] = pooled{url: u.URL, source: source, path: u.Path}
+	}
+	return u
+}
+
+// claim records url as listed by source, reporting false if it already is.
+// URLs are kept as 64-bit hashes, so millions fit in memory.
+func (g *Generator) claim(source int, url string) bool {
+	h := fnv.New64a()
+	h.Write([]byte{byte(source)})
+	h.Write([]byte(url))
+	key := h.Sum64()
+	if _, ok := g.seen[key]; ok {
+		return false
+	}
+	g.seen[key] = struct{}{}
+	return true
+}
+
+func (g *Generator) fresh(source int) URL {
+	u := URL{Source: source}
+	var b strings.Builder
+	if g.rng.Float64() < 0.65 {
+		b.WriteString("https://")
+	} else {
+		b.WriteString("http://")
+	}
+	if g.rng.Float64() < ipRatio {
+		u.IP = true
+		b.WriteString(g.ip())
+	} else {
+		b.WriteString(g.host())
+	}
+	if g.rng.Float64() < portRatio {
+		b.WriteString(":" + pick(g.rng, ports))
+	}
+	b.WriteString("/")
+	if g.rng.Float64() < g.opts.PathRatio {
+		u.Path = true
+		b.WriteString(g.path())
+	}
+	u.URL = b.String()
+	return u
+}
+
+func (g *Generator) source() int {
+	f := g.rng.Float64()
+	for i, w := range g.weights {
+		if f < w {
+			return i
+		}
+	}
+	return len(g.weights) - 1
+}
+
+// host returns a registrable domain, under a subdomain a third of the time.
+func (g *Generator) host() string {
+	var b strings.Builder
+	if g.rng.Float64() < 0.35 {
+		b.WriteString(pick(g.rng, subdomains))
+		b.WriteString(".")
+	}
+	b.WriteString(g.label())
+	b.WriteString(".")
+	b.WriteString(g.tld())
+	return b.String()
+}
+
+// label returns a second-level label. Lengths peak around 10 characters
+// like registered domains; some labels are hyphenated brand lures or
+// algorithmically generated strings.
+func (g *Generator) label() string {
+	switch f := g.rng.Float64(); {
+	case f < 0.15:
+		return pick(g.rng, lures) + "-" + pick(g.rng, words) + "-" + pick(g.rng, words)
+	case f < 0.25:
+		return g.token(alnum, 12+g.rng.IntN(14))
+	case f < 0.55:
+		return pick(g.rng, words) + pick(g.rng, words)
+	default:
+		return g.pronounceable(3 + g.rng.IntN(6) + g.rng.IntN(6) + g.rng.IntN(6))
+	}
+}
+
+func (g *Generator) tld() string {
+	f := g.rng.Float64()
+	for _, t := range tlds {
+		if f < t.weight {
+			return t.name
+		}
+		f -= t.weight
+	}
+	return "com"
+}
+
+// ip returns an address in a public range.
+func (g *Generator) ip() string {
+	return strconv.Itoa(publicOctets[g.rng.IntN(len(publicOctets))]) + "." +
+		strconv.Itoa(g.rng.IntN(256)) + "." + strconv.Itoa(g.rng.IntN(256)) + "." + strconv.Itoa(1+g.rng.IntN(254))
+}
+
+// path returns one to four segments, often ending in a file, sometimes with
+// a query string.
+func (g *Generator) path() string {
+	depth := 1 + g.rng.IntN(2) + g.rng.IntN(3)/2
+	segments := make([]string, depth)
+	for i := range segments {
+		if g.rng.Float64() < 0.6 {
+			segments[i] = pick(g.rng, pathWords)
+		} else {
+			segments[i] = g.token(alnum, 4+g.rng.IntN(12))
+		}
+	}
+	if g.rng.Float64() < 0.35 {
+		segments[depth-1] += pick(g.rng, extensions)
+	}
+	p := strings.Join(segments, "/")
+	if g.rng.Float64() < 0.15 {
+		p += "?" + pick(g.rng, queryKeys) + "=" + g.token(alnum, 6+g.rng.IntN(20))
+	}
+	return p
+}
+
+func (g *Generator) pronounceable(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		if i%2 == 0 {
+			b[i] = consonants[g.rng.IntN(len(consonants))]
+		} else {
+			b[i] = vowels[g.rng.IntN(len(vowels))]
+		}
+	}
+	return string(b)
+}
+
+func (g *Generator) token(alphabet string, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphabet[g.rng.IntN(len(alphabet))]
+	}
+	return string(b)
+}
+
+func pick(rng *rand.Rand, list []string) string {
+	return list[rng.IntN(len(list))]
+}
+
+const (
+	alnum      = "abcdefghijklmnopqrstuvwxyz0123456789"
+	consonants = "bcdfghjklmnprstvz"
+	vowels     = "aeiou"
+)
+
+var (
+	categories = []string{"malware", "phishing", "spam", "c2", "adware"}
+	subdomains = []string{"www", "login", "secure", "mail", "cdn", "update", "account", "app", "api", "static", "portal", "m"}
+	lures      = []string{"paypal", "apple", "microsoft", "office365", "netflix", "amazon", "dhl", "chase", "wellsfargo", "steam"}
+	words      = []string{"secure", "login", "verify", "account", "update", "support", "service", "online", "bank", "cloud",
+		"mail", "free", "best", "shop", "deal", "pay", "info", "center", "web", "host", "net", "safe", "auth", "help"}
+	pathWords = []string{"wp-admin", "wp-content", "uploads", "login", "signin", "verify", "admin", "bin", "files", "download",
+		"invoice", "doc", "secure", "update", "auth", "index", "includes", "images", "js", "css", "tmp", "gate", "panel"}
+	extensions   = []string{".php", ".html", ".htm", ".exe", ".zip", ".apk", ".js", ".doc", ".bin", ".sh"}
+	queryKeys    = []string{"id", "token", "session", "ref", "u", "email", "redirect", "q"}
+	ports        = []string{"8080", "8443", "8000", "81", "3000"}
+	publicOctets = []int{5, 23, 31, 37, 45, 46, 62, 77, 78, 79, 80, 85, 89, 91, 93, 95, 103, 104, 109, 139, 141, 151, 154, 162, 176, 178, 185, 188, 193, 194, 195, 212, 213, 217}
+
+	// tlds are weighted roughly like their share of blacklisted domains.
+	tlds = []struct {
+		name   string
+		weight float64
+	}{
+		{"com", 0.42}, {"net", 0.08}, {"org", 0.05}, {"xyz", 0.05}, {"top", 0.05}, {"ru", 0.04}, {"info", 0.03},
+		{"online", 0.03}, {"site", 0.03}, {"io", 0.02}, {"cn", 0.02}, {"br", 0.02}, {"de", 0.02}, {"tk", 0.02},
+		{"shop", 0.02}, {"club", 0.02}, {"co", 0.02}, {"in", 0.01}, {"uk", 0.01}, {"icu", 0.01}, {"live", 0.01},
+		{"biz", 0.01}, {"app", 0.01},
+	}
+)
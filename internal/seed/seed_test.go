@@ -0,0 +1,96 @@
+package seed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"blacked/features/entries"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCollector struct {
+	started   map[string]string
+	submitted map[string][]*entries.Entry
+}
+
+func newFakeCollector() *fakeCollector {
+	return &fakeCollector{started: map[string]string{}, submitted: map[string][]*entries.Entry{}}
+}
+
+func (f *fakeCollector) Submit(e *entries.Entry) {
+	f.submitted[e.Source] = append(f.submitted[e.Source], e)
+}
+
+func (f *fakeCollector) StartProviderProcessing(_ context.Context, name, processID string) {
+	f.started[name] = processID
+}
+
+func (f *fakeCollector) FinishProviderProcessing(name, processID string) (int, time.Duration, bool) {
+	return len(f.submitted[name]), 0, f.started[name] == processID
+}
+
+func TestGeneratorDeterministic(t *testing.T) {
+	opts := Options{Entries: 1000, Sources: 3, Prefix: "seed", Seed: 42, DuplicateRatio: 0.1, PathRatio: 0.6}
+	a, b := NewGenerator(opts), NewGenerator(opts)
+	for range 1000 {
+		assert.Equal(t, a.Next(), b.Next())
+	}
+
+	opts.Seed = 43
+	c := NewGenerator(opts)
+	assert.NotEqual(t, NewGenerator(Options{Sources: 3, Prefix: "seed", Seed: 42}).Next().URL, c.Next().URL)
+}
+
+func TestRunDistributions(t *testing.T) {
+	fc := newFakeCollector()
+	opts := Options{Entries: 20_000, Sources: 5, Prefix: "seed", Seed: 7, DuplicateRatio: 0.1, PathRatio: 0.6}
+	report, err := Run(context.Background(), fc, opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, opts.Entries, report.Generated)
+	assert.Equal(t, opts.Entries, report.Saved)
+	require.Len(t, report.Sources, 5)
+	assert.Equal(t, "seed-1", report.Sources[0].Name)
+	assert.Greater(t, report.Sources[0].Generated, report.Sources[4].Generated, "first source is the largest")
+
+	assert.InDelta(t, 0.1, float64(report.Duplicates)/float64(report.Generated), 0.02)
+	assert.InDelta(t, 0.6, float64(report.WithPath)/float64(report.Generated), 0.03)
+	assert.InDelta(t, ipRatio, float64(report.IPHosts)/float64(report.Generated), 0.01)
+
+	// Every URL is listed at most once per source; duplicates span sources.
+	listedBy := map[string]map[string]bool{}
+	for source, list := range fc.submitted {
+		for _, e := range list {
+			assert.Equal(t, source, e.Source)
+			assert.NotEmpty(t, e.Host)
+			assert.NotEmpty(t, e.Category)
+			if listedBy[e.SourceURL] == nil {
+				listedBy[e.SourceURL] = map[string]bool{}
+			}
+			assert.False(t, listedBy[e.SourceURL][source], e.SourceURL)
+			listedBy[e.SourceURL][source] = true
+		}
+	}
+	shared := 0
+	for _, sources := range listedBy {
+		if len(sources) > 1 {
+			shared++
+		}
+	}
+	assert.Greater(t, shared, 0)
+}
+
+func TestRunValidation(t *testing.T) {
+	fc := newFakeCollector()
+	_, err := Run(context.Background(), fc, Options{Sources: 1, Prefix: "seed"})
+	assert.ErrorIs(t, err, ErrInvalidEntries)
+	_, err = Run(context.Background(), fc, Options{Entries: 1, Sources: 0, Prefix: "seed"})
+	assert.ErrorIs(t, err, ErrInvalidSources)
+	_, err = Run(context.Background(), fc, Options{Entries: 1, Sources: 1, Prefix: "seed", PathRatio: 1.5})
+	assert.ErrorIs(t, err, ErrInvalidRatio)
+	_, err = Run(context.Background(), nil, Options{Entries: 1, Sources: 1, Prefix: "seed"})
+	assert.ErrorIs(t, err, ErrSeedUnavailable)
+}
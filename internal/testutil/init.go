@@ -52,12 +52,35 @@ func defaultProviderConfigs() map[string]*config.ProviderOptions {
 		},
 		"phishtank-online-valid": {
 			Enabled:         boolPtr(false),
-			SourceURL:       "https://data.phishtank.com/data/{api_key}/online-valid.json",
+			SourceURL:       "https://data.phishtank.com/data/{api_key}/online-valid.json.gz",
 			Cron:            "45 */6 * * *",
 			Category:        "phishing",
 			ParserWorkers:   4,
 			ParserBatchSize: 1000,
 		},
+		"abuseipdb-blacklist": {
+			Enabled:       boolPtr(false),
+			SourceURL:     "https://api.abuseipdb.com/api/v2/blacklist?confidenceMinimum=90",
+			Cron:          "20 */6 * * *",
+			Category:      "abuse",
+			ParserWorkers: 4,
+		},
+		"feodotracker-ipblocklist": {
+			Enabled:         boolPtr(true),
+			SourceURL:       "https://feodotracker.abuse.ch/downloads/ipblocklist.txt",
+			Cron:            "10 * * * *",
+			Category:        "botnet",
+			ParserWorkers:   4,
+			ParserBatchSize: 1000,
+		},
+		"sslbl-ipblacklist": {
+			Enabled:         boolPtr(true),
+			SourceURL:       "https://sslbl.abuse.ch/blacklist/sslipblacklist.txt",
+			Cron:            "40 */2 * * *",
+			Category:        "malware_ssl",
+			ParserWorkers:   4,
+			ParserBatchSize: 1000,
+		},
 	}
 }
 
@@ -0,0 +1,275 @@
+package utils
+
+import (
+	"blacked/internal/config"
+	"net"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/idna"
+)
+
+// TrailingSlashKeep keeps trailing slashes of paths; any other policy strips them.
+const TrailingSlashKeep = "keep"
+
+// Normalizer puts URLs in the canonical form entries are stored and queried
+// in. The same Normalizer must run at ingest and at query time, or
+// equivalent URLs stop matching.
+type Normalizer struct {
+	cfg    config.NormalizeConfig
+	exact  map[string]struct{} // Stripped parameter names
+	prefix []string            // Stripped parameter prefixes, from "name*" patterns
+}
+
+// NewNormalizer returns a Normalizer applying cfg.
+func NewNormalizer(cfg config.NormalizeConfig) *Normalizer {
+	n := &Normalizer{cfg: cfg, exact: make(map[string]struct{})}
+	for _, p := range cfg.StripParams {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if name, ok := strings.CutSuffix(p, "*"); ok {
+			n.prefix = append(n.prefix, name)
+		} else {
+			n.exact[p] = struct{}{}
+		}
+	}
+	return n
+}
+
+var defaultNormalizer atomic.Pointer[Normalizer]
+
+// DefaultNormalizer returns the Normalizer of the [Normalize] config section.
+func DefaultNormalizer() *Normalizer {
+	if n := defaultNormalizer.Load(); n != nil {
+		return n
+	}
+	var cfg config.NormalizeConfig
+	if c := config.GetConfig(); c != nil {
+		cfg = c.Normalize
+	}
+	defaultNormalizer.CompareAndSwap(nil, NewNormalizer(cfg))
+	return defaultNormalizer.Load()
+}
+
+// SetDefaultNormalizer replaces the Normalizer DefaultNormalizer returns;
+// nil reverts to the configured one.
+func SetDefaultNormalizer(n *Normalizer) {
+	defaultNormalizer.Store(n)
+}
+
+// NormalizeURL returns link in the form of the default Normalizer.
+func NormalizeURL(link string) string {
+	return DefaultNormalizer().URL(link)
+}
+
+// URL returns link normalized, or link itself when it does not parse.
+func (n *Normalizer) URL(link string) string {
+	if n.cfg.LowercasePath {
+		link = strings.ToLower(link)
+	}
+
+	parsedURL, err := url.Parse(link)
+	if err != nil {
+		log.Err(err).Str("url", link).Msg("Failed to parse URL for normalization")
+		return link
+	}
+	n.Apply(parsedURL)
+
+	return parsedURL.String()
+}
+
+// NormalizeStep is one step of Trace that changed the URL.
+type NormalizeStep struct {
+	Step   string `json:"step"`
+	Result string `json:"result"` // URL after the step
+}
+
+// Apply normalizes u in place: scheme and host are lower-cased, the host
+// converted to punycode and the default port dropped, then path and query
+// follow the configured case, trailing slash, parameter and escaping policy.
+func (n *Normalizer) Apply(u *url.URL) {
+	n.apply(u, nil)
+}
+
+// Trace normalizes u in place as Apply does and returns the steps that
+// changed it.
+func (n *Normalizer) Trace(u *url.URL) []NormalizeStep {
+	var steps []NormalizeStep
+	last := u.String()
+	n.apply(u, func(step string) {
+		if result := u.String(); result != last {
+			steps = append(steps, NormalizeStep{Step: step, Result: result})
+			last = result
+		}
+	})
+	return steps
+}
+
+// apply runs the steps of Apply, calling done after each one when set.
+func (n *Normalizer) apply(u *url.URL, done func(step string)) {
+	if done == nil {
+		done = func(string) {}
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	done("lowercase_scheme")
+	u.Host = n.hostPort(u.Scheme, u.Host)
+	done("host")
+
+	if n.cfg.LowercasePath {
+		u.Path = strings.ToLower(u.Path)
+		u.RawPath = strings.ToLower(u.RawPath)
+		u.RawQuery = strings.ToLower(u.RawQuery)
+		done("lowercase_path")
+	}
+	if n.cfg.DecodePercent {
+		// Without RawPath the path is re-encoded canonically.
+		u.RawPath = ""
+		done("decode_percent")
+	}
+	if n.cfg.TrailingSlash != TrailingSlashKeep {
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawPath = strings.TrimRight(u.RawPath, "/")
+		done("trailing_slash")
+	}
+
+	if u.RawQuery != "" {
+		u.RawQuery = n.query(u.RawQuery)
+	}
+	u.ForceQuery = u.ForceQuery && u.RawQuery != ""
+	done("query")
+}
+
+// Host returns host lower-cased, without its trailing dot and, if configured,
+// in punycode.
+func (n *Normalizer) Host(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if n.cfg.Punycode && !isASCII(host) {
+		if ascii, err := idna.ToASCII(host); err == nil {
+			host = ascii
+		} else if log.Trace().Enabled() {
+			log.Trace().Err(err).Str("host", host).Msg("Punycode conversion failed, keeping host")
+		}
+	}
+	return host
+}
+
+func (n *Normalizer) hostPort(scheme, hostport string) string {
+	if hostport == "" {
+		return ""
+	}
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		// No port; IPv6 literals keep their brackets.
+		if strings.HasPrefix(hostport, "[") {
+			return strings.ToLower(hostport)
+		}
+		return n.Host(hostport)
+	}
+	if n.cfg.StripDefaultPort && (scheme == "http" && port == "80" || scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + strings.ToLower(host) + "]"
+	} else {
+		host = n.Host(host)
+	}
+	if port == "" {
+		return host
+	}
+	return host + ":" + port
+}
+
+// query drops the stripped parameters of a raw query, keeping the order and
+// encoding of the others.
+func (n *Normalizer) query(raw string) string {
+	kept := make([]string, 0, strings.Count(raw, "&")+1)
+	for pair := range strings.SplitSeq(raw, "&") {
+		if pair == "" || n.stripped(pair) {
+			continue
+		}
+		if n.cfg.DecodePercent {
+			pair = decodeUnreserved(pair)
+		}
+		kept = append(kept, pair)
+	}
+	return strings.Join(kept, "&")
+}
+
+func (n *Normalizer) stripped(pair string) bool {
+	if len(n.exact) == 0 && len(n.prefix) == 0 {
+		return false
+	}
+	name, _, _ := strings.Cut(pair, "=")
+	if unescaped, err := url.QueryUnescape(name); err == nil {
+		name = unescaped
+	}
+	name = strings.ToLower(name)
+	if _, ok := n.exact[name]; ok {
+		return true
+	}
+	for _, p := range n.prefix {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeUnreserved decodes the percent-escapes of unreserved characters
+// (RFC 3986 section 2.3) and upper-cases the hex digits of the others.
+func decodeUnreserved(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
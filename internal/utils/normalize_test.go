@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"blacked/internal/config"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testNormalize = config.NormalizeConfig{
+	LowercasePath:    true,
+	Punycode:         true,
+	StripDefaultPort: true,
+	TrailingSlash:    "strip",
+	StripParams:      []string{"utm_*", "fbclid"},
+	DecodePercent:    true,
+}
+
+func TestNormalizerURL(t *testing.T) {
+	n := NewNormalizer(testNormalize)
+	for in, want := range map[string]string{
+		"HTTP://Example.COM/Path/":                         "http://example.com/path",
+		"https://example.com:443/a":                        "https://example.com/a",
+		"http://example.com:8080/a":                        "http://example.com:8080/a",
+		"https://example.com./a":                           "https://example.com/a",
+		"https://bücher.example/login":                     "https://xn--bcher-kva.example/login",
+		"https://example.com/a?utm_source=x&id=1&fbclid=y": "https://example.com/a?id=1",
+		"https://example.com/?utm_medium=mail":             "https://example.com",
+		"https://example.com/%7euser?q=%7e%2f":             "https://example.com/~user?q=~%2F",
+		"https://[::1]:443/a":                              "https://[::1]/a",
+	} {
+		assert.Equal(t, want, n.URL(in), in)
+		assert.Equal(t, want, n.URL(want), "normalizing is idempotent")
+	}
+}
+
+func TestNormalizerPolicies(t *testing.T) {
+	n := NewNormalizer(config.NormalizeConfig{TrailingSlash: TrailingSlashKeep})
+	assert.Equal(t, "https://example.com/Path/?utm_source=x", n.URL("HTTPS://EXAMPLE.com/Path/?utm_source=x"),
+		"scheme and host are always lower-cased")
+	assert.Equal(t, "https://example.com:443", n.URL("https://example.com:443"))
+	assert.Equal(t, "bücher.example", n.Host("Bücher.example."))
+}
+
+func TestNormalizerTrace(t *testing.T) {
+	n := NewNormalizer(testNormalize)
+	u, err := url.Parse("HTTPS://Bücher.Example:443/Login/?utm_source=x&id=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := n.Trace(u)
+	assert.Equal(t, []NormalizeStep{
+		{Step: "host", Result: "https://xn--bcher-kva.example/Login/?utm_source=x&id=1"},
+		{Step: "lowercase_path", Result: "https://xn--bcher-kva.example/login/?utm_source=x&id=1"},
+		{Step: "trailing_slash", Result: "https://xn--bcher-kva.example/login?utm_source=x&id=1"},
+		{Step: "query", Result: "https://xn--bcher-kva.example/login?id=1"},
+	}, steps, "url.Parse already lower-cased the scheme; steps that change nothing are left out")
+	assert.Equal(t, n.URL("HTTPS://Bücher.Example:443/Login/?utm_source=x&id=1"), u.String())
+}
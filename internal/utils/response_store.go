@@ -0,0 +1,242 @@
+package utils
+
+import (
+	"blacked/internal/config"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	responseSuffix = "_response"
+	dataExt        = ".dat"
+	metaExt        = ".meta.json"
+
+	archiveStampLayout = "20060102T150405.000Z"
+)
+
+var (
+	ErrStoredResponseNotFound = errors.New("stored response not found")
+	ErrListStoredResponses    = errors.New("failed to list stored responses")
+	ErrArchiveStoredResponse  = errors.New("failed to archive stored response")
+)
+
+// StoredResponse is one stored provider response in the store path.
+type StoredResponse struct {
+	Provider   string    `json:"provider"`
+	File       string    `json:"file"` // Data file name, relative to the store path
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+	ProcessID  string    `json:"process_id,omitempty"`
+	Current    bool      `json:"current"` // The response runs reuse and startup restores from
+}
+
+// parseResponseFile splits a data file name into its provider and whether it
+// is the current response rather than an archived one.
+func parseResponseFile(name string) (provider string, current bool, ok bool) {
+	base, found := strings.CutSuffix(name, dataExt)
+	if !found {
+		return "", false, false
+	}
+	i := strings.LastIndex(base, responseSuffix)
+	if i <= 0 {
+		return "", false, false
+	}
+	rest := base[i+len(responseSuffix):]
+	return base[:i], rest == "", rest == "" || strings.HasPrefix(rest, ".")
+}
+
+// metaFileFor returns the metadata file stored next to data file name.
+func metaFileFor(name string) string {
+	return strings.TrimSuffix(name, dataExt) + metaExt
+}
+
+// ListStoredResponses returns the stored responses, optionally of one
+// provider, newest first within each provider.
+func ListStoredResponses(provider string) ([]StoredResponse, error) {
+	return listStoredResponses(config.GetConfig().Collector.StorePath, provider)
+}
+
+func listStoredResponses(dir, provider string) ([]StoredResponse, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []StoredResponse{}, nil
+		}
+		log.Err(err).Str("directory", dir).Msg("Failed to read stored responses directory")
+		return nil, ErrListStoredResponses
+	}
+
+	responses := make([]StoredResponse, 0, len(dirEntries))
+	for _, de := range dirEntries {
+		name, current, ok := parseResponseFile(de.Name())
+		if !ok || de.IsDir() || (provider != "" && name != provider) {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue // Removed meanwhile
+		}
+
+		r := StoredResponse{
+			Provider:   name,
+			File:       de.Name(),
+			Size:       info.Size(),
+			ModifiedAt: info.ModTime().UTC(),
+			Current:    current,
+		}
+		if raw, err := os.ReadFile(filepath.Join(dir, metaFileFor(de.Name()))); err == nil {
+			var meta ResponseMetadata
+			if json.Unmarshal(raw, &meta) == nil {
+				r.ProcessID = meta.ProcessID
+			}
+		}
+		responses = append(responses, r)
+	}
+
+	slices.SortFunc(responses, func(a, b StoredResponse) int {
+		if c := strings.Compare(a.Provider, b.Provider); c != 0 {
+			return c
+		}
+		if a.Current != b.Current {
+			if a.Current {
+				return -1
+			}
+			return 1
+		}
+		return b.ModifiedAt.Compare(a.ModifiedAt)
+	})
+	return responses, nil
+}
+
+// DeleteStoredResponses removes the stored responses of provider, or only
+// file when set, and returns how many were removed.
+func DeleteStoredResponses(provider, file string) (int, error) {
+	dir := config.GetConfig().Collector.StorePath
+	responses, err := listStoredResponses(dir, provider)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, r := range responses {
+		if file != "" && r.File != file {
+			continue
+		}
+		if err := removeResponseFiles(dir, r.File); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	if removed == 0 {
+		return 0, ErrStoredResponseNotFound
+	}
+	return removed, nil
+}
+
+// removeResponseFiles removes a data file and its metadata file.
+func removeResponseFiles(dir, file string) error {
+	if err := os.Remove(filepath.Join(dir, file)); err != nil && !os.IsNotExist(err) {
+		log.Err(err).Str("file", file).Msg("Failed to remove stored response data file")
+		return ErrRemoveResponseDataFile
+	}
+	if err := os.Remove(filepath.Join(dir, metaFileFor(file))); err != nil && !os.IsNotExist(err) {
+		log.Err(err).Str("file", file).Msg("Failed to remove stored response metadata file")
+		return ErrRemoveResponseMetaFile
+	}
+	return nil
+}
+
+// archiveStoredResponse renames the current response of provider to a
+// timestamped archive, so the next run stores a fresh one.
+func archiveStoredResponse(dir, provider string) error {
+	dataFilename, metaFilename := GenerateFilenames(dir, provider)
+	info, err := os.Stat(dataFilename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		log.Err(err).Str("file", dataFilename).Msg("Failed to stat stored response data file")
+		return ErrArchiveStoredResponse
+	}
+
+	stamp := info.ModTime().UTC().Format(archiveStampLayout)
+	base := strings.TrimSuffix(dataFilename, dataExt) + "." + stamp
+	if err := os.Rename(dataFilename, base+dataExt); err != nil {
+		log.Err(err).Str("file", dataFilename).Msg("Failed to archive stored response data file")
+		return ErrArchiveStoredResponse
+	}
+	if err := os.Rename(metaFilename, base+metaExt); err != nil && !os.IsNotExist(err) {
+		log.Err(err).Str("file", metaFilename).Msg("Failed to archive stored response metadata file")
+		return ErrArchiveStoredResponse
+	}
+
+	log.Info().Str("file", base+dataExt).Msg("Archived stored response")
+	return nil
+}
+
+// PruneStoredResponses applies the configured retention to the store path.
+func PruneStoredResponses() error {
+	cfg := config.GetConfig().Collector
+	removed, err := pruneStoredResponses(cfg.StorePath, cfg.MaxStoredResponses, cfg.MaxStoredBytes)
+	if removed > 0 {
+		log.Info().Int("removed", removed).Msg("Pruned stored responses")
+	}
+	return err
+}
+
+// pruneStoredResponses keeps at most maxPerProvider responses per provider
+// and removes the oldest archives while all responses exceed maxBytes.
+// Current responses are never removed; non-positive limits are off.
+func pruneStoredResponses(dir string, maxPerProvider int, maxBytes int64) (int, error) {
+	responses, err := listStoredResponses(dir, "")
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	var archives []StoredResponse
+	removed, kept := 0, 0
+	for i, r := range responses {
+		if i == 0 || r.Provider != responses[i-1].Provider {
+			kept = 0
+		}
+		kept++
+
+		if !r.Current && maxPerProvider > 0 && kept > maxPerProvider {
+			if err := removeResponseFiles(dir, r.File); err != nil {
+				return removed, err
+			}
+			removed++
+			continue
+		}
+		total += r.Size
+		if !r.Current {
+			archives = append(archives, r)
+		}
+	}
+
+	if maxBytes <= 0 {
+		return removed, nil
+	}
+	slices.SortFunc(archives, func(a, b StoredResponse) int {
+		return a.ModifiedAt.Compare(b.ModifiedAt)
+	})
+	for _, r := range archives {
+		if total <= maxBytes {
+			break
+		}
+		if err := removeResponseFiles(dir, r.File); err != nil {
+			return removed, err
+		}
+		total -= r.Size
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResponseFile(t *testing.T) {
+	provider, current, ok := parseResponseFile("oisd-big_response.dat")
+	assert.True(t, ok)
+	assert.True(t, current)
+	assert.Equal(t, "oisd-big", provider)
+
+	provider, current, ok = parseResponseFile("oisd-big_response.20261016T120000.000Z.dat")
+	assert.True(t, ok)
+	assert.False(t, current)
+	assert.Equal(t, "oisd-big", provider)
+
+	for _, name := range []string{"oisd-big_response.meta.json", "notes.dat", "_response.dat", "oisd-big_responses.dat"} {
+		_, _, ok = parseResponseFile(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestPruneStoredResponses(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	// write stores a response of size bytes, age old, with its metadata.
+	write := func(name string, size int, age time.Duration) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, make([]byte, size), 0644))
+		require.NoError(t, os.WriteFile(metaFileFor(path), []byte(`{"process_id":"p"}`), 0644))
+		require.NoError(t, os.Chtimes(path, now.Add(-age), now.Add(-age)))
+	}
+	write("a_response.dat", 100, 0)
+	write("a_response.3.dat", 100, 3*time.Hour)
+	write("a_response.1.dat", 100, time.Hour)
+	write("a_response.2.dat", 100, 2*time.Hour)
+	write("b_response.dat", 300, 0)
+	write("b_response.1.dat", 50, 4*time.Hour)
+
+	removed, err := pruneStoredResponses(dir, 3, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	responses, err := listStoredResponses(dir, "a")
+	require.NoError(t, err)
+	var files []string
+	for _, r := range responses {
+		files = append(files, r.File)
+	}
+	assert.Equal(t, []string{"a_response.dat", "a_response.1.dat", "a_response.2.dat"}, files, "current first, then newest archives")
+	assert.Equal(t, "p", responses[0].ProcessID)
+	assert.NoFileExists(t, filepath.Join(dir, "a_response.3.meta.json"))
+
+	// 650 bytes are left; the oldest archives go first and current ones stay.
+	removed, err = pruneStoredResponses(dir, 0, 500)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	assert.NoFileExists(t, filepath.Join(dir, "b_response.1.dat"))
+	assert.NoFileExists(t, filepath.Join(dir, "a_response.2.dat"))
+	assert.FileExists(t, filepath.Join(dir, "a_response.1.dat"))
+
+	removed, err = pruneStoredResponses(dir, 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.FileExists(t, filepath.Join(dir, "a_response.dat"))
+	assert.FileExists(t, filepath.Join(dir, "b_response.dat"))
+}
+
+func TestArchiveStoredResponse(t *testing.T) {
+	dir := t.TempDir()
+	dataFilename, metaFilename := GenerateFilenames(dir, "urlhaus-online")
+	require.NoError(t, os.WriteFile(dataFilename, []byte("x"), 0644))
+	require.NoError(t, os.WriteFile(metaFilename, []byte(`{}`), 0644))
+
+	require.NoError(t, archiveStoredResponse(dir, "urlhaus-online"))
+	assert.NoFileExists(t, dataFilename)
+
+	responses, err := listStoredResponses(dir, "urlhaus-online")
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	assert.False(t, responses[0].Current)
+	assert.FileExists(t, filepath.Join(dir, metaFileFor(responses[0].File)))
+
+	require.NoError(t, archiveStoredResponse(dir, "urlhaus-online"), "nothing to archive is not an error")
+}
@@ -30,9 +30,12 @@ var (
 )
 
 // ResponseMetadata holds metadata information for a stored response.
+// ProcessID is the run reading the response; SnapshotID is the run that
+// fetched it, so the two differ when a stored response is reused.
 type ResponseMetadata struct {
-	ProcessID string    `json:"process_id"`
-	CreatedAt time.Time `json:"created_at"`
+	ProcessID  string    `json:"process_id"`
+	SnapshotID string    `json:"snapshot_id,omitempty"` // Empty in files stored before snapshot IDs; ProcessID is the fetching run there
+	CreatedAt  time.Time `json:"created_at"`
 	// You can add more metadata fields here in the future if needed.
 	Description string `json:"description,omitempty"` // Example of storing a description
 }
@@ -47,14 +50,19 @@ func GetResponseReader(sourceURL string, fetchFunc func() (io.Reader, error), pr
 	if storeResponses {
 		reader, meta, err := getStoredResponse(dataFilename, metaFilename, cacheTTL)
 		if err == nil {
-			log.Info().Str("file", dataFilename).Str("process_id", meta.ProcessID).Msg("Using stored response")
-			return reader, meta, nil
+			reused := reusedMetadata(meta, processID)
+			log.Info().Str("file", dataFilename).Str("process_id", processID).Str("snapshot_id", reused.SnapshotID).Msg("Using stored response")
+			return reader, reused, nil
 		}
 		log.Warn().Err(err).Str("file", dataFilename).Msg("Stored response not found or invalid, fetching from source")
 	}
 
 	// Fetch data from source using the provided fetch function
 	responseReader, fetchErr := fetchFunc()
+	if errors.Is(fetchErr, ErrSourceNotModified) {
+		log.Info().Str("url", sourceURL).Str("provider", providerName).Msg("Source not modified since the last import")
+		return nil, nil, ErrSourceNotModified
+	}
 	if fetchErr != nil {
 		log.Err(fetchErr).Str("url", sourceURL).Str("provider", providerName).Msg("Failed to fetch response from source")
 		return nil, nil, ErrFetchSourceResponse
@@ -62,16 +70,24 @@ func GetResponseReader(sourceURL string, fetchFunc func() (io.Reader, error), pr
 
 	if storeResponses {
 		metadata := ResponseMetadata{
-			ProcessID: processID,
-			CreatedAt: time.Now(),
+			ProcessID:  processID,
+			SnapshotID: processID,
+			CreatedAt:  time.Now(),
 		}
 		description := strings.Join([]string{"Response from", providerName, "sync run at", time.Now().Format(time.RFC3339)}, " ")
 		metadata.Description = description
 
-		if err := saveResponseToFile(dataFilename, metaFilename, responseReader, metadata); err != nil {
+		err := saveResponseToFile(dataFilename, metaFilename, responseReader, metadata)
+		if c, ok := responseReader.(io.Closer); ok {
+			c.Close() // A streamed response is fully read into the file
+		}
+		if err != nil {
 			log.Err(err).Str("dataFile", dataFilename).Str("metaFile", metaFilename).Msg("Failed to save response to file")
 		} else {
 			log.Info().Str("dataFile", dataFilename).Str("metaFile", metaFilename).Str("processID", metadata.ProcessID).Msg("Response saved to file")
+			if err := PruneStoredResponses(); err != nil {
+				log.Warn().Err(err).Msg("Failed to prune stored responses")
+			}
 		}
 
 		// Get a fresh reader from the saved file to avoid consuming the original reader
@@ -82,6 +98,18 @@ func GetResponseReader(sourceURL string, fetchFunc func() (io.Reader, error), pr
 	return responseReader, nil, nil // Return reader from fetched response (without saving)
 }
 
+// reusedMetadata returns the metadata of a stored response read by the run
+// processID. The stored IDs only name the snapshot: entries parsed now belong
+// to the current run, or RemoveOlderInsertions would sweep against an old one.
+func reusedMetadata(stored *ResponseMetadata, processID string) *ResponseMetadata {
+	reused := *stored
+	if reused.SnapshotID == "" {
+		reused.SnapshotID = stored.ProcessID
+	}
+	reused.ProcessID = processID
+	return &reused
+}
+
 // getStoredResponse attempts to open and return a reader for the stored response and metadata.
 func getStoredResponse(dataFilename string, metaFilename string, cacheTTL time.Duration) (io.Reader, *ResponseMetadata, error) {
 	metaFile, err := os.Open(metaFilename)
@@ -147,13 +175,15 @@ func saveResponseToFile(dataFilename string, metaFilename string, data io.Reader
 
 // generateFilenames and RemoveStoredResponse - no changes needed
 func GenerateFilenames(storePath string, providerName string) (dataFilename string, metaFilename string) {
-	baseFilename := filepath.Join(storePath, providerName+"_response") // Base filename without extensions
-	dataFilename = baseFilename + ".dat"
-	metaFilename = baseFilename + ".meta.json"
+	baseFilename := filepath.Join(storePath, providerName+responseSuffix) // Base filename without extensions
+	dataFilename = baseFilename + dataExt
+	metaFilename = baseFilename + metaExt
 	return dataFilename, metaFilename
 }
 
-// RemoveStoredResponse removes both data and metadata files for a provider.
+// RemoveStoredResponse retires the current response of a provider so the
+// next run fetches fresh data. It is archived when retention keeps more than
+// one response per provider, and removed otherwise.
 func RemoveStoredResponse(providerName string) error {
 	cfg := config.GetConfig()
 	storeResponses := cfg.Collector.StoreResponses
@@ -162,6 +192,13 @@ func RemoveStoredResponse(providerName string) error {
 	}
 
 	storePath := cfg.Collector.StorePath
+	if cfg.Collector.MaxStoredResponses != 1 {
+		if err := archiveStoredResponse(storePath, providerName); err != nil {
+			return err
+		}
+		return PruneStoredResponses()
+	}
+
 	dataFilename, metaFilename := GenerateFilenames(storePath, providerName)
 
 	if err := os.Remove(dataFilename); err == nil {
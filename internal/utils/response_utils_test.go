@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReusedMetadata(t *testing.T) {
+	stored := &ResponseMetadata{ProcessID: "old", SnapshotID: "old"}
+	reused := reusedMetadata(stored, "new")
+	assert.Equal(t, "new", reused.ProcessID, "entries belong to the reading run")
+	assert.Equal(t, "old", reused.SnapshotID)
+	assert.Equal(t, "old", stored.ProcessID, "the stored metadata is left as is")
+
+	legacy := reusedMetadata(&ResponseMetadata{ProcessID: "old"}, "new")
+	assert.Equal(t, "old", legacy.SnapshotID, "files without a snapshot id name the fetching run in process_id")
+}
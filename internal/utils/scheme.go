@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnsupportedScheme is returned for a link of an opaque scheme where only
+// host-based lookups are possible, such as the bloom filters.
+var ErrUnsupportedScheme = errors.New("unsupported URL scheme")
+
+// opaqueSchemes are the schemes whose links carry no host: javascript: and
+// data: payloads, mail and phone links. Read as "//"+link they lose their
+// scheme or fail to parse, so they are recognized before any URL parsing.
+var opaqueSchemes = map[string]bool{
+	"about":      true,
+	"blob":       true,
+	"data":       true,
+	"javascript": true,
+	"mailto":     true,
+	"sms":        true,
+	"tel":        true,
+	"vbscript":   true,
+}
+
+// OpaqueScheme returns the lower-cased scheme of link when it is one of the
+// schemes whose links carry no host, and "" otherwise. Such links have
+// nothing to decompose: they are stored and matched as written.
+func OpaqueScheme(link string) string {
+	link = strings.TrimSpace(link)
+	i := strings.IndexByte(link, ':')
+	if i <= 0 {
+		return ""
+	}
+	scheme := strings.ToLower(link[:i])
+	if !opaqueSchemes[scheme] {
+		return ""
+	}
+	return scheme
+}
+
+// OpaqueLink returns link trimmed and with its scheme lower-cased when it is
+// of an opaque scheme, the form such links are stored and looked up in, and
+// "" otherwise.
+func OpaqueLink(link string) string {
+	scheme := OpaqueScheme(link)
+	if scheme == "" {
+		return ""
+	}
+	link = strings.TrimSpace(link)
+	return scheme + link[len(scheme):]
+}
+
+// UnusualScheme reports whether scheme is set to anything but http or https.
+func UnusualScheme(scheme string) bool {
+	switch strings.ToLower(scheme) {
+	case "", "http", "https":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpaqueScheme(t *testing.T) {
+	assert.Equal(t, "javascript", OpaqueScheme(" JavaScript:alert(1)"))
+	assert.Equal(t, "data", OpaqueScheme("data:text/html;base64,PHNjcmlwdD4="))
+	assert.Equal(t, "mailto", OpaqueScheme("mailto:phish@example.com"))
+	assert.Equal(t, "tel", OpaqueScheme("tel:+15550100"))
+
+	for _, link := range []string{
+		"https://example.com/data:x",
+		"example.com:8080/login",
+		"admin:secret@example.com",
+		"[2001:db8::1]:443",
+		"ftp://example.com/file.exe",
+		"",
+	} {
+		assert.Empty(t, OpaqueScheme(link), link)
+	}
+}
+
+func TestOpaqueLink(t *testing.T) {
+	assert.Equal(t, "javascript:alert(1)", OpaqueLink(" JavaScript:alert(1)\n"))
+	assert.Equal(t, "data:text/html;base64,PHNjcmlwdD4=", OpaqueLink("DATA:text/html;base64,PHNjcmlwdD4="))
+	assert.Equal(t, "mailto:Phish@Example.com", OpaqueLink("mailto:Phish@Example.com"), "only the scheme is lower-cased")
+	assert.Empty(t, OpaqueLink(" https://example.com/"))
+}
+
+func TestUnusualScheme(t *testing.T) {
+	assert.False(t, UnusualScheme(""))
+	assert.False(t, UnusualScheme("HTTPS"))
+	assert.True(t, UnusualScheme("ftp"))
+	assert.True(t, UnusualScheme("javascript"))
+}
@@ -1,12 +1,13 @@
 package utils
 
 import (
+	"blacked/internal/psl"
 	"errors"
-	"net/url"
+	"net"
+	"slices"
 	"strings"
 
 	"github.com/rs/zerolog/log"
-	"golang.org/x/net/publicsuffix"
 )
 
 // Error variables for URL utils
@@ -20,7 +21,7 @@ var (
 // return domain = "example.co.uk", subdomains = []string{"foo", "bar"}.
 func ExtractDomainAndSubDomains(host string) (domain string, subs []string, err error) {
 	// 1) Try to determine the “effective top-level domain + 1” using the PSL library.
-	eTLDPlusOne, err := publicsuffix.EffectiveTLDPlusOne(host)
+	eTLDPlusOne, err := psl.EffectiveTLDPlusOne(host)
 	if err != nil {
 		if log.Trace().Enabled() {
 			log.Trace().Err(err).Str("host", host).Msg("EffectiveTLDPlusOne failed, using naive fallback")
@@ -65,22 +66,65 @@ func ExtractDomainAndSubDomains(host string) (domain string, subs []string, err
 	return eTLDPlusOne, subs, nil
 }
 
-func NormalizeURL(link string) string {
-	// 1. Convert to lowercase:
-	link = strings.ToLower(link)
+// ExtractDomain returns the domain ExtractDomainAndSubDomains would, without
+// allocating the subdomains. The domain is a substring of host.
+func ExtractDomain(host string) string {
+	eTLDPlusOne, err := psl.EffectiveTLDPlusOne(host)
+	if err == nil && eTLDPlusOne != host {
+		return eTLDPlusOne
+	}
 
-	// 2. Parse the URL to handle encoding and path manipulation correctly:
-	parsedURL, err := url.Parse(link)
-	if err != nil {
-		log.Err(err).Str("url", link).Msg("Failed to parse URL for normalization")
-		return link
+	// Naive fallback: the last two labels.
+	last := strings.LastIndexByte(host, '.')
+	if last < 0 {
+		return host // Just a single word or localhost
+	}
+	return host[strings.LastIndexByte(host[:last], '.')+1:]
+}
+
+// ReverseHost returns host with its labels reversed and a trailing dot,
+// e.g. "sub.bad.com" → "com.bad.sub.". Every host under a name then shares
+// the reversed name as a prefix, so subdomain lookups become index range scans.
+func ReverseHost(host string) string {
+	host = strings.Trim(strings.ToLower(host), ".")
+	if host == "" {
+		return ""
 	}
+	labels := strings.Split(host, ".")
+	slices.Reverse(labels)
+	return strings.Join(labels, ".") + "."
+}
 
-	// 3. Normalize the path (remove trailing slashes, etc.):
-	parsedURL.Path = strings.TrimRight(parsedURL.Path, "/")
+// ParentHosts returns host and each parent of it down to its registered
+// domain, most specific first: "a.b.example.co.uk" → "a.b.example.co.uk",
+// "b.example.co.uk", "example.co.uk". IP literals have no parents.
+func ParentHosts(host string) []string {
+	host = strings.Trim(strings.ToLower(host), ".")
+	if host == "" {
+		return nil
+	}
+	if HostIP(host) != "" {
+		return []string{host}
+	}
 
-	// 4. Re-encode the URL to ensure consistent encoding:
-	normalizedURL := parsedURL.String()
+	domain := ExtractDomain(host)
+	parents := []string{host}
+	for h := host; h != domain; {
+		i := strings.IndexByte(h, '.')
+		if i < 0 {
+			break
+		}
+		h = h[i+1:]
+		parents = append(parents, h)
+	}
+	return parents
+}
 
-	return normalizedURL
+// HostIP returns host in canonical form when it is an IP literal, or "" otherwise.
+func HostIP(host string) string {
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
 }
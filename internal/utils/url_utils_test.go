@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractDomain(t *testing.T) {
+	for _, host := range []string{
+		"foo.bar.example.co.uk",
+		"example.com",
+		"www.example.com",
+		"localhost",
+		"co.uk",
+		"10.0.0.1",
+		"a.b.unknown-tld-xyz",
+		"example.",
+	} {
+		want, _, err := ExtractDomainAndSubDomains(host)
+		assert.NoError(t, err)
+		assert.Equal(t, want, ExtractDomain(host), host)
+	}
+}
+
+func TestParentHosts(t *testing.T) {
+	assert.Equal(t, []string{"a.b.example.co.uk", "b.example.co.uk", "example.co.uk"}, ParentHosts("A.b.example.co.uk."))
+	assert.Equal(t, []string{"example.com"}, ParentHosts("example.com"))
+	assert.Equal(t, []string{"10.0.0.1"}, ParentHosts("10.0.0.1"))
+	assert.Equal(t, []string{"localhost"}, ParentHosts("localhost"))
+	assert.Nil(t, ParentHosts(""))
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"blacked/internal/config"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+)
+
+const validatorsSuffix = "_validators.json"
+
+var (
+	// ErrSourceNotModified is returned by a conditional fetch answered with
+	// 304 Not Modified: the source still serves the last imported response.
+	ErrSourceNotModified = errors.New("source not modified")
+
+	ErrReadValidators  = errors.New("failed to read fetch validators")
+	ErrWriteValidators = errors.New("failed to write fetch validators")
+)
+
+// FetchValidators are the cache validators a source sent with a response.
+// Those of the last imported response are sent back as If-None-Match and
+// If-Modified-Since, so an unchanged source answers 304 without a body.
+type FetchValidators struct {
+	URL          string `json:"url"` // The source or mirror that sent them
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// Empty reports whether the response carried no validator.
+func (v FetchValidators) Empty() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+func validatorsFilename(providerName string) string {
+	return filepath.Join(config.GetConfig().Collector.StorePath, providerName+validatorsSuffix)
+}
+
+// LoadValidators returns the validators of the last response imported from
+// the provider, empty when there are none.
+func LoadValidators(providerName string) (FetchValidators, error) {
+	var v FetchValidators
+	data, err := os.ReadFile(validatorsFilename(providerName))
+	if os.IsNotExist(err) {
+		return v, nil
+	}
+	if err != nil {
+		log.Err(err).Str("provider", providerName).Msg("Failed to read fetch validators")
+		return v, ErrReadValidators
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		log.Err(err).Str("provider", providerName).Msg("Failed to decode fetch validators")
+		return FetchValidators{}, ErrReadValidators
+	}
+	return v, nil
+}
+
+// SaveValidators stores the validators of a response imported from the
+// provider, replacing the previous ones. Empty validators remove them, so a
+// source that stops sending validators is fetched unconditionally.
+func SaveValidators(providerName string, v FetchValidators) error {
+	filename := validatorsFilename(providerName)
+	if v.Empty() {
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			log.Err(err).Str("file", filename).Msg("Failed to remove fetch validators")
+			return ErrWriteValidators
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Err(err).Str("provider", providerName).Msg("Failed to encode fetch validators")
+		return ErrWriteValidators
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		log.Err(err).Str("file", filename).Msg("Failed to create directory for fetch validators")
+		return ErrWriteValidators
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		log.Err(err).Str("file", filename).Msg("Failed to write fetch validators")
+		return ErrWriteValidators
+	}
+	return nil
+}
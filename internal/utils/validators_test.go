@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"blacked/internal/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveValidators(t *testing.T) {
+	cfg := config.GetConfig()
+	storePath := cfg.Collector.StorePath
+	cfg.Collector.StorePath = t.TempDir()
+	defer func() { cfg.Collector.StorePath = storePath }()
+
+	v, err := LoadValidators("feed")
+	require.NoError(t, err)
+	assert.True(t, v.Empty(), "a provider never imported has no validators")
+
+	saved := FetchValidators{URL: "https://feeds.example/feed.txt", ETag: `"abc"`, LastModified: "Fri, 16 Oct 2026 12:00:00 GMT"}
+	require.NoError(t, SaveValidators("feed", saved))
+	v, err = LoadValidators("feed")
+	require.NoError(t, err)
+	assert.Equal(t, saved, v)
+
+	require.NoError(t, SaveValidators("feed", FetchValidators{URL: saved.URL}))
+	v, err = LoadValidators("feed")
+	require.NoError(t, err)
+	assert.True(t, v.Empty(), "a response without validators removes the old ones")
+}
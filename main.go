@@ -2,9 +2,17 @@ package main
 
 import (
 	"blacked/cmd"
+	"blacked/features/apikeys"
+	"blacked/features/audit"
 	"blacked/features/cache"
 	"blacked/features/entry_collector"
+	"blacked/features/eventbus"
+	"blacked/features/hits"
+	"blacked/features/maintenance"
 	"blacked/features/providers"
+	providerrepo "blacked/features/providers/repository"
+	"blacked/features/stats"
+	"blacked/features/watchlist"
 	"blacked/internal/config"
 	"blacked/internal/db"
 	"blacked/internal/logger"
@@ -87,9 +95,15 @@ func before(ctx context.Context) cli.BeforeFunc {
 		c.Context = context.WithValue(ctx, "telemetry_shutdown", shutdownTelemetry)
 		log.Debug().Msg("Telemetry initialized")
 
+		// Edge nodes serve from a memory-mapped dataset only.
+		if cmd.IsEdgeServe(c.Args().Slice()) {
+			log.Debug().Msg("Edge mode: skipping database, cache and provider initialization")
+			return nil
+		}
+
 		log.Trace().Msg("Initializing database connections")
 		// Initialize DB triggers creation of both read and write connections
-		db.InitializeDB()
+		db.InitializeDB(db.WithConfig(config.GetConfig().Database))
 
 		// Get the write connection for the collector (handles inserts/updates)
 		writeDB, err := db.GetWriteDB()
@@ -107,17 +121,44 @@ func before(ctx context.Context) cli.BeforeFunc {
 		}
 		log.Debug().Msg("Schema migration completed (providers, sources, entries, provider_processes)")
 
-		log.Trace().Msg("Initializing Cache Provider")
-		if err := cache.InitializeCache(ctx); err != nil {
-			log.Error().Err(err).Stack().Msg("Failed to initialize Cache Provider")
-			return err
+		// Every role honours read-only mode, so it is loaded before any of them starts.
+		maintenance.Init(writeDB)
+
+		// Ingest-only roles serve no queries and leave the cache to the API pods.
+		skipCache := cmd.SkipsCache(c.Args().Slice())
+		if skipCache {
+			log.Debug().Msg("Ingest-only mode: skipping cache initialization")
+		} else {
+			log.Trace().Msg("Initializing Cache Provider")
+			if err := cache.InitializeCache(ctx); err != nil {
+				log.Error().Err(err).Stack().Msg("Failed to initialize Cache Provider")
+				return err
+			}
+			log.Debug().Msg("Cache Provider Initialized")
 		}
-		log.Debug().Msg("Cache Provider Initialized")
 
 		log.Debug().Msg("Initializing Pond Collector")
-		entry_collector.InitPondCollector(ctx, writeDB)
+		pond := entry_collector.InitPondCollector(ctx, writeDB)
+		if skipCache {
+			pond.DisableCacheSync()
+		}
 		log.Debug().Msg("Pond Collector Initialized")
 
+		// Only the served API counts hits, audits queries and authenticates
+		// callers; ad-hoc CLI queries are not traffic.
+		if cmd.RunModeFromArgs(c.Args().Slice()).Subsystems().API {
+			hits.InitCounter(writeDB)
+			audit.Init(writeDB)
+			stats.Init(writeDB)
+			apikeys.Init(writeDB)
+		}
+
+		// Provider runs record their fetch, parse and error events for
+		// GET /provider/processes/:processID/events.
+		providers.GetProcessManager().SetEventStore(providerrepo.NewSQLiteProviderProcessRepository(writeDB))
+		// They raise the events of the watchlists their additions match.
+		watchlist.Init(writeDB)
+
 		log.Trace().Msg("Initializing providers")
 		_, err = providers.InitProviders()
 		if err != nil {
@@ -140,6 +181,32 @@ func cleanup() {
 		log.Debug().Msg("Pond collector closed")
 	}
 
+	// Write pending entry hits
+	if counter := hits.GetCounter(); counter != nil {
+		counter.Close()
+		log.Debug().Msg("Hit counter closed")
+	}
+
+	// Write pending audited queries
+	if recorder := audit.Get(); recorder != nil {
+		recorder.Close()
+		log.Debug().Msg("Query audit recorder closed")
+	}
+
+	// Write the rollups of the current interval
+	if recorder := stats.Get(); recorder != nil {
+		recorder.Close()
+		log.Debug().Msg("Stats recorder closed")
+	}
+
+	// Close event bus publisher
+	if bus := eventbus.Get(); bus != nil {
+		if err := bus.Close(); err != nil {
+			log.Warn().Err(err).Msg("Failed to close event bus publisher")
+		}
+		log.Debug().Msg("Event bus publisher closed")
+	}
+
 	// Close cache
 	cache.CloseCache()
 	log.Debug().Msg("Cache closed")